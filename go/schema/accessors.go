@@ -0,0 +1,176 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Errors from the typed accessors, for errors.Is.
+var (
+	ErrMissingKey = errors.New("missing key")
+	ErrWrongType  = errors.New("wrong type")
+)
+
+// Get returns result[key] as T. Decoded numbers are float64; asking for
+// int, int64 or uint64 converts them when they are whole and in range.
+//
+//	temp, err := schema.Get[float64](result, "temperature")
+func Get[T any](result map[string]any, key string) (T, error) {
+	v, ok := result[key]
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%s: %w", key, ErrMissingKey)
+	}
+	return convertAs[T](key, v)
+}
+
+// GetNested returns the value at a dotted path into nested objects and
+// arrays, e.g. "gps.lat" or "readings[2].value", as T.
+func GetNested[T any](result map[string]any, path string) (T, error) {
+	var zero T
+	var cur any = result
+	at := ""
+	for _, part := range strings.Split(path, ".") {
+		name, indexes, err := splitIndexes(part)
+		if err != nil {
+			return zero, fmt.Errorf("%s: %w", path, err)
+		}
+		if name != "" {
+			at = joinPath(at, name)
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return zero, fmt.Errorf("%s: expected object, got %s: %w", parentPath(at), describeInput(cur), ErrWrongType)
+			}
+			if cur, ok = obj[name]; !ok {
+				return zero, fmt.Errorf("%s: %w", at, ErrMissingKey)
+			}
+		}
+		for _, i := range indexes {
+			arr, ok := cur.([]any)
+			if !ok {
+				return zero, fmt.Errorf("%s: expected array, got %s: %w", at, describeInput(cur), ErrWrongType)
+			}
+			at = fmt.Sprintf("%s[%d]", at, i)
+			if i >= len(arr) {
+				return zero, fmt.Errorf("%s: %w (length %d)", at, ErrMissingKey, len(arr))
+			}
+			cur = arr[i]
+		}
+	}
+	return convertAs[T](path, cur)
+}
+
+// GetSlice returns the array result[key] with each element as T.
+func GetSlice[T any](result map[string]any, key string) ([]T, error) {
+	v, ok := result[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, ErrMissingKey)
+	}
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected array, got %s: %w", key, describeInput(v), ErrWrongType)
+	}
+	out := make([]T, len(arr))
+	for i, e := range arr {
+		t, err := convertAs[T](fmt.Sprintf("%s[%d]", key, i), e)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = t
+	}
+	return out, nil
+}
+
+// convertAs returns v as T, converting between number types without loss.
+func convertAs[T any](path string, v any) (T, error) {
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+	var zero T
+	ok := false
+	switch p := any(&zero).(type) {
+	case *float64:
+		*p, ok = toFloat64(v)
+	case *int:
+		var n int64
+		if n, ok = wholeInt64(v); ok && int64(int(n)) == n {
+			*p = int(n)
+		} else {
+			ok = false
+		}
+	case *int64:
+		*p, ok = wholeInt64(v)
+	case *uint64:
+		*p, ok = wholeUint64(v)
+	}
+	if !ok {
+		return zero, fmt.Errorf("%s: expected %T, got %s: %w", path, zero, describeInput(v), ErrWrongType)
+	}
+	return zero, nil
+}
+
+func wholeInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64
+	}
+	return 0, false
+}
+
+func wholeUint64(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return n, true
+	case int64:
+		return uint64(n), n >= 0
+	case int:
+		return uint64(n), n >= 0
+	case float64:
+		return uint64(n), n == math.Trunc(n) && n >= 0 && n < math.MaxUint64
+	}
+	return 0, false
+}
+
+// splitIndexes splits "readings[2][0]" into "readings" and [2 0].
+func splitIndexes(part string) (string, []int, error) {
+	name, rest, _ := strings.Cut(part, "[")
+	if rest == "" {
+		return name, nil, nil
+	}
+	var indexes []int
+	for _, s := range strings.Split(strings.TrimSuffix(rest, "]"), "][") {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 {
+			return "", nil, fmt.Errorf("bad index %q in %q", s, part)
+		}
+		indexes = append(indexes, i)
+	}
+	return name, indexes, nil
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return "(result)"
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTypedAccessors(t *testing.T) {
+	result := map[string]any{
+		"temperature": 21.5,
+		"count":       3.0,
+		"total":       uint64(18446744073709551615),
+		"status":      "ok",
+		"gps":         map[string]any{"lat": 45.5, "fix": true},
+		"readings": []any{
+			map[string]any{"value": 1.0},
+			map[string]any{"value": 2.0},
+		},
+		"levels": []any{1.0, 2.0, 4.0},
+	}
+
+	if v, err := Get[float64](result, "temperature"); err != nil || v != 21.5 {
+		t.Errorf("Get[float64](temperature) = %v, %v", v, err)
+	}
+	if v, err := Get[int](result, "count"); err != nil || v != 3 {
+		t.Errorf("Get[int](count) = %v, %v", v, err)
+	}
+	if v, err := Get[uint64](result, "total"); err != nil || v != 18446744073709551615 {
+		t.Errorf("Get[uint64](total) = %v, %v", v, err)
+	}
+	if v, err := GetNested[bool](result, "gps.fix"); err != nil || !v {
+		t.Errorf("GetNested[bool](gps.fix) = %v, %v", v, err)
+	}
+	if v, err := GetNested[float64](result, "readings[1].value"); err != nil || v != 2 {
+		t.Errorf("GetNested(readings[1].value) = %v, %v", v, err)
+	}
+	if v, err := GetSlice[int](result, "levels"); err != nil || !reflect.DeepEqual(v, []int{1, 2, 4}) {
+		t.Errorf("GetSlice[int](levels) = %v, %v", v, err)
+	}
+
+	errTests := []struct {
+		name    string
+		get     func() error
+		is      error
+		message string
+	}{
+		{"missing", func() error { _, err := Get[float64](result, "humidity"); return err }, ErrMissingKey, "humidity"},
+		{"wrong type", func() error { _, err := Get[float64](result, "status"); return err }, ErrWrongType, `status: expected float64, got string "ok"`},
+		{"not whole", func() error { _, err := Get[int](result, "temperature"); return err }, ErrWrongType, "expected int, got number"},
+		{"overflow", func() error { _, err := Get[int64](result, "total"); return err }, ErrWrongType, "expected int64"},
+		{"nested missing", func() error { _, err := GetNested[float64](result, "gps.alt"); return err }, ErrMissingKey, "gps.alt"},
+		{"not an object", func() error { _, err := GetNested[float64](result, "status.x"); return err }, ErrWrongType, "status: expected object"},
+		{"index out of range", func() error { _, err := GetNested[float64](result, "readings[5].value"); return err }, ErrMissingKey, "readings[5]"},
+		{"slice element", func() error { _, err := GetSlice[string](result, "levels"); return err }, ErrWrongType, "levels[0]: expected string"},
+	}
+	for _, tt := range errTests {
+		err := tt.get()
+		if !errors.Is(err, tt.is) || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: error = %v, want %v containing %q", tt.name, err, tt.is, tt.message)
+		}
+	}
+}
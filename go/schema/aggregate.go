@@ -0,0 +1,105 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+)
+
+// Aggregates summarize a repeat decoded earlier in the payload, the usual
+// need of multi-sample uplinks:
+//
+//	- name: readings
+//	  type: repeat
+//	  count: $n
+//	  fields:
+//	    - {name: temp, type: s16, div: 10}
+//	- name: avg_temp
+//	  type: number
+//	  compute: {fn: avg, over: $readings, pick: temp}
+//
+// over names the array (indexed references such as $log[0].samples are
+// allowed) and pick the member of each element, for arrays of objects.
+// Null and non-numeric values are skipped. count is the number of values
+// aggregated; with none, sum and count are 0 and the other functions leave
+// the field out.
+
+// aggregateFns are the functions compute: fn accepts.
+var aggregateFns = map[string]bool{"sum": true, "avg": true, "min": true, "max": true, "count": true, "last": true}
+
+// checkAggregate reports a malformed aggregate compute:, or "".
+func checkAggregate(cd *ComputeDef) string {
+	switch {
+	case cd.Fn == "":
+		if cd.Over != "" || cd.Pick != "" {
+			return "compute: over and pick need fn"
+		}
+		return ""
+	case !aggregateFns[cd.Fn]:
+		return fmt.Sprintf("compute: unknown fn %q (want sum, avg, min, max, count or last)", cd.Fn)
+	case cd.Op != "":
+		return "compute: fn and op cannot be combined"
+	case cd.Over == "":
+		return fmt.Sprintf("compute: fn %s needs over", cd.Fn)
+	}
+	return ""
+}
+
+// evaluateAggregate applies an aggregate compute:, returning nil when
+// there is nothing to aggregate.
+func evaluateAggregate(cd *ComputeDef, ctx *DecodeContext) (any, error) {
+	v, ok := lookupVar(ctx.Variables, cd.Over)
+	if !ok {
+		return nil, fmt.Errorf("compute over: field not found: %s", cd.Over)
+	}
+	elems, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("compute over: %s is not an array", cd.Over)
+	}
+
+	var sum, last float64
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	n := 0
+	for _, elem := range elems {
+		if cd.Pick != "" {
+			m, isMap := elem.(map[string]any)
+			if !isMap {
+				continue
+			}
+			elem, _ = lookupVar(m, cd.Pick)
+		}
+		f, ok := toFloat64(elem)
+		if !ok {
+			continue
+		}
+		sum += f
+		minVal = math.Min(minVal, f)
+		maxVal = math.Max(maxVal, f)
+		last = f
+		n++
+	}
+
+	switch cd.Fn {
+	case "sum":
+		return sum, nil
+	case "count":
+		return float64(n), nil
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	switch cd.Fn {
+	case "avg":
+		return sum / float64(n), nil
+	case "min":
+		return minVal, nil
+	case "max":
+		return maxVal, nil
+	case "last":
+		return last, nil
+	}
+	return nil, fmt.Errorf("unknown compute fn: %s", cd.Fn)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+const aggregateSchema = `
+name: multi_sample
+fields:
+  - {name: n, type: u8}
+  - name: readings
+    type: repeat
+    count: $n
+    fields:
+      - {name: temp, type: s16, div: 10, invalid: [0x7FFF]}
+  - {name: temp_sum, type: number, compute: {fn: sum, over: $readings, pick: temp}, round: 1}
+  - {name: temp_avg, type: number, compute: {fn: avg, over: $readings, pick: temp}, round: 2}
+  - {name: temp_min, type: number, compute: {fn: min, over: $readings, pick: temp}}
+  - {name: temp_max, type: number, compute: {fn: max, over: $readings, pick: temp}}
+  - {name: temp_count, type: number, compute: {fn: count, over: $readings, pick: temp}}
+  - {name: temp_last, type: number, compute: {fn: last, over: $readings, pick: temp}}
+`
+
+func TestAggregateCompute(t *testing.T) {
+	s, err := ParseSchema(aggregateSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+	// 20.0, invalid, 23.5, 21.0
+	result, err := s.Decode([]byte{0x04, 0x00, 0xC8, 0x7F, 0xFF, 0x00, 0xEB, 0x00, 0xD2})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]float64{
+		"temp_sum": 64.5, "temp_avg": 21.5, "temp_min": 20, "temp_max": 23.5, "temp_count": 3, "temp_last": 21,
+	}
+	for key, w := range want {
+		if result[key] != w {
+			t.Errorf("%s = %v, want %v", key, result[key], w)
+		}
+	}
+
+	// No samples: sum and count are 0, the rest are left out
+	result, err = s.Decode([]byte{0x00})
+	if err != nil {
+		t.Fatalf("Decode() empty error = %v", err)
+	}
+	if result["temp_sum"] != 0.0 || result["temp_count"] != 0.0 {
+		t.Errorf("empty sum, count = %v, %v", result["temp_sum"], result["temp_count"])
+	}
+	if _, ok := result["temp_avg"]; ok {
+		t.Errorf("empty temp_avg = %v, want absent", result["temp_avg"])
+	}
+}
+
+func TestAggregateScalarsAndErrors(t *testing.T) {
+	s, err := ParseSchema(`
+name: flat
+fields:
+  - {name: values, type: repeat, count: 3, flatten: true, fields: [{name: v, type: u8}]}
+  - {name: peak, type: number, compute: {fn: max, over: $values}}
+  - {name: bad, type: number, compute: {fn: median, over: $values}}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], `unknown fn "median"`) {
+		t.Errorf("Warnings = %v", s.Warnings)
+	}
+	result, err := s.Decode([]byte{0x03, 0x09, 0x04})
+	if err == nil {
+		t.Fatalf("Decode() = %v, want unknown fn error", result)
+	}
+
+	s, err = ParseSchema(`
+name: flat
+fields:
+  - {name: values, type: repeat, count: 3, flatten: true, fields: [{name: v, type: u8}]}
+  - {name: peak, type: number, compute: {fn: max, over: $values}}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err = s.Decode([]byte{0x03, 0x09, 0x04})
+	if err != nil || result["peak"] != 9.0 {
+		t.Errorf("peak = %v, %v; want 9", result["peak"], err)
+	}
+}
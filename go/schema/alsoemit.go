@@ -0,0 +1,93 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+)
+
+// AlsoEmit is a second output key derived from a field's decoded value,
+// typically the same reading in another unit:
+//
+//	fields:
+//	  - name: temperature
+//	    type: s16
+//	    div: 10
+//	    also_emit: {name: temperature_f, convert: "x * 1.8 + 32", round: 1}
+//
+// convert is a formula of x, the field's value after its modifiers, and
+// may read other fields as $name. also_emit takes a list for several
+// units. The key is decoded only: encoding reads the field's own key.
+type AlsoEmit struct {
+	Name    string `json:"name" yaml:"name"`
+	Convert string `json:"convert" yaml:"convert"`
+	Round   *int   `json:"round,omitempty" yaml:"round,omitempty"` // Decimal places
+
+	convert *compiledFormula // Convert, compiled at parse time
+}
+
+// parseAlsoEmit reads also_emit: as one entry or a list of them.
+func parseAlsoEmit(raw any) ([]AlsoEmit, []string) {
+	items, ok := raw.([]any)
+	if !ok {
+		items = []any{raw}
+	}
+	var out []AlsoEmit
+	var msgs []string
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			msgs = append(msgs, fmt.Sprintf("also_emit[%d]: expected {name, convert}", i))
+			continue
+		}
+		ae := AlsoEmit{}
+		ae.Name, _ = m["name"].(string)
+		ae.Convert, _ = m["convert"].(string)
+		if ae.Name == "" || ae.Convert == "" {
+			msgs = append(msgs, fmt.Sprintf("also_emit[%d]: name: and convert: are required", i))
+			continue
+		}
+		if round, ok := intKey(m, "round"); ok {
+			ae.Round = &round
+		}
+		c, err := compileFormula(ae.Convert)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("also_emit %s: %v", ae.Name, err))
+			continue
+		}
+		ae.convert = c
+		out = append(out, ae)
+	}
+	return out, msgs
+}
+
+// emitAlso sets the also_emit keys of field, decoded as value, in result.
+// A null or non-numeric value gives null keys.
+func (ctx *DecodeContext) emitAlso(field Field, value any, result map[string]any) error {
+	for _, ae := range field.AlsoEmit {
+		numVal, ok := toFloat64(value)
+		if !ok {
+			result[ae.Name] = nil
+			ctx.Variables[ae.Name] = nil
+			continue
+		}
+		c, err := formulaOf(ae.convert, ae.Convert)
+		if err != nil {
+			return err
+		}
+		v, err := c.eval(numVal, ctx.Variables)
+		if err != nil {
+			return fmt.Errorf("%s: also_emit %s: %w", field.Name, ae.Name, err)
+		}
+		if ae.Round != nil && *ae.Round >= 0 && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			scale := math.Pow(10, float64(*ae.Round))
+			v = math.Round(v*scale) / scale
+		}
+		result[ae.Name] = v
+		ctx.Variables[ae.Name] = v
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const alsoEmitSchema = `
+name: climate
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+    also_emit: {name: temperature_f, convert: "x * 1.8 + 32", round: 1}
+  - name: pressure
+    type: u16
+    invalid: 0xFFFF
+    also_emit:
+      - {name: pressure_inhg, convert: "x * 0.02953", round: 2}
+      - {name: pressure_psi, convert: "x * 0.0145038", round: 3}
+  - name: above_freezing
+    type: number
+    formula: "$temperature_f > 32 ? 1 : 0"
+`
+
+func TestAlsoEmit(t *testing.T) {
+	s, err := ParseSchema(alsoEmitSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	got, err := s.Decode([]byte{0x00, 0xFD, 0x03, 0xF5})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"temperature":    25.3,
+		"temperature_f":  77.5,
+		"pressure":       int64(1013),
+		"pressure_inhg":  29.91,
+		"pressure_psi":   14.692,
+		"above_freezing": 1.0,
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(toComparable(got[k]), toComparable(v)) {
+			t.Errorf("%s = %v (%T), want %v", k, got[k], got[k], v)
+		}
+	}
+
+	// A null reading gives null conversions
+	got, err = s.Decode([]byte{0x00, 0xFD, 0xFF, 0xFF})
+	if err != nil {
+		t.Fatalf("Decode(invalid) error = %v", err)
+	}
+	for _, k := range []string{"pressure", "pressure_inhg", "pressure_psi"} {
+		if v, ok := got[k]; !ok || v != nil {
+			t.Errorf("%s = %v, %v; want null", k, v, ok)
+		}
+	}
+
+	// Decoded output re-encodes; the converted keys are ignored
+	encoded, err := s.Encode(map[string]any{"temperature": 25.3, "temperature_f": 77.5, "pressure": 1013, "pressure_inhg": 29.91})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !reflect.DeepEqual(encoded, []byte{0x00, 0xFD, 0x03, 0xF5}) {
+		t.Errorf("Encode() = % X", encoded)
+	}
+}
+
+func toComparable(v any) any {
+	if f, ok := toFloat64(v); ok {
+		return f
+	}
+	return v
+}
+
+func TestAlsoEmitWarnings(t *testing.T) {
+	s, err := ParseSchema(`
+name: bad
+fields:
+  - name: a
+    type: u8
+    also_emit: {name: a_f}
+  - name: b
+    type: u8
+    also_emit: {name: c, convert: "x *"}
+  - name: c
+    type: u8
+    also_emit: {name: a, convert: "x + 1"}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	joined := strings.Join(s.Warnings, "\n")
+	for _, want := range []string{
+		"also_emit[0]: name: and convert: are required",
+		"also_emit c:",
+		`duplicate output name "a"`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Warnings = %v, want %q", s.Warnings, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package schema
 
 import (
 	"encoding/hex"
+	"fmt"
 	"testing"
 )
 
@@ -216,6 +217,51 @@ func BenchmarkBinaryParse(b *testing.B) {
 	}
 }
 
+// Downlink schema for encode benchmarks
+const downlinkSchema = `
+name: set_config
+fields:
+  - name: command
+    type: u8
+  - name: interval
+    type: u16
+  - name: threshold
+    type: s16
+    div: 10
+  - name: enabled
+    type: bool
+    consume: 1
+`
+
+var downlinkValues = map[string]any{"command": 1, "interval": 600, "threshold": -12.5, "enabled": true}
+
+func BenchmarkEncode(b *testing.B) {
+	schema, err := ParseSchema(downlinkSchema)
+	if err != nil {
+		b.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = schema.Encode(downlinkValues)
+	}
+}
+
+func BenchmarkAppendEncode(b *testing.B) {
+	schema, err := ParseSchema(downlinkSchema)
+	if err != nil {
+		b.Fatalf("Failed to parse schema: %v", err)
+	}
+	buf := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, _ = schema.AppendEncode(buf[:0], downlinkValues)
+	}
+}
+
 // Print results for comparison
 func TestBenchmarkResults(t *testing.T) {
 	payload, _ := hex.DecodeString(testPayloadHex)
@@ -248,4 +294,54 @@ func TestBenchmarkResults(t *testing.T) {
 	t.Log("  Parse-only:")
 	t.Log("    BenchmarkYAMLParse                  - YAML parsing overhead")
 	t.Log("    BenchmarkBinaryParse                - Binary parsing overhead")
+	t.Log("")
+	t.Log("  Encode:")
+	t.Log("    BenchmarkEncode                     - Fresh buffer per encode")
+	t.Log("    BenchmarkAppendEncode               - Reused caller buffer")
+	t.Log("")
+	t.Log("  Integers:")
+	t.Log("    BenchmarkDecodeUint / DecodeSint    - 2 to 8 byte integer decode")
+	t.Log("    BenchmarkEncodeUint                 - 2 to 8 byte integer encode")
+}
+
+var (
+	intBench = []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}
+	intSink  uint64
+)
+
+// Integer decode micro-benchmarks: 2, 4 and 8 bytes take the
+// encoding/binary fast path, 3 bytes the byte loop.
+func BenchmarkDecodeUint(b *testing.B) {
+	for _, length := range []int{2, 3, 4, 8} {
+		for _, endian := range []string{"big", "little"} {
+			data := intBench[:length]
+			b.Run(fmt.Sprintf("%d_%s", length, endian), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					intSink += decodeUint(data, endian)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecodeSint(b *testing.B) {
+	for _, length := range []int{2, 3, 4, 8} {
+		data := intBench[:length]
+		b.Run(fmt.Sprint(length), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				intSink += uint64(decodeSint(data, "little"))
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeUint(b *testing.B) {
+	for _, length := range []int{2, 3, 4, 8} {
+		b.Run(fmt.Sprint(length), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = encodeUint(0x123456789ABCDEF0, length, "big")
+			}
+		})
+	}
 }
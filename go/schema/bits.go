@@ -0,0 +1,157 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+)
+
+// A bits field reads a bit range from the word at the cursor (or at
+// byte_offset) without consuming it. length: sets the word size in bytes,
+// so ranges can span the bytes of a 16- or 32-bit register; the word is
+// read in the field's endianness before bit_offset is applied:
+//
+//	- {name: status, type: u16, endian: little}
+//	- {name: mode, type: bits, length: 2, endian: little, byte_offset: -2, bit_offset: 10, bits: 4}
+
+// bitsLength returns the word size of a bits field in bytes.
+func bitsLength(field Field) int {
+	if field.Length > 0 {
+		return field.Length
+	}
+	return 1
+}
+
+// bitsWidth returns the number of bits a bits field extracts.
+func bitsWidth(field Field) int {
+	if field.Bits > 0 {
+		return field.Bits
+	}
+	return 1
+}
+
+// checkBits validates a bits field's word size and bit range.
+func checkBits(f Field) string {
+	if f.Type != TypeBits && f.Type != TypeBitsLower {
+		return ""
+	}
+	n := bitsLength(f)
+	if n > 8 {
+		return fmt.Sprintf("length: bits words are 1 to 8 bytes, got %d", n)
+	}
+	if f.BitOffset < 0 || f.BitOffset+bitsWidth(f) > 8*n {
+		return fmt.Sprintf("bits %d at bit_offset %d do not fit a %d-byte word", bitsWidth(f), f.BitOffset, n)
+	}
+	return ""
+}
+
+// decodeBitsField extracts a bits field from its word.
+func decodeBitsField(field Field, ctx *DecodeContext, endian string) (float64, error) {
+	data, err := ctx.readField(field, bitsLength(field))
+	if err != nil {
+		return 0, err
+	}
+	word := decodeUint(data, endian)
+	return float64(word >> field.BitOffset & (uint64(1)<<bitsWidth(field) - 1)), nil
+}
+
+// encodeBits ORs a bits field into its word. Bytes of the word not yet in
+// the buffer are held until a later write (or the end of the encode)
+// reaches them, so a bits field may precede the field that owns the word
+// (see fillBitsWord for an owner missing from the input).
+func (ctx *EncodeContext) encodeBits(field Field, value any, endian string) error {
+	if f, isFloat := value.(float64); isFloat && !math.IsNaN(f) {
+		value = ctx.round(f)
+	}
+	n, ok := toInt(value)
+	width := bitsWidth(field)
+	if !ok || n < 0 || uint64(n) >= uint64(1)<<width {
+		return fmt.Errorf("%s: %v does not fit in %d bits", ctx.fieldPath(field.Name), value, width)
+	}
+	return ctx.orWord(field, encodeUint(uint64(n)<<field.BitOffset, bitsLength(field), endian))
+}
+
+// encodeBool ORs a bool field's bit into its byte, as encodeBits does.
+func (ctx *EncodeContext) encodeBool(field Field, value any) error {
+	set, ok := value.(bool)
+	if !ok {
+		n, isNum := toInt(value)
+		if !isNum {
+			return fmt.Errorf("%s: expected a bool, got %v", ctx.fieldPath(field.Name), value)
+		}
+		set = n != 0
+	}
+	var word byte
+	if set {
+		word = 1 << field.Bit
+	}
+	return ctx.orWord(field, []byte{word})
+}
+
+// orWord ORs word into the bytes at the field's byte_offset from the end
+// of the buffer, holding bytes past it, then writes consume: zero bytes.
+func (ctx *EncodeContext) orWord(field Field, word []byte) error {
+	pos := len(ctx.Buffer) + field.ByteOffset
+	if pos < 0 {
+		return fmt.Errorf("%s: byte_offset %d before start of payload", ctx.fieldPath(field.Name), field.ByteOffset)
+	}
+	for i, b := range word {
+		if pos+i < len(ctx.Buffer) {
+			ctx.Buffer[pos+i] |= b
+			continue
+		}
+		if ctx.pendingBits == nil {
+			ctx.pendingBits = map[int]byte{}
+		}
+		ctx.pendingBits[pos+i] |= b
+	}
+	if consume := consumeLength(field, 0); consume > 0 {
+		ctx.Write(make([]byte, consume))
+	}
+	return nil
+}
+
+// applyPendingBits merges held bits into bytes the buffer now covers.
+func (ctx *EncodeContext) applyPendingBits() {
+	for pos, b := range ctx.pendingBits {
+		if pos < len(ctx.Buffer) {
+			ctx.Buffer[pos] |= b
+			delete(ctx.pendingBits, pos)
+		}
+	}
+}
+
+// fillBitsWord writes zeros for a skipped field that owns the word held
+// bits are waiting for, so the bits land in that word rather than in the
+// bytes of whichever field is written next.
+func (ctx *EncodeContext) fillBitsWord(field Field) {
+	if len(ctx.pendingBits) == 0 || isPositionalView(field) {
+		return
+	}
+	_, consume, err := fieldReadSize(field)
+	if err != nil || consume == 0 {
+		return
+	}
+	for i := 0; i < consume; i++ {
+		if _, held := ctx.pendingBits[len(ctx.Buffer)+i]; held {
+			ctx.Write(make([]byte, consume))
+			return
+		}
+	}
+}
+
+// flushBits writes out words of bits fields that no later field covered.
+func (ctx *EncodeContext) flushBits() {
+	end := len(ctx.Buffer)
+	for pos := range ctx.pendingBits {
+		if pos >= end {
+			end = pos + 1
+		}
+	}
+	if end > len(ctx.Buffer) {
+		ctx.Write(make([]byte, end-len(ctx.Buffer)))
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBitsMultiByteWord(t *testing.T) {
+	s, err := ParseSchema(`
+name: status
+fields:
+  - name: mode
+    type: bits
+    length: 2
+    endian: little
+    bit_offset: 10
+    bits: 4
+    lookup: {0: idle, 9: active}
+  - name: alarm
+    type: bits
+    length: 2
+    endian: little
+    bit_offset: 0
+    bits: 3
+  - name: status
+    type: u16
+    endian: little
+  - name: counter
+    type: bits
+    length: 4
+    bit_offset: 12
+    bits: 16
+  - name: _word
+    type: u32
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	// Little-endian 0x2405: bits 10..13 = 9, bits 0..2 = 5.
+	// Big-endian 0x0ABCD000: bits 12..27 = 0xABCD.
+	data := []byte{0x05, 0x24, 0x0A, 0xBC, 0xD0, 0x00}
+	got, err := s.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["mode"] != "active" || got["alarm"] != 5.0 || got["counter"] != float64(0xABCD) {
+		t.Errorf("Decode() = %v", got)
+	}
+
+	// _word is not encoded; counter alone rebuilds it
+	out, err := s.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("Encode() = % X, want % X", out, data)
+	}
+}
+
+func TestBitsEncodeIntoOwningWord(t *testing.T) {
+	s, err := ParseSchema(`
+name: status
+endian: little
+fields:
+  - {name: channel, type: bits, length: 2, bit_offset: 8, bits: 4}
+  - {name: status, type: u16}
+  - {name: battery, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	out, err := s.Encode(map[string]any{"channel": 3, "status": 0x8001, "battery": 200})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := []byte{0x01, 0x83, 0xC8}; !bytes.Equal(out, want) {
+		t.Errorf("Encode() = % X, want % X", out, want)
+	}
+
+	// A bits word no later field writes is flushed at the end
+	out, err = s.Encode(map[string]any{"channel": 3})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := []byte{0x00, 0x03}; !bytes.Equal(out, want) {
+		t.Errorf("Encode() = % X, want % X", out, want)
+	}
+
+	if _, err := s.Encode(map[string]any{"channel": 16}); err == nil || !strings.Contains(err.Error(), "does not fit in 4 bits") {
+		t.Errorf("Encode(16) error = %v, want overflow", err)
+	}
+}
+
+func TestBitsEncodeAbsentOwningWord(t *testing.T) {
+	s, err := ParseSchema(`
+name: status
+fields:
+  - {name: mode, type: bits, length: 2, endian: little, bit_offset: 10, bits: 4}
+  - {name: status, type: u16, endian: little}
+  - {name: temp, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	// status is absent: its word is written as zeros carrying mode
+	out, err := s.Encode(map[string]any{"mode": 5, "temp": 1})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := []byte{0x00, 0x14, 0x00, 0x01}; !bytes.Equal(out, want) {
+		t.Fatalf("Encode() = % X, want % X", out, want)
+	}
+	got, err := s.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["mode"] != 5.0 || got["status"] != float64(0x1400) || got["temp"] != 1.0 {
+		t.Errorf("Decode() = %v", got)
+	}
+}
+
+func TestBitsWarnings(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{"{name: a, type: bits, length: 2, bit_offset: 12, bits: 5}", "do not fit a 2-byte word"},
+		{"{name: a, type: bits, bit_offset: 6, bits: 3}", "do not fit a 1-byte word"},
+		{"{name: a, type: bits, length: 9, bits: 3}", "1 to 8 bytes"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.field, s.Warnings, tt.want)
+		}
+	}
+}
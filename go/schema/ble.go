@@ -0,0 +1,99 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BLE advertisements carry vendor payloads in Manufacturer Specific Data
+// AD structures: a little-endian company identifier followed by the
+// vendor's bytes. A bundle selects the member for a beacon by company ID,
+// optionally refined by bytes of the vendor payload:
+//
+//	dispatch:
+//	  - match: {company_id: 0x0499, byte0: 0x05}
+//	    schema: ruuvi_df5
+//
+// byteN offsets count from after the company identifier, and the member
+// decodes the vendor payload like an uplink.
+
+// ADTypeManufacturerData is the AD type of Manufacturer Specific Data.
+const ADTypeManufacturerData = 0xFF
+
+// ADStructure is one length-type-value element of BLE advertising or scan
+// response data (Core Specification Vol 3, Part C, §11).
+type ADStructure struct {
+	Type byte
+	Data []byte
+}
+
+// ParseAdvertisement splits advertising data into its AD structures. A
+// zero length byte ends the data early, as in padded 31-byte
+// advertisements.
+func ParseAdvertisement(adv []byte) ([]ADStructure, error) {
+	var out []ADStructure
+	for off := 0; off < len(adv); {
+		n := int(adv[off])
+		if n == 0 {
+			break
+		}
+		if off+1+n > len(adv) {
+			return nil, fmt.Errorf("AD structure at offset %d: length %d exceeds data", off, n)
+		}
+		out = append(out, ADStructure{Type: adv[off+1], Data: adv[off+2 : off+1+n]})
+		off += 1 + n
+	}
+	return out, nil
+}
+
+// ManufacturerData returns the company identifier and vendor payload of a
+// Manufacturer Specific Data structure.
+func (ad ADStructure) ManufacturerData() (companyID uint16, payload []byte, ok bool) {
+	if ad.Type != ADTypeManufacturerData || len(ad.Data) < 2 {
+		return 0, nil, false
+	}
+	return binary.LittleEndian.Uint16(ad.Data), ad.Data[2:], true
+}
+
+// ResolveBLE returns the member chosen by the first dispatch rule matching
+// a vendor payload from companyID, or nil if none matches. Rules with a
+// port never match.
+func (b *Bundle) ResolveBLE(companyID uint16, payload []byte) *Schema {
+	for _, r := range b.Dispatch {
+		if r.Port != nil || r.CompanyID != nil && *r.CompanyID != int(companyID) {
+			continue
+		}
+		if r.matchBytes(payload) {
+			return b.Schemas[r.Schema]
+		}
+	}
+	return nil
+}
+
+// DecodeBLE decodes the first Manufacturer Specific Data structure of adv
+// that a dispatch rule matches.
+func (b *Bundle) DecodeBLE(adv []byte) (map[string]any, error) {
+	ads, err := ParseAdvertisement(adv)
+	if err != nil {
+		return nil, err
+	}
+	var seen []string
+	for _, ad := range ads {
+		companyID, payload, ok := ad.ManufacturerData()
+		if !ok {
+			continue
+		}
+		if s := b.ResolveBLE(companyID, payload); s != nil {
+			return s.Decode(payload)
+		}
+		seen = append(seen, fmt.Sprintf("0x%04X", companyID))
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("advertisement has no manufacturer data")
+	}
+	return nil, fmt.Errorf("no dispatch rule matches company ID %v", seen)
+}
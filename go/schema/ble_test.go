@@ -0,0 +1,78 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+const bleBundle = `
+schemas:
+  - name: beacon_v5
+    fields:
+      - {name: format, type: u8}
+      - {name: temperature, type: s16, mult: 0.005}
+  - name: beacon_v3
+    fields:
+      - {name: format, type: u8}
+      - {name: humidity, type: u8, mult: 0.5}
+  - name: lorawan
+    fields:
+      - {name: battery, type: u8}
+dispatch:
+  - match: {company_id: 0x0499, byte0: 0x05}
+    schema: beacon_v5
+  - match: {company_id: 0x0499}
+    schema: beacon_v3
+  - match: {port: 1}
+    schema: lorawan
+`
+
+func TestDecodeBLE(t *testing.T) {
+	b, err := ParseBundle(bleBundle)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+
+	// Flags, then manufacturer data for company 0x0499, then padding
+	adv := []byte{0x02, 0x01, 0x06, 0x06, 0xFF, 0x99, 0x04, 0x05, 0x12, 0xFC, 0x00, 0x00}
+	result, err := b.DecodeBLE(adv)
+	if err != nil {
+		t.Fatalf("DecodeBLE() error = %v", err)
+	}
+	if result["format"] != 5.0 || result["temperature"] != 24.3 {
+		t.Errorf("DecodeBLE() = %v", result)
+	}
+
+	result, err = b.DecodeBLE([]byte{0x05, 0xFF, 0x99, 0x04, 0x03, 0x50})
+	if err != nil || result["humidity"] != 40.0 {
+		t.Errorf("DecodeBLE() format 3 = %v, %v", result, err)
+	}
+
+	// Company rules never match LoRaWAN uplinks, nor port rules beacons
+	if s := b.Resolve(1, []byte{0x05}); s == nil || s.Name != "lorawan" {
+		t.Errorf("Resolve() = %v, want lorawan", s)
+	}
+	if _, err := b.DecodeBLE([]byte{0x04, 0xFF, 0x4C, 0x00, 0x02}); err == nil || !strings.Contains(err.Error(), "0x004C") {
+		t.Errorf("DecodeBLE() unknown company error = %v", err)
+	}
+	if _, err := b.DecodeBLE([]byte{0x02, 0x01, 0x06}); err == nil || !strings.Contains(err.Error(), "no manufacturer data") {
+		t.Errorf("DecodeBLE() without manufacturer data error = %v", err)
+	}
+}
+
+func TestParseAdvertisement(t *testing.T) {
+	ads, err := ParseAdvertisement([]byte{0x02, 0x01, 0x06, 0x03, 0x09, 'h', 'i'})
+	if err != nil {
+		t.Fatalf("ParseAdvertisement() error = %v", err)
+	}
+	if len(ads) != 2 || ads[0].Type != 0x01 || string(ads[1].Data) != "hi" {
+		t.Errorf("ParseAdvertisement() = %+v", ads)
+	}
+	if _, err := ParseAdvertisement([]byte{0x05, 0xFF, 0x99}); err == nil {
+		t.Error("ParseAdvertisement() of truncated structure: want error")
+	}
+}
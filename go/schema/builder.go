@@ -0,0 +1,255 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import "fmt"
+
+// Builder constructs a Schema in Go, for generating schemas from device
+// registries rather than YAML. Field methods append a field; modifier
+// methods apply to the most recently added field.
+//
+//	s, err := schema.New("env_sensor").
+//		U16("temperature").Add(-400).Mult(0.1).
+//		U8("humidity").Div(2).
+//		Build()
+//
+// Modifiers apply in call order, the same as YAML key order, so a built
+// schema decodes and encodes exactly like the equivalent parsed one.
+type Builder struct {
+	schema *Schema
+	port   *PortDef // Destination of new fields; nil for top-level fields
+	header bool     // New fields go to the header of the schema or port
+	err    error
+}
+
+// New starts a schema with the given name and big-endian byte order.
+func New(name string) *Builder {
+	return &Builder{schema: &Schema{Name: name, Endian: "big"}}
+}
+
+// Version sets the schema version.
+func (b *Builder) Version(v int) *Builder {
+	b.schema.Version = v
+	return b
+}
+
+// Endian sets the schema's default byte order ("big" or "little").
+func (b *Builder) Endian(endian string) *Builder {
+	b.schema.Endian = endian
+	return b
+}
+
+// Strict makes Build fail on any schema warning.
+func (b *Builder) Strict() *Builder {
+	b.schema.Strict = true
+	return b
+}
+
+// Port directs subsequent fields to a port-specific field list.
+func (b *Builder) Port(port int, direction string) *Builder {
+	if b.schema.Ports == nil {
+		b.schema.Ports = make(map[string]*PortDef)
+	}
+	key := fmt.Sprintf("%d", port)
+	pd, ok := b.schema.Ports[key]
+	if !ok {
+		pd = &PortDef{Direction: direction}
+		b.schema.Ports[key] = pd
+	}
+	b.port = pd
+	b.header = false
+	return b
+}
+
+// Header directs subsequent fields to the header decoded and encoded
+// before the fields: the schema's, or after Port that port's own. Fields
+// or Port ends the header.
+func (b *Builder) Header() *Builder {
+	b.header = true
+	return b
+}
+
+// Fields directs subsequent fields back to the field list of the schema,
+// or of the current port, after Header.
+func (b *Builder) Fields() *Builder {
+	b.header = false
+	return b
+}
+
+// Field appends a field defined directly. Modifier methods called after it
+// apply to it as to any other field.
+func (b *Builder) Field(f Field) *Builder {
+	list := b.fields()
+	*list = append(*list, f)
+	return b
+}
+
+// fields returns the list new fields are appended to.
+func (b *Builder) fields() *[]Field {
+	switch {
+	case b.port != nil && b.header:
+		return &b.port.Header
+	case b.port != nil:
+		return &b.port.Fields
+	case b.header:
+		return &b.schema.Header
+	}
+	return &b.schema.Fields
+}
+
+func (b *Builder) typed(name string, t FieldType) *Builder {
+	return b.Field(Field{Name: name, Type: t})
+}
+
+// U8 appends an unsigned 8-bit field.
+func (b *Builder) U8(name string) *Builder { return b.typed(name, TypeU8) }
+
+// U16 appends an unsigned 16-bit field.
+func (b *Builder) U16(name string) *Builder { return b.typed(name, TypeU16) }
+
+// U24 appends an unsigned 24-bit field.
+func (b *Builder) U24(name string) *Builder { return b.typed(name, TypeU24) }
+
+// U32 appends an unsigned 32-bit field.
+func (b *Builder) U32(name string) *Builder { return b.typed(name, TypeU32) }
+
+// U64 appends an unsigned 64-bit field.
+func (b *Builder) U64(name string) *Builder { return b.typed(name, TypeU64) }
+
+// S8 appends a signed 8-bit field.
+func (b *Builder) S8(name string) *Builder { return b.typed(name, TypeS8) }
+
+// S16 appends a signed 16-bit field.
+func (b *Builder) S16(name string) *Builder { return b.typed(name, TypeS16) }
+
+// S24 appends a signed 24-bit field.
+func (b *Builder) S24(name string) *Builder { return b.typed(name, TypeS24) }
+
+// S32 appends a signed 32-bit field.
+func (b *Builder) S32(name string) *Builder { return b.typed(name, TypeS32) }
+
+// S64 appends a signed 64-bit field.
+func (b *Builder) S64(name string) *Builder { return b.typed(name, TypeS64) }
+
+// F32 appends a 32-bit float field.
+func (b *Builder) F32(name string) *Builder { return b.typed(name, TypeF32) }
+
+// F64 appends a 64-bit float field.
+func (b *Builder) F64(name string) *Builder { return b.typed(name, TypeF64) }
+
+// Bytes appends a raw bytes field of the given length.
+func (b *Builder) Bytes(name string, length int) *Builder {
+	return b.Field(Field{Name: name, Type: TypeBytesLower, Length: length})
+}
+
+// Skip appends length bytes of padding.
+func (b *Builder) Skip(length int) *Builder {
+	return b.Field(Field{Type: TypeSkipLower, Length: length})
+}
+
+// Reserved appends length reserved bytes, checked against expect when
+// given: one byte for every position, or length bytes.
+func (b *Builder) Reserved(length int, expect ...byte) *Builder {
+	if len(expect) > 1 && len(expect) != length && b.err == nil {
+		b.err = fmt.Errorf("schema '%s': Reserved: %d expected bytes for length %d", b.schema.Name, len(expect), length)
+	}
+	return b.Field(Field{Type: TypeReservedLower, Length: length, Expect: expect})
+}
+
+// Magic appends a sync pattern the payload must carry.
+func (b *Builder) Magic(value ...byte) *Builder {
+	if len(value) == 0 && b.err == nil {
+		b.err = fmt.Errorf("schema '%s': Magic needs at least one byte", b.schema.Name)
+	}
+	return b.Field(Field{Type: TypeMagicLower, Length: len(value), Expect: value})
+}
+
+// last returns the most recently added field, recording an error if there
+// is none.
+func (b *Builder) last(method string) *Field {
+	fields := *b.fields()
+	if len(fields) == 0 {
+		if b.err == nil {
+			b.err = fmt.Errorf("schema '%s': %s called before any field", b.schema.Name, method)
+		}
+		return nil
+	}
+	return &fields[len(fields)-1]
+}
+
+func (b *Builder) modifier(method, key string, v float64) *Builder {
+	f := b.last(method)
+	if f == nil {
+		return b
+	}
+	switch key {
+	case "add":
+		f.Add = &v
+	case "mult":
+		f.Mult = &v
+	case "div":
+		f.Div = &v
+	}
+	for _, k := range f.ModOrder {
+		if k == key {
+			return b
+		}
+	}
+	f.ModOrder = append(f.ModOrder, key)
+	return b
+}
+
+// Add adds v to the last field's value.
+func (b *Builder) Add(v float64) *Builder { return b.modifier("Add", "add", v) }
+
+// Mult multiplies the last field's value by v.
+func (b *Builder) Mult(v float64) *Builder { return b.modifier("Mult", "mult", v) }
+
+// Div divides the last field's value by v.
+func (b *Builder) Div(v float64) *Builder { return b.modifier("Div", "div", v) }
+
+// Round rounds the last field's value to the given decimal places.
+func (b *Builder) Round(places int) *Builder {
+	if f := b.last("Round"); f != nil {
+		f.Round = &places
+	}
+	return b
+}
+
+// Var stores the last field's value in a variable.
+func (b *Builder) Var(name string) *Builder {
+	if f := b.last("Var"); f != nil {
+		f.Var = name
+	}
+	return b
+}
+
+// Lookup maps the last field's raw values to labels.
+func (b *Builder) Lookup(table map[int]string) *Builder {
+	if f := b.last("Lookup"); f != nil {
+		f.Lookup = table
+	}
+	return b
+}
+
+// Required makes encoding fail when the last field has no value.
+func (b *Builder) Required() *Builder {
+	if f := b.last("Required"); f != nil {
+		f.Required = true
+	}
+	return b
+}
+
+// Build returns the schema, with the same warnings (and strict-mode
+// failure) ParseSchema would produce. The builder must not be reused.
+func (b *Builder) Build() (*Schema, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := b.schema.finish(); err != nil {
+		return nil, err
+	}
+	return b.schema, nil
+}
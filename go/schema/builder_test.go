@@ -0,0 +1,115 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilderMatchesParsedSchema(t *testing.T) {
+	parsed, err := ParseSchema(`
+name: env_sensor
+fields:
+  - name: temperature
+    type: u16
+    add: -400
+    mult: 0.1
+  - name: humidity
+    type: u8
+    div: 2
+    round: 1
+  - name: status
+    type: u8
+    lookup: {0: ok, 1: fault}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	built, err := New("env_sensor").
+		U16("temperature").Add(-400).Mult(0.1).
+		U8("humidity").Div(2).Round(1).
+		U8("status").Lookup(map[int]string{0: "ok", 1: "fault"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !reflect.DeepEqual(built.Fields, parsed.Fields) {
+		t.Errorf("built fields = %+v\nparsed fields = %+v", built.Fields, parsed.Fields)
+	}
+
+	payload := []byte{0x02, 0x26, 91, 1}
+	want, _ := parsed.Decode(payload)
+	got, err := built.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("built Decode() = %v, parsed = %v", got, want)
+	}
+	encoded, err := built.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = %X, want %X", encoded, payload)
+	}
+}
+
+func TestBuilderPorts(t *testing.T) {
+	s, err := New("ported").Endian("little").
+		Port(1, "uplink").U16("counter").
+		Port(10, "downlink").U8("interval").Mult(60).Required().
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	decoded, err := s.DecodeWithPort([]byte{0x34, 0x12}, 1)
+	if err != nil {
+		t.Fatalf("DecodeWithPort() error = %v", err)
+	}
+	if decoded["counter"] != 4660.0 {
+		t.Errorf("counter = %v, want 4660", decoded["counter"])
+	}
+	if _, err := s.EncodeWithPort(map[string]any{}, 10); err == nil {
+		t.Error("EncodeWithPort() should fail for a missing required field")
+	}
+}
+
+func TestBuilderHeader(t *testing.T) {
+	s, err := New("headed").
+		Header().U8("version").
+		Port(1, "uplink").U16("counter").
+		Port(2, "uplink").Header().U8("kind").Var("kind").Fields().U8("status").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if len(s.Header) != 1 || len(s.Ports["1"].Fields) != 1 {
+		t.Fatalf("Header = %v, port 1 = %v", s.Header, s.Ports["1"].Fields)
+	}
+	got, err := s.DecodeWithPort([]byte{0x04, 0x01}, 2)
+	if err != nil {
+		t.Fatalf("DecodeWithPort() error = %v", err)
+	}
+	if got["kind"] != 4.0 || got["status"] != 1.0 || got["version"] != nil {
+		t.Errorf("DecodeWithPort() = %v", got)
+	}
+	pd := s.Ports["2"]
+	if len(pd.Header) != 1 || pd.Header[0].Var != "kind" || len(pd.Fields) != 1 {
+		t.Errorf("port 2 header = %v, fields = %v", pd.Header, pd.Fields)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	if _, err := New("x").Mult(2).U8("a").Build(); err == nil || !strings.Contains(err.Error(), "Mult called before any field") {
+		t.Errorf("Build() error = %v, want modifier-before-field error", err)
+	}
+	if _, err := New("x").Strict().U8("a").U8("a").Build(); err == nil {
+		t.Error("strict Build() should fail on duplicate output names")
+	}
+}
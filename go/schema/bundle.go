@@ -0,0 +1,204 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle is a set of related schemas shipped together, e.g. the uplink,
+// downlink and configuration schemas of one device family.
+type Bundle struct {
+	Schemas  map[string]*Schema // Keyed by schema name
+	Names    []string           // Names in document order
+	Dispatch []DispatchRule     // Per-message schema selection, see Resolve
+}
+
+// Get returns the named schema, or nil.
+func (b *Bundle) Get(name string) *Schema {
+	return b.Schemas[name]
+}
+
+// ParseBundle parses a bundle of schemas from either a multi-document YAML
+// stream (documents separated by ---) or a single document with a
+// `schemas:` list. A plain single schema yields a one-entry bundle.
+//
+// Members may use `extends:` to inherit from another member by name. A
+// `dispatch:` list, beside `schemas:` or as a document of its own, selects
+// members per message (see DispatchRule).
+func ParseBundle(data string) (*Bundle, error) {
+	return ParseBundleWithLoader(data, nil)
+}
+
+// ParseBundleWithLoader is ParseBundle with `extends:` references outside
+// the bundle resolved by load.
+func ParseBundleWithLoader(data string, load SchemaLoader) (*Bundle, error) {
+	docs, dispatch, err := splitBundle(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index members by name so they can extend each other
+	sources := make(map[string]string, len(docs))
+	names := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		var head struct {
+			Name string `yaml:"name"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil {
+			return nil, fmt.Errorf("bundle document %d: %w", i+1, err)
+		}
+		if head.Name == "" {
+			return nil, fmt.Errorf("bundle document %d has no name", i+1)
+		}
+		if _, dup := sources[head.Name]; dup {
+			return nil, fmt.Errorf("bundle has more than one schema named %q", head.Name)
+		}
+		sources[head.Name] = doc
+		names = append(names, head.Name)
+	}
+
+	bundleLoad := func(ref string) (string, error) {
+		if src, ok := sources[ref]; ok {
+			return src, nil
+		}
+		if load == nil {
+			return "", fmt.Errorf("schema %q is not in the bundle", ref)
+		}
+		return load(ref)
+	}
+
+	b := &Bundle{Schemas: make(map[string]*Schema, len(names)), Names: names}
+	for _, name := range names {
+		s, err := ParseSchemaWithLoader(sources[name], bundleLoad)
+		if err != nil {
+			return nil, fmt.Errorf("schema %q: %w", name, err)
+		}
+		b.Schemas[name] = s
+	}
+	for _, node := range dispatch {
+		rules, err := parseDispatch(node, b.Schemas)
+		if err != nil {
+			return nil, err
+		}
+		b.Dispatch = append(b.Dispatch, rules...)
+	}
+	return b, nil
+}
+
+// ParseBundleFile parses a bundle file. JSON files (by extension, or
+// content starting with { or [) are validated as JSON first so syntax
+// errors are reported in JSON terms. extends: references outside the
+// bundle resolve relative to the file's directory.
+func ParseBundleFile(path string) (*Bundle, error) {
+	data, err := readSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBundleWithLoader(data, FileSchemaLoader(filepath.Dir(path)))
+}
+
+// readSchemaFile reads a schema file, checking JSON syntax for JSON files.
+func readSchemaFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if isJSONSchemaFile(path, raw) {
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return "", fmt.Errorf("%s: invalid JSON: %w", path, err)
+		}
+	}
+	return string(raw), nil
+}
+
+func isJSONSchemaFile(path string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return true
+	case ".yaml", ".yml":
+		return false
+	}
+	trimmed := strings.TrimSpace(string(data))
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+}
+
+// splitBundle returns the source of each schema in a bundle and the
+// `dispatch:` lists found beside them.
+func splitBundle(data string) ([]string, []*yaml.Node, error) {
+	var docs []string
+	var dispatch []*yaml.Node
+	add := func(n *yaml.Node) error {
+		out, err := yaml.Marshal(n)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, string(out))
+		return nil
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse bundle: %w", err)
+		}
+		root := &doc
+		if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+			root = root.Content[0]
+		}
+
+		if root.Kind == yaml.MappingNode && mappingValue(root, "name") == nil {
+			if d := mappingValue(root, "dispatch"); d != nil {
+				dispatch = append(dispatch, d)
+				if mappingValue(root, "schemas") == nil {
+					continue // Dispatch-only document
+				}
+			}
+		}
+
+		var members []*yaml.Node
+		switch {
+		case root.Kind == yaml.SequenceNode:
+			// JSON array of schemas
+			members = root.Content
+		case root.Kind == yaml.MappingNode && mappingValue(root, "schemas") != nil:
+			list := mappingValue(root, "schemas")
+			if list.Kind != yaml.SequenceNode {
+				return nil, nil, fmt.Errorf("schemas: must be a list")
+			}
+			members = list.Content
+		case root.Kind == yaml.MappingNode:
+			members = []*yaml.Node{root}
+		default:
+			continue // Empty document
+		}
+		for _, m := range members {
+			if m.Kind != yaml.MappingNode {
+				return nil, nil, fmt.Errorf("bundle member must be a mapping")
+			}
+			if err := add(m); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if len(docs) == 0 {
+		return nil, nil, fmt.Errorf("bundle contains no schemas")
+	}
+	return docs, dispatch, nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const deviceFamilyBundle = `
+name: sensor_uplink
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+---
+name: sensor_downlink
+fields:
+  - name: interval
+    type: u16
+---
+extends: sensor_uplink
+name: sensor_uplink_v2
+fields:
+  - name: humidity
+    type: u8
+`
+
+func TestParseBundleMultiDocument(t *testing.T) {
+	b, err := ParseBundle(deviceFamilyBundle)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	want := []string{"sensor_uplink", "sensor_downlink", "sensor_uplink_v2"}
+	if strings.Join(b.Names, ",") != strings.Join(want, ",") {
+		t.Fatalf("Names = %v, want %v", b.Names, want)
+	}
+	if got := len(b.Get("sensor_uplink_v2").Fields); got != 2 {
+		t.Errorf("extended member has %d fields, want 2", got)
+	}
+	decoded, err := b.Get("sensor_uplink").Decode([]byte{0x00, 0xFA})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded["temperature"] != 25.0 {
+		t.Errorf("temperature = %v, want 25", decoded["temperature"])
+	}
+	if b.Get("missing") != nil {
+		t.Error("Get() of unknown name should be nil")
+	}
+}
+
+func TestParseBundleSchemasList(t *testing.T) {
+	yamlList := `
+schemas:
+  - name: up
+    fields: [{name: a, type: u8}]
+  - name: down
+    fields: [{name: b, type: u8}]
+`
+	jsonList := `[{"name": "up", "fields": [{"name": "a", "type": "u8"}]},
+ {"name": "down", "fields": [{"name": "b", "type": "u8"}]}]`
+
+	for name, src := range map[string]string{"yaml": yamlList, "json": jsonList} {
+		t.Run(name, func(t *testing.T) {
+			b, err := ParseBundle(src)
+			if err != nil {
+				t.Fatalf("ParseBundle() error = %v", err)
+			}
+			if len(b.Schemas) != 2 || b.Get("up") == nil || b.Get("down") == nil {
+				t.Errorf("Schemas = %v, want up and down", b.Names)
+			}
+		})
+	}
+}
+
+func TestParseBundleErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"empty", "", "no schemas"},
+		{"no name", "fields: []\n", "no name"},
+		{"duplicate", "name: a\n---\nname: a\n", "more than one schema"},
+		{"bad list", "schemas: up\n", "must be a list"},
+		{"missing extends", "name: a\nextends: nowhere\n", "not in the bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBundle(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseBundle() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBundleFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	family := write("family.yaml", deviceFamilyBundle)
+	b, err := ParseBundleFile(family)
+	if err != nil {
+		t.Fatalf("ParseBundleFile() error = %v", err)
+	}
+	if len(b.Schemas) != 3 {
+		t.Errorf("got %d schemas, want 3", len(b.Schemas))
+	}
+	if _, err := ParseSchemaFile(family); err == nil || !strings.Contains(err.Error(), "ParseBundleFile") {
+		t.Errorf("ParseSchemaFile() of a bundle error = %v, want hint to use ParseBundleFile", err)
+	}
+
+	single := write("single.json", `{"name": "j", "fields": [{"name": "a", "type": "u8"}]}`)
+	s, err := ParseSchemaFile(single)
+	if err != nil || s.Name != "j" {
+		t.Errorf("ParseSchemaFile(json) = %v, %v", s, err)
+	}
+
+	broken := write("broken.json", `{"name": "j", "fields": [}`)
+	if _, err := ParseBundleFile(broken); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("ParseBundleFile(broken json) error = %v, want invalid JSON", err)
+	}
+}
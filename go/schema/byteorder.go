@@ -0,0 +1,152 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Byte orders for legacy devices that scramble multi-byte values inside an
+// otherwise big- or little-endian frame. The reorder is applied to the raw
+// bytes before integer interpretation (and undone after encoding).
+const (
+	ByteOrderSwap16 = "swap16" // swap the bytes of each 16-bit word: 0x1234 sent as 34 12
+	ByteOrderSwap32 = "swap32" // reverse the bytes of each 32-bit word
+	ByteOrderNibble = "nibble" // swap the nibbles of each byte (nibble-swapped BCD)
+	ByteOrderCustom = "custom" // explicit permutation, e.g. [2, 3, 0, 1]
+)
+
+// parseByteOrder reads byte_order: as a name, a permutation list, or the
+// string form custom([2,3,0,1]).
+func parseByteOrder(raw any, f *Field) {
+	switch v := raw.(type) {
+	case []any:
+		f.ByteOrder = ByteOrderCustom
+		for _, item := range v {
+			idx, ok := toWholeInt(item)
+			if !ok {
+				f.invalid = append(f.invalid, fmt.Sprintf("byte_order: invalid index %v", item))
+				f.ByteOrder, f.ByteOrderMap = "", nil
+				return
+			}
+			f.ByteOrderMap = append(f.ByteOrderMap, idx)
+		}
+	case string:
+		s := strings.TrimSpace(v)
+		if strings.HasPrefix(s, "custom(") && strings.HasSuffix(s, ")") {
+			list := strings.Trim(s[len("custom("):len(s)-1], "[] ")
+			f.ByteOrder = ByteOrderCustom
+			for _, part := range strings.Split(list, ",") {
+				idx, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					f.invalid = append(f.invalid, fmt.Sprintf("byte_order: invalid index %q", part))
+					f.ByteOrder, f.ByteOrderMap = "", nil
+					return
+				}
+				f.ByteOrderMap = append(f.ByteOrderMap, idx)
+			}
+			break
+		}
+		switch s {
+		case ByteOrderSwap16, ByteOrderSwap32, ByteOrderNibble:
+			f.ByteOrder = s
+		default:
+			f.invalid = append(f.invalid, fmt.Sprintf("byte_order: unknown order %q", s))
+			return
+		}
+	default:
+		f.invalid = append(f.invalid, fmt.Sprintf("byte_order: unsupported value %v", raw))
+		return
+	}
+
+	if f.ByteOrder == ByteOrderCustom {
+		seen := make([]bool, len(f.ByteOrderMap))
+		for _, idx := range f.ByteOrderMap {
+			if idx < 0 || idx >= len(seen) || seen[idx] {
+				f.invalid = append(f.invalid, fmt.Sprintf("byte_order: %v is not a permutation", f.ByteOrderMap))
+				f.ByteOrder, f.ByteOrderMap = "", nil
+				return
+			}
+			seen[idx] = true
+		}
+	}
+}
+
+// byteOrderPerm returns the permutation for n bytes: wire byte perm[i]
+// becomes byte i of the value.
+func byteOrderPerm(field Field, n int) ([]int, error) {
+	word := 0
+	switch field.ByteOrder {
+	case ByteOrderSwap16:
+		word = 2
+	case ByteOrderSwap32:
+		word = 4
+	case ByteOrderCustom:
+		if len(field.ByteOrderMap) != n {
+			return nil, fmt.Errorf("%s: byte_order has %d entries for a %d-byte value",
+				field.Name, len(field.ByteOrderMap), n)
+		}
+		return field.ByteOrderMap, nil
+	default:
+		return nil, nil
+	}
+	if n%word != 0 {
+		return nil, fmt.Errorf("%s: byte_order %s needs a multiple of %d bytes, got %d",
+			field.Name, field.ByteOrder, word, n)
+	}
+	perm := make([]int, n)
+	for i := range perm {
+		base := i - i%word
+		perm[i] = base + word - 1 - i%word
+	}
+	return perm, nil
+}
+
+// unscrambleBytes converts wire bytes to value order for decoding.
+func unscrambleBytes(field Field, data []byte) ([]byte, error) {
+	if field.ByteOrder == "" {
+		return data, nil
+	}
+	out := make([]byte, len(data))
+	if field.ByteOrder == ByteOrderNibble {
+		for i, b := range data {
+			out[i] = b<<4 | b>>4
+		}
+		return out, nil
+	}
+	perm, err := byteOrderPerm(field, len(data))
+	if err != nil {
+		return nil, err
+	}
+	for i, src := range perm {
+		out[i] = data[src]
+	}
+	return out, nil
+}
+
+// scrambleBytes converts value bytes to wire order in place for encoding.
+func scrambleBytes(field Field, data []byte) error {
+	if field.ByteOrder == "" {
+		return nil
+	}
+	if field.ByteOrder == ByteOrderNibble {
+		for i, b := range data {
+			data[i] = b<<4 | b>>4
+		}
+		return nil
+	}
+	perm, err := byteOrderPerm(field, len(data))
+	if err != nil {
+		return err
+	}
+	wire := make([]byte, len(data))
+	for i, dst := range perm {
+		wire[dst] = data[i]
+	}
+	copy(data, wire)
+	return nil
+}
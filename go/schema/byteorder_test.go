@@ -0,0 +1,105 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestByteOrderRoundtrip(t *testing.T) {
+	schema, err := ParseSchema(`
+name: legacy
+endian: big
+fields:
+  - name: word
+    type: u16
+    byte_order: swap16
+  - name: counter
+    type: u32
+    byte_order: swap16
+  - name: energy
+    type: u32
+    byte_order: [2, 3, 0, 1]
+  - name: reversed
+    type: u32
+    byte_order: swap32
+  - name: custom
+    type: s16
+    byte_order: "custom([1,0])"
+  - name: bcd
+    type: u8
+    byte_order: nibble
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(schema.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", schema.Warnings)
+	}
+
+	payload := []byte{
+		0x34, 0x12, // word = 0x1234
+		0x34, 0x12, 0x78, 0x56, // counter = 0x12345678
+		0x56, 0x78, 0x12, 0x34, // energy = 0x12345678 (word-swapped)
+		0x78, 0x56, 0x34, 0x12, // reversed = 0x12345678
+		0xFF, 0xFE, // custom = 0xFEFF = -257
+		0x21, // bcd = 0x12
+	}
+	decoded, err := schema.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]float64{
+		"word": 0x1234, "counter": 0x12345678, "energy": 0x12345678,
+		"reversed": 0x12345678, "custom": -257, "bcd": 0x12,
+	}
+	for k, v := range want {
+		if decoded[k] != v {
+			t.Errorf("%s = %v, want %v", k, decoded[k], v)
+		}
+	}
+
+	encoded, err := schema.Encode(decoded)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = %X, want %X", encoded, payload)
+	}
+}
+
+func TestByteOrderErrors(t *testing.T) {
+	for _, order := range []string{"swap64", "[0, 0]", "[1, 2]", `"custom(a,b)"`} {
+		schema, err := ParseSchema("name: bad\nfields:\n  - name: v\n    type: u16\n    byte_order: " + order + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema(%s) error = %v", order, err)
+		}
+		if len(schema.Warnings) != 1 {
+			t.Errorf("byte_order %s: Warnings = %v, want one", order, schema.Warnings)
+		}
+	}
+
+	// Permutation length must match the value size
+	schema, err := ParseSchema(`
+name: mismatch
+fields:
+  - name: v
+    type: u16
+    byte_order: [3, 2, 1, 0]
+  - name: w
+    type: u16
+    byte_order: swap32
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := schema.Decode([]byte{1, 2, 3, 4}); err == nil {
+		t.Error("Decode() should fail for a 4-entry byte_order on a u16")
+	}
+	if _, err := schema.Encode(map[string]any{"w": 1.0}); err == nil {
+		t.Error("Encode() should fail for swap32 on a u16")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Capability names a schema language feature a runtime must implement to
+// run a schema. Hosting platforms compare a schema's capabilities against
+// what their build supports before deploying it.
+type Capability string
+
+// Capabilities reported by Schema.Capabilities.
+const (
+	CapPorts          Capability = "ports"            // ports: routing by fPort
+	CapPortFallback   Capability = "port_fallback"    // fallback: port chains
+	CapHeader         Capability = "header"           // Schema-level header:
+	CapRevisions      Capability = "revisions"        // revisions: by version field
+	CapFrames         Capability = "frames"           // frames: concatenated structures
+	CapDefinitions    Capability = "definitions"      // $ref to definitions:
+	CapMatch          Capability = "match"            // match on a field or selector
+	CapFlagged        Capability = "flagged"          // flagged: bitmask presence
+	CapTLV            Capability = "tlv"              // Type-length-value sections
+	CapRepeat         Capability = "repeat"           // Arrays with count or byte_length
+	CapRepeatUntilEnd Capability = "repeat_until_end" // Arrays until the payload ends
+	CapSeries         Capability = "series"           // Interval logs
+	CapObject         Capability = "object"           // Nested objects
+	CapVector3        Capability = "vector3"          // x/y/z triplets
+	CapByteGroup      Capability = "byte_group"       // Several values from shared bytes
+	CapBits           Capability = "bits"             // Bitfields
+	CapByteOrder      Capability = "byte_order"       // Swapped byte orders
+	CapFloat          Capability = "float"            // f16/f32/f64
+	CapFixedPoint     Capability = "fixed_point"      // fixed/ufixed Qm.n
+	CapText           Capability = "text"             // ascii/string fields
+	CapRestOfPayload  Capability = "rest_of_payload"  // Bytes or text to the end
+	CapEnum           Capability = "enum"             // enum: values
+	CapLookup         Capability = "lookup"           // lookup: tables
+	CapVariables      Capability = "variables"        // var: and scope:
+	CapFormula        Capability = "formula"          // formula: expressions
+	CapCompute        Capability = "compute"          // compute: binary operations
+	CapPolynomial     Capability = "polynomial"       // polynomial: calibration
+	CapCurve          Capability = "curve"            // curve: interpolation tables
+	CapGuard          Capability = "guard"            // guard: conditions
+	CapScaleBy        Capability = "scale_by"         // Scaling by an exponent field
+	CapAlsoEmit       Capability = "also_emit"        // Derived output keys
+	CapWASM           Capability = "wasm"             // WASM-hosted decoders
+)
+
+// Capabilities returns the features the schema uses, sorted, walking the
+// header, fields, ports, revisions, frames and definitions.
+func (s *Schema) Capabilities() []Capability {
+	used := map[Capability]bool{}
+	if len(s.Ports) > 0 {
+		used[CapPorts] = true
+	}
+	for _, pd := range s.Ports {
+		if pd.Fallback != "" {
+			used[CapPortFallback] = true
+		}
+		if len(pd.Header) > 0 {
+			used[CapHeader] = true
+		}
+	}
+	if len(s.Header) > 0 {
+		used[CapHeader] = true
+	}
+	if s.Revisions != nil {
+		used[CapRevisions] = true
+		used[CapMatch] = true
+	}
+	if s.Frames != nil {
+		used[CapFrames] = true
+		used[CapMatch] = true
+	}
+	s.walkAllFields(func(f Field, _ string) {
+		fieldCapabilities(f, used)
+	})
+
+	caps := make([]Capability, 0, len(used))
+	for c := range used {
+		caps = append(caps, c)
+	}
+	sort.Slice(caps, func(i, j int) bool { return caps[i] < caps[j] })
+	return caps
+}
+
+// Unsupported returns the capabilities the schema uses that are not in
+// supported, sorted.
+func (s *Schema) Unsupported(supported ...Capability) []Capability {
+	var missing []Capability
+	for _, c := range s.Capabilities() {
+		if !slices.Contains(supported, c) {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// CheckCapabilities fails when the schema uses a capability not in
+// supported, naming every one missing.
+func (s *Schema) CheckCapabilities(supported ...Capability) error {
+	missing := s.Unsupported(supported...)
+	if len(missing) == 0 {
+		return nil
+	}
+	names := make([]string, len(missing))
+	for i, c := range missing {
+		names[i] = string(c)
+	}
+	return fmt.Errorf("schema %s uses unsupported features: %s", s.Name, strings.Join(names, ", "))
+}
+
+// fieldCapabilities records the features one field uses; nested fields are
+// visited by the caller.
+func fieldCapabilities(f Field, used map[Capability]bool) {
+	mark := func(c Capability, cond bool) {
+		if cond {
+			used[c] = true
+		}
+	}
+	mark(CapDefinitions, f.Ref2 != "")
+	mark(CapFlagged, f.Flagged != nil)
+	mark(CapByteGroup, len(f.ByteGroup) > 0)
+	mark(CapMatch, f.MatchInline != nil)
+	mark(CapTLV, f.TLVInline != nil)
+	mark(CapWASM, f.WASM != nil)
+	mark(CapVariables, f.Var != "")
+	mark(CapFormula, f.Formula != "" || f.On != "" && !bareVarPattern.MatchString(f.On))
+	mark(CapCompute, f.Compute != nil)
+	mark(CapPolynomial, len(f.Polynomial) > 0)
+	mark(CapCurve, f.Curve != nil)
+	mark(CapGuard, f.Guard != nil)
+	mark(CapScaleBy, f.ScaleBy != "")
+	mark(CapByteOrder, f.ByteOrder != "")
+	mark(CapLookup, f.Lookup != nil || f.LookupArray != nil)
+	mark(CapAlsoEmit, len(f.AlsoEmit) > 0)
+	mark(CapRestOfPayload, readsRest(f))
+
+	switch f.Type {
+	case TypeMatch, TypeMatchLower, "CTRL-SWITCH", "Switch":
+		used[CapMatch] = true
+	case TypeTLV, TypeTLVLower:
+		used[CapTLV] = true
+	case TypeRepeat, TypeRepeatLower:
+		if f.Count == nil && f.ByteLength == nil && f.Until == "end" {
+			used[CapRepeatUntilEnd] = true
+		} else {
+			used[CapRepeat] = true
+		}
+	case TypeSeries:
+		used[CapSeries] = true
+	case TypeObject, TypeObjectLower:
+		used[CapObject] = true
+	case TypeVector3:
+		used[CapVector3] = true
+	case TypeBits, TypeBitsLower:
+		used[CapBits] = true
+	case TypeFloat16, TypeFloat32, TypeFloat64, TypeF16, TypeF32, TypeF64:
+		used[CapFloat] = true
+	case TypeFixed, TypeUFixed:
+		used[CapFixedPoint] = true
+	case TypeAscii, TypeAsciiLower, TypeString, TypeStringLower:
+		used[CapText] = true
+	case TypeEnum, TypeEnumLower:
+		used[CapEnum] = true
+	}
+	if f.BitOffset != 0 || f.Bits != 0 {
+		used[CapBits] = true
+	}
+}
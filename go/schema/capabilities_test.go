@@ -0,0 +1,105 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	s, err := ParseSchema(`
+name: sensor
+definitions:
+  reading:
+    fields:
+      - name: value
+        type: s16
+        polynomial: [0.5, 0]
+ports:
+  "1":
+    fields:
+      - name: flags
+        type: u8
+        var: flags
+      - flagged:
+          field: flags
+          groups:
+            - bit: 0
+              fields:
+                - $ref: "#/definitions/reading"
+  "2":
+    fallback: "1"
+    fields:
+      - name: log
+        type: repeat
+        until: end
+        fields:
+          - name: temp
+            type: f32
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	got := s.Capabilities()
+	want := []Capability{CapDefinitions, CapFlagged, CapFloat, CapPolynomial,
+		CapPortFallback, CapPorts, CapRepeatUntilEnd, CapVariables}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Capabilities() = %v, want %v", got, want)
+	}
+
+	missing := s.Unsupported(CapPorts, CapPortFallback, CapDefinitions, CapFlagged, CapFloat, CapVariables)
+	if !reflect.DeepEqual(missing, []Capability{CapPolynomial, CapRepeatUntilEnd}) {
+		t.Errorf("Unsupported() = %v", missing)
+	}
+	err = s.CheckCapabilities(CapPorts)
+	if err == nil || !strings.Contains(err.Error(), "flagged, float, polynomial") {
+		t.Errorf("CheckCapabilities() error = %v", err)
+	}
+	if err := s.CheckCapabilities(got...); err != nil {
+		t.Errorf("CheckCapabilities(all) error = %v", err)
+	}
+}
+
+func TestCapabilitiesTLVAndFrames(t *testing.T) {
+	s, err := ParseSchema(`
+name: gateway
+frames:
+  header:
+    - {name: kind, type: u8}
+  on: $kind
+  cases:
+    - case: 1
+      fields:
+        - tlv:
+            tag_size: 1
+            length_size: 1
+            cases:
+              "0x01":
+                - {name: temperature, type: s16, formula: "x / 10"}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	got := s.Capabilities()
+	for _, c := range []Capability{CapFrames, CapMatch, CapTLV, CapFormula} {
+		if !containsCapability(got, c) {
+			t.Errorf("Capabilities() = %v, missing %s", got, c)
+		}
+	}
+	if containsCapability(got, CapWASM) {
+		t.Errorf("Capabilities() = %v, has wasm", got)
+	}
+}
+
+func containsCapability(caps []Capability, c Capability) bool {
+	for _, have := range caps {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
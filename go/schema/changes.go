@@ -0,0 +1,152 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeTracker reduces write amplification for chatty sensors: it
+// remembers the last reported result of each device and passes on only
+// the fields that changed. Numeric fields may have a deadband, so a
+// temperature with deadband 0.2 is reported when it moves more than 0.2
+// from the value last reported, and slow drift is still reported once it
+// adds up. A minimum interval holds back further changes of a field until
+// that long after its last report. A ChangeTracker is safe for concurrent
+// use.
+type ChangeTracker struct {
+	// Deadband and MinInterval by flattened key. A key without array
+	// indices (readings.temp) covers every element (readings.3.temp).
+	Deadband    map[string]float64
+	MinInterval map[string]time.Duration
+
+	mu   sync.Mutex
+	last map[string]map[string]reported // Device -> flattened key -> last report
+}
+
+// reported is the last value passed on for a key, and when.
+type reported struct {
+	value any
+	at    time.Time
+}
+
+// NewChangeTracker returns a tracker with the given deadbands.
+func NewChangeTracker(deadband map[string]float64) *ChangeTracker {
+	return &ChangeTracker{Deadband: deadband}
+}
+
+// ChangeTracker returns a tracker whose deadbands and minimum intervals
+// come from the deadband: and report_min_interval: keys of the fields
+// decoded on fPort:
+//
+//	fields:
+//	  - name: temperature
+//	    type: s16
+//	    div: 10
+//	    deadband: 0.5
+//	    report_min_interval: 300   # Seconds
+//
+// The maps may be amended before use, e.g. with per-customer overrides.
+func (s *Schema) ChangeTracker(fPort int) (*ChangeTracker, error) {
+	t := &ChangeTracker{Deadband: map[string]float64{}, MinInterval: map[string]time.Duration{}}
+	err := s.walkOutputPaths(fPort, func(f Field, path string) {
+		if f.Deadband != nil {
+			t.Deadband[path] = *f.Deadband
+		}
+		if f.ReportMinInterval != nil {
+			t.MinInterval[path] = time.Duration(*f.ReportMinInterval * float64(time.Second))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Changes returns the fields of result that changed since the last result
+// reported for device, flattened as by Flatten. The first result of a
+// device is reported in full. Metadata keys (_quality, _raw) are not
+// tracked or returned, and fields absent from result are left as they
+// were. An empty map means nothing changed.
+func (t *ChangeTracker) Changes(device string, result map[string]any) map[string]any {
+	return t.ChangesAt(device, result, time.Now())
+}
+
+// ChangesAt is Changes for a result received at now, e.g. the uplink's
+// gateway time when replaying stored traffic.
+func (t *ChangeTracker) ChangesAt(device string, result map[string]any, now time.Time) map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil {
+		t.last = map[string]map[string]reported{}
+	}
+	prev := t.last[device]
+	if prev == nil {
+		prev = map[string]reported{}
+		t.last[device] = prev
+	}
+
+	changed := map[string]any{}
+	for key, v := range Flatten(result) {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		old, seen := prev[key]
+		if seen && (!t.differs(key, old.value, v) || now.Sub(old.at) < t.minInterval(key)) {
+			continue
+		}
+		prev[key] = reported{value: v, at: now}
+		changed[key] = v
+	}
+	return changed
+}
+
+// Forget drops the state of device, so its next result is reported in
+// full.
+func (t *ChangeTracker) Forget(device string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.last, device)
+}
+
+// differs reports whether v is a change from the last reported old.
+func (t *ChangeTracker) differs(key string, old, v any) bool {
+	a, okA := toFloat64(old)
+	b, okB := toFloat64(v)
+	if !okA || !okB {
+		return !reflect.DeepEqual(old, v)
+	}
+	if a == b {
+		return false
+	}
+	band, ok := t.Deadband[key]
+	if !ok {
+		band = t.Deadband[stripIndices(key)]
+	}
+	return b-a > band || a-b > band
+}
+
+func (t *ChangeTracker) minInterval(key string) time.Duration {
+	if d, ok := t.MinInterval[key]; ok {
+		return d
+	}
+	return t.MinInterval[stripIndices(key)]
+}
+
+// stripIndices removes the array positions from a flattened key.
+func stripIndices(key string) string {
+	parts := strings.Split(key, ".")
+	kept := parts[:0]
+	for _, p := range parts {
+		if _, err := strconv.Atoi(p); err != nil {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, ".")
+}
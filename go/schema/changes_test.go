@@ -0,0 +1,110 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeTracker(t *testing.T) {
+	tr := NewChangeTracker(map[string]float64{"temperature": 0.2, "zones.level": 5})
+
+	first := map[string]any{
+		"temperature": 21.0, "door": "closed",
+		"zones":    []any{map[string]any{"level": 50.0}},
+		"_quality": map[string]string{"temperature": "good"},
+	}
+	want := map[string]any{"temperature": 21.0, "door": "closed", "zones.0.level": 50.0}
+	if got := tr.Changes("dev1", first); !reflect.DeepEqual(got, want) {
+		t.Errorf("first Changes() = %v, want %v", got, want)
+	}
+
+	steps := []struct {
+		result map[string]any
+		want   map[string]any
+	}{
+		// Within the deadbands
+		{map[string]any{"temperature": 21.1, "door": "closed", "zones": []any{map[string]any{"level": 54.0}}}, map[string]any{}},
+		// Drift adds up against the last reported value
+		{map[string]any{"temperature": 21.25, "door": "closed"}, map[string]any{"temperature": 21.25}},
+		{map[string]any{"temperature": 21.25, "door": "open", "zones": []any{map[string]any{"level": 44.0}}},
+			map[string]any{"door": "open", "zones.0.level": 44.0}},
+	}
+	for i, step := range steps {
+		if got := tr.Changes("dev1", step.result); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: Changes() = %v, want %v", i, got, step.want)
+		}
+	}
+
+	// Devices are tracked separately, and Forget starts over
+	if got := tr.Changes("dev2", map[string]any{"temperature": 21.25}); len(got) != 1 {
+		t.Errorf("dev2 Changes() = %v, want full result", got)
+	}
+	tr.Forget("dev1")
+	if got := tr.Changes("dev1", map[string]any{"temperature": 21.25, "door": "open"}); len(got) != 2 {
+		t.Errorf("Changes() after Forget = %v, want full result", got)
+	}
+}
+
+func TestSchemaChangeTracker(t *testing.T) {
+	s, err := ParseSchema(`
+name: reporting
+fields:
+  - {name: temperature, type: s16, div: 10, deadband: 0.5, report_min_interval: 300}
+  - name: zones
+    type: repeat
+    count: 1
+    fields:
+      - {name: level, type: u8, deadband: 5}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	tr, err := s.ChangeTracker(0)
+	if err != nil {
+		t.Fatalf("ChangeTracker() error = %v", err)
+	}
+	if tr.Deadband["temperature"] != 0.5 || tr.Deadband["zones.level"] != 5 || tr.MinInterval["temperature"] != 300*time.Second {
+		t.Fatalf("ChangeTracker() = %+v", tr)
+	}
+
+	start := time.Unix(1700000000, 0)
+	decode := func(payload []byte) map[string]any {
+		result, err := s.Decode(payload)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		return result
+	}
+	tr.ChangesAt("dev", decode([]byte{0x00, 0xD2, 0x32}), start) // 21.0, 50
+
+	steps := []struct {
+		after   time.Duration
+		payload []byte
+		want    map[string]any
+	}{
+		// 22.0 is past the deadband but within the minimum interval
+		{time.Minute, []byte{0x00, 0xDC, 0x32}, map[string]any{}},
+		{2 * time.Minute, []byte{0x00, 0xDC, 0x3A}, map[string]any{"zones.0.level": 58.0}},
+		{5 * time.Minute, []byte{0x00, 0xDC, 0x3A}, map[string]any{"temperature": 22.0}},
+		// Reported at 5 minutes, so held until 10
+		{9 * time.Minute, []byte{0x00, 0xE6, 0x3A}, map[string]any{}},
+		{10 * time.Minute, []byte{0x00, 0xE6, 0x3A}, map[string]any{"temperature": 23.0}},
+	}
+	for i, step := range steps {
+		got := tr.ChangesAt("dev", decode(step.payload), start.Add(step.after))
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: ChangesAt() = %v, want %v", i, got, step.want)
+		}
+	}
+
+	bad, err := ParseSchema("name: x\nfields:\n  - {name: a, type: u8, deadband: -1}\n")
+	if err != nil || len(bad.Warnings) != 1 || !strings.Contains(bad.Warnings[0], "deadband") {
+		t.Errorf("negative deadband: Warnings = %v, err = %v", bad.Warnings, err)
+	}
+}
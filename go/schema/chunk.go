@@ -0,0 +1,112 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import "fmt"
+
+// Chunked downlinks split an encoded payload larger than the regional
+// downlink limit (e.g. a configuration blob) into frames that each fit
+// maxLen bytes and can be queued one after another. Every frame starts with
+// a continuation header:
+//
+//	byte 0: index of this frame, from 0
+//	byte 1: total number of frames
+//
+// followed by the next slice of the payload. A payload that fits in one
+// frame is still sent with the header (index 0, total 1), so the device
+// parses every downlink the same way.
+const (
+	ChunkHeaderSize = 2
+	MaxChunks       = 255
+)
+
+// EncodeChunked encodes values and splits the result into frames of at
+// most maxLen bytes, each starting with the continuation header.
+func (s *Schema) EncodeChunked(values map[string]any, maxLen int) ([][]byte, error) {
+	return s.EncodeChunkedWithPort(values, 0, maxLen)
+}
+
+// EncodeChunkedWithPort is EncodeChunked using port-based schema selection.
+func (s *Schema) EncodeChunkedWithPort(values map[string]any, fPort, maxLen int) ([][]byte, error) {
+	payload, err := s.EncodeWithPort(values, fPort)
+	if err != nil {
+		return nil, err
+	}
+	return SplitChunks(payload, maxLen)
+}
+
+// SplitChunks splits payload into frames of at most maxLen bytes, each
+// starting with the continuation header.
+func SplitChunks(payload []byte, maxLen int) ([][]byte, error) {
+	room := maxLen - ChunkHeaderSize
+	if room < 1 {
+		return nil, fmt.Errorf("chunk size %d leaves no room after the %d-byte header", maxLen, ChunkHeaderSize)
+	}
+	total := (len(payload) + room - 1) / room
+	if total == 0 {
+		total = 1
+	}
+	if total > MaxChunks {
+		return nil, fmt.Errorf("%d bytes need %d chunks of %d bytes, more than %d", len(payload), total, maxLen, MaxChunks)
+	}
+	frames := make([][]byte, 0, total)
+	for i := 0; i < total; i++ {
+		end := min((i+1)*room, len(payload))
+		frame := make([]byte, 0, ChunkHeaderSize+end-i*room)
+		frame = append(frame, byte(i), byte(total))
+		frames = append(frames, append(frame, payload[i*room:end]...))
+	}
+	return frames, nil
+}
+
+// ReassembleChunks joins frames produced by SplitChunks, which may arrive
+// in any order, back into the payload. Every frame of the set must be
+// present exactly once.
+func ReassembleChunks(frames [][]byte) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no chunks")
+	}
+	var total int
+	var parts [][]byte
+	for n, frame := range frames {
+		if len(frame) < ChunkHeaderSize {
+			return nil, fmt.Errorf("chunk %d: %d bytes is shorter than the header", n, len(frame))
+		}
+		index, count := int(frame[0]), int(frame[1])
+		if parts == nil {
+			if count == 0 {
+				return nil, fmt.Errorf("chunk %d: total of 0 chunks", n)
+			}
+			total, parts = count, make([][]byte, count)
+		}
+		switch {
+		case count != total:
+			return nil, fmt.Errorf("chunk %d: total %d, other chunks say %d", n, count, total)
+		case index >= total:
+			return nil, fmt.Errorf("chunk %d: index %d out of %d chunks", n, index, total)
+		case parts[index] != nil:
+			return nil, fmt.Errorf("chunk %d: index %d received twice", n, index)
+		}
+		parts[index] = frame[ChunkHeaderSize:]
+	}
+	var payload []byte
+	for i, part := range parts {
+		if part == nil {
+			return nil, fmt.Errorf("chunk %d of %d missing", i, total)
+		}
+		payload = append(payload, part...)
+	}
+	return payload, nil
+}
+
+// DecodeChunked reassembles frames with ReassembleChunks and decodes the
+// payload, configured by opts as for Decode.
+func (s *Schema) DecodeChunked(frames [][]byte, opts ...DecodeOption) (map[string]any, error) {
+	payload, err := ReassembleChunks(frames)
+	if err != nil {
+		return nil, err
+	}
+	return s.Decode(payload, opts...)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const chunkSchema = `
+name: config
+ports:
+  20:
+    direction: downlink
+    fields:
+      - {name: command, type: u8}
+      - {name: blob, type: Hex, length: 40}
+`
+
+func TestEncodeChunkedRoundTrip(t *testing.T) {
+	s, err := ParseSchema(chunkSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	values := map[string]any{"command": 7, "blob": strings.Repeat("A5", 40)}
+	frames, err := s.EncodeChunkedWithPort(values, 20, 11)
+	if err != nil {
+		t.Fatalf("EncodeChunkedWithPort() error = %v", err)
+	}
+	// 41 bytes at 9 per frame
+	if len(frames) != 5 {
+		t.Fatalf("got %d frames, want 5", len(frames))
+	}
+	for i, f := range frames {
+		if len(f) > 11 || f[0] != byte(i) || f[1] != 5 {
+			t.Errorf("frame %d = % X", i, f)
+		}
+	}
+	if want := []byte{0x00, 0x05, 0x07, 0xA5}; !bytes.Equal(frames[0][:4], want) {
+		t.Errorf("frame 0 = % X, want prefix % X", frames[0], want)
+	}
+
+	// Frames may arrive out of order
+	shuffled := [][]byte{frames[3], frames[0], frames[4], frames[2], frames[1]}
+	got, err := s.DecodeChunked(shuffled, WithPort(20))
+	if err != nil {
+		t.Fatalf("DecodeChunked() error = %v", err)
+	}
+	if got["command"] != 7.0 || !strings.EqualFold(got["blob"].(string), values["blob"].(string)) {
+		t.Errorf("DecodeChunked() = %v", got)
+	}
+}
+
+func TestSplitChunksSingleFrame(t *testing.T) {
+	frames, err := SplitChunks([]byte{0x01, 0x02}, 51)
+	if err != nil {
+		t.Fatalf("SplitChunks() error = %v", err)
+	}
+	if len(frames) != 1 || !bytes.Equal(frames[0], []byte{0x00, 0x01, 0x01, 0x02}) {
+		t.Errorf("SplitChunks() = % X", frames)
+	}
+	if frames, _ = SplitChunks(nil, 51); len(frames) != 1 || len(frames[0]) != ChunkHeaderSize {
+		t.Errorf("SplitChunks(nil) = % X, want one empty frame", frames)
+	}
+}
+
+func TestChunkErrors(t *testing.T) {
+	if _, err := SplitChunks([]byte{1}, 2); err == nil {
+		t.Error("SplitChunks(maxLen 2) should fail: no room after the header")
+	}
+	if _, err := SplitChunks(make([]byte, 300), 3); err == nil {
+		t.Error("SplitChunks() should fail beyond 255 chunks")
+	}
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   string
+	}{
+		{"none", nil, "no chunks"},
+		{"short", [][]byte{{0x00}}, "shorter than the header"},
+		{"missing", [][]byte{{0, 3, 1}, {2, 3, 3}}, "chunk 1 of 3 missing"},
+		{"duplicate", [][]byte{{0, 2, 1}, {0, 2, 1}}, "received twice"},
+		{"total", [][]byte{{0, 2, 1}, {1, 3, 2}}, "other chunks say 2"},
+		{"index", [][]byte{{2, 2, 1}}, "index 2 out of 2"},
+	}
+	for _, tt := range tests {
+		if _, err := ReassembleChunks(tt.frames); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReassembleChunks() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
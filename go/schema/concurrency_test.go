@@ -0,0 +1,104 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentDecodeEncode shares one Schema between goroutines. It is
+// meant to run under the race detector (make test-go-race), which reports
+// any state a decode or encode writes outside its own context.
+func TestConcurrentDecodeEncode(t *testing.T) {
+	s, err := ParseSchema(`
+name: shared
+endian: big
+definitions:
+  reading:
+    fields:
+      - {name: value, type: s16, div: 10}
+ports:
+  "1":
+    fields:
+      - {name: kind, type: u8, var: kind, lookup: {1: env, 2: power}}
+      - {name: exp, type: s8, var: exp}
+      - {name: flow, type: u16, scale_by: $exp}
+      - {name: count, type: u8, var: n}
+      - {name: readings, type: repeat, count: $n, fields: [{$ref: "#/definitions/reading"}]}
+      - {name: level, type: number, formula: "$flow * 2"}
+  "2":
+    fields:
+      - name: records
+        type: tlv
+        name_template: "ch{channel}_{name}"
+        cases:
+          "0x80/0xF0":
+            tag_bind: {channel: "tag & 0x0F"}
+            fields:
+              - {name: temperature, type: s8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	type job struct {
+		port int
+		data []byte
+	}
+	jobs := []job{
+		{1, []byte{0x01, 0xFF, 0x00, 0x7B, 0x02, 0x00, 0x64, 0xFF, 0x9C}},
+		{1, []byte{0x02, 0x00, 0x00, 0x05, 0x00}},
+		{2, []byte{0x81, 0x10, 0x83, 0xF0}},
+	}
+	want := make([]map[string]any, len(jobs))
+	for i, j := range jobs {
+		if want[i], err = s.DecodeWithPort(j.data, j.port); err != nil {
+			t.Fatalf("DecodeWithPort(%d) error = %v", j.port, err)
+		}
+	}
+
+	const workers, rounds = 8, 200
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				i := (w + r) % len(jobs)
+				got, err := s.Decode(jobs[i].data, WithPort(jobs[i].port))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(got, want[i]) {
+					errs <- fmt.Errorf("job %d: Decode() = %v, want %v", i, got, want[i])
+					return
+				}
+				if jobs[i].port != 1 {
+					continue
+				}
+				out, err := s.EncodeWithPort(got, 1)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !bytes.Equal(out, jobs[i].data) {
+					errs <- fmt.Errorf("job %d: Encode() = % X, want % X", i, out, jobs[i].data)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
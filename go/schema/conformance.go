@@ -0,0 +1,245 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TS013Input is the decodeUplink input defined by the LoRaWAN Payload Codec
+// API (TS013).
+type TS013Input struct {
+	Bytes    []byte
+	FPort    int
+	RecvTime time.Time
+}
+
+// TS013Output is the decodeUplink result: decoded data plus any warnings
+// and errors the codec reported.
+type TS013Output struct {
+	Data     map[string]any
+	Warnings []string
+	Errors   []string
+}
+
+// TS013Codec runs an existing TS013 codec, typically vendor JavaScript in
+// an embedded interpreter. The schema package does not bundle a JS engine;
+// the gojacodec module adapts the goja interpreter:
+//
+//	codec, err := gojacodec.New(vendorJS)
+type TS013Codec interface {
+	DecodeUplink(input TS013Input) (*TS013Output, error)
+}
+
+// ConformanceCase is one payload in a parity corpus.
+type ConformanceCase struct {
+	Name  string `json:"name" yaml:"name"`
+	Bytes []byte `json:"bytes" yaml:"bytes"`
+	FPort int    `json:"fport" yaml:"fport"`
+}
+
+// ConformanceOptions tunes the comparison.
+type ConformanceOptions struct {
+	Tolerance float64  // Absolute difference allowed between numbers
+	Ignore    []string // Paths excluded from comparison, e.g. "meta.rssi"
+}
+
+// Mismatch is one difference between the schema and codec outputs.
+type Mismatch struct {
+	Case   string `json:"case"`
+	Path   string `json:"path"`             // Dotted field path; empty for whole-payload errors
+	Schema any    `json:"schema,omitempty"` // Schema value (nil if absent)
+	Codec  any    `json:"codec,omitempty"`  // Codec value (nil if absent)
+	Reason string `json:"reason"`
+}
+
+func (m Mismatch) String() string {
+	if m.Path == "" {
+		return fmt.Sprintf("%s: %s", m.Case, m.Reason)
+	}
+	return fmt.Sprintf("%s: %s: %s (schema=%v, codec=%v)", m.Case, m.Path, m.Reason, m.Schema, m.Codec)
+}
+
+// ConformanceReport summarizes a parity run.
+type ConformanceReport struct {
+	Cases      int        `json:"cases"`
+	Passed     int        `json:"passed"`
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// OK reports whether every case matched.
+func (r *ConformanceReport) OK() bool {
+	return r.Cases == r.Passed
+}
+
+func (r *ConformanceReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d/%d cases match", r.Passed, r.Cases)
+	for _, m := range r.Mismatches {
+		sb.WriteString("\n  ")
+		sb.WriteString(m.String())
+	}
+	return sb.String()
+}
+
+// CheckConformance decodes every case with the schema and with codec and
+// reports field-by-field differences. Both outputs are normalized through
+// JSON, so integer/float representation differences do not count. Top-level
+// keys starting with _ (schema metadata such as _quality) are not compared.
+// A case where both sides fail to decode counts as a match.
+func CheckConformance(s *Schema, codec TS013Codec, cases []ConformanceCase, opts ConformanceOptions) *ConformanceReport {
+	report := &ConformanceReport{Cases: len(cases)}
+	ignore := make(map[string]bool, len(opts.Ignore))
+	for _, p := range opts.Ignore {
+		ignore[p] = true
+	}
+
+	for i, tc := range cases {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+
+		schemaOut, schemaErr := s.DecodeWithPort(tc.Bytes, tc.FPort)
+		codecOut, codecErr := codec.DecodeUplink(TS013Input{Bytes: tc.Bytes, FPort: tc.FPort, RecvTime: time.Now()})
+		if codecErr == nil && codecOut != nil && len(codecOut.Errors) > 0 {
+			codecErr = fmt.Errorf("%s", strings.Join(codecOut.Errors, "; "))
+		}
+
+		var diffs []Mismatch
+		switch {
+		case schemaErr != nil && codecErr != nil:
+		case schemaErr != nil:
+			diffs = append(diffs, Mismatch{Case: name, Reason: "schema failed: " + schemaErr.Error()})
+		case codecErr != nil:
+			diffs = append(diffs, Mismatch{Case: name, Reason: "codec failed: " + codecErr.Error()})
+		default:
+			var codecData map[string]any
+			if codecOut != nil {
+				codecData = codecOut.Data
+			}
+			a, errA := normalizeJSON(withoutMetaKeys(schemaOut))
+			b, errB := normalizeJSON(codecData)
+			if errA != nil || errB != nil {
+				diffs = append(diffs, Mismatch{Case: name, Reason: fmt.Sprintf("output not JSON-compatible: %v", firstErr(errA, errB))})
+				break
+			}
+			diffs = compareValues(name, "", a, b, opts.Tolerance, ignore, diffs)
+		}
+
+		if len(diffs) == 0 {
+			report.Passed++
+		}
+		report.Mismatches = append(report.Mismatches, diffs...)
+	}
+	return report
+}
+
+func withoutMetaKeys(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if !strings.HasPrefix(k, "_") {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func normalizeJSON(v any) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareValues appends the differences between a (schema) and b (codec)
+// at path to diffs.
+func compareValues(name, path string, a, b any, tol float64, ignore map[string]bool, diffs []Mismatch) []Mismatch {
+	if ignore[path] && path != "" {
+		return diffs
+	}
+	mismatch := func(reason string) []Mismatch {
+		return append(diffs, Mismatch{Case: name, Path: path, Schema: a, Codec: b, Reason: reason})
+	}
+
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			return mismatch("type differs")
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := k
+			if path != "" {
+				sub = path + "." + k
+			}
+			va, inA := av[k]
+			vb, inB := bv[k]
+			switch {
+			case ignore[sub]:
+			case !inA:
+				diffs = append(diffs, Mismatch{Case: name, Path: sub, Codec: vb, Reason: "missing in schema output"})
+			case !inB:
+				diffs = append(diffs, Mismatch{Case: name, Path: sub, Schema: va, Reason: "missing in codec output"})
+			default:
+				diffs = compareValues(name, sub, va, vb, tol, ignore, diffs)
+			}
+		}
+		return diffs
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			return mismatch("type differs")
+		}
+		if len(av) != len(bv) {
+			return mismatch(fmt.Sprintf("length %d vs %d", len(av), len(bv)))
+		}
+		for i := range av {
+			diffs = compareValues(name, fmt.Sprintf("%s[%d]", path, i), av[i], bv[i], tol, ignore, diffs)
+		}
+		return diffs
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return mismatch("type differs")
+		}
+		if math.Abs(av-bv) > tol {
+			return mismatch("value differs")
+		}
+		return diffs
+	default:
+		if a != b {
+			return mismatch("value differs")
+		}
+		return diffs
+	}
+}
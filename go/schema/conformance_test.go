@@ -0,0 +1,112 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeCodec stands in for a JS codec: it mimics a vendor decodeUplink.
+type fakeCodec func(input TS013Input) (*TS013Output, error)
+
+func (f fakeCodec) DecodeUplink(input TS013Input) (*TS013Output, error) { return f(input) }
+
+func TestCheckConformance(t *testing.T) {
+	s, err := ParseSchema(`
+name: env
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+  - name: humidity
+    type: u8
+  - name: status
+    type: u8
+    lookup: {0: ok, 1: fault}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	codec := fakeCodec(func(in TS013Input) (*TS013Output, error) {
+		if len(in.Bytes) < 4 {
+			return &TS013Output{Errors: []string{"payload too short"}}, nil
+		}
+		temp := float64(int16(uint16(in.Bytes[0])<<8|uint16(in.Bytes[1]))) / 10
+		status := "ok"
+		if in.Bytes[3] != 0 {
+			status = "FAULT" // vendor codec disagrees on casing
+		}
+		return &TS013Output{Data: map[string]any{
+			"temperature": temp + 0.001, // JS float noise
+			"humidity":    int64(in.Bytes[2]),
+			"status":      status,
+			"battery":     3.6, // not in the schema
+		}}, nil
+	})
+
+	report := CheckConformance(s, codec, []ConformanceCase{
+		{Name: "normal", Bytes: []byte{0x00, 0xFA, 45, 0}},
+		{Name: "fault", Bytes: []byte{0x00, 0xFA, 45, 1}},
+		{Name: "short", Bytes: []byte{0x00}},
+	}, ConformanceOptions{Tolerance: 0.01, Ignore: []string{"battery"}})
+
+	if report.Cases != 3 || report.Passed != 2 || report.OK() {
+		t.Fatalf("report = %v", report)
+	}
+	if len(report.Mismatches) != 1 {
+		t.Fatalf("Mismatches = %v, want one", report.Mismatches)
+	}
+	m := report.Mismatches[0]
+	if m.Case != "fault" || m.Path != "status" || m.Schema != "fault" || m.Codec != "FAULT" {
+		t.Errorf("Mismatch = %+v", m)
+	}
+	if !strings.Contains(report.String(), "2/3 cases match") {
+		t.Errorf("String() = %q", report.String())
+	}
+}
+
+func TestCheckConformanceStructural(t *testing.T) {
+	s, err := ParseSchema(`
+name: nested
+fields:
+  - name: readings
+    type: repeat
+    count: 2
+    fields:
+      - {name: v, type: u8}
+  - name: extra
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	codec := fakeCodec(func(in TS013Input) (*TS013Output, error) {
+		if in.FPort != 7 {
+			return nil, fmt.Errorf("unexpected fPort %d", in.FPort)
+		}
+		return &TS013Output{Data: map[string]any{
+			"readings": []any{map[string]any{"v": 1}, map[string]any{"v": 3}},
+			"other":    true,
+		}}, nil
+	})
+	report := CheckConformance(s, codec, []ConformanceCase{{Bytes: []byte{1, 2, 9}, FPort: 7}}, ConformanceOptions{})
+
+	want := map[string]string{
+		"readings[1].v": "value differs",
+		"extra":         "missing in codec output",
+		"other":         "missing in schema output",
+	}
+	if len(report.Mismatches) != len(want) {
+		t.Fatalf("Mismatches = %v", report.Mismatches)
+	}
+	for _, m := range report.Mismatches {
+		if m.Case != "case 1" || want[m.Path] != m.Reason {
+			t.Errorf("unexpected mismatch %v", m)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Curve end behaviours.
+const (
+	CurveClamp       = "clamp"       // hold the first/last value (default)
+	CurveExtrapolate = "extrapolate" // extend the first/last segment
+)
+
+// CurveDef is a piecewise-linear interpolation table applied to the raw
+// value, e.g. thermistor or load-cell linearization.
+//
+//	curve:
+//	  points: [[0, -40], [512, 25], [1023, 125]]   # [raw, value]
+//	  ends: extrapolate
+//
+// The short form `curve: [[0, -40], ...]` clamps at the ends.
+type CurveDef struct {
+	Points [][2]float64 `json:"points" yaml:"points"`
+	Ends   string       `json:"ends,omitempty" yaml:"ends,omitempty"`
+}
+
+func parseCurveDef(raw any) (*CurveDef, error) {
+	cd := &CurveDef{}
+	pointsRaw, ok := raw.([]any)
+	if m, isMap := raw.(map[string]any); isMap {
+		pointsRaw, ok = m["points"].([]any)
+		if ends, isStr := m["ends"].(string); isStr {
+			cd.Ends = ends
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("curve: expected a list of [raw, value] points")
+	}
+	switch cd.Ends {
+	case "", CurveClamp, CurveExtrapolate:
+	default:
+		return nil, fmt.Errorf("curve: unknown ends %q (want clamp or extrapolate)", cd.Ends)
+	}
+
+	for i, pRaw := range pointsRaw {
+		pair, ok := pRaw.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("curve: point %d must be [raw, value]", i)
+		}
+		x, okX := toFloat64(pair[0])
+		y, okY := toFloat64(pair[1])
+		if !okX || !okY {
+			return nil, fmt.Errorf("curve: point %d must be numeric", i)
+		}
+		cd.Points = append(cd.Points, [2]float64{x, y})
+	}
+	if len(cd.Points) < 2 {
+		return nil, fmt.Errorf("curve: need at least 2 points")
+	}
+	sort.SliceStable(cd.Points, func(i, j int) bool { return cd.Points[i][0] < cd.Points[j][0] })
+	for i := 1; i < len(cd.Points); i++ {
+		if cd.Points[i][0] == cd.Points[i-1][0] {
+			return nil, fmt.Errorf("curve: duplicate raw value %v", cd.Points[i][0])
+		}
+	}
+	return cd, nil
+}
+
+// Apply maps a raw value through the curve.
+func (cd *CurveDef) Apply(x float64) float64 {
+	return interpolate(cd.Points, x, cd.Ends == CurveExtrapolate, 0, 1)
+}
+
+// Invert maps a value back to raw. It requires the curve's values to be
+// strictly monotonic; ok is false otherwise.
+func (cd *CurveDef) Invert(y float64) (float64, bool) {
+	pts := make([][2]float64, len(cd.Points))
+	copy(pts, cd.Points)
+	increasing := pts[len(pts)-1][1] > pts[0][1]
+	for i := 1; i < len(pts); i++ {
+		if (pts[i][1] > pts[i-1][1]) != increasing || pts[i][1] == pts[i-1][1] {
+			return 0, false
+		}
+	}
+	if !increasing {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return interpolate(pts, y, cd.Ends == CurveExtrapolate, 1, 0), true
+}
+
+// interpolate evaluates the polyline pts (sorted by pts[i][in]) at v,
+// reading the input from column in and the result from column out.
+func interpolate(pts [][2]float64, v float64, extrapolate bool, in, out int) float64 {
+	last := len(pts) - 1
+	if !extrapolate {
+		if v <= pts[0][in] {
+			return pts[0][out]
+		}
+		if v >= pts[last][in] {
+			return pts[last][out]
+		}
+	}
+	i := sort.Search(last, func(i int) bool { return pts[i+1][in] >= v })
+	if i >= last {
+		i = last - 1
+	}
+	a, b := pts[i], pts[i+1]
+	return a[out] + (v-a[in])*(b[out]-a[out])/(b[in]-a[in])
+}
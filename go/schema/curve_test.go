@@ -0,0 +1,127 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCurveInterpolation(t *testing.T) {
+	clamp := &CurveDef{Points: [][2]float64{{0, -40}, {500, 10}, {1000, 110}}}
+	extra := &CurveDef{Points: clamp.Points, Ends: CurveExtrapolate}
+
+	tests := []struct {
+		name  string
+		curve *CurveDef
+		raw   float64
+		want  float64
+	}{
+		{"first point", clamp, 0, -40},
+		{"first segment", clamp, 250, -15},
+		{"knee", clamp, 500, 10},
+		{"second segment", clamp, 750, 60},
+		{"clamp low", clamp, -100, -40},
+		{"clamp high", clamp, 2000, 110},
+		{"extrapolate low", extra, -100, -50},
+		{"extrapolate high", extra, 1100, 130},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.curve.Apply(tt.raw); got != tt.want {
+				t.Errorf("Apply(%v) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurveInvert(t *testing.T) {
+	// Decreasing curve (NTC thermistor: higher ADC = colder)
+	cd := &CurveDef{Points: [][2]float64{{100, 80}, {500, 25}, {900, -20}}}
+	raw, ok := cd.Invert(25)
+	if !ok || raw != 500 {
+		t.Errorf("Invert(25) = %v, %v; want 500", raw, ok)
+	}
+	raw, _ = cd.Invert(52.5)
+	if raw != 300 {
+		t.Errorf("Invert(52.5) = %v, want 300", raw)
+	}
+
+	notMonotonic := &CurveDef{Points: [][2]float64{{0, 0}, {1, 10}, {2, 5}}}
+	if _, ok := notMonotonic.Invert(7); ok {
+		t.Error("Invert should fail for a non-monotonic curve")
+	}
+}
+
+func TestCurveField(t *testing.T) {
+	schema, err := ParseSchema(`
+name: thermistor
+fields:
+  - name: temperature
+    type: u16
+    curve:
+      points: [[900, -20], [100, 80], [500, 25]]
+    round: 1
+  - name: load_raw
+    type: u8
+    var: load
+  - name: load_kg
+    type: number
+    ref: $load
+    curve: [[0, 0], [100, 50], [200, 150]]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(schema.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", schema.Warnings)
+	}
+
+	decoded, err := schema.Decode([]byte{0x01, 0x2C, 150})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded["temperature"] != 52.5 {
+		t.Errorf("temperature = %v, want 52.5", decoded["temperature"])
+	}
+	if decoded["load_kg"] != 100.0 {
+		t.Errorf("load_kg = %v, want 100", decoded["load_kg"])
+	}
+
+	encoded, err := schema.Encode(map[string]any{"temperature": 52.5, "load_raw": 150.0})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x01, 0x2C, 150}) {
+		t.Errorf("Encode() = %X, want 012C96", encoded)
+	}
+}
+
+func TestCurveInvalid(t *testing.T) {
+	tests := []struct {
+		name, curve, want string
+	}{
+		{"one point", "[[0, 1]]", "at least 2 points"},
+		{"bad pair", "[[0, 1, 2], [3, 4]]", "must be [raw, value]"},
+		{"duplicate raw", "[[0, 1], [0, 2]]", "duplicate raw value"},
+		{"bad ends", "{points: [[0, 1], [1, 2]], ends: wrap}", "unknown ends"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "name: bad\nfields:\n  - name: v\n    type: u8\n    curve: " + tt.curve + "\n"
+			schema, err := ParseSchema(yaml)
+			if err != nil {
+				t.Fatalf("ParseSchema() error = %v", err)
+			}
+			if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], tt.want) {
+				t.Errorf("Warnings = %v, want %q", schema.Warnings, tt.want)
+			}
+			if _, err := ParseSchema("strict: true\n" + yaml); err == nil {
+				t.Error("strict schema should reject an invalid curve")
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxMACPayload holds the maximum MACPayload size (M) per data rate from
+// the LoRaWAN Regional Parameters (RP002), assuming no repeater and, for
+// AS923, dwell time off. Entries of 0 mark data rates that are not defined.
+var maxMACPayload = map[string][]int{
+	"EU868": {59, 59, 59, 123, 250, 250, 250, 250},
+	"US915": {19, 61, 133, 250, 250, 58, 133, 0, 61, 137, 250, 250, 250, 250},
+	"AS923": {59, 59, 59, 123, 250, 250, 250, 250},
+}
+
+// frameOverhead is the MACPayload minus the application payload: FHDR
+// without FOpts (7 bytes) plus FPort (1 byte).
+const frameOverhead = 8
+
+// MaxPayloadSize returns the largest application payload (FRMPayload)
+// allowed at a region's data rate, assuming no MAC commands in FOpts.
+// Regions are EU868, US915 and AS923.
+func MaxPayloadSize(region string, dr int) (int, error) {
+	table, ok := maxMACPayload[strings.ToUpper(region)]
+	if !ok {
+		return 0, fmt.Errorf("unknown region %q", region)
+	}
+	if dr < 0 || dr >= len(table) || table[dr] == 0 {
+		return 0, fmt.Errorf("region %s has no DR%d", strings.ToUpper(region), dr)
+	}
+	return table[dr] - frameOverhead, nil
+}
+
+// FitsDataRate checks each port's worst-case payload size (see Layout)
+// against the budget of a region's data rate. It returns one warning per
+// port that may not fit; a port with no upper size bound never fits.
+func (s *Schema) FitsDataRate(region string, dr int) ([]string, error) {
+	budget, err := MaxPayloadSize(region, dr)
+	if err != nil {
+		return nil, err
+	}
+	target := fmt.Sprintf("%s DR%d", strings.ToUpper(region), dr)
+
+	type portFields struct {
+		label          string
+		header, fields []Field
+	}
+	var ports []portFields
+	if len(s.Ports) == 0 {
+		ports = append(ports, portFields{"payload", s.Header, s.topFields()})
+	} else {
+		keys := make([]string, 0, len(s.Ports))
+		for k := range s.Ports {
+			keys = append(keys, k)
+		}
+		// Numeric ports in order, then named ones such as default
+		sort.Slice(keys, func(i, j int) bool {
+			a, errA := strconv.Atoi(keys[i])
+			b, errB := strconv.Atoi(keys[j])
+			if errA != nil || errB != nil {
+				return errA == nil || (errB != nil && keys[i] < keys[j])
+			}
+			return a < b
+		})
+		for _, k := range keys {
+			ports = append(ports, portFields{"port " + k, s.portHeader(s.Ports[k]), s.Ports[k].Fields})
+		}
+	}
+
+	var warnings []string
+	for _, p := range ports {
+		l, err := s.layoutFields(p.header, p.fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.label, err)
+		}
+		switch {
+		case l.MaxSize == Unbounded:
+			warnings = append(warnings, fmt.Sprintf("%s: size is unbounded, %s allows %d bytes",
+				p.label, target, budget))
+		case l.MaxSize > budget:
+			warnings = append(warnings, fmt.Sprintf("%s: worst case %d bytes exceeds %s budget of %d bytes",
+				p.label, l.MaxSize, target, budget))
+		}
+	}
+	return warnings, nil
+}
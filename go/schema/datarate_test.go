@@ -0,0 +1,85 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxPayloadSize(t *testing.T) {
+	tests := []struct {
+		region string
+		dr     int
+		want   int
+	}{
+		{"EU868", 0, 51},
+		{"eu868", 5, 242},
+		{"US915", 0, 11},
+		{"US915", 3, 242},
+		{"AS923", 3, 115},
+	}
+	for _, tt := range tests {
+		got, err := MaxPayloadSize(tt.region, tt.dr)
+		if err != nil || got != tt.want {
+			t.Errorf("MaxPayloadSize(%s, %d) = %d, %v; want %d", tt.region, tt.dr, got, err, tt.want)
+		}
+	}
+	for _, bad := range []struct {
+		region string
+		dr     int
+	}{{"XX123", 0}, {"EU868", 8}, {"US915", 7}, {"EU868", -1}} {
+		if _, err := MaxPayloadSize(bad.region, bad.dr); err == nil {
+			t.Errorf("MaxPayloadSize(%s, %d) should fail", bad.region, bad.dr)
+		}
+	}
+}
+
+func TestFitsDataRate(t *testing.T) {
+	s, err := ParseSchema(`
+name: budget
+ports:
+  1:
+    fields:
+      - {name: temperature, type: s16}
+      - {name: humidity, type: u8}
+  2:
+    fields:
+      - {name: count, type: u8}
+      - name: readings
+        type: repeat
+        count: $count
+        max: 10
+        fields: [{name: v, type: u16}]
+  3:
+    fields:
+      - name: log
+        type: repeat
+        until: end
+        fields: [{name: v, type: u8}]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	// US915 DR0 allows 11 bytes: port 1 (3) fits, port 2 (21) does not
+	warnings, err := s.FitsDataRate("US915", 0)
+	if err != nil {
+		t.Fatalf("FitsDataRate() error = %v", err)
+	}
+	if len(warnings) != 2 ||
+		!strings.Contains(warnings[0], "port 2: worst case 21 bytes exceeds US915 DR0 budget of 11 bytes") ||
+		!strings.Contains(warnings[1], "port 3: size is unbounded") {
+		t.Errorf("warnings = %q", warnings)
+	}
+
+	warnings, err = s.FitsDataRate("EU868", 5)
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("FitsDataRate(EU868, 5) = %q, %v; want only port 3", warnings, err)
+	}
+	if _, err := s.FitsDataRate("EU868", 9); err == nil {
+		t.Error("FitsDataRate() should reject an undefined data rate")
+	}
+}
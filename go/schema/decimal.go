@@ -0,0 +1,186 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OutputDecimalString is the `output:` value that emits an integer field as
+// an exact decimal string, raw × 10^-scale, for registers (kWh, currency)
+// where float64 scaling would show artifacts such as 12345.678000000001.
+const OutputDecimalString = "decimal_string"
+
+// checkDecimalOutput validates `output:` and resolves the scale of a
+// decimal_string field, taking it from a power-of-ten div when scale: is
+// not given.
+func checkDecimalOutput(f *Field) string {
+	if f.Output == "" {
+		return ""
+	}
+	if f.Output != OutputDecimalString {
+		return fmt.Sprintf("%s: output: expected %s, got %q", f.Name, OutputDecimalString, f.Output)
+	}
+	if !isIntegerType(f.Type) {
+		return fmt.Sprintf("%s: output: decimal_string needs an integer type, got %s", f.Name, f.Type)
+	}
+	if f.Add != nil || f.Mult != nil || len(f.Transform) > 0 || len(f.Modifiers) > 0 ||
+		f.Curve != nil || f.Formula != "" || f.Lookup != nil || f.LookupArray != nil {
+		return fmt.Sprintf("%s: output: decimal_string scales by scale: (or a power-of-ten div) only", f.Name)
+	}
+	if f.Div != nil {
+		k, ok := powerOfTen(*f.Div)
+		if !ok || (f.Scale != nil && *f.Scale != k) {
+			return fmt.Sprintf("%s: output: decimal_string needs div to be a power of ten matching scale", f.Name)
+		}
+		f.Scale = &k
+	}
+	if f.Scale == nil {
+		zero := 0
+		f.Scale = &zero
+	}
+	return ""
+}
+
+// powerOfTen returns k when v is 10^k for a whole k.
+func powerOfTen(v float64) (int, bool) {
+	if v <= 0 {
+		return 0, false
+	}
+	k := math.Round(math.Log10(v))
+	if math.Abs(k) > 18 || math.Pow(10, k) != v {
+		return 0, false
+	}
+	return int(k), true
+}
+
+func isIntegerType(t FieldType) bool {
+	switch t {
+	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU32, TypeU64, TypeU24, TypeBInt:
+		return true
+	}
+	return isSignedIntType(t)
+}
+
+func isSignedIntType(t FieldType) bool {
+	switch t {
+	case TypeSInt, TypeS8, TypeS16, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64, TypeS24:
+		return true
+	}
+	return false
+}
+
+// decimalString renders a raw integer times 10^-scale without going
+// through float64.
+func decimalString(raw any, scale int) (string, bool) {
+	var digits string
+	neg := false
+	switch v := raw.(type) {
+	case uint64:
+		digits = strconv.FormatUint(v, 10)
+	case int64:
+		neg = v < 0
+		u := uint64(v)
+		if neg {
+			u = -u
+		}
+		digits = strconv.FormatUint(u, 10)
+	default:
+		return "", false
+	}
+
+	switch {
+	case scale < 0 && digits != "0":
+		digits += strings.Repeat("0", -scale)
+	case scale > 0:
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	return digits, true
+}
+
+// parseDecimalString is the inverse of decimalString. It rejects strings
+// with more decimals than scale rather than rounding them.
+func parseDecimalString(s string, scale int) (any, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+
+	if scale >= 0 {
+		if len(frac) > scale {
+			if strings.TrimRight(frac[scale:], "0") != "" {
+				return nil, fmt.Errorf("%q has more than %d decimals", s, scale)
+			}
+			frac = frac[:scale]
+		}
+		whole += frac + strings.Repeat("0", scale-len(frac))
+	} else {
+		trim := strings.TrimRight(frac, "0")
+		zeros := strings.Repeat("0", -scale)
+		if trim != "" || !strings.HasSuffix(whole, zeros) {
+			return nil, fmt.Errorf("%q is not a multiple of 1e%d", s, -scale)
+		}
+		whole = whole[:len(whole)-len(zeros)]
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	u, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a decimal number", s)
+	}
+	if !neg {
+		return u, nil
+	}
+	if u > 1<<63 {
+		return nil, fmt.Errorf("%q is out of range", s)
+	}
+	return -int64(u-1) - 1, nil
+}
+
+// decimalEncodeValue converts the input of a decimal_string field to its
+// raw integer: uint64 for unsigned types, int64 for signed ones. Numbers
+// are accepted too and scaled as floats.
+func decimalEncodeValue(field Field, value any) (any, error) {
+	scale := *field.Scale
+	strVal, isString := value.(string)
+	if !isString {
+		numVal, ok := toFloat64(value)
+		if !ok {
+			return value, nil
+		}
+		return math.Round(numVal * math.Pow10(scale)), nil
+	}
+
+	raw, err := parseDecimalString(strVal, scale)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", field.Name, err)
+	}
+	signed := isSignedIntType(field.Type)
+	switch v := raw.(type) {
+	case uint64:
+		if signed {
+			if v > math.MaxInt64 {
+				return nil, fmt.Errorf("field %s: %q is out of range", field.Name, strVal)
+			}
+			return int64(v), nil
+		}
+	case int64:
+		if !signed {
+			return nil, fmt.Errorf("field %s: %q is negative", field.Name, strVal)
+		}
+	}
+	return raw, nil
+}
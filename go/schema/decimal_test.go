@@ -0,0 +1,121 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDecimalStringOutput(t *testing.T) {
+	s, err := ParseSchema(`
+name: meter
+endian: big
+fields:
+  - name: energy_kwh
+    type: u32
+    output: decimal_string
+    scale: 3
+  - name: balance
+    type: s16
+    div: 100
+    output: decimal_string
+  - name: total_wh
+    type: u64
+    output: decimal_string
+  - name: pulses
+    type: u8
+    output: decimal_string
+    scale: -2
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	payload := []byte{
+		0x00, 0xBC, 0x61, 0x4E, // 12345678
+		0xFF, 0xFB, // -5
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x07,
+	}
+	result, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]string{
+		"energy_kwh": "12345.678",
+		"balance":    "-0.05",
+		"total_wh":   "18446744073709551615",
+		"pulses":     "700",
+	}
+	for k, v := range want {
+		if result[k] != v {
+			t.Errorf("%s = %#v, want %q", k, result[k], v)
+		}
+	}
+
+	encoded, err := s.Encode(result)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = % X, want % X", encoded, payload)
+	}
+
+	// Numbers are accepted on encode; strings must not lose digits
+	encoded, _ = s.Encode(map[string]any{"energy_kwh": 1.5})
+	if !bytes.Equal(encoded, []byte{0x00, 0x00, 0x05, 0xDC}) {
+		t.Errorf("Encode(1.5) = % X", encoded)
+	}
+	if _, err := s.Encode(map[string]any{"energy_kwh": "1.2345"}); err == nil || !strings.Contains(err.Error(), "more than 3 decimals") {
+		t.Errorf("Encode() error = %v, want too many decimals", err)
+	}
+	if _, err := s.Encode(map[string]any{"energy_kwh": "-1"}); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("Encode() error = %v, want negative", err)
+	}
+}
+
+func TestDecimalStringOutputWarnings(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{"{name: v, type: u16, output: decimal}", "expected decimal_string"},
+		{"{name: v, type: f32, output: decimal_string}", "needs an integer type"},
+		{"{name: v, type: u16, div: 4, output: decimal_string}", "power of ten"},
+		{"{name: v, type: u16, div: 10, scale: 2, output: decimal_string}", "power of ten"},
+		{"{name: v, type: u16, add: 1, output: decimal_string}", "scales by scale"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema(%s) error = %v", tt.field, err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.field, s.Warnings, tt.want)
+		}
+	}
+}
+
+func TestDecimalStringZero(t *testing.T) {
+	tests := []struct {
+		raw   any
+		scale int
+		want  string
+	}{
+		{uint64(0), -2, "0"},
+		{int64(0), -3, "0"},
+		{uint64(0), 2, "0.00"},
+		{int64(-7), -1, "-70"},
+	}
+	for _, tt := range tests {
+		if got, ok := decimalString(tt.raw, tt.scale); !ok || got != tt.want {
+			t.Errorf("decimalString(%v, %d) = %q, want %q", tt.raw, tt.scale, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DispatchRule selects a bundle member for the messages it matches, for
+// devices that multiplex payload families behind a magic byte rather than
+// a port. Rules are tried in order:
+//
+//	dispatch:
+//	  - match: {port: 1, byte0: 0x67}
+//	    schema: env_report
+//	  - match: {port: 1}
+//	    schema: status
+//
+// Matching does not consume the magic byte; the member decodes the whole
+// payload and usually declares the byte as a field.
+type DispatchRule struct {
+	Port      *int         // fPort to match, nil for any
+	CompanyID *int         // BLE company identifier to match (see DecodeBLE)
+	Bytes     map[int]byte // Payload bytes to match, by offset (byte0, byte1, ...)
+	Schema    string       // Member selected
+}
+
+// Matches reports whether a message on fPort with payload fits the rule.
+// Rules with a company_id only match BLE advertisements.
+func (r DispatchRule) Matches(fPort int, payload []byte) bool {
+	if r.CompanyID != nil || r.Port != nil && *r.Port != fPort {
+		return false
+	}
+	return r.matchBytes(payload)
+}
+
+func (r DispatchRule) matchBytes(payload []byte) bool {
+	for off, want := range r.Bytes {
+		if off >= len(payload) || payload[off] != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the member chosen by the first dispatch rule matching
+// fPort and payload, or nil if none matches.
+func (b *Bundle) Resolve(fPort int, payload []byte) *Schema {
+	for _, r := range b.Dispatch {
+		if r.Matches(fPort, payload) {
+			return b.Schemas[r.Schema]
+		}
+	}
+	return nil
+}
+
+// DecodeWithPort decodes payload with the member Resolve selects.
+func (b *Bundle) DecodeWithPort(payload []byte, fPort int) (map[string]any, error) {
+	s := b.Resolve(fPort, payload)
+	if s == nil {
+		first := "none"
+		if len(payload) > 0 {
+			first = fmt.Sprintf("0x%02X", payload[0])
+		}
+		return nil, fmt.Errorf("no dispatch rule matches fPort %d, first byte %s", fPort, first)
+	}
+	return s.DecodeWithPort(payload, fPort)
+}
+
+// parseDispatch parses a `dispatch:` list, checking that every rule names a
+// member of the bundle.
+func parseDispatch(node *yaml.Node, members map[string]*Schema) ([]DispatchRule, error) {
+	var raw []struct {
+		Match  map[string]any `yaml:"match"`
+		Schema string         `yaml:"schema"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("dispatch: must be a list of {match, schema}: %w", err)
+	}
+
+	rules := make([]DispatchRule, 0, len(raw))
+	for i, entry := range raw {
+		if _, ok := members[entry.Schema]; !ok {
+			return nil, fmt.Errorf("dispatch %d: schema %q is not in the bundle", i, entry.Schema)
+		}
+		if len(entry.Match) == 0 {
+			return nil, fmt.Errorf("dispatch %d: match is empty", i)
+		}
+		r := DispatchRule{Schema: entry.Schema}
+
+		keys := make([]string, 0, len(entry.Match))
+		for k := range entry.Match {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			n, ok := toWholeInt(entry.Match[k])
+			switch {
+			case k == "port":
+				if !ok || n < 0 || n > 255 {
+					return nil, fmt.Errorf("dispatch %d: port must be 0-255", i)
+				}
+				r.Port = &n
+			case k == "company_id":
+				if !ok || n < 0 || n > 0xFFFF {
+					return nil, fmt.Errorf("dispatch %d: company_id must be 0-0xFFFF", i)
+				}
+				r.CompanyID = &n
+			case strings.HasPrefix(k, "byte"):
+				off, err := strconv.Atoi(strings.TrimPrefix(k, "byte"))
+				if err != nil || off < 0 {
+					return nil, fmt.Errorf("dispatch %d: unknown match key %q", i, k)
+				}
+				if !ok || n < 0 || n > 255 {
+					return nil, fmt.Errorf("dispatch %d: %s must be 0-255", i, k)
+				}
+				if r.Bytes == nil {
+					r.Bytes = map[int]byte{}
+				}
+				r.Bytes[off] = byte(n)
+			default:
+				return nil, fmt.Errorf("dispatch %d: unknown match key %q (want port, company_id or byteN)", i, k)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
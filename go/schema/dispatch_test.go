@@ -0,0 +1,105 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+const dispatchBundle = `
+schemas:
+  - name: env_report
+    fields:
+      - {name: magic, type: u8}
+      - {name: temperature, type: s16, div: 10}
+  - name: status
+    fields:
+      - {name: battery, type: u8}
+dispatch:
+  - match: {port: 1, byte0: 0x67}
+    schema: env_report
+  - match: {port: 1}
+    schema: status
+`
+
+func TestBundleDispatch(t *testing.T) {
+	b, err := ParseBundle(dispatchBundle)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	if len(b.Dispatch) != 2 {
+		t.Fatalf("Dispatch = %+v, want 2 rules", b.Dispatch)
+	}
+
+	result, err := b.DecodeWithPort([]byte{0x67, 0x00, 0xFA}, 1)
+	if err != nil {
+		t.Fatalf("DecodeWithPort() error = %v", err)
+	}
+	if result["temperature"] != 25.0 || result["magic"] != 103.0 {
+		t.Errorf("magic payload = %v", result)
+	}
+
+	result, err = b.DecodeWithPort([]byte{0x5A}, 1)
+	if err != nil || result["battery"] != 90.0 {
+		t.Errorf("fallback payload = %v, %v", result, err)
+	}
+
+	if s := b.Resolve(2, []byte{0x67}); s != nil {
+		t.Errorf("Resolve() on port 2 = %s, want nil", s.Name)
+	}
+	if _, err := b.DecodeWithPort([]byte{0x67}, 2); err == nil || !strings.Contains(err.Error(), "fPort 2, first byte 0x67") {
+		t.Errorf("DecodeWithPort() error = %v", err)
+	}
+}
+
+func TestBundleDispatchDocument(t *testing.T) {
+	src := `
+name: a
+fields: [{name: x, type: u8}]
+---
+name: b
+fields: [{name: y, type: u8}]
+---
+dispatch:
+  - match: {byte1: 2}
+    schema: b
+  - match: {port: 9}
+    schema: a
+`
+	b, err := ParseBundle(src)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	if len(b.Names) != 2 {
+		t.Errorf("Names = %v, want a and b", b.Names)
+	}
+	if s := b.Resolve(9, []byte{1, 2}); s == nil || s.Name != "b" {
+		t.Errorf("Resolve() = %v, want b", s)
+	}
+	if s := b.Resolve(9, []byte{1}); s == nil || s.Name != "a" {
+		t.Errorf("Resolve() of a short payload = %v, want a", s)
+	}
+}
+
+func TestBundleDispatchErrors(t *testing.T) {
+	head := "name: a\nfields: [{name: x, type: u8}]\n---\n"
+	tests := []struct {
+		rule, want string
+	}{
+		{"- {match: {port: 1}, schema: nope}", "not in the bundle"},
+		{"- {match: {}, schema: a}", "match is empty"},
+		{"- {match: {port: 300}, schema: a}", "port must be 0-255"},
+		{"- {match: {byte0: 0x167}, schema: a}", "byte0 must be 0-255"},
+		{"- {match: {magic: 1}, schema: a}", "unknown match key"},
+		{"{match: {port: 1}}", "must be a list"},
+	}
+	for _, tt := range tests {
+		_, err := ParseBundle(head + "dispatch:\n  " + strings.ReplaceAll(tt.rule, "\n", "\n  "))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("dispatch %s: error = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cEndianMacros convert 16/32/64-bit values between host order and wire
+// order. Each is its own inverse, so the same macro reads and writes.
+const cEndianMacros = `#ifndef PS_ENDIAN_MACROS
+#define PS_ENDIAN_MACROS
+#if defined(__BYTE_ORDER__) && __BYTE_ORDER__ == __ORDER_BIG_ENDIAN__
+#define PS_BE16(x) ((uint16_t)(x))
+#define PS_BE32(x) ((uint32_t)(x))
+#define PS_BE64(x) ((uint64_t)(x))
+#define PS_LE16(x) __builtin_bswap16((uint16_t)(x))
+#define PS_LE32(x) __builtin_bswap32((uint32_t)(x))
+#define PS_LE64(x) __builtin_bswap64((uint64_t)(x))
+#else
+#define PS_BE16(x) __builtin_bswap16((uint16_t)(x))
+#define PS_BE32(x) __builtin_bswap32((uint32_t)(x))
+#define PS_BE64(x) __builtin_bswap64((uint64_t)(x))
+#define PS_LE16(x) ((uint16_t)(x))
+#define PS_LE32(x) ((uint32_t)(x))
+#define PS_LE64(x) ((uint64_t)(x))
+#endif
+#endif
+`
+
+// ExportC renders the fixed-size prefix of the payload on fPort as a C
+// header: a packed struct of raw wire values, accessor macros that convert
+// multi-byte members to host order, shift/mask macros for bit fields, and
+// constants for enum and lookup values. Members keep wire byte order so the
+// struct can be overlaid on a received buffer or sent as-is.
+//
+// The struct ends at the first field whose size or presence varies (match,
+// flagged, TLV, variable repeat); a comment names where the variable part
+// begins. Like the Python firmware generator, values are raw: modifiers
+// are not applied.
+func (s *Schema) ExportC(fPort int) (string, error) {
+	fields, err := s.ResolveFields(fPort)
+	if err != nil {
+		return "", err
+	}
+	name := s.Name
+	if len(s.Ports) > 0 {
+		name = fmt.Sprintf("%s_port%d", name, fPort)
+	}
+	g := &cExporter{
+		defs:   s.Definitions,
+		endian: s.Endian,
+		prefix: cMacro(name),
+	}
+	g.walk(append(append([]Field{}, s.ResolveHeader(fPort)...), fields...), "", "    ")
+	if g.size == 0 {
+		return "", fmt.Errorf("%s: payload has no fixed-size prefix", name)
+	}
+
+	typeName := cIdent(name) + "_t"
+	guard := cMacro(name) + "_H"
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "/* Generated from payload schema %q", s.Name)
+	if s.Version != 0 {
+		fmt.Fprintf(&sb, " version %d", s.Version)
+	}
+	sb.WriteString(". Raw wire values; modifiers are not applied. */\n")
+	fmt.Fprintf(&sb, "#ifndef %s\n#define %s\n\n#include <stdint.h>\n\n", guard, guard)
+	sb.WriteString(cEndianMacros)
+	sb.WriteString("\n")
+
+	fmt.Fprintf(&sb, "typedef struct __attribute__((packed)) {\n")
+	for _, line := range g.members {
+		sb.WriteString(line + "\n")
+	}
+	if g.rest != "" {
+		fmt.Fprintf(&sb, "    /* variable part follows: %s */\n", g.rest)
+	}
+	fmt.Fprintf(&sb, "} %s;\n\n", typeName)
+	fmt.Fprintf(&sb, "#define %s_SIZE %d\n", g.prefix, g.size)
+	fmt.Fprintf(&sb, "#if defined(__STDC_VERSION__) && __STDC_VERSION__ >= 201112L\n")
+	fmt.Fprintf(&sb, "_Static_assert(sizeof(%s) == %s_SIZE, \"%s must be packed\");\n", typeName, g.prefix, typeName)
+	sb.WriteString("#endif\n")
+
+	for _, section := range [][]string{g.accessors, g.bits, g.constants} {
+		if len(section) == 0 {
+			continue
+		}
+		sb.WriteString("\n")
+		for _, line := range section {
+			sb.WriteString(line + "\n")
+		}
+	}
+	fmt.Fprintf(&sb, "\n#endif /* %s */\n", guard)
+	return sb.String(), nil
+}
+
+type cExporter struct {
+	defs      map[string]*DefinitionDef
+	endian    string
+	prefix    string // Macro prefix, e.g. ENV_SENSOR
+	members   []string
+	accessors []string
+	bits      []string
+	constants []string
+	size      int
+	rest      string // First variable field; empty if the whole payload is fixed
+	depth     int
+}
+
+// walk appends struct members for fields until one varies in size, and
+// reports whether it got through all of them.
+func (g *cExporter) walk(fields []Field, path, indent string) bool {
+	inherited := g.endian
+	defer func() { g.endian = inherited }()
+	for _, f := range fields {
+		g.endian = blockEndian(f, inherited)
+		if !g.field(f, path, indent) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *cExporter) field(f Field, path, indent string) bool {
+	member := path + cIdent(f.Name)
+	macro := g.prefix + "_" + cMacro(strings.ReplaceAll(member, ".", "_"))
+	endian := f.Endian
+	if endian == "" {
+		endian = g.endian
+	}
+
+	switch {
+	case f.Ref2 != "":
+		def, err := lookupDefinition(f.Ref2, g.defs)
+		if err != nil || g.depth >= maxRefDepth {
+			return g.stop(f.Ref2)
+		}
+		g.depth++
+		defer func() { g.depth-- }()
+		return g.walk(def.Fields, path, indent)
+
+	case len(f.ByteGroup) > 0:
+		size := byteGroupSize(f)
+		var names []string
+		for _, m := range f.ByteGroup {
+			if m.Name != "" {
+				names = append(names, cIdent(m.Name))
+			}
+		}
+		groupMember := path + strings.Join(names, "_")
+		g.scalar(groupMember, size, false, "little", indent, "byte group")
+		g.accessor(g.prefix+"_"+cMacro(strings.ReplaceAll(groupMember, ".", "_")), groupMember, size, false, "little")
+		for _, m := range f.ByteGroup {
+			if m.Name == "" {
+				continue
+			}
+			start, length, _ := byteGroupBits(m)
+			g.bitMacros(g.prefix+"_"+cMacro(path+m.Name), start, length)
+			g.tableConstants(g.prefix+"_"+cMacro(path+m.Name), m)
+		}
+		return true
+
+	case f.Flagged != nil:
+		return g.stop("flagged " + f.Flagged.Field)
+	case f.TLVInline != nil, f.WASM != nil, f.MatchInline != nil, readsRest(f):
+		return g.stop(pathOr(path+f.Name, "(variable)"))
+	}
+
+	switch f.Type {
+	case TypeNumber, "number":
+		return true
+	case TypeBool, TypeBoolLower, TypeBits, TypeBitsLower:
+		start, length := f.Bit, 1
+		if f.Type == TypeBits || f.Type == TypeBitsLower {
+			start, length = f.BitOffset, f.Bits
+		}
+		if f.Name != "" {
+			g.bitMacros(macro, start, length)
+		}
+		if n := consumeLength(f, 1); n > 0 {
+			g.array(fmt.Sprintf("_bits%d", g.size), "uint8_t", n, indent, "")
+		}
+		return true
+	case TypeObject:
+		g.members = append(g.members, indent+"struct {")
+		ok := g.walk(f.Fields, member+".", indent+"    ")
+		g.members = append(g.members, fmt.Sprintf("%s} %s;", indent, cIdent(f.Name)))
+		return ok
+	case TypeVector3:
+		g.members = append(g.members, indent+"struct {")
+		ok := g.walk(vectorAxes(f), member+".", indent+"    ")
+		g.members = append(g.members, fmt.Sprintf("%s} %s;", indent, cIdent(f.Name)))
+		return ok
+	case TypeRepeat, TypeRepeatLower:
+		return g.repeat(f, path, indent)
+	case TypeSkip, TypeSkipLower, TypeReserved, TypeReservedLower, TypeMagic, TypeMagicLower:
+		g.array(fmt.Sprintf("_reserved%d", g.size), "uint8_t", fieldLength(f), indent, "")
+		return true
+	case TypeMatch, TypeMatchLower, "CTRL-SWITCH", "Switch", TypeTLV, TypeTLVLower:
+		return g.stop(pathOr(path+f.Name, "(variable)"))
+	}
+
+	if isPositionalView(f) {
+		// Views re-read bytes already in the struct
+		return true
+	}
+	if f.ByteOrder != "" {
+		g.array(member, "uint8_t", fieldLength(f), indent, "byte_order "+f.ByteOrder+", reorder before use")
+		return true
+	}
+
+	switch f.Type {
+	case TypeAscii, TypeAsciiLower, TypeString, TypeStringLower:
+		if f.Length == 0 {
+			return g.stop(path + f.Name)
+		}
+		g.array(member, "char", f.Length, indent, "not NUL-terminated")
+	case TypeHex, TypeBytes, TypeBytesLower:
+		if f.Length == 0 {
+			return g.stop(path + f.Name)
+		}
+		g.array(member, "uint8_t", f.Length, indent, "")
+	case TypeFloat16, TypeF16:
+		g.scalar(member, 2, false, endian, indent, "IEEE 754 binary16 bits")
+		g.accessor(macro, member, 2, false, endian)
+	case TypeFloat32, TypeF32:
+		g.scalar(member, 4, false, endian, indent, "IEEE 754 binary32 bits")
+		g.accessor(macro, member, 4, false, endian)
+	case TypeFloat64, TypeF64:
+		g.scalar(member, 8, false, endian, indent, "IEEE 754 binary64 bits")
+		g.accessor(macro, member, 8, false, endian)
+	case TypeEnum, TypeEnumLower:
+		n := enumBaseLength(f)
+		g.scalar(member, n, strings.HasPrefix(f.Base, "s"), endian, indent, "")
+		g.accessor(macro, member, n, strings.HasPrefix(f.Base, "s"), endian)
+		g.tableConstants(macro, f)
+	case TypeFixed, TypeUFixed:
+		n := fixedLength(f)
+		g.scalar(member, n, f.Type == TypeFixed, endian, indent, fmt.Sprintf("Q%d.%d", f.IntBits, f.FracBits))
+		g.accessor(macro, member, n, f.Type == TypeFixed, endian)
+	case TypeBInt:
+		g.scalar(member, fieldLength(f), false, "big", indent, "")
+		g.accessor(macro, member, fieldLength(f), false, "big")
+	case TypeBitfieldString:
+		g.scalar(member, fieldLength(f), false, endian, indent, "")
+		g.accessor(macro, member, fieldLength(f), false, endian)
+	default:
+		n := fieldLength(f)
+		if n == 0 {
+			return g.stop(path + f.Name)
+		}
+		signed := isSignedGoldenType(f.Type)
+		g.scalar(member, n, signed, endian, indent, "")
+		g.accessor(macro, member, n, signed, endian)
+		g.tableConstants(macro, f)
+	}
+	return true
+}
+
+func (g *cExporter) repeat(f Field, path, indent string) bool {
+	n, ok := toWholeInt(f.Count)
+	if !ok || f.Count == nil {
+		return g.stop(path + f.Name)
+	}
+	// Only a fixed count of fixed-size elements belongs in the struct
+	body := &cExporter{defs: g.defs, endian: g.endian, prefix: g.prefix, depth: g.depth}
+	if !body.walk(f.Fields, "", indent+"    ") || body.size == 0 {
+		return g.stop(path + f.Name)
+	}
+	g.members = append(g.members, indent+"struct {")
+	g.members = append(g.members, body.members...)
+	g.members = append(g.members, fmt.Sprintf("%s} %s[%d];", indent, cIdent(f.Name), n))
+	g.bits = append(g.bits, body.bits...)
+	g.constants = append(g.constants, body.constants...)
+	g.size += n * body.size
+	return true
+}
+
+func (g *cExporter) stop(what string) bool {
+	g.rest = what
+	return false
+}
+
+func (g *cExporter) scalar(member string, size int, signed bool, endian, indent, note string) {
+	name := member[strings.LastIndex(member, ".")+1:]
+	if size != 1 && size != 2 && size != 4 && size != 8 {
+		g.array(member, "uint8_t", size, indent, endian+"-endian")
+		return
+	}
+	ctype := fmt.Sprintf("uint%d_t", size*8)
+	if signed {
+		ctype = ctype[1:]
+	}
+	var comments []string
+	if size > 1 {
+		comments = append(comments, endian+"-endian")
+	}
+	if note != "" {
+		comments = append(comments, note)
+	}
+	line := fmt.Sprintf("%s%s %s;", indent, ctype, name)
+	if len(comments) > 0 {
+		line += " /* " + strings.Join(comments, ", ") + " */"
+	}
+	g.members = append(g.members, line)
+	g.size += size
+}
+
+func (g *cExporter) array(member, ctype string, n int, indent, note string) {
+	name := member[strings.LastIndex(member, ".")+1:]
+	line := fmt.Sprintf("%s%s %s[%d];", indent, ctype, name, n)
+	if note != "" {
+		line += " /* " + note + " */"
+	}
+	g.members = append(g.members, line)
+	g.size += n
+}
+
+// accessor defines a macro reading a multi-byte member in host order.
+func (g *cExporter) accessor(macro, member string, size int, signed bool, endian string) {
+	if size != 2 && size != 4 && size != 8 {
+		return
+	}
+	conv := "PS_BE"
+	if endian == "little" {
+		conv = "PS_LE"
+	}
+	expr := fmt.Sprintf("%s%d((p)->%s)", conv, size*8, member)
+	if signed {
+		expr = fmt.Sprintf("(int%d_t)%s", size*8, expr)
+	}
+	g.accessors = append(g.accessors, fmt.Sprintf("#define %s(p) (%s)", macro, expr))
+}
+
+func (g *cExporter) bitMacros(macro string, start, length int) {
+	g.bits = append(g.bits,
+		fmt.Sprintf("#define %s_SHIFT %d", macro, start),
+		fmt.Sprintf("#define %s_MASK 0x%XU", macro, uint64(1)<<length-1))
+}
+
+// tableConstants defines a constant per enum value or lookup entry.
+func (g *cExporter) tableConstants(macro string, f Field) {
+	table := f.Values
+	if table == nil {
+		table = f.Lookup
+	}
+	keys := make([]int, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		g.constants = append(g.constants, fmt.Sprintf("#define %s_%s %d", macro, cMacro(table[k]), k))
+	}
+}
+
+var cInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// cIdent turns a schema name into a C identifier.
+func cIdent(name string) string {
+	id := strings.Trim(cInvalidChars.ReplaceAllString(name, "_"), "_")
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "_" + id
+	}
+	return strings.ToLower(id)
+}
+
+func cMacro(name string) string {
+	return strings.ToUpper(strings.TrimPrefix(cIdent(name), "_"))
+}
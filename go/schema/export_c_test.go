@@ -0,0 +1,128 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportC(t *testing.T) {
+	s, err := ParseSchema(`
+name: env-sensor
+endian: big
+fields:
+  - name: version
+    type: u8
+  - name: temperature
+    type: s16
+    div: 10
+  - byte_group:
+      - {name: mode, type: "u8[0:2]", lookup: {0: idle, 1: active}}
+      - {name: alarm, type: "u8[7:7]"}
+  - name: pressure
+    type: u24
+  - name: counter
+    type: u32
+    endian: little
+  - type: skip
+    length: 2
+  - name: position
+    type: Object
+    fields:
+      - {name: lat, type: s32}
+      - {name: lon, type: s32}
+  - name: status
+    type: enum
+    values: {0: ok, 1: low battery}
+  - name: samples
+    type: repeat
+    count: 2
+    fields:
+      - {name: v, type: u16}
+  - name: celsius
+    type: number
+    ref: $temperature
+  - name: flags
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - {name: battery, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	out, err := s.ExportC(0)
+	if err != nil {
+		t.Fatalf("ExportC() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"#ifndef ENV_SENSOR_H",
+		"typedef struct __attribute__((packed)) {",
+		"    int16_t temperature; /* big-endian */",
+		"    uint8_t mode_alarm; /* byte group */",
+		"    uint8_t pressure[3]; /* big-endian */",
+		"    uint32_t counter; /* little-endian */",
+		"    uint8_t _reserved11[2];",
+		"        int32_t lat; /* big-endian */",
+		"    } position;",
+		"    } samples[2];",
+		"    /* variable part follows: flagged flags */",
+		"} env_sensor_t;",
+		"#define ENV_SENSOR_SIZE 27",
+		"#define ENV_SENSOR_TEMPERATURE(p) ((int16_t)PS_BE16((p)->temperature))",
+		"#define ENV_SENSOR_COUNTER(p) (PS_LE32((p)->counter))",
+		"#define ENV_SENSOR_POSITION_LAT(p) ((int32_t)PS_BE32((p)->position.lat))",
+		"#define ENV_SENSOR_MODE_MASK 0x7U",
+		"#define ENV_SENSOR_ALARM_SHIFT 7",
+		"#define ENV_SENSOR_MODE_ACTIVE 1",
+		"#define ENV_SENSOR_STATUS_LOW_BATTERY 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ExportC() missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "celsius") || strings.Contains(out, "battery;") {
+		t.Errorf("ExportC() includes computed or variable fields\n%s", out)
+	}
+}
+
+func TestExportCPorts(t *testing.T) {
+	s, err := ParseSchema(`
+name: tracker
+ports:
+  1:
+    direction: uplink
+    fields:
+      - {name: kind, type: u8}
+      - type: Match
+        on: $kind
+        cases:
+          - case: 1
+            fields:
+              - {name: lat, type: s32}
+  2:
+    direction: uplink
+    fields:
+      - {name: data, type: bytes}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	out, err := s.ExportC(1)
+	if err != nil {
+		t.Fatalf("ExportC(1) error = %v", err)
+	}
+	if !strings.Contains(out, "} tracker_port1_t;") || !strings.Contains(out, "#define TRACKER_PORT1_SIZE 1") {
+		t.Errorf("ExportC(1) =\n%s", out)
+	}
+	if _, err := s.ExportC(2); err == nil {
+		t.Error("ExportC(2) succeeded for a payload with no fixed prefix")
+	}
+}
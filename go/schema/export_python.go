@@ -0,0 +1,860 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pyRuntime is the support code every generated Python module carries.
+// _round and _fmt_num follow the Go decoder (half away from zero, shortest
+// number text) rather than Python's round() and repr().
+const pyRuntime = `import base64
+import math
+import struct
+
+_OMIT = object()
+
+
+class _Reader:
+    def __init__(self, data):
+        self.data = bytes(data)
+        self.pos = 0
+
+    def read(self, n, offset=0, consume=None):
+        start = self.pos + offset
+        if start < 0 or start + n > len(self.data):
+            raise ValueError("payload too short: need %d bytes at offset %d" % (n, start))
+        self.pos += n if consume is None else consume
+        return self.data[start:start + n]
+
+    def uint(self, n, little=False, offset=0, consume=None):
+        return int.from_bytes(self.read(n, offset, consume), "little" if little else "big")
+
+    def sint(self, n, little=False, offset=0, consume=None):
+        return int.from_bytes(self.read(n, offset, consume), "little" if little else "big", signed=True)
+
+    def float(self, n, little=False, offset=0, consume=None):
+        fmt = ("<" if little else ">") + {2: "e", 4: "f", 8: "d"}[n]
+        return struct.unpack(fmt, self.read(n, offset, consume))[0]
+
+    def remaining(self):
+        return len(self.data) - self.pos
+
+
+def _round(x, places):
+    if math.isnan(x) or math.isinf(x):
+        return x
+    scale = 10 ** places
+    return math.copysign(math.floor(abs(x) * scale + 0.5), x) / scale
+
+
+def _precision(x, digits):
+    if x == 0 or math.isnan(x) or math.isinf(x):
+        return x
+    return float("%.*g" % (digits, x))
+
+
+def _fmt_num(x, places=None):
+    if places is not None:
+        return "%.*f" % (places, x)
+    text = repr(float(x))
+    return text[:-2] if text.endswith(".0") else text
+
+
+def _int(x):
+    try:
+        return int(x)
+    except (TypeError, ValueError):
+        return 0
+
+
+def _lookup(table, x, name, unknown=None):
+    key = int(x) if not isinstance(x, bool) and x == int(x) else x
+    if key in table:
+        return table[key]
+    if unknown == "null":
+        return _OMIT
+    if unknown == "error":
+        raise ValueError("field %s: unknown value %s" % (name, _fmt_num(x)))
+    if unknown is not None:
+        return unknown % key
+    return x
+
+
+def _lookup_array(items, x, name, unknown=None):
+    key = int(x)
+    if 0 <= key < len(items):
+        return items[key]
+    return _lookup({}, x, name, unknown)
+`
+
+// ExportPython renders the schema as a standalone Python 3 module with a
+// decode(payload, fport=0) function that returns the same values as
+// Decode/DecodeWithPort, modifiers and lookups included. The module
+// depends only on the standard library, so offline analysis of payload
+// archives stays in step with the schema.
+//
+// Fields with no direct Python equivalent (TLV, WASM, formula, compute,
+// guard, curve, byte_order and expression selectors) are reported as
+// errors rather than exported with different behavior.
+func (s *Schema) ExportPython() (string, error) {
+	if s.Frames != nil {
+		return "", fmt.Errorf("frames cannot be exported to Python")
+	}
+	for _, key := range sortedKeys(s.Ports) {
+		if s.Ports[key].Fallback != "" {
+			return "", fmt.Errorf("port %s: fallback cannot be exported to Python", key)
+		}
+	}
+	g := &pyExporter{defs: s.Definitions, endian: s.Endian}
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "\"\"\"Decoder for payload schema %s.\n\n", strconv.Quote(s.Name))
+	b.WriteString("Generated from the schema; do not edit. decode(payload, fport) returns the\n")
+	b.WriteString("same values as the reference decoder, modifiers and lookups applied.\n\"\"\"\n\n")
+	b.WriteString(pyRuntime)
+
+	var funcs strings.Builder
+	emitFunc := func(name string, fields []Field) error {
+		g.lines = nil
+		if err := g.fields(fields, "out", 1); err != nil {
+			return err
+		}
+		fmt.Fprintf(&funcs, "\n\ndef %s(r, out, v):\n", name)
+		if len(g.lines) == 0 {
+			funcs.WriteString("    pass\n")
+		}
+		for _, line := range g.lines {
+			funcs.WriteString(line + "\n")
+		}
+		return nil
+	}
+
+	// With per-port header: overrides, each port function decodes its own
+	// header
+	portHeaders := false
+	for _, pd := range s.Ports {
+		portHeaders = portHeaders || pd.Header != nil
+	}
+	if len(s.Header) > 0 && !portHeaders {
+		if err := emitFunc("_decode_header", s.Header); err != nil {
+			return "", fmt.Errorf("header: %w", err)
+		}
+	}
+	var portKeys []string
+	if len(s.Ports) == 0 {
+		if err := emitFunc("_decode_fields", s.topFields()); err != nil {
+			return "", err
+		}
+	} else {
+		// The decoder uses "N:up" entries, else "N"
+		for key := range s.Ports {
+			_, dir, err := parsePortKey(key)
+			if err != nil || dir == PortDown || dir == "" && s.Ports[key+":"+PortUp] != nil {
+				continue
+			}
+			portKeys = append(portKeys, key)
+		}
+		sort.Slice(portKeys, func(i, j int) bool {
+			a, _, _ := parsePortKey(portKeys[i])
+			b, _, _ := parsePortKey(portKeys[j])
+			if a < 0 || b < 0 {
+				return b < 0 && a >= 0
+			}
+			return a < b
+		})
+		for _, key := range portKeys {
+			fields := s.Ports[key].Fields
+			if portHeaders {
+				fields = append(append([]Field{}, s.portHeader(s.Ports[key])...), fields...)
+			}
+			if err := emitFunc(pyPortFunc(key), fields); err != nil {
+				return "", fmt.Errorf("port %s: %w", key, err)
+			}
+		}
+	}
+
+	if len(g.tables) > 0 {
+		b.WriteString("\n")
+		for _, t := range g.tables {
+			b.WriteString("\n" + t)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(funcs.String())
+
+	if len(portKeys) > 0 {
+		b.WriteString("\n\n_PORTS = {\n")
+		for _, key := range portKeys {
+			if port, _, _ := parsePortKey(key); port >= 0 {
+				fmt.Fprintf(&b, "    %d: %s,\n", port, pyPortFunc(key))
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\n\ndef decode(payload, fport=0):\n")
+	b.WriteString("    \"\"\"Decode a payload (bytes) received on fport into a dict.\"\"\"\n")
+	b.WriteString("    r = _Reader(payload)\n    out = {}\n    v = {}\n")
+	if len(s.Header) > 0 && !portHeaders {
+		b.WriteString("    _decode_header(r, out, v)\n")
+	}
+	switch {
+	case len(portKeys) == 0:
+		b.WriteString("    _decode_fields(r, out, v)\n")
+	case s.Ports["default"] != nil:
+		b.WriteString("    _PORTS.get(fport, _decode_port_default)(r, out, v)\n")
+	default:
+		b.WriteString("    if fport not in _PORTS:\n")
+		fmt.Fprintf(&b, "        raise ValueError(\"no port definition for fPort %%d in schema '%s'\" %% fport)\n", s.Name)
+		b.WriteString("    _PORTS[fport](r, out, v)\n")
+	}
+	b.WriteString("    return out\n")
+	return b.String(), nil
+}
+
+// pyPortFunc names the decode function of a ports: entry.
+func pyPortFunc(key string) string {
+	return "_decode_port_" + strings.ReplaceAll(key, ":", "_")
+}
+
+type pyExporter struct {
+	defs   map[string]*DefinitionDef
+	endian string
+	lines  []string
+	tables []string // Module-level lookup tables
+	tmp    int
+	depth  int
+}
+
+func (g *pyExporter) line(indent int, format string, args ...any) {
+	g.lines = append(g.lines, strings.Repeat("    ", indent)+fmt.Sprintf(format, args...))
+}
+
+// temp returns a fresh local variable name.
+func (g *pyExporter) temp(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+func (g *pyExporter) fields(fields []Field, target string, indent int) error {
+	inherited := g.endian
+	defer func() { g.endian = inherited }()
+	for _, f := range fields {
+		g.endian = blockEndian(f, inherited)
+		if err := g.field(f, target, indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *pyExporter) field(f Field, target string, indent int) error {
+	unsupported := func(what string) error {
+		return fmt.Errorf("field %s: %s cannot be exported to Python", pathOr(f.Name, "("+string(f.Type)+")"), what)
+	}
+	switch {
+	case f.Ref2 != "":
+		def, err := lookupDefinition(f.Ref2, g.defs)
+		if err != nil {
+			return err
+		}
+		if g.depth >= maxRefDepth {
+			return fmt.Errorf("$ref %s: nested deeper than %d (circular reference?)", f.Ref2, maxRefDepth)
+		}
+		g.depth++
+		defer func() { g.depth-- }()
+		return g.fields(def.Fields, target, indent)
+	case f.TLVInline != nil || f.Type == TypeTLV || f.Type == TypeTLVLower:
+		return unsupported("tlv")
+	case f.WASM != nil:
+		return unsupported("wasm")
+	case f.Compute != nil:
+		return unsupported("compute")
+	case f.Guard != nil:
+		return unsupported("guard")
+	case f.Formula != "":
+		return unsupported("formula")
+	case f.Curve != nil:
+		return unsupported("curve")
+	case len(f.AlsoEmit) > 0:
+		return unsupported("also_emit")
+	case f.ScaleBy != "":
+		return unsupported("scale_by")
+	case f.ByteOrder != "":
+		return unsupported("byte_order")
+	case len(f.Invalid) > 0:
+		return unsupported("invalid")
+	case f.Scope == ScopeLocal || f.Scope == ScopeParent:
+		return unsupported("scope")
+	case f.Output != "":
+		return unsupported("output")
+	case f.NonFinite != "" && f.NonFinite != NonFiniteKeep:
+		return unsupported("non_finite")
+	case readsRest(f):
+		return unsupported("length: rest")
+	case len(f.ByteGroup) > 0:
+		return g.byteGroup(f, target, indent)
+	case f.Flagged != nil:
+		flags := g.temp("flags")
+		g.line(indent, "%s = _int(v[%s])", flags, strconv.Quote(f.Flagged.Field))
+		for _, grp := range f.Flagged.Groups {
+			g.line(indent, "if (%s >> %d) & 1:", flags, grp.Bit)
+			if err := g.block(grp.Fields, target, indent+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case f.MatchInline != nil:
+		return g.match(*f.MatchInline, target, indent)
+	}
+
+	endian := f.Endian
+	if endian == "" {
+		endian = g.endian
+	}
+	little := pyBool(endian == "little")
+	length := fieldLength(f)
+	x := g.temp("x")
+
+	switch f.Type {
+	case TypeObject:
+		g.line(indent, "%s = {}", x)
+		if err := g.fields(f.Fields, x, indent); err != nil {
+			return err
+		}
+		g.store(f, x, target, indent)
+		return nil
+	case TypeMatch, "CTRL-SWITCH", "Switch":
+		g.line(indent, "%s = {}", x)
+		if err := g.match(f, x, indent); err != nil {
+			return err
+		}
+		g.store(f, x, target, indent)
+		return nil
+	case TypeRepeat, TypeRepeatLower:
+		if err := g.repeat(f, x, indent); err != nil {
+			return err
+		}
+		g.store(f, x, target, indent)
+		return nil
+	case TypeSkip, TypeSkipLower, TypeReserved, TypeReservedLower:
+		g.line(indent, "r.read(%d)", length)
+		return nil
+	case TypeMagic, TypeMagicLower:
+		if f.Mismatch == MismatchWarn {
+			g.line(indent, "r.read(%d)", length)
+			return nil
+		}
+		g.line(indent, "if r.read(%d) != bytes.fromhex(%q):", length, hex.EncodeToString(f.Expect))
+		g.line(indent+1, "raise ValueError(\"magic bytes at offset %%d do not match\" %% (r.pos - %d))", length)
+		return nil
+	case TypeNumber, "number":
+		if f.Ref == "" {
+			if f.Value == nil {
+				return nil
+			}
+			g.line(indent, "%s = %s", x, pyLiteral(f.Value))
+			g.store(f, x, target, indent)
+			return nil
+		}
+		g.line(indent, "%s = float(v[%s])", x, strconv.Quote(strings.TrimPrefix(f.Ref, "$")))
+		if len(f.Polynomial) > 0 {
+			terms := make([]string, len(f.Polynomial))
+			for i, c := range f.Polynomial {
+				terms[i] = pyFloat(c)
+			}
+			acc := g.temp("acc")
+			g.line(indent, "%s = 0.0", acc)
+			g.line(indent, "for c in (%s,):", strings.Join(terms, ", "))
+			g.line(indent+1, "%s = %s * %s + c", acc, acc, x)
+			g.line(indent, "%s = %s", x, acc)
+		}
+		g.stages(f.Transform, x, indent)
+		// Ref fields apply top-level modifiers as mult, div, add
+		for _, op := range []struct {
+			sym string
+			val *float64
+		}{{"*", f.Mult}, {"/", f.Div}, {"+", f.Add}} {
+			if op.val != nil && (op.sym != "/" || *op.val != 0) {
+				g.line(indent, "%s = %s %s %s", x, x, op.sym, pyFloat(*op.val))
+			}
+		}
+		return g.finish(f, x, target, indent, false)
+	}
+
+	// Positional views and bit reads pass offset/consume through
+	read := func(method string, n int) string {
+		args := fmt.Sprintf("%d, %s", n, little)
+		consume := consumeLength(f, n)
+		if f.ByteOffset != 0 || consume != n {
+			args += fmt.Sprintf(", %d, %d", f.ByteOffset, consume)
+		}
+		return fmt.Sprintf("r.%s(%s)", method, args)
+	}
+
+	switch f.Type {
+	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU24, TypeU32, TypeU64:
+		g.line(indent, "%s = %s", x, read("uint", length))
+	case TypeSInt, TypeS8, TypeS16, TypeS24, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64:
+		g.line(indent, "%s = %s", x, read("sint", length))
+	case TypeBInt:
+		little = "False"
+		g.line(indent, "%s = %s", x, read("uint", length))
+	case TypeFloat16, TypeF16:
+		g.line(indent, "%s = %s", x, read("float", 2))
+	case TypeFloat32, TypeF32:
+		g.line(indent, "%s = %s", x, read("float", 4))
+	case TypeFloat64, TypeF64:
+		g.line(indent, "%s = %s", x, read("float", 8))
+	case TypeFixed:
+		g.line(indent, "%s = math.ldexp(%s, -%d)", x, read("sint", fixedLength(f)), f.FracBits)
+	case TypeUFixed:
+		g.line(indent, "%s = math.ldexp(%s, -%d)", x, read("uint", fixedLength(f)), f.FracBits)
+	case TypeBool, TypeBoolLower:
+		g.line(indent, "%s = (%s >> %d) & 1 != 0", x, read("uint", 1), f.Bit)
+		return g.finish(f, x, target, indent, false)
+	case TypeBits, TypeBitsLower:
+		g.line(indent, "%s = (%s >> %d) & 0x%X", x, read("uint", bitsLength(f)), f.BitOffset, uint64(1)<<bitsWidth(f)-1)
+	case TypeString, TypeStringLower, TypeAscii, TypeAsciiLower:
+		if isConstString(f) {
+			if f.Const != nil {
+				g.line(indent, "%s = %s", x, pyLiteral(f.Const))
+				g.store(f, x, target, indent)
+			}
+			return nil
+		}
+		strip := `"\x00"`
+		if f.Pad == PadSpace {
+			strip = `" \x00"`
+		}
+		codec := map[string]string{EncodingASCII: "ascii", EncodingLatin1: "latin-1"}[f.Encoding]
+		if f.Encoding == EncodingUCS2 {
+			codec = "utf-16-be"
+			if endian == "little" {
+				codec = "utf-16-le"
+			}
+		} else if codec == "" {
+			codec = "utf-8"
+		}
+		errors := "replace"
+		if f.InvalidChars == InvalidCharsError {
+			errors = "strict"
+		}
+		g.line(indent, "%s = r.read(%d).decode(%q, %q).rstrip(%s)", x, length, codec, errors, strip)
+		g.store(f, x, target, indent)
+		return nil
+	case TypeHex:
+		g.line(indent, "%s = r.read(%d).hex()", x, length)
+		g.store(f, x, target, indent)
+		return nil
+	case TypeBytes, TypeBytesLower:
+		expr, err := pyBytesFormat(f, fmt.Sprintf("r.read(%d)", length))
+		if err != nil {
+			return unsupported(err.Error())
+		}
+		g.line(indent, "%s = %s", x, expr)
+		g.store(f, x, target, indent)
+		return nil
+	case TypeEnum, TypeEnumLower:
+		g.line(indent, "%s = r.uint(%d, %s)", x, enumBaseLength(f), little)
+		if f.Values != nil {
+			table := g.table(f.Values)
+			g.line(indent, "%s = _lookup(%s, %s, %s%s)", x, table, x, strconv.Quote(f.Name), g.unknownArg(f))
+		}
+		g.omitOr(f, x, target, indent)
+		return nil
+	case TypeBitfieldString:
+		return g.bitfieldString(f, x, target, indent, length, endian)
+	default:
+		return unsupported("type " + string(f.Type))
+	}
+	return g.finish(f, x, target, indent, true)
+}
+
+// block emits fields as the body of an if/else branch.
+func (g *pyExporter) block(fields []Field, target string, indent int) error {
+	start := len(g.lines)
+	if err := g.fields(fields, target, indent); err != nil {
+		return err
+	}
+	if len(g.lines) == start {
+		g.line(indent, "pass")
+	}
+	return nil
+}
+
+// finish applies modifiers, rounding and lookups like finishValue, then
+// stores the value.
+func (g *pyExporter) finish(f Field, x, target string, indent int, modifiers bool) error {
+	if modifiers {
+		switch {
+		case len(f.Transform) > 0:
+			g.stages(f.Transform, x, indent)
+		case len(f.Modifiers) > 0:
+			g.stages(f.Modifiers, x, indent)
+		default:
+			order := f.ModOrder
+			if len(order) == 0 {
+				order = []string{"add", "mult", "div"}
+			}
+			for _, key := range order {
+				switch {
+				case key == "add" && f.Add != nil:
+					g.line(indent, "%s = %s + %s", x, x, pyFloat(*f.Add))
+				case key == "mult" && f.Mult != nil:
+					g.line(indent, "%s = %s * %s", x, x, pyFloat(*f.Mult))
+				case key == "div" && f.Div != nil && *f.Div != 0:
+					g.line(indent, "%s = %s / %s", x, x, pyFloat(*f.Div))
+				}
+			}
+		}
+	}
+	if f.Precision != nil && *f.Precision > 0 {
+		g.line(indent, "%s = _precision(%s, %d)", x, x, *f.Precision)
+	}
+	if f.Round != nil && *f.Round >= 0 {
+		g.line(indent, "%s = _round(%s, %d)", x, x, *f.Round)
+	}
+	if f.Lookup != nil {
+		g.line(indent, "%s = _lookup(%s, %s, %s%s)", x, g.table(f.Lookup), x, strconv.Quote(f.Name), g.unknownArg(f))
+	}
+	if f.LookupArray != nil {
+		items := make([]string, len(f.LookupArray))
+		for i, item := range f.LookupArray {
+			items[i] = pyLiteral(item)
+		}
+		g.line(indent, "%s = _lookup_array((%s,), %s, %s%s)", x, strings.Join(items, ", "), x, strconv.Quote(f.Name), g.unknownArg(f))
+	}
+	g.omitOr(f, x, target, indent)
+	return nil
+}
+
+// omitOr stores x unless an unknown: null policy dropped it.
+func (g *pyExporter) omitOr(f Field, x, target string, indent int) {
+	if f.Unknown == UnknownNull && (f.Lookup != nil || f.LookupArray != nil || f.Values != nil) {
+		g.line(indent, "if %s is not _OMIT:", x)
+		indent++
+	}
+	if f.Var != "" {
+		g.line(indent, "v[%s] = %s", strconv.Quote(f.Var), x)
+	}
+	if f.AsString {
+		g.line(indent, "if isinstance(%s, float):", x)
+		if f.Round != nil && *f.Round >= 0 {
+			g.line(indent+1, "%s = _fmt_num(%s, %d)", x, x, *f.Round)
+		} else {
+			g.line(indent+1, "%s = _fmt_num(%s)", x, x)
+		}
+	}
+	g.store(f, x, target, indent)
+}
+
+func (g *pyExporter) store(f Field, x, target string, indent int) {
+	if f.Name == "" {
+		return
+	}
+	name := strconv.Quote(f.Name)
+	g.line(indent, "%s[%s] = %s", target, name, x)
+	g.line(indent, "v[%s] = %s", name, x)
+}
+
+func (g *pyExporter) stages(stages []Transform, x string, indent int) {
+	for _, st := range stages {
+		if st.Sub != nil {
+			g.line(indent, "%s = %s - %s", x, x, pyFloat(*st.Sub))
+		}
+		if st.Add != nil {
+			g.line(indent, "%s = %s + %s", x, x, pyFloat(*st.Add))
+		}
+		if st.Mult != nil {
+			g.line(indent, "%s = %s * %s", x, x, pyFloat(*st.Mult))
+		}
+		if st.Div != nil && *st.Div != 0 {
+			g.line(indent, "%s = %s / %s", x, x, pyFloat(*st.Div))
+		}
+	}
+}
+
+func (g *pyExporter) byteGroup(f Field, target string, indent int) error {
+	raw := g.temp("group")
+	g.line(indent, "%s = r.uint(%d, True)", raw, byteGroupSize(f))
+	for _, m := range f.ByteGroup {
+		start, length, _ := byteGroupBits(m)
+		x := g.temp("x")
+		if m.Type == TypeBool || m.Type == TypeBoolLower {
+			g.line(indent, "%s = (%s >> %d) & 0x%X != 0", x, raw, start, uint64(1)<<length-1)
+			if err := g.finish(m, x, target, indent, false); err != nil {
+				return err
+			}
+			continue
+		}
+		g.line(indent, "%s = (%s >> %d) & 0x%X", x, raw, start, uint64(1)<<length-1)
+		if err := g.finish(m, x, target, indent, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *pyExporter) match(f Field, target string, indent int) error {
+	sel := g.temp("sel")
+	switch {
+	case f.On == "":
+		length := f.Length
+		if length == 0 {
+			length = 1
+		}
+		g.line(indent, "%s = r.uint(%d, %s)", sel, length, pyBool(g.endian == "little"))
+	case bareVarPattern.MatchString(f.On):
+		g.line(indent, "%s = _int(v[%s])", sel, strconv.Quote(strings.TrimPrefix(f.On, "$")))
+	default:
+		return fmt.Errorf("match on %q: expression selectors cannot be exported to Python", f.On)
+	}
+
+	keyword := "if"
+	for _, c := range f.Cases {
+		if c.Default {
+			if keyword == "if" {
+				// A leading default always applies
+				return g.fields(c.Fields, target, indent)
+			}
+			g.line(indent, "else:")
+			return g.block(c.Fields, target, indent+1)
+		}
+		cond, ok := pyCaseCondition(c, sel)
+		if !ok {
+			continue
+		}
+		g.line(indent, "%s %s:", keyword, cond)
+		if err := g.block(c.Fields, target, indent+1); err != nil {
+			return err
+		}
+		keyword = "elif"
+	}
+	if f.revisions {
+		name := strings.TrimPrefix(f.On, "$")
+		if keyword == "if" {
+			g.line(indent, "raise ValueError(\"no revision for %s = %%d\" %% %s)", name, sel)
+		} else {
+			g.line(indent, "else:")
+			g.line(indent+1, "raise ValueError(\"no revision for %s = %%d\" %% %s)", name, sel)
+		}
+	}
+	return nil
+}
+
+func pyCaseCondition(c Case, sel string) (string, bool) {
+	v := c.Case
+	if v == nil {
+		v = c.Match
+	}
+	switch cv := v.(type) {
+	case []any:
+		var items []string
+		for _, item := range cv {
+			if n, ok := toInt(item); ok {
+				items = append(items, strconv.Itoa(n))
+			}
+		}
+		return fmt.Sprintf("%s in (%s,)", sel, strings.Join(items, ", ")), len(items) > 0
+	case map[string]any:
+		var parts []string
+		if m, ok := cv["min"]; ok {
+			n, _ := toInt(m)
+			parts = append(parts, fmt.Sprintf("%s >= %d", sel, n))
+		}
+		if m, ok := cv["max"]; ok {
+			n, _ := toInt(m)
+			parts = append(parts, fmt.Sprintf("%s <= %d", sel, n))
+		}
+		if len(parts) == 0 {
+			return "True", true
+		}
+		return strings.Join(parts, " and "), true
+	}
+	n, ok := toInt(v)
+	return fmt.Sprintf("%s == %d", sel, n), ok
+}
+
+func (g *pyExporter) repeat(f Field, x string, indent int) error {
+	limit := f.Max
+	if limit == 0 {
+		limit = 1000
+	}
+	e := g.temp("e")
+	g.line(indent, "%s = []", x)
+	switch {
+	case f.Count != nil:
+		count := ""
+		switch c := f.Count.(type) {
+		case string:
+			count = fmt.Sprintf("_int(v[%s])", strconv.Quote(strings.TrimPrefix(c, "$")))
+		default:
+			n, ok := toWholeInt(c)
+			if !ok {
+				return fmt.Errorf("repeat %s: invalid count %v", f.Name, c)
+			}
+			count = strconv.Itoa(n)
+		}
+		g.line(indent, "for _ in range(min(%s, %d)):", count, limit)
+	case f.ByteLength != nil:
+		size := ""
+		switch bl := f.ByteLength.(type) {
+		case string:
+			size = fmt.Sprintf("_int(v[%s])", strconv.Quote(strings.TrimPrefix(bl, "$")))
+		default:
+			n, ok := toWholeInt(bl)
+			if !ok {
+				return fmt.Errorf("repeat %s: invalid byte_length %v", f.Name, bl)
+			}
+			size = strconv.Itoa(n)
+		}
+		end := g.temp("end")
+		g.line(indent, "%s = r.pos + %s", end, size)
+		g.line(indent, "while r.pos < %s and len(%s) < %d:", end, x, limit)
+		defer func() {
+			g.line(indent, "if r.pos != %s:", end)
+			g.line(indent+1, "raise ValueError(\"repeat byte_length mismatch: expected end at %%d, got %%d\" %% (%s, r.pos))", end)
+			g.repeatTail(f, x, indent)
+		}()
+	case f.Until == "end":
+		g.line(indent, "while r.remaining() > 0 and len(%s) < %d:", x, limit)
+	default:
+		return fmt.Errorf("repeat %s: must specify one of count, byte_length or until", f.Name)
+	}
+
+	g.line(indent+1, "%s = {}", e)
+	if err := g.fields(f.Fields, e, indent+1); err != nil {
+		return err
+	}
+	g.line(indent+1, "%s.append(%s)", x, e)
+	if f.ByteLength == nil {
+		g.repeatTail(f, x, indent)
+	}
+	return nil
+}
+
+// repeatTail checks min: and applies flatten: after the loop.
+func (g *pyExporter) repeatTail(f Field, x string, indent int) {
+	if f.Min > 0 {
+		g.line(indent, "if len(%s) < %d:", x, f.Min)
+		g.line(indent+1, "raise ValueError(\"repeat produced %%d elements, but minimum is %d\" %% len(%s))", f.Min, x)
+	}
+	if f.Flatten {
+		if name, err := flattenName(f); err == nil {
+			g.line(indent, "%s = [e.get(%s) for e in %s]", x, strconv.Quote(name), x)
+		}
+	}
+}
+
+func (g *pyExporter) bitfieldString(f Field, x, target string, indent, length int, endian string) error {
+	raw := g.temp("raw")
+	g.line(indent, "%s = r.uint(%d, %s)", raw, length, pyBool(endian == "little"))
+	var parts []string
+	for _, part := range f.Parts {
+		if len(part) < 2 {
+			continue
+		}
+		off, _ := toInt(part[0])
+		n, _ := toInt(part[1])
+		format := "%d"
+		if len(part) >= 3 && part[2] == "hex" {
+			format = "%X"
+		}
+		parts = append(parts, fmt.Sprintf("\"%s\" %% ((%s >> %d) & 0x%X)", format, raw, off, uint64(1)<<n-1))
+	}
+	delimiter := f.Delimiter
+	if delimiter == "" {
+		delimiter = "."
+	}
+	g.line(indent, "%s = %s + %s.join([%s])", x, strconv.Quote(f.Prefix), strconv.Quote(delimiter), strings.Join(parts, ", "))
+	g.store(f, x, target, indent)
+	return nil
+}
+
+// table registers a lookup table as a module constant.
+func (g *pyExporter) table(m map[int]string) string {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		entries[i] = fmt.Sprintf("%d: %s", k, strconv.Quote(m[k]))
+	}
+	name := fmt.Sprintf("_TABLE_%d", len(g.tables))
+	g.tables = append(g.tables, fmt.Sprintf("%s = {%s}", name, strings.Join(entries, ", ")))
+	return name
+}
+
+func (g *pyExporter) unknownArg(f Field) string {
+	switch f.Unknown {
+	case "", UnknownRaw:
+		return ""
+	case UnknownNull, UnknownError:
+		return ", " + strconv.Quote(f.Unknown)
+	}
+	if format, ok := unknownLabelFormat(f.Unknown); ok {
+		return ", " + strconv.Quote(format)
+	}
+	return ""
+}
+
+func pyBytesFormat(f Field, data string) (string, error) {
+	switch f.Format {
+	case "", "hex", "hex:lower", "hex:upper":
+		expr := data + ".hex()"
+		if f.Separator != "" {
+			expr = fmt.Sprintf("%s.hex(%s)", data, strconv.Quote(f.Separator))
+		}
+		if f.Format == "hex:upper" {
+			expr += ".upper()"
+		}
+		return expr, nil
+	case "array":
+		return "list(" + data + ")", nil
+	case "base64":
+		return "base64.b64encode(" + data + ").decode()", nil
+	}
+	return "", fmt.Errorf("bytes format %q", f.Format)
+}
+
+func pyBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func pyFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0"
+	}
+	return s
+}
+
+func pyLiteral(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "None"
+	case bool:
+		return pyBool(x)
+	case string:
+		return strconv.Quote(x)
+	case int:
+		return strconv.Itoa(x)
+	case float64:
+		return pyFloat(x)
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pythonExportSchema = `
+name: env_sensor
+endian: big
+definitions:
+  battery:
+    fields:
+      - {name: battery_mv, type: u16}
+fields:
+  - name: kind
+    type: u8
+    lookup: {1: periodic, 2: alarm}
+  - name: temperature
+    type: s16
+    add: -400
+    mult: 0.1
+    round: 1
+  - byte_group:
+      - {name: mode, type: "u8[0:2]", lookup: {0: idle, 1: active}}
+      - {name: alarm, type: "u8[7:7]"}
+  - name: pressure
+    type: u24
+    div: 100
+  - name: ratio
+    type: f16
+  - type: skip
+    length: 1
+  - name: status
+    type: enum
+    values: {0: ok, 1: low}
+    unknown: label("status_%d")
+  - name: position
+    type: Object
+    fields:
+      - {name: lat, type: s32, div: 1000000}
+      - {name: lon, type: s32, div: 1000000}
+  - name: flags
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - $ref: '#/definitions/battery'
+        - bit: 1
+          fields:
+            - {name: serial, type: Hex, length: 2}
+  - name: count
+    type: u8
+  - name: readings
+    type: repeat
+    count: $count
+    fields:
+      - {name: v, type: u8, mult: 2}
+  - name: extra
+    type: Match
+    on: $count
+    cases:
+      - case: 2
+        fields:
+          - {name: code, type: u16}
+      - default: true
+        fields: []
+  - name: celsius_x2
+    type: number
+    ref: $temperature
+    transform:
+      - mult: 2
+`
+
+func TestExportPythonMatchesDecode(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	s, err := ParseSchema(pythonExportSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	module, err := s.ExportPython()
+	if err != nil {
+		t.Fatalf("ExportPython() error = %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "env_sensor.py"), []byte(module), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := []string{
+		"01 0FA0 81 002710 3C00 00 00 00000001 FFFFFFFF 03 0E10 BEEF 02 05 06 0102",
+		"02 0000 00 000000 0000 00 07 00000000 00000000 00 01 FF 000A",
+	}
+	for _, p := range payloads {
+		data, _ := hex.DecodeString(strings.ReplaceAll(p, " ", ""))
+		want, err := s.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode(%s) error = %v", p, err)
+		}
+		script := "import json, sys\nsys.path.insert(0, sys.argv[1])\nimport env_sensor\n" +
+			"print(json.dumps(env_sensor.decode(bytes.fromhex(sys.argv[2]))))\n"
+		out, err := exec.Command(python, "-c", script, dir, hex.EncodeToString(data)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("python decode(%s) error = %v\n%s", p, err, out)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("python output %q: %v", out, err)
+		}
+		norm, err := normalizeJSON(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(norm, got) {
+			t.Errorf("payload %s:\n python = %v\n go     = %v", p, got, want)
+		}
+	}
+}
+
+func TestExportPythonPorts(t *testing.T) {
+	s, err := ParseSchema(`
+name: tracker
+ports:
+  1:
+    fields:
+      - {name: a, type: u8}
+  2:
+    fields:
+      - {name: b, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	module, err := s.ExportPython()
+	if err != nil {
+		t.Fatalf("ExportPython() error = %v", err)
+	}
+	for _, want := range []string{
+		"def _decode_port_1(r, out, v):",
+		"def _decode_port_2(r, out, v):",
+		"    1: _decode_port_1,",
+		"raise ValueError(\"no port definition for fPort %d in schema 'tracker'\" % fport)",
+	} {
+		if !strings.Contains(module, want) {
+			t.Errorf("ExportPython() missing %q", want)
+		}
+	}
+}
+
+func TestExportPythonPortHeaders(t *testing.T) {
+	s, err := ParseSchema(headerPortSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	module, err := s.ExportPython()
+	if err != nil {
+		t.Fatalf("ExportPython() error = %v", err)
+	}
+	// Each port function decodes the header that port uses
+	if strings.Contains(module, "_decode_header") {
+		t.Error("ExportPython() should not emit a shared header with per-port headers")
+	}
+	for _, want := range []string{`out["sequence"]`, `out["reboot"]`} {
+		if !strings.Contains(module, want) {
+			t.Errorf("ExportPython() missing %q", want)
+		}
+	}
+	port3 := module[strings.Index(module, "def _decode_port_3"):]
+	if port3 = port3[:strings.Index(port3, "_PORTS")]; strings.Contains(port3, "version") {
+		t.Errorf("port 3 decodes the dropped header:\n%s", port3)
+	}
+}
+
+func TestExportPythonUnsupported(t *testing.T) {
+	s, err := ParseSchema(`
+name: custom
+fields:
+  - {name: raw, type: u8}
+  - name: calc
+    type: number
+    compute: {op: div, a: $raw, b: 2}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.ExportPython(); err == nil || !strings.Contains(err.Error(), "calc: compute") {
+		t.Errorf("ExportPython() error = %v, want compute not exportable", err)
+	}
+}
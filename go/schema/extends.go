@@ -0,0 +1,216 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaLoader returns the source text of a schema referenced by `extends:`.
+// The reference is either a schema name or a path, as written in the schema.
+type SchemaLoader func(ref string) (string, error)
+
+// maxExtendsDepth bounds inheritance chains.
+const maxExtendsDepth = 16
+
+// ParseSchemaWithLoader parses a schema that may use `extends:` to inherit
+// from a base schema, resolving references with load.
+//
+// The child is deep-merged over the base:
+//   - top-level keys (name, endian, ...) replace the base values
+//   - mappings such as ports and definitions are merged key by key
+//   - fields are matched by name: a matching field is merged key by key
+//     (so a child can change only `div:`), `replace: true` replaces it
+//     entirely, `remove: true` drops it, and unmatched fields are appended
+func ParseSchemaWithLoader(data string, load SchemaLoader) (*Schema, error) {
+	merged, err := resolveExtends(data, load, map[string]bool{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render merged schema: %w", err)
+	}
+	return ParseSchema(string(out))
+}
+
+// ParseSchemaFile parses a schema file, resolving `extends:` references
+// relative to the file's directory. A bare name without an extension is
+// looked up as <name>.yaml. JSON files are detected by extension or
+// content; a file holding several schemas must be read with ParseBundleFile.
+func ParseSchemaFile(path string) (*Schema, error) {
+	data, err := readSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if docs, _, err := splitBundle(data); err == nil && len(docs) > 1 {
+		return nil, fmt.Errorf("%s contains %d schemas; use ParseBundleFile", path, len(docs))
+	}
+	return ParseSchemaWithLoader(data, FileSchemaLoader(filepath.Dir(path)))
+}
+
+// FileSchemaLoader returns a SchemaLoader that reads schemas from dir.
+func FileSchemaLoader(dir string) SchemaLoader {
+	return func(ref string) (string, error) {
+		path := ref
+		if filepath.Ext(path) == "" {
+			path += ".yaml"
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// resolveExtends parses data into a YAML mapping node with all `extends:`
+// references merged in.
+func resolveExtends(data string, load SchemaLoader, visiting map[string]bool, depth int) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse schema: expected a mapping")
+	}
+
+	ref := mappingValue(root, "extends")
+	if ref == nil {
+		return root, nil
+	}
+	if ref.Kind != yaml.ScalarNode || ref.Value == "" {
+		return nil, fmt.Errorf("extends must be a schema name or path")
+	}
+	if load == nil {
+		return nil, fmt.Errorf("schema extends %q but no loader was provided", ref.Value)
+	}
+	if visiting[ref.Value] {
+		return nil, fmt.Errorf("circular extends: %s", ref.Value)
+	}
+	if depth >= maxExtendsDepth {
+		return nil, fmt.Errorf("extends chain deeper than %d", maxExtendsDepth)
+	}
+	visiting[ref.Value] = true
+	defer delete(visiting, ref.Value)
+
+	baseData, err := load(ref.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base schema %q: %w", ref.Value, err)
+	}
+	base, err := resolveExtends(baseData, load, visiting, depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeMappingNodes(base, root)
+	return base, nil
+}
+
+// mappingValue returns the value node for key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// deleteMappingKey removes key from a mapping node.
+func deleteMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// mergeMappingNodes merges over into base in place, preserving base key order.
+func mergeMappingNodes(base, over *yaml.Node) {
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		key, val := over.Content[i], over.Content[i+1]
+		if key.Value == "extends" {
+			continue
+		}
+		existing := mappingValue(base, key.Value)
+		switch {
+		case existing == nil:
+			base.Content = append(base.Content, key, val)
+		case key.Value == "fields" && existing.Kind == yaml.SequenceNode && val.Kind == yaml.SequenceNode:
+			mergeFieldSequences(existing, val)
+		case existing.Kind == yaml.MappingNode && val.Kind == yaml.MappingNode:
+			mergeMappingNodes(existing, val)
+		default:
+			*existing = *val
+		}
+	}
+}
+
+// mergeFieldSequences merges a child field list into a base field list by name.
+func mergeFieldSequences(base, over *yaml.Node) {
+	for _, item := range over.Content {
+		name := ""
+		if item.Kind == yaml.MappingNode {
+			if n := mappingValue(item, "name"); n != nil && n.Kind == yaml.ScalarNode {
+				name = n.Value
+			}
+		}
+		remove := nodeFlag(item, "remove")
+		replace := nodeFlag(item, "replace")
+		if item.Kind == yaml.MappingNode {
+			deleteMappingKey(item, "remove")
+			deleteMappingKey(item, "replace")
+		}
+
+		idx := -1
+		if name != "" {
+			for j, b := range base.Content {
+				if b.Kind != yaml.MappingNode {
+					continue
+				}
+				if n := mappingValue(b, "name"); n != nil && n.Value == name {
+					idx = j
+					break
+				}
+			}
+		}
+
+		switch {
+		case idx < 0 && !remove:
+			base.Content = append(base.Content, item)
+		case idx < 0:
+			// Removing a field the base doesn't have is a no-op
+		case remove:
+			base.Content = append(base.Content[:idx], base.Content[idx+1:]...)
+		case replace:
+			base.Content[idx] = item
+		default:
+			mergeMappingNodes(base.Content[idx], item)
+		}
+	}
+}
+
+// nodeFlag reports whether a mapping node has key set to true.
+func nodeFlag(node *yaml.Node, key string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	v := mappingValue(node, key)
+	return v != nil && v.Kind == yaml.ScalarNode && strings.EqualFold(v.Value, "true")
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baseSensorYAML = `
+name: am300_base
+endian: big
+fields:
+  - name: temperature
+    type: s16
+    add: -400
+    div: 10
+  - name: humidity
+    type: u8
+    div: 2
+  - name: battery
+    type: u8
+`
+
+func mapLoader(schemas map[string]string) SchemaLoader {
+	return func(ref string) (string, error) {
+		if data, ok := schemas[ref]; ok {
+			return data, nil
+		}
+		return "", fmt.Errorf("not found: %s", ref)
+	}
+}
+
+func TestExtendsMergeFields(t *testing.T) {
+	schema, err := ParseSchemaWithLoader(`
+extends: am300_base
+name: am308
+fields:
+  - name: humidity
+    div: 4
+  - name: battery
+    remove: true
+  - name: co2
+    type: u16
+`, mapLoader(map[string]string{"am300_base": baseSensorYAML}))
+	if err != nil {
+		t.Fatalf("ParseSchemaWithLoader error: %v", err)
+	}
+	if schema.Name != "am308" {
+		t.Errorf("Name = %s, want am308", schema.Name)
+	}
+	if len(schema.Fields) != 3 {
+		t.Fatalf("got %d fields, want 3", len(schema.Fields))
+	}
+	// temperature keeps base YAML key order (add before div)
+	if got := schema.Fields[0].ModOrder; len(got) != 2 || got[0] != "add" || got[1] != "div" {
+		t.Errorf("temperature ModOrder = %v, want [add div]", got)
+	}
+
+	// temp raw 650 -> (650-400)/10 = 25, humidity 100/4 = 25, co2 = 800
+	result, err := schema.Decode([]byte{0x02, 0x8A, 0x64, 0x03, 0x20})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["temperature"] != float64(25) {
+		t.Errorf("temperature = %v, want 25", result["temperature"])
+	}
+	if result["humidity"] != float64(25) {
+		t.Errorf("humidity = %v, want 25", result["humidity"])
+	}
+	if result["co2"] != float64(800) {
+		t.Errorf("co2 = %v, want 800", result["co2"])
+	}
+	if _, ok := result["battery"]; ok {
+		t.Errorf("battery should have been removed")
+	}
+}
+
+func TestExtendsReplaceField(t *testing.T) {
+	schema, err := ParseSchemaWithLoader(`
+extends: am300_base
+fields:
+  - name: temperature
+    type: s16
+    replace: true
+`, mapLoader(map[string]string{"am300_base": baseSensorYAML}))
+	if err != nil {
+		t.Fatalf("ParseSchemaWithLoader error: %v", err)
+	}
+	if schema.Fields[0].Add != nil || schema.Fields[0].Div != nil {
+		t.Errorf("replaced field kept base modifiers: %+v", schema.Fields[0])
+	}
+}
+
+func TestExtendsPorts(t *testing.T) {
+	base := `
+name: base
+ports:
+  1:
+    direction: uplink
+    fields:
+      - name: a
+        type: u8
+  2:
+    fields:
+      - name: b
+        type: u8
+`
+	schema, err := ParseSchemaWithLoader(`
+extends: base
+ports:
+  1:
+    fields:
+      - name: c
+        type: u8
+`, mapLoader(map[string]string{"base": base}))
+	if err != nil {
+		t.Fatalf("ParseSchemaWithLoader error: %v", err)
+	}
+	result, err := schema.DecodeWithPort([]byte{0x01, 0x02}, 1)
+	if err != nil {
+		t.Fatalf("DecodeWithPort error: %v", err)
+	}
+	if result["a"] != float64(1) || result["c"] != float64(2) {
+		t.Errorf("port 1 result = %v, want a=1 c=2", result)
+	}
+	if schema.Ports["1"].Direction != "uplink" {
+		t.Errorf("port 1 direction = %q, want uplink", schema.Ports["1"].Direction)
+	}
+	if _, ok := schema.Ports["2"]; !ok {
+		t.Errorf("port 2 not inherited")
+	}
+}
+
+func TestExtendsChainAndCycle(t *testing.T) {
+	loader := mapLoader(map[string]string{
+		"a": "extends: b\nfields:\n  - name: x\n    type: u8\n",
+		"b": "extends: a\n",
+	})
+	_, err := ParseSchemaWithLoader("extends: a\n", loader)
+	if err == nil || !strings.Contains(err.Error(), "circular") {
+		t.Errorf("expected circular extends error, got %v", err)
+	}
+}
+
+func TestExtendsRequiresLoader(t *testing.T) {
+	if _, err := ParseSchema("extends: base\nfields: []\n"); err == nil {
+		t.Error("expected error for extends without loader")
+	}
+}
+
+func TestParseSchemaFileExtends(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "base.yaml"), []byte(baseSensorYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	child := filepath.Join(dir, "variant.yaml")
+	if err := os.WriteFile(child, []byte("extends: base\nname: variant\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ParseSchemaFile(child)
+	if err != nil {
+		t.Fatalf("ParseSchemaFile error: %v", err)
+	}
+	if schema.Name != "variant" || len(schema.Fields) != 3 {
+		t.Errorf("got name=%s fields=%d, want variant/3", schema.Name, len(schema.Fields))
+	}
+}
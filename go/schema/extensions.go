@@ -0,0 +1,56 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+// Keys interpreted by ParseSchema. Anything else is kept verbatim in the
+// Extensions map of the schema or field, so toolchains can carry vendor
+// metadata (by convention x-prefixed, e.g. `x-vendor-sku`) through parsing.
+// New schema keys must be added here.
+var (
+	knownSchemaKeys = keySet(
+		"name", "version", "description", "endian", "fields", "ports", "definitions", "extends",
+		"header", "frames", "revisions", "emit_aliases", "strict", "strict_types", "encode_overflow", "encode_rounding", "key_style", "namespace", "namespace_mode", "output_mode",
+	)
+
+	knownFieldKeys = keySet(
+		"name", "type", "length", "endian", "var", "scope", "fields", "on", "cases",
+		"add", "mult", "div", "transform", "modifiers", "lookup", "polynomial",
+		"ref", "compute", "guard", "curve", "formula", "encode_inverse",
+		"aliases", "deprecated", "round", "precision", "as_string", "output", "scale", "required",
+		"tag_size", "length_size", "tag_fields", "tag_key", "merge", "unknown", "name_template",
+		"count", "byte_length", "until", "max", "min", "flatten", "interval", "order",
+		"format", "separator", "bit", "consume", "byte_offset", "bit_offset", "bits",
+		"base", "values", "byte_group", "bitfield", "size", "$ref",
+		"delimiter", "prefix", "parts", "valid_range", "resolution", "unece", "role", "deadband", "report_min_interval", "invalid", "non_finite",
+		"flagged", "tlv", "match", "wasm", "q", "int_bits", "frac_bits", "byte_order", "tag_bind",
+		"scale_by", "scale_base", "const", "pad", "overflow", "also_emit",
+		"encoding", "invalid_chars", "expect", "mismatch", "value",
+		"description", "display_name", "example",
+	)
+)
+
+func keySet(keys ...string) map[string]bool {
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[k] = true
+	}
+	return m
+}
+
+// collectExtensions returns the entries of m whose keys are not in known,
+// or nil if there are none.
+func collectExtensions(m map[string]any, known map[string]bool) map[string]any {
+	var ext map[string]any
+	for k, v := range m {
+		if known[k] {
+			continue
+		}
+		if ext == nil {
+			ext = make(map[string]any)
+		}
+		ext[k] = v
+	}
+	return ext
+}
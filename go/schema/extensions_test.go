@@ -0,0 +1,58 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionsPassthrough(t *testing.T) {
+	schema, err := ParseSchema(`
+name: vendor
+x-vendor-sku: AM308
+x-release:
+  channel: beta
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+    unit: "°C"
+    x-chart: {color: red}
+  - name: status
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	wantSchema := map[string]any{
+		"x-vendor-sku": "AM308",
+		"x-release":    map[string]any{"channel": "beta"},
+	}
+	if !reflect.DeepEqual(schema.Extensions, wantSchema) {
+		t.Errorf("Schema.Extensions = %v, want %v", schema.Extensions, wantSchema)
+	}
+
+	wantField := map[string]any{
+		"unit":    "°C",
+		"x-chart": map[string]any{"color": "red"},
+	}
+	if !reflect.DeepEqual(schema.Fields[0].Extensions, wantField) {
+		t.Errorf("Fields[0].Extensions = %v, want %v", schema.Fields[0].Extensions, wantField)
+	}
+	if schema.Fields[1].Extensions != nil {
+		t.Errorf("Fields[1].Extensions = %v, want nil", schema.Fields[1].Extensions)
+	}
+
+	// Extensions do not affect decoding
+	decoded, err := schema.Decode([]byte{0x00, 0xFA, 0x01})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded["temperature"] != 25.0 || len(decoded) != 2 {
+		t.Errorf("decoded = %v", decoded)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Fixed-point types. Qm.n uses m integer bits (including the sign bit for
+// fixed) and n fractional bits; the stored integer is value * 2^n.
+//
+//	name: temperature
+//	type: fixed
+//	q: 8.8            # or int_bits: 8, frac_bits: 8
+const (
+	TypeFixed  FieldType = "fixed"  // signed two's complement
+	TypeUFixed FieldType = "ufixed" // unsigned
+)
+
+func isFixedType(t FieldType) bool {
+	return t == TypeFixed || t == TypeUFixed
+}
+
+// parseFixedFormat reads q: / int_bits: / frac_bits: into the field.
+func parseFixedFormat(fm map[string]any, f *Field) {
+	if q, ok := fm["q"]; ok {
+		var spec string
+		switch v := q.(type) {
+		case string:
+			spec = v
+		default:
+			if num, ok := toFloat64(v); ok {
+				// YAML reads 8.8 as a number; quote formats like "8.10"
+				spec = strconv.FormatFloat(num, 'f', -1, 64)
+			}
+		}
+		m, n, found := strings.Cut(strings.TrimPrefix(strings.TrimSpace(spec), "Q"), ".")
+		intBits, errM := strconv.Atoi(m)
+		fracBits, errN := strconv.Atoi(n)
+		if !found || errM != nil || errN != nil {
+			f.invalid = append(f.invalid, fmt.Sprintf("q: expected m.n, got %v", q))
+			return
+		}
+		f.IntBits, f.FracBits = intBits, fracBits
+	}
+	if intBits, ok := intKey(fm, "int_bits"); ok {
+		f.IntBits = intBits
+	}
+	if fracBits, ok := intKey(fm, "frac_bits"); ok {
+		f.FracBits = fracBits
+	}
+	total := f.IntBits + f.FracBits
+	if f.IntBits < 0 || f.FracBits < 0 || total == 0 || total > 64 || total%8 != 0 {
+		f.invalid = append(f.invalid, fmt.Sprintf(
+			"fixed Q%d.%d: total bits must be a multiple of 8 between 8 and 64", f.IntBits, f.FracBits))
+	}
+}
+
+// fixedLength returns the storage size in bytes.
+func fixedLength(field Field) int {
+	if field.Length > 0 {
+		return field.Length
+	}
+	return (field.IntBits + field.FracBits + 7) / 8
+}
+
+// fixedRange returns the representable range of a fixed-point field.
+func fixedRange(field Field) (min, max float64) {
+	bits := uint(fixedLength(field) * 8)
+	scale := math.Ldexp(1, field.FracBits)
+	if field.Type == TypeUFixed {
+		return 0, (math.Ldexp(1, int(bits)) - 1) / scale
+	}
+	return -math.Ldexp(1, int(bits-1)) / scale, (math.Ldexp(1, int(bits-1)) - 1) / scale
+}
+
+func decodeFixed(field Field, ctx *DecodeContext, endian string) (float64, error) {
+	data, err := ctx.readField(field, fixedLength(field))
+	if err != nil {
+		return 0, err
+	}
+	var raw float64
+	if field.Type == TypeUFixed {
+		raw = float64(decodeUint(data, endian))
+	} else {
+		raw = float64(decodeSint(data, endian))
+	}
+	return math.Ldexp(raw, -field.FracBits), nil
+}
+
+func encodeFixed(field Field, value any, endian string, ctx *EncodeContext) error {
+	numVal, ok := toFloat64(value)
+	if !ok {
+		return nil
+	}
+	min, max := fixedRange(field)
+	if numVal < min || numVal > max || math.IsNaN(numVal) {
+		return fmt.Errorf("field %s: %v out of range for Q%d.%d [%v, %v]",
+			field.Name, numVal, field.IntBits, field.FracBits, min, max)
+	}
+	raw := ctx.round(math.Ldexp(numVal, field.FracBits))
+	length := fixedLength(field)
+	if field.Type == TypeUFixed {
+		ctx.Write(encodeUint(uint64(raw), length, endian))
+	} else {
+		ctx.Write(encodeSint(int64(raw), length, endian))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFixedPointDecode(t *testing.T) {
+	schema, err := ParseSchema(`
+name: fixed_test
+fields:
+  - name: temperature
+    type: fixed
+    q: 8.8
+  - name: ratio
+    type: ufixed
+    int_bits: 4
+    frac_bits: 12
+  - name: offset
+    type: fixed
+    q: "Q4.4"
+    add: 1
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(schema.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", schema.Warnings)
+	}
+
+	// -2.5 = 0xFD80, 9.75 = 0x9C00, -1.5 = 0xE8 (+1 = -0.5)
+	decoded, err := schema.Decode([]byte{0xFD, 0x80, 0x9C, 0x00, 0xE8})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded["temperature"] != -2.5 {
+		t.Errorf("temperature = %v, want -2.5", decoded["temperature"])
+	}
+	if decoded["ratio"] != 9.75 {
+		t.Errorf("ratio = %v, want 9.75", decoded["ratio"])
+	}
+	if decoded["offset"] != -0.5 {
+		t.Errorf("offset = %v, want -0.5", decoded["offset"])
+	}
+
+	encoded, err := schema.Encode(decoded)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0xFD, 0x80, 0x9C, 0x00, 0xE8}) {
+		t.Errorf("Encode() = %X, want FD809C00E8", encoded)
+	}
+}
+
+func TestFixedPointEncodeRange(t *testing.T) {
+	schema, err := ParseSchema(`
+name: fixed_range
+endian: little
+fields:
+  - name: v
+    type: fixed
+    q: 8.8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		value   float64
+		want    []byte
+		wantErr bool
+	}{
+		{127.99609375, []byte{0xFF, 0x7F}, false},
+		{-128, []byte{0x00, 0x80}, false},
+		{1.00195, []byte{0x00, 0x01}, false}, // rounds to nearest step
+		{128, nil, true},
+		{-128.5, nil, true},
+	}
+	for _, tt := range tests {
+		encoded, err := schema.Encode(map[string]any{"v": tt.value})
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("Encode(%v) err = %v, want out of range", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Encode(%v) error = %v", tt.value, err)
+			continue
+		}
+		if !bytes.Equal(encoded, tt.want) {
+			t.Errorf("Encode(%v) = %X, want %X", tt.value, encoded, tt.want)
+		}
+	}
+}
+
+func TestFixedPointInvalidFormat(t *testing.T) {
+	for _, q := range []string{"q: 8.4", "q: abc", "int_bits: 0"} {
+		schema, err := ParseSchema("name: bad\nfields:\n  - name: v\n    type: fixed\n    " + q + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema(%s) error = %v", q, err)
+		}
+		if len(schema.Warnings) == 0 {
+			t.Errorf("%s: expected a warning", q)
+		}
+	}
+}
@@ -0,0 +1,762 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Formulas (formula:, and the expression form of on:) are compiled once,
+// when the schema is parsed, into a tree that decode evaluates against the
+// raw value x and the decoded variables. A formula that does not compile
+// is a schema warning (an error with strict: true). Fields built without
+// the parser (Builder, JSON) are compiled on use.
+//
+// Supported: $field_name references (indexed as $readings[0].temp, see
+// varpath.go), x (raw value), pow/abs/sqrt/min/max, arithmetic, bitwise
+// and shift operators, comparisons, ternary (cond ? a : b), and/or (&&,
+// ||), and string tests ($mode == "fast", $status in [1, 2, 3]). A
+// missing variable reads as 0; a string variable, such as a lookup result,
+// may only be compared with ==, != or in.
+
+// compiledFormula is a parsed formula, safe for concurrent use.
+type compiledFormula struct {
+	src  string
+	root exprNode
+	vars []string // Variables the formula reads, in order of appearance
+	err  error    // Compile error of a formula kept from schema parsing
+}
+
+// compileFormula parses src.
+func compileFormula(src string) (*compiledFormula, error) {
+	p := &exprParser{input: strings.TrimSpace(src)}
+	root, err := p.parseTernary()
+	if err == nil {
+		p.skipSpaces()
+		if p.pos != len(p.input) {
+			err = fmt.Errorf("unexpected token at position %d: %q", p.pos, p.input[p.pos:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("formula eval failed for %q: %w", src, err)
+	}
+	return &compiledFormula{src: src, root: root, vars: p.vars}, nil
+}
+
+// compileFieldFormula compiles a field's key: src when the schema is
+// parsed. A formula that does not compile is reported in invalid and kept
+// with its error, so decoding fails with that error instead of compiling
+// it again.
+func compileFieldFormula(key, src string, invalid *[]string) *compiledFormula {
+	c, err := compileFormula(src)
+	if err != nil {
+		*invalid = append(*invalid, fmt.Sprintf("%s: %v", key, err))
+		return &compiledFormula{src: src, err: err}
+	}
+	return c
+}
+
+// formulaOf returns c, or src compiled now when the field was not parsed.
+func formulaOf(c *compiledFormula, src string) (*compiledFormula, error) {
+	if c != nil {
+		if c.err != nil {
+			return nil, c.err
+		}
+		return c, nil
+	}
+	return compileFormula(src)
+}
+
+// eval evaluates the formula for raw value x.
+func (c *compiledFormula) eval(x float64, vars map[string]any) (float64, error) {
+	v, err := c.root.eval(exprEnv{x: x, vars: vars})
+	if err == nil {
+		var n float64
+		if n, err = v.number(); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("formula eval failed for %q: %w", c.src, err)
+}
+
+// evaluateFormula (DEPRECATED - use polynomial/compute/guard instead)
+// compiles and evaluates formula in one step.
+func evaluateFormula(formula string, x float64, ctx *DecodeContext) (float64, error) {
+	c, err := compileFormula(formula)
+	if err != nil {
+		return 0, err
+	}
+	return c.eval(x, ctx.Variables)
+}
+
+// evalExpr evaluates a formula that reads no variables.
+func evalExpr(expr string) (float64, error) {
+	return evaluateFormula(expr, 0, &DecodeContext{})
+}
+
+// exprEnv holds the inputs of one evaluation.
+type exprEnv struct {
+	x    float64
+	vars map[string]any
+}
+
+// exprVal is a number or, for string literals and variables, a string.
+type exprVal struct {
+	num   float64
+	str   string
+	isStr bool
+}
+
+func (v exprVal) number() (float64, error) {
+	if v.isStr {
+		return 0, fmt.Errorf("string %q must be compared with ==, != or in", v.str)
+	}
+	return v.num, nil
+}
+
+// equal compares two operands; a number never equals a string.
+func (v exprVal) equal(o exprVal) bool {
+	if v.isStr || o.isStr {
+		return v.isStr == o.isStr && v.str == o.str
+	}
+	return v.num == o.num
+}
+
+func exprBool(b bool) exprVal {
+	if b {
+		return exprVal{num: 1}
+	}
+	return exprVal{}
+}
+
+type exprNode interface {
+	eval(env exprEnv) (exprVal, error)
+}
+
+type (
+	numNode  float64
+	strNode  string
+	varNode  string
+	pathNode struct {
+		name  string
+		steps []varStep
+	}
+	rawNode  struct{}
+	negNode  struct{ operand exprNode }
+	callNode struct {
+		fn   string
+		args []exprNode
+	}
+	binaryNode struct {
+		op          string
+		left, right exprNode
+	}
+	equalNode struct {
+		negate      bool
+		left, right exprNode
+	}
+	inNode struct {
+		negate bool
+		left   exprNode
+		items  []exprNode
+	}
+	ternaryNode struct {
+		cond, then, otherwise exprNode
+	}
+)
+
+func (n numNode) eval(exprEnv) (exprVal, error)   { return exprVal{num: float64(n)}, nil }
+func (n strNode) eval(exprEnv) (exprVal, error)   { return exprVal{str: string(n), isStr: true}, nil }
+func (rawNode) eval(env exprEnv) (exprVal, error) { return exprVal{num: env.x}, nil }
+
+func (n varNode) eval(env exprEnv) (exprVal, error) {
+	return varVal(env.vars[string(n)]), nil
+}
+
+func (n pathNode) eval(env exprEnv) (exprVal, error) {
+	val, _ := resolveVarPath(env.vars, n.name, n.steps)
+	return varVal(val), nil
+}
+
+// varVal is the operand a variable's value gives: a string, or a number
+// (0 when missing or not numeric).
+func varVal(val any) exprVal {
+	if s, ok := val.(string); ok {
+		return exprVal{str: s, isStr: true}
+	}
+	f, _ := toFloat64(val)
+	return exprVal{num: f}
+}
+
+func (n negNode) eval(env exprEnv) (exprVal, error) {
+	v, err := evalNumber(n.operand, env)
+	return exprVal{num: -v}, err
+}
+
+// evalNumber evaluates n, which must give a number.
+func evalNumber(n exprNode, env exprEnv) (float64, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	return v.number()
+}
+
+func (n callNode) eval(env exprEnv) (exprVal, error) {
+	var args [2]float64
+	for i, arg := range n.args {
+		v, err := evalNumber(arg, env)
+		if err != nil {
+			return exprVal{}, err
+		}
+		args[i] = v
+	}
+	switch n.fn {
+	case "abs":
+		return exprVal{num: math.Abs(args[0])}, nil
+	case "sqrt":
+		return exprVal{num: math.Sqrt(args[0])}, nil
+	case "pow":
+		return exprVal{num: math.Pow(args[0], args[1])}, nil
+	case "min":
+		return exprVal{num: math.Min(args[0], args[1])}, nil
+	default:
+		return exprVal{num: math.Max(args[0], args[1])}, nil
+	}
+}
+
+func (n binaryNode) eval(env exprEnv) (exprVal, error) {
+	l, err := evalNumber(n.left, env)
+	if err != nil {
+		return exprVal{}, err
+	}
+	// and/or stop at the left operand when it decides the result
+	switch {
+	case n.op == "&&" && l == 0:
+		return exprBool(false), nil
+	case n.op == "||" && l != 0:
+		return exprBool(true), nil
+	}
+	r, err := evalNumber(n.right, env)
+	if err != nil {
+		return exprVal{}, err
+	}
+	switch n.op {
+	case "&&", "||":
+		return exprBool(r != 0), nil
+	case "|":
+		return exprVal{num: float64(int64(l) | int64(r))}, nil
+	case "^":
+		return exprVal{num: float64(int64(l) ^ int64(r))}, nil
+	case "&":
+		return exprVal{num: float64(int64(l) & int64(r))}, nil
+	case ">=":
+		return exprBool(l >= r), nil
+	case "<=":
+		return exprBool(l <= r), nil
+	case ">":
+		return exprBool(l > r), nil
+	case "<":
+		return exprBool(l < r), nil
+	case "<<":
+		return exprVal{num: float64(int64(l) << uint(r))}, nil
+	case ">>":
+		return exprVal{num: float64(int64(l) >> uint(r))}, nil
+	case "+":
+		return exprVal{num: l + r}, nil
+	case "-":
+		return exprVal{num: l - r}, nil
+	case "*":
+		return exprVal{num: l * r}, nil
+	default: // "/"
+		if r == 0 {
+			return exprVal{}, nil
+		}
+		return exprVal{num: l / r}, nil
+	}
+}
+
+func (n equalNode) eval(env exprEnv) (exprVal, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return exprVal{}, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return exprVal{}, err
+	}
+	return exprBool(l.equal(r) != n.negate), nil
+}
+
+func (n inNode) eval(env exprEnv) (exprVal, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return exprVal{}, err
+	}
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return exprVal{}, err
+		}
+		if l.equal(v) {
+			return exprBool(!n.negate), nil
+		}
+	}
+	return exprBool(n.negate), nil
+}
+
+func (n ternaryNode) eval(env exprEnv) (exprVal, error) {
+	c, err := evalNumber(n.cond, env)
+	if err != nil {
+		return exprVal{}, err
+	}
+	if c != 0 {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+// exprParser is a recursive descent parser producing an exprNode tree.
+// Precedence, loosest first: ternary, ||, &&, |, ^, & (bitwise operators
+// bind looser than comparisons, as in C: a & b == c is a & (b == c)),
+// comparisons, shifts, + -, * /, unary minus.
+type exprParser struct {
+	input string
+	pos   int
+	vars  []string
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *exprParser) peekStr(n int) string {
+	p.skipSpaces()
+	end := p.pos + n
+	if end > len(p.input) {
+		end = len(p.input)
+	}
+	return p.input[p.pos:end]
+}
+
+// peekLogical returns the length of the operator op or its keyword form
+// (and, or) when one comes next, or 0.
+func (p *exprParser) peekLogical(op, word string) int {
+	p.skipSpaces()
+	rest := p.input[p.pos:]
+	if strings.HasPrefix(rest, op) {
+		return len(op)
+	}
+	if strings.HasPrefix(rest, word) && (len(rest) == len(word) || !isIdentByte(rest[len(word)])) {
+		return len(word)
+	}
+	return 0
+}
+
+func (p *exprParser) parseTernary() (exprNode, error) {
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != '?' {
+		return cond, nil
+	}
+	p.pos++
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ':' {
+		return nil, fmt.Errorf("expected ':' in ternary")
+	}
+	p.pos++
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return ternaryNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for n := p.peekLogical("||", "or"); n > 0; n = p.peekLogical("||", "or") {
+		p.pos += n
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: "||", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	node, err := p.parseBitOr()
+	if err != nil {
+		return nil, err
+	}
+	for n := p.peekLogical("&&", "and"); n > 0; n = p.peekLogical("&&", "and") {
+		p.pos += n
+		right, err := p.parseBitOr()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: "&&", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseBitOr() (exprNode, error) {
+	node, err := p.parseBitXor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '|' && p.peekStr(2) != "||" {
+		p.pos++
+		right, err := p.parseBitXor()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: "|", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseBitXor() (exprNode, error) {
+	node, err := p.parseBitAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '^' {
+		p.pos++
+		right, err := p.parseBitAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: "^", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseBitAnd() (exprNode, error) {
+	node, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '&' && p.peekStr(2) != "&&" {
+		p.pos++
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: "&", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	node, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peekStr(2)
+		switch {
+		case op == ">=" || op == "<=":
+			p.pos += 2
+			right, err := p.parseShift()
+			if err != nil {
+				return nil, err
+			}
+			node = binaryNode{op: op, left: node, right: right}
+		case op == "==" || op == "!=":
+			p.pos += 2
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			node = equalNode{negate: op == "!=", left: node, right: right}
+		default:
+			if negate, ok := p.peekIn(); ok {
+				items, err := p.parseInList()
+				if err != nil {
+					return nil, err
+				}
+				node = inNode{negate: negate, left: node, items: items}
+				continue
+			}
+			if c := p.peek(); c != '>' && c != '<' {
+				return node, nil
+			}
+			op = op[:1]
+			p.pos++
+			right, err := p.parseShift()
+			if err != nil {
+				return nil, err
+			}
+			node = binaryNode{op: op, left: node, right: right}
+		}
+	}
+}
+
+// parseOperand parses a comparison operand: a string literal or a
+// numeric expression.
+func (p *exprParser) parseOperand() (exprNode, error) {
+	if q := p.peek(); q == '"' || q == '\'' {
+		s, err := p.parseString()
+		return strNode(s), err
+	}
+	return p.parseShift()
+}
+
+// parseString parses a "double" (Go escapes) or 'single' (verbatim)
+// quoted string literal.
+func (p *exprParser) parseString() (string, error) {
+	q := p.input[p.pos]
+	end := p.pos + 1
+	for end < len(p.input) && p.input[end] != q {
+		if q == '"' && p.input[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(p.input) {
+		return "", fmt.Errorf("unterminated string at position %d", p.pos)
+	}
+	lit := p.input[p.pos : end+1]
+	p.pos = end + 1
+	if q == '\'' {
+		return lit[1 : len(lit)-1], nil
+	}
+	str, err := strconv.Unquote(lit)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", lit)
+	}
+	return str, nil
+}
+
+// peekIn reports whether an `in` or `not in` operator comes next, and
+// which.
+func (p *exprParser) peekIn() (negate, ok bool) {
+	p.skipSpaces()
+	rest := p.input[p.pos:]
+	if strings.HasPrefix(rest, "not ") {
+		negate = true
+		rest = strings.TrimLeft(rest[4:], " ")
+	}
+	if !strings.HasPrefix(rest, "in") || len(rest) > 2 && isIdentByte(rest[2]) {
+		return false, false
+	}
+	return negate, true
+}
+
+// parseInList consumes an `in` / `not in` operator and its [a, b, ...]
+// list.
+func (p *exprParser) parseInList() ([]exprNode, error) {
+	p.pos = strings.Index(p.input[p.pos:], "in") + p.pos + 2
+	if p.peek() != '[' {
+		return nil, fmt.Errorf("expected '[' after in at position %d", p.pos)
+	}
+	p.pos++
+	var items []exprNode
+	for p.peek() != ']' {
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in list at position %d", p.pos)
+		}
+	}
+	p.pos++
+	return items, nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *exprParser) parseShift() (exprNode, error) {
+	node, err := p.parseAddSub()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peekStr(2); op == "<<" || op == ">>"; op = p.peekStr(2) {
+		p.pos += 2
+		right, err := p.parseAddSub()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: op, left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseAddSub() (exprNode, error) {
+	node, err := p.parseMulDiv()
+	if err != nil {
+		return nil, err
+	}
+	for c := p.peek(); c == '+' || c == '-'; c = p.peek() {
+		p.pos++
+		right, err := p.parseMulDiv()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: string(c), left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseMulDiv() (exprNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for c := p.peek(); c == '*' || c == '/'; c = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = binaryNode{op: string(c), left: node, right: right}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := operand.(numNode); ok {
+			return -n, nil
+		}
+		return negNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	p.skipSpaces()
+	rest := p.input[p.pos:]
+
+	// Parenthesized expression; a missing ')' is tolerated
+	if p.peek() == '(' {
+		p.pos++
+		node, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == ')' {
+			p.pos++
+		}
+		return node, nil
+	}
+
+	// $field_name reference, optionally indexed: $readings[0].temp
+	if len(rest) > 1 && rest[0] == '$' && !(rest[1] >= '0' && rest[1] <= '9') && isIdentByte(rest[1]) {
+		name, steps, n, err := parseVarPath(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		p.pos += 1 + n
+		p.vars = append(p.vars, name)
+		if len(steps) > 0 {
+			return pathNode{name: name, steps: steps}, nil
+		}
+		return varNode(name), nil
+	}
+
+	// Raw value
+	if rest != "" && rest[0] == 'x' && (len(rest) == 1 || !isIdentByte(rest[1])) {
+		p.pos++
+		return rawNode{}, nil
+	}
+
+	// Function calls: pow, abs, sqrt, min, max
+	for _, fname := range []string{"pow", "abs", "sqrt", "min", "max"} {
+		if !strings.HasPrefix(rest, fname+"(") {
+			continue
+		}
+		p.pos += len(fname) + 1
+		call := callNode{fn: fname}
+		arg, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if fname != "abs" && fname != "sqrt" {
+			// Two-argument functions
+			if p.peek() == ',' {
+				p.pos++
+			}
+			if arg, err = p.parseTernary(); err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+		}
+		if p.peek() == ')' {
+			p.pos++
+		}
+		return call, nil
+	}
+
+	// Hex literal
+	if len(rest) > 2 && rest[0] == '0' && (rest[1] == 'x' || rest[1] == 'X') {
+		end := 2
+		for end < len(rest) && strings.IndexByte("0123456789abcdefABCDEF", rest[end]) >= 0 {
+			end++
+		}
+		val, err := strconv.ParseUint(rest[2:end], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex number: %s", rest[:end])
+		}
+		p.pos += end
+		return numNode(val), nil
+	}
+
+	// Number literal
+	end := 0
+	if end < len(rest) && (rest[end] == '-' || rest[end] == '+') {
+		end++
+	}
+	for end < len(rest) && (rest[end] >= '0' && rest[end] <= '9' || rest[end] == '.' || rest[end] == 'e' || rest[end] == 'E') {
+		end++
+	}
+	if end > 0 {
+		val, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", rest[:end])
+		}
+		p.pos += end
+		return numNode(val), nil
+	}
+
+	return nil, fmt.Errorf("unexpected token at position %d: %q", p.pos, rest)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const formulaSchema = `
+name: formula
+fields:
+  - {name: mode, type: u8, var: mode, lookup: {0: eco, 1: fast}}
+  - {name: exp, type: s8, var: exp}
+  - name: temperature
+    type: s16
+    formula: "$mode == \"fast\" and x > 0 ? x * pow(10, $exp) + 0.5 : x / 10"
+  - name: kind
+    type: Match
+    on: "($exp & 0x0F) | 0x10"
+    cases:
+      - case: 0x1E
+        fields:
+          - {name: flag, type: u8}
+`
+
+func TestFormulaCompiledAtParse(t *testing.T) {
+	s, err := ParseSchema(formulaSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if s.Fields[2].formula == nil || s.Fields[3].onExpr == nil {
+		t.Fatal("formula and on: were not compiled")
+	}
+	if vars := s.Fields[2].formula.vars; len(vars) != 2 || vars[0] != "mode" || vars[1] != "exp" {
+		t.Errorf("vars = %v, want [mode exp]", vars)
+	}
+
+	got, err := s.Decode([]byte{0x01, 0xFE, 0x00, 0x64, 0x07})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if kind, _ := got["kind"].(map[string]any); got["temperature"] != 1.5 || kind["flag"] != 7.0 {
+		t.Errorf("Decode() = %v", got)
+	}
+	got, err = s.Decode([]byte{0x00, 0x00, 0x00, 0x64})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["temperature"] != 10.0 {
+		t.Errorf("temperature = %v, want 10", got["temperature"])
+	}
+}
+
+func TestFormulaCompileErrorAtParse(t *testing.T) {
+	const bad = `
+name: bad
+fields:
+  - {name: raw, type: u8, formula: "x * * 2"}
+`
+	s, err := ParseSchema(bad)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], "formula:") {
+		t.Errorf("Warnings = %v, want formula compile error", s.Warnings)
+	}
+	if s.Fields[0].formula == nil || s.Fields[0].formula.err == nil {
+		t.Fatal("compile error was not kept on the field")
+	}
+	if _, err := s.Decode([]byte{0x01}); err == nil || !strings.Contains(err.Error(), "x * * 2") {
+		t.Errorf("Decode() error = %v, want formula compile error", err)
+	}
+
+	if _, err := ParseSchema("strict: true" + bad); err == nil {
+		t.Error("ParseSchema(strict) error = nil, want formula compile error")
+	}
+}
+
+func TestFormulaTrailingInput(t *testing.T) {
+	tests := []struct {
+		formula string
+		pos     int
+	}{
+		{"x 2", 2},
+		{"x * 2 )", 6},
+		{"$a $b", 3},
+	}
+	for _, tt := range tests {
+		_, err := compileFormula(tt.formula)
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("unexpected token at position %d", tt.pos)) {
+			t.Errorf("compileFormula(%q) error = %v, want unexpected token at position %d", tt.formula, err, tt.pos)
+		}
+	}
+	if _, err := compileFormula(" x * 2 "); err != nil {
+		t.Errorf("compileFormula() error = %v, want surrounding spaces accepted", err)
+	}
+}
+
+func TestFormulaCompiledOnUse(t *testing.T) {
+	// Fields not built by the parser carry no compiled formula
+	s := &Schema{Name: "built", Fields: []Field{
+		{Name: "raw", Type: TypeU8, Formula: "x * 2 + 1"},
+	}}
+	got, err := s.Decode([]byte{0x14})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["raw"] != 41.0 {
+		t.Errorf("raw = %v, want 41", got["raw"])
+	}
+}
+
+func TestFormulaShortCircuit(t *testing.T) {
+	ctx := &DecodeContext{Variables: map[string]any{"mode": "fast"}}
+	tests := []struct {
+		formula string
+		want    float64
+	}{
+		// The branch not taken may use the string in arithmetic
+		{`$mode == "fast" ? 1 : $mode + 1`, 1},
+		{`$mode != "fast" and $mode > 1`, 0},
+		{`$mode == "fast" or $mode > 1`, 1},
+	}
+	for _, tt := range tests {
+		got, err := evaluateFormula(tt.formula, 0, ctx)
+		if err != nil {
+			t.Errorf("evaluateFormula(%q) error = %v", tt.formula, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("evaluateFormula(%q) = %v, want %v", tt.formula, got, tt.want)
+		}
+	}
+	if _, err := evaluateFormula(`$mode + 1`, 0, ctx); err == nil {
+		t.Error("string arithmetic: no error")
+	}
+}
+
+const benchFormula = "($raw_dielectric > 0 ? $raw_dielectric / 50 : 0) * pow(x, 2) + min(x, 100) - 3"
+
+func BenchmarkFormulaCompiled(b *testing.B) {
+	c, err := compileFormula(benchFormula)
+	if err != nil {
+		b.Fatal(err)
+	}
+	vars := map[string]any{"raw_dielectric": 1234.0}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.eval(21.5, vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFormulaParseEach parses the formula on every evaluation, as
+// decode did before formulas were compiled at schema load.
+func BenchmarkFormulaParseEach(b *testing.B) {
+	ctx := &DecodeContext{Variables: map[string]any{"raw_dielectric": 1234.0}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := evaluateFormula(benchFormula, 21.5, ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultFramesKey is the output key of decoded frames when frames: gives
+// no name.
+const DefaultFramesKey = "frames"
+
+// FramesDef describes payloads that concatenate independent frames, each
+// starting with its own header whose type selects the frame body:
+//
+//	frames:
+//	  header:
+//	    - {name: type, type: u8}
+//	    - {name: len, type: u8}
+//	  on: $type
+//	  length: $len        # optional: body bytes, lets unknown types be skipped
+//	  cases:
+//	    - case: 1
+//	      fields: [...]
+//
+// Frames follow the schema's fields and repeat until the payload ends.
+type FramesDef struct {
+	Name   string  // Output key (DefaultFramesKey if empty)
+	Header []Field // Decoded at the start of every frame
+	Match  Field   // Selects the frame body (on + cases)
+	Length string  // Optional $var: bytes of body after the header
+}
+
+// key returns the output key of the frames array.
+func (fd *FramesDef) key() string {
+	if fd.Name != "" {
+		return fd.Name
+	}
+	return DefaultFramesKey
+}
+
+// parseFramesDef parses the schema-level frames: key.
+func parseFramesDef(raw map[string]any) (*FramesDef, error) {
+	fd := &FramesDef{}
+	if name, ok := raw["name"].(string); ok {
+		fd.Name = name
+	}
+	if header, ok := raw["header"].([]any); ok {
+		fd.Header = parseFieldsRaw(header)
+	}
+	if length, ok := raw["length"].(string); ok {
+		fd.Length = length
+	} else if _, ok := raw["length"]; ok {
+		return nil, fmt.Errorf("frames: length must be a $variable from the frame header")
+	}
+	fd.Match = parseFieldMap(map[string]any{"type": string(TypeMatch), "on": raw["on"], "cases": raw["cases"]}, nil)
+	if fd.Match.On == "" {
+		return nil, fmt.Errorf("frames: on: is required (the header field selecting the frame body)")
+	}
+	if len(fd.Header) == 0 {
+		return nil, fmt.Errorf("frames: header: must read at least one field")
+	}
+	return fd, nil
+}
+
+// decodeFrames decodes frames until the payload ends. Each frame is an
+// object holding its header fields and the fields of the selected case.
+func decodeFrames(fd *FramesDef, ctx *DecodeContext) ([]any, error) {
+	frames := []any{}
+	ctx.nested++
+	defer func() { ctx.nested-- }()
+	for ctx.Remaining() > 0 {
+		if limit := ctx.Limits.maxRepeat(); len(frames) >= limit {
+			return nil, fmt.Errorf("frames: more than %d frames", limit)
+		}
+		start := ctx.Offset
+		frame, err := decodeFields(fd.Header, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d at byte %d: %w", len(frames), start, err)
+		}
+		end := -1
+		if fd.Length != "" {
+			name := strings.TrimPrefix(fd.Length, "$")
+			n, ok := toInt(ctx.Variables[name])
+			if !ok || n < 0 {
+				return nil, fmt.Errorf("frame %d at byte %d: length variable %s not found", len(frames), start, fd.Length)
+			}
+			end = ctx.Offset + n
+			if end > len(ctx.Data) {
+				return nil, fmt.Errorf("frame %d at byte %d: length %d exceeds payload", len(frames), start, n)
+			}
+		}
+
+		body, err := decodeMatch(fd.Match, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d at byte %d: %w", len(frames), start, err)
+		}
+		if body == nil && end < 0 {
+			// Without a length the rest of the payload cannot be framed
+			return nil, fmt.Errorf("frame %d at byte %d: no case for %s = %v",
+				len(frames), start, fd.Match.On, ctx.Variables[strings.TrimPrefix(fd.Match.On, "$")])
+		}
+		if m, ok := body.(map[string]any); ok {
+			for k, v := range m {
+				frame[k] = v
+			}
+		}
+		if end >= 0 {
+			if ctx.Offset > end {
+				return nil, fmt.Errorf("frame %d at byte %d: body read %d bytes past its length",
+					len(frames), start, ctx.Offset-end)
+			}
+			ctx.Offset = end // Unknown types and trailing bytes are skipped
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// encodeFrames writes each frame in data[key]: its header, then the body
+// of the case its selector picks. A length variable is filled in from the
+// encoded body.
+func encodeFrames(fd *FramesDef, data map[string]any, ctx *EncodeContext) error {
+	raw, ok := data[fd.key()]
+	if !ok {
+		return nil
+	}
+	var frames []map[string]any
+	switch list := raw.(type) {
+	case []map[string]any:
+		frames = list
+	case []any:
+		for i, item := range list {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("frame %d: expected an object, got %T", i, item)
+			}
+			frames = append(frames, m)
+		}
+	default:
+		return fmt.Errorf("%s: expected an array of frames, got %T", fd.key(), raw)
+	}
+
+	for i, frame := range frames {
+		body := NewEncodeContext(ctx.Endian)
+		body.Definitions = ctx.Definitions
+		body.StrictTypes = ctx.StrictTypes
+		body.Overflow = ctx.Overflow
+		body.Rounding = ctx.Rounding
+		if err := encodeMatch(fd.Match, frame, frame, body); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+		if fd.Length != "" {
+			withLength := make(map[string]any, len(frame)+1)
+			for k, v := range frame {
+				withLength[k] = v
+			}
+			withLength[strings.TrimPrefix(fd.Length, "$")] = len(body.Buffer)
+			frame = withLength
+		}
+		if err := encodeFields(fd.Header, frame, ctx); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+		ctx.Write(body.Buffer)
+	}
+	return nil
+}
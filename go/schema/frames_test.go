@@ -0,0 +1,123 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const framesSchema = `
+name: multi_frame
+fields:
+  - {name: version, type: u8}
+frames:
+  header:
+    - {name: type, type: u8}
+    - {name: len, type: u8}
+  on: $type
+  length: $len
+  cases:
+    - case: 1
+      fields:
+        - {name: temperature, type: s16, div: 10}
+    - case: 2
+      fields:
+        - {name: lat, type: s32, div: 1000000}
+        - {name: lon, type: s32, div: 1000000}
+`
+
+func TestDecodeFrames(t *testing.T) {
+	s, err := ParseSchema(framesSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{
+		0x01,                   // version
+		0x01, 0x02, 0x00, 0xD7, // temperature 21.5
+		0x09, 0x01, 0xAA, // unknown type 9, skipped by length
+		0x02, 0x08, 0x02, 0xFA, 0xF0, 0x80, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x01, 0x02, 0xFF, 0x9C, // temperature -10
+	}
+	result, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"version": 1.0,
+		"frames": []any{
+			map[string]any{"type": 1.0, "len": 2.0, "temperature": 21.5},
+			map[string]any{"type": 9.0, "len": 1.0},
+			map[string]any{"type": 2.0, "len": 8.0, "lat": 50.0, "lon": -0.000001},
+			map[string]any{"type": 1.0, "len": 2.0, "temperature": -10.0},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Decode() = %v\nwant %v", result, want)
+	}
+
+	// Frames encode back, with len filled in from each body
+	values := map[string]any{
+		"version": 1,
+		"frames": []any{
+			map[string]any{"type": 1, "temperature": 21.5},
+			map[string]any{"type": 2, "lat": 50.0, "lon": -0.000001},
+		},
+	}
+	encoded, err := s.Encode(values)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	wantBytes := []byte{0x01, 0x01, 0x02, 0x00, 0xD7, 0x02, 0x08, 0x02, 0xFA, 0xF0, 0x80, 0xFF, 0xFF, 0xFF, 0xFF}
+	if !bytes.Equal(encoded, wantBytes) {
+		t.Errorf("Encode() = %X, want %X", encoded, wantBytes)
+	}
+
+	layout, err := s.Layout(0)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	if layout.MaxSize != Unbounded {
+		t.Errorf("Layout().MaxSize = %d, want unbounded", layout.MaxSize)
+	}
+}
+
+func TestDecodeFramesErrors(t *testing.T) {
+	s, err := ParseSchema(`
+name: no_length
+frames:
+  header:
+    - {name: type, type: u8}
+  on: $type
+  cases:
+    - case: 1
+      fields:
+        - {name: level, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode(nil)
+	if err != nil || !reflect.DeepEqual(result["frames"], []any{}) {
+		t.Errorf("Decode(empty) = %v, %v; want no frames", result, err)
+	}
+	if _, err := s.Decode([]byte{0x01, 0x10, 0x07, 0x00}); err == nil || !strings.Contains(err.Error(), "frame 1 at byte 2: no case for $type = 7") {
+		t.Errorf("Decode() error = %v, want unknown type without length", err)
+	}
+
+	s, err = ParseSchema(framesSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.Decode([]byte{0x01, 0x01, 0x05, 0x00}); err == nil || !strings.Contains(err.Error(), "exceeds payload") {
+		t.Errorf("Decode() error = %v, want length overrun", err)
+	}
+
+	if _, err := ParseSchema("name: x\nframes:\n  header: [{name: t, type: u8}]\n  cases: []\n"); err == nil {
+		t.Error("ParseSchema() accepted frames without on:")
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/MultiTechSystems/lorawan-payload-schema/go/schema/gojacodec
+
+go 1.21
+
+require (
+	github.com/MultiTechSystems/lorawan-payload-schema/go/schema v0.0.0
+	github.com/dop251/goja v0.0.0-20260106131823-651366fbe6e3
+)
+
+require (
+	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/MultiTechSystems/lorawan-payload-schema/go/schema => ../
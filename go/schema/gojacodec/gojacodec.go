@@ -0,0 +1,132 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+// Package gojacodec runs TS013 JavaScript codecs in the goja interpreter,
+// so a vendor codec can be checked against a schema for parity:
+//
+//	codec, err := gojacodec.New(vendorJS)
+//	report := schema.CheckConformance(s, codec, cases, schema.ConformanceOptions{})
+//
+// It is a module of its own so the schema package does not depend on goja.
+package gojacodec
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+
+	schema "github.com/MultiTechSystems/lorawan-payload-schema/go/schema"
+)
+
+// Codec is a schema.TS013Codec backed by one goja runtime. A runtime runs
+// one call at a time, so concurrent DecodeUplink calls are serialized.
+type Codec struct {
+	// Timeout interrupts a decodeUplink call that runs longer (no limit
+	// if zero).
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	vm     *goja.Runtime
+	decode goja.Callable
+}
+
+// New evaluates source, which must define a global decodeUplink(input)
+// function as TS013 specifies.
+func New(source string) (*Codec, error) {
+	vm := goja.New()
+	if _, err := vm.RunString(source); err != nil {
+		return nil, fmt.Errorf("codec: %w", err)
+	}
+	decode, ok := goja.AssertFunction(vm.Get("decodeUplink"))
+	if !ok {
+		return nil, fmt.Errorf("codec: decodeUplink is not a function")
+	}
+	return &Codec{vm: vm, decode: decode}, nil
+}
+
+// DecodeUplink calls decodeUplink with {bytes, fPort, recvTime} and
+// returns the data, warnings and errors of its result.
+func (c *Codec) DecodeUplink(input schema.TS013Input) (*schema.TS013Output, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Timeout > 0 {
+		timer := time.AfterFunc(c.Timeout, func() {
+			c.vm.Interrupt(fmt.Sprintf("exceeded time limit of %v", c.Timeout))
+		})
+		defer func() {
+			timer.Stop()
+			c.vm.ClearInterrupt()
+		}()
+	}
+
+	arg, err := c.input(input)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.decode(goja.Undefined(), arg)
+	if err != nil {
+		return nil, fmt.Errorf("decodeUplink: %w", err)
+	}
+	return exportOutput(res)
+}
+
+// input builds the decodeUplink argument. bytes is a plain array of
+// numbers and recvTime a Date, as TS013 codecs expect.
+func (c *Codec) input(in schema.TS013Input) (goja.Value, error) {
+	bytes := make([]any, len(in.Bytes))
+	for i, b := range in.Bytes {
+		bytes[i] = int(b)
+	}
+	obj := c.vm.NewObject()
+	if err := obj.Set("bytes", c.vm.NewArray(bytes...)); err != nil {
+		return nil, err
+	}
+	if err := obj.Set("fPort", in.FPort); err != nil {
+		return nil, err
+	}
+	if !in.RecvTime.IsZero() {
+		date, err := c.vm.New(c.vm.Get("Date"), c.vm.ToValue(in.RecvTime.UnixMilli()))
+		if err != nil {
+			return nil, err
+		}
+		if err := obj.Set("recvTime", date); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// exportOutput converts a decodeUplink result to Go values.
+func exportOutput(v goja.Value) (*schema.TS013Output, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, fmt.Errorf("decodeUplink returned no result")
+	}
+	m, ok := v.Export().(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("decodeUplink returned %s, want an object", v.String())
+	}
+	out := &schema.TS013Output{
+		Warnings: stringList(m["warnings"]),
+		Errors:   stringList(m["errors"]),
+	}
+	if data, ok := m["data"].(map[string]any); ok {
+		out.Data = data
+	}
+	return out, nil
+}
+
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, fmt.Sprint(item))
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
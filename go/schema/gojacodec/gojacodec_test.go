@@ -0,0 +1,108 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package gojacodec
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	schema "github.com/MultiTechSystems/lorawan-payload-schema/go/schema"
+)
+
+const envCodec = `
+function decodeUplink(input) {
+  if (input.bytes.length < 3) {
+    return { errors: ["payload too short"] };
+  }
+  var raw = (input.bytes[0] << 8) | input.bytes[1];
+  if (raw & 0x8000) raw -= 0x10000;
+  return {
+    data: {
+      temperature: raw / 10,
+      humidity: input.bytes[2],
+      port: input.fPort,
+      year: input.recvTime.getUTCFullYear()
+    },
+    warnings: input.bytes[2] > 100 ? ["humidity out of range"] : []
+  };
+}
+`
+
+func TestCodecDecodeUplink(t *testing.T) {
+	c, err := New(envCodec)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	out, err := c.DecodeUplink(schema.TS013Input{
+		Bytes:    []byte{0xFF, 0x38, 120},
+		FPort:    2,
+		RecvTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("DecodeUplink() error = %v", err)
+	}
+	// goja exports whole numbers as int64
+	if out.Data["temperature"] != int64(-20) || out.Data["humidity"] != int64(120) ||
+		out.Data["port"] != int64(2) || out.Data["year"] != int64(2026) {
+		t.Errorf("Data = %v", out.Data)
+	}
+	if len(out.Warnings) != 1 || out.Warnings[0] != "humidity out of range" || out.Errors != nil {
+		t.Errorf("Warnings = %v, Errors = %v", out.Warnings, out.Errors)
+	}
+
+	out, err = c.DecodeUplink(schema.TS013Input{Bytes: []byte{0x01}})
+	if err != nil || len(out.Errors) != 1 {
+		t.Errorf("DecodeUplink(short) = %+v, %v, want one error", out, err)
+	}
+}
+
+func TestCodecConformance(t *testing.T) {
+	s, err := schema.ParseSchema(`
+name: env
+fields:
+  - {name: temperature, type: s16, div: 10}
+  - {name: humidity, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	c, err := New(envCodec)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	report := schema.CheckConformance(s, c, []schema.ConformanceCase{
+		{Name: "cold", Bytes: []byte{0xFF, 0x38, 40}, FPort: 1},
+		{Name: "warm", Bytes: []byte{0x00, 0xFA, 55}, FPort: 1},
+		{Name: "short", Bytes: []byte{0x00}, FPort: 1},
+	}, schema.ConformanceOptions{Ignore: []string{"port", "year"}})
+	if !report.OK() {
+		t.Errorf("report = %v", report)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	if _, err := New("var decodeUplink = 1;"); err == nil || !strings.Contains(err.Error(), "not a function") {
+		t.Errorf("New() error = %v, want not a function", err)
+	}
+	if _, err := New("function decodeUplink( {"); err == nil {
+		t.Error("New() of invalid source should fail")
+	}
+
+	c, err := New("function decodeUplink(input) { for (;;) {} }")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	c.Timeout = 20 * time.Millisecond
+	if _, err := c.DecodeUplink(schema.TS013Input{Bytes: []byte{0x01}}); err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Errorf("DecodeUplink() error = %v, want time limit", err)
+	}
+
+	// The runtime is usable after an interrupted call
+	c, _ = New("function decodeUplink(input) { return 42; }")
+	if _, err := c.DecodeUplink(schema.TS013Input{}); err == nil || !strings.Contains(err.Error(), "want an object") {
+		t.Errorf("DecodeUplink() error = %v, want an object", err)
+	}
+}
@@ -0,0 +1,699 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoldenVector is a (values, payload) pair that both sides of a link must
+// agree on: decoding Payload yields Values and encoding Values yields
+// Payload, bit for bit.
+type GoldenVector struct {
+	Name    string         `json:"name" yaml:"name"`
+	FPort   int            `json:"fport,omitempty" yaml:"fport,omitempty"`
+	Values  map[string]any `json:"values" yaml:"values"`
+	Payload string         `json:"payload" yaml:"payload"` // Hex
+}
+
+// maxGoldenFlagGroups is the largest flagged construct whose group
+// combinations are enumerated exhaustively; larger ones get none, each
+// group alone, and all groups.
+const maxGoldenFlagGroups = 4
+
+// GenerateGolden produces reproducible golden vectors for firmware unit
+// tests: a baseline, the minimum and maximum of each numeric field, each
+// match case, and each flagged group combination, for every port. Field
+// values not under test are drawn from a generator seeded with seed, so the
+// same schema and seed always give the same vectors.
+//
+// Every vector is checked to round-trip exactly; a schema that cannot
+// (e.g. an encoder-ignored _field owning wire bytes) is reported as an
+// error naming the vector. TLV fields get one record per case, in tag
+// order; WASM fields are not supported.
+func (s *Schema) GenerateGolden(seed int64) ([]GoldenVector, error) {
+	var vectors []GoldenVector
+	for _, port := range goldenPorts(s) {
+		fields, err := s.ResolveFields(port)
+		if err != nil {
+			return nil, err
+		}
+		all := append(append([]Field{}, s.ResolveHeader(port)...), fields...)
+
+		d := &goldenDiscovery{defs: s.Definitions, counts: map[string]uint64{}}
+		if err := d.walk(all, ""); err != nil {
+			return nil, err
+		}
+
+		seen := map[string]bool{}
+		for _, sc := range d.scenarios() {
+			g := &goldenGen{
+				rng:      rand.New(rand.NewSource(seed)),
+				defs:     s.Definitions,
+				endian:   s.Endian,
+				scenario: sc,
+				counts:   d.counts,
+				vars:     map[string]uint64{},
+			}
+			if err := g.walk(all, ""); err != nil {
+				return nil, fmt.Errorf("golden %s: %w", sc.name, err)
+			}
+			payload := g.buf.Bytes()
+			key := hex.EncodeToString(payload)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			name := sc.name
+			if len(s.Ports) > 0 {
+				name = fmt.Sprintf("port %d %s", port, name)
+			}
+			values, err := s.DecodeWithPort(payload, port)
+			if err != nil {
+				return nil, fmt.Errorf("golden %s: decode %X: %w", name, payload, err)
+			}
+			encoded, err := s.EncodeWithPort(values, port)
+			if err != nil {
+				return nil, fmt.Errorf("golden %s: encode: %w", name, err)
+			}
+			if !bytes.Equal(encoded, payload) {
+				return nil, fmt.Errorf("golden %s: payload %X does not round-trip, encodes as %X", name, payload, encoded)
+			}
+			vectors = append(vectors, GoldenVector{Name: name, FPort: port, Values: values, Payload: strings.ToUpper(key)})
+		}
+	}
+	return vectors, nil
+}
+
+// goldenPorts lists the numeric ports in order, or 0 for a portless schema.
+func goldenPorts(s *Schema) []int {
+	if len(s.Ports) == 0 {
+		return []int{0}
+	}
+	var ports []int
+	for key := range s.Ports {
+		if n, err := strconv.Atoi(key); err == nil {
+			ports = append(ports, n)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// goldenScenario fixes some raw values; everything else is random.
+type goldenScenario struct {
+	name    string
+	extreme map[string]string // Field path -> "min" or "max"
+	pin     map[string]uint64 // Variable name or "#path" of an inline match -> raw value
+}
+
+// goldenDiscovery collects what the scenarios vary.
+type goldenDiscovery struct {
+	defs    map[string]*DefinitionDef
+	scalars []goldenScalar
+	reach   map[string]uint64 // Pins that reach the fields being walked
+	choices []goldenChoice
+	counts  map[string]uint64 // Repeat count variables -> element count
+	always  map[string]uint64 // Pins every scenario needs, e.g. a revision selector
+	depth   int
+}
+
+// goldenScalar is a numeric field and the pins that make it present.
+type goldenScalar struct {
+	path  string
+	reach map[string]uint64
+}
+
+// goldenChoice is a set of alternatives for one pinned value, e.g. the
+// cases of a match or the combinations of a flagged construct.
+type goldenChoice struct {
+	key    string
+	labels []string
+	values []uint64
+}
+
+func (d *goldenDiscovery) scenarios() []goldenScenario {
+	out := []goldenScenario{{name: "baseline"}}
+	for _, sc := range d.scalars {
+		if _, ok := d.counts[sc.path]; ok {
+			// Repeat counts stay at the element count the body needs
+			continue
+		}
+		if _, ok := d.always[sc.path]; ok {
+			// Revision selectors are varied by their cases
+			continue
+		}
+		out = append(out,
+			goldenScenario{name: sc.path + " min", extreme: map[string]string{sc.path: "min"}, pin: sc.reach},
+			goldenScenario{name: sc.path + " max", extreme: map[string]string{sc.path: "max"}, pin: sc.reach})
+	}
+	for _, c := range d.choices {
+		for i, v := range c.values {
+			out = append(out, goldenScenario{name: c.labels[i], pin: map[string]uint64{c.key: v}})
+		}
+	}
+	for i := range out {
+		if len(d.always) == 0 {
+			break
+		}
+		pin := map[string]uint64{}
+		for k, v := range d.always {
+			pin[k] = v
+		}
+		for k, v := range out[i].pin {
+			pin[k] = v
+		}
+		out[i].pin = pin
+	}
+	return out
+}
+
+func (d *goldenDiscovery) walk(fields []Field, prefix string) error {
+	for _, f := range fields {
+		path := prefix + f.Name
+		switch {
+		case f.Ref2 != "":
+			def, err := lookupDefinition(f.Ref2, d.defs)
+			if err != nil {
+				return err
+			}
+			if d.depth >= maxRefDepth {
+				return fmt.Errorf("$ref %s: nested deeper than %d (circular reference?)", f.Ref2, maxRefDepth)
+			}
+			d.depth++
+			err = d.walk(def.Fields, prefix)
+			d.depth--
+			if err != nil {
+				return err
+			}
+		case f.Flagged != nil:
+			d.choices = append(d.choices, flagChoice(f.Flagged))
+			for _, g := range f.Flagged.Groups {
+				if err := d.within(f.Flagged.Field, d.reach[f.Flagged.Field]|1<<g.Bit, g.Fields, prefix); err != nil {
+					return err
+				}
+			}
+		case f.MatchInline != nil:
+			if err := d.match(*f.MatchInline, prefix); err != nil {
+				return err
+			}
+		case f.Type == TypeMatch || f.Type == "CTRL-SWITCH" || f.Type == "Switch":
+			if err := d.match(f, matchPrefix(f, prefix)); err != nil {
+				return err
+			}
+		case f.Type == TypeObject:
+			if err := d.walk(f.Fields, path+"."); err != nil {
+				return err
+			}
+		case f.Type == TypeRepeat || f.Type == TypeRepeatLower:
+			if name, ok := f.Count.(string); ok {
+				n := uint64(2)
+				if f.Min > 2 {
+					n = uint64(f.Min)
+				}
+				if f.Max > 0 && n > uint64(f.Max) {
+					n = uint64(f.Max)
+				}
+				d.counts[strings.TrimPrefix(name, "$")] = n
+			}
+			if err := d.walk(f.Fields, path+"[]."); err != nil {
+				return err
+			}
+		case f.WASM != nil:
+			return fmt.Errorf("%s: WASM fields are not supported", pathOr(path, "(unnamed)"))
+		case f.Type == TypeTLV || f.Type == TypeTLVLower || f.TLVInline != nil:
+			tlv := f
+			if f.TLVInline != nil {
+				tlv = *f.TLVInline
+			}
+			for _, key := range sortedKeys(tlv.TLVCases) {
+				if err := d.walk(tlv.TLVCases[key], prefix); err != nil {
+					return err
+				}
+			}
+		case goldenNumeric(f) && f.Name != "" && !isPositionalView(f):
+			d.scalars = append(d.scalars, goldenScalar{path: path, reach: d.reach})
+		}
+	}
+	return nil
+}
+
+// within walks fields that are only present while key holds value.
+func (d *goldenDiscovery) within(key string, value uint64, fields []Field, prefix string) error {
+	outer := d.reach
+	d.reach = map[string]uint64{key: value}
+	for k, v := range outer {
+		if k != key {
+			d.reach[k] = v
+		}
+	}
+	err := d.walk(fields, prefix)
+	d.reach = outer
+	return err
+}
+
+func flagChoice(fd *FlaggedDef) goldenChoice {
+	c := goldenChoice{key: fd.Field}
+	add := func(mask uint64) {
+		c.labels = append(c.labels, fmt.Sprintf("%s=0b%b", fd.Field, mask))
+		c.values = append(c.values, mask)
+	}
+	var all uint64
+	for _, g := range fd.Groups {
+		all |= 1 << g.Bit
+	}
+	if len(fd.Groups) <= maxGoldenFlagGroups {
+		for combo := 0; combo < 1<<len(fd.Groups); combo++ {
+			var mask uint64
+			for i, g := range fd.Groups {
+				if combo&(1<<i) != 0 {
+					mask |= 1 << g.Bit
+				}
+			}
+			add(mask)
+		}
+		return c
+	}
+	add(0)
+	for _, g := range fd.Groups {
+		add(1 << g.Bit)
+	}
+	add(all)
+	return c
+}
+
+func (d *goldenDiscovery) match(f Field, prefix string) error {
+	key := strings.TrimPrefix(f.On, "$")
+	if f.On == "" {
+		key = "#" + prefix + f.Name
+	} else if !bareVarPattern.MatchString(f.On) {
+		// Expression selectors cannot be steered; cases are left to chance
+		key = ""
+	}
+
+	c := goldenChoice{key: key}
+	for _, cs := range f.Cases {
+		v, ok := caseRepresentative(cs)
+		label := fmt.Sprintf("%s=%d", strings.TrimPrefix(key, "#"), v)
+		if cs.Default {
+			v, ok = unmatchedCaseValue(f.Cases)
+			label = fmt.Sprintf("%s default", strings.TrimPrefix(key, "#"))
+		}
+		if key == "" || !ok {
+			if err := d.walk(cs.Fields, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+		c.labels = append(c.labels, label)
+		c.values = append(c.values, v)
+		if err := d.within(key, v, cs.Fields, prefix); err != nil {
+			return err
+		}
+	}
+	if len(c.values) > 0 {
+		d.choices = append(d.choices, c)
+		if _, ok := d.always[key]; f.revisions && !ok {
+			// An unknown revision does not decode
+			if d.always == nil {
+				d.always = map[string]uint64{}
+			}
+			d.always[key] = c.values[0]
+		}
+	}
+	return nil
+}
+
+// goldenNumeric reports whether a field has numeric min/max extremes.
+func goldenNumeric(f Field) bool {
+	switch f.Type {
+	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU24, TypeU32, TypeU64, TypeBInt,
+		TypeSInt, TypeS8, TypeS16, TypeS24, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64,
+		TypeFloat16, TypeFloat32, TypeFloat64, TypeF16, TypeF32, TypeF64,
+		TypeFixed, TypeUFixed, TypeEnum, TypeEnumLower:
+		return true
+	}
+	return false
+}
+
+// goldenGen writes one payload for a scenario.
+type goldenGen struct {
+	rng      *rand.Rand
+	defs     map[string]*DefinitionDef
+	endian   string
+	scenario goldenScenario
+	counts   map[string]uint64
+	vars     map[string]uint64 // Raw integer values written so far
+	random   bool              // Random payload: honour valid_range, vary open repeats and TLV records
+	buf      bytes.Buffer
+	depth    int
+}
+
+func (g *goldenGen) walk(fields []Field, prefix string) error {
+	inherited := g.endian
+	defer func() { g.endian = inherited }()
+	for _, f := range fields {
+		g.endian = blockEndian(f, inherited)
+		if err := g.field(f, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *goldenGen) field(f Field, prefix string) error {
+	path := prefix + f.Name
+	endian := f.Endian
+	if endian == "" {
+		endian = g.endian
+	}
+
+	switch {
+	case f.Ref2 != "":
+		def, err := lookupDefinition(f.Ref2, g.defs)
+		if err != nil {
+			return err
+		}
+		if g.depth >= maxRefDepth {
+			return fmt.Errorf("$ref %s: nested deeper than %d (circular reference?)", f.Ref2, maxRefDepth)
+		}
+		g.depth++
+		defer func() { g.depth-- }()
+		return g.walk(def.Fields, prefix)
+
+	case len(f.ByteGroup) > 0:
+		// Random member values composed like decodeByteGroup, so unused
+		// bits stay zero and the group round-trips
+		var raw uint64
+		for _, m := range f.ByteGroup {
+			if m.Name == "" || strings.HasPrefix(m.Name, "_") {
+				continue
+			}
+			start, length, _ := byteGroupBits(m)
+			v := g.rawFor(m, uint64(1)<<length-1) & (uint64(1)<<length - 1)
+			g.record(m, v)
+			raw |= v << start
+		}
+		g.buf.Write(encodeUint(raw, byteGroupSize(f), "little"))
+		return nil
+
+	case f.Flagged != nil:
+		mask := g.vars[f.Flagged.Field]
+		for _, grp := range f.Flagged.Groups {
+			if mask&(1<<grp.Bit) != 0 {
+				if err := g.walk(grp.Fields, prefix); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case f.MatchInline != nil:
+		return g.match(*f.MatchInline, prefix, endian)
+
+	case f.TLVInline != nil:
+		return g.tlv(*f.TLVInline, prefix)
+	}
+
+	switch f.Type {
+	case TypeMatch, "CTRL-SWITCH", "Switch":
+		return g.match(f, matchPrefix(f, prefix), endian)
+	case TypeObject:
+		return g.walk(f.Fields, path+".")
+	case TypeVector3:
+		return g.walk(vectorAxes(f), path+".")
+	case TypeRepeat, TypeRepeatLower:
+		return g.repeat(f, path)
+	case TypeTLV, TypeTLVLower:
+		return g.tlv(f, prefix)
+	case TypeSkip, TypeSkipLower:
+		g.buf.Write(make([]byte, fieldLength(f)))
+		return nil
+	case TypeReserved, TypeReservedLower:
+		g.buf.Write(reservedBytes(f, fieldLength(f)))
+		return nil
+	case TypeMagic, TypeMagicLower:
+		g.buf.Write(f.Expect)
+		return nil
+	}
+
+	if f.Type == TypeBool || f.Type == TypeBoolLower || f.Type == TypeBits || f.Type == TypeBitsLower {
+		// Shared-byte fields write only the bytes they consume, which
+		// hold nothing but their group's bits
+		g.buf.Write(make([]byte, consumeLength(f, 0)))
+		return nil
+	}
+	if isPositionalView(f) {
+		// Views read bytes owned by other fields
+		return nil
+	}
+	return g.scalar(f, path, endian)
+}
+
+func (g *goldenGen) match(f Field, prefix, endian string) error {
+	if f.On == "" {
+		length := f.Length
+		if length == 0 {
+			length = 1
+		}
+		sel, ok := g.scenario.pin["#"+prefix+f.Name]
+		if !ok {
+			if v, found := caseRepresentative(firstCase(f.Cases)); found {
+				sel = v
+			}
+		}
+		g.buf.Write(encodeUint(sel, length, g.endian))
+		return g.matchBody(f, int(sel), prefix)
+	}
+	// The selector field was written earlier, pinned or at random
+	return g.matchBody(f, int(g.vars[strings.TrimPrefix(f.On, "$")]), prefix)
+}
+
+func (g *goldenGen) matchBody(f Field, sel int, prefix string) error {
+	for _, c := range f.Cases {
+		if c.Default || caseMatches(c, sel) {
+			return g.walk(c.Fields, prefix)
+		}
+	}
+	return nil
+}
+
+// matchPrefix is the path prefix of a typed match's case fields, which
+// decode into an object named after the match.
+func matchPrefix(f Field, prefix string) string {
+	if f.Name == "" {
+		return prefix
+	}
+	return prefix + f.Name + "."
+}
+
+func firstCase(cases []Case) Case {
+	for _, c := range cases {
+		if !c.Default {
+			return c
+		}
+	}
+	return Case{}
+}
+
+func (g *goldenGen) repeat(f Field, path string) error {
+	n := 2
+	switch c := f.Count.(type) {
+	case string:
+		n = int(g.counts[strings.TrimPrefix(c, "$")])
+	case nil:
+		if f.ByteLength != nil {
+			return fmt.Errorf("%s: repeat byte_length is not supported", path)
+		}
+		if g.random {
+			n = 1 + g.rng.Intn(4)
+		}
+	default:
+		if count, ok := toWholeInt(c); ok {
+			n = count
+		}
+	}
+	for i := 0; i < n; i++ {
+		if err := g.walk(f.Fields, path+"[]."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record remembers the raw value written for a field so later flagged
+// and match constructs follow the same path the decoder will.
+func (g *goldenGen) record(f Field, raw uint64) {
+	for _, name := range []string{f.Name, f.Var} {
+		if name != "" {
+			g.vars[name] = raw
+		}
+	}
+}
+
+// rawFor returns the raw value for an integer field: pinned, a repeat
+// count, a lookup key, or random within max.
+func (g *goldenGen) rawFor(f Field, max uint64) uint64 {
+	if v, ok := g.fixed(f); ok {
+		return v
+	}
+	if keys := tableKeys(f); len(keys) > 0 {
+		return uint64(keys[g.rng.Intn(len(keys))])
+	}
+	if max >= math.MaxInt64 {
+		return g.rng.Uint64() & max
+	}
+	return uint64(g.rng.Int63n(int64(max) + 1))
+}
+
+// fixed returns the raw value a scenario pin or repeat count imposes on f.
+func (g *goldenGen) fixed(f Field) (uint64, bool) {
+	for _, name := range []string{f.Name, f.Var} {
+		if name == "" {
+			continue
+		}
+		if v, ok := g.scenario.pin[name]; ok {
+			return v, true
+		}
+		if v, ok := g.counts[name]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// tableKeys returns the sorted keys of a field's enum values or lookup.
+func tableKeys(f Field) []int {
+	table := f.Values
+	if table == nil {
+		table = f.Lookup
+	}
+	keys := make([]int, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func (g *goldenGen) scalar(f Field, path, endian string) error {
+	extreme := g.scenario.extreme[path]
+	length := fieldLength(f)
+
+	switch f.Type {
+	case TypeFloat16, TypeF16, TypeFloat32, TypeF32, TypeFloat64, TypeF64:
+		size := map[FieldType]int{TypeFloat16: 2, TypeF16: 2, TypeFloat32: 4, TypeF32: 4}[f.Type]
+		limit := map[int]float64{2: 65504, 4: math.MaxFloat32, 0: math.MaxFloat64}[size]
+		v := math.Round((g.rng.Float64()*200-100)*8) / 8 // Exact in every width
+		if lo, hi, ok := g.validRange(f); ok {
+			v = lo + g.rng.Float64()*(hi-lo)
+		}
+		switch extreme {
+		case "min":
+			v = -limit
+		case "max":
+			v = limit
+		}
+		switch size {
+		case 2:
+			g.buf.Write(encodeFloat16(v, endian))
+		case 4:
+			g.buf.Write(encodeFloat32(float32(v), endian))
+		default:
+			g.buf.Write(encodeFloat64(v, endian))
+		}
+		return nil
+
+	case TypeEnum, TypeEnumLower:
+		length = enumBaseLength(f)
+	case TypeFixed, TypeUFixed:
+		length = fixedLength(f)
+	case TypeAscii, TypeAsciiLower, TypeString, TypeStringLower:
+		text := make([]byte, length)
+		for i := range text {
+			text[i] = byte('A' + g.rng.Intn(26))
+		}
+		g.buf.Write(text)
+		return nil
+	case TypeHex, TypeBytes, TypeBytesLower:
+		data := make([]byte, length)
+		g.rng.Read(data)
+		g.buf.Write(data)
+		return nil
+	case TypeBitfieldString:
+		var raw uint64
+		for _, part := range f.Parts {
+			if len(part) < 2 {
+				continue
+			}
+			off, _ := toInt(part[0])
+			n, _ := toInt(part[1])
+			raw |= (g.rng.Uint64() & (uint64(1)<<n - 1)) << off
+		}
+		g.buf.Write(encodeUint(raw, length, endian))
+		return nil
+	case TypeNumber, "number":
+		return nil
+	}
+
+	bits := uint(length * 8)
+	mask := uint64(math.MaxUint64)
+	if bits < 64 {
+		mask = uint64(1)<<bits - 1
+	}
+	signed := isSignedGoldenType(f.Type)
+	var raw uint64
+	if keys := tableKeys(f); len(keys) > 0 && extreme != "" {
+		raw = uint64(keys[0])
+		if extreme == "max" {
+			raw = uint64(keys[len(keys)-1])
+		}
+	} else {
+		switch {
+		case extreme == "min" && signed:
+			raw = uint64(1) << (bits - 1)
+		case extreme == "max" && signed:
+			raw = uint64(1)<<(bits-1) - 1
+		case extreme == "min":
+			raw = 0
+		case extreme == "max":
+			raw = mask
+		default:
+			raw = g.rawFor(f, mask)
+			if r, ok := g.rangedRaw(f, bits, signed); ok {
+				raw = r
+			}
+		}
+	}
+	if f.Type == TypeBInt {
+		endian = "big"
+	}
+	g.record(f, raw&mask)
+	g.buf.Write(encodeUint(raw&mask, length, endian))
+	return nil
+}
+
+func isSignedGoldenType(t FieldType) bool {
+	switch t {
+	case TypeSInt, TypeS8, TypeS16, TypeS24, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64, TypeFixed:
+		return true
+	}
+	return false
+}
+
+func fieldLength(f Field) int {
+	if f.Length > 0 {
+		return f.Length
+	}
+	return inferLengthFromType(f.Type)
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGoldenExtremes(t *testing.T) {
+	s, err := ParseSchema(`
+name: env
+endian: big
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+  - name: pressure
+    type: u24
+  - type: skip
+    length: 1
+  - name: ratio
+    type: f16
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	vectors, err := s.GenerateGolden(42)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+
+	byName := map[string]GoldenVector{}
+	for _, v := range vectors {
+		byName[v.Name] = v
+	}
+	for _, name := range []string{"baseline", "temperature min", "temperature max", "pressure max", "ratio min", "ratio max"} {
+		if _, ok := byName[name]; !ok {
+			t.Errorf("missing vector %q", name)
+		}
+	}
+	if got := byName["temperature min"].Values["temperature"]; got != -3276.8 {
+		t.Errorf("temperature min = %v, want -3276.8", got)
+	}
+	if got := byName["pressure max"].Values["pressure"]; got != float64(0xFFFFFF) {
+		t.Errorf("pressure max = %v, want %v", got, 0xFFFFFF)
+	}
+	if got := byName["ratio max"].Values["ratio"]; got != 65504.0 {
+		t.Errorf("ratio max = %v, want 65504", got)
+	}
+	if p := byName["baseline"].Payload; len(p) != 16 || p[10:12] != "00" {
+		t.Errorf("baseline payload = %s, want 8 bytes with a zero skip byte", p)
+	}
+
+	again, err := s.GenerateGolden(42)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+	if !reflect.DeepEqual(vectors, again) {
+		t.Error("same seed gave different vectors")
+	}
+	other, _ := s.GenerateGolden(7)
+	if reflect.DeepEqual(vectors[0], other[0]) {
+		t.Error("different seeds gave the same baseline")
+	}
+}
+
+func TestGenerateGoldenCases(t *testing.T) {
+	s, err := ParseSchema(`
+name: multi
+fields:
+  - name: kind
+    type: u8
+  - name: reading
+    type: Match
+    on: $kind
+    cases:
+      - case: 1
+        fields:
+          - {name: temperature, type: s16}
+      - case: 2
+        fields:
+          - {name: humidity, type: u8}
+      - default: true
+        fields: []
+  - name: flags
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - {name: battery, type: u8}
+        - bit: 1
+          fields:
+            - {name: counter, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	vectors, err := s.GenerateGolden(1)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+
+	byName := map[string]GoldenVector{}
+	for _, v := range vectors {
+		byName[v.Name] = v
+	}
+	reading := func(name, key string) any {
+		r, _ := byName[name].Values["reading"].(map[string]any)
+		return r[key]
+	}
+	if reading("kind=1", "temperature") == nil {
+		t.Errorf("kind=1 vector = %+v, want reading.temperature", byName["kind=1"])
+	}
+	if reading("kind=2", "humidity") == nil {
+		t.Errorf("kind=2 vector = %+v, want reading.humidity", byName["kind=2"])
+	}
+	if got := reading("reading.temperature max", "temperature"); got != 32767.0 {
+		t.Errorf("reading.temperature max = %v, want 32767", got)
+	}
+	if got := byName["battery min"].Values["flags"]; got != 1.0 {
+		t.Errorf("battery min flags = %v, want only the battery group", got)
+	}
+
+	// Vectors with the same payload are merged, so look for each flag
+	// combination by content
+	combos := map[int]bool{}
+	for _, v := range vectors {
+		flags, _ := toInt(v.Values["flags"])
+		_, battery := v.Values["battery"]
+		_, counter := v.Values["counter"]
+		if battery != (flags&1 != 0) || counter != (flags&2 != 0) {
+			t.Errorf("%s: flags %d with battery=%v counter=%v", v.Name, flags, battery, counter)
+		}
+		combos[flags&3] = true
+	}
+	if len(combos) != 4 {
+		t.Errorf("flag combinations = %v, want all 4", combos)
+	}
+
+	// Every vector holds both ways
+	for _, v := range vectors {
+		payload, _ := hex.DecodeString(v.Payload)
+		encoded, err := s.Encode(v.Values)
+		if err != nil || !bytes.Equal(encoded, payload) {
+			t.Errorf("%s: Encode() = %X, %v; want %s", v.Name, encoded, err, v.Payload)
+		}
+	}
+}
+
+func TestGenerateGoldenMatchVarName(t *testing.T) {
+	// The selector is the var:, which differs from the field name
+	s, err := ParseSchema(`
+name: events
+fields:
+  - {name: event_type, type: u8, var: evt}
+  - match:
+      field: $evt
+      cases:
+        0:
+          - {name: a, type: u8}
+        1:
+          - {name: b, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	encoded, err := s.Encode(map[string]any{"event_type": 0, "a": 5})
+	if err != nil || !bytes.Equal(encoded, []byte{0x00, 0x05}) {
+		t.Fatalf("Encode() = %X, %v; want 0005", encoded, err)
+	}
+
+	vectors, err := s.GenerateGolden(1)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+	for _, v := range vectors {
+		payload, _ := hex.DecodeString(v.Payload)
+		encoded, err := s.Encode(v.Values)
+		if err != nil || !bytes.Equal(encoded, payload) {
+			t.Errorf("%s: Encode() = %X, %v; want %s", v.Name, encoded, err, v.Payload)
+		}
+	}
+}
+
+func TestGenerateGoldenPorts(t *testing.T) {
+	s, err := ParseSchema(`
+name: ported
+ports:
+  1:
+    direction: uplink
+    fields:
+      - {name: count, type: u8}
+      - name: readings
+        type: repeat
+        count: $count
+        fields:
+          - {name: v, type: u16}
+  10:
+    direction: downlink
+    fields:
+      - {name: interval, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	vectors, err := s.GenerateGolden(3)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+	ports := map[int]int{}
+	for _, v := range vectors {
+		ports[v.FPort]++
+		if !strings.HasPrefix(v.Name, "port ") {
+			t.Errorf("vector name %q lacks port", v.Name)
+		}
+		if v.FPort == 1 {
+			if readings, _ := v.Values["readings"].([]any); len(readings) != 2 {
+				t.Errorf("%s: readings = %v, want 2 elements", v.Name, v.Values["readings"])
+			}
+		}
+	}
+	if ports[1] == 0 || ports[10] == 0 {
+		t.Errorf("vectors per port = %v, want both ports", ports)
+	}
+}
+
+func TestGenerateGoldenUnsupported(t *testing.T) {
+	s, err := ParseSchema(`
+name: wasm
+fields:
+  - wasm:
+      module: vendor.wasm
+      fn: decode
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.GenerateGolden(1); err == nil || !strings.Contains(err.Error(), "WASM") {
+		t.Errorf("GenerateGolden() error = %v, want WASM not supported", err)
+	}
+}
+
+func TestGenerateGoldenTLV(t *testing.T) {
+	s, err := ParseSchema(`
+name: tlv
+endian: little
+fields:
+  - tlv:
+      tag_fields:
+        - {name: channel_id, type: u8}
+        - {name: channel_type, type: u8}
+      tag_key: [channel_id, channel_type]
+      cases:
+        "[3, 0x67]":
+          - {name: temperature, type: s16, mult: 0.1}
+        "[1, 0x75]":
+          - {name: battery, type: u8}
+        "[4, 0x67]":
+          - {name: temperature, type: s16, mult: 0.1}
+          - {name: alarm, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	vectors, err := s.GenerateGolden(1)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+	for _, v := range vectors {
+		// [4, 0x67] repeats temperature, so only [1, 0x75] and [3, 0x67]
+		if !strings.HasPrefix(v.Payload, "0175") || v.Payload[6:10] != "0367" || len(v.Payload) != 14 {
+			t.Errorf("%s: Payload = %s, want records [1, 0x75] and [3, 0x67]", v.Name, v.Payload)
+		}
+		if _, ok := v.Values["alarm"]; ok {
+			t.Errorf("%s: Values = %v, want no alarm", v.Name, v.Values)
+		}
+	}
+	if len(vectors) < 3 {
+		t.Errorf("got %d vectors, want baseline and temperature/battery extremes", len(vectors))
+	}
+}
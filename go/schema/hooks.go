@@ -0,0 +1,56 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FieldHook is called after each named field decodes, with the field's
+// path in the result (keys as Flatten joins them, e.g. readings.2.temp),
+// the payload bytes it read and its value. It returns the value to store,
+// which lets a hook count or trace fields, or correct a single field a
+// firmware batch gets wrong without forking the schema:
+//
+//	fix := func(path string, raw []byte, v any) any {
+//		if path == "battery" && len(raw) == 1 {
+//			return float64(raw[0]) / 50 // Batch 7 sends 20 mV steps
+//		}
+//		return v
+//	}
+//	result, err := s.Decode(payload, schema.WithFieldHook(fix))
+//
+// Returning nil leaves the field out. Objects and repeats are passed to
+// the hook after their members, with all the bytes they span. Range
+// checks apply to the value the hook returns.
+type FieldHook func(path string, raw []byte, value any) any
+
+// WithFieldHook calls hook after each named field decodes. Several hooks
+// run in the order given, each seeing the value the previous one returned.
+func WithFieldHook(hook FieldHook) DecodeOption {
+	return func(c *decodeConfig) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// runHooks passes a decoded field through the hooks.
+func (ctx *DecodeContext) runHooks(raw []byte, value any) any {
+	path := strings.Join(ctx.path, ".")
+	for _, hook := range ctx.hooks {
+		value = hook(path, raw, value)
+	}
+	return value
+}
+
+// decodeElement decodes repeat element i, under its index in hook paths.
+func decodeElement(field Field, i int, ctx *DecodeContext) (map[string]any, error) {
+	if ctx.hooks == nil {
+		return decodeFields(field.Fields, ctx)
+	}
+	ctx.path = append(ctx.path, strconv.Itoa(i))
+	defer func() { ctx.path = ctx.path[:len(ctx.path)-1] }()
+	return decodeFields(field.Fields, ctx)
+}
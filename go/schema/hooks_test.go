@@ -0,0 +1,82 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestWithFieldHook(t *testing.T) {
+	s, err := ParseSchema(`
+name: hooks
+fields:
+  - {name: battery, type: u8, div: 10, valid_range: [2, 4]}
+  - name: env
+    type: Object
+    fields:
+      - {name: temp, type: s16, div: 10}
+  - name: readings
+    type: repeat
+    count: 2
+    fields:
+      - {name: v, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x64, 0x00, 0xFA, 0x01, 0x02}
+
+	var seen []string
+	raws := map[string]string{}
+	trace := func(path string, raw []byte, v any) any {
+		seen = append(seen, path)
+		raws[path] = hex.EncodeToString(raw)
+		return v
+	}
+	// A batch that sends battery in 20 mV steps
+	fix := func(path string, raw []byte, v any) any {
+		if path == "battery" {
+			return float64(raw[0]) / 50
+		}
+		return v
+	}
+	got, err := s.Decode(payload, WithFieldHook(trace), WithFieldHook(fix))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	want := []string{"battery", "env.temp", "env", "readings.0.v", "readings.1.v", "readings"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("hook paths = %v, want %v", seen, want)
+	}
+	if raws["env"] != "00fa" || raws["readings"] != "0102" || raws["readings.1.v"] != "02" {
+		t.Errorf("hook raw = %v", raws)
+	}
+	if got["battery"] != 2.0 {
+		t.Errorf("battery = %v, want shimmed 2", got["battery"])
+	}
+	if q, _ := got["_quality"].(map[string]string); q["battery"] != "good" {
+		t.Errorf("_quality = %v, want range checked after the hook", got["_quality"])
+	}
+	if env, _ := got["env"].(map[string]any); env["temp"] != 25.0 {
+		t.Errorf("env = %v", got["env"])
+	}
+
+	drop := func(path string, raw []byte, v any) any {
+		if path == "env" {
+			return nil
+		}
+		return v
+	}
+	got, err = s.Decode(payload, WithFieldHook(drop))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if _, ok := got["env"]; ok {
+		t.Errorf("env = %v, want dropped", got["env"])
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// InferHintKey is the field extension holding inference notes.
+const InferHintKey = "x-infer"
+
+// Infer proposes a draft schema from sample payloads of one message type,
+// for reverse engineering undocumented devices. It is a starting point, not
+// an answer: fields are named by offset (value_3, counter_1) and each
+// carries its reasoning under the x-infer extension.
+//
+// Heuristics, in order, at each offset:
+//   - a byte that never changes is a constant (magic or version byte)
+//   - a 1/2/4-byte window whose value never decreases is a counter
+//   - two bytes where one takes only a few adjacent values while the other
+//     sweeps most of its range are the high and low bytes of a 16-bit value;
+//     which one is high gives the endianness, a high byte crossing 0xFF/0x00
+//     suggests a signed value
+//   - anything else is a u8, flagged as bits or bool when it looks like one
+//
+// Samples should be in receive order so counters can be recognized. Only
+// the shortest sample's length is analysed.
+func Infer(samples [][]byte) (*Schema, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("infer: no samples")
+	}
+	minLen, maxLen := len(samples[0]), len(samples[0])
+	for _, s := range samples {
+		if len(s) < minLen {
+			minLen = len(s)
+		}
+		if len(s) > maxLen {
+			maxLen = len(s)
+		}
+	}
+	if minLen == 0 {
+		return nil, fmt.Errorf("infer: empty sample")
+	}
+
+	cols := make([][]byte, minLen)
+	for i := range cols {
+		cols[i] = make([]byte, len(samples))
+		for j, s := range samples {
+			cols[i][j] = s[i]
+		}
+	}
+
+	var fields []Field
+	endianVotes := map[string]int{}
+	for off := 0; off < minLen; {
+		if f, width, endian, ok := inferCounter(cols, off, len(samples)); ok {
+			fields = append(fields, f)
+			if width > 1 {
+				endianVotes[endian]++
+			}
+			off += width
+			continue
+		}
+		if distinctCount(cols[off]) == 1 {
+			fields = append(fields, inferField(fmt.Sprintf("const_%d", off), TypeU8, "",
+				fmt.Sprintf("constant 0x%02X: magic or version byte?", cols[off][0])))
+			off++
+			continue
+		}
+		if f, endian, ok := inferWord(cols, off); ok {
+			fields = append(fields, f)
+			endianVotes[endian]++
+			off += 2
+			continue
+		}
+		fields = append(fields, inferByte(cols[off], off))
+		off++
+	}
+
+	s := &Schema{Name: "inferred", Endian: "big"}
+	if endianVotes["little"] > endianVotes["big"] {
+		s.Endian = "little"
+	}
+	for i := range fields {
+		if fields[i].Endian == s.Endian {
+			fields[i].Endian = ""
+		}
+	}
+	s.Fields = fields
+	s.Extensions = map[string]any{"x-infer-samples": len(samples)}
+	if maxLen != minLen {
+		s.Extensions["x-infer-note"] = fmt.Sprintf(
+			"payload length varies (%d..%d bytes); only the first %d bytes were analysed", minLen, maxLen, minLen)
+	}
+	if err := s.finish(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func inferField(name string, t FieldType, endian, hint string) Field {
+	return Field{Name: name, Type: t, Endian: endian, Extensions: map[string]any{InferHintKey: hint}}
+}
+
+// inferCounter recognizes a monotonic counter starting at off.
+func inferCounter(cols [][]byte, off, n int) (Field, int, string, bool) {
+	if n < 3 {
+		return Field{}, 0, "", false
+	}
+	// Narrowest first: the high bytes of a wide counter also never
+	// decrease, but only the full counter increases on most samples
+	for _, width := range []int{1, 2, 4} {
+		if off+width > len(cols) {
+			continue
+		}
+		endians := []string{"big", "little"}
+		if width == 1 {
+			endians = endians[:1]
+		}
+		for _, endian := range endians {
+			msb, lsb := off, off+width-1
+			if endian == "little" {
+				msb, lsb = lsb, msb
+			}
+			if distinctCount(cols[msb]) == 1 || distinctCount(cols[lsb]) == 1 {
+				continue
+			}
+			vals := windowValues(cols, off, width, endian)
+			increases := 0
+			monotonic := true
+			for j := 1; j < n; j++ {
+				if vals[j] < vals[j-1] {
+					monotonic = false
+					break
+				}
+				if vals[j] > vals[j-1] {
+					increases++
+				}
+			}
+			if !monotonic || increases*2 < n-1 {
+				continue
+			}
+			t := map[int]FieldType{1: TypeU8, 2: TypeU16, 4: TypeU32}[width]
+			hint := fmt.Sprintf("never decreases (%d..%d): frame counter or uptime?", vals[0], vals[n-1])
+			if width == 1 {
+				endian = ""
+			}
+			return inferField(fmt.Sprintf("counter_%d", off), t, endian, hint), width, endian, true
+		}
+	}
+	return Field{}, 0, "", false
+}
+
+// inferWord recognizes a 16-bit value at off by its high byte staying
+// within a few adjacent values while the low byte sweeps its range.
+func inferWord(cols [][]byte, off int) (Field, string, bool) {
+	if off+1 >= len(cols) || distinctCount(cols[off+1]) == 1 {
+		return Field{}, "", false
+	}
+	for _, endian := range []string{"big", "little"} {
+		hi, lo := cols[off], cols[off+1]
+		if endian == "little" {
+			hi, lo = lo, hi
+		}
+		signed, ok := clusteredHighByte(hi)
+		if !ok || byteSpan(lo) < 128 {
+			continue
+		}
+		t := TypeU16
+		if signed {
+			t = TypeS16
+		}
+		vals := windowValues(cols, off, 2, endian)
+		min, max := math.MaxInt64, math.MinInt64
+		for _, v := range vals {
+			x := int(v)
+			if signed {
+				x = int(int16(v))
+			}
+			if x < min {
+				min = x
+			}
+			if x > max {
+				max = x
+			}
+		}
+		hint := fmt.Sprintf("values %d..%d", min, max) + scalingHint(signed, min, max)
+		return inferField(fmt.Sprintf("value_%d", off), t, endian, hint), endian, true
+	}
+	return Field{}, "", false
+}
+
+// clusteredHighByte reports whether the values lie within 4 adjacent
+// values (taking at least 2), and whether they straddle zero as signed.
+func clusteredHighByte(col []byte) (signed, ok bool) {
+	d := distinctCount(col)
+	if d < 2 || d > 4 {
+		return false, false
+	}
+	uMin, uMax := 255, 0
+	sMin, sMax := 127, -128
+	for _, b := range col {
+		uMin, uMax = minInt(uMin, int(b)), maxInt(uMax, int(b))
+		sMin, sMax = minInt(sMin, int(int8(b))), maxInt(sMax, int(int8(b)))
+	}
+	if uMax-uMin < 4 {
+		return false, true
+	}
+	if sMax-sMin < 4 {
+		return sMin < 0 && sMax >= 0, true
+	}
+	return false, false
+}
+
+func scalingHint(signed bool, min, max int) string {
+	switch {
+	case signed && min >= -400 && max <= 1250:
+		return "; temperature x10? try div: 10"
+	case !signed && min >= 2000 && max <= 4200:
+		return "; battery millivolts?"
+	case !signed && max <= 10000 && max-min > 100:
+		return "; scaled reading? try div: 10 or div: 100"
+	}
+	return ""
+}
+
+func inferByte(col []byte, off int) Field {
+	min, max := 255, 0
+	flagLike := distinctCount(col) <= 8
+	for _, b := range col {
+		min, max = minInt(min, int(b)), maxInt(max, int(b))
+		if bits.OnesCount8(b) > 2 {
+			flagLike = false
+		}
+	}
+	hint := fmt.Sprintf("values %d..%d", min, max)
+	switch {
+	case max <= 1:
+		hint += "; boolean?"
+	case flagLike:
+		hint += "; bit flags? try a byte_group"
+	case max <= 100 && max-min > 5:
+		hint += "; percentage?"
+	}
+	return inferField(fmt.Sprintf("value_%d", off), TypeU8, "", hint)
+}
+
+func windowValues(cols [][]byte, off, width int, endian string) []uint64 {
+	vals := make([]uint64, len(cols[off]))
+	buf := make([]byte, width)
+	for j := range vals {
+		for k := 0; k < width; k++ {
+			buf[k] = cols[off+k][j]
+		}
+		vals[j] = decodeUint(buf, endian)
+	}
+	return vals
+}
+
+func distinctCount(col []byte) int {
+	var seen [256]bool
+	n := 0
+	for _, b := range col {
+		if !seen[b] {
+			seen[b] = true
+			n++
+		}
+	}
+	return n
+}
+
+func byteSpan(col []byte) int {
+	min, max := 255, 0
+	for _, b := range col {
+		min, max = minInt(min, int(b)), maxInt(max, int(b))
+	}
+	return max - min
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	// magic | u16 BE counter | s16 LE temperature x10 | u8 humidity | u8 flags
+	var samples [][]byte
+	for k := 0; k < 40; k++ {
+		counter := 0x00F0 + 7*k
+		temp := int16(-50 + (k*37)%300)
+		samples = append(samples, []byte{
+			0xA5,
+			byte(counter >> 8), byte(counter),
+			byte(temp), byte(uint16(temp) >> 8),
+			byte(30 + (k*13)%60),
+			[]byte{0, 1, 2, 4, 5}[k%5],
+		})
+	}
+
+	s, err := Infer(samples)
+	if err != nil {
+		t.Fatalf("Infer() error = %v", err)
+	}
+	want := []struct {
+		name   string
+		typ    FieldType
+		endian string
+		hint   string
+	}{
+		{"const_0", TypeU8, "", "constant 0xA5"},
+		{"counter_1", TypeU16, "big", "never decreases"},
+		{"value_3", TypeS16, "little", "temperature"},
+		{"value_5", TypeU8, "", "percentage"},
+		{"value_6", TypeU8, "", "bit flags"},
+	}
+	if len(s.Fields) != len(want) {
+		t.Fatalf("got %d fields %+v, want %d", len(s.Fields), s.Fields, len(want))
+	}
+	// One vote each way; ties keep big endian and per-field overrides
+	if s.Endian != "big" {
+		t.Errorf("Endian = %q, want big", s.Endian)
+	}
+	for i, w := range want {
+		f := s.Fields[i]
+		endian := f.Endian
+		if endian == "" && (f.Type == TypeU16 || f.Type == TypeS16) {
+			endian = s.Endian
+		}
+		hint, _ := f.Extensions[InferHintKey].(string)
+		if f.Name != w.name || f.Type != w.typ || endian != w.endian || !strings.Contains(hint, w.hint) {
+			t.Errorf("field %d = %s %s %s (%q), want %s %s %s (%q)",
+				i, f.Name, f.Type, endian, hint, w.name, w.typ, w.endian, w.hint)
+		}
+	}
+
+	// The draft decodes the samples it was inferred from
+	decoded, err := s.Decode(samples[2])
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded["counter_1"] != float64(0x00F0+14) || decoded["value_3"] != 24.0 {
+		t.Errorf("Decode() = %v", decoded)
+	}
+}
+
+func TestInferVaryingLength(t *testing.T) {
+	s, err := Infer([][]byte{{1, 2, 3}, {1, 5}, {1, 9, 7, 7}})
+	if err != nil {
+		t.Fatalf("Infer() error = %v", err)
+	}
+	if len(s.Fields) != 2 {
+		t.Errorf("got %d fields, want 2", len(s.Fields))
+	}
+	if note, _ := s.Extensions["x-infer-note"].(string); !strings.Contains(note, "2..4 bytes") {
+		t.Errorf("x-infer-note = %q", note)
+	}
+	if _, err := Infer(nil); err == nil {
+		t.Error("Infer(nil) should fail")
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// EncodeInverse (the `encode_inverse:` key) lets Encode accept the value of
+// a computed `ref:` field and derive the raw field it references, so
+// calibrated values round-trip. `encode_inverse: true` undoes the field's
+// own steps: modifiers, transform stages, curve and a linear polynomial.
+// Higher-degree polynomials are solved numerically and need a Range to
+// search. Polynomial or Transform replace the automatic inverse with an
+// explicit one, applied to the computed value.
+type EncodeInverse struct {
+	Range      []float64   `json:"range,omitempty" yaml:"range,omitempty"`           // [min, max] raw values searched for a polynomial root
+	Polynomial []float64   `json:"polynomial,omitempty" yaml:"polynomial,omitempty"` // Explicit inverse polynomial, Horner coefficients
+	Transform  []Transform `json:"transform,omitempty" yaml:"transform,omitempty"`   // Explicit inverse transform stages
+}
+
+// parseEncodeInverse parses `encode_inverse:`, which is true or a map.
+func parseEncodeInverse(raw any) (*EncodeInverse, error) {
+	switch v := raw.(type) {
+	case bool:
+		if !v {
+			return nil, nil
+		}
+		return &EncodeInverse{}, nil
+	case map[string]any:
+		inv := &EncodeInverse{}
+		if r, ok := v["range"]; ok {
+			list, _ := r.([]any)
+			if len(list) != 2 {
+				return nil, fmt.Errorf("encode_inverse: range must be [min, max]")
+			}
+			lo, okLo := toFloat64(list[0])
+			hi, okHi := toFloat64(list[1])
+			if !okLo || !okHi || lo > hi {
+				return nil, fmt.Errorf("encode_inverse: range must be [min, max]")
+			}
+			inv.Range = []float64{lo, hi}
+		}
+		if p, ok := v["polynomial"].([]any); ok {
+			for _, c := range p {
+				cf, ok := toFloat64(c)
+				if !ok {
+					return nil, fmt.Errorf("encode_inverse: polynomial coefficients must be numbers")
+				}
+				inv.Polynomial = append(inv.Polynomial, cf)
+			}
+		}
+		if ts, ok := v["transform"].([]any); ok {
+			for _, tRaw := range ts {
+				tm, ok := tRaw.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("encode_inverse: transform stages must be maps")
+				}
+				inv.Transform = append(inv.Transform, Transform{
+					Add:  numberPtr(tm, "add"),
+					Sub:  numberPtr(tm, "sub"),
+					Mult: numberPtr(tm, "mult"),
+					Div:  numberPtr(tm, "div"),
+				})
+			}
+		}
+		return inv, nil
+	}
+	return nil, fmt.Errorf("encode_inverse must be true or a map, got %T", raw)
+}
+
+// checkEncodeInverse reports encode_inverse settings that cannot work.
+func checkEncodeInverse(f Field) string {
+	inv := f.EncodeInverse
+	switch {
+	case inv == nil:
+		return ""
+	case f.Ref == "":
+		return fmt.Sprintf("%s: encode_inverse requires ref", f.Name)
+	case len(inv.Polynomial) > 0 || len(inv.Transform) > 0:
+		return ""
+	case len(f.Polynomial) > 2 && inv.Range == nil:
+		return fmt.Sprintf("%s: encode_inverse of a degree %d polynomial needs range: [min, max]",
+			f.Name, len(f.Polynomial)-1)
+	}
+	return ""
+}
+
+// invertRef maps the computed value of a ref field back to the value of the
+// variable it references.
+func (f Field) invertRef(y float64) (float64, error) {
+	inv := f.EncodeInverse
+	if len(inv.Polynomial) > 0 || len(inv.Transform) > 0 {
+		if len(inv.Polynomial) > 0 {
+			y = evaluatePolynomial(inv.Polynomial, y)
+		}
+		return applyTransforms(y, inv.Transform), nil
+	}
+
+	// Undo the decode steps in reverse: add, div, mult, transform, curve,
+	// polynomial
+	if f.Add != nil {
+		y -= *f.Add
+	}
+	if f.Div != nil && *f.Div != 0 {
+		y *= *f.Div
+	}
+	if f.Mult != nil && *f.Mult != 0 {
+		y /= *f.Mult
+	}
+	for i := len(f.Transform) - 1; i >= 0; i-- {
+		stage := f.Transform[i]
+		if stage.Div != nil && *stage.Div != 0 {
+			y *= *stage.Div
+		}
+		if stage.Mult != nil && *stage.Mult != 0 {
+			y /= *stage.Mult
+		}
+		if stage.Add != nil {
+			y -= *stage.Add
+		}
+		if stage.Sub != nil {
+			y += *stage.Sub
+		}
+	}
+	if f.Curve != nil {
+		x, ok := f.Curve.Invert(y)
+		if !ok {
+			return 0, fmt.Errorf("%s: curve cannot be inverted at %v", f.Name, y)
+		}
+		y = x
+	}
+	if len(f.Polynomial) > 0 {
+		x, ok := solvePolynomial(f.Polynomial, y, inv.Range)
+		if !ok {
+			return 0, fmt.Errorf("%s: no raw value in range %v gives %v", f.Name, inv.Range, y)
+		}
+		y = x
+	}
+	return y, nil
+}
+
+// solvePolynomial finds x with p(x) = y. Constant and linear polynomials
+// are solved directly; others by bisection over rng, which must bracket a
+// single root.
+func solvePolynomial(coeffs []float64, y float64, rng []float64) (float64, bool) {
+	// Leading zero coefficients do not raise the degree
+	for len(coeffs) > 1 && coeffs[0] == 0 {
+		coeffs = coeffs[1:]
+	}
+	switch len(coeffs) {
+	case 1:
+		return 0, false
+	case 2:
+		return (y - coeffs[1]) / coeffs[0], true
+	}
+	if len(rng) != 2 {
+		return 0, false
+	}
+
+	lo, hi := rng[0], rng[1]
+	g := func(x float64) float64 { return evaluatePolynomial(coeffs, x) - y }
+	glo, ghi := g(lo), g(hi)
+	switch {
+	case glo == 0:
+		return lo, true
+	case ghi == 0:
+		return hi, true
+	case (glo < 0) == (ghi < 0):
+		return 0, false
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12*math.Max(1, math.Abs(lo)); i++ {
+		mid := (lo + hi) / 2
+		gm := g(mid)
+		if gm == 0 {
+			return mid, true
+		}
+		if (gm < 0) == (glo < 0) {
+			lo, glo = mid, gm
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, true
+}
+
+// applyTransforms runs transform stages in order, as decoding does.
+func applyTransforms(v float64, stages []Transform) float64 {
+	for _, stage := range stages {
+		if stage.Sub != nil {
+			v -= *stage.Sub
+		}
+		if stage.Add != nil {
+			v += *stage.Add
+		}
+		if stage.Mult != nil {
+			v *= *stage.Mult
+		}
+		if stage.Div != nil && *stage.Div != 0 {
+			v /= *stage.Div
+		}
+	}
+	return v
+}
+
+// deriveInverseValues returns data extended with the raw values implied by
+// computed fields that have encode_inverse, and the names it added. Fields
+// are scanned last to first so a computed field can feed another ref
+// chain. Values the caller supplied for the raw field itself win.
+func deriveInverseValues(fields []Field, data map[string]any) (map[string]any, map[string]bool, error) {
+	var out map[string]any
+	var derived map[string]bool
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.EncodeInverse == nil || f.Ref == "" {
+			continue
+		}
+		src := data
+		if out != nil {
+			src = out
+		}
+		v, ok := lookupEncodeValue(f, src)
+		if !ok || v == nil {
+			continue
+		}
+		y, ok := toFloat64(v)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: encode_inverse needs a number, got %T", f.Name, v)
+		}
+
+		refName := strings.TrimPrefix(f.Ref, "$")
+		target := ""
+		for j := i - 1; j >= 0; j-- {
+			if fields[j].Var == refName || fields[j].Name == refName {
+				target = fields[j].Name
+				break
+			}
+		}
+		if target == "" {
+			return nil, nil, fmt.Errorf("%s: encode_inverse: $%s is not decoded before it in the same field list", f.Name, refName)
+		}
+		if _, given := src[target]; given {
+			continue
+		}
+
+		x, err := f.invertRef(y)
+		if err != nil {
+			return nil, nil, err
+		}
+		if out == nil {
+			out = make(map[string]any, len(data)+1)
+			for k, dv := range data {
+				out[k] = dv
+			}
+			derived = map[string]bool{}
+		}
+		out[target] = x
+		derived[target] = true
+	}
+	if out == nil {
+		return data, nil, nil
+	}
+	return out, derived, nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEncodeInverse(t *testing.T) {
+	s, err := ParseSchema(`
+name: soil
+endian: big
+fields:
+  - name: _raw_temp
+    type: u16
+  - name: temperature
+    type: number
+    ref: $_raw_temp
+    transform:
+      - sub: 400
+      - div: 10
+    encode_inverse: true
+  - name: raw_dielectric
+    type: u16
+    var: eps
+    div: 50
+  - name: vwc
+    type: number
+    ref: $eps
+    polynomial: [4.3e-6, -5.5e-4, 2.92e-2, -5.3e-2]
+    mult: 100
+    encode_inverse:
+      range: [1, 80]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x02, 0x3F, 0x03, 0x84}
+	decoded, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	encoded, err := s.Encode(map[string]any{
+		"temperature": decoded["temperature"],
+		"vwc":         decoded["vwc"],
+	})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = % X, want % X", encoded, payload)
+	}
+
+	// A raw value given alongside the computed one wins
+	encoded, _ = s.Encode(map[string]any{"temperature": 20.0, "raw_dielectric": 10.0})
+	if !bytes.Equal(encoded, []byte{0x02, 0x58, 0x01, 0xF4}) {
+		t.Errorf("Encode() = % X", encoded)
+	}
+}
+
+func TestEncodeInverseExplicit(t *testing.T) {
+	s, err := ParseSchema(`
+name: probe
+fields:
+  - name: raw
+    type: u8
+  - name: level
+    type: number
+    ref: $raw
+    polynomial: [0.01, 0, 0]
+    encode_inverse:
+      polynomial: [10, 0]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// level = 0.01 * raw^2; the sqrt is not a polynomial, but 10 * level
+	// is exact for the one value under test
+	encoded, err := s.Encode(map[string]any{"level": 4.0})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{40}) {
+		t.Errorf("Encode() = % X, want 28", encoded)
+	}
+}
+
+func TestEncodeInverseErrors(t *testing.T) {
+	s, err := ParseSchema(`
+name: cubic
+fields:
+  - name: raw
+    type: u8
+  - name: v
+    type: number
+    ref: $raw
+    polynomial: [1, 0, 0, 0]
+    encode_inverse: true
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], "needs range") {
+		t.Errorf("Warnings = %v, want a missing range warning", s.Warnings)
+	}
+	if _, err := s.Encode(map[string]any{"v": 8.0}); err == nil {
+		t.Error("Encode() without a range succeeded")
+	}
+
+	x, ok := solvePolynomial([]float64{1, 0, 0, 0}, 8, []float64{0, 10})
+	if !ok || math.Abs(x-2) > 1e-9 {
+		t.Errorf("solvePolynomial() = %v, %v; want 2", x, ok)
+	}
+	if _, ok := solvePolynomial([]float64{1, 0, 0, 0}, 8, []float64{3, 10}); ok {
+		t.Error("solvePolynomial() found a root outside the range")
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Unbounded marks a layout size or offset with no upper limit, e.g. after
+// a TLV section or a repeat until the end of the payload.
+const Unbounded = -1
+
+// LayoutEntry places one field in the payload. Offsets and sizes are
+// ranges because earlier optional or variable-length fields shift them.
+type LayoutEntry struct {
+	Path      string // Dotted output path, e.g. "readings.value"
+	Type      string
+	MinOffset int
+	MaxOffset int // Unbounded if not limited
+	MinSize   int
+	MaxSize   int    // Unbounded if not limited
+	Condition string // When the field is present, e.g. "flags bit 2"; empty if always
+}
+
+// Layout is the static byte layout of a payload.
+type Layout struct {
+	MinSize int
+	MaxSize int // Unbounded if not limited
+	Entries []LayoutEntry
+	fields  []Field // Field behind each entry, for Markdown
+}
+
+// Layout computes the byte layout of the payload on fPort: its minimum and
+// maximum size and where each field sits. Flagged groups, match cases and
+// repeats make sizes and later offsets ranges.
+func (s *Schema) Layout(fPort int) (*Layout, error) {
+	fields, err := s.ResolveFields(fPort)
+	if err != nil {
+		return nil, err
+	}
+	return s.layoutFields(s.ResolveHeader(fPort), fields)
+}
+
+// layoutFields lays out header followed by fields.
+func (s *Schema) layoutFields(header, fields []Field) (*Layout, error) {
+	w := &layoutWalker{defs: s.Definitions}
+	minSize, maxSize, err := w.walk(append(append([]Field{}, header...), fields...), "", 0, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if s.Frames != nil {
+		// Frames repeat until the payload ends
+		w.entries = append(w.entries, LayoutEntry{
+			Path: s.Frames.key(), Type: "frames",
+			MinOffset: minSize, MaxOffset: maxSize, MinSize: 0, MaxSize: Unbounded,
+		})
+		w.fields = append(w.fields, Field{Name: s.Frames.key(), Type: "frames"})
+		maxSize = Unbounded
+	}
+	return &Layout{MinSize: minSize, MaxSize: maxSize, Entries: w.entries, fields: w.fields}, nil
+}
+
+// String renders the layout as a table.
+func (l *Layout) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "size: %s bytes\n", formatRange(l.MinSize, l.MaxSize))
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tSIZE\tFIELD\tTYPE\tCONDITION")
+	for _, e := range l.Entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			formatRange(e.MinOffset, e.MaxOffset), formatRange(e.MinSize, e.MaxSize), e.Path, e.Type, e.Condition)
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func formatRange(min, max int) string {
+	switch {
+	case max == Unbounded:
+		return fmt.Sprintf("%d+", min)
+	case min == max:
+		return fmt.Sprintf("%d", min)
+	}
+	return fmt.Sprintf("%d-%d", min, max)
+}
+
+// addSize adds size ranges, keeping Unbounded sticky.
+func addSize(a, b int) int {
+	if a == Unbounded || b == Unbounded {
+		return Unbounded
+	}
+	return a + b
+}
+
+func mulSize(n, size int) int {
+	if n == Unbounded || size == Unbounded {
+		if n == 0 || size == 0 {
+			return 0
+		}
+		return Unbounded
+	}
+	return n * size
+}
+
+func maxSizeOf(a, b int) int {
+	if a == Unbounded || b == Unbounded {
+		return Unbounded
+	}
+	return maxInt(a, b)
+}
+
+type layoutWalker struct {
+	defs    map[string]*DefinitionDef
+	entries []LayoutEntry
+	fields  []Field
+	depth   int
+}
+
+func (w *layoutWalker) add(f Field, path, typ string, lo, hi, minSize, maxSize int, cond string) {
+	w.fields = append(w.fields, f)
+	w.entries = append(w.entries, LayoutEntry{
+		Path: path, Type: typ, MinOffset: lo, MaxOffset: hi,
+		MinSize: minSize, MaxSize: maxSize, Condition: cond,
+	})
+}
+
+// walk lays out fields starting at offsets lo..hi and returns the range of
+// bytes they occupy.
+func (w *layoutWalker) walk(fields []Field, prefix string, lo, hi int, cond string) (int, int, error) {
+	totalMin, totalMax := 0, 0
+	for _, f := range fields {
+		curLo, curHi := lo+totalMin, addSize(hi, totalMax)
+		fMin, fMax, err := w.field(f, prefix, curLo, curHi, cond)
+		if err != nil {
+			return 0, 0, err
+		}
+		totalMin += fMin
+		totalMax = addSize(totalMax, fMax)
+	}
+	return totalMin, totalMax, nil
+}
+
+func (w *layoutWalker) field(f Field, prefix string, lo, hi int, cond string) (int, int, error) {
+	path := prefix + f.Name
+
+	switch {
+	case f.Ref2 != "":
+		def, err := lookupDefinition(f.Ref2, w.defs)
+		if err != nil {
+			return 0, 0, err
+		}
+		if w.depth >= maxRefDepth {
+			return 0, 0, fmt.Errorf("$ref %s: nested deeper than %d (circular reference?)", f.Ref2, maxRefDepth)
+		}
+		w.depth++
+		defer func() { w.depth-- }()
+		return w.walk(def.Fields, prefix, lo, hi, cond)
+
+	case len(f.ByteGroup) > 0:
+		var names []string
+		for _, m := range f.ByteGroup {
+			if m.Name != "" {
+				names = append(names, prefix+m.Name)
+			}
+		}
+		size := byteGroupSize(f)
+		w.add(f, strings.Join(names, ", "), "byte_group", lo, hi, size, size, cond)
+		return size, size, nil
+
+	case f.Flagged != nil:
+		total := 0
+		for _, g := range f.Flagged.Groups {
+			_, gMax, err := w.walk(g.Fields, prefix, lo, addSize(hi, total),
+				joinCondition(cond, fmt.Sprintf("%s bit %d", f.Flagged.Field, g.Bit)))
+			if err != nil {
+				return 0, 0, err
+			}
+			total = addSize(total, gMax)
+		}
+		return 0, total, nil
+
+	case f.Type == TypeTLV || f.Type == TypeTLVLower || f.TLVInline != nil:
+		w.add(f, pathOr(path, "(tlv)"), "tlv", lo, hi, 0, Unbounded, cond)
+		return 0, Unbounded, nil
+
+	case f.WASM != nil:
+		w.add(f, pathOr(path, "(wasm)"), "wasm", lo, hi, 0, Unbounded, cond)
+		return 0, Unbounded, nil
+
+	case readsRest(f):
+		w.add(f, path, string(f.Type), lo, hi, 0, Unbounded, cond)
+		return 0, Unbounded, nil
+
+	case f.MatchInline != nil:
+		return w.match(*f.MatchInline, prefix, lo, hi, cond)
+	}
+
+	switch f.Type {
+	case TypeMatch, "CTRL-SWITCH", "Switch":
+		return w.match(f, prefix, lo, hi, cond)
+	case TypeObject:
+		return w.walk(f.Fields, path+".", lo, hi, cond)
+	case TypeVector3:
+		return w.walk(vectorAxes(f), path+".", lo, hi, cond)
+	case TypeRepeat, TypeRepeatLower, TypeSeries:
+		return w.repeat(f, path, lo, hi, cond)
+	}
+
+	read, consume, err := fieldReadSize(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	if read > 0 {
+		w.add(f, pathOr(path, "("+string(f.Type)+")"), string(f.Type),
+			lo+f.ByteOffset, addSize(hi, f.ByteOffset), read, read, cond)
+	}
+	return consume, consume, nil
+}
+
+func (w *layoutWalker) match(f Field, prefix string, lo, hi int, cond string) (int, int, error) {
+	selector := 0
+	if f.On == "" {
+		// Inline match reads its own selector
+		selector = f.Length
+		if selector == 0 {
+			selector = 1
+		}
+		w.add(f, pathOr(prefix+f.Name, "(match)"), "match", lo, hi, selector, selector, cond)
+	}
+	on := f.On
+	if on == "" {
+		on = "selector"
+	}
+
+	caseMin, caseMax, hasDefault := -1, 0, false
+	for _, c := range f.Cases {
+		label := "default"
+		if !c.Default {
+			v := c.Case
+			if v == nil {
+				v = c.Match
+			}
+			label = fmt.Sprintf("%s == %v", on, v)
+		} else {
+			hasDefault = true
+		}
+		cMin, cMax, err := w.walk(c.Fields, prefix, lo+selector, addSize(hi, selector), joinCondition(cond, label))
+		if err != nil {
+			return 0, 0, err
+		}
+		if caseMin < 0 || cMin < caseMin {
+			caseMin = cMin
+		}
+		caseMax = maxSizeOf(caseMax, cMax)
+	}
+	if caseMin < 0 || !hasDefault && !f.revisions {
+		// No case matching decodes nothing (an unknown revision fails)
+		caseMin = 0
+	}
+	return selector + caseMin, addSize(selector, caseMax), nil
+}
+
+func (w *layoutWalker) repeat(f Field, path string, lo, hi int, cond string) (int, int, error) {
+	bodyMin, bodyMax, err := w.walk(f.Fields, path+"[].", lo, hi, joinCondition(cond, "per element"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	maxCount := f.Max
+	if maxCount == 0 {
+		maxCount = Unbounded
+	}
+	switch {
+	case f.Count != nil:
+		if n, ok := toWholeInt(f.Count); ok {
+			return n * bodyMin, mulSize(n, bodyMax), nil
+		}
+		return f.Min * bodyMin, mulSize(maxCount, bodyMax), nil
+	case f.ByteLength != nil:
+		if n, ok := toWholeInt(f.ByteLength); ok {
+			return n, n, nil
+		}
+		return 0, Unbounded, nil
+	default:
+		return f.Min * bodyMin, mulSize(maxCount, bodyMax), nil
+	}
+}
+
+// fieldReadSize returns how many bytes a scalar field reads and how many
+// it advances the cursor by.
+func fieldReadSize(f Field) (read, consume int, err error) {
+	length := f.Length
+	if length == 0 {
+		length = inferLengthFromType(f.Type)
+	}
+	switch f.Type {
+	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU32, TypeU64, TypeU24,
+		TypeSInt, TypeS8, TypeS16, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64, TypeS24, TypeBInt:
+		read = length
+	case TypeFloat16, TypeF16:
+		read = 2
+	case TypeFloat32, TypeF32:
+		read = 4
+	case TypeFloat64, TypeF64:
+		read = 8
+	case TypeFixed, TypeUFixed:
+		read = fixedLength(f)
+	case TypeBool, TypeBoolLower:
+		read = 1
+	case TypeBits, TypeBitsLower:
+		read = bitsLength(f)
+	case TypeString, TypeStringLower:
+		return f.Length, f.Length, nil
+	case TypeAscii, TypeAsciiLower, TypeHex, TypeSkip, TypeSkipLower, TypeReserved, TypeReservedLower, TypeMagic, TypeMagicLower, TypeBytes, TypeBytesLower, TypeBitfieldString:
+		return length, length, nil
+	case TypeEnum, TypeEnumLower:
+		n := enumBaseLength(f)
+		return n, n, nil
+	case TypeNumber, "number":
+		return 0, 0, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown field type: %s", f.Type)
+	}
+	return read, consumeLength(f, read), nil
+}
+
+func joinCondition(outer, inner string) string {
+	if outer == "" {
+		return inner
+	}
+	return outer + " and " + inner
+}
+
+func pathOr(path, fallback string) string {
+	if path == "" || strings.HasSuffix(path, ".") {
+		return path + fallback
+	}
+	return path
+}
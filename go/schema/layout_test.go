@@ -0,0 +1,140 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayoutFixed(t *testing.T) {
+	s, err := ParseSchema(`
+name: fixed
+fields:
+  - name: version
+    type: u8
+  - name: temperature
+    type: s16
+  - type: skip
+    length: 2
+  - name: position
+    type: Object
+    fields:
+      - {name: lat, type: s32}
+      - {name: lon, type: s32}
+  - name: celsius
+    type: number
+    ref: $temperature
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	l, err := s.Layout(0)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	if l.MinSize != 13 || l.MaxSize != 13 {
+		t.Errorf("size = %d..%d, want 13", l.MinSize, l.MaxSize)
+	}
+	want := []LayoutEntry{
+		{Path: "version", Type: "u8", MinOffset: 0, MaxOffset: 0, MinSize: 1, MaxSize: 1},
+		{Path: "temperature", Type: "s16", MinOffset: 1, MaxOffset: 1, MinSize: 2, MaxSize: 2},
+		{Path: "(skip)", Type: "skip", MinOffset: 3, MaxOffset: 3, MinSize: 2, MaxSize: 2},
+		{Path: "position.lat", Type: "s32", MinOffset: 5, MaxOffset: 5, MinSize: 4, MaxSize: 4},
+		{Path: "position.lon", Type: "s32", MinOffset: 9, MaxOffset: 9, MinSize: 4, MaxSize: 4},
+	}
+	if len(l.Entries) != len(want) {
+		t.Fatalf("Entries = %+v", l.Entries)
+	}
+	for i := range want {
+		if l.Entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, l.Entries[i], want[i])
+		}
+	}
+}
+
+func TestLayoutVariable(t *testing.T) {
+	s, err := ParseSchema(`
+name: variable
+ports:
+  2:
+    fields:
+      - name: flags
+        type: u8
+      - flagged:
+          field: flags
+          groups:
+            - bit: 0
+              fields: [{name: temperature, type: s16}]
+            - bit: 1
+              fields: [{name: pressure, type: u32}]
+      - name: kind
+        type: u8
+        var: kind
+      - name: body
+        type: Match
+        on: $kind
+        cases:
+          - case: 1
+            fields: [{name: a, type: u8}]
+          - case: 2
+            fields: [{name: b, type: u16}, {name: c, type: u16}]
+      - name: count
+        type: u8
+      - name: readings
+        type: repeat
+        count: $count
+        max: 4
+        fields: [{name: v, type: u16}]
+  3:
+    fields:
+      - name: n
+        type: u8
+      - name: rest
+        type: repeat
+        until: end
+        fields: [{name: v, type: u8}]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	l, err := s.Layout(2)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	// flags 1 + flagged 0..6 + kind 1 + match 0..4 + count 1 + readings 0..8
+	if l.MinSize != 3 || l.MaxSize != 21 {
+		t.Errorf("size = %d..%d, want 3..21", l.MinSize, l.MaxSize)
+	}
+	byPath := map[string]LayoutEntry{}
+	for _, e := range l.Entries {
+		byPath[e.Path] = e
+	}
+	if e := byPath["pressure"]; e.MinOffset != 1 || e.MaxOffset != 3 || e.Condition != "flags bit 1" {
+		t.Errorf("pressure = %+v", e)
+	}
+	if e := byPath["c"]; e.MinOffset != 4 || e.MaxOffset != 10 || e.Condition != "$kind == 2" {
+		t.Errorf("c = %+v", e)
+	}
+	if e := byPath["readings[].v"]; e.MinOffset != 3 || e.MaxOffset != 13 || e.Condition != "per element" {
+		t.Errorf("readings[].v = %+v", e)
+	}
+
+	l, err = s.Layout(3)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	if l.MinSize != 1 || l.MaxSize != Unbounded {
+		t.Errorf("size = %d..%d, want 1..unbounded", l.MinSize, l.MaxSize)
+	}
+	if !strings.Contains(l.String(), "size: 1+ bytes") {
+		t.Errorf("String() = %s", l.String())
+	}
+
+	if _, err := s.Layout(9); err == nil {
+		t.Error("Layout() of an undefined port should fail")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// LazyResult is a decode result whose computed fields are evaluated when
+// first read. Pipelines that read a few keys of a large schema skip the
+// polynomials, curves and formulas of the rest. A LazyResult is not safe
+// for concurrent use.
+type LazyResult struct {
+	schema  *Schema
+	ctx     *DecodeContext
+	values  map[string]any
+	pending map[string]pendingField
+	refs    map[string]bool // Names other fields may read; never deferred
+}
+
+// pendingField is a deferred computed field and the variables it would
+// have seen when decoded in order.
+type pendingField struct {
+	field Field
+	vars  map[string]any
+}
+
+// DecodeLazy decodes data like DecodeWithPort but defers top-level
+// computed number fields (ref, polynomial, compute, formula) that no other
+// field references. Keys and values use field names; output key styling
+// applies only to Materialize.
+func (s *Schema) DecodeLazy(data []byte, fPort int) (*LazyResult, error) {
+	chain, err := s.portChain(fPort, PortUp)
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for _, pd := range chain {
+		r := &LazyResult{schema: s, pending: map[string]pendingField{}, refs: s.referenced}
+		if r.refs == nil {
+			r.refs = referencedNames(s)
+		}
+		r.ctx = s.newDecodeContext(data, time.Time{})
+		r.ctx.lazy = r
+		if r.values, err = s.decodeWith(r.ctx, s.portHeader(pd), pd.Fields); err == nil {
+			r.ctx.lazy = nil
+			return r, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// Get returns the value of key, evaluating it if it is a deferred computed
+// field. Absent keys give (nil, false, nil).
+func (r *LazyResult) Get(key string) (any, bool, error) {
+	if p, ok := r.pending[key]; ok {
+		if err := r.evaluate(key, p); err != nil {
+			return nil, false, err
+		}
+	}
+	v, ok := r.values[key]
+	return v, ok, nil
+}
+
+// Keys returns the keys of the result, including unevaluated ones, sorted.
+// A deferred field whose guard yields no value disappears when evaluated.
+func (r *LazyResult) Keys() []string {
+	keys := sortedKeys(r.values)
+	for k := range r.pending {
+		if _, ok := r.values[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Pending reports how many computed fields have not been evaluated yet.
+func (r *LazyResult) Pending() int {
+	return len(r.pending)
+}
+
+// Materialize evaluates every deferred field and returns the result Decode
+// would have returned.
+func (r *LazyResult) Materialize() (map[string]any, error) {
+	for _, key := range sortedKeys(r.pending) {
+		if err := r.evaluate(key, r.pending[key]); err != nil {
+			return nil, err
+		}
+	}
+	result := make(map[string]any, len(r.values))
+	for k, v := range r.values {
+		result[k] = v
+	}
+	return r.schema.finishResult(result, r.ctx), nil
+}
+
+// evaluate decodes a deferred field against its saved variables, sharing
+// the quality flags and warnings of the original decode.
+func (r *LazyResult) evaluate(key string, p pendingField) error {
+	ctx := r.schema.newDecodeContext(r.ctx.Data, r.ctx.ReceivedAt)
+	ctx.Variables = p.vars
+	ctx.Quality = r.ctx.Quality
+	values, err := decodeFieldsWithSchema([]Field{p.field}, ctx, r.schema)
+	if err != nil {
+		return err
+	}
+	delete(r.pending, key)
+	for k, v := range values {
+		r.values[k] = v
+	}
+	r.ctx.Warnings = append(r.ctx.Warnings, ctx.Warnings...)
+	return nil
+}
+
+// deferComputed records field for lazy evaluation when the decode is lazy
+// and nothing else depends on its value.
+func (ctx *DecodeContext) deferComputed(field Field) bool {
+	r := ctx.lazy
+	if r == nil || ctx.nested > 0 || field.Name == "" || r.refs[field.Name] || len(field.AlsoEmit) > 0 ||
+		(field.Type != TypeNumber && field.Type != "number") {
+		return false
+	}
+	vars := make(map[string]any, len(ctx.Variables))
+	for k, v := range ctx.Variables {
+		vars[k] = v
+	}
+	r.pending[field.Name] = pendingField{field: field, vars: vars}
+	return true
+}
+
+var identPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// referencedNames collects the identifiers that appear in the schema's
+// field attributes (refs, operands, guards, formulas, match selectors,
+// counts and so on), i.e. the names some field may read as a variable.
+// It is deliberately broad: a name that merely looks referenced is only
+// evaluated eagerly.
+func referencedNames(s *Schema) map[string]bool {
+	names := map[string]bool{}
+	collectIdents(reflect.ValueOf(s.Header), names)
+	collectIdents(reflect.ValueOf(s.Fields), names)
+	collectIdents(reflect.ValueOf(s.Ports), names)
+	collectIdents(reflect.ValueOf(s.Definitions), names)
+	collectIdents(reflect.ValueOf(s.Frames), names)
+	collectIdents(reflect.ValueOf(s.Revisions), names)
+	return names
+}
+
+// Field attributes that hold names, labels or docs rather than references.
+var nonRefAttrs = map[string]bool{
+	"Name": true, "Type": true, "Description": true, "DisplayName": true,
+	"Example": true, "Aliases": true, "Extensions": true, "Lookup": true,
+	"LookupArray": true, "Values": true, "Base": true, "Endian": true,
+}
+
+func collectIdents(v reflect.Value, names map[string]bool) {
+	switch v.Kind() {
+	case reflect.String:
+		for _, id := range identPattern.FindAllString(v.String(), -1) {
+			names[id] = true
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectIdents(v.Elem(), names)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectIdents(v.Index(i), names)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectIdents(iter.Value(), names)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if sf := t.Field(i); sf.IsExported() && !nonRefAttrs[sf.Name] {
+				collectIdents(v.Field(i), names)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+const lazySchema = `
+name: tank
+fields:
+  - name: raw_level
+    type: u16
+  - name: level_cm
+    type: number
+    ref: $raw_level
+    polynomial: [0.5, 2]
+  - name: volume_l
+    type: number
+    compute: {op: mul, a: $level_cm, b: 3}
+  - name: raw_temp
+    type: s8
+  - name: temp_f
+    type: number
+    ref: $raw_temp
+    polynomial: [1.8, 32]
+    valid_range: [0, 50]
+  - name: readings
+    type: repeat
+    count: 1
+    fields:
+      - name: v
+        type: u8
+      - name: v2
+        type: number
+        ref: $v
+        polynomial: [2, 0]
+`
+
+func TestDecodeLazy(t *testing.T) {
+	s, err := ParseSchema(lazySchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x00, 0x14, 0x19, 0x07}
+
+	r, err := s.DecodeLazy(payload, 0)
+	if err != nil {
+		t.Fatalf("DecodeLazy() error = %v", err)
+	}
+	// level_cm feeds volume_l, so only the leaves wait
+	if r.Pending() != 2 {
+		t.Errorf("Pending() = %d, want 2", r.Pending())
+	}
+	wantKeys := []string{"level_cm", "raw_level", "raw_temp", "readings", "temp_f", "volume_l"}
+	if !reflect.DeepEqual(r.Keys(), wantKeys) {
+		t.Errorf("Keys() = %v, want %v", r.Keys(), wantKeys)
+	}
+
+	v, ok, err := r.Get("volume_l")
+	if err != nil || !ok || v != 36.0 {
+		t.Errorf("Get(volume_l) = %v, %v, %v", v, ok, err)
+	}
+	if r.Pending() != 1 {
+		t.Errorf("Pending() = %d after Get, want 1", r.Pending())
+	}
+	if _, ok, _ := r.Get("missing"); ok {
+		t.Error("Get(missing) found a value")
+	}
+
+	got, err := r.Materialize()
+	if err != nil {
+		t.Fatalf("Materialize() error = %v", err)
+	}
+	want, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Materialize() = %v, want %v", got, want)
+	}
+	if got["temp_f"] != 77.0 || got["_quality"].(map[string]string)["temp_f"] != "out_of_range" {
+		t.Errorf("temp_f = %v, quality %v", got["temp_f"], got["_quality"])
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Magic fields assert the sync pattern or signature a framed payload
+// carries, so a payload from another device or a misaligned frame fails
+// instead of decoding to nonsense:
+//
+//	fields:
+//	  - {type: magic, value: [0xAA, 0x55]}
+//	  - {name: temperature, type: s16, div: 10}
+//
+// value: is a byte or list of bytes and sets the length. A mismatch fails
+// the decode; with mismatch: warn it is a warning instead (an error again
+// with WithStrict). Encoding writes the bytes. Magic fields add nothing to
+// the result.
+
+// Magic mismatch policies (`mismatch:`).
+const (
+	MismatchError = "error" // Fail the decode (default)
+	MismatchWarn  = "warn"  // Add a decode warning and continue
+)
+
+func isMagicType(t FieldType) bool {
+	return t == TypeMagic || t == TypeMagicLower
+}
+
+// parseMagic sets a magic field's bytes and mismatch policy.
+func parseMagic(fm map[string]any, f *Field) []string {
+	mismatch, hasMismatch := fm["mismatch"]
+	if !isMagicType(f.Type) {
+		if hasMismatch {
+			return []string{"mismatch: applies to magic fields"}
+		}
+		return nil
+	}
+	var msgs []string
+	if raw, ok := fm["value"]; !ok {
+		msgs = append(msgs, "value: magic fields need the expected bytes")
+	} else if value, msg := parseByteList("value", raw); msg != "" {
+		msgs = append(msgs, msg)
+	} else {
+		f.Expect = value
+		if f.Length == 0 {
+			f.Length = len(value)
+		} else if f.Length != len(value) {
+			msgs = append(msgs, fmt.Sprintf("value: %d bytes for length %d", len(value), f.Length))
+		}
+	}
+	if hasMismatch {
+		switch mismatch {
+		case MismatchError, MismatchWarn:
+			f.Mismatch = mismatch.(string)
+		default:
+			msgs = append(msgs, fmt.Sprintf("mismatch: expected error or warn, got %v", mismatch))
+		}
+	}
+	return msgs
+}
+
+// checkMagic compares the bytes read for a magic field with its value:.
+func (ctx *DecodeContext) checkMagic(f Field, data []byte, offset int) error {
+	if bytes.Equal(data, f.Expect) {
+		return nil
+	}
+	msg := fmt.Sprintf("%s: magic bytes at offset %d are % X, expected % X",
+		pathOr(f.Name, "(magic)"), offset, data, f.Expect)
+	if f.Mismatch != MismatchWarn || ctx.Limits.ErrorOnLimit {
+		return errors.New(msg)
+	}
+	ctx.Warnings = append(ctx.Warnings, msg)
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const magicSchema = `
+name: framed
+fields:
+  - {type: magic, value: [0xAA, 0x55]}
+  - {name: temperature, type: s16, div: 10}
+  - {name: trailer, type: magic, value: 0x0D, mismatch: warn}
+`
+
+func TestMagicField(t *testing.T) {
+	s, err := ParseSchema(magicSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 || s.Fields[0].Extensions != nil {
+		t.Fatalf("Warnings = %v, extensions = %v", s.Warnings, s.Fields[0].Extensions)
+	}
+
+	var info DecodeInfo
+	got, err := s.Decode([]byte{0xAA, 0x55, 0x00, 0xFA, 0x0D}, WithInfo(&info))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["temperature"] != 25.0 || len(got) != 1 || len(info.Warnings) > 0 {
+		t.Errorf("Decode() = %v, warnings %v", got, info.Warnings)
+	}
+
+	_, err = s.Decode([]byte{0x55, 0xAA, 0x00, 0xFA, 0x0D})
+	if want := "(magic): magic bytes at offset 0 are 55 AA, expected AA 55"; err == nil || err.Error() != want {
+		t.Errorf("Decode() error = %v, want %q", err, want)
+	}
+
+	payload := []byte{0xAA, 0x55, 0x00, 0xFA, 0x0A}
+	got, err = s.Decode(payload, WithInfo(&info))
+	if err != nil || got["temperature"] != 25.0 {
+		t.Fatalf("Decode() = %v, %v", got, err)
+	}
+	if len(info.Warnings) != 1 || info.Warnings[0] != "trailer: magic bytes at offset 4 are 0A, expected 0D" {
+		t.Errorf("warnings = %v", info.Warnings)
+	}
+	if _, err := s.Decode(payload, WithStrict()); err == nil || !strings.HasPrefix(err.Error(), "trailer:") {
+		t.Errorf("Decode(WithStrict) error = %v", err)
+	}
+
+	enc, err := s.Encode(map[string]any{"temperature": 25.0})
+	if err != nil || !bytes.Equal(enc, []byte{0xAA, 0x55, 0x00, 0xFA, 0x0D}) {
+		t.Errorf("Encode() = % X, %v", enc, err)
+	}
+}
+
+func TestBuilderMagic(t *testing.T) {
+	s, err := New("built").Magic(0xAA, 0x55).U8("a").Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if got, err := s.Decode([]byte{0xAA, 0x55, 0x07}); err != nil || got["a"] != 7.0 {
+		t.Errorf("Decode() = %v, %v", got, err)
+	}
+	if _, err := s.Decode([]byte{0xAA, 0x00, 0x07}); err == nil {
+		t.Error("Decode() with wrong magic: want error")
+	}
+	if _, err := New("built").Magic().Build(); err == nil {
+		t.Error("Build() with empty Magic: want error")
+	}
+}
+
+func TestMagicFieldInvalid(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"{type: magic}", "value: magic fields need the expected bytes"},
+		{"{type: magic, value: [0xAA, 0x1FF]}", "value: expected a byte or list of bytes (0-255), got 511"},
+		{"{type: magic, length: 1, value: [0xAA, 0x55]}", "value: 2 bytes for length 1"},
+		{"{type: magic, value: 0xAA, mismatch: ignore}", "mismatch: expected error or warn, got ignore"},
+		{"{name: x, type: u8, mismatch: warn}", "mismatch: applies to magic fields"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSchema("name: bad\nstrict: true\nfields:\n  - " + tt.field + "\n")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.field, err, tt.want)
+		}
+	}
+}
+
+func TestExportPythonMagic(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	s, err := ParseSchema(magicSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	module, err := s.ExportPython()
+	if err != nil {
+		t.Fatalf("ExportPython() error = %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "framed.py"), []byte(module), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := "import sys\nsys.path.insert(0, sys.argv[1])\nimport framed\n" +
+		"print(framed.decode(bytes.fromhex(sys.argv[2])))\n"
+	out, err := exec.Command(python, "-c", script, dir, "aa5500fa0a").CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "'temperature': 25") {
+		t.Errorf("python decode(aa5500fa0a) = %s, %v", out, err)
+	}
+	out, err = exec.Command(python, "-c", script, dir, "55aa00fa0d").CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "magic bytes at offset 0 do not match") {
+		t.Errorf("python decode(55aa00fa0d) = %s, %v; want ValueError", out, err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Markdown renders a payload reference for the schema: one table per port
+// (or one for the payload) listing each field's offset, size, type,
+// scaling, unit and description, with lookup and enum values spelled out.
+// It replaces hand-written payload docs that drift from the YAML.
+func (s *Schema) Markdown() (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", pathOr(s.Name, "Payload"))
+	if s.Version != 0 {
+		fmt.Fprintf(&sb, "Version %d\n\n", s.Version)
+	}
+	if s.Description != "" {
+		fmt.Fprintf(&sb, "%s\n\n", s.Description)
+	}
+
+	if len(s.Ports) == 0 {
+		layout, err := s.layoutFields(s.Header, s.topFields())
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("## Payload\n\n")
+		writeMarkdownTable(&sb, layout)
+		return sb.String(), nil
+	}
+
+	ports, err := s.PortList()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range ports {
+		title := fmt.Sprintf("fPort %d", p.Port)
+		if p.Default {
+			title = "Other ports"
+		}
+		fmt.Fprintf(&sb, "## %s (%s)\n\n", title, p.Direction)
+		if p.Description != "" {
+			fmt.Fprintf(&sb, "%s\n\n", p.Description)
+		}
+		layout, err := s.layoutFields(s.portHeader(s.Ports[p.Key]), s.Ports[p.Key].Fields)
+		if err != nil {
+			return "", err
+		}
+		writeMarkdownTable(&sb, layout)
+	}
+	return sb.String(), nil
+}
+
+func writeMarkdownTable(sb *strings.Builder, layout *Layout) {
+	fmt.Fprintf(sb, "Size: %s bytes\n\n", formatRange(layout.MinSize, layout.MaxSize))
+	sb.WriteString("| Offset | Size | Field | Type | Scaling | Unit | Description |\n")
+	sb.WriteString("|--------|------|-------|------|---------|------|-------------|\n")
+	for i, e := range layout.Entries {
+		f := layout.fields[i]
+		name := "`" + e.Path + "`"
+		if f.DisplayName != "" {
+			name = f.DisplayName + " (" + name + ")"
+		}
+		cells := []string{
+			formatRange(e.MinOffset, e.MaxOffset),
+			formatRange(e.MinSize, e.MaxSize),
+			name,
+			e.Type,
+			markdownScaling(f),
+			fieldUnit(f),
+			markdownDescription(f, e.Condition),
+		}
+		for j, c := range cells {
+			cells[j] = strings.ReplaceAll(strings.ReplaceAll(c, "|", `\|`), "\n", " ")
+		}
+		fmt.Fprintf(sb, "| %s |\n", strings.Join(cells, " | "))
+	}
+	sb.WriteString("\n")
+}
+
+// markdownScaling describes how the raw value becomes the output value.
+func markdownScaling(f Field) string {
+	var steps []string
+	op := func(sym string, v *float64) {
+		if v != nil {
+			steps = append(steps, sym+" "+strconv.FormatFloat(*v, 'g', -1, 64))
+		}
+	}
+	if f.ScaleBy != "" {
+		base := f.ScaleBase
+		if base == 0 {
+			base = 10
+		}
+		steps = append(steps, fmt.Sprintf("× %s^%s", strconv.FormatFloat(base, 'g', -1, 64), f.ScaleBy))
+	}
+	stages := f.Transform
+	if len(stages) == 0 {
+		stages = f.Modifiers
+	}
+	switch {
+	case f.Output == OutputDecimalString && f.Scale != nil:
+		steps = append(steps, fmt.Sprintf("× 1e%d (exact)", -*f.Scale))
+	case len(stages) > 0:
+		for _, t := range stages {
+			op("+", t.Add)
+			op("×", t.Mult)
+			op("÷", t.Div)
+		}
+	case len(f.ModOrder) > 0:
+		for _, key := range f.ModOrder {
+			switch key {
+			case "add":
+				op("+", f.Add)
+			case "mult":
+				op("×", f.Mult)
+			case "div":
+				op("÷", f.Div)
+			}
+		}
+	default:
+		op("+", f.Add)
+		op("×", f.Mult)
+		op("÷", f.Div)
+	}
+	if len(f.Polynomial) > 0 {
+		steps = append(steps, "polynomial")
+	}
+	if f.Curve != nil {
+		steps = append(steps, "curve")
+	}
+	if f.Compute != nil || f.Formula != "" {
+		steps = append(steps, "computed")
+	}
+	return strings.Join(steps, ", ")
+}
+
+// markdownDescription combines a field's description with its values,
+// example and the condition under which it is present.
+func markdownDescription(f Field, cond string) string {
+	var parts []string
+	if f.Description != "" {
+		parts = append(parts, strings.TrimSuffix(f.Description, "."))
+	}
+	values := f.Values
+	if values == nil {
+		values = f.Lookup
+	}
+	if len(values) > 0 {
+		keys := make([]int, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+		labels := make([]string, len(keys))
+		for i, k := range keys {
+			labels[i] = fmt.Sprintf("%d = %s", k, values[k])
+		}
+		parts = append(parts, "Values: "+strings.Join(labels, ", "))
+	}
+	if f.Example != nil {
+		parts = append(parts, fmt.Sprintf("Example: `%v`", f.Example))
+	}
+	if cond != "" {
+		parts = append(parts, "Present when "+cond)
+	}
+	if f.Deprecated {
+		parts = append(parts, "Deprecated")
+	}
+	return strings.Join(parts, ". ")
+}
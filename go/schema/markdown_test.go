@@ -0,0 +1,80 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	s, err := ParseSchema(`
+name: env_sensor
+version: 2
+description: Temperature and humidity sensor.
+fields:
+  - name: temperature
+    display_name: Temperature
+    description: Ambient temperature.
+    type: s16
+    div: 10
+    unit: "°C"
+    example: 21.5
+  - name: mode
+    type: u8
+    lookup: {0: idle, 1: "run|fast"}
+  - name: flags
+    type: u8
+    var: flags
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - {name: battery, type: u8, add: 150, mult: 0.01, unit: V}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+	md, err := s.Markdown()
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+	for _, want := range []string{
+		"# env_sensor\n\nVersion 2\n\nTemperature and humidity sensor.\n\n## Payload\n\nSize: 4-5 bytes\n",
+		"| 0 | 2 | Temperature (`temperature`) | s16 | ÷ 10 | °C | Ambient temperature. Example: `21.5` |",
+		`| 2 | 1 | ` + "`mode`" + ` | u8 |  |  | Values: 0 = idle, 1 = run\|fast |`,
+		"| 4 | 1 | `battery` | u8 | + 150, × 0.01 | V | Present when flags bit 0 |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q in:\n%s", want, md)
+		}
+	}
+	if len(s.Fields[0].Extensions) != 1 {
+		t.Errorf("Extensions = %v, want unit only", s.Fields[0].Extensions)
+	}
+}
+
+func TestMarkdownPorts(t *testing.T) {
+	s, err := ParseSchema(autoPortSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	md, err := s.Markdown()
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+	for _, want := range []string{"## fPort 1 (uplink)", "## fPort 12 (bidirectional)", "| 1 | 2 | `duration` | u16 |"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q in:\n%s", want, md)
+		}
+	}
+	if strings.Index(md, "fPort 10 ") > strings.Index(md, "fPort 11 ") {
+		t.Error("ports out of order")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+// Package mqtt routes decoded payloads to MQTT topics. Topics are
+// templates such as "devices/{device_id}/temp" whose placeholders are
+// filled from connection metadata and decoded values; the broker client
+// is supplied by the caller through Publisher, so the package has no MQTT
+// dependency of its own.
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	schema "github.com/MultiTechSystems/lorawan-payload-schema/go/schema"
+)
+
+// EachField in Route.Field publishes every decoded value as its own
+// message; the template's {field} placeholder receives the value's dotted
+// key.
+const EachField = "*"
+
+// Publisher sends one message. Wrap a client (paho, autopaho, ...) to
+// satisfy it.
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(topic string, payload []byte) error
+
+// Publish calls f(topic, payload).
+func (f PublisherFunc) Publish(topic string, payload []byte) error {
+	return f(topic, payload)
+}
+
+// Route maps a decoded result to one or more messages.
+type Route struct {
+	Topic string // Template, e.g. "devices/{device_id}/{field}"
+	Field string // Dotted key of a single value, EachField, or "" for the whole document
+}
+
+// Router publishes decoded results along its routes.
+type Router struct {
+	Routes    []Route
+	Publisher Publisher
+}
+
+// Publish renders every route for result and publishes the messages.
+// meta holds values that are not part of the payload (device_id, fport,
+// gateway) and takes precedence over decoded values of the same name.
+// Payloads are JSON: the whole result, or the bare value of one field.
+// Publishing stops at the first error.
+func (r *Router) Publish(result map[string]any, meta map[string]any) error {
+	msgs, err := r.Messages(result, meta)
+	if err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if err := r.Publisher.Publish(m.Topic, m.Payload); err != nil {
+			return fmt.Errorf("publish %s: %w", m.Topic, err)
+		}
+	}
+	return nil
+}
+
+// Message is a rendered topic and its payload.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Messages renders every route for result without publishing, in route
+// order (EachField routes in key order).
+func (r *Router) Messages(result map[string]any, meta map[string]any) ([]Message, error) {
+	flat := schema.Flatten(result)
+	vars := make(map[string]any, len(flat)+len(meta))
+	for k, v := range flat {
+		vars[k] = v
+	}
+	for k, v := range meta {
+		vars[k] = v
+	}
+
+	var msgs []Message
+	for _, route := range r.Routes {
+		switch route.Field {
+		case "":
+			topic, err := Render(route.Topic, vars)
+			if err != nil {
+				return nil, err
+			}
+			payload, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("topic %s: %w", topic, err)
+			}
+			msgs = append(msgs, Message{topic, payload})
+		case EachField:
+			keys := make([]string, 0, len(flat))
+			for k := range flat {
+				if !strings.HasPrefix(k, "_") {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				vars["field"] = k
+				msg, err := fieldMessage(route.Topic, vars, flat[k])
+				if err != nil {
+					return nil, err
+				}
+				msgs = append(msgs, msg)
+			}
+			delete(vars, "field")
+		default:
+			v, ok := flat[route.Field]
+			if !ok {
+				continue // value absent from this uplink
+			}
+			msg, err := fieldMessage(route.Topic, vars, v)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func fieldMessage(template string, vars map[string]any, v any) (Message, error) {
+	topic, err := Render(template, vars)
+	if err != nil {
+		return Message{}, err
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return Message{}, fmt.Errorf("topic %s: %w", topic, err)
+	}
+	return Message{topic, payload}, nil
+}
+
+// Render replaces each {name} in template with vars[name]. A missing
+// name, or a value that would change the topic structure (containing /,
+// + or #), is an error.
+func Render(template string, vars map[string]any) (string, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("topic %q: unclosed {", template)
+		}
+		name := rest[open+1 : open+end]
+		v, ok := vars[name]
+		if !ok || v == nil {
+			return "", fmt.Errorf("topic %q: no value for {%s}", template, name)
+		}
+		s := fmt.Sprint(v)
+		if s == "" || strings.ContainsAny(s, "/+#\x00") {
+			return "", fmt.Errorf("topic %q: {%s} value %q is not a valid topic level", template, name, s)
+		}
+		b.WriteString(rest[:open])
+		b.WriteString(s)
+		rest = rest[open+end+1:]
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package mqtt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	vars := map[string]any{"device_id": "a84041", "fport": 2, "sensor.temp": 21.5}
+	tests := []struct {
+		template string
+		want     string
+		err      string
+	}{
+		{"devices/{device_id}/up/{fport}", "devices/a84041/up/2", ""},
+		{"devices/{device_id}/{sensor.temp}", "devices/a84041/21.5", ""},
+		{"plain/topic", "plain/topic", ""},
+		{"devices/{dev_eui}", "", "no value for {dev_eui}"},
+		{"devices/{device_id", "", "unclosed"},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.template, vars)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Render(%q) error = %v, want %q", tt.template, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Render(%q) = %q, %v; want %q", tt.template, got, err, tt.want)
+		}
+	}
+
+	if _, err := Render("devices/{id}", map[string]any{"id": "a/#"}); err == nil {
+		t.Error("Render() accepted a value containing topic separators")
+	}
+}
+
+func TestRouterPublish(t *testing.T) {
+	result := map[string]any{
+		"temperature": 21.5,
+		"mode":        "active",
+		"sensor":      map[string]any{"humidity": 40.0},
+		"_quality":    map[string]string{"temperature": "good"},
+	}
+	var got []string
+	r := &Router{
+		Routes: []Route{
+			{Topic: "devices/{device_id}/up"},
+			{Topic: "devices/{device_id}/temp", Field: "temperature"},
+			{Topic: "devices/{device_id}/battery", Field: "battery"},
+			{Topic: "devices/{device_id}/{mode}/{field}", Field: EachField},
+		},
+		Publisher: PublisherFunc(func(topic string, payload []byte) error {
+			got = append(got, topic+" "+string(payload))
+			return nil
+		}),
+	}
+	if err := r.Publish(result, map[string]any{"device_id": "dev1"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	want := []string{
+		`devices/dev1/up {"_quality":{"temperature":"good"},"mode":"active","sensor":{"humidity":40},"temperature":21.5}`,
+		`devices/dev1/temp 21.5`,
+		`devices/dev1/active/mode "active"`,
+		`devices/dev1/active/sensor.humidity 40`,
+		`devices/dev1/active/temperature 21.5`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("published:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	r.Publisher = PublisherFunc(func(string, []byte) error { return errors.New("offline") })
+	if err := r.Publish(result, map[string]any{"device_id": "dev1"}); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("Publish() error = %v, want offline", err)
+	}
+}
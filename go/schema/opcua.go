@@ -0,0 +1,258 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// OPC UA export describes each output field as an AnalogItemType-style
+// variable for an edge gateway's address space, and turns decoded results
+// into DataValues for those variables. The semantic annotations map onto
+// the standard properties:
+//
+//	valid_range -> EURange
+//	unece       -> EngineeringUnits (UnitId from the UNECE Rec 20 code)
+//	resolution  -> ValuePrecision (decimal places)
+//
+// Fields inside a repeat become one-dimensional array variables.
+
+// OPCUAUnitsNamespace is the namespace URI of UNECE-coded EUInformation.
+const OPCUAUnitsNamespace = "http://www.opcfoundation.org/UA/units/un/cefact"
+
+// OPC UA status codes used for decoder quality.
+const (
+	OPCUAGood                              uint32 = 0x00000000
+	OPCUAUncertain                         uint32 = 0x40000000
+	OPCUAUncertainEngineeringUnitsExceeded uint32 = 0x40940000
+	OPCUABadSensorFailure                  uint32 = 0x808B0000
+	OPCUABadOutOfRange                     uint32 = 0x803C0000
+)
+
+// opcuaStatus maps _quality values to status codes.
+var opcuaStatus = map[string]uint32{
+	"good":         OPCUAGood,
+	"out_of_range": OPCUAUncertainEngineeringUnitsExceeded,
+	"invalid":      OPCUABadSensorFailure,
+	"non_finite":   OPCUABadOutOfRange,
+}
+
+// OPCUAOptions controls OPC UA export.
+type OPCUAOptions struct {
+	FPort     int    // Port the result was decoded on (port-based schemas)
+	Namespace int    // Namespace index of the node IDs; 0 means 1
+	Prefix    string // Prepended to the field path in string node IDs, e.g. "dev42."
+}
+
+// OPCUARange is an EURange: the bounds of normal operation.
+type OPCUARange struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// OPCUAEUInformation is the EngineeringUnits property of a variable.
+type OPCUAEUInformation struct {
+	NamespaceURI string `json:"namespace_uri"`
+	UnitID       int32  `json:"unit_id"`
+	DisplayName  string `json:"display_name"`
+	Description  string `json:"description,omitempty"`
+}
+
+// OPCUAVariable describes the variable node for one output field.
+type OPCUAVariable struct {
+	NodeID           string              `json:"node_id"`
+	Path             string              `json:"path"`
+	BrowseName       string              `json:"browse_name"`
+	Description      string              `json:"description,omitempty"`
+	DataType         string              `json:"data_type"`  // Double, Boolean or String
+	ValueRank        int                 `json:"value_rank"` // -1 scalar, 1 array
+	EURange          *OPCUARange         `json:"eu_range,omitempty"`
+	EngineeringUnits *OPCUAEUInformation `json:"engineering_units,omitempty"`
+	ValuePrecision   *int                `json:"value_precision,omitempty"`
+}
+
+// OPCUADataValue is a value to write to a variable.
+type OPCUADataValue struct {
+	NodeID          string
+	Value           any // float64, bool, string, or []any for array variables
+	StatusCode      uint32
+	SourceTimestamp time.Time // From the role: timestamp field; zero if none
+}
+
+// OPCUAVariables describes a variable per output field, in schema order.
+// Containers (objects, repeats, match and TLV fields) are not variables;
+// their members are.
+func (s *Schema) OPCUAVariables(opts OPCUAOptions) ([]OPCUAVariable, error) {
+	var vars []OPCUAVariable
+	err := s.walkOPCUA(opts, func(f Field, path string, array bool) {
+		dataType := opcuaDataType(f)
+		if dataType == "" {
+			return
+		}
+		v := OPCUAVariable{
+			NodeID:      opts.nodeID(path),
+			Path:        path,
+			BrowseName:  path[strings.LastIndexByte(path, '.')+1:],
+			Description: f.Description,
+			DataType:    dataType,
+			ValueRank:   -1,
+		}
+		if array {
+			v.ValueRank = 1
+		}
+		if len(f.ValidRange) == 2 {
+			v.EURange = &OPCUARange{Low: f.ValidRange[0], High: f.ValidRange[1]}
+		}
+		if f.UNECE != "" {
+			v.EngineeringUnits = &OPCUAEUInformation{
+				NamespaceURI: OPCUAUnitsNamespace,
+				UnitID:       UNECEUnitID(f.UNECE),
+				DisplayName:  f.UNECE,
+			}
+			if u := fieldUnit(f); u != "" {
+				v.EngineeringUnits.DisplayName = u
+			}
+		}
+		if f.Resolution != nil && *f.Resolution > 0 {
+			p := int(math.Max(0, math.Ceil(-math.Log10(*f.Resolution)-1e-9)))
+			v.ValuePrecision = &p
+		}
+		vars = append(vars, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// OPCUAValues converts a decoded result into DataValues for the variables
+// of OPCUAVariables. Status codes come from the _quality map: good is
+// Good, out_of_range UncertainEngineeringUnitsExceeded, invalid
+// BadSensorFailure, non_finite BadOutOfRange, and any other quality
+// Uncertain. A role: timestamp field sets the SourceTimestamp of every
+// value.
+func (s *Schema) OPCUAValues(result map[string]any, opts OPCUAOptions) ([]OPCUADataValue, error) {
+	known := map[string]Field{}
+	arrays := map[string]bool{}
+	if err := s.walkOPCUA(opts, func(f Field, path string, array bool) {
+		if opcuaDataType(f) != "" {
+			known[path] = f
+			arrays[path] = array
+		}
+	}); err != nil {
+		return nil, err
+	}
+	quality := qualityMap(result["_quality"])
+
+	var values []OPCUADataValue
+	index := map[string]int{}
+	var source time.Time
+	err := s.walkLeaves(result, opts.FPort, func(prefix string, v any) error {
+		path := s.rolePath(prefix)
+		f, ok := known[path]
+		if !ok {
+			return nil
+		}
+		if f.Role == RoleTimestamp && v != nil {
+			t, err := pointTime(v)
+			if err != nil {
+				return fmt.Errorf("opcua: %s: %w", prefix, err)
+			}
+			source = t
+		}
+		if i, seen := index[path]; seen {
+			values[i].Value = append(values[i].Value.([]any), v)
+			return nil
+		}
+		dv := OPCUADataValue{NodeID: opts.nodeID(path), Value: v, StatusCode: OPCUAGood}
+		if arrays[path] {
+			dv.Value = []any{v}
+		}
+		q, ok := quality[path]
+		if !ok {
+			q, ok = quality[f.Name]
+		}
+		if ok {
+			if code, known := opcuaStatus[q]; known {
+				dv.StatusCode = code
+			} else {
+				dv.StatusCode = OPCUAUncertain
+			}
+		}
+		index[path] = len(values)
+		values = append(values, dv)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range values {
+		values[i].SourceTimestamp = source
+	}
+	return values, nil
+}
+
+// UNECEUnitID returns the OPC UA UnitId of a UNECE Rec 20 common code:
+// its characters packed big-endian into an Int32 ("CEL" is 0x43454C).
+func UNECEUnitID(code string) int32 {
+	var id int32
+	for i := 0; i < len(code) && i < 4; i++ {
+		id = id<<8 | int32(code[i])
+	}
+	return id
+}
+
+// walkOPCUA calls fn for each output path with whether it lies inside a
+// repeat.
+func (s *Schema) walkOPCUA(opts OPCUAOptions, fn func(f Field, path string, array bool)) error {
+	var repeats []string
+	return s.walkOutputPaths(opts.FPort, func(f Field, path string) {
+		array := false
+		for _, r := range repeats {
+			if strings.HasPrefix(path, r+".") {
+				array = true
+				break
+			}
+		}
+		if f.Type == TypeRepeat || f.Type == TypeRepeatLower {
+			repeats = append(repeats, path)
+		}
+		fn(f, path, array)
+	})
+}
+
+func (o OPCUAOptions) nodeID(path string) string {
+	ns := o.Namespace
+	if ns == 0 {
+		ns = 1
+	}
+	return fmt.Sprintf("ns=%d;s=%s%s", ns, o.Prefix, path)
+}
+
+// opcuaDataType returns the built-in DataType of a field's decoded value,
+// or "" for containers.
+func opcuaDataType(f Field) string {
+	switch f.Type {
+	case TypeObject, TypeObjectLower, TypeRepeat, TypeRepeatLower, TypeMatch, TypeMatchLower,
+		TypeTLV, TypeTLVLower, TypeSkip, TypeSkipLower, TypeReserved, TypeReservedLower, TypeMagic, TypeMagicLower, TypeSeries, TypeVector3:
+		return ""
+	case TypeBool, TypeBoolLower:
+		return "Boolean"
+	case TypeAscii, TypeAsciiLower, TypeHex, TypeBase64, TypeString, TypeStringLower,
+		TypeBytes, TypeBytesLower, TypeEnum, TypeEnumLower, TypeBitfieldString:
+		return "String"
+	}
+	if len(f.Fields) > 0 || len(f.Cases) > 0 || len(f.ByteGroup) > 0 || len(f.TLVCases) > 0 ||
+		f.Flagged != nil || f.MatchInline != nil || f.TLVInline != nil {
+		return ""
+	}
+	if len(f.Lookup) > 0 {
+		return "String"
+	}
+	return "Double"
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const opcuaSchema = `
+name: tank
+fields:
+  - {name: time, type: u32, role: timestamp}
+  - name: temperature
+    type: s16
+    mult: 0.1
+    unit: "°C"
+    unece: CEL
+    valid_range: [-20, 60]
+    resolution: 0.1
+    description: Tank temperature
+  - {name: pump_on, type: bool, consume: 1}
+  - name: levels
+    type: repeat
+    count: 2
+    fields:
+      - {name: level, type: u8, unece: P1, valid_range: [0, 100]}
+`
+
+func TestOPCUAVariables(t *testing.T) {
+	s, err := ParseSchema(opcuaSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	vars, err := s.OPCUAVariables(OPCUAOptions{Namespace: 2, Prefix: "tank1."})
+	if err != nil {
+		t.Fatalf("OPCUAVariables() error = %v", err)
+	}
+	one := 1
+	want := []OPCUAVariable{
+		{NodeID: "ns=2;s=tank1.time", Path: "time", BrowseName: "time", DataType: "Double", ValueRank: -1},
+		{
+			NodeID: "ns=2;s=tank1.temperature", Path: "temperature", BrowseName: "temperature",
+			Description: "Tank temperature", DataType: "Double", ValueRank: -1,
+			EURange: &OPCUARange{Low: -20, High: 60},
+			EngineeringUnits: &OPCUAEUInformation{
+				NamespaceURI: OPCUAUnitsNamespace, UnitID: 4408652, DisplayName: "°C",
+			},
+			ValuePrecision: &one,
+		},
+		{NodeID: "ns=2;s=tank1.pump_on", Path: "pump_on", BrowseName: "pump_on", DataType: "Boolean", ValueRank: -1},
+		{
+			NodeID: "ns=2;s=tank1.levels.level", Path: "levels.level", BrowseName: "level",
+			DataType: "Double", ValueRank: 1,
+			EURange: &OPCUARange{Low: 0, High: 100},
+			EngineeringUnits: &OPCUAEUInformation{
+				NamespaceURI: OPCUAUnitsNamespace, UnitID: 0x5031, DisplayName: "P1",
+			},
+		},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("OPCUAVariables() =\n%+v\nwant\n%+v", vars, want)
+	}
+}
+
+func TestOPCUAValues(t *testing.T) {
+	s, err := ParseSchema(opcuaSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// temperature 70.0 is outside valid_range
+	result, err := s.Decode([]byte{0x65, 0x53, 0xF1, 0x00, 0x02, 0xBC, 0x01, 0x28, 0x32})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	values, err := s.OPCUAValues(result, OPCUAOptions{})
+	if err != nil {
+		t.Fatalf("OPCUAValues() error = %v", err)
+	}
+	ts := time.Unix(1700000000, 0).UTC()
+	want := []OPCUADataValue{
+		{NodeID: "ns=1;s=time", Value: float64(1700000000), StatusCode: OPCUAGood, SourceTimestamp: ts},
+		{NodeID: "ns=1;s=temperature", Value: float64(70), StatusCode: OPCUAUncertainEngineeringUnitsExceeded, SourceTimestamp: ts},
+		{NodeID: "ns=1;s=pump_on", Value: true, StatusCode: OPCUAGood, SourceTimestamp: ts},
+		{NodeID: "ns=1;s=levels.level", Value: []any{float64(40), float64(50)}, StatusCode: OPCUAGood, SourceTimestamp: ts},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("OPCUAValues() =\n%+v\nwant\n%+v", values, want)
+	}
+}
+
+func TestUNECEUnitID(t *testing.T) {
+	for code, want := range map[string]int32{"CEL": 4408652, "KEL": 4932940, "P1": 20529} {
+		if got := UNECEUnitID(code); got != want {
+			t.Errorf("UNECEUnitID(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"time"
+)
+
+// DecodeOption configures a single Decode call. New decode settings are
+// added as options, so the Decode signature stays fixed:
+//
+//	result, err := s.Decode(payload, schema.WithPort(fPort), schema.WithInfo(&info))
+//
+// DecodeWithPort, DecodeAt and DecodeWithInfo are wrappers around the
+// equivalent options. The safety limits the options override are a
+// DecodeLimits.
+type DecodeOption func(*decodeConfig)
+
+// decodeConfig collects the options of a Decode call.
+type decodeConfig struct {
+	fPort      int
+	portSet    bool
+	receivedAt time.Time
+	limits     *DecodeLimits
+	strict     bool
+	params     map[string]any
+	info       *DecodeInfo
+	order      *OrderedMap
+	result     map[string]any
+	hooks      []FieldHook
+	metrics    DecodeMetrics
+}
+
+// WithPort selects the fields for an uplink on fPort, as DecodeWithPort
+// does. Without it Decode uses the top-level fields.
+func WithPort(fPort int) DecodeOption {
+	return func(c *decodeConfig) {
+		c.fPort, c.portSet = fPort, true
+	}
+}
+
+// WithReceivedAt dates the samples of series fields, as DecodeAt does.
+func WithReceivedAt(t time.Time) DecodeOption {
+	return func(c *decodeConfig) {
+		c.receivedAt = t
+	}
+}
+
+// WithLimits replaces the schema's DecodeLimits for this call.
+func WithLimits(o DecodeLimits) DecodeOption {
+	return func(c *decodeConfig) {
+		c.limits = &o
+	}
+}
+
+// WithStrict fails the decode on trailing bytes, on repeats or TLV
+// sections cut short at a limit and on reserved bytes that differ from
+// their expect:, which otherwise only add warnings.
+func WithStrict() DecodeOption {
+	return func(c *decodeConfig) {
+		c.strict = true
+	}
+}
+
+// WithParams makes values available to the schema as variables before
+// decoding starts, e.g. a per-device calibration offset read by a
+// formula as $cal_offset. Variables the payload sets take precedence.
+func WithParams(params map[string]any) DecodeOption {
+	return func(c *decodeConfig) {
+		c.params = params
+	}
+}
+
+// WithInfo fills info with how the payload was decoded, as
+// DecodeWithInfo does.
+func WithInfo(info *DecodeInfo) DecodeOption {
+	return func(c *decodeConfig) {
+		c.info = info
+	}
+}
+
+// DecodeStats describes one Decode call for a DecodeMetrics sink.
+type DecodeStats struct {
+	Schema   string
+	FPort    int // 0 without WithPort
+	Bytes    int // Payload length
+	Duration time.Duration
+	Err      error // nil if the decode succeeded
+}
+
+// DecodeMetrics receives the stats of each Decode call given WithMetrics,
+// e.g. to feed decode latency and error counters. ObserveDecode runs on
+// the decoding goroutine and must be safe for concurrent use if decodes
+// are.
+type DecodeMetrics interface {
+	ObserveDecode(stats DecodeStats)
+}
+
+// WithMetrics reports the call's stats to m when it returns, failed or not.
+func WithMetrics(m DecodeMetrics) DecodeOption {
+	return func(c *decodeConfig) {
+		c.metrics = m
+	}
+}
+
+// Decode decodes binary data using the schema, configured by opts.
+func (s *Schema) Decode(data []byte, opts ...DecodeOption) (map[string]any, error) {
+	var cfg decodeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.metrics == nil {
+		return s.decode(data, &cfg)
+	}
+
+	start := time.Now()
+	result, err := s.decode(data, &cfg)
+	cfg.metrics.ObserveDecode(DecodeStats{
+		Schema:   s.Name,
+		FPort:    cfg.fPort,
+		Bytes:    len(data),
+		Duration: time.Since(start),
+		Err:      err,
+	})
+	return result, err
+}
+
+// decode runs a Decode call configured by cfg.
+func (s *Schema) decode(data []byte, cfg *decodeConfig) (map[string]any, error) {
+	chain := []*PortDef{{Fields: s.topFields()}}
+	if cfg.portSet {
+		var err error
+		if chain, err = s.portChain(cfg.fPort, PortUp); err != nil {
+			return nil, err
+		}
+	}
+
+	// Each fallback: entry decodes afresh when the one before it fails;
+	// if all fail, the error is the first entry's
+	var ctx *DecodeContext
+	var result map[string]any
+	var firstErr error
+	var used int
+	for i, pd := range chain {
+		var err error
+		ctx = s.newDecodeContext(data, cfg.receivedAt)
+		if cfg.limits != nil {
+			ctx.Limits = *cfg.limits
+		}
+		if cfg.strict {
+			ctx.Limits.ErrorOnLimit = true
+		}
+		for k, v := range cfg.params {
+			ctx.Variables[k] = v
+		}
+		ctx.hooks = cfg.hooks
+		if cfg.result != nil {
+			clear(cfg.result)
+			ctx.result = cfg.result
+		}
+
+		result, err = s.decodeWith(ctx, s.portHeader(pd), pd.Fields)
+		if err == nil && cfg.strict && ctx.Offset < len(data) {
+			err = fmt.Errorf("%d trailing bytes after the last field", len(data)-ctx.Offset)
+		}
+		if err == nil {
+			used = i
+			break
+		}
+		if i == 0 {
+			firstErr = err
+		}
+		if i == len(chain)-1 {
+			return nil, firstErr
+		}
+	}
+	result = s.finishResult(result, ctx)
+
+	if cfg.info != nil {
+		*cfg.info = DecodeInfo{
+			Schema:        s.Name,
+			Version:       s.Version,
+			FPort:         cfg.fPort,
+			BytesConsumed: ctx.Offset,
+			Warnings:      ctx.Warnings,
+			Fallbacks:     used,
+		}
+		if ctx.Offset < len(data) {
+			cfg.info.TrailingBytes = len(data) - ctx.Offset
+		}
+	}
+	if cfg.order != nil {
+		pd := chain[used]
+		*cfg.order = s.orderResult(result, s.portHeader(pd), pd.Fields)
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeOptions(t *testing.T) {
+	s, err := ParseSchema(`
+name: opts
+version: 2
+ports:
+  "1":
+    fields:
+      - {name: raw, type: u8}
+      - {name: level, type: number, formula: "$raw + $offset"}
+  default:
+    fields:
+      - {name: other, type: u8}
+fields:
+  - {name: top, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	got, err := s.Decode([]byte{0x05})
+	if err != nil || got["top"] != 5.0 {
+		t.Errorf("Decode() = %v, %v, want top-level fields", got, err)
+	}
+
+	var info DecodeInfo
+	got, err = s.Decode([]byte{0x05, 0xFF}, WithPort(1), WithParams(map[string]any{"offset": 10.0}), WithInfo(&info))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["raw"] != 5.0 || got["level"] != 15.0 {
+		t.Errorf("Decode() = %v", got)
+	}
+	if info.Schema != "opts" || info.Version != 2 || info.FPort != 1 || info.BytesConsumed != 1 || info.TrailingBytes != 1 {
+		t.Errorf("info = %+v", info)
+	}
+
+	_, err = s.Decode([]byte{0x05, 0xFF}, WithPort(1), WithParams(map[string]any{"offset": 1}), WithStrict())
+	if err == nil || !strings.Contains(err.Error(), "1 trailing bytes") {
+		t.Errorf("Decode(WithStrict) error = %v", err)
+	}
+}
+
+func TestDecodeWithLimits(t *testing.T) {
+	s, err := ParseSchema(`
+name: limits
+fields:
+  - {name: items, type: repeat, until: end, fields: [{name: v, type: u8}]}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	data := []byte{1, 2, 3}
+
+	var info DecodeInfo
+	got, err := s.Decode(data, WithLimits(DecodeLimits{MaxRepeat: 2}), WithInfo(&info))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if n := len(got["items"].([]any)); n != 2 || len(info.Warnings) != 1 {
+		t.Errorf("items = %d, warnings = %v", n, info.Warnings)
+	}
+
+	if _, err := s.Decode(data, WithLimits(DecodeLimits{MaxRepeat: 2}), WithStrict()); err == nil {
+		t.Error("Decode(WithStrict) should fail at the repeat limit")
+	}
+}
+
+type recordMetrics []DecodeStats
+
+func (r *recordMetrics) ObserveDecode(stats DecodeStats) {
+	*r = append(*r, stats)
+}
+
+func TestDecodeWithMetrics(t *testing.T) {
+	s, err := ParseSchema(`
+name: metered
+fields:
+  - {name: temp, type: s16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	var seen recordMetrics
+	if _, err := s.Decode([]byte{0x00, 0xFA}, WithMetrics(&seen)); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if _, err := s.Decode([]byte{0x00}, WithMetrics(&seen), WithPort(3)); err == nil {
+		t.Fatal("Decode() of a short payload should fail")
+	}
+	if len(seen) != 2 {
+		t.Fatalf("observed %d decodes, want 2", len(seen))
+	}
+	if st := seen[0]; st.Schema != "metered" || st.Bytes != 2 || st.Err != nil || st.Duration < 0 {
+		t.Errorf("stats[0] = %+v", st)
+	}
+	if st := seen[1]; st.FPort != 3 || st.Bytes != 1 || st.Err == nil {
+		t.Errorf("stats[1] = %+v", st)
+	}
+}
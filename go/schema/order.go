@@ -0,0 +1,349 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrderedMap is a decode result whose keys are in schema declaration
+// order, for documentation generators and UIs that show fields the way the
+// schema lists them. Nested objects are OrderedMaps too; arrays are []any.
+// It marshals to a JSON object in the same order.
+//
+// Keys the schema does not declare (unknown TLV tags, WASM output) follow
+// the declared ones alphabetically, and metadata keys such as _quality
+// come last.
+type OrderedMap []KeyValue
+
+// KeyValue is one entry of an OrderedMap.
+type KeyValue struct {
+	Key   string
+	Value any
+}
+
+// WithOrder fills out with the result in schema declaration order, beside
+// the map Decode returns.
+func WithOrder(out *OrderedMap) DecodeOption {
+	return func(c *decodeConfig) {
+		c.order = out
+	}
+}
+
+// Get returns the value of key.
+func (m OrderedMap) Get(key string) (any, bool) {
+	for _, kv := range m {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns the keys in order.
+func (m OrderedMap) Keys() []string {
+	keys := make([]string, len(m))
+	for i, kv := range m {
+		keys[i] = kv.Key
+	}
+	return keys
+}
+
+// MarshalJSON encodes m as a JSON object with its keys in order.
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, kv := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// orderNode holds the declaration order of the keys of one output object
+// and the order of the objects nested under them.
+type orderNode struct {
+	rank     map[string]int
+	children map[string]*orderNode
+}
+
+// orderBuilder builds orderNodes from fields, naming keys as the output
+// style does.
+type orderBuilder struct {
+	defs  map[string]*DefinitionDef
+	style OutputStyle
+	depth int
+}
+
+func (b *orderBuilder) node(lists ...[]Field) *orderNode {
+	n := &orderNode{rank: map[string]int{}, children: map[string]*orderNode{}}
+	for _, fields := range lists {
+		b.add(n, fields)
+	}
+	return n
+}
+
+func (b *orderBuilder) add(n *orderNode, fields []Field) {
+	for _, f := range fields {
+		switch {
+		case f.Ref2 != "":
+			if def, err := lookupDefinition(f.Ref2, b.defs); err == nil && b.depth < maxRefDepth {
+				b.depth++
+				b.add(n, def.Fields)
+				b.depth--
+			}
+			continue
+		case f.Flagged != nil:
+			for _, g := range f.Flagged.Groups {
+				b.add(n, g.Fields)
+			}
+			continue
+		case len(f.ByteGroup) > 0:
+			b.add(n, f.ByteGroup)
+			continue
+		case f.MatchInline != nil:
+			for _, c := range f.MatchInline.Cases {
+				b.add(n, c.Fields)
+			}
+			continue
+		case f.TLVInline != nil:
+			for _, key := range sortedKeys(f.TLVInline.TLVCases) {
+				b.add(n, f.TLVInline.TLVCases[key])
+			}
+			continue
+		}
+		if f.Name == "" {
+			continue
+		}
+
+		var child *orderNode
+		switch f.Type {
+		case TypeObject, TypeObjectLower:
+			child = b.node(f.Fields)
+		case TypeMatch, TypeMatchLower, "CTRL-SWITCH", "Switch":
+			child = b.node()
+			for _, c := range f.Cases {
+				b.add(child, c.Fields)
+			}
+		case TypeRepeat, TypeRepeatLower:
+			child = b.node(f.Fields)
+		case TypeSeries:
+			child = b.node(f.Fields, []Field{{Name: SeriesOffsetKey}, {Name: SeriesTimeKey}})
+		case TypeVector3:
+			child = b.node(vectorAxes(f))
+		case TypeTLV, TypeTLVLower:
+			child = b.node()
+			for _, key := range sortedKeys(f.TLVCases) {
+				b.add(child, f.TLVCases[key])
+			}
+		}
+		b.set(n, f.Name, child)
+		for _, alias := range f.Aliases {
+			b.set(n, alias, child)
+		}
+		for _, ae := range f.AlsoEmit {
+			b.set(n, ae.Name, nil)
+		}
+	}
+}
+
+// set records key after the keys already declared, merging the children
+// of a key declared twice (e.g. by several match cases).
+func (b *orderBuilder) set(n *orderNode, key string, child *orderNode) {
+	key = b.style.restyleKey(key)
+	if _, ok := n.rank[key]; !ok {
+		n.rank[key] = len(n.rank)
+	}
+	if child == nil {
+		return
+	}
+	prev := n.children[key]
+	if prev == nil {
+		n.children[key] = child
+		return
+	}
+	for _, k := range sortedByRank(child.rank) {
+		b.set(prev, k, child.children[k])
+	}
+}
+
+func sortedByRank(rank map[string]int) []string {
+	keys := make([]string, 0, len(rank))
+	for k := range rank {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return rank[keys[i]] < rank[keys[j]] })
+	return keys
+}
+
+// orderResult returns result, decoded from header and fields, in
+// declaration order.
+func (s *Schema) orderResult(result map[string]any, header, fields []Field) OrderedMap {
+	b := &orderBuilder{defs: s.Definitions, style: s.Output}
+	root := b.node(header, fields)
+	if s.Frames != nil {
+		frame := b.node(s.Frames.Header)
+		for _, c := range s.Frames.Match.Cases {
+			b.add(frame, c.Fields)
+		}
+		b.set(root, s.Frames.key(), frame)
+	}
+
+	prefix := ""
+	if ns := s.Output.Namespace; ns != "" {
+		if s.Output.NamespaceMode == NamespaceNest {
+			root = &orderNode{rank: map[string]int{ns: 0}, children: map[string]*orderNode{ns: root}}
+		} else {
+			prefix = ns + "."
+		}
+	}
+	o := &orderer{prefix: prefix, flat: s.Output.Mode == OutputFlat}
+	return o.object(result, root, true)
+}
+
+// orderer sorts output objects by their orderNodes.
+type orderer struct {
+	prefix string // Namespace prefix of top-level keys
+	flat   bool   // Keys are dotted paths
+}
+
+func (o *orderer) value(v any, n *orderNode) any {
+	switch tv := v.(type) {
+	case map[string]any:
+		return o.object(tv, n, false)
+	case []any:
+		out := make([]any, len(tv))
+		for i, elem := range tv {
+			out[i] = o.value(elem, n)
+		}
+		return out
+	}
+	return v
+}
+
+func (o *orderer) object(m map[string]any, n *orderNode, top bool) OrderedMap {
+	keys := sortedKeys(m)
+	names := make(map[string]string, len(keys))
+	paths := make(map[string][]string, len(keys))
+	for _, k := range keys {
+		names[k] = k
+		if top && !strings.HasPrefix(k, "_") {
+			names[k] = strings.TrimPrefix(k, o.prefix)
+		}
+		paths[k] = []string{names[k]}
+		if o.flat && top {
+			paths[k] = strings.Split(names[k], ".")
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return comparePaths(n, paths[keys[i]], paths[keys[j]]) < 0
+	})
+
+	out := make(OrderedMap, len(keys))
+	for i, k := range keys {
+		var child *orderNode
+		if n != nil {
+			child = n.children[names[k]]
+		}
+		out[i] = KeyValue{Key: k, Value: o.value(m[k], child)}
+	}
+	return out
+}
+
+// comparePaths orders two key paths: declared keys by rank, then
+// undeclared keys, then metadata keys; array indexes numerically.
+func comparePaths(n *orderNode, a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			if _, err := strconv.Atoi(a[i]); err != nil && n != nil {
+				n = n.children[a[i]]
+			}
+			continue
+		}
+		ia, errA := strconv.Atoi(a[i])
+		ib, errB := strconv.Atoi(b[i])
+		if errA == nil && errB == nil {
+			return ia - ib
+		}
+		ra, rb := keyRank(n, a[i]), keyRank(n, b[i])
+		if ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a[i], b[i])
+	}
+	return len(a) - len(b)
+}
+
+// keyRank is the declaration rank of key in n; undeclared keys rank after
+// declared ones, and metadata keys after those.
+func keyRank(n *orderNode, key string) int {
+	const undeclared, metadata = 1 << 30, 1<<30 + 1
+	if strings.HasPrefix(key, "_") {
+		return metadata
+	}
+	if n != nil {
+		if r, ok := n.rank[key]; ok {
+			return r
+		}
+	}
+	return undeclared
+}
+
+// walkLeaves calls fn with the flattened key of each scalar in result
+// decoded on fPort, in schema order. Metadata keys are skipped.
+func (s *Schema) walkLeaves(result map[string]any, fPort int, fn func(key string, v any) error) error {
+	fields, err := s.ResolveFields(fPort)
+	if err != nil {
+		return err
+	}
+	var walk func(prefix string, v any) error
+	walk = func(prefix string, v any) error {
+		switch val := v.(type) {
+		case OrderedMap:
+			for _, kv := range val {
+				if strings.HasPrefix(kv.Key, "_") {
+					continue
+				}
+				if err := walk(joinKey(prefix, kv.Key), kv.Value); err != nil {
+					return err
+				}
+			}
+			return nil
+		case []any:
+			for i, elem := range val {
+				if err := walk(joinKey(prefix, strconv.Itoa(i)), elem); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return fn(prefix, v)
+	}
+	return walk("", s.orderResult(result, s.ResolveHeader(fPort), fields))
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const orderSchema = `
+name: ordered
+header:
+  - {name: version, type: u8}
+fields:
+  - {name: zone, type: u8, var: zone}
+  - name: reading
+    type: Object
+    fields:
+      - {name: temperature, type: s16, div: 10}
+      - {name: battery_level, type: u8}
+  - match:
+      field: $zone
+      cases:
+        1:
+          - {name: valve, type: u8}
+  - name: samples
+    type: repeat
+    count: 2
+    fields:
+      - {name: value, type: u8}
+      - {name: flag, type: u8}
+  - {name: alarm, type: u8, valid_range: [0, 1]}
+`
+
+func TestDecodeWithOrder(t *testing.T) {
+	s, err := ParseSchema(orderSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	var ordered OrderedMap
+	result, err := s.Decode([]byte{1, 1, 0x00, 0xD7, 90, 3, 10, 0, 11, 1, 7}, WithOrder(&ordered))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(ordered) != len(result) {
+		t.Fatalf("ordered has %d keys, result %d", len(ordered), len(result))
+	}
+	want := []string{"version", "zone", "reading", "valve", "samples", "alarm", "_quality"}
+	if got := ordered.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+
+	out, err := json.Marshal(ordered)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	wantJSON := `{"version":1,"zone":1,"reading":{"temperature":21.5,"battery_level":90},"valve":3,` +
+		`"samples":[{"value":10,"flag":0},{"value":11,"flag":1}],"alarm":7,"_quality":{"alarm":"out_of_range"}}`
+	if string(out) != wantJSON {
+		t.Errorf("Marshal() = %s\nwant %s", out, wantJSON)
+	}
+	if v, ok := ordered.Get("valve"); !ok || v != 3.0 {
+		t.Errorf("Get(valve) = %v, %v", v, ok)
+	}
+}
+
+func TestDecodeWithOrderStyled(t *testing.T) {
+	s, err := ParseSchema(orderSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{1, 2, 0x00, 0xD7, 90, 10, 0, 11, 1, 0}
+
+	s.Output = OutputStyle{KeyStyle: KeyStyleCamel}
+	var ordered OrderedMap
+	if _, err := s.Decode(payload, WithOrder(&ordered)); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	reading, _ := ordered.Get("reading")
+	if got := reading.(OrderedMap).Keys(); !reflect.DeepEqual(got, []string{"temperature", "batteryLevel"}) {
+		t.Errorf("reading keys = %v", got)
+	}
+
+	s.Output = OutputStyle{Mode: OutputFlat}
+	if _, err := s.Decode(payload, WithOrder(&ordered)); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []string{"version", "zone", "reading.temperature", "reading.battery_level",
+		"samples.0.value", "samples.0.flag", "samples.1.value", "samples.1.flag", "alarm", "_quality"}
+	if got := ordered.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("flat Keys() = %v, want %v", got, want)
+	}
+}
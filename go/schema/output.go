@@ -0,0 +1,241 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Output key styles.
+const (
+	KeyStyleSnake = "snake" // battery_voltage
+	KeyStyleCamel = "camel" // batteryVoltage
+	KeyStyleKebab = "kebab" // battery-voltage
+)
+
+// Namespace modes.
+const (
+	NamespacePrefix = "prefix" // {"vendor.model.temperature": 21.5}
+	NamespaceNest   = "nest"   // {"vendor.model": {"temperature": 21.5}}
+)
+
+// Output modes.
+const (
+	OutputNested = "nested" // Objects and arrays as decoded (default)
+	OutputFlat   = "flat"   // {"sensor.temp": 21.5, "readings.0.temp": 20.1}
+)
+
+// RecordIndexKey tags each record produced by ExplodeRecords with the
+// position of its array element.
+const RecordIndexKey = "index"
+
+// OutputStyle controls how decoded keys are presented to the destination
+// platform. The zero value leaves output unchanged.
+type OutputStyle struct {
+	KeyStyle      string `json:"key_style,omitempty" yaml:"key_style,omitempty"`
+	Namespace     string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	NamespaceMode string `json:"namespace_mode,omitempty" yaml:"namespace_mode,omitempty"`
+	Mode          string `json:"output_mode,omitempty" yaml:"output_mode,omitempty"`
+}
+
+// IsZero reports whether the style leaves output unchanged.
+func (o OutputStyle) IsZero() bool {
+	return o.KeyStyle == "" && o.Namespace == "" && o.Mode != OutputFlat
+}
+
+// Apply returns result with keys restyled (recursively) and namespaced.
+// Keys starting with an underscore (such as _quality) are kept as-is at
+// the top level so consumers can still find decoder metadata.
+func (o OutputStyle) Apply(result map[string]any) map[string]any {
+	if o.IsZero() || result == nil {
+		return result
+	}
+
+	styled := make(map[string]any, len(result))
+	meta := make(map[string]any)
+	for k, v := range result {
+		if strings.HasPrefix(k, "_") {
+			meta[k] = o.restyleValue(v)
+			continue
+		}
+		styled[o.restyleKey(k)] = o.restyleValue(v)
+	}
+
+	out := styled
+	if o.Namespace != "" {
+		if o.NamespaceMode == NamespaceNest {
+			out = map[string]any{o.Namespace: styled}
+		} else {
+			out = make(map[string]any, len(styled))
+			for k, v := range styled {
+				out[o.Namespace+"."+k] = v
+			}
+		}
+	}
+	if o.Mode == OutputFlat {
+		out = Flatten(out)
+	}
+	for k, v := range meta {
+		out[k] = v
+	}
+	return out
+}
+
+// Flatten returns result with nested objects and arrays folded into
+// dotted keys, e.g. {"sensor": {"temp": 21.5}} becomes {"sensor.temp":
+// 21.5} and the second element of readings becomes "readings.1.temp".
+// Empty objects and arrays disappear. Top-level keys starting with an
+// underscore (decoder metadata) are kept as-is.
+func Flatten(result map[string]any) map[string]any {
+	out := make(map[string]any, len(result))
+	for k, v := range result {
+		if strings.HasPrefix(k, "_") {
+			out[k] = v
+			continue
+		}
+		flattenInto(out, k, v)
+	}
+	return out
+}
+
+func flattenInto(out map[string]any, prefix string, v any) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, inner := range val {
+			flattenInto(out, prefix+"."+k, inner)
+		}
+	case []any:
+		for i, inner := range val {
+			flattenInto(out, prefix+"."+strconv.Itoa(i), inner)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+// ExplodeRecords splits a decoded result into one flat record per element
+// of the array at key, the shape time-series ingesters expect. Each record
+// holds the flattened fields outside the array, the element's own fields
+// (or the element under key, for arrays of scalars) and its position under
+// RecordIndexKey. Element fields win over outer fields of the same name.
+// If key is not an array, the single flattened result is returned.
+func ExplodeRecords(result map[string]any, key string) []map[string]any {
+	elements, ok := result[key].([]any)
+	if !ok {
+		return []map[string]any{Flatten(result)}
+	}
+	outer := make(map[string]any, len(result))
+	for k, v := range result {
+		if k != key {
+			outer[k] = v
+		}
+	}
+	base := Flatten(outer)
+
+	records := make([]map[string]any, 0, len(elements))
+	for i, elem := range elements {
+		rec := make(map[string]any, len(base)+4)
+		for k, v := range base {
+			rec[k] = v
+		}
+		if m, ok := elem.(map[string]any); ok {
+			for k, v := range Flatten(m) {
+				rec[k] = v
+			}
+		} else {
+			flattenInto(rec, key, elem)
+		}
+		rec[RecordIndexKey] = i
+		records = append(records, rec)
+	}
+	return records
+}
+
+func (o OutputStyle) restyleValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(val))
+		for k, inner := range val {
+			m[o.restyleKey(k)] = o.restyleValue(inner)
+		}
+		return m
+	case map[string]string:
+		m := make(map[string]string, len(val))
+		for k, inner := range val {
+			m[o.restyleKey(k)] = inner
+		}
+		return m
+	case []any:
+		arr := make([]any, len(val))
+		for i, inner := range val {
+			arr[i] = o.restyleValue(inner)
+		}
+		return arr
+	}
+	return v
+}
+
+func (o OutputStyle) restyleKey(key string) string {
+	if o.KeyStyle == "" {
+		return key
+	}
+	return RestyleKey(key, o.KeyStyle)
+}
+
+// RestyleKey converts a snake_case, camelCase or kebab-case key to style.
+// Unknown styles return the key unchanged.
+func RestyleKey(key, style string) string {
+	words := splitKeyWords(key)
+	if len(words) == 0 {
+		return key
+	}
+	switch style {
+	case KeyStyleSnake:
+		return strings.Join(words, "_")
+	case KeyStyleKebab:
+		return strings.Join(words, "-")
+	case KeyStyleCamel:
+		var b strings.Builder
+		b.WriteString(words[0])
+		for _, w := range words[1:] {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+		return b.String()
+	}
+	return key
+}
+
+// splitKeyWords splits a key into lowercase words on '_', '-', '.', ' ' and
+// lower-to-upper case transitions.
+func splitKeyWords(key string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])):
+			flush()
+			cur = append(cur, r)
+		case unicode.IsUpper(r) && i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]):
+			// "CO2Level" / "HTTPServer": split before the last capital of an acronym
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return words
+}
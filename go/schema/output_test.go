@@ -0,0 +1,174 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import "testing"
+
+func TestRestyleKey(t *testing.T) {
+	tests := []struct {
+		key, style, want string
+	}{
+		{"battery_voltage", KeyStyleCamel, "batteryVoltage"},
+		{"battery_voltage", KeyStyleKebab, "battery-voltage"},
+		{"batteryVoltage", KeyStyleSnake, "battery_voltage"},
+		{"battery-voltage", KeyStyleSnake, "battery_voltage"},
+		{"CO2Level", KeyStyleSnake, "co2_level"},
+		{"pm2_5", KeyStyleCamel, "pm25"},
+		{"temperature", KeyStyleCamel, "temperature"},
+		{"temperature", "unknown", "temperature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.style, func(t *testing.T) {
+			if got := RestyleKey(tt.key, tt.style); got != tt.want {
+				t.Errorf("RestyleKey(%q, %q) = %q, want %q", tt.key, tt.style, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyStyleAndNamespacePrefix(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+key_style: camel
+namespace: milesight.am308
+fields:
+  - name: battery_level
+    type: u8
+    valid_range: [0, 100]
+  - name: co2_sensor
+    type: Object
+    fields:
+      - name: co2_ppm
+        type: u16
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	result, err := schema.Decode([]byte{0x50, 0x01, 0x90})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["milesight.am308.batteryLevel"] != float64(80) {
+		t.Errorf("result = %v, want milesight.am308.batteryLevel=80", result)
+	}
+	nested, ok := result["milesight.am308.co2Sensor"].(map[string]any)
+	if !ok || nested["co2Ppm"] != float64(400) {
+		t.Errorf("co2Sensor = %v, want co2Ppm=400", result["milesight.am308.co2Sensor"])
+	}
+	quality, ok := result["_quality"].(map[string]string)
+	if !ok || quality["batteryLevel"] != "good" {
+		t.Errorf("_quality = %v, want batteryLevel=good", result["_quality"])
+	}
+}
+
+func TestNamespaceNest(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+key_style: kebab
+namespace: vendor.model
+namespace_mode: nest
+fields:
+  - name: battery_level
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	result, err := schema.Decode([]byte{0x50})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	inner, ok := result["vendor.model"].(map[string]any)
+	if !ok || inner["battery-level"] != float64(80) {
+		t.Errorf("result = %v, want vendor.model.battery-level=80", result)
+	}
+}
+
+func TestOutputModeFlat(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+output_mode: flat
+fields:
+  - name: sensor
+    type: Object
+    fields:
+      - name: temp
+        type: s16
+        div: 10
+  - name: count
+    type: u8
+  - name: readings
+    type: repeat
+    count: $count
+    fields:
+      - name: temp
+        type: u8
+  - name: level
+    type: u8
+    valid_range: [0, 100]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	result, err := schema.Decode([]byte{0x00, 0xD7, 0x02, 0x14, 0x15, 0x50})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := map[string]any{
+		"sensor.temp":     21.5,
+		"count":           float64(2),
+		"readings.0.temp": float64(20),
+		"readings.1.temp": float64(21),
+		"level":           float64(80),
+	}
+	for k, v := range want {
+		if result[k] != v {
+			t.Errorf("result[%q] = %v, want %v", k, result[k], v)
+		}
+	}
+	if _, ok := result["_quality"].(map[string]string); !ok {
+		t.Errorf("_quality = %v, want metadata kept unflattened", result["_quality"])
+	}
+	if len(result) != len(want)+1 {
+		t.Errorf("result = %v, want only flat keys", result)
+	}
+}
+
+func TestExplodeRecords(t *testing.T) {
+	result := map[string]any{
+		"device":   map[string]any{"battery": 3.6},
+		"interval": float64(60),
+		"readings": []any{
+			map[string]any{"temp": 20.5, "pos": map[string]any{"x": 1.0}},
+			map[string]any{"temp": 21.0, "pos": map[string]any{"x": 2.0}},
+		},
+		"samples": []any{1.0, 2.0, 3.0},
+	}
+
+	records := ExplodeRecords(result, "readings")
+	if len(records) != 2 {
+		t.Fatalf("ExplodeRecords() = %d records, want 2", len(records))
+	}
+	rec := records[1]
+	if rec["temp"] != 21.0 || rec["pos.x"] != 2.0 || rec[RecordIndexKey] != 1 {
+		t.Errorf("record 1 = %v, want temp 21, pos.x 2, index 1", rec)
+	}
+	if rec["device.battery"] != 3.6 || rec["interval"] != float64(60) || rec["samples.2"] != 3.0 {
+		t.Errorf("record 1 = %v, want outer fields flattened", rec)
+	}
+	if _, ok := rec["readings"]; ok {
+		t.Errorf("record 1 = %v, exploded array still present", rec)
+	}
+
+	scalars := ExplodeRecords(result, "samples")
+	if len(scalars) != 3 || scalars[2]["samples"] != 3.0 || scalars[2][RecordIndexKey] != 2 {
+		t.Errorf("ExplodeRecords(samples) = %v", scalars)
+	}
+
+	single := ExplodeRecords(result, "missing")
+	if len(single) != 1 || single[0]["readings.0.temp"] != 20.5 {
+		t.Errorf("ExplodeRecords(missing) = %v, want one flattened record", single)
+	}
+}
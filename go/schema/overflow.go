@@ -0,0 +1,137 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+)
+
+// Integer overflow policies for encoding, set by the schema-level
+// encode_overflow: key. Reversed modifiers can produce values the field's
+// type cannot hold, e.g. a temperature of 7000 °C with div: 10 is 70000,
+// too large for an s16.
+const (
+	EncodeOverflowError = "error" // Fail encoding (default with strict or strict_types)
+	EncodeOverflowClamp = "clamp" // Write the nearest value the type holds
+	EncodeOverflowWrap  = "wrap"  // Keep the low bytes, two's complement (default)
+)
+
+// parseEncodeOverflow reads the schema-level encode_overflow: key.
+func parseEncodeOverflow(raw map[string]any) (string, error) {
+	v, ok := raw["encode_overflow"]
+	if !ok {
+		return "", nil
+	}
+	switch v {
+	case EncodeOverflowError, EncodeOverflowClamp, EncodeOverflowWrap:
+		return v.(string), nil
+	}
+	return "", fmt.Errorf("encode_overflow: expected error, clamp or wrap, got %v", v)
+}
+
+// encodeOverflow is the policy encoding uses: encode_overflow if set,
+// otherwise error for strict schemas and wrap for the rest.
+func (s *Schema) encodeOverflow() string {
+	switch {
+	case s.EncodeOverflow != "":
+		return s.EncodeOverflow
+	case s.Strict || s.StrictTypes:
+		return EncodeOverflowError
+	}
+	return EncodeOverflowWrap
+}
+
+// intRange is the range of a length-byte integer, as float64 for comparing
+// against inputs that went through modifiers.
+func intRange(length int, signed bool) (lo, hi float64) {
+	bits := float64(8 * length)
+	if signed {
+		return -math.Exp2(bits - 1), math.Exp2(bits-1) - 1
+	}
+	return 0, math.Exp2(bits) - 1
+}
+
+// fitUint converts an encode input to an unsigned length-byte integer
+// under the overflow policy.
+func (ctx *EncodeContext) fitUint(field Field, value any, length int) (uint64, bool, error) {
+	if u, ok := value.(uint64); ok {
+		// Exact, for 64-bit values beyond float64 precision
+		if length >= 8 || u < 1<<(8*length) {
+			return u, true, nil
+		}
+		return ctx.overflow(field, float64(u), length, false)
+	}
+	if n, ok := value.(int64); ok && n >= 0 {
+		return ctx.fitUint(field, uint64(n), length)
+	}
+	numVal, ok := toFloat64(value)
+	if !ok {
+		return 0, false, nil
+	}
+	numVal = ctx.round(numVal)
+	if lo, hi := intRange(length, false); numVal >= lo && numVal <= hi {
+		return uint64(numVal), true, nil
+	}
+	return ctx.overflow(field, numVal, length, false)
+}
+
+// fitSint is fitUint for signed integers, returning the two's complement
+// bits of the value.
+func (ctx *EncodeContext) fitSint(field Field, value any, length int) (int64, bool, error) {
+	if n, ok := value.(int64); ok {
+		lo, hi := intRange(length, true)
+		if length >= 8 || float64(n) >= lo && float64(n) <= hi {
+			return n, true, nil
+		}
+		u, ok, err := ctx.overflow(field, float64(n), length, true)
+		return int64(u), ok, err
+	}
+	numVal, ok := toFloat64(value)
+	if !ok {
+		return 0, false, nil
+	}
+	numVal = ctx.round(numVal)
+	if lo, hi := intRange(length, true); numVal >= lo && numVal <= hi {
+		return int64(numVal), true, nil
+	}
+	u, ok, err := ctx.overflow(field, numVal, length, true)
+	return int64(u), ok, err
+}
+
+// overflow applies the policy to v, which the type cannot hold.
+func (ctx *EncodeContext) overflow(field Field, v float64, length int, signed bool) (uint64, bool, error) {
+	lo, hi := intRange(length, signed)
+	switch ctx.Overflow {
+	case EncodeOverflowError:
+		return 0, false, fmt.Errorf("%s: %v out of range for %s [%v, %v]",
+			ctx.fieldPath(field.Name), v, field.Type, lo, hi)
+	case EncodeOverflowClamp:
+		bits := uint(8 * min(length, 8))
+		switch {
+		case math.IsNaN(v):
+			return 0, true, nil
+		case v < lo && signed:
+			return uint64(int64(math.MinInt64) >> (64 - bits)), true, nil
+		case v < lo:
+			return 0, true, nil
+		case signed:
+			return math.MaxInt64 >> (64 - bits), true, nil
+		}
+		return math.MaxUint64 >> (64 - bits), true, nil
+	}
+	// Wrap: keep the low bytes of the value
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, true, nil
+	}
+	v = math.Mod(v, math.Exp2(64))
+	switch {
+	case v < math.MinInt64:
+		return uint64(v + math.Exp2(64)), true, nil
+	case v < 0:
+		return uint64(int64(v)), true, nil
+	}
+	return uint64(v), true, nil
+}
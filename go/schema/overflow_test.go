@@ -0,0 +1,86 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const overflowSchema = `
+name: setpoint
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+  - name: level
+    type: u8
+`
+
+func TestEncodeOverflow(t *testing.T) {
+	tests := []struct {
+		policy string
+		input  map[string]any
+		want   []byte
+		err    string
+	}{
+		{"error", map[string]any{"temperature": 7000.0, "level": 1}, nil,
+			"temperature: 70000 out of range for s16 [-32768, 32767]"},
+		{"error", map[string]any{"temperature": 1.0, "level": -1}, nil,
+			"level: -1 out of range for u8 [0, 255]"},
+		{"error", map[string]any{"temperature": -3276.8, "level": 255}, []byte{0x80, 0x00, 0xFF}, ""},
+		{"clamp", map[string]any{"temperature": 7000.0, "level": 300}, []byte{0x7F, 0xFF, 0xFF}, ""},
+		{"clamp", map[string]any{"temperature": -7000.0, "level": -5}, []byte{0x80, 0x00, 0x00}, ""},
+		{"wrap", map[string]any{"temperature": 7000.0, "level": 300}, []byte{0x11, 0x70, 0x2C}, ""},
+		{"wrap", map[string]any{"temperature": 1.0, "level": -1}, []byte{0x00, 0x0A, 0xFF}, ""},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("encode_overflow: " + tt.policy + overflowSchema)
+		if err != nil {
+			t.Fatalf("ParseSchema(%s) error = %v", tt.policy, err)
+		}
+		got, err := s.Encode(tt.input)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: Encode(%v) error = %v, want %q", tt.policy, tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Encode(%v) error = %v", tt.policy, tt.input, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: Encode(%v) = % X, want % X", tt.policy, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeOverflowDefault(t *testing.T) {
+	input := map[string]any{"temperature": 7000.0, "level": 1}
+
+	s, err := ParseSchema(overflowSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if got, err := s.Encode(input); err != nil || !bytes.Equal(got, []byte{0x11, 0x70, 0x01}) {
+		t.Errorf("lenient Encode() = % X, %v; want wrapped bytes", got, err)
+	}
+
+	for _, key := range []string{"strict", "strict_types"} {
+		s, err := ParseSchema(key + ": true" + overflowSchema)
+		if err != nil {
+			t.Fatalf("ParseSchema(%s) error = %v", key, err)
+		}
+		if _, err := s.Encode(input); err == nil {
+			t.Errorf("%s: Encode() overflow: no error", key)
+		}
+	}
+
+	if _, err := ParseSchema("encode_overflow: saturate" + overflowSchema); err == nil {
+		t.Error("ParseSchema(encode_overflow: saturate): no error")
+	}
+}
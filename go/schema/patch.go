@@ -0,0 +1,359 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatchOp is one change applied by Schema.Patch, modelled on JSON Patch
+// (RFC 6902). Op is "add", "replace" or "remove". Path is a JSON Pointer
+// into the schema that addresses fields by name rather than index:
+//
+//	/fields/temperature/div          modifier of a field
+//	/fields/-                        end of a field list (add only)
+//	/fields/status/fields/code       field nested in an object
+//	/header/version                  header field
+//	/ports/2                         a whole port
+//	/ports/2/fields/battery/mult     field of a port
+//	/definitions/reading/fields/temp field of a definition
+//
+// Value is the new field or port as a map written like the schema source
+// ({name: offset, type: s16, div: 10}), or the new attribute value.
+type PatchOp struct {
+	Op    string `json:"op" yaml:"op"`
+	Path  string `json:"path" yaml:"path"`
+	Value any    `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Patch operation names.
+const (
+	PatchAdd     = "add"
+	PatchReplace = "replace"
+	PatchRemove  = "remove"
+)
+
+// patchAttrs are the field attributes a patch can change in place. Other
+// changes replace the whole field.
+var patchAttrs = []string{"add", "mult", "div", "round", "precision", "valid_range", "unit"}
+
+// Patch applies ops to a parsed schema, so per-tenant tweaks (a different
+// scale on one channel) need no forked schema document. Ops apply in order
+// and all or nothing: on error the schema is left unchanged. Adding a field
+// before a named one inserts it there; "-" appends. Patch must not run
+// concurrently with Decode or Encode on the same schema.
+func (s *Schema) Patch(ops []PatchOp) error {
+	c := *s
+	for i, op := range ops {
+		if err := c.applyPatch(op); err != nil {
+			return fmt.Errorf("patch %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	if err := c.finish(); err != nil {
+		return err
+	}
+	*s = c
+	return nil
+}
+
+// applyPatch applies one op. Every slice and map it changes is copied
+// first so that a failing op leaves the original schema intact.
+func (s *Schema) applyPatch(op PatchOp) error {
+	switch op.Op {
+	case PatchAdd, PatchReplace, PatchRemove:
+	default:
+		return fmt.Errorf("unknown op %q (want add, replace or remove)", op.Op)
+	}
+	segs := splitPointer(op.Path)
+	if len(segs) < 2 {
+		return fmt.Errorf("path must address a field or port")
+	}
+
+	switch segs[0] {
+	case "fields":
+		return patchFields(&s.Fields, segs[1:], op)
+	case "header":
+		return patchFields(&s.Header, segs[1:], op)
+	case "ports":
+		ports := make(map[string]*PortDef, len(s.Ports)+1)
+		for k, pd := range s.Ports {
+			ports[k] = pd
+		}
+		key, existing := segs[1], ports[segs[1]]
+		if len(segs) == 2 {
+			if op.Op != PatchAdd && existing == nil {
+				return fmt.Errorf("no port %s", key)
+			}
+			if op.Op == PatchRemove {
+				delete(ports, key)
+			} else {
+				m, ok := op.Value.(map[string]any)
+				if !ok {
+					return fmt.Errorf("port value must be a map, got %T", op.Value)
+				}
+				ports[key] = parsePortDef(m)
+			}
+			s.Ports = ports
+			return nil
+		}
+		if existing == nil {
+			return fmt.Errorf("no port %s", key)
+		}
+		if segs[2] != "fields" && segs[2] != "header" || len(segs) < 4 {
+			return fmt.Errorf("expected /ports/%s/fields/<name> or /ports/%s/header/<name>", key, key)
+		}
+		pd := *existing
+		list := &pd.Fields
+		if segs[2] == "header" {
+			list = &pd.Header
+		}
+		if err := patchFields(list, segs[3:], op); err != nil {
+			return err
+		}
+		ports[key] = &pd
+		s.Ports = ports
+		return nil
+	case "definitions":
+		existing := s.Definitions[segs[1]]
+		if existing == nil {
+			return fmt.Errorf("no definition %s", segs[1])
+		}
+		if len(segs) < 4 || segs[2] != "fields" {
+			return fmt.Errorf("expected /definitions/%s/fields/<name>", segs[1])
+		}
+		dd := *existing
+		if err := patchFields(&dd.Fields, segs[3:], op); err != nil {
+			return err
+		}
+		defs := make(map[string]*DefinitionDef, len(s.Definitions))
+		for k, d := range s.Definitions {
+			defs[k] = d
+		}
+		defs[segs[1]] = &dd
+		s.Definitions = defs
+		return nil
+	}
+	return fmt.Errorf("path must start with /fields, /header, /ports or /definitions")
+}
+
+// patchFields applies op to the field list *list at the path segments
+// below it: a field name, optionally followed by an attribute or by
+// fields/<name> to descend into an object.
+func patchFields(list *[]Field, segs []string, op PatchOp) error {
+	name := segs[0]
+	if name == "-" {
+		if op.Op != PatchAdd || len(segs) > 1 {
+			return fmt.Errorf("- can only be the target of add")
+		}
+		f, err := patchField(op.Value)
+		if err != nil {
+			return err
+		}
+		*list = append(append([]Field(nil), *list...), f)
+		return nil
+	}
+
+	idx := -1
+	for i, f := range *list {
+		if f.Name == name {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("no field %s", name)
+	}
+	fields := append([]Field(nil), *list...)
+
+	switch {
+	case len(segs) == 1 && op.Op == PatchRemove:
+		fields = append(fields[:idx], fields[idx+1:]...)
+	case len(segs) == 1:
+		f, err := patchField(op.Value)
+		if err != nil {
+			return err
+		}
+		if op.Op == PatchReplace {
+			fields[idx] = f
+		} else {
+			fields = append(fields[:idx], append([]Field{f}, fields[idx:]...)...)
+		}
+	case segs[1] == "fields" && len(segs) > 2:
+		if err := patchFields(&fields[idx].Fields, segs[2:], op); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case len(segs) == 2:
+		if err := patchAttr(&fields[idx], segs[1], op); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	default:
+		return fmt.Errorf("cannot resolve %s below field %s", strings.Join(segs[1:], "/"), name)
+	}
+	*list = fields
+	return nil
+}
+
+// patchField parses the value of a field add or replace.
+func patchField(v any) (Field, error) {
+	switch val := v.(type) {
+	case Field:
+		return val, nil
+	case map[string]any:
+		f := parseFieldMap(val, nil)
+		if f.Type == "" && f.Ref2 == "" && f.MatchInline == nil && f.TLVInline == nil && f.Flagged == nil {
+			return Field{}, fmt.Errorf("field value has no type")
+		}
+		return f, nil
+	}
+	return Field{}, fmt.Errorf("field value must be a map, got %T", v)
+}
+
+// patchAttr changes one attribute of f in place.
+func patchAttr(f *Field, attr string, op PatchOp) error {
+	var present bool
+	switch attr {
+	case "add":
+		present = f.Add != nil
+	case "mult":
+		present = f.Mult != nil
+	case "div":
+		present = f.Div != nil
+	case "round":
+		present = f.Round != nil
+	case "precision":
+		present = f.Precision != nil
+	case "valid_range":
+		present = f.ValidRange != nil
+	case "unit":
+		_, present = f.Extensions["unit"]
+	default:
+		return fmt.Errorf("cannot patch %s (patchable: %s); replace the field instead",
+			attr, strings.Join(patchAttrs, ", "))
+	}
+	if op.Op != PatchAdd && !present {
+		return fmt.Errorf("has no %s", attr)
+	}
+
+	switch attr {
+	case "add", "mult", "div":
+		if len(f.Transform) > 0 || len(f.Modifiers) > 0 {
+			return fmt.Errorf("uses a transform pipeline; replace the field instead")
+		}
+		var p *float64
+		if op.Op != PatchRemove {
+			v, ok := toFloat64(op.Value)
+			if !ok {
+				return fmt.Errorf("%s must be a number, got %T", attr, op.Value)
+			}
+			p = &v
+		}
+		setModifier(f, attr, p)
+	case "round", "precision":
+		var p *int
+		if op.Op != PatchRemove {
+			v, ok := toWholeInt(op.Value)
+			if !ok || v < 0 {
+				return fmt.Errorf("%s must be a non-negative integer, got %v", attr, op.Value)
+			}
+			p = &v
+		}
+		if attr == "round" {
+			f.Round = p
+		} else {
+			f.Precision = p
+		}
+	case "valid_range":
+		if op.Op == PatchRemove {
+			f.ValidRange = nil
+			break
+		}
+		list, ok := op.Value.([]any)
+		if fl, isFloats := op.Value.([]float64); isFloats {
+			for _, v := range fl {
+				list = append(list, v)
+			}
+			ok = true
+		}
+		if !ok || len(list) != 2 {
+			return fmt.Errorf("valid_range must be [min, max]")
+		}
+		lo, okLo := toFloat64(list[0])
+		hi, okHi := toFloat64(list[1])
+		if !okLo || !okHi {
+			return fmt.Errorf("valid_range must be [min, max]")
+		}
+		f.ValidRange = []float64{lo, hi}
+	case "unit":
+		ext := make(map[string]any, len(f.Extensions)+1)
+		for k, v := range f.Extensions {
+			ext[k] = v
+		}
+		if op.Op == PatchRemove {
+			delete(ext, "unit")
+		} else if u, ok := op.Value.(string); ok {
+			ext["unit"] = u
+		} else {
+			return fmt.Errorf("unit must be a string, got %T", op.Value)
+		}
+		f.Extensions = ext
+	}
+	return nil
+}
+
+// setModifier sets (or with nil, clears) one of add/mult/div, keeping
+// ModOrder in step. A new modifier applies after the existing ones.
+func setModifier(f *Field, key string, v *float64) {
+	order := f.ModOrder
+	if len(order) == 0 {
+		// Without ModOrder the decoder applies add, mult, div
+		if f.Add != nil {
+			order = append(order, "add")
+		}
+		if f.Mult != nil {
+			order = append(order, "mult")
+		}
+		if f.Div != nil {
+			order = append(order, "div")
+		}
+	}
+	kept := make([]string, 0, len(order)+1)
+	found := false
+	for _, k := range order {
+		if k == key {
+			found = true
+			if v == nil {
+				continue
+			}
+		}
+		kept = append(kept, k)
+	}
+	if !found && v != nil {
+		kept = append(kept, key)
+	}
+	f.ModOrder = kept
+
+	switch key {
+	case "add":
+		f.Add = v
+	case "mult":
+		f.Mult = v
+	case "div":
+		f.Div = v
+	}
+}
+
+// splitPointer splits a JSON Pointer into unescaped segments.
+func splitPointer(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		segs[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(seg)
+	}
+	return segs
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const patchSchema = `
+name: meter
+ports:
+  1:
+    direction: uplink
+    fields:
+      - {name: ch1, type: u16, div: 10}
+      - {name: ch2, type: u16, add: -100, div: 10}
+  2:
+    direction: uplink
+    fields:
+      - {name: battery, type: u8}
+`
+
+func TestSchemaPatch(t *testing.T) {
+	s, err := ParseSchema(patchSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	base, _ := ParseSchema(patchSchema)
+
+	err = s.Patch([]PatchOp{
+		{Op: "replace", Path: "/ports/1/fields/ch1/div", Value: 100},
+		{Op: "add", Path: "/ports/1/fields/ch2/mult", Value: 2.0},
+		{Op: "add", Path: "/ports/1/fields/-", Value: map[string]any{"name": "status", "type": "u8"}},
+		{Op: "remove", Path: "/ports/2"},
+	})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+
+	result, err := s.DecodeWithPort([]byte{0x03, 0xE8, 0x03, 0xE8, 0x07}, 1)
+	if err != nil {
+		t.Fatalf("DecodeWithPort() error = %v", err)
+	}
+	want := map[string]any{"ch1": 10.0, "ch2": 180.0, "status": 7.0}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("DecodeWithPort() = %v, want %v", result, want)
+	}
+	if _, err := s.DecodeWithPort([]byte{0x50}, 2); err == nil {
+		t.Error("port 2 still decodes after removal")
+	}
+
+	// The original parse is untouched by patches to another instance
+	result, _ = base.DecodeWithPort([]byte{0x03, 0xE8, 0x03, 0xE8}, 1)
+	if result["ch1"] != 100.0 || result["ch2"] != 90.0 {
+		t.Errorf("unpatched DecodeWithPort() = %v", result)
+	}
+}
+
+func TestSchemaPatchAtomic(t *testing.T) {
+	s, err := ParseSchema(patchSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		op   PatchOp
+		want string
+	}{
+		{PatchOp{Op: "replace", Path: "/ports/1/fields/ch9/div", Value: 1}, "no field ch9"},
+		{PatchOp{Op: "replace", Path: "/ports/1/fields/ch1/mult", Value: 1}, "has no mult"},
+		{PatchOp{Op: "replace", Path: "/ports/1/fields/ch1/type", Value: "u8"}, "cannot patch type"},
+		{PatchOp{Op: "replace", Path: "/ports/1/fields/ch1/div", Value: "x"}, "div must be a number"},
+		{PatchOp{Op: "move", Path: "/ports/1"}, "unknown op"},
+		{PatchOp{Op: "remove", Path: "/ports/9"}, "no port 9"},
+		{PatchOp{Op: "add", Path: "/fields/-", Value: map[string]any{"name": "x"}}, "has no type"},
+	}
+	for _, tt := range tests {
+		err := s.Patch([]PatchOp{
+			{Op: "replace", Path: "/ports/1/fields/ch1/div", Value: 1000},
+			tt.op,
+		})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Patch(%+v) error = %v, want %q", tt.op, err, tt.want)
+		}
+	}
+
+	// Failed patches leave the schema as parsed
+	result, _ := s.DecodeWithPort([]byte{0x03, 0xE8, 0x03, 0xE8}, 1)
+	if result["ch1"] != 100.0 {
+		t.Errorf("ch1 = %v after failed patches, want 100", result["ch1"])
+	}
+}
+
+func TestSchemaPatchModifierOrder(t *testing.T) {
+	// Fields built in code carry no key order; the implicit order of the
+	// existing modifiers must survive a patch
+	add, div := 1.0, 2.0
+	s := &Schema{Name: "built", Endian: "big", Fields: []Field{{Name: "v", Type: "u8", Div: &div, Add: &add}}}
+	if err := s.Patch([]PatchOp{{Op: "add", Path: "/fields/v/mult", Value: 3}}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if got := s.Fields[0].ModOrder; !reflect.DeepEqual(got, []string{"add", "div", "mult"}) {
+		t.Errorf("ModOrder = %v", got)
+	}
+	result, _ := s.Decode([]byte{9})
+	if result["v"] != 15.0 {
+		t.Errorf("v = %v, want 15", result["v"])
+	}
+}
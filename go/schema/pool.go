@@ -0,0 +1,62 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import "sync"
+
+// High-rate servers can stop allocating a result map per payload in two
+// ways: decode into a map they own with WithResult, or take a pooled
+// Result from DecodePooled and Release it when done.
+//
+// Ownership: only the top-level map is reused. Values nested in it
+// (objects, arrays, _quality) are allocated per decode as usual, and stay
+// valid after the map is reused. The map itself, and any reference to it,
+// must not be used once it is passed to another decode or released; copy
+// what needs to outlive that. With an output style (key_style, namespace,
+// flat output) the styled result is a new map and the reuse is lost.
+
+// WithResult decodes into m instead of a new map. m is cleared first, and
+// is the map Decode returns unless an output style replaces it.
+func WithResult(m map[string]any) DecodeOption {
+	return func(c *decodeConfig) {
+		c.result = m
+	}
+}
+
+// Result is a decode result whose map comes from a pool shared by all
+// schemas.
+type Result struct {
+	Values map[string]any
+}
+
+var resultPool = sync.Pool{
+	New: func() any { return make(map[string]any) },
+}
+
+// DecodePooled is Decode into a map taken from the pool. Call Release when
+// done with Values; on error the map goes back to the pool at once.
+func (s *Schema) DecodePooled(data []byte, opts ...DecodeOption) (Result, error) {
+	m := resultPool.Get().(map[string]any)
+	// Full slice expression: never append into the caller's backing array
+	values, err := s.Decode(data, append(opts[:len(opts):len(opts)], WithResult(m))...)
+	if err != nil {
+		clear(m)
+		resultPool.Put(m)
+		return Result{}, err
+	}
+	return Result{Values: values}, nil
+}
+
+// Release clears Values and returns the map to the pool. Releasing the
+// same Result twice is a no-op, but a copy of it must not be released as
+// well.
+func (r *Result) Release() {
+	if r.Values == nil {
+		return
+	}
+	clear(r.Values)
+	resultPool.Put(r.Values)
+	r.Values = nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/hex"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithResult(t *testing.T) {
+	s, err := ParseSchema(dl5tmSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload, _ := hex.DecodeString(testPayloadHex)
+	want, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	m := map[string]any{"stale": true}
+	got, err := s.Decode(payload, WithResult(m))
+	if err != nil {
+		t.Fatalf("Decode(WithResult) error = %v", err)
+	}
+	if reflect.ValueOf(got).Pointer() != reflect.ValueOf(m).Pointer() {
+		t.Error("Decode(WithResult) returned a new map")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode(WithResult) = %v, want %v", got, want)
+	}
+}
+
+func TestDecodePooled(t *testing.T) {
+	s, err := ParseSchema(dl5tmSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload, _ := hex.DecodeString(testPayloadHex)
+	want, _ := s.Decode(payload)
+
+	for i := 0; i < 3; i++ {
+		r, err := s.DecodePooled(payload)
+		if err != nil {
+			t.Fatalf("DecodePooled() error = %v", err)
+		}
+		if !reflect.DeepEqual(r.Values, want) {
+			t.Errorf("DecodePooled() = %v, want %v", r.Values, want)
+		}
+		r.Release()
+		r.Release()
+		if r.Values != nil {
+			t.Error("Values not cleared by Release")
+		}
+	}
+
+	if _, err := s.DecodePooled(payload[:2]); err == nil {
+		t.Error("DecodePooled(short): no error")
+	}
+}
+
+func TestDecodePooledSharedOptions(t *testing.T) {
+	s, err := ParseSchema(dl5tmSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload, _ := hex.DecodeString(testPayloadHex)
+	want, _ := s.Decode(payload)
+
+	// Spare capacity must not be written, or concurrent calls race on it
+	opts := make([]DecodeOption, 1, 2)
+	opts[0] = WithReceivedAt(time.Unix(0, 0))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := s.DecodePooled(payload, opts...)
+			if err != nil {
+				t.Errorf("DecodePooled() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(r.Values, want) {
+				t.Errorf("DecodePooled() = %v, want %v", r.Values, want)
+			}
+			r.Release()
+		}()
+	}
+	wg.Wait()
+	if opts[:2][1] != nil {
+		t.Error("DecodePooled wrote into the caller's options")
+	}
+}
+
+func BenchmarkDecodePooled(b *testing.B) {
+	s, err := ParseSchema(dl5tmSchema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	payload, _ := hex.DecodeString(testPayloadHex)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := s.DecodePooled(payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+		r.Release()
+	}
+}
+
+func BenchmarkDecodeWithResult(b *testing.B) {
+	s, err := ParseSchema(dl5tmSchema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	payload, _ := hex.DecodeString(testPayloadHex)
+	m := make(map[string]any)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Decode(payload, WithResult(m)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EncodeAuto encodes a downlink without the caller naming the port. The
+// port is chosen among downlink and bidirectional ports as the one whose
+// fields accept every key in values and whose required fields are all
+// present. It returns the encoded payload and the selected fPort; no match,
+// or more than one, is an error.
+func (s *Schema) EncodeAuto(values map[string]any) ([]byte, int, error) {
+	if len(s.Ports) == 0 {
+		return nil, 0, fmt.Errorf("schema '%s' has no ports to select from", s.Name)
+	}
+
+	var matches []int
+	for _, port := range s.portNumbers() {
+		pd := s.portDef(port, PortDown)
+		if pd == nil || !isDownlinkPort(pd) {
+			continue
+		}
+		if portAccepts(pd.Fields, s.portHeader(pd), values, s.Definitions) {
+			matches = append(matches, port)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, 0, fmt.Errorf("schema '%s': no downlink port accepts keys %s",
+			s.Name, strings.Join(sortedKeys(values), ", "))
+	case 1:
+		out, err := s.EncodeWithPort(values, matches[0])
+		return out, matches[0], err
+	default:
+		return nil, 0, fmt.Errorf("schema '%s': keys %s match several downlink ports %v",
+			s.Name, strings.Join(sortedKeys(values), ", "), matches)
+	}
+}
+
+func isDownlinkPort(pd *PortDef) bool {
+	return pd.Direction == "downlink" || pd.Direction == "bidirectional"
+}
+
+// portAccepts reports whether every key in values is encoded by fields (or
+// the schema header) and every required field has a value.
+func portAccepts(fields, header []Field, values map[string]any, defs map[string]*DefinitionDef) bool {
+	known := map[string]bool{}
+	vars := map[string]any{}
+	var required []Field
+	for _, list := range [][]Field{header, fields} {
+		collectEncodeNames(list, values, vars, defs, known, &required, 0)
+	}
+	present := 0
+	for key, v := range values {
+		if v == Omit {
+			continue
+		}
+		if !known[key] {
+			return false
+		}
+		present++
+	}
+	for _, f := range required {
+		if _, ok := lookupEncodeValue(f, values); !ok {
+			return false
+		}
+	}
+	return present > 0
+}
+
+// collectEncodeNames gathers the input keys read when encoding fields from
+// values, and the fields that must have a value. vars collects the values
+// of var: fields, which select match cases as they do on encode.
+func collectEncodeNames(fields []Field, values, vars map[string]any, defs map[string]*DefinitionDef, known map[string]bool, required *[]Field, depth int) {
+	for _, f := range fields {
+		if f.Ref2 != "" {
+			if def, err := lookupDefinition(f.Ref2, defs); err == nil && depth < maxRefDepth {
+				collectEncodeNames(def.Fields, values, vars, defs, known, required, depth+1)
+			}
+			continue
+		}
+		if f.Flagged != nil {
+			// Groups are optional as a whole, so their fields never are
+			for _, g := range f.Flagged.Groups {
+				collectEncodeNames(g.Fields, values, vars, defs, known, new([]Field), depth)
+			}
+			continue
+		}
+		collectEncodeNames(f.ByteGroup, values, vars, defs, known, required, depth)
+		if tlv := f.TLVInline; tlv != nil || f.Type == TypeTLV || f.Type == TypeTLVLower {
+			if tlv == nil {
+				tlv = &f
+			}
+			// Records are optional, like flagged groups
+			known[TLVChannelsKey] = true
+			for _, fields := range tlv.TLVCases {
+				collectEncodeNames(fields, values, vars, defs, known, new([]Field), depth)
+			}
+			continue
+		}
+		isMatch := f.Type == TypeMatch || f.Type == "CTRL-SWITCH" || f.Type == "Switch"
+		if m := f.MatchInline; m != nil || isMatch && f.Name == "" {
+			if m == nil {
+				m = &f
+			}
+			// Every case's keys are accepted; only the selected case's
+			// fields are required
+			chosen, _, _ := chooseEncodeCase(*m, vars, values, values, defs)
+			for i := range m.Cases {
+				caseRequired := new([]Field)
+				if &m.Cases[i] == chosen {
+					caseRequired = required
+				}
+				collectEncodeNames(m.Cases[i].Fields, values, vars, defs, known, caseRequired, depth)
+			}
+			continue
+		}
+		if f.Name == "" || strings.HasPrefix(f.Name, "_") {
+			continue
+		}
+		if v, ok := lookupEncodeValue(f, values); ok && f.Var != "" {
+			vars[f.Var] = v
+		}
+		known[f.Name] = true
+		for _, alias := range f.Aliases {
+			known[alias] = true
+		}
+		for _, ae := range f.AlsoEmit {
+			// Decoded results re-encode as they are
+			known[ae.Name] = true
+		}
+		if f.Required {
+			*required = append(*required, f)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Direction suffixes of ports: keys. An fPort whose uplink and downlink
+// layouts differ has two entries, "1:up" and "1:down" (or, equivalently,
+// uplink: and downlink: maps under "1"). Decoding uses "N:up", encoding
+// "N:down", each falling back to "N" and then "default".
+const (
+	PortUp   = "up"
+	PortDown = "down"
+)
+
+// addPortDef stores one ports: entry, splitting one with uplink: and
+// downlink: maps into direction-qualified entries.
+func addPortDef(ports map[string]*PortDef, key string, portMap map[string]any) {
+	up, hasUp := portMap["uplink"].(map[string]any)
+	down, hasDown := portMap["downlink"].(map[string]any)
+	if !hasUp && !hasDown {
+		pd := parsePortDef(portMap)
+		if _, dir, err := parsePortKey(key); err == nil && pd.Direction == "" {
+			switch dir {
+			case PortUp:
+				pd.Direction = "uplink"
+			case PortDown:
+				pd.Direction = "downlink"
+			}
+		}
+		ports[key] = pd
+		return
+	}
+	if hasUp {
+		addPortDef(ports, key+":"+PortUp, up)
+	}
+	if hasDown {
+		addPortDef(ports, key+":"+PortDown, down)
+	}
+}
+
+// parsePortKey splits a ports: key into its fPort and direction suffix.
+// The default entry is port -1.
+func parsePortKey(key string) (int, string, error) {
+	if key == "default" {
+		return -1, "", nil
+	}
+	num, dir, _ := strings.Cut(key, ":")
+	port, err := strconv.Atoi(num)
+	if err != nil {
+		return 0, "", fmt.Errorf("port %q is not a number", key)
+	}
+	if dir != "" && dir != PortUp && dir != PortDown {
+		return 0, "", fmt.Errorf("port %q: direction must be %s or %s", key, PortUp, PortDown)
+	}
+	return port, dir, nil
+}
+
+// portDef returns the entry for fPort in direction dir ("N:dir", then
+// "N"), or nil. The default entry is not considered.
+func (s *Schema) portDef(fPort int, dir string) *PortDef {
+	key := strconv.Itoa(fPort)
+	if pd, ok := s.Ports[key+":"+dir]; ok {
+		return pd
+	}
+	return s.Ports[key]
+}
+
+// resolvePort returns the entry for fPort in direction dir, else the
+// default entry. It is nil for a schema without ports.
+func (s *Schema) resolvePort(fPort int, dir string) (*PortDef, error) {
+	if s.Ports == nil {
+		return nil, nil
+	}
+	if pd := s.portDef(fPort, dir); pd != nil {
+		return pd, nil
+	}
+	if pd, ok := s.Ports["default"]; ok {
+		return pd, nil
+	}
+	return nil, fmt.Errorf("no port definition for fPort %d and no default in schema '%s'", fPort, s.Name)
+}
+
+// portChain returns the entry for fPort in direction dir followed by the
+// entries named by its fallback: chain, tried in turn when decoding fails.
+// A schema without ports has one entry holding its top-level fields. A
+// chain stops at an unknown key or at an entry already in it.
+func (s *Schema) portChain(fPort int, dir string) ([]*PortDef, error) {
+	pd, err := s.resolvePort(fPort, dir)
+	if err != nil {
+		return nil, err
+	}
+	if pd == nil {
+		return []*PortDef{{Fields: s.topFields()}}, nil
+	}
+	chain := []*PortDef{pd}
+	for pd.Fallback != "" {
+		if pd = s.fallbackPort(pd.Fallback, dir); pd == nil || slices.Contains(chain, pd) {
+			break
+		}
+		chain = append(chain, pd)
+	}
+	return chain, nil
+}
+
+// fallbackPort returns the entry a fallback: key names, "default" or an
+// fPort, or nil.
+func (s *Schema) fallbackPort(key, dir string) *PortDef {
+	if key == "default" {
+		return s.Ports["default"]
+	}
+	port, err := strconv.Atoi(key)
+	if err != nil {
+		return nil
+	}
+	return s.portDef(port, dir)
+}
+
+// resolvePortFields returns the fields for fPort in direction dir.
+func (s *Schema) resolvePortFields(fPort int, dir string) ([]Field, error) {
+	pd, err := s.resolvePort(fPort, dir)
+	if err != nil {
+		return nil, err
+	}
+	if pd == nil {
+		return s.topFields(), nil
+	}
+	return pd.Fields, nil
+}
+
+// portHeader returns the header used with port entry pd, which may be nil.
+func (s *Schema) portHeader(pd *PortDef) []Field {
+	if pd != nil && pd.Header != nil {
+		return pd.Header
+	}
+	return s.Header
+}
+
+// portNumbers returns the distinct fPorts with entries, in order.
+func (s *Schema) portNumbers() []int {
+	seen := map[int]bool{}
+	var ports []int
+	for key := range s.Ports {
+		if port, _, err := parsePortKey(key); err == nil && port >= 0 && !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// checkPortKeys reports ports: keys that are not numbers, "default" or a
+// number with a direction suffix, and suffixes contradicting direction:.
+func (s *Schema) checkPortKeys() []string {
+	var warnings []string
+	for _, key := range sortedKeys(s.Ports) {
+		_, dir, err := parsePortKey(key)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		if d := s.Ports[key].Direction; dir == PortUp && d == "downlink" || dir == PortDown && d == "uplink" {
+			warnings = append(warnings, fmt.Sprintf("port %q: direction %s contradicts the key", key, d))
+		}
+		if fb := s.Ports[key].Fallback; fb != "" && s.fallbackPort(fb, PortUp) == nil {
+			warnings = append(warnings, fmt.Sprintf("port %q: fallback %q has no port entry", key, fb))
+		}
+	}
+	return warnings
+}
+
+// PortInfo describes one entry of the ports: map, for tools and UIs that
+// list the uplink and downlink formats a device speaks.
+type PortInfo struct {
+	Key         string // ports: key, e.g. "1", "1:down" or "default"
+	Port        int    // fPort; 0 for the default entry
+	Default     bool   // The `default` entry, used for unlisted ports
+	Direction   string // uplink, downlink or bidirectional
+	Description string
+	MinSize     int
+	MaxSize     int           // Unbounded if not limited
+	Fields      []LayoutEntry // Header and port fields in payload order
+}
+
+// Uplink reports whether the port carries uplinks.
+func (p PortInfo) Uplink() bool {
+	return p.Direction != "downlink"
+}
+
+// Downlink reports whether the port accepts downlinks.
+func (p PortInfo) Downlink() bool {
+	return p.Direction == "downlink" || p.Direction == "bidirectional"
+}
+
+// PortList returns the schema's ports in fPort order, with the default
+// entry last. A schema without ports: returns an empty list.
+func (s *Schema) PortList() ([]PortInfo, error) {
+	var ports []PortInfo
+	for key, pd := range s.Ports {
+		info := PortInfo{Direction: pd.Direction, Description: pd.Description}
+		if info.Direction == "" {
+			info.Direction = "uplink"
+		}
+		port, _, err := parsePortKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("schema '%s': %w", s.Name, err)
+		}
+		info.Key = key
+		if port < 0 {
+			info.Default = true
+		} else {
+			info.Port = port
+		}
+		layout, err := s.layoutFields(s.portHeader(pd), pd.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("port %s: %w", key, err)
+		}
+		info.MinSize, info.MaxSize, info.Fields = layout.MinSize, layout.MaxSize, layout.Entries
+		ports = append(ports, info)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Default != ports[j].Default {
+			return ports[j].Default
+		}
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Key < ports[j].Key
+	})
+	return ports, nil
+}
+
+// Port returns the port used to decode fPort: its own entry ("N:up"
+// before "N"), else the default.
+func (s *Schema) Port(fPort int) (PortInfo, bool, error) {
+	ports, err := s.PortList()
+	if err != nil {
+		return PortInfo{}, false, err
+	}
+	want := s.portDef(fPort, PortUp)
+	for _, p := range ports {
+		if !p.Default && want != nil && s.Ports[p.Key] == want {
+			return p, true, nil
+		}
+	}
+	if n := len(ports); n > 0 && ports[n-1].Default {
+		return ports[n-1], true, nil
+	}
+	return PortInfo{}, false, nil
+}
@@ -0,0 +1,467 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const autoPortSchema = `
+name: controller
+ports:
+  1:
+    direction: uplink
+    fields:
+      - name: interval
+        type: u16
+  10:
+    direction: downlink
+    fields:
+      - name: interval
+        type: u16
+        required: true
+  11:
+    direction: downlink
+    fields:
+      - name: relay
+        type: u8
+      - name: duration
+        type: u16
+  12:
+    direction: bidirectional
+    fields:
+      - name: relay
+        type: u8
+      - name: mode
+        type: u8
+`
+
+func TestEncodeAuto(t *testing.T) {
+	s, err := ParseSchema(autoPortSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]any
+		port   int
+		want   []byte
+		errMsg string
+	}{
+		{"single downlink", map[string]any{"interval": 300.0}, 10, []byte{0x01, 0x2C}, ""},
+		{"disambiguated by key", map[string]any{"relay": 1.0, "duration": 5.0}, 11, []byte{0x01, 0x00, 0x05}, ""},
+		{"bidirectional port", map[string]any{"relay": 1.0, "mode": 2.0}, 12, []byte{0x01, 0x02}, ""},
+		{"ambiguous", map[string]any{"relay": 1.0}, 0, nil, "several downlink ports [11 12]"},
+		{"unknown key", map[string]any{"relay": 1.0, "color": "red"}, 0, nil, "no downlink port"},
+		{"empty", map[string]any{}, 0, nil, "no downlink port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, port, err := s.EncodeAuto(tt.values)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("EncodeAuto() error = %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncodeAuto() error = %v", err)
+			}
+			if port != tt.port || !bytes.Equal(got, tt.want) {
+				t.Errorf("EncodeAuto() = %X on port %d, want %X on port %d", got, port, tt.want, tt.port)
+			}
+		})
+	}
+}
+
+func TestEncodeAutoRequiredFields(t *testing.T) {
+	s, err := ParseSchema(`
+name: cfg
+ports:
+  20:
+    direction: downlink
+    fields:
+      - {name: channel, type: u8, required: true}
+      - {name: level, type: u8}
+  21:
+    direction: downlink
+    fields:
+      - {name: level, type: u8}
+      - {name: ramp, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// Port 20 would accept level but needs channel, so only 21 matches
+	_, port, err := s.EncodeAuto(map[string]any{"level": 5.0})
+	if err != nil || port != 21 {
+		t.Errorf("EncodeAuto() port = %d, err = %v; want 21", port, err)
+	}
+}
+
+func TestEncodeAutoMatchCases(t *testing.T) {
+	s, err := ParseSchema(`
+name: cmds
+ports:
+  30:
+    direction: downlink
+    fields:
+      - {name: cmd, type: u8, var: cmd}
+      - match:
+          field: $cmd
+          cases:
+            1:
+              - {name: interval, type: u16}
+            2:
+              - {name: relay, type: u8, required: true}
+  31:
+    direction: downlink
+    fields:
+      - {name: reboot, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	got, port, err := s.EncodeAuto(map[string]any{"cmd": 1.0, "interval": 60.0})
+	if err != nil {
+		t.Fatalf("EncodeAuto() error = %v", err)
+	}
+	if want := []byte{0x01, 0x00, 0x3C}; port != 30 || !bytes.Equal(got, want) {
+		t.Errorf("EncodeAuto() = %X on port %d, want %X on port 30", got, port, want)
+	}
+
+	// relay is required only when cmd selects case 2
+	if _, _, err := s.EncodeAuto(map[string]any{"cmd": 2.0}); err == nil || !strings.Contains(err.Error(), "no downlink port") {
+		t.Errorf("EncodeAuto(cmd 2) error = %v, want no downlink port", err)
+	}
+}
+
+func TestPortList(t *testing.T) {
+	s, err := ParseSchema(autoPortSchema + `
+  default:
+    description: Fallback
+    fields:
+      - name: raw
+        type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	ports, err := s.PortList()
+	if err != nil {
+		t.Fatalf("PortList() error = %v", err)
+	}
+	var got []string
+	for _, p := range ports {
+		got = append(got, fmt.Sprintf("%d/%v/%s/%d", p.Port, p.Default, p.Direction, p.MinSize))
+	}
+	want := "1/false/uplink/2 10/false/downlink/2 11/false/downlink/3 12/false/bidirectional/2 0/true/uplink/1"
+	if strings.Join(got, " ") != want {
+		t.Errorf("PortList() = %v, want %s", got, want)
+	}
+	if ports[2].Fields[1].Path != "duration" || ports[2].Fields[1].MinOffset != 1 {
+		t.Errorf("port 11 fields = %+v", ports[2].Fields)
+	}
+	if !ports[3].Uplink() || !ports[3].Downlink() || ports[0].Downlink() {
+		t.Error("Uplink()/Downlink() wrong")
+	}
+
+	p, ok, err := s.Port(99)
+	if err != nil || !ok || !p.Default || p.Description != "Fallback" {
+		t.Errorf("Port(99) = %+v, %v, %v", p, ok, err)
+	}
+}
+
+func TestDirectionPorts(t *testing.T) {
+	for _, src := range []string{`
+name: valve
+ports:
+  "1:up":
+    fields:
+      - {name: position, type: u8}
+      - {name: battery, type: u8}
+  "1:down":
+    fields:
+      - {name: target, type: u16}
+  2:
+    fields:
+      - {name: status, type: u8}
+`, `
+name: valve
+ports:
+  1:
+    uplink:
+      fields:
+        - {name: position, type: u8}
+        - {name: battery, type: u8}
+    downlink:
+      fields:
+        - {name: target, type: u16}
+  2:
+    fields:
+      - {name: status, type: u8}
+`} {
+		s, err := ParseSchema(src)
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) > 0 {
+			t.Fatalf("Warnings = %v", s.Warnings)
+		}
+
+		got, err := s.DecodeWithPort([]byte{40, 90}, 1)
+		if err != nil || got["position"] != 40.0 || got["battery"] != 90.0 {
+			t.Errorf("DecodeWithPort(1) = %v, %v", got, err)
+		}
+		out, err := s.EncodeWithPort(map[string]any{"target": 300}, 1)
+		if err != nil || !bytes.Equal(out, []byte{0x01, 0x2C}) {
+			t.Errorf("EncodeWithPort(1) = % X, %v", out, err)
+		}
+		// Undirected ports serve both
+		if out, _ := s.EncodeWithPort(map[string]any{"status": 3}, 2); !bytes.Equal(out, []byte{3}) {
+			t.Errorf("EncodeWithPort(2) = % X", out)
+		}
+
+		// EncodeAuto only considers downlink entries
+		out, port, err := s.EncodeAuto(map[string]any{"target": 1})
+		if err != nil || port != 1 || !bytes.Equal(out, []byte{0x00, 0x01}) {
+			t.Errorf("EncodeAuto() = % X, %d, %v", out, port, err)
+		}
+
+		ports, err := s.PortList()
+		if err != nil {
+			t.Fatalf("PortList() error = %v", err)
+		}
+		var keys []string
+		for _, p := range ports {
+			keys = append(keys, p.Key+"/"+p.Direction)
+		}
+		if strings.Join(keys, " ") != "1:down/downlink 1:up/uplink 2/uplink" {
+			t.Errorf("PortList() keys = %v", keys)
+		}
+		if p, ok, _ := s.Port(1); !ok || p.Key != "1:up" {
+			t.Errorf("Port(1) = %+v", p)
+		}
+		if py, err := s.ExportPython(); err != nil || !strings.Contains(py, "    1: _decode_port_1_up,\n") ||
+			strings.Contains(py, "1_down") {
+			t.Errorf("ExportPython() error = %v, ports:\n%s", err, py[strings.Index(py, "_PORTS"):])
+		}
+	}
+}
+
+func TestPortKeyWarnings(t *testing.T) {
+	s, err := ParseSchema(`
+name: t
+ports:
+  "1:sideways":
+    fields: [{name: a, type: u8}]
+  "2:up":
+    direction: downlink
+    fields: [{name: b, type: u8}]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	joined := strings.Join(s.Warnings, "; ")
+	if !strings.Contains(joined, `"1:sideways": direction must be up or down`) ||
+		!strings.Contains(joined, `"2:up": direction downlink contradicts the key`) {
+		t.Errorf("Warnings = %v", s.Warnings)
+	}
+}
+
+const headerPortSchema = `
+name: headed
+header:
+  - {name: version, type: u8, add: 0}
+ports:
+  1:
+    fields:
+      - {name: temperature, type: s16}
+  2:
+    header:
+      - {name: version, type: u8}
+      - {name: sequence, type: u16}
+    fields:
+      - {name: battery, type: u8}
+  3:
+    header: []
+    fields:
+      - {name: reboot, type: u8}
+`
+
+func TestSchemaHeader(t *testing.T) {
+	s, err := ParseSchema(headerPortSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	tests := []struct {
+		port    int
+		payload []byte
+		want    map[string]any
+	}{
+		{1, []byte{0x02, 0xFF, 0x38}, map[string]any{"version": 2.0, "temperature": -200.0}},
+		{2, []byte{0x02, 0x00, 0x07, 0x5A}, map[string]any{"version": 2.0, "sequence": 7.0, "battery": 90.0}},
+		{3, []byte{0x01}, map[string]any{"reboot": 1.0}},
+	}
+	for _, tt := range tests {
+		got, err := s.DecodeWithPort(tt.payload, tt.port)
+		if err != nil {
+			t.Fatalf("port %d: DecodeWithPort() error = %v", tt.port, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("port %d: DecodeWithPort() = %v, want %v", tt.port, got, tt.want)
+		}
+		// Encoding writes the header the port decodes
+		out, err := s.EncodeWithPort(got, tt.port)
+		if err != nil {
+			t.Fatalf("port %d: EncodeWithPort() error = %v", tt.port, err)
+		}
+		if !bytes.Equal(out, tt.payload) {
+			t.Errorf("port %d: EncodeWithPort() = % X, want % X", tt.port, out, tt.payload)
+		}
+	}
+
+	if _, err := s.EncodeWithPort(map[string]any{"version": 2}, 9); err == nil {
+		t.Error("EncodeWithPort(9) should fail: no port entry and no default")
+	}
+	if h := s.ResolveHeader(2); len(h) != 2 || h[1].Name != "sequence" {
+		t.Errorf("ResolveHeader(2) = %v", h)
+	}
+	if l, err := s.Layout(1); err != nil || l.MinSize != 3 {
+		t.Errorf("Layout(1) = %+v, %v; want 3 bytes with the header", l, err)
+	}
+}
+
+func TestSchemaHeaderWithoutPorts(t *testing.T) {
+	s, err := ParseSchema(`
+name: flat
+header:
+  - {name: version, type: u8}
+fields:
+  - {name: humidity, type: u8, div: 2}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	got, err := s.Decode([]byte{0x03, 0x64})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["version"] != 3.0 || got["humidity"] != 50.0 {
+		t.Errorf("Decode() = %v", got)
+	}
+	out, err := s.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := []byte{0x03, 0x64}; !bytes.Equal(out, want) {
+		t.Errorf("Encode() = % X, want % X", out, want)
+	}
+}
+
+const fallbackPortSchema = `
+name: fallback
+ports:
+  5:
+    fields:
+      - {name: counter, type: u32}
+    fallback: 10
+  10:
+    fields:
+      - {name: extended, type: u16}
+    fallback: default
+  20:
+    fields:
+      - {name: a, type: u32}
+    fallback: 21
+  21:
+    fields:
+      - {name: b, type: u32}
+    fallback: 20
+  default:
+    fields:
+      - {name: raw, type: u8}
+`
+
+func TestPortFallback(t *testing.T) {
+	s, err := ParseSchema(fallbackPortSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Errorf("Warnings = %v", s.Warnings)
+	}
+	tests := []struct {
+		payload   []byte
+		key       string
+		fallbacks int
+	}{
+		{[]byte{0, 0, 0, 7}, "counter", 0},
+		{[]byte{0, 7}, "extended", 1},
+		{[]byte{7}, "raw", 2},
+	}
+	for _, tt := range tests {
+		var info DecodeInfo
+		got, err := s.Decode(tt.payload, WithPort(5), WithInfo(&info))
+		if err != nil {
+			t.Fatalf("Decode(%X) error = %v", tt.payload, err)
+		}
+		if got[tt.key] != 7.0 || len(got) != 1 || info.Fallbacks != tt.fallbacks {
+			t.Errorf("Decode(%X) = %v, %d fallbacks, want %s after %d", tt.payload, got, info.Fallbacks, tt.key, tt.fallbacks)
+		}
+	}
+
+	// Strict decoding falls back on trailing bytes too
+	got, err := s.Decode([]byte{0, 0, 0, 7}, WithPort(10), WithStrict())
+	if err == nil {
+		t.Errorf("strict Decode() = %v, want error", got)
+	}
+
+	// The chain can fail as a whole; a loop stops
+	if got, err := s.DecodeWithPort(nil, 5); err == nil {
+		t.Errorf("DecodeWithPort(nil, 5) = %v, want error", got)
+	}
+	if got, err := s.DecodeWithPort([]byte{1}, 20); err == nil {
+		t.Errorf("DecodeWithPort(loop) = %v, want error", got)
+	}
+
+	r, err := s.DecodeLazy([]byte{0, 7}, 5)
+	if err != nil {
+		t.Fatalf("DecodeLazy() error = %v", err)
+	}
+	if v, _, _ := r.Get("extended"); v != 7.0 {
+		t.Errorf("DecodeLazy() extended = %v", v)
+	}
+}
+
+func TestPortFallbackWarnings(t *testing.T) {
+	s, err := ParseSchema(`
+name: t
+ports:
+  1:
+    fields: [{name: a, type: u8}]
+    fallback: 9
+  2:
+    fields: [{name: b, type: u8}]
+    fallback: default
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	joined := strings.Join(s.Warnings, "; ")
+	if !strings.Contains(joined, `port "1": fallback "9" has no port entry`) ||
+		!strings.Contains(joined, `port "2": fallback "default"`) {
+		t.Errorf("Warnings = %v", s.Warnings)
+	}
+}
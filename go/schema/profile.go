@@ -0,0 +1,59 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"path"
+	"strings"
+)
+
+// A decode profile (DecodeLimits.Fields and Omit) limits the top-level
+// keys a decode returns. Patterns use path.Match syntax, e.g. "temp*".
+// Fields outside the profile that read bytes are still read, since later
+// fields depend on their position and variables; computed number fields
+// outside it are skipped without being evaluated, so a computed field that
+// another one references must stay in the profile.
+
+// wants reports whether the profile keeps the top-level key name.
+func (o DecodeLimits) wants(name string) bool {
+	if len(o.Fields) > 0 && !matchAny(o.Fields, name) {
+		return false
+	}
+	return !matchAny(o.Omit, name)
+}
+
+func (o DecodeLimits) hasProfile() bool {
+	return len(o.Fields) > 0 || len(o.Omit) > 0
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skipComputed reports whether field is a computed value the profile
+// leaves out, so that decoding can skip evaluating it.
+func (ctx *DecodeContext) skipComputed(field Field) bool {
+	return ctx.nested == 0 && ctx.Limits.hasProfile() && field.Name != "" &&
+		(field.Type == TypeNumber || field.Type == "number") && !ctx.Limits.wants(field.Name)
+}
+
+// applyProfile drops the top-level keys outside the profile. Metadata
+// keys (_quality, _raw) are kept; _quality loses entries for dropped keys.
+func (o DecodeLimits) applyProfile(result map[string]any, quality map[string]string) {
+	if !o.hasProfile() {
+		return
+	}
+	for k := range result {
+		if !strings.HasPrefix(k, "_") && !o.wants(k) {
+			delete(result, k)
+			delete(quality, k)
+		}
+	}
+}
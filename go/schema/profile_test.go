@@ -0,0 +1,91 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+const profileSchema = `
+name: meter
+fields:
+  - name: raw_level
+    type: u16
+    valid_range: [0, 10]
+  - name: level_cm
+    type: number
+    ref: $raw_level
+    polynomial: [0.5, 2]
+  - name: temp_in
+    type: s8
+  - name: temp_out
+    type: s8
+  - name: samples
+    type: repeat
+    count: 2
+    fields:
+      - name: v
+        type: u8
+      - name: scaled
+        type: number
+        ref: $v
+        polynomial: [1, 0]
+`
+
+func TestDecodeProfile(t *testing.T) {
+	s, err := ParseSchema(profileSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x00, 0x14, 0x05, 0xFB, 0x01, 0x02}
+
+	tests := []struct {
+		name         string
+		fields, omit []string
+		want         map[string]any
+	}{
+		{"whitelist", []string{"level_cm", "temp_*"}, nil,
+			map[string]any{"level_cm": 12.0, "temp_in": 5.0, "temp_out": -5.0}},
+		{"omit", nil, []string{"level_cm", "temp_out", "raw_level"},
+			map[string]any{"temp_in": 5.0, "samples": []any{
+				map[string]any{"v": 1.0, "scaled": 1.0},
+				map[string]any{"v": 2.0, "scaled": 2.0},
+			}}},
+		{"both", []string{"temp_*", "raw_level"}, []string{"temp_in"},
+			map[string]any{"temp_out": -5.0, "raw_level": 20.0, "_quality": map[string]string{"raw_level": "out_of_range"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.DecodeLimits = DecodeLimits{Fields: tt.fields, Omit: tt.omit}
+			got, err := s.Decode(payload)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeProfileSkipsComputed(t *testing.T) {
+	s, err := ParseSchema(profileSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	s.DecodeLimits.Omit = []string{"level_cm"}
+	ctx := NewDecodeContext([]byte{0x00, 0x14, 0x05, 0xFB, 0x01, 0x02}, s.Endian)
+	ctx.Limits = s.DecodeLimits
+	if _, err := decodeFieldsWithSchema(s.Fields, ctx, s); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if _, evaluated := ctx.Variables["level_cm"]; evaluated {
+		t.Error("omitted computed field was evaluated")
+	}
+	if ctx.Variables["raw_level"] != 20.0 {
+		t.Errorf("raw_level = %v, want 20", ctx.Variables["raw_level"])
+	}
+}
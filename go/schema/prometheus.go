@@ -0,0 +1,302 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PromMetricFamily is one Prometheus metric family: all samples sharing a
+// name. Families are plain data so callers can hand them to
+// client_golang collectors or write them with WritePromText.
+type PromMetricFamily struct {
+	Name    string
+	Help    string
+	Type    string // Always "gauge": decoded payloads carry point readings
+	Metrics []PromMetric
+}
+
+// PromMetric is a single labelled sample.
+type PromMetric struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// PromQualitySuffix names the family that carries the _quality map.
+const PromQualitySuffix = "quality"
+
+// promUnit is the Prometheus base-unit suffix for a schema unit and the
+// factor that converts a value into that base unit.
+type promUnit struct {
+	suffix string
+	scale  float64
+}
+
+// promUnits maps the unit strings found in schemas (`unit:` or
+// `senml_unit:`) to Prometheus base units.
+var promUnits = map[string]promUnit{
+	"°C": {"celsius", 1}, "Cel": {"celsius", 1}, "C": {"celsius", 1},
+	"%": {"percent", 1}, "%RH": {"percent", 1},
+	"V": {"volts", 1}, "mV": {"volts", 0.001},
+	"A": {"amperes", 1}, "mA": {"amperes", 0.001},
+	"W": {"watts", 1}, "kW": {"watts", 1000},
+	"Wh": {"joules", 3600}, "kWh": {"joules", 3.6e6}, "J": {"joules", 1},
+	"s": {"seconds", 1}, "ms": {"seconds", 0.001}, "min": {"seconds", 60}, "h": {"seconds", 3600},
+	"m": {"meters", 1}, "cm": {"meters", 0.01}, "mm": {"meters", 0.001}, "km": {"meters", 1000},
+	"Pa": {"pascals", 1}, "hPa": {"pascals", 100}, "kPa": {"pascals", 1000}, "mbar": {"pascals", 100}, "bar": {"pascals", 1e5},
+	"Hz": {"hertz", 1}, "lx": {"lux", 1}, "lux": {"lux", 1},
+	"ppm": {"ppm", 1}, "ppb": {"ppb", 1}, "dB": {"decibels", 1}, "dBm": {"dbm", 1},
+	"m3": {"cubic_meters", 1}, "m³": {"cubic_meters", 1}, "L": {"cubic_meters", 0.001}, "l": {"cubic_meters", 0.001},
+	"g": {"grams", 1}, "kg": {"grams", 1000},
+	"B": {"bytes", 1}, "byte": {"bytes", 1},
+}
+
+// ToPromMetrics converts the numeric values of a decoded result into
+// Prometheus gauge families named <schema>_<field path>[_<unit>]. Values
+// are scaled to the Prometheus base unit of their `unit:` (mV becomes
+// volts, hPa pascals). Fields with role: tag become labels on the samples
+// of their object (or, at top level, on every sample), repeat elements are
+// told apart by an index label, booleans are 0/1 and other strings are
+// skipped. The _quality map becomes a separate <schema>_quality family
+// with one sample per field: 1 when good, 0 otherwise. labels are added
+// to every sample.
+func (s *Schema) ToPromMetrics(result map[string]any, labels map[string]string) ([]PromMetricFamily, error) {
+	return s.ToPromMetricsPort(result, 0, labels)
+}
+
+// ToPromMetricsPort is ToPromMetrics for a result decoded on fPort.
+func (s *Schema) ToPromMetricsPort(result map[string]any, fPort int, labels map[string]string) ([]PromMetricFamily, error) {
+	roles := map[string]string{}
+	units := map[string]string{}
+	err := s.walkOutputPaths(fPort, func(f Field, path string) {
+		if f.Role != "" {
+			roles[path] = f.Role
+		}
+		if u := fieldUnit(f); u != "" {
+			units[path] = u
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	prefix := promName(s.Name)
+
+	flat := Flatten(result)
+	// Tag values, keyed by the object they label ("" is the top level)
+	tags := map[string]map[string]string{}
+	for key, v := range flat {
+		if roles[s.rolePath(key)] != RoleTag || v == nil {
+			continue
+		}
+		scope, name := splitLastDot(key)
+		if tags[scope] == nil {
+			tags[scope] = map[string]string{}
+		}
+		tags[scope][promName(name)] = tagValue(v)
+	}
+
+	families := map[string]*PromMetricFamily{}
+	for key, v := range flat {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		path := s.rolePath(key)
+		if roles[path] == RoleTag || roles[path] == RoleTimestamp {
+			continue
+		}
+		value, ok := promValue(v)
+		if !ok {
+			continue
+		}
+		name := prefix + "_" + promName(path)
+		help := path
+		if unit := units[path]; unit != "" {
+			help += " (" + unit + ")"
+			if pu, ok := promUnits[unit]; ok {
+				if pu.scale < 1 {
+					// Divide for sub-units so 3300 mV is exactly 3.3 V
+					value /= math.Round(1 / pu.scale)
+				} else {
+					value *= pu.scale
+				}
+				if !strings.HasSuffix(name, "_"+pu.suffix) {
+					name += "_" + pu.suffix
+				}
+			}
+		}
+
+		sample := PromMetric{Labels: copyLabels(labels), Value: value}
+		for scope, tv := range tags {
+			if scope == "" || strings.HasPrefix(key, scope+".") {
+				for k, v := range tv {
+					sample.Labels[k] = v
+				}
+			}
+		}
+		if idx := arrayIndices(key); idx != "" {
+			sample.Labels[RecordIndexKey] = idx
+		}
+		fam := families[name]
+		if fam == nil {
+			fam = &PromMetricFamily{Name: name, Help: help, Type: "gauge"}
+			families[name] = fam
+		}
+		fam.Metrics = append(fam.Metrics, sample)
+	}
+
+	if quality := qualityMap(result["_quality"]); len(quality) > 0 {
+		name := prefix + "_" + PromQualitySuffix
+		fam := &PromMetricFamily{Name: name, Help: "Decoder quality per field (1 = good)", Type: "gauge"}
+		for _, field := range sortedKeys(quality) {
+			sample := PromMetric{Labels: copyLabels(labels)}
+			sample.Labels["field"] = field
+			sample.Labels["quality"] = quality[field]
+			if quality[field] == "good" {
+				sample.Value = 1
+			}
+			fam.Metrics = append(fam.Metrics, sample)
+		}
+		families[name] = fam
+	}
+
+	out := make([]PromMetricFamily, 0, len(families))
+	for _, name := range sortedKeys(families) {
+		fam := families[name]
+		sort.Slice(fam.Metrics, func(i, j int) bool {
+			return promLabelString(fam.Metrics[i].Labels) < promLabelString(fam.Metrics[j].Labels)
+		})
+		out = append(out, *fam)
+	}
+	return out, nil
+}
+
+// WritePromText writes families in the Prometheus text exposition format.
+func WritePromText(w io.Writer, families []PromMetricFamily) error {
+	for _, fam := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", fam.Name,
+			strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(fam.Help), fam.Name, fam.Type); err != nil {
+			return err
+		}
+		for _, m := range fam.Metrics {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", fam.Name, promLabelString(m.Labels), promFloat(m.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldUnit returns the unit a field declares through its `unit:` or
+// `senml_unit:` key.
+func fieldUnit(f Field) string {
+	if u, ok := f.Extensions["unit"].(string); ok {
+		return u
+	}
+	u, _ := f.Extensions["senml_unit"].(string)
+	return u
+}
+
+// promName folds s into the Prometheus metric/label name alphabet.
+func promName(s string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range s {
+		if r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func promValue(v any) (float64, bool) {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return 1, true
+		}
+		return 0, true
+	case string, nil:
+		return 0, false
+	}
+	return toFloat64(v)
+}
+
+func promFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func promLabelString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	esc := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		parts = append(parts, k+`="`+esc.Replace(labels[k])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func qualityMap(v any) map[string]string {
+	switch q := v.(type) {
+	case map[string]string:
+		return q
+	case map[string]any:
+		m := make(map[string]string, len(q))
+		for k, s := range q {
+			m[k] = fmt.Sprint(s)
+		}
+		return m
+	}
+	return nil
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	m := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		m[k] = v
+	}
+	return m
+}
+
+// arrayIndices returns the array positions within a flattened key joined
+// by dots ("2" for readings.2.temp), or "" outside arrays.
+func arrayIndices(key string) string {
+	var idx []string
+	for _, p := range strings.Split(key, ".") {
+		if _, err := strconv.Atoi(p); err == nil {
+			idx = append(idx, p)
+		}
+	}
+	return strings.Join(idx, ".")
+}
+
+func splitLastDot(key string) (string, string) {
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
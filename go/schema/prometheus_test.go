@@ -0,0 +1,89 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToPromMetrics(t *testing.T) {
+	s, err := ParseSchema(`
+name: env-sensor
+fields:
+  - name: mode
+    type: u8
+    lookup: {0: idle, 1: active}
+    role: tag
+  - name: temperature
+    type: s16
+    div: 10
+    unit: "°C"
+    valid_range: [-40, 85]
+  - name: battery
+    type: u16
+    unit: mV
+  - name: alarm
+    type: bool
+    bit: 0
+    consume: 1
+  - name: channels
+    type: repeat
+    count: 2
+    fields:
+      - {name: id, type: u8, role: tag}
+      - {name: pressure, type: u16, unit: hPa}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode([]byte{0x01, 0x03, 0xE8, 0x0C, 0xE4, 0x01, 0x07, 0x03, 0xF5, 0x09, 0x03, 0xF2})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	families, err := s.ToPromMetrics(result, map[string]string{"dev_eui": "0011"})
+	if err != nil {
+		t.Fatalf("ToPromMetrics() error = %v", err)
+	}
+	var b strings.Builder
+	if err := WritePromText(&b, families); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP env_sensor_alarm alarm
+# TYPE env_sensor_alarm gauge
+env_sensor_alarm{dev_eui="0011",mode="active"} 1
+# HELP env_sensor_battery_volts battery (mV)
+# TYPE env_sensor_battery_volts gauge
+env_sensor_battery_volts{dev_eui="0011",mode="active"} 3.3
+# HELP env_sensor_channels_pressure_pascals channels.pressure (hPa)
+# TYPE env_sensor_channels_pressure_pascals gauge
+env_sensor_channels_pressure_pascals{dev_eui="0011",id="7",index="0",mode="active"} 101300
+env_sensor_channels_pressure_pascals{dev_eui="0011",id="9",index="1",mode="active"} 101000
+# HELP env_sensor_quality Decoder quality per field (1 = good)
+# TYPE env_sensor_quality gauge
+env_sensor_quality{dev_eui="0011",field="temperature",quality="out_of_range"} 0
+# HELP env_sensor_temperature_celsius temperature (°C)
+# TYPE env_sensor_temperature_celsius gauge
+env_sensor_temperature_celsius{dev_eui="0011",mode="active"} 100
+`
+	if b.String() != want {
+		t.Errorf("WritePromText() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestPromName(t *testing.T) {
+	tests := map[string]string{
+		"env-sensor":    "env_sensor",
+		"readings.temp": "readings_temp",
+		"2nd":           "_2nd",
+		"co2 (ppm)":     "co2_ppm",
+		"temp°C":        "temp_C",
+	}
+	for in, want := range tests {
+		if got := promName(in); got != want {
+			t.Errorf("promName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// maxRandomTLVRecords bounds the records written for a TLV field.
+const maxRandomTLVRecords = 3
+
+// RandomPayload generates a random payload for fPort, for load tests and
+// for fuzzing downstream pipelines with realistic traffic. It follows the
+// schema's structure: match and revision selectors take one of their
+// cases, flagged fields a combination of groups, TLV fields one to three
+// records of known tags, and open-ended repeats one to four elements.
+// Numeric fields with valid_range stay within it. The same rng state
+// gives the same payload.
+//
+// Every payload is checked to decode; WASM fields are not supported.
+func (s *Schema) RandomPayload(rng *rand.Rand, fPort int) ([]byte, error) {
+	fields, err := s.ResolveFields(fPort)
+	if err != nil {
+		return nil, err
+	}
+	all := append(append([]Field{}, s.ResolveHeader(fPort)...), fields...)
+
+	d := &goldenDiscovery{defs: s.Definitions, counts: map[string]uint64{}}
+	if err := d.walk(all, ""); err != nil {
+		return nil, err
+	}
+	pin := make(map[string]uint64, len(d.always)+len(d.choices))
+	for k, v := range d.always {
+		pin[k] = v
+	}
+	for _, c := range d.choices {
+		pin[c.key] = c.values[rng.Intn(len(c.values))]
+	}
+
+	g := &goldenGen{
+		rng:      rng,
+		defs:     s.Definitions,
+		endian:   s.Endian,
+		scenario: goldenScenario{name: "random", pin: pin},
+		counts:   d.counts,
+		vars:     map[string]uint64{},
+		random:   true,
+	}
+	if err := g.walk(all, ""); err != nil {
+		return nil, err
+	}
+	payload := g.buf.Bytes()
+	if _, err := s.DecodeWithPort(payload, fPort); err != nil {
+		return nil, fmt.Errorf("random payload %X does not decode: %w", payload, err)
+	}
+	return payload, nil
+}
+
+// validRange returns the bounds a random value of f must respect.
+func (g *goldenGen) validRange(f Field) (lo, hi float64, ok bool) {
+	if !g.random || len(f.ValidRange) < 2 {
+		return 0, 0, false
+	}
+	return f.ValidRange[0], f.ValidRange[1], true
+}
+
+// rangedRaw returns a random raw integer that decodes within f's
+// valid_range, or false if f has none, is pinned, or takes table keys.
+func (g *goldenGen) rangedRaw(f Field, bits uint, signed bool) (uint64, bool) {
+	lo, hi, ok := g.validRange(f)
+	if !ok || len(tableKeys(f)) > 0 {
+		return 0, false
+	}
+	if _, pinned := g.fixed(f); pinned {
+		return 0, false
+	}
+	a, okA := toFloat64(reverseValue(f, lo))
+	b, okB := toFloat64(reverseValue(f, hi))
+	if !okA || !okB {
+		return 0, false
+	}
+	if a > b {
+		a, b = b, a
+	}
+	// Snap away float noise so 60 / 0.1 still admits raw 600
+	snap := func(x float64) float64 {
+		if r := math.Round(x); math.Abs(x-r) < 1e-6 {
+			return r
+		}
+		return x
+	}
+	rlo, rhi := math.Ceil(snap(a)), math.Floor(snap(b))
+	typeMin, typeMax := 0.0, math.Exp2(float64(bits))-1
+	if signed {
+		typeMin, typeMax = -math.Exp2(float64(bits-1)), math.Exp2(float64(bits-1))-1
+	}
+	rlo, rhi = math.Max(rlo, typeMin), math.Min(rhi, typeMax)
+	if rlo > rhi || rhi-rlo >= 1<<62 {
+		return 0, false
+	}
+	n := int64(rlo) + g.rng.Int63n(int64(rhi-rlo)+1)
+	mask := uint64(math.MaxUint64)
+	if bits < 64 {
+		mask = uint64(1)<<bits - 1
+	}
+	return uint64(n) & mask, true
+}
+
+// tlv writes records of known tags: one to three random ones for a random
+// payload, otherwise one per case in tag order, leaving out a case that
+// shares an output key with an earlier case (decoding would merge the two
+// values, and the encoder writes a shared key with the lowest tag only).
+func (g *goldenGen) tlv(f Field, prefix string) error {
+	var keys []string
+	tags := map[string][]int{}
+	for _, key := range sortedKeys(f.TLVCases) {
+		tag, ok := caseKeyTag(key)
+		if !ok || len(f.TagFields) == 0 && len(tag) != 1 || len(f.TagFields) > 0 && len(tag) != len(tagKeyNames(f)) {
+			continue
+		}
+		keys = append(keys, key)
+		tags[key] = tag
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%s: TLV has no case with a literal tag", pathOr(f.Name, "(unnamed)"))
+	}
+
+	if g.random {
+		for n := 1 + g.rng.Intn(maxRandomTLVRecords); n > 0; n-- {
+			key := keys[g.rng.Intn(len(keys))]
+			if err := g.tlvRecord(f, tags[key], f.TLVCases[key], prefix); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	merge := f.Merge == nil || *f.Merge
+	if merge && f.NameTemplate != "" {
+		return fmt.Errorf("%s: TLV with name_template encodes only from a %s array", pathOr(f.Name, "(unnamed)"), TLVChannelsKey)
+	}
+	slices.SortFunc(keys, func(a, b string) int { return slices.Compare(tags[a], tags[b]) })
+	claimed := map[string]bool{}
+	for _, key := range keys {
+		fields := f.TLVCases[key]
+		if merge {
+			if slices.ContainsFunc(fields, func(cf Field) bool { return cf.Name != "" && claimed[cf.Name] }) {
+				continue
+			}
+			for _, cf := range fields {
+				if cf.Name != "" {
+					claimed[cf.Name] = true
+				}
+			}
+		}
+		if err := g.tlvRecord(f, tags[key], fields, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tlvRecord writes the tag, length and value of one TLV record.
+func (g *goldenGen) tlvRecord(f Field, tag []int, fields []Field, prefix string) error {
+	if len(f.TagFields) > 0 {
+		values := map[string]int{}
+		for i, name := range tagKeyNames(f) {
+			values[name] = tag[i]
+		}
+		for _, tf := range f.TagFields {
+			length := tf.Length
+			if length == 0 {
+				length = 1
+			}
+			g.buf.Write(encodeUint(uint64(values[tf.Name]), length, g.endian))
+		}
+	} else {
+		tagSize := f.TagSize
+		if tagSize == 0 {
+			tagSize = 1
+		}
+		g.buf.Write(encodeUint(uint64(tag[0]), tagSize, g.endian))
+	}
+
+	outer := g.buf
+	g.buf = bytes.Buffer{}
+	err := g.walk(fields, prefix)
+	body := g.buf.Bytes()
+	g.buf = outer
+	if err != nil {
+		return err
+	}
+	if f.LengthSize > 0 {
+		g.buf.Write(encodeUint(uint64(len(body)), f.LengthSize, g.endian))
+	}
+	g.buf.Write(body)
+	return nil
+}
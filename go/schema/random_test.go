@@ -0,0 +1,128 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestRandomPayload(t *testing.T) {
+	s, err := ParseSchema(`
+name: random_test
+fields:
+  - {name: msg_type, type: u8}
+  - name: body
+    type: Match
+    on: $msg_type
+    cases:
+      - case: 1
+        fields:
+          - {name: temperature, type: s16, mult: 0.1, valid_range: [-20, 60]}
+          - {name: humidity, type: u8, valid_range: [10, 90]}
+      - case: 2
+        fields:
+          - {name: battery, type: u8, lookup: {0: low, 1: ok}}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	seen := map[float64]bool{}
+	for i := 0; i < 200; i++ {
+		payload, err := s.RandomPayload(rng, 0)
+		if err != nil {
+			t.Fatalf("RandomPayload() error = %v", err)
+		}
+		result, err := s.Decode(payload)
+		if err != nil {
+			t.Fatalf("Decode(%X) error = %v", payload, err)
+		}
+		seen[result["msg_type"].(float64)] = true
+		body := result["body"].(map[string]any)
+		if temp, ok := body["temperature"].(float64); ok && (temp < -20 || temp > 60) {
+			t.Errorf("temperature %v outside valid_range", temp)
+		}
+		if hum, ok := body["humidity"].(float64); ok && (hum < 10 || hum > 90) {
+			t.Errorf("humidity %v outside valid_range", hum)
+		}
+	}
+	if !seen[1] || !seen[2] {
+		t.Errorf("cases generated = %v, want both", seen)
+	}
+
+	a, _ := s.RandomPayload(rand.New(rand.NewSource(7)), 0)
+	b, _ := s.RandomPayload(rand.New(rand.NewSource(7)), 0)
+	if !bytes.Equal(a, b) {
+		t.Errorf("same seed gave %X and %X", a, b)
+	}
+}
+
+func TestRandomPayloadTLVAndFlagged(t *testing.T) {
+	s, err := ParseSchema(`
+name: random_tlv
+fields:
+  - {name: flags, type: u8}
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - {name: battery, type: u8, valid_range: [0, 100]}
+        - bit: 1
+          fields:
+            - {name: rssi, type: s8}
+  - name: records
+    type: tlv
+    tag_size: 1
+    length_size: 1
+    cases:
+      "1":
+        - {name: temperature, type: s16, div: 10}
+      "2":
+        - {name: pressure, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 100; i++ {
+		payload, err := s.RandomPayload(rng, 0)
+		if err != nil {
+			t.Fatalf("RandomPayload() error = %v", err)
+		}
+		result, err := s.Decode(payload)
+		if err != nil {
+			t.Fatalf("Decode(%X) error = %v", payload, err)
+		}
+		if bat, ok := result["battery"].(float64); ok && bat > 100 {
+			t.Errorf("battery %v outside valid_range", bat)
+		}
+		if _, ok := result["temperature"]; !ok {
+			if _, ok := result["pressure"]; !ok {
+				t.Errorf("Decode(%X) = %v, want a TLV record", payload, result)
+			}
+		}
+	}
+}
+
+func TestRandomPayloadCompositeTLV(t *testing.T) {
+	s, err := ParseSchema(compositeTLVSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 50; i++ {
+		payload, err := s.RandomPayload(rng, 0)
+		if err != nil {
+			t.Fatalf("RandomPayload() error = %v", err)
+		}
+		result, err := s.Decode(payload)
+		if err != nil || len(result) == 0 {
+			t.Fatalf("Decode(%X) = %v, %v", payload, result, err)
+		}
+	}
+}
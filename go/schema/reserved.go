@@ -0,0 +1,102 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Reserved fields skip bytes the device documents as unused, like skip,
+// and can check they hold what the documentation says:
+//
+//	fields:
+//	  - {type: reserved, length: 2, expect: 0x00}  # Every byte 0x00
+//	  - {type: reserved, expect: [0xFF, 0x00]}     # Length 2 implied
+//
+// A mismatch means the firmware has started using the bytes. It is a
+// decode warning, or an error with WithStrict. Encoding writes the
+// expected bytes, or zeros without expect:.
+
+// parseByteList reads a byte value or list of byte values, as expect:
+// takes them.
+func parseByteList(key string, raw any) ([]byte, string) {
+	list, isList := raw.([]any)
+	if !isList {
+		list = []any{raw}
+	}
+	if len(list) == 0 {
+		return nil, key + ": expected at least one byte"
+	}
+	out := make([]byte, len(list))
+	for i, v := range list {
+		n, ok := toWholeInt(v)
+		if !ok || n < 0 || n > 0xFF {
+			return nil, fmt.Sprintf("%s: expected a byte or list of bytes (0-255), got %v", key, v)
+		}
+		out[i] = byte(n)
+	}
+	return out, ""
+}
+
+// parseExpect sets a reserved field's expect: and the length it implies.
+func parseExpect(fm map[string]any, f *Field) []string {
+	raw, ok := fm["expect"]
+	if !ok {
+		return nil
+	}
+	if !isReservedType(f.Type) {
+		return []string{"expect: applies to reserved fields"}
+	}
+	expect, msg := parseByteList("expect", raw)
+	if msg != "" {
+		return []string{msg}
+	}
+	f.Expect = expect
+	if len(expect) > 1 {
+		if f.Length == 0 {
+			f.Length = len(expect)
+		} else if f.Length != len(expect) {
+			return []string{fmt.Sprintf("expect: %d bytes for length %d", len(expect), f.Length)}
+		}
+	}
+	return nil
+}
+
+func isReservedType(t FieldType) bool {
+	return t == TypeReserved || t == TypeReservedLower
+}
+
+// reservedBytes returns the n bytes a reserved field should hold.
+func reservedBytes(f Field, n int) []byte {
+	if len(f.Expect) == n {
+		return f.Expect
+	}
+	fill := byte(0)
+	if len(f.Expect) == 1 {
+		fill = f.Expect[0]
+	}
+	return bytes.Repeat([]byte{fill}, n)
+}
+
+// checkReserved compares the bytes read for a reserved field with its
+// expect:.
+func (ctx *DecodeContext) checkReserved(f Field, data []byte, offset int) error {
+	if len(f.Expect) == 0 {
+		return nil
+	}
+	want := reservedBytes(f, len(data))
+	if bytes.Equal(data, want) {
+		return nil
+	}
+	msg := fmt.Sprintf("%s: reserved bytes at offset %d are % X, expected % X",
+		pathOr(f.Name, "(reserved)"), offset, data, want)
+	if ctx.Limits.ErrorOnLimit {
+		return errors.New(msg)
+	}
+	ctx.Warnings = append(ctx.Warnings, msg)
+	return nil
+}
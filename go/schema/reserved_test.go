@@ -0,0 +1,104 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReservedField(t *testing.T) {
+	s, err := ParseSchema(`
+name: reserved
+fields:
+  - {name: a, type: u8}
+  - {type: reserved, length: 2, expect: 0x00}
+  - {name: spare, type: reserved, expect: [0xFF, 0x00]}
+  - {type: reserved}
+  - {name: b, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	var info DecodeInfo
+	got, err := s.Decode([]byte{0x01, 0x00, 0x00, 0xFF, 0x00, 0x99, 0x02}, WithInfo(&info))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["a"] != 1.0 || got["b"] != 2.0 || len(got) != 2 || len(info.Warnings) > 0 {
+		t.Errorf("Decode() = %v, warnings %v", got, info.Warnings)
+	}
+
+	// Firmware that has started using the reserved bytes
+	payload := []byte{0x01, 0x00, 0x05, 0xFF, 0x01, 0x99, 0x02}
+	got, err = s.Decode(payload, WithInfo(&info))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["b"] != 2.0 || len(info.Warnings) != 2 {
+		t.Fatalf("Decode() = %v, warnings %v", got, info.Warnings)
+	}
+	if want := "(reserved): reserved bytes at offset 1 are 00 05, expected 00 00"; info.Warnings[0] != want {
+		t.Errorf("warning = %q, want %q", info.Warnings[0], want)
+	}
+	if !strings.HasPrefix(info.Warnings[1], "spare: reserved bytes at offset 3") {
+		t.Errorf("warning = %q", info.Warnings[1])
+	}
+	if _, err := s.Decode(payload, WithStrict()); err == nil || !strings.Contains(err.Error(), "offset 1 are 00 05") {
+		t.Errorf("Decode(WithStrict) error = %v", err)
+	}
+
+	enc, err := s.Encode(map[string]any{"a": 1, "b": 2})
+	if err != nil || !bytes.Equal(enc, []byte{0x01, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x02}) {
+		t.Errorf("Encode() = % X, %v", enc, err)
+	}
+
+	vectors, err := s.GenerateGolden(1)
+	if err != nil {
+		t.Fatalf("GenerateGolden() error = %v", err)
+	}
+	for _, v := range vectors {
+		if !strings.HasPrefix(v.Payload[2:], "0000FF00") {
+			t.Errorf("golden %s payload = %s, want expected reserved bytes", v.Name, v.Payload)
+		}
+	}
+}
+
+func TestReservedFieldInvalid(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"{type: reserved, expect: 0x100}", "expect: expected a byte or list of bytes (0-255), got 256"},
+		{"{type: reserved, length: 3, expect: [1, 2]}", "expect: 2 bytes for length 3"},
+		{"{type: reserved, expect: []}", "expect: expected at least one byte"},
+		{"{name: x, type: u8, expect: 0}", "expect: applies to reserved fields"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSchema("name: bad\nstrict: true\nfields:\n  - " + tt.field + "\n")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.field, err, tt.want)
+		}
+	}
+}
+
+func TestBuilderReserved(t *testing.T) {
+	s, err := New("built").U8("a").Reserved(2, 0xAA).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	enc, err := s.Encode(map[string]any{"a": 7})
+	if err != nil || !bytes.Equal(enc, []byte{0x07, 0xAA, 0xAA}) {
+		t.Errorf("Encode() = % X, %v", enc, err)
+	}
+	if _, err := New("built").Reserved(3, 1, 2).Build(); err == nil {
+		t.Error("Build() with 2 expected bytes for length 3: want error")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Bytes, hex and text fields can read everything left in the payload, for
+// trailing opaque blobs and messages:
+//
+//	- {name: firmware_blob, type: bytes, length: rest}
+//	- {name: message, type: ascii, until: end}
+//
+// Such a field must come last. Encoding writes the value's bytes as they
+// are, with no padding or truncation.
+
+// readsRest reports whether f reads to the end of the payload.
+func readsRest(f Field) bool {
+	return f.Until == "end" && isRestType(f.Type)
+}
+
+func isRestType(t FieldType) bool {
+	switch t {
+	case TypeBytes, TypeBytesLower, TypeHex, TypeString, TypeStringLower, TypeAscii, TypeAsciiLower:
+		return true
+	}
+	return false
+}
+
+// parseRestLength reads `length: rest` as until: end.
+func parseRestLength(fm map[string]any, f *Field) []string {
+	if fm["length"] != "rest" {
+		return nil
+	}
+	if !isRestType(f.Type) {
+		return []string{"length: rest applies to bytes, hex and text fields"}
+	}
+	f.Until = "end"
+	return nil
+}
+
+// checkRestLast flags fields reading to the end of the payload that other
+// fields follow, since those would find no bytes left.
+func checkRestLast(fields []Field) {
+	for i := range fields {
+		if !readsRest(fields[i]) {
+			continue
+		}
+		for _, next := range fields[i+1:] {
+			if next.Name != "" {
+				fields[i].invalid = append(fields[i].invalid,
+					fmt.Sprintf("reads to the end of the payload but is followed by %s", next.Name))
+				break
+			}
+		}
+	}
+}
+
+// encodeRest writes a to-the-end field's value verbatim.
+func encodeRest(field Field, value any, endian string, ctx *EncodeContext) error {
+	path := ctx.fieldPath(field.Name)
+	if isTextType(field.Type) {
+		text, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		chars, err := textToBytes(field, text, path, endian)
+		if err != nil {
+			return err
+		}
+		for _, c := range chars {
+			ctx.Write(c)
+		}
+		return nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		var data []byte
+		var err error
+		if field.Format == "base64" {
+			data, err = base64.StdEncoding.DecodeString(v)
+		} else {
+			stripped := strings.NewReplacer(":", "", "-", "", " ", "").Replace(v)
+			if field.Separator != "" {
+				stripped = strings.ReplaceAll(stripped, field.Separator, "")
+			}
+			data, err = hex.DecodeString(stripped)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		ctx.Write(data)
+	case []any:
+		data := make([]byte, len(v))
+		for i, b := range v {
+			if num, ok := toFloat64(b); ok {
+				data[i] = byte(num)
+			}
+		}
+		ctx.Write(data)
+	case []byte:
+		ctx.Write(v)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLengthRest(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		payload []byte
+		want    any
+	}{
+		{"bytes", "{name: blob, type: bytes, length: rest}", []byte{1, 0xDE, 0xAD, 0xBE, 0xEF}, "deadbeef"},
+		{"base64", "{name: blob, type: bytes, length: rest, format: base64}", []byte{1, 'h', 'i'}, "aGk="},
+		{"hex", "{name: blob, type: Hex, until: end}", []byte{1, 0x0A, 0x0B}, "0a0b"},
+		{"ascii", "{name: blob, type: ascii, until: end}", []byte{1, 'o', 'k', '!'}, "ok!"},
+		{"string", "{name: blob, type: string, length: rest}", []byte{1, 0xC3, 0xA9}, "é"},
+		{"empty", "{name: blob, type: bytes, length: rest}", []byte{1}, ""},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - {name: kind, type: u8}\n  - " + tt.schema + "\n")
+		if err != nil {
+			t.Fatalf("%s: ParseSchema() error = %v", tt.name, err)
+		}
+		if len(s.Warnings) > 0 {
+			t.Errorf("%s: Warnings = %v", tt.name, s.Warnings)
+		}
+		got, err := s.Decode(tt.payload, WithStrict())
+		if err != nil {
+			t.Fatalf("%s: Decode() error = %v", tt.name, err)
+		}
+		if got["blob"] != tt.want {
+			t.Errorf("%s: blob = %v, want %v", tt.name, got["blob"], tt.want)
+		}
+		encoded, err := s.Encode(got)
+		if err != nil {
+			t.Fatalf("%s: Encode() error = %v", tt.name, err)
+		}
+		if !bytes.Equal(encoded, tt.payload) {
+			t.Errorf("%s: Encode() = %X, want %X", tt.name, encoded, tt.payload)
+		}
+	}
+}
+
+func TestLengthRestLayoutAndErrors(t *testing.T) {
+	s, err := ParseSchema("name: t\nfields:\n  - {name: kind, type: u8}\n  - {name: blob, type: bytes, length: rest}\n")
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	layout, err := s.Layout(0)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	if layout.MinSize != 1 || layout.MaxSize != Unbounded {
+		t.Errorf("Layout() size = %d..%d, want 1..unbounded", layout.MinSize, layout.MaxSize)
+	}
+	if _, err := s.Encode(map[string]any{"kind": 1, "blob": "not hex"}); err == nil {
+		t.Error("Encode(invalid hex): no error")
+	}
+
+	warnings := []struct {
+		field, want string
+	}{
+		{"{name: blob, type: bytes, length: rest}\n  - {name: crc, type: u16}", "reads to the end of the payload but is followed by crc"},
+		{"{name: blob, type: u16, length: rest}", "length: rest applies to bytes, hex and text fields"},
+	}
+	for _, tt := range warnings {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("Warnings = %v, want %q", s.Warnings, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RevisionsDef selects the rest of the payload by a version field, so one
+// schema describes every layout a device has shipped:
+//
+//	header:
+//	  - {name: protocol_version, type: u8}
+//	revisions:
+//	  field: protocol_version
+//	  cases:
+//	    - case: 1
+//	      fields: [...]
+//	    - case: [2, 3]
+//	      fields: [...]
+//
+// The selected revision's fields follow the header and the schema's own
+// fields, which must have decoded the version field. A version no case
+// matches is an error, unless a default case is given.
+type RevisionsDef struct {
+	Field string // Name of the version field
+	Match Field  // Selects the revision (on + cases)
+}
+
+// parseRevisionsDef parses the schema-level revisions: key.
+func parseRevisionsDef(raw map[string]any) (*RevisionsDef, error) {
+	rd := &RevisionsDef{}
+	name, _ := raw["field"].(string)
+	rd.Field = strings.TrimPrefix(name, "$")
+	if rd.Field == "" {
+		return nil, fmt.Errorf("revisions: field: is required (the field holding the version)")
+	}
+	rd.Match = parseFieldMap(map[string]any{"type": string(TypeMatch), "on": "$" + rd.Field, "cases": raw["cases"]}, nil)
+	if len(rd.Match.Cases) == 0 {
+		return nil, fmt.Errorf("revisions: cases: must list at least one revision")
+	}
+	rd.Match.revisions = true
+	return rd, nil
+}
+
+// topFields returns the fields decoded without ports: the schema's fields,
+// then the revision selected by the version field.
+func (s *Schema) topFields() []Field {
+	if s.Revisions == nil {
+		return s.Fields
+	}
+	match := s.Revisions.Match
+	return append(s.Fields[:len(s.Fields):len(s.Fields)], Field{MatchInline: &match})
+}
+
+// checkRevisions reports revisions that cannot be selected.
+func (s *Schema) checkRevisions() []string {
+	if s.Revisions == nil {
+		return nil
+	}
+	if len(s.Ports) > 0 {
+		return []string{"revisions: not used by a schema with ports"}
+	}
+	for _, list := range [][]Field{s.Header, s.Fields} {
+		for _, f := range list {
+			if f.Name == s.Revisions.Field {
+				return nil
+			}
+		}
+	}
+	return []string{fmt.Sprintf("revisions: version field %s is not in header: or fields:", s.Revisions.Field)}
+}
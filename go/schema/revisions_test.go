@@ -0,0 +1,108 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const revisionsSchema = `
+name: revised
+header:
+  - {name: protocol_version, type: u8}
+revisions:
+  field: protocol_version
+  cases:
+    - case: 1
+      fields:
+        - {name: temperature, type: s16, div: 10}
+    - case: [2, 3]
+      fields:
+        - {name: temperature, type: s16, div: 100}
+        - {name: humidity, type: u8}
+`
+
+func TestRevisions(t *testing.T) {
+	s, err := ParseSchema(revisionsSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Errorf("Warnings = %v", s.Warnings)
+	}
+	tests := []struct {
+		payload []byte
+		want    map[string]any
+	}{
+		{[]byte{1, 0x00, 0xE1}, map[string]any{"protocol_version": 1.0, "temperature": 22.5}},
+		{[]byte{3, 0x08, 0xCA, 0x37}, map[string]any{"protocol_version": 3.0, "temperature": 22.5, "humidity": 55.0}},
+	}
+	for _, tt := range tests {
+		got, err := s.Decode(tt.payload)
+		if err != nil {
+			t.Fatalf("Decode(%X) error = %v", tt.payload, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Decode(%X) = %v, want %v", tt.payload, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("Decode(%X)[%s] = %v, want %v", tt.payload, k, got[k], v)
+			}
+		}
+		encoded, err := s.Encode(got)
+		if err != nil {
+			t.Fatalf("Encode(%v) error = %v", got, err)
+		}
+		if !bytes.Equal(encoded, tt.payload) {
+			t.Errorf("Encode(%v) = %X, want %X", got, encoded, tt.payload)
+		}
+	}
+
+	if _, err := s.Decode([]byte{4, 0, 0}); err == nil || !strings.Contains(err.Error(), "no revision for protocol_version = 4") {
+		t.Errorf("Decode(unknown version) error = %v", err)
+	}
+	if _, err := s.Encode(map[string]any{"protocol_version": 4, "temperature": 1}); err == nil {
+		t.Error("Encode(unknown version): no error")
+	}
+
+	layout, err := s.Layout(0)
+	if err != nil {
+		t.Fatalf("Layout() error = %v", err)
+	}
+	if layout.MinSize != 3 || layout.MaxSize != 4 {
+		t.Errorf("Layout() size = %d..%d, want 3..4", layout.MinSize, layout.MaxSize)
+	}
+
+	// Golden vectors only use versions the schema knows
+	if _, err := s.GenerateGolden(1); err != nil {
+		t.Errorf("GenerateGolden() error = %v", err)
+	}
+}
+
+func TestRevisionsWarnings(t *testing.T) {
+	tests := []struct {
+		schema, want string
+	}{
+		{"revisions: {field: version, cases: [{case: 1, fields: [{name: a, type: u8}]}]}", "version field version is not in header: or fields:"},
+		{"fields: [{name: v, type: u8}]\nrevisions: {field: v, cases: [{case: 1, fields: []}]}\nports: {1: {fields: [{name: a, type: u8}]}}", "not used by a schema with ports"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\n" + tt.schema + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("Warnings = %v, want %q", s.Warnings, tt.want)
+		}
+	}
+	for _, bad := range []string{"revisions: {cases: []}", "revisions: {field: v}"} {
+		if _, err := ParseSchema("name: t\n" + bad + "\n"); err == nil {
+			t.Errorf("ParseSchema(%s): no error", bad)
+		}
+	}
+}
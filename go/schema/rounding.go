@@ -0,0 +1,65 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rounding modes for encoding, set by the schema-level encode_rounding:
+// key. Reversed modifiers rarely land on a whole number: 25.1 with
+// mult: 0.1 is 250.99999999999997, which must encode as 251, not 250.
+// Results within float noise of a whole or half number are snapped to it
+// first, so every mode sees 251 there and ties are real ties.
+const (
+	EncodeRoundHalfAway = "half_away" // Ties away from zero, as round: decodes (default)
+	EncodeRoundHalfEven = "half_even" // Ties to the even neighbour (banker's rounding)
+	EncodeRoundTruncate = "truncate"  // Toward zero, for firmware that truncates
+)
+
+// roundTolerance is how close, relative to its size, a value must be to
+// a whole or half number to count as that number.
+const roundTolerance = 1e-9
+
+// parseEncodeRounding reads the schema-level encode_rounding: key.
+func parseEncodeRounding(raw map[string]any) (string, error) {
+	v, ok := raw["encode_rounding"]
+	if !ok {
+		return "", nil
+	}
+	switch v {
+	case EncodeRoundHalfAway, EncodeRoundHalfEven, EncodeRoundTruncate:
+		return v.(string), nil
+	}
+	return "", fmt.Errorf("encode_rounding: expected half_away, half_even or truncate, got %v", v)
+}
+
+// roundRaw converts v to the integer an integer field encodes under mode.
+func roundRaw(v float64, mode string) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	if h := math.Round(v*2) / 2; math.Abs(v-h) <= roundTolerance*math.Max(1, math.Abs(v)) {
+		v = h
+	}
+	switch mode {
+	case EncodeRoundHalfEven:
+		v = math.RoundToEven(v)
+	case EncodeRoundTruncate:
+		v = math.Trunc(v)
+	default:
+		v = math.Round(v)
+	}
+	if v == 0 {
+		return 0 // Integers have no negative zero
+	}
+	return v
+}
+
+// round applies the encode's rounding mode to v.
+func (ctx *EncodeContext) round(v float64) float64 {
+	return roundRaw(v, ctx.Rounding)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEncodeRoundingFloatPitfalls(t *testing.T) {
+	tests := []struct {
+		modifier string
+		value    float64
+		want     map[string]uint16 // By encode_rounding
+	}{
+		// 25.1 / 0.1 = 250.99999999999997
+		{"mult: 0.1", 25.1, map[string]uint16{"half_away": 251, "half_even": 251, "truncate": 251}},
+		// 1.15 * 100 = 114.99999999999999
+		{"div: 100", 1.15, map[string]uint16{"half_away": 115, "half_even": 115, "truncate": 115}},
+		// 4.35 * 100 = 434.99999999999994
+		{"div: 100", 4.35, map[string]uint16{"half_away": 435, "half_even": 435, "truncate": 435}},
+		// 0.25 / 0.1 = 2.4999999999999996, a tie
+		{"mult: 0.1", 0.25, map[string]uint16{"half_away": 3, "half_even": 2, "truncate": 2}},
+		{"mult: 0.1", 0.35, map[string]uint16{"half_away": 4, "half_even": 4, "truncate": 3}},
+		{"mult: 0.1", 25.19, map[string]uint16{"half_away": 252, "half_even": 252, "truncate": 251}},
+	}
+	for _, tt := range tests {
+		for _, mode := range []string{EncodeRoundHalfAway, EncodeRoundHalfEven, EncodeRoundTruncate} {
+			s, err := ParseSchema(fmt.Sprintf("name: r\nencode_rounding: %s\nfields:\n  - {name: v, type: u16, %s}\n", mode, tt.modifier))
+			if err != nil {
+				t.Fatalf("ParseSchema() error = %v", err)
+			}
+			got, err := s.Encode(map[string]any{"v": tt.value})
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if raw := uint16(got[0])<<8 | uint16(got[1]); raw != tt.want[mode] {
+				t.Errorf("%s, %s: Encode(%v) raw = %d, want %d", tt.modifier, mode, tt.value, raw, tt.want[mode])
+			}
+		}
+	}
+}
+
+func TestEncodeRoundingDefault(t *testing.T) {
+	s, err := ParseSchema("name: r\nfields:\n  - {name: v, type: s8, mult: 0.5}\n")
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if s.EncodeRounding != "" {
+		t.Errorf("EncodeRounding = %q, want default", s.EncodeRounding)
+	}
+	// Ties away from zero, in both directions
+	for value, want := range map[float64]byte{1.25: 0x03, -1.25: 0xFD} {
+		if got, err := s.Encode(map[string]any{"v": value}); err != nil || got[0] != want {
+			t.Errorf("Encode(%v) = % X, %v; want %02X", value, got, err, want)
+		}
+	}
+
+	if _, err := ParseSchema("name: r\nencode_rounding: floor\nfields: []\n"); err == nil ||
+		!strings.Contains(err.Error(), "encode_rounding: expected half_away, half_even or truncate, got floor") {
+		t.Errorf("ParseSchema(encode_rounding: floor) error = %v", err)
+	}
+}
+
+func TestEncodeRoundingRoundTrip(t *testing.T) {
+	s, err := ParseSchema(`
+name: r
+encode_rounding: half_even
+fields:
+  - {name: temp, type: s16, mult: 0.1}
+  - {name: volts, type: u16, div: 1000, add: 0.5}
+  - {name: level, type: bits, bits: 6, mult: 0.1}
+  - {name: pad, type: u8}
+  - {name: q, type: fixed, int_bits: 8, frac_bits: 8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// Decoded values re-encode to the same bytes
+	for i := -300; i <= 300; i++ {
+		in := map[string]any{
+			"temp":  float64(i) / 10,
+			"volts": 0.5 + float64(i+300)/1000,
+			"level": float64((i+300)%64) / 10,
+			"pad":   0.0,
+			"q":     float64(i) / 3,
+		}
+		first, err := s.Encode(in)
+		if err != nil {
+			t.Fatalf("Encode(%v) error = %v", in, err)
+		}
+		decoded, err := s.Decode(first)
+		if err != nil {
+			t.Fatalf("Decode(% X) error = %v", first, err)
+		}
+		again, err := s.Encode(decoded)
+		if err != nil {
+			t.Fatalf("Encode(%v) error = %v", decoded, err)
+		}
+		if !bytes.Equal(first, again) {
+			t.Fatalf("round trip of %v: % X, then % X", in, first, again)
+		}
+		if got := decoded["temp"].(float64); math.Abs(got-float64(i)/10) > 1e-9 {
+			t.Fatalf("temp = %v, want %v", got, float64(i)/10)
+		}
+	}
+}
+
+func TestEncodeNegativeZero(t *testing.T) {
+	s, err := ParseSchema(`
+name: z
+fields:
+  - {name: inverted, type: f32, mult: -1}
+  - {name: plain, type: f32}
+  - {name: n, type: s8, mult: -0.5}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	got, err := s.Encode(map[string]any{"inverted": 0.0, "plain": math.Copysign(0, -1), "n": 0.0})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	// 0 through mult -1 has no sign bit; an explicit -0 input keeps it
+	if want := []byte{0, 0, 0, 0, 0x80, 0, 0, 0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("Encode() = % X, want % X", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// scale_by scales a mantissa by an exponent decoded earlier, for payloads
+// that send a value and its power of ten (or two) separately:
+//
+//	- {name: exponent, type: s8, var: exp}
+//	- {name: flow, type: u16, scale_by: $exp}                 # flow × 10^exp
+//	- {name: energy, type: u32, scale_by: $exp, scale_base: 2} # energy × 2^exp
+//
+// The scale applies to the raw value, before curve and add/mult/div. On
+// encode the exponent is taken from the input and the value divided back.
+
+// parseScaleBy reads scale_by and scale_base.
+func parseScaleBy(fm map[string]any, f *Field) []string {
+	var msgs []string
+	if raw, ok := fm["scale_by"]; ok {
+		ref, _ := raw.(string)
+		if !bareVarPattern.MatchString(ref) {
+			msgs = append(msgs, fmt.Sprintf("scale_by: expected a variable like $exp, got %v", raw))
+		} else {
+			f.ScaleBy = ref
+		}
+	}
+	if raw, ok := fm["scale_base"]; ok {
+		base, ok := toFloat64(raw)
+		switch {
+		case !ok || base <= 0 || base == 1:
+			msgs = append(msgs, fmt.Sprintf("scale_base: expected a positive number other than 1, got %v", raw))
+		case f.ScaleBy == "":
+			msgs = append(msgs, "scale_base: applies to scale_by, which is not set")
+		default:
+			f.ScaleBase = base
+		}
+	}
+	return msgs
+}
+
+// scaleByPow returns v × base^exp, dividing for negative exponents so
+// that decimal results like 1234 × 10^-2 come out exact.
+func scaleByPow(v, base, exp float64) float64 {
+	if base == 0 {
+		base = 10
+	}
+	if exp < 0 {
+		return v / math.Pow(base, -exp)
+	}
+	return v * math.Pow(base, exp)
+}
+
+// scaleByExponent returns the exponent a scale_by field refers to. ok is
+// false when the exponent itself decoded as invalid (null).
+func (ctx *DecodeContext) scaleByExponent(field Field) (exp float64, ok bool, err error) {
+	name := strings.TrimPrefix(field.ScaleBy, "$")
+	v, set := ctx.Variables[name]
+	if !set {
+		return 0, false, fmt.Errorf("%s: scale_by: variable not found: $%s", field.Name, name)
+	}
+	exp, ok = toFloat64(v)
+	return exp, ok, nil
+}
+
+// unscale divides an encode value by its scale_by factor.
+func (ctx *EncodeContext) unscale(field Field, value any) (any, error) {
+	numVal, ok := toFloat64(value)
+	if !ok {
+		return value, nil
+	}
+	name := strings.TrimPrefix(field.ScaleBy, "$")
+	exp, ok := toFloat64(ctx.Variables[name])
+	if !ok {
+		return nil, fmt.Errorf("%s: scale_by: $%s must be encoded before it", ctx.fieldPath(field.Name), name)
+	}
+	return scaleByPow(numVal, field.ScaleBase, -exp), nil
+}
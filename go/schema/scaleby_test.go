@@ -0,0 +1,84 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScaleBy(t *testing.T) {
+	s, err := ParseSchema(`
+name: meter
+fields:
+  - {name: exponent, type: s8, var: exp}
+  - {name: flow, type: u16, scale_by: $exp, unit: "m3/h"}
+  - byte_group:
+      - {name: shift, type: "u8[0:3]", var: shift}
+  - {name: energy, type: u16, scale_by: $shift, scale_base: 2, mult: 0.5}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	data := []byte{0xFE, 0x04, 0xD2, 0x03, 0x00, 0x05}
+	got, err := s.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["flow"] != 12.34 || got["energy"] != 20.0 {
+		t.Errorf("flow, energy = %v, %v, want 12.34, 20", got["flow"], got["energy"])
+	}
+
+	out, err := s.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("Encode() = % X, want % X", out, data)
+	}
+
+	if _, err := s.Encode(map[string]any{"flow": 1.5, "shift": 0, "energy": 1}); err == nil ||
+		!strings.Contains(err.Error(), "$exp must be encoded before it") {
+		t.Errorf("Encode() without exponent error = %v", err)
+	}
+}
+
+func TestScaleByMissingVariable(t *testing.T) {
+	s, err := ParseSchema(`
+name: t
+fields:
+  - {name: flow, type: u16, scale_by: $exp}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.Decode([]byte{0x00, 0x01}); err == nil || !strings.Contains(err.Error(), "variable not found: $exp") {
+		t.Errorf("Decode() error = %v", err)
+	}
+}
+
+func TestScaleByWarnings(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{"{name: a, type: u8, scale_by: 3}", "expected a variable"},
+		{"{name: a, type: u8, scale_by: $e, scale_base: 1}", "positive number other than 1"},
+		{"{name: a, type: u8, scale_base: 2}", "scale_by, which is not set"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.field, s.Warnings, tt.want)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -90,8 +91,22 @@ const (
 	TypeRepeat      FieldType = "Repeat"
 	TypeRepeatLower FieldType = "repeat"
 
+	// Samples at a fixed interval (see series.go)
+	TypeSeries FieldType = "series"
+
+	// x/y/z triplet of a numeric base type (see vector.go)
+	TypeVector3 FieldType = "vector3"
+
 	// Bitfield string (version strings)
 	TypeBitfieldString FieldType = "bitfield_string"
+
+	// Unused bytes, optionally checked against expect: (see reserved.go)
+	TypeReserved      FieldType = "Reserved"
+	TypeReservedLower FieldType = "reserved"
+
+	// Sync pattern or signature bytes (see magic.go)
+	TypeMagic      FieldType = "Magic"
+	TypeMagicLower FieldType = "magic"
 )
 
 // Field represents a field definition in the schema.
@@ -109,9 +124,12 @@ type Field struct {
 	ModOrder    []string       `json:"-" yaml:"-"` // YAML key order for add/mult/div
 	Transform   []Transform    `json:"transform,omitempty" yaml:"transform,omitempty"`
 	Modifiers   []Transform    `json:"modifiers,omitempty" yaml:"modifiers,omitempty"` // Legacy support
+	ScaleBy     string         `json:"scale_by,omitempty" yaml:"scale_by,omitempty"`     // Exponent variable: value × base^$exp
+	ScaleBase   float64        `json:"scale_base,omitempty" yaml:"scale_base,omitempty"` // Base for scale_by (default 10)
 	Lookup      map[int]string `json:"lookup,omitempty" yaml:"lookup,omitempty"`
 	LookupArray []any          `json:"lookup_array,omitempty" yaml:"lookup_array,omitempty"`
 	Var         string         `json:"var,omitempty" yaml:"var,omitempty"`
+	Scope       string         `json:"scope,omitempty" yaml:"scope,omitempty"` // Lifetime of var: global (default), parent or local
 	Value       any            `json:"value,omitempty" yaml:"value,omitempty"`
 	Fields      []Field        `json:"fields,omitempty" yaml:"fields,omitempty"`
 	On          string         `json:"on,omitempty" yaml:"on,omitempty"`
@@ -122,15 +140,25 @@ type Field struct {
 	Until      string `json:"until,omitempty" yaml:"until,omitempty"`           // "end" for until end of payload
 	Max        int    `json:"max,omitempty" yaml:"max,omitempty"`               // Maximum iterations (safety limit)
 	Min        int    `json:"min,omitempty" yaml:"min,omitempty"`               // Minimum required iterations
+	// Series: seconds between samples (number or $var) and sample order
+	Interval    any    `json:"interval,omitempty" yaml:"interval,omitempty"`
+	SeriesOrder string `json:"order,omitempty" yaml:"order,omitempty"`
 	// Bytes field options
 	Format    string `json:"format,omitempty" yaml:"format,omitempty"`       // hex, hex:upper, base64, array
 	Separator string `json:"separator,omitempty" yaml:"separator,omitempty"` // Byte separator for hex output
+	// Text field options
+	Const    any    `json:"const,omitempty" yaml:"const,omitempty"`       // Constant string (reads no bytes)
+	Pad      string `json:"pad,omitempty" yaml:"pad,omitempty"`           // null (default) or space
+	Overflow string `json:"overflow,omitempty" yaml:"overflow,omitempty"` // Encode: truncate (default) or error
+	Encoding     string `json:"encoding,omitempty" yaml:"encoding,omitempty"`           // utf8 (default), ascii, latin1, ucs2
+	InvalidChars string `json:"invalid_chars,omitempty" yaml:"invalid_chars,omitempty"` // replace (default) or error
 	// Enum field options
 	Base       string         `json:"base,omitempty" yaml:"base,omitempty"`     // Base type (u8, u16, etc.)
 	Values     map[int]string `json:"values,omitempty" yaml:"values,omitempty"` // Enum value mapping
 	// Bool field options
 	Bit     int  `json:"bit,omitempty" yaml:"bit,omitempty"`         // Bit position for bool extraction
 	Consume int  `json:"consume,omitempty" yaml:"consume,omitempty"` // Bytes to consume after reading
+	ConsumeSet bool `json:"-" yaml:"-"` // consume: given explicitly (may be 0)
 	// Byte group (inline grouped bitfields)
 	ByteGroup []Field `json:"byte_group,omitempty" yaml:"byte_group,omitempty"`
 	Size      int     `json:"size,omitempty" yaml:"size,omitempty"` // Size of byte group in bytes
@@ -142,7 +170,8 @@ type Field struct {
 	TagFields  []Field            `json:"tag_fields,omitempty" yaml:"tag_fields,omitempty"`
 	TagKey     any                `json:"tag_key,omitempty" yaml:"tag_key,omitempty"`
 	Merge      *bool              `json:"merge,omitempty" yaml:"merge,omitempty"`
-	Unknown    string             `json:"unknown,omitempty" yaml:"unknown,omitempty"`
+	Unknown    string             `json:"unknown,omitempty" yaml:"unknown,omitempty"` // TLV tags; enum/lookup values: raw|null|error|label("fmt")
+	NameTemplate string           `json:"name_template,omitempty" yaml:"name_template,omitempty"` // TLV: output key per record, e.g. "ch{channel}_{name}"
 	TLVCases   map[string][]Field `json:"-" yaml:"-"` // Populated during parsing for TLV
 	// Bitfield string fields
 	Parts     [][]any `json:"parts,omitempty" yaml:"parts,omitempty"`
@@ -153,18 +182,69 @@ type Field struct {
 	// Semantic fields
 	ValidRange []float64 `json:"valid_range,omitempty" yaml:"valid_range,omitempty"` // [min, max] bounds for quality checks
 	Resolution *float64  `json:"resolution,omitempty" yaml:"resolution,omitempty"`   // Minimum detectable change
+	Invalid    []float64 `json:"invalid,omitempty" yaml:"invalid,omitempty"`         // Raw sentinel values decoded as null
 	UNECE      string    `json:"unece,omitempty" yaml:"unece,omitempty"`             // UNECE Rec 20 unit code
+	Role       string    `json:"role,omitempty" yaml:"role,omitempty"`               // Time-series role: tag, field or timestamp
+	Expect     []byte    `json:"-" yaml:"-"`                                         // Reserved and magic fields: expected bytes (expect:, value:)
+	Mismatch   string    `json:"mismatch,omitempty" yaml:"mismatch,omitempty"`       // Magic fields: error (default) or warn
+	// Reporting policy, applied by ChangeTracker
+	Deadband          *float64 `json:"deadband,omitempty" yaml:"deadband,omitempty"`                       // Change needed before the field is reported again
+	ReportMinInterval *float64 `json:"report_min_interval,omitempty" yaml:"report_min_interval,omitempty"` // Seconds between reports of the field
+	// Documentation, rendered by Markdown
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	DisplayName string `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Example     any    `json:"example,omitempty" yaml:"example,omitempty"` // Typical decoded value
+	// NaN/Inf policy: keep, null, error or sentinel (NonFiniteValue)
+	NonFinite      string   `json:"non_finite,omitempty" yaml:"non_finite,omitempty"`
+	NonFiniteValue *float64 `json:"-" yaml:"-"`
 	// Phase 2: Declarative computed values
 	Ref        string     `json:"ref,omitempty" yaml:"ref,omitempty"`               // Reference to another field ($field_name)
 	Polynomial []float64  `json:"polynomial,omitempty" yaml:"polynomial,omitempty"` // Coefficients [a_n, ..., a_0] for Horner's method
 	Compute    *ComputeDef `json:"-" yaml:"-"`                                       // Binary operation (div, mul, add, sub)
 	Guard      *GuardDef   `json:"-" yaml:"-"`                                       // Conditional evaluation
+	// Encode: derive the ref'd raw value from a computed value
+	EncodeInverse *EncodeInverse `json:"encode_inverse,omitempty" yaml:"encode_inverse,omitempty"`
 	// Flagged construct (inline struct)
 	Flagged *FlaggedDef `json:"-" yaml:"-"`
 	// TLV inline (for port-based schemas where tlv: is a nested key)
 	TLVInline *Field `json:"-" yaml:"-"`
 	// Match inline (for Option B syntax: `- match: { field: $var, cases: {...} }`)
 	MatchInline *Field `json:"-" yaml:"-"`
+	// WASM-hosted custom decoder
+	WASM *WASMDef `json:"-" yaml:"-"`
+	// Renames: legacy names accepted on encode (and emitted on decode if enabled)
+	Aliases    []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// Extra output keys derived from the decoded value (e.g. another unit)
+	AlsoEmit   []AlsoEmit `json:"also_emit,omitempty" yaml:"also_emit,omitempty"`
+	Deprecated bool     `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// Output number formatting (applied after modifiers)
+	Round     *int   `json:"round,omitempty" yaml:"round,omitempty"`         // Decimal places
+	Precision *int   `json:"precision,omitempty" yaml:"precision,omitempty"` // Significant digits
+	AsString  bool   `json:"as_string,omitempty" yaml:"as_string,omitempty"` // Emit formatted string
+	Output    string `json:"output,omitempty" yaml:"output,omitempty"`       // decimal_string: exact raw × 10^-scale
+	Scale     *int   `json:"scale,omitempty" yaml:"scale,omitempty"`         // Decimal places of a decimal_string
+	// Encode: error when the input has no value for this field
+	Required bool `json:"required,omitempty" yaml:"required,omitempty"`
+	// Piecewise-linear interpolation of the raw value
+	Curve *CurveDef `json:"curve,omitempty" yaml:"curve,omitempty"`
+	// Fixed-point format (fixed/ufixed): Qm.n
+	IntBits  int `json:"int_bits,omitempty" yaml:"int_bits,omitempty"`
+	FracBits int `json:"frac_bits,omitempty" yaml:"frac_bits,omitempty"`
+	// Byte reordering before integer interpretation (swap16, swap32, nibble, custom)
+	ByteOrder    string `json:"byte_order,omitempty" yaml:"byte_order,omitempty"`
+	ByteOrderMap []int  `json:"byte_order_map,omitempty" yaml:"byte_order_map,omitempty"`
+	// Repeat: emit the single body field's value instead of an object per element
+	Flatten bool `json:"flatten,omitempty" yaml:"flatten,omitempty"`
+	// Keys not interpreted by the parser (vendor x- keys, tooling metadata)
+	Extensions map[string]any `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+
+	invalid      []string                // keys that failed to parse, reported as schema warnings
+	tlvPatterns  []tlvPattern            // Masked and range TLV case keys, narrowest first
+	tagBinds     []tagBinding            // TLV tag_bind for every case
+	tlvCaseBinds map[string][]tagBinding // TLV tag_bind per case key
+	formula      *compiledFormula        // Formula, compiled at parse time
+	onExpr       *compiledFormula        // Expression form of On, compiled at parse time
+	revisions    bool                    // Match built from revisions:, failing when no case matches
 }
 
 // Transform represents a single transformation stage.
@@ -183,27 +263,40 @@ type Case struct {
 	Fields  []Field `json:"fields,omitempty" yaml:"fields,omitempty"`
 }
 
-// ComputeDef represents a binary arithmetic operation.
+// ComputeDef represents a binary arithmetic operation, or with Fn an
+// aggregate over an array (see aggregate.go).
 type ComputeDef struct {
-	Op string `json:"op" yaml:"op"` // div, mul, add, sub
-	A  string `json:"a" yaml:"a"`   // First operand ($field or literal)
-	B  string `json:"b" yaml:"b"`   // Second operand ($field or literal)
+	Op   string `json:"op,omitempty" yaml:"op,omitempty"`     // div, mul, add, sub
+	A    string `json:"a,omitempty" yaml:"a,omitempty"`       // First operand ($field or literal)
+	B    string `json:"b,omitempty" yaml:"b,omitempty"`       // Second operand ($field or literal)
+	Fn   string `json:"fn,omitempty" yaml:"fn,omitempty"`     // sum, avg, min, max, count, last
+	Over string `json:"over,omitempty" yaml:"over,omitempty"` // Array to aggregate ($readings)
+	Pick string `json:"pick,omitempty" yaml:"pick,omitempty"` // Element member to aggregate, for arrays of objects
 }
 
 // GuardCondition represents a single guard condition.
 type GuardCondition struct {
-	Field string   `json:"field" yaml:"field"` // Field reference ($field_name)
+	Field string   `json:"field" yaml:"field"` // Field reference ($field_name), or x for the field's own value
 	Gt    *float64 `json:"gt,omitempty" yaml:"gt,omitempty"`
 	Gte   *float64 `json:"gte,omitempty" yaml:"gte,omitempty"`
 	Lt    *float64 `json:"lt,omitempty" yaml:"lt,omitempty"`
 	Lte   *float64 `json:"lte,omitempty" yaml:"lte,omitempty"`
 	Eq    *float64 `json:"eq,omitempty" yaml:"eq,omitempty"`
+	Ne    *float64 `json:"ne,omitempty" yaml:"ne,omitempty"`
+	In    []any    `json:"in,omitempty" yaml:"in,omitempty"`         // Value must be one of these (numbers or strings)
+	NotIn []any    `json:"not_in,omitempty" yaml:"not_in,omitempty"` // Value must be none of these
+	// Groups, combined with the tests above by AND
+	Any []GuardCondition `json:"any,omitempty" yaml:"any,omitempty"` // At least one must hold
+	All []GuardCondition `json:"all,omitempty" yaml:"all,omitempty"` // Every one must hold
+	Not *GuardCondition  `json:"not,omitempty" yaml:"not,omitempty"` // Must not hold
 }
 
 // GuardDef represents conditional evaluation with fallback.
+// Else is a float64 for numeric fallbacks, a string such as "invalid",
+// or nil (else: null) to omit the field from the output.
 type GuardDef struct {
 	When []GuardCondition `json:"when" yaml:"when"`
-	Else float64          `json:"else" yaml:"else"`
+	Else any              `json:"else" yaml:"else"`
 }
 
 // FlaggedGroup represents a single bitmask-gated field group.
@@ -223,6 +316,10 @@ type PortDef struct {
 	Direction   string  `json:"direction,omitempty" yaml:"direction,omitempty"`
 	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
 	Fields      []Field `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Replaces the schema header on this port when set; empty for none
+	Header []Field `json:"header,omitempty" yaml:"header,omitempty"`
+	// Port entry ("default" or an fPort) to decode with when these fields fail
+	Fallback string `json:"fallback,omitempty" yaml:"fallback,omitempty"`
 }
 
 // DefinitionDef represents a reusable field definition.
@@ -231,6 +328,12 @@ type DefinitionDef struct {
 }
 
 // Schema represents a payload schema definition.
+//
+// A Schema is not modified by decoding or encoding: once parsed or built,
+// Decode, Encode and the other read-only methods may be called from many
+// goroutines at once. Each call keeps its variables in its own
+// DecodeContext or EncodeContext. Patch, and assignments to the exported
+// fields, must not run concurrently with them.
 type Schema struct {
 	Name        string                    `json:"name,omitempty" yaml:"name,omitempty"`
 	Version     int                       `json:"version,omitempty" yaml:"version,omitempty"`
@@ -239,10 +342,24 @@ type Schema struct {
 	Header      []Field                   `json:"header,omitempty" yaml:"header,omitempty"`
 	Fields      []Field                   `json:"fields,omitempty" yaml:"fields,omitempty"`
 	Ports       map[string]*PortDef       `json:"-" yaml:"-"` // Port-based schema selection
+	Frames      *FramesDef                `json:"-" yaml:"-"` // Concatenated frames after the fields
+	Revisions   *RevisionsDef             `json:"-" yaml:"-"` // Field sets selected by a version field
 	Definitions map[string]*DefinitionDef `json:"-" yaml:"-"` // Reusable definitions
+	WASMRuntime WASMRuntime               `json:"-" yaml:"-"` // Executes wasm: field decoders
+	EmitAliases bool                      `json:"emit_aliases,omitempty" yaml:"emit_aliases,omitempty"`
+	Strict      bool                      `json:"strict,omitempty" yaml:"strict,omitempty"` // Treat schema warnings as errors
+	StrictTypes bool                      `json:"strict_types,omitempty" yaml:"strict_types,omitempty"` // Encode rejects input of the wrong type
+	EncodeOverflow string                 `json:"encode_overflow,omitempty" yaml:"encode_overflow,omitempty"` // Encode: error, clamp or wrap integers out of range
+	EncodeRounding string                 `json:"encode_rounding,omitempty" yaml:"encode_rounding,omitempty"` // Encode: half_away, half_even or truncate to integers
+	Warnings    []string                  `json:"-" yaml:"-"`                               // Parse-time schema warnings
+	Output      OutputStyle               `json:"-" yaml:"-"`                               // Output key casing and namespacing
+	DecodeLimits DecodeLimits             `json:"-" yaml:"-"`                               // Decode safety limits
+	Extensions  map[string]any            `json:"extensions,omitempty" yaml:"extensions,omitempty"` // Keys not interpreted by the parser
+	referenced  map[string]bool           // Names fields may read as variables, for DecodeLazy
 }
 
-// DecodeContext maintains state during decoding.
+// DecodeContext maintains state during decoding. It belongs to a single
+// decode and must not be shared between goroutines.
 type DecodeContext struct {
 	Data      []byte
 	Offset    int
@@ -250,13 +367,183 @@ type DecodeContext struct {
 	Variables map[string]any
 	Quality   map[string]string   // Quality status for fields with valid_range
 	Warnings  []string            // Quality warnings
+	WASM      WASMRuntime         // Runtime for wasm: fields (nil if not configured)
+	EmitAliases bool              // Also emit decoded values under field aliases
+	Definitions map[string]*DefinitionDef // Targets of $ref, resolvable at any depth
+	Limits      DecodeLimits              // Safety limits (zero values use the defaults)
+	ReceivedAt  time.Time                 // Transmit time for series timestamps (zero if unknown)
+	refDepth    int
+	depth       int                   // Nesting of field lists being decoded
+	scopes      []map[string]savedVar // Per field list: values to restore on exit
+	lastRead    []byte                // Bytes of the last readField, for IncludeFieldRaw
+	nested      int                   // Objects, repeats and frames entered, for decode profiles
+	lazy        *LazyResult           // Collects deferred computed fields (DecodeLazy)
+	result      map[string]any        // Map the top-level values go into (WithResult), or nil
+	hooks       []FieldHook           // Called after each named field (WithFieldHook)
+	path        []string              // Result keys of the field being decoded, for hooks
+}
+
+// Variable scopes (the `scope:` field key), the lifetime of a var:.
+const (
+	ScopeGlobal = "global" // Visible for the rest of the decode (default)
+	ScopeParent = "parent" // Visible until the enclosing field list ends
+	ScopeLocal  = "local"  // Visible until its own field list ends
+)
+
+// savedVar is the value a scoped variable shadowed.
+type savedVar struct {
+	value any
+	set   bool
+}
+
+// setVar stores field's var:, remembering the value it shadows when the
+// var is scoped so that the list where the scope ends can restore it.
+func (ctx *DecodeContext) setVar(field Field, value any) {
+	up := -1
+	switch field.Scope {
+	case ScopeLocal:
+		up = 0
+	case ScopeParent:
+		up = 1
+	}
+	if i := len(ctx.scopes) - 1 - up; up >= 0 && i >= 0 {
+		if ctx.scopes[i] == nil {
+			ctx.scopes[i] = map[string]savedVar{}
+		}
+		if _, saved := ctx.scopes[i][field.Var]; !saved {
+			prev, set := ctx.Variables[field.Var]
+			ctx.scopes[i][field.Var] = savedVar{prev, set}
+		}
+	}
+	ctx.Variables[field.Var] = value
+}
+
+// popScope ends the innermost field list, restoring the variables scoped
+// to it.
+func (ctx *DecodeContext) popScope() {
+	last := len(ctx.scopes) - 1
+	for name, prev := range ctx.scopes[last] {
+		if prev.set {
+			ctx.Variables[name] = prev.value
+		} else {
+			delete(ctx.Variables, name)
+		}
+	}
+	ctx.scopes = ctx.scopes[:last]
+}
+
+// Default decode safety limits.
+const (
+	DefaultMaxRepeat     = 1000
+	DefaultMaxTLVRecords = 1000
+	DefaultMaxDepth      = 64
+)
+
+// DecodeLimits bounds the work a decode may do, so a hostile or corrupt
+// payload (or schema) cannot run away. Zero values use the defaults. It
+// also selects raw-byte echoes in the output, for audit trails and for
+// re-decoding stored results after a schema fix.
+type DecodeLimits struct {
+	MaxRepeat       int  // Elements per repeat without its own max: (DefaultMaxRepeat)
+	MaxTLVRecords   int  // Records per TLV section (DefaultMaxTLVRecords)
+	MaxDepth        int  // Nesting of objects, matches, repeats and groups (DefaultMaxDepth)
+	ErrorOnLimit    bool // Fail instead of truncating a repeat or TLV section at its limit
+	IncludeRaw      bool // Add _raw, the hex of the whole payload
+	IncludeFieldRaw bool // Add _raw_fields to each object: field name -> hex of the bytes it read
+	IncludeSources  bool // Add _sources to merged TLV output: key -> {tag, offset} of its record
+	// NaN and ±Inf results, which JSON cannot carry: NonFiniteKeep (default),
+	// NonFiniteNull, NonFiniteError or NonFiniteSentinel with NonFiniteValue.
+	// A field's non_finite: overrides it.
+	NonFinite      string
+	NonFiniteValue float64
+	// Decode profile: keep only top-level keys matching Fields (all if
+	// empty) and not matching Omit. Computed fields left out are not
+	// evaluated.
+	Fields []string
+	Omit   []string
+}
+
+// Non-finite number policies (DecodeLimits.NonFinite, `non_finite:`).
+const (
+	NonFiniteKeep     = "keep"     // Emit NaN/Inf as decoded
+	NonFiniteNull     = "null"     // Emit null; quality non_finite
+	NonFiniteError    = "error"    // Fail the decode
+	NonFiniteSentinel = "sentinel" // Emit a fixed number; quality non_finite
+)
+
+// nonFinite applies the NaN/Inf policy of field (or the decode options)
+// to f and reports whether it replaced the value. A nil result means null.
+func (ctx *DecodeContext) nonFinite(field Field, f float64) (any, bool, error) {
+	policy, sentinel := ctx.Limits.NonFinite, ctx.Limits.NonFiniteValue
+	if field.NonFinite != "" {
+		policy = field.NonFinite
+		if field.NonFiniteValue != nil {
+			sentinel = *field.NonFiniteValue
+		}
+	}
+	switch policy {
+	case NonFiniteNull:
+		return nil, true, nil
+	case NonFiniteError:
+		return nil, false, fmt.Errorf("%s: non-finite value %v", field.Name, f)
+	case NonFiniteSentinel:
+		return sentinel, true, nil
+	}
+	return f, false, nil
+}
+
+// Keys of the raw-byte echoes selected by DecodeLimits.
+const (
+	RawKey       = "_raw"
+	RawFieldsKey = "_raw_fields"
+	SourcesKey   = "_sources"
+)
+
+func (o DecodeLimits) maxRepeat() int {
+	if o.MaxRepeat > 0 {
+		return o.MaxRepeat
+	}
+	return DefaultMaxRepeat
+}
+
+func (o DecodeLimits) maxTLVRecords() int {
+	if o.MaxTLVRecords > 0 {
+		return o.MaxTLVRecords
+	}
+	return DefaultMaxTLVRecords
+}
+
+func (o DecodeLimits) maxDepth() int {
+	if o.MaxDepth > 0 {
+		return o.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// limitReached handles a repeat or TLV section cut short at limit: an
+// error with ErrorOnLimit, otherwise a warning and truncation.
+func (ctx *DecodeContext) limitReached(name, what string, limit int) error {
+	msg := fmt.Sprintf("%s: truncated at %d %s", pathOr(name, "(unnamed)"), limit, what)
+	if ctx.Limits.ErrorOnLimit {
+		return fmt.Errorf("%s (limit reached)", msg)
+	}
+	ctx.Warnings = append(ctx.Warnings, msg)
+	return nil
 }
 
 // EncodeContext maintains state during encoding.
 type EncodeContext struct {
-	Buffer    []byte
-	Endian    string
-	Variables map[string]any
+	Buffer      []byte
+	Endian      string
+	Variables   map[string]any
+	Definitions map[string]*DefinitionDef // Targets of $ref
+	StrictTypes bool                      // Reject input of the wrong type (strict_types)
+	Overflow    string                    // Integers out of range: error, clamp or wrap (EncodeOverflow*)
+	Rounding    string                    // Floats to integers: half_away, half_even or truncate (EncodeRound*)
+	refDepth    int
+	path        []string // Objects and repeat elements being encoded, for errors
+	pendingBits map[int]byte // Bits field bytes beyond the buffer, by offset
+	flagScopes  []map[string]flagPatch // Per field list being encoded: flags field values
 }
 
 // NewEncodeContext creates a new encode context.
@@ -274,6 +561,9 @@ func NewEncodeContext(endian string) *EncodeContext {
 // Write appends bytes to the buffer.
 func (ctx *EncodeContext) Write(data []byte) {
 	ctx.Buffer = append(ctx.Buffer, data...)
+	if len(ctx.pendingBits) > 0 {
+		ctx.applyPendingBits()
+	}
 }
 
 // inferLengthFromType returns the byte length for shorthand types like u8, s16, etc.
@@ -344,6 +634,9 @@ func (ctx *DecodeContext) Remaining() int {
 
 // Read reads n bytes and advances the offset.
 func (ctx *DecodeContext) Read(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid length %d at offset %d", n, ctx.Offset)
+	}
 	if ctx.Offset+n > len(ctx.Data) {
 		return nil, fmt.Errorf("buffer underflow: need %d bytes at offset %d, but only %d remaining",
 			n, ctx.Offset, ctx.Remaining())
@@ -356,12 +649,77 @@ func (ctx *DecodeContext) Read(n int) ([]byte, error) {
 // Peek reads n bytes without advancing the offset.
 func (ctx *DecodeContext) Peek(n int, offset int) ([]byte, error) {
 	pos := ctx.Offset + offset
+	if n < 0 {
+		return nil, fmt.Errorf("invalid length %d at peek offset %d", n, pos)
+	}
 	if pos+n > len(ctx.Data) {
 		return nil, fmt.Errorf("buffer underflow at peek offset %d", pos)
 	}
 	return ctx.Data[pos : pos+n], nil
 }
 
+// consumeLength returns how many bytes a field advances the cursor after
+// reading n bytes at byte_offset.
+//
+// Byte-aligned numeric fields consume what they read. A field with a
+// byte_offset is a positional view and consumes nothing, as do bool/bits
+// fields, which read from a shared byte. An explicit consume: always wins
+// and is counted from the cursor, not from the end of the read.
+func consumeLength(field Field, n int) int {
+	if field.ConsumeSet {
+		return field.Consume
+	}
+	switch field.Type {
+	case TypeBool, TypeBoolLower, TypeBits, TypeBitsLower:
+		return 0
+	}
+	if field.ByteOffset != 0 {
+		return 0
+	}
+	return n
+}
+
+// isPositionalView reports whether a numeric field re-reads bytes via
+// byte_offset or an explicit consume instead of owning them sequentially.
+// A consume: equal to the field's own size still owns its bytes.
+func isPositionalView(field Field) bool {
+	switch field.Type {
+	case TypeBool, TypeBoolLower, TypeBits, TypeBitsLower:
+		return false
+	}
+	if field.ByteOffset != 0 {
+		return true
+	}
+	if !field.ConsumeSet {
+		return false
+	}
+	read, _, err := fieldReadSize(field)
+	return err != nil || field.Consume != read
+}
+
+// readField reads n bytes at the field's byte_offset relative to the cursor,
+// undoes any byte_order scrambling, then advances the cursor according to
+// consumeLength.
+func (ctx *DecodeContext) readField(field Field, n int) ([]byte, error) {
+	if field.ByteOffset < 0 && ctx.Offset+field.ByteOffset < 0 {
+		return nil, fmt.Errorf("%s: byte_offset %d before start of payload", field.Name, field.ByteOffset)
+	}
+	data, err := ctx.Peek(n, field.ByteOffset)
+	if err != nil {
+		return nil, err
+	}
+	ctx.lastRead = data
+	if data, err = unscrambleBytes(field, data); err != nil {
+		return nil, err
+	}
+	if consume := consumeLength(field, n); consume > 0 {
+		if _, err := ctx.Read(consume); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
 // extractModOrder extracts the YAML key order of modifier keys (add, mult, div)
 // from a yaml.Node mapping node.
 func extractModOrder(node *yaml.Node) []string {
@@ -418,6 +776,9 @@ func ParseSchema(data string) (*Schema, error) {
 			return nil, fmt.Errorf("failed to parse schema: %w", err)
 		}
 	}
+	if ext, ok := raw["extends"]; ok {
+		return nil, fmt.Errorf("schema extends %v: use ParseSchemaWithLoader or ParseSchemaFile", ext)
+	}
 
 	// Also parse into yaml.Node tree to extract YAML key ordering for modifiers
 	var rootNode yaml.Node
@@ -429,7 +790,7 @@ func ParseSchema(data string) (*Schema, error) {
 	if name, ok := raw["name"].(string); ok {
 		schema.Name = name
 	}
-	if version, ok := raw["version"].(int); ok {
+	if version, ok := intKey(raw, "version"); ok {
 		schema.Version = version
 	}
 	if endian, ok := raw["endian"].(string); ok {
@@ -438,6 +799,37 @@ func ParseSchema(data string) (*Schema, error) {
 	if schema.Endian == "" {
 		schema.Endian = "big"
 	}
+	if emit, ok := raw["emit_aliases"].(bool); ok {
+		schema.EmitAliases = emit
+	}
+	if strict, ok := raw["strict"].(bool); ok {
+		schema.Strict = strict
+	}
+	if strictTypes, ok := raw["strict_types"].(bool); ok {
+		schema.StrictTypes = strictTypes
+	}
+	overflow, err := parseEncodeOverflow(raw)
+	if err != nil {
+		return nil, err
+	}
+	schema.EncodeOverflow = overflow
+	if schema.EncodeRounding, err = parseEncodeRounding(raw); err != nil {
+		return nil, err
+	}
+	if keyStyle, ok := raw["key_style"].(string); ok {
+		schema.Output.KeyStyle = keyStyle
+	}
+	if namespace, ok := raw["namespace"].(string); ok {
+		schema.Output.Namespace = namespace
+	}
+	if mode, ok := raw["namespace_mode"].(string); ok {
+		schema.Output.NamespaceMode = mode
+	}
+	if mode, ok := raw["output_mode"].(string); ok {
+		schema.Output.Mode = mode
+	}
+	schema.Description, _ = raw["description"].(string)
+	schema.Extensions = collectExtensions(raw, knownSchemaKeys)
 
 	// Parse definitions
 	if defsRaw, ok := raw["definitions"].(map[string]any); ok {
@@ -478,23 +870,31 @@ func ParseSchema(data string) (*Schema, error) {
 	if fieldsRaw, ok := raw["fields"].([]any); ok {
 		schema.Fields = parseFieldsRawWithNodes(fieldsRaw, fieldNodes)
 	}
+	if headerRaw, ok := raw["header"].([]any); ok {
+		schema.Header = parseFieldsRawWithNodes(headerRaw, findFieldNodes(&rootNode, "header"))
+	}
+
+	if framesRaw, ok := raw["frames"].(map[string]any); ok {
+		fd, err := parseFramesDef(framesRaw)
+		if err != nil {
+			return nil, err
+		}
+		schema.Frames = fd
+	}
+	if revisionsRaw, ok := raw["revisions"].(map[string]any); ok {
+		rd, err := parseRevisionsDef(revisionsRaw)
+		if err != nil {
+			return nil, err
+		}
+		schema.Revisions = rd
+	}
 
 	// Parse ports (port-based schema selection)
 	if portsRaw, ok := raw["ports"].(map[string]any); ok {
 		schema.Ports = make(map[string]*PortDef)
 		for portKey, portVal := range portsRaw {
 			if portMap, ok := portVal.(map[string]any); ok {
-				pd := &PortDef{}
-				if dir, ok := portMap["direction"].(string); ok {
-					pd.Direction = dir
-				}
-				if desc, ok := portMap["description"].(string); ok {
-					pd.Description = desc
-				}
-				if pFields, ok := portMap["fields"].([]any); ok {
-					pd.Fields = parseFieldsRaw(pFields)
-				}
-				schema.Ports[portKey] = pd
+				addPortDef(schema.Ports, portKey, portMap)
 			}
 		}
 	}
@@ -504,17 +904,7 @@ func ParseSchema(data string) (*Schema, error) {
 		for portKey, portVal := range portsRaw {
 			key := fmt.Sprintf("%v", portKey)
 			if portMap, ok := portVal.(map[string]any); ok {
-				pd := &PortDef{}
-				if dir, ok := portMap["direction"].(string); ok {
-					pd.Direction = dir
-				}
-				if desc, ok := portMap["description"].(string); ok {
-					pd.Description = desc
-				}
-				if pFields, ok := portMap["fields"].([]any); ok {
-					pd.Fields = parseFieldsRaw(pFields)
-				}
-				schema.Ports[key] = pd
+				addPortDef(schema.Ports, key, portMap)
 			}
 			if portMap, ok := portVal.(map[any]any); ok {
 				pd := &PortDef{}
@@ -527,14 +917,73 @@ func ParseSchema(data string) (*Schema, error) {
 				if pFields, ok := portMap["fields"].([]any); ok {
 					pd.Fields = parseFieldsRaw(pFields)
 				}
+				if pHeader, ok := portMap["header"].([]any); ok {
+					pd.Header = append([]Field{}, parseFieldsRaw(pHeader)...)
+				}
+				if fallback, ok := portMap["fallback"]; ok && fallback != nil {
+					pd.Fallback = fmt.Sprint(fallback)
+				}
 				schema.Ports[key] = pd
 			}
 		}
 	}
 
+	if err := schema.finish(); err != nil {
+		return nil, err
+	}
 	return schema, nil
 }
 
+// blockEndian returns the byte order of field and everything inside it:
+// its own endian: (on an inline match: or tlv:, the endian: given there),
+// else the one inherited from the enclosing block.
+func blockEndian(field Field, inherited string) string {
+	switch {
+	case field.Endian != "":
+		return field.Endian
+	case field.MatchInline != nil && field.MatchInline.Endian != "":
+		return field.MatchInline.Endian
+	case field.TLVInline != nil && field.TLVInline.Endian != "":
+		return field.TLVInline.Endian
+	}
+	return inherited
+}
+
+// parsePortDef parses one entry of the ports: map.
+func parsePortDef(portMap map[string]any) *PortDef {
+	pd := &PortDef{}
+	if dir, ok := portMap["direction"].(string); ok {
+		pd.Direction = dir
+	}
+	if desc, ok := portMap["description"].(string); ok {
+		pd.Description = desc
+	}
+	if pFields, ok := portMap["fields"].([]any); ok {
+		pd.Fields = parseFieldsRaw(pFields)
+	}
+	// Non-nil even when empty: header: [] drops the schema header
+	if pHeader, ok := portMap["header"].([]any); ok {
+		pd.Header = append([]Field{}, parseFieldsRaw(pHeader)...)
+	}
+	if fallback, ok := portMap["fallback"]; ok && fallback != nil {
+		pd.Fallback = fmt.Sprint(fallback)
+	}
+	return pd
+}
+
+// finish collects schema warnings, failing a strict schema that has any.
+func (s *Schema) finish() error {
+	s.referenced = referencedNames(s)
+	s.Warnings = append(s.CheckOutputNames(), s.checkDeprecated()...)
+	s.Warnings = append(s.Warnings, s.checkInvalid()...)
+	s.Warnings = append(s.Warnings, s.checkPortKeys()...)
+	s.Warnings = append(s.Warnings, s.checkRevisions()...)
+	if s.Strict && len(s.Warnings) > 0 {
+		return fmt.Errorf("schema '%s': %s", s.Name, strings.Join(s.Warnings, "; "))
+	}
+	return nil
+}
+
 func parseFieldsRaw(fieldsRaw []any) []Field {
 	return parseFieldsRawWithNodes(fieldsRaw, nil)
 }
@@ -550,46 +999,36 @@ func parseFieldsRawWithNodes(fieldsRaw []any, nodes []*yaml.Node) []Field {
 			fields = append(fields, parseFieldMap(fm, node))
 		}
 	}
+	checkRestLast(fields)
 	return fields
 }
 
 func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
+	if expanded, ok := expandSemantic(fm); ok {
+		return parseFieldMap(expanded, nil)
+	}
 	f := Field{}
 	
 	if name, ok := fm["name"].(string); ok {
 		f.Name = name
 	}
+	f.Extensions = collectExtensions(fm, knownFieldKeys)
+	f.Description, _ = fm["description"].(string)
+	f.DisplayName, _ = fm["display_name"].(string)
+	f.Example = fm["example"]
 	if typ, ok := fm["type"].(string); ok {
 		f.Type = FieldType(typ)
 	}
-	if length, ok := fm["length"].(int); ok {
+	if length, ok := intKey(fm, "length"); ok {
 		f.Length = length
 	}
-	if length, ok := fm["length"].(float64); ok {
-		f.Length = int(length)
-	}
 	if endian, ok := fm["endian"].(string); ok {
 		f.Endian = endian
 	}
 	// Handle modifiers - could be float64 or int
-	if mult, ok := fm["mult"].(float64); ok {
-		f.Mult = &mult
-	} else if mult, ok := fm["mult"].(int); ok {
-		m := float64(mult)
-		f.Mult = &m
-	}
-	if div, ok := fm["div"].(float64); ok {
-		f.Div = &div
-	} else if div, ok := fm["div"].(int); ok {
-		d := float64(div)
-		f.Div = &d
-	}
-	if add, ok := fm["add"].(float64); ok {
-		f.Add = &add
-	} else if add, ok := fm["add"].(int); ok {
-		a := float64(add)
-		f.Add = &a
-	}
+	f.Mult = numberPtr(fm, "mult")
+	f.Div = numberPtr(fm, "div")
+	f.Add = numberPtr(fm, "add")
 	// Extract modifier key order from YAML node
 	f.ModOrder = extractModOrder(node)
 
@@ -598,30 +1037,10 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		for _, tRaw := range transformRaw {
 			if tm, ok := tRaw.(map[string]any); ok {
 				t := Transform{}
-				if add, ok := tm["add"].(float64); ok {
-					t.Add = &add
-				} else if add, ok := tm["add"].(int); ok {
-					a := float64(add)
-					t.Add = &a
-				}
-				if sub, ok := tm["sub"].(float64); ok {
-					t.Sub = &sub
-				} else if sub, ok := tm["sub"].(int); ok {
-					s := float64(sub)
-					t.Sub = &s
-				}
-				if mult, ok := tm["mult"].(float64); ok {
-					t.Mult = &mult
-				} else if mult, ok := tm["mult"].(int); ok {
-					m := float64(mult)
-					t.Mult = &m
-				}
-				if div, ok := tm["div"].(float64); ok {
-					t.Div = &div
-				} else if div, ok := tm["div"].(int); ok {
-					d := float64(div)
-					t.Div = &d
-				}
+				t.Add = numberPtr(tm, "add")
+				t.Sub = numberPtr(tm, "sub")
+				t.Mult = numberPtr(tm, "mult")
+				t.Div = numberPtr(tm, "div")
 				f.Transform = append(f.Transform, t)
 			}
 		}
@@ -632,24 +1051,9 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		for _, mRaw := range modifiersRaw {
 			if mm, ok := mRaw.(map[string]any); ok {
 				t := Transform{}
-				if add, ok := mm["add"].(float64); ok {
-					t.Add = &add
-				} else if add, ok := mm["add"].(int); ok {
-					a := float64(add)
-					t.Add = &a
-				}
-				if mult, ok := mm["mult"].(float64); ok {
-					t.Mult = &mult
-				} else if mult, ok := mm["mult"].(int); ok {
-					m := float64(mult)
-					t.Mult = &m
-				}
-				if div, ok := mm["div"].(float64); ok {
-					t.Div = &div
-				} else if div, ok := mm["div"].(int); ok {
-					d := float64(div)
-					t.Div = &d
-				}
+				t.Add = numberPtr(mm, "add")
+				t.Mult = numberPtr(mm, "mult")
+				t.Div = numberPtr(mm, "div")
 				f.Modifiers = append(f.Modifiers, t)
 			}
 		}
@@ -658,8 +1062,49 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 	if varName, ok := fm["var"].(string); ok {
 		f.Var = varName
 	}
+	if scope, ok := fm["scope"].(string); ok {
+		switch {
+		case scope != ScopeGlobal && scope != ScopeParent && scope != ScopeLocal:
+			f.invalid = append(f.invalid, fmt.Sprintf("scope: expected global, parent or local, got %q", scope))
+		case f.Var == "":
+			f.invalid = append(f.invalid, "scope: applies to var:, which is not set")
+		default:
+			f.Scope = scope
+		}
+	}
+	if aliasesRaw, ok := fm["aliases"].([]any); ok {
+		for _, a := range aliasesRaw {
+			if alias, ok := a.(string); ok {
+				f.Aliases = append(f.Aliases, alias)
+			}
+		}
+	}
+	if deprecated, ok := fm["deprecated"].(bool); ok {
+		f.Deprecated = deprecated
+	}
+	if round, ok := intKey(fm, "round"); ok {
+		f.Round = &round
+	}
+	if precision, ok := intKey(fm, "precision"); ok {
+		f.Precision = &precision
+	}
+	if asString, ok := fm["as_string"].(bool); ok {
+		f.AsString = asString
+	}
+	if output, ok := fm["output"].(string); ok {
+		f.Output = output
+	}
+	if scale, ok := intKey(fm, "scale"); ok {
+		f.Scale = &scale
+	}
+	if required, ok := fm["required"].(bool); ok {
+		f.Required = required
+	}
 	if on, ok := fm["on"].(string); ok {
 		f.On = on
+		if !bareVarPattern.MatchString(on) {
+			f.onExpr = compileFieldFormula("on", on, &f.invalid)
+		}
 	}
 	
 	// Lookup table - handle both string and int keys
@@ -711,9 +1156,9 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		for _, cr := range casesRaw {
 			if cm, ok := cr.(map[string]any); ok {
 				c := Case{}
-				c.Case = cm["case"]
+				c.Case = wholeNumber(cm["case"])
 				if c.Case == nil {
-					c.Case = cm["match"]
+					c.Case = wholeNumber(cm["match"])
 				}
 				if def, ok := cm["default"].(bool); ok {
 					c.Default = def
@@ -727,18 +1172,12 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 	}
 	
 	// TLV-specific fields
-	if tagSize, ok := fm["tag_size"].(int); ok {
+	if tagSize, ok := intKey(fm, "tag_size"); ok {
 		f.TagSize = tagSize
 	}
-	if tagSize, ok := fm["tag_size"].(float64); ok {
-		f.TagSize = int(tagSize)
-	}
-	if lengthSize, ok := fm["length_size"].(int); ok {
+	if lengthSize, ok := intKey(fm, "length_size"); ok {
 		f.LengthSize = lengthSize
 	}
-	if lengthSize, ok := fm["length_size"].(float64); ok {
-		f.LengthSize = int(lengthSize)
-	}
 	if tagFieldsRaw, ok := fm["tag_fields"].([]any); ok {
 		f.TagFields = parseFieldsRaw(tagFieldsRaw)
 	}
@@ -748,8 +1187,38 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 	if merge, ok := fm["merge"].(bool); ok {
 		f.Merge = &merge
 	}
+	if tmpl, ok := fm["name_template"].(string); ok {
+		f.NameTemplate = tmpl
+	}
+	if raw, ok := fm["tag_bind"]; ok {
+		binds, msgs := parseTagBind(raw, f.TagFields)
+		f.tagBinds = binds
+		f.invalid = append(f.invalid, msgs...)
+	}
 	if unknown, ok := fm["unknown"].(string); ok {
 		f.Unknown = unknown
+	} else if v, present := fm["unknown"]; present && v == nil {
+		f.Unknown = UnknownNull // `unknown: null` parses as a YAML null
+	}
+	if v, present := fm["non_finite"]; present {
+		switch nf := v.(type) {
+		case nil:
+			f.NonFinite = NonFiniteNull
+		case string:
+			switch nf {
+			case NonFiniteKeep, NonFiniteNull, NonFiniteError:
+				f.NonFinite = nf
+			default:
+				f.invalid = append(f.invalid, fmt.Sprintf("non_finite: expected keep, null, error or a number, got %q", nf))
+			}
+		default:
+			if n, ok := toFloat64(nf); ok && !math.IsNaN(n) && !math.IsInf(n, 0) {
+				f.NonFinite = NonFiniteSentinel
+				f.NonFiniteValue = &n
+			} else {
+				f.invalid = append(f.invalid, fmt.Sprintf("non_finite: expected keep, null, error or a number, got %v", nf))
+			}
+		}
 	}
 
 	// Repeat/array fields
@@ -762,15 +1231,23 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 	if until, ok := fm["until"].(string); ok {
 		f.Until = until
 	}
-	if max, ok := fm["max"].(int); ok {
+	if flatten, ok := fm["flatten"].(bool); ok {
+		f.Flatten = flatten
+		if _, err := flattenName(f); flatten && err != nil {
+			f.invalid = append(f.invalid, err.Error())
+		}
+	}
+	if max, ok := intKey(fm, "max"); ok {
 		f.Max = max
-	} else if max, ok := fm["max"].(float64); ok {
-		f.Max = int(max)
 	}
-	if min, ok := fm["min"].(int); ok {
+	if min, ok := intKey(fm, "min"); ok {
 		f.Min = min
-	} else if min, ok := fm["min"].(float64); ok {
-		f.Min = int(min)
+	}
+	if interval, ok := fm["interval"]; ok {
+		f.Interval = interval
+	}
+	if order, ok := fm["order"].(string); ok {
+		f.SeriesOrder = order
 	}
 
 	// Bytes format options
@@ -782,15 +1259,23 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 	}
 
 	// Bool field options
-	if bit, ok := fm["bit"].(int); ok {
+	if bit, ok := intKey(fm, "bit"); ok {
 		f.Bit = bit
-	} else if bit, ok := fm["bit"].(float64); ok {
-		f.Bit = int(bit)
 	}
-	if consume, ok := fm["consume"].(int); ok {
+	if consume, ok := intKey(fm, "consume"); ok {
 		f.Consume = consume
-	} else if consume, ok := fm["consume"].(float64); ok {
-		f.Consume = int(consume)
+		f.ConsumeSet = true
+	}
+
+	// Positional reads (relative to the cursor)
+	if byteOffset, ok := intKey(fm, "byte_offset"); ok {
+		f.ByteOffset = byteOffset
+	}
+	if bitOffset, ok := intKey(fm, "bit_offset"); ok {
+		f.BitOffset = bitOffset
+	}
+	if bits, ok := intKey(fm, "bits"); ok {
+		f.Bits = bits
 	}
 
 	// Enum field options
@@ -825,36 +1310,33 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		}
 	}
 
-	// Byte group (inline grouped bitfields) - array format
-	if bgRaw, ok := fm["byte_group"].([]any); ok {
-		f.ByteGroup = parseFieldsRaw(bgRaw)
-	}
-	// Byte group - Option B format: `- byte_group: { size: N, fields: [...] }`
-	if bgMap, ok := fm["byte_group"].(map[string]any); ok {
-		if bgSize, ok := bgMap["size"].(int); ok {
-			f.Size = bgSize
-		} else if bgSize, ok := bgMap["size"].(float64); ok {
-			f.Size = int(bgSize)
-		}
-		if bgFields, ok := bgMap["fields"].([]any); ok {
-			f.ByteGroup = parseFieldsRaw(bgFields)
+	// Byte group (inline grouped bitfields); `bitfield:` is an alias
+	for _, bgKey := range []string{"byte_group", "bitfield"} {
+		// Array format
+		if bgRaw, ok := fm[bgKey].([]any); ok {
+			f.ByteGroup = parseFieldsRaw(bgRaw)
 		}
-	}
-	// Also handle map[any]any for YAML parsing quirks
-	if bgMap, ok := fm["byte_group"].(map[any]any); ok {
-		if bgSize, ok := bgMap["size"].(int); ok {
-			f.Size = bgSize
-		} else if bgSize, ok := bgMap["size"].(float64); ok {
-			f.Size = int(bgSize)
+		// Option B format: `- byte_group: { size: N, fields: [...] }`
+		if bgMap, ok := fm[bgKey].(map[string]any); ok {
+			if bgSize, ok := intKey(bgMap, "size"); ok {
+				f.Size = bgSize
+			}
+			if bgFields, ok := bgMap["fields"].([]any); ok {
+				f.ByteGroup = parseFieldsRaw(bgFields)
+			}
 		}
-		if bgFields, ok := bgMap["fields"].([]any); ok {
-			f.ByteGroup = parseFieldsRaw(bgFields)
+		// Also handle map[any]any for YAML parsing quirks
+		if bgMap, ok := fm[bgKey].(map[any]any); ok {
+			if bgSize, ok := toWholeInt(bgMap["size"]); ok {
+				f.Size = bgSize
+			}
+			if bgFields, ok := bgMap["fields"].([]any); ok {
+				f.ByteGroup = parseFieldsRaw(bgFields)
+			}
 		}
 	}
-	if size, ok := fm["size"].(int); ok {
+	if size, ok := intKey(fm, "size"); ok {
 		f.Size = size
-	} else if size, ok := fm["size"].(float64); ok {
-		f.Size = int(size)
 	}
 
 	// $ref for definitions
@@ -870,7 +1352,26 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 				if caseFieldsRaw, ok := value.([]any); ok {
 					f.TLVCases[key] = parseFieldsRaw(caseFieldsRaw)
 				}
+				// Map form: {tag_bind: {...}, fields: [...]}
+				if caseMap, ok := value.(map[string]any); ok {
+					caseFieldsRaw, _ := caseMap["fields"].([]any)
+					f.TLVCases[key] = parseFieldsRaw(caseFieldsRaw)
+					if raw, ok := caseMap["tag_bind"]; ok {
+						binds, msgs := parseTagBind(raw, f.TagFields)
+						if f.tlvCaseBinds == nil {
+							f.tlvCaseBinds = map[string][]tagBinding{}
+						}
+						f.tlvCaseBinds[key] = binds
+						f.invalid = append(f.invalid, msgs...)
+					}
+				}
 			}
+			f.invalid = append(f.invalid, compileTLVCases(&f)...)
+		}
+	}
+	if f.NameTemplate != "" {
+		if err := checkNameTemplate(f.NameTemplate, f.TagFields, tagBindNames(&f)); err != nil {
+			f.invalid = append(f.invalid, err.Error())
 		}
 	}
 
@@ -892,6 +1393,7 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 	// Formula (deprecated)
 	if formula, ok := fm["formula"].(string); ok {
 		f.Formula = formula
+		f.formula = compileFieldFormula("formula", formula, &f.invalid)
 	}
 
 	// Semantic fields
@@ -902,15 +1404,39 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 			}
 		}
 	}
-	if res, ok := fm["resolution"].(float64); ok {
-		f.Resolution = &res
-	} else if res, ok := fm["resolution"].(int); ok {
-		r := float64(res)
-		f.Resolution = &r
+	f.Resolution = numberPtr(fm, "resolution")
+	f.Deadband = numberPtr(fm, "deadband")
+	if f.Deadband != nil && *f.Deadband < 0 {
+		f.invalid = append(f.invalid, "deadband: must not be negative")
+	}
+	f.ReportMinInterval = numberPtr(fm, "report_min_interval")
+	if f.ReportMinInterval != nil && *f.ReportMinInterval < 0 {
+		f.invalid = append(f.invalid, "report_min_interval: must be a non-negative number of seconds")
+	}
+	if invalid, ok := fm["invalid"]; ok {
+		list, isList := invalid.([]any)
+		if !isList {
+			list = []any{invalid}
+		}
+		for _, v := range list {
+			if vf, ok := toFloat64(v); ok {
+				f.Invalid = append(f.Invalid, vf)
+			} else {
+				f.invalid = append(f.invalid, fmt.Sprintf("invalid: expected a number or list of numbers, got %v", v))
+			}
+		}
 	}
 	if unece, ok := fm["unece"].(string); ok {
 		f.UNECE = unece
 	}
+	if role, ok := fm["role"].(string); ok {
+		switch role {
+		case RoleTag, RoleField, RoleTimestamp:
+			f.Role = role
+		default:
+			f.invalid = append(f.invalid, fmt.Sprintf("role: expected tag, field or timestamp, got %q", role))
+		}
+	}
 
 	// Phase 2: ref (field reference)
 	if ref, ok := fm["ref"].(string); ok {
@@ -926,6 +1452,43 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		}
 	}
 
+	if isFixedType(f.Type) {
+		parseFixedFormat(fm, &f)
+	}
+	if byteOrder, ok := fm["byte_order"]; ok {
+		parseByteOrder(byteOrder, &f)
+	}
+	if invRaw, ok := fm["encode_inverse"]; ok {
+		if inv, err := parseEncodeInverse(invRaw); err != nil {
+			f.invalid = append(f.invalid, err.Error())
+		} else {
+			f.EncodeInverse = inv
+			if msg := checkEncodeInverse(f); msg != "" {
+				f.invalid = append(f.invalid, msg)
+			}
+		}
+	}
+
+	f.invalid = append(f.invalid, parseScaleBy(fm, &f)...)
+	f.invalid = append(f.invalid, parseTextOptions(fm, &f)...)
+	f.invalid = append(f.invalid, parseRestLength(fm, &f)...)
+	f.invalid = append(f.invalid, parseExpect(fm, &f)...)
+	f.invalid = append(f.invalid, parseMagic(fm, &f)...)
+
+	if alsoRaw, ok := fm["also_emit"]; ok {
+		var msgs []string
+		f.AlsoEmit, msgs = parseAlsoEmit(alsoRaw)
+		f.invalid = append(f.invalid, msgs...)
+	}
+
+	if curveRaw, ok := fm["curve"]; ok {
+		if cd, err := parseCurveDef(curveRaw); err != nil {
+			f.invalid = append(f.invalid, err.Error())
+		} else {
+			f.Curve = cd
+		}
+	}
+
 	// Phase 2: compute (binary operation)
 	if compRaw, ok := fm["compute"].(map[string]any); ok {
 		cd := &ComputeDef{}
@@ -934,69 +1497,35 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		}
 		if a, ok := compRaw["a"].(string); ok {
 			cd.A = a
-		} else if a, ok := compRaw["a"].(float64); ok {
+		} else if a, ok := numberKey(compRaw, "a"); ok {
 			cd.A = strconv.FormatFloat(a, 'f', -1, 64)
-		} else if a, ok := compRaw["a"].(int); ok {
-			cd.A = strconv.Itoa(a)
 		}
 		if b, ok := compRaw["b"].(string); ok {
 			cd.B = b
-		} else if b, ok := compRaw["b"].(float64); ok {
+		} else if b, ok := numberKey(compRaw, "b"); ok {
 			cd.B = strconv.FormatFloat(b, 'f', -1, 64)
-		} else if b, ok := compRaw["b"].(int); ok {
-			cd.B = strconv.Itoa(b)
+		}
+		cd.Fn, _ = compRaw["fn"].(string)
+		cd.Over, _ = compRaw["over"].(string)
+		cd.Pick, _ = compRaw["pick"].(string)
+		if msg := checkAggregate(cd); msg != "" {
+			f.invalid = append(f.invalid, msg)
 		}
 		f.Compute = cd
 	}
 
 	// Phase 2: guard (conditional evaluation)
 	if guardRaw, ok := fm["guard"].(map[string]any); ok {
-		gd := &GuardDef{}
-		if elseVal, ok := guardRaw["else"].(float64); ok {
-			gd.Else = elseVal
-		} else if elseVal, ok := guardRaw["else"].(int); ok {
-			gd.Else = float64(elseVal)
+		gd := &GuardDef{Else: float64(0)}
+		if elseRaw, present := guardRaw["else"]; present {
+			if elseVal, ok := toFloat64(elseRaw); ok {
+				gd.Else = elseVal
+			} else {
+				gd.Else = elseRaw // string, bool, or nil (omit field)
+			}
 		}
 		if whenRaw, ok := guardRaw["when"].([]any); ok {
-			for _, w := range whenRaw {
-				if wm, ok := w.(map[string]any); ok {
-					gc := GuardCondition{}
-					if field, ok := wm["field"].(string); ok {
-						gc.Field = field
-					}
-					if gt, ok := wm["gt"].(float64); ok {
-						gc.Gt = &gt
-					} else if gt, ok := wm["gt"].(int); ok {
-						gtf := float64(gt)
-						gc.Gt = &gtf
-					}
-					if gte, ok := wm["gte"].(float64); ok {
-						gc.Gte = &gte
-					} else if gte, ok := wm["gte"].(int); ok {
-						gtef := float64(gte)
-						gc.Gte = &gtef
-					}
-					if lt, ok := wm["lt"].(float64); ok {
-						gc.Lt = &lt
-					} else if lt, ok := wm["lt"].(int); ok {
-						ltf := float64(lt)
-						gc.Lt = &ltf
-					}
-					if lte, ok := wm["lte"].(float64); ok {
-						gc.Lte = &lte
-					} else if lte, ok := wm["lte"].(int); ok {
-						ltef := float64(lte)
-						gc.Lte = &ltef
-					}
-					if eq, ok := wm["eq"].(float64); ok {
-						gc.Eq = &eq
-					} else if eq, ok := wm["eq"].(int); ok {
-						eqf := float64(eq)
-						gc.Eq = &eqf
-					}
-					gd.When = append(gd.When, gc)
-				}
-			}
+			gd.When = parseGuardConditions(whenRaw)
 		}
 		f.Guard = gd
 	}
@@ -1011,10 +1540,8 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 			for _, gRaw := range groupsRaw {
 				if gMap, ok := gRaw.(map[string]any); ok {
 					g := FlaggedGroup{}
-					if bit, ok := gMap["bit"].(int); ok {
+					if bit, ok := intKey(gMap, "bit"); ok {
 						g.Bit = bit
-					} else if bit, ok := gMap["bit"].(float64); ok {
-						g.Bit = int(bit)
 					}
 					if gFields, ok := gMap["fields"].([]any); ok {
 						g.Fields = parseFieldsRaw(gFields)
@@ -1028,6 +1555,15 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 
 	// TLV inline (for port-based schemas: `- tlv: { ... }`)
 	if tlvRaw, ok := fm["tlv"].(map[string]any); ok {
+		if _, typed := tlvRaw["type"]; !typed {
+			// The type must be known while parsing so cases: is read as a tag map
+			withType := make(map[string]any, len(tlvRaw)+1)
+			for k, v := range tlvRaw {
+				withType[k] = v
+			}
+			withType["type"] = "tlv"
+			tlvRaw = withType
+		}
 		tlvField := parseFieldMap(tlvRaw, nil)
 		tlvField.Type = "tlv"
 		f.TLVInline = &tlvField
@@ -1038,6 +1574,12 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		matchField := Field{Type: TypeMatch}
 		if fieldRef, ok := matchRaw["field"].(string); ok {
 			matchField.On = fieldRef
+			if !bareVarPattern.MatchString(fieldRef) {
+				matchField.onExpr = compileFieldFormula("match.field", fieldRef, &f.invalid)
+			}
+		}
+		if endian, ok := matchRaw["endian"].(string); ok {
+			matchField.Endian = endian
 		}
 		if casesRaw, ok := matchRaw["cases"].(map[string]any); ok {
 			for caseKey, caseVal := range casesRaw {
@@ -1081,6 +1623,36 @@ func parseFieldMap(fm map[string]any, node *yaml.Node) Field {
 		}
 		f.MatchInline = &matchField
 	}
+
+	// WASM-hosted decoder: `- wasm: { module: vendor.wasm, fn: decode_port_5 }`
+	if wasmRaw, ok := fm["wasm"].(map[string]any); ok {
+		f.WASM = parseWASMDef(wasmRaw)
+	}
+
+	// unknown: on enum/lookup fields is a value policy (TLV has its own modes)
+	if f.Unknown != "" && (f.Lookup != nil || f.Type == TypeEnum || f.Type == TypeEnumLower) {
+		switch f.Unknown {
+		case UnknownRaw, UnknownNull, UnknownError:
+		default:
+			if _, ok := unknownLabelFormat(f.Unknown); !ok {
+				f.invalid = append(f.invalid, fmt.Sprintf(
+					`unknown: expected raw, null, error or label("fmt"), got %q`, f.Unknown))
+			}
+		}
+	}
+
+	if msg := checkDecimalOutput(&f); msg != "" {
+		f.invalid = append(f.invalid, msg)
+	}
+	if msg := checkSeries(f); msg != "" {
+		f.invalid = append(f.invalid, msg)
+	}
+	if msg := checkVector3(f); msg != "" {
+		f.invalid = append(f.invalid, msg)
+	}
+	if msg := checkBits(f); msg != "" {
+		f.invalid = append(f.invalid, msg)
+	}
 	
 	return f
 }
@@ -1091,16 +1663,19 @@ type FieldMetadata struct {
 	ValidRange  []float64 `json:"valid_range,omitempty"`
 	Resolution  *float64  `json:"resolution,omitempty"`
 	UNECE       string    `json:"unece,omitempty"`
+	Role        string    `json:"role,omitempty"`
 	Description string    `json:"description,omitempty"`
 	IPSO        int       `json:"ipso,omitempty"`
 	SenMLUnit   string    `json:"senml_unit,omitempty"`
+	Aliases     []string  `json:"aliases,omitempty"`
+	Deprecated  bool      `json:"deprecated,omitempty"`
 }
 
 // GetFieldMetadata returns semantic metadata for schema fields.
 // If fieldName is empty, returns metadata for all fields.
 func (s *Schema) GetFieldMetadata(fieldName string) map[string]FieldMetadata {
 	result := make(map[string]FieldMetadata)
-	collectFieldMetadata(s.Fields, result)
+	collectFieldMetadata(s.topFields(), result)
 	
 	if fieldName != "" {
 		if meta, ok := result[fieldName]; ok {
@@ -1121,12 +1696,17 @@ func collectFieldMetadata(fields []Field, result map[string]FieldMetadata) {
 			ValidRange:  f.ValidRange,
 			Resolution:  f.Resolution,
 			UNECE:       f.UNECE,
+			Role:        f.Role,
+			Description: f.Description,
+			Aliases:     f.Aliases,
+			Deprecated:  f.Deprecated,
 		}
 		
 		// These would need to be added to Field struct if needed
 		// For now, just include the semantic fields
 		
-		if len(meta.ValidRange) > 0 || meta.Resolution != nil || meta.UNECE != "" {
+		if len(meta.ValidRange) > 0 || meta.Resolution != nil || meta.UNECE != "" ||
+			meta.Role != "" || meta.Description != "" || len(meta.Aliases) > 0 || meta.Deprecated {
 			result[f.Name] = meta
 		}
 		
@@ -1140,91 +1720,107 @@ func collectFieldMetadata(fields []Field, result map[string]FieldMetadata) {
 	}
 }
 
-// ResolveFields returns the field set for a given fPort.
-// If the schema uses ports, selects the matching port entry.
-// Otherwise returns the top-level fields.
+// ResolveFields returns the field set for decoding an uplink on fPort.
+// If the schema uses ports, selects the matching port entry ("N:up" before
+// "N"). Otherwise returns the top-level fields.
 func (s *Schema) ResolveFields(fPort int) ([]Field, error) {
-	if s.Ports == nil {
-		return s.Fields, nil
-	}
+	return s.resolvePortFields(fPort, PortUp)
+}
 
-	portKey := strconv.Itoa(fPort)
-	if pd, ok := s.Ports[portKey]; ok {
-		return pd.Fields, nil
-	}
-	if pd, ok := s.Ports["default"]; ok {
-		return pd.Fields, nil
-	}
-	return nil, fmt.Errorf("no port definition for fPort %d and no default in schema '%s'", fPort, s.Name)
+// ResolveHeader returns the header decoded before the fields of an uplink
+// on fPort: the port entry's own header: if it has one, else the schema's.
+func (s *Schema) ResolveHeader(fPort int) []Field {
+	pd, _ := s.resolvePort(fPort, PortUp)
+	return s.portHeader(pd)
 }
 
-// DecodeWithPort decodes binary data using the schema, selecting fields by fPort.
+// DecodeWithPort decodes binary data using the schema, selecting fields by
+// fPort. It is Decode with WithPort.
 func (s *Schema) DecodeWithPort(data []byte, fPort int) (map[string]any, error) {
-	fields, err := s.ResolveFields(fPort)
+	return s.Decode(data, WithPort(fPort))
+}
+
+// DecodeInfo describes how a payload was decoded, for callers that store
+// results or need to notice payloads the schema does not fully cover.
+type DecodeInfo struct {
+	Schema        string   // Schema name
+	Version       int      // Schema version
+	FPort         int      // Port the fields were selected by
+	BytesConsumed int      // Payload bytes read
+	TrailingBytes int      // Bytes left after the last field (often a schema mismatch)
+	Warnings      []string // Quality warnings and limits reached while decoding
+	Fallbacks     int      // fallback: steps taken before a port entry decoded the payload
+}
+
+// DecodeWithInfo is DecodeWithPort that also reports a DecodeInfo.
+func (s *Schema) DecodeWithInfo(data []byte, fPort int) (map[string]any, *DecodeInfo, error) {
+	var info DecodeInfo
+	result, err := s.Decode(data, WithPort(fPort), WithInfo(&info))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return result, &info, nil
+}
+
+// DecodeAt is DecodeWithPort for a payload received at the given time,
+// which dates the samples of series fields.
+func (s *Schema) DecodeAt(data []byte, fPort int, receivedAt time.Time) (map[string]any, error) {
+	return s.Decode(data, WithPort(fPort), WithReceivedAt(receivedAt))
+}
 
+// newDecodeContext returns a context for decoding data with the schema's
+// settings.
+func (s *Schema) newDecodeContext(data []byte, receivedAt time.Time) *DecodeContext {
 	ctx := NewDecodeContext(data, s.Endian)
-	result := make(map[string]any)
+	ctx.ReceivedAt = receivedAt
+	ctx.WASM = s.WASMRuntime
+	ctx.EmitAliases = s.EmitAliases
+	ctx.Definitions = s.Definitions
+	ctx.Limits = s.DecodeLimits
+	return ctx
+}
 
-	if len(s.Header) > 0 {
-		headerResult, err := decodeFields(s.Header, ctx)
-		if err != nil {
-			return nil, err
-		}
-		for k, v := range headerResult {
-			result[k] = v
-		}
-	}
-
-	fieldsResult, err := decodeFields(fields, ctx)
-	if err != nil {
-		return nil, err
-	}
-	for k, v := range fieldsResult {
-		result[k] = v
-	}
-
-	// Add quality dict to output if any quality flags were set
-	if len(ctx.Quality) > 0 {
-		result["_quality"] = ctx.Quality
+// decodeWith decodes the header, fields and frames in ctx.
+func (s *Schema) decodeWith(ctx *DecodeContext, header, fields []Field) (map[string]any, error) {
+	result := ctx.result
+	if result == nil {
+		result = make(map[string]any)
 	}
 
-	return result, nil
-}
-
-// Decode decodes binary data using the schema.
-func (s *Schema) Decode(data []byte) (map[string]any, error) {
-	ctx := NewDecodeContext(data, s.Endian)
-	result := make(map[string]any)
-
 	// Decode header fields
-	if len(s.Header) > 0 {
-		headerResult, err := decodeFieldsWithSchema(s.Header, ctx, s)
-		if err != nil {
+	if len(header) > 0 {
+		if err := decodeFieldsInto(result, header, ctx, s); err != nil {
 			return nil, err
 		}
-		for k, v := range headerResult {
-			result[k] = v
-		}
 	}
 
 	// Decode main fields
-	fieldsResult, err := decodeFieldsWithSchema(s.Fields, ctx, s)
+	err := decodeFieldsInto(result, fields, ctx, s)
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range fieldsResult {
-		result[k] = v
+	if s.Frames != nil {
+		if result[s.Frames.key()], err = decodeFrames(s.Frames, ctx); err != nil {
+			return nil, err
+		}
 	}
+	return result, nil
+}
+
+// finishResult applies the decode profile, adds the metadata keys and
+// styles the output keys.
+func (s *Schema) finishResult(result map[string]any, ctx *DecodeContext) map[string]any {
+	ctx.Limits.applyProfile(result, ctx.Quality)
 
 	// Add quality dict to output if any quality flags were set
 	if len(ctx.Quality) > 0 {
 		result["_quality"] = ctx.Quality
 	}
+	if ctx.Limits.IncludeRaw {
+		result[RawKey] = hex.EncodeToString(ctx.Data)
+	}
 
-	return result, nil
+	return s.Output.Apply(result)
 }
 
 func decodeFields(fields []Field, ctx *DecodeContext) (map[string]any, error) {
@@ -1233,16 +1829,40 @@ func decodeFields(fields []Field, ctx *DecodeContext) (map[string]any, error) {
 
 func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema) (map[string]any, error) {
 	result := make(map[string]any)
+	if err := decodeFieldsInto(result, fields, ctx, schema); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// decodeFieldsInto decodes fields, adding their values to result.
+func decodeFieldsInto(result map[string]any, fields []Field, ctx *DecodeContext, schema *Schema) error {
+	if schema != nil && ctx.Definitions == nil {
+		ctx.Definitions = schema.Definitions
+	}
+	if ctx.depth >= ctx.Limits.maxDepth() {
+		return fmt.Errorf("fields nested deeper than %d", ctx.Limits.maxDepth())
+	}
+	ctx.depth++
+	ctx.scopes = append(ctx.scopes, nil)
+	inherited := ctx.Endian
+	defer func() {
+		ctx.depth--
+		ctx.Endian = inherited
+		ctx.popScope()
+	}()
 
 	for _, field := range fields {
+		ctx.Endian = blockEndian(field, inherited)
+
 		// $ref to definition
-		if field.Ref2 != "" && schema != nil {
-			refResult, err := resolveRef(field.Ref2, ctx, schema)
+		if field.Ref2 != "" {
+			refResult, err := resolveRef(field.Ref2, ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			for k, v := range refResult {
-				result[k] = v
+				mergeValue(result, k, v)
 				ctx.Variables[k] = v
 			}
 			continue
@@ -1252,10 +1872,10 @@ func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema)
 		if len(field.ByteGroup) > 0 {
 			bgResult, err := decodeByteGroup(field, ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			for k, v := range bgResult {
-				result[k] = v
+				mergeValue(result, k, v)
 				ctx.Variables[k] = v
 			}
 			continue
@@ -1265,10 +1885,10 @@ func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema)
 		if field.Type == TypeTLV || field.Type == "tlv" {
 			tlvResult, err := decodeTLV(field, ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			for k, v := range tlvResult {
-				result[k] = v
+				mergeValue(result, k, v)
 			}
 			continue
 		}
@@ -1277,10 +1897,10 @@ func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema)
 		if field.TLVInline != nil {
 			tlvResult, err := decodeTLV(*field.TLVInline, ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			for k, v := range tlvResult {
-				result[k] = v
+				mergeValue(result, k, v)
 			}
 			continue
 		}
@@ -1289,10 +1909,23 @@ func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema)
 		if field.Flagged != nil {
 			flaggedResult, err := decodeFlagged(field.Flagged, ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			for k, v := range flaggedResult {
-				result[k] = v
+				mergeValue(result, k, v)
+				ctx.Variables[k] = v
+			}
+			continue
+		}
+
+		// WASM-hosted decoder
+		if field.WASM != nil {
+			wasmResult, err := decodeWASM(field.WASM, ctx)
+			if err != nil {
+				return err
+			}
+			for k, v := range wasmResult {
+				mergeValue(result, k, v)
 				ctx.Variables[k] = v
 			}
 			continue
@@ -1302,22 +1935,80 @@ func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema)
 		if field.MatchInline != nil {
 			matchResult, err := decodeMatch(*field.MatchInline, ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if matchMap, ok := matchResult.(map[string]any); ok {
 				for k, v := range matchMap {
-					result[k] = v
+					mergeValue(result, k, v)
 					ctx.Variables[k] = v
 				}
 			}
 			continue
 		}
 
+		if ctx.skipComputed(field) || ctx.deferComputed(field) {
+			continue
+		}
+
+		start := ctx.Offset
+		ctx.lastRead = nil
+		hooked := ctx.hooks != nil && field.Name != ""
+		if hooked {
+			ctx.path = append(ctx.path, field.Name)
+		}
 		value, err := decodeField(field, ctx)
+		// Fields that share or revisit bytes consume nothing; use what they read
+		raw := ctx.lastRead
+		if ctx.Offset > start {
+			raw = ctx.Data[start:ctx.Offset]
+		}
+		if hooked {
+			if err == nil && value != nil && value != invalidValue {
+				value = ctx.runHooks(raw, value)
+			}
+			ctx.path = ctx.path[:len(ctx.path)-1]
+		}
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if ctx.Limits.IncludeFieldRaw && field.Name != "" {
+			if len(raw) > 0 {
+				rawFields, _ := result[RawFieldsKey].(map[string]any)
+				if rawFields == nil {
+					rawFields = map[string]any{}
+					result[RawFieldsKey] = rawFields
+				}
+				rawFields[field.Name] = hex.EncodeToString(raw)
+			}
 		}
 
+		if value == invalidValue {
+			if field.Name != "" {
+				result[field.Name] = nil
+				ctx.Variables[field.Name] = nil
+				ctx.Quality[field.Name] = "invalid"
+				if err := ctx.emitAlso(field, nil, result); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			var replaced bool
+			if value, replaced, err = ctx.nonFinite(field, f); err != nil {
+				return err
+			}
+			if replaced && field.Name != "" {
+				ctx.Quality[field.Name] = "non_finite"
+				if value == nil {
+					result[field.Name] = nil
+					ctx.Variables[field.Name] = nil
+				}
+			}
+			if value == nil {
+				continue
+			}
+		}
 		if value != nil && field.Name != "" {
 			result[field.Name] = value
 			ctx.Variables[field.Name] = value
@@ -1325,76 +2016,161 @@ func decodeFieldsWithSchema(fields []Field, ctx *DecodeContext, schema *Schema)
 			if len(field.ValidRange) >= 2 {
 				ctx.checkValidRange(value, field)
 			}
+			if field.Deprecated {
+				ctx.Warnings = append(ctx.Warnings, fmt.Sprintf("%s: field is deprecated", field.Name))
+			}
+			if ctx.EmitAliases {
+				for _, alias := range field.Aliases {
+					result[alias] = value
+				}
+			}
+			if err := ctx.emitAlso(field, value, result); err != nil {
+				return err
+			}
 		}
 	}
 
-	return result, nil
+	return nil
+}
+
+// mergeValue sets k in a field list's result, combining the _raw_fields
+// and _sources of a merged child (a $ref, match or TLV) with those already
+// there.
+func mergeValue(result map[string]any, k string, v any) {
+	if k == RawFieldsKey || k == SourcesKey {
+		dst, okDst := result[k].(map[string]any)
+		src, okSrc := v.(map[string]any)
+		if okDst && okSrc {
+			for name, raw := range src {
+				dst[name] = raw
+			}
+			return
+		}
+	}
+	result[k] = v
 }
 
-// resolveRef resolves a $ref reference to a definition.
-func resolveRef(ref string, ctx *DecodeContext, schema *Schema) (map[string]any, error) {
-	// Parse ref like "#/definitions/header"
+// maxRefDepth bounds nested $ref resolution (and catches cycles).
+const maxRefDepth = 32
+
+// resolveRef decodes the definition a $ref points to. References work in
+// any field list: ports, nested objects, repeats, match/TLV/flagged cases.
+func resolveRef(ref string, ctx *DecodeContext) (map[string]any, error) {
+	def, err := lookupDefinition(ref, ctx.Definitions)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.refDepth >= maxRefDepth {
+		return nil, fmt.Errorf("$ref %s: nested deeper than %d (circular reference?)", ref, maxRefDepth)
+	}
+	ctx.refDepth++
+	defer func() { ctx.refDepth-- }()
+	return decodeFields(def.Fields, ctx)
+}
+
+// lookupDefinition finds the target of a "#/definitions/name" reference.
+func lookupDefinition(ref string, defs map[string]*DefinitionDef) (*DefinitionDef, error) {
 	if !strings.HasPrefix(ref, "#/definitions/") {
 		return nil, fmt.Errorf("unsupported $ref format: %s", ref)
 	}
 	defName := strings.TrimPrefix(ref, "#/definitions/")
-	
-	if schema.Definitions == nil {
+	if defs == nil {
 		return nil, fmt.Errorf("no definitions in schema")
 	}
-	
-	def, ok := schema.Definitions[defName]
+	def, ok := defs[defName]
 	if !ok {
 		return nil, fmt.Errorf("definition not found: %s", defName)
 	}
-	
-	return decodeFieldsWithSchema(def.Fields, ctx, schema)
+	return def, nil
+}
+
+// byteGroupBits returns the bit range a byte group member occupies and the
+// byte size implied by its type.
+//
+// Members are written as a sliced type (`u8[4:7]`), a bool with `bit:`,
+// bits with `bit_offset:`/`bits:`, or a plain integer type covering its
+// full width.
+func byteGroupBits(f Field) (start, length, byteLen int) {
+	typeStr := string(f.Type)
+	if idx := strings.Index(typeStr, "["); idx >= 0 && strings.HasSuffix(typeStr, "]") {
+		byteLen = inferLengthFromType(FieldType(typeStr[:idx]))
+		parts := strings.Split(typeStr[idx+1:len(typeStr)-1], ":")
+		start, _ = strconv.Atoi(parts[0])
+		end := start
+		if len(parts) == 2 {
+			end, _ = strconv.Atoi(parts[1])
+		}
+		return start, end - start + 1, byteLen
+	}
+	switch f.Type {
+	case TypeBool, TypeBoolLower:
+		return f.Bit, 1, 1
+	case TypeBits, TypeBitsLower:
+		return f.BitOffset, bitsWidth(f), bitsLength(f)
+	}
+	byteLen = f.Length
+	if byteLen == 0 {
+		byteLen = inferLengthFromType(f.Type)
+	}
+	return 0, byteLen * 8, byteLen
+}
+
+// byteGroupSize returns the declared size of a byte group, or the smallest
+// size that holds all member bit ranges.
+func byteGroupSize(field Field) int {
+	if field.Size > 0 {
+		return field.Size
+	}
+	size := 1
+	for _, sub := range field.ByteGroup {
+		start, length, byteLen := byteGroupBits(sub)
+		if n := (start + length + 7) / 8; n > size {
+			size = n
+		}
+		if byteLen > size {
+			size = byteLen
+		}
+	}
+	return size
 }
 
 // decodeByteGroup decodes a byte group (multiple bitfields from shared bytes).
 func decodeByteGroup(field Field, ctx *DecodeContext) (map[string]any, error) {
-	size := field.Size
-	if size == 0 {
-		size = 1
-	}
-	
-	data, err := ctx.Read(size)
+	data, err := ctx.Read(byteGroupSize(field))
 	if err != nil {
 		return nil, err
 	}
-	
+
+	var rawVal uint64
+	for i, b := range data {
+		rawVal |= uint64(b) << (8 * i)
+	}
+
 	result := make(map[string]any)
-	
+
 	// Parse each subfield from the shared bytes
 	for _, subfield := range field.ByteGroup {
-		// Parse bit range from type like "u8[4:7]"
-		typeStr := string(subfield.Type)
-		bitStart, bitEnd := 0, 7
-		
-		if idx := strings.Index(typeStr, "["); idx >= 0 {
-			rangeStr := typeStr[idx+1 : len(typeStr)-1]
-			parts := strings.Split(rangeStr, ":")
-			if len(parts) == 2 {
-				bitStart, _ = strconv.Atoi(parts[0])
-				bitEnd, _ = strconv.Atoi(parts[1])
-			}
+		bitStart, bitLen, _ := byteGroupBits(subfield)
+		bits := (rawVal >> bitStart) & (uint64(1)<<bitLen - 1)
+
+		var value any = float64(bits)
+		if subfield.Type == TypeBool || subfield.Type == TypeBoolLower {
+			value = bits != 0
 		}
-		
-		// Extract bits from the data
-		var rawVal uint64
-		for i, b := range data {
-			rawVal |= uint64(b) << (8 * i)
+		value, err := finishValue(subfield, value, ctx)
+		if err != nil {
+			return nil, err
 		}
-		
-		bitLen := bitEnd - bitStart + 1
-		mask := uint64((1 << bitLen) - 1)
-		value := float64((rawVal >> bitStart) & mask)
-		
+		if value == invalidValue {
+			value = nil
+			ctx.Quality[subfield.Name] = "invalid"
+		}
+
 		if subfield.Name != "" {
 			result[subfield.Name] = value
 		}
 	}
-	
+
 	return result, nil
 }
 
@@ -1425,7 +2201,9 @@ func decodeFlagged(fd *FlaggedDef, ctx *DecodeContext) (map[string]any, error) {
 
 func decodeField(field Field, ctx *DecodeContext) (any, error) {
 	length := field.Length
-	if length == 0 {
+	if readsRest(field) {
+		length = ctx.Remaining()
+	} else if length == 0 {
 		// Infer length from shorthand type names
 		length = inferLengthFromType(field.Type)
 	}
@@ -1439,21 +2217,21 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 
 	switch field.Type {
 	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU32, TypeU64, TypeU24:
-		data, err := ctx.Read(length)
+		data, err := ctx.readField(field, length)
 		if err != nil {
 			return nil, err
 		}
 		value = decodeUint(data, endian)
 
 	case TypeSInt, TypeS8, TypeS16, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64, TypeS24:
-		data, err := ctx.Read(length)
+		data, err := ctx.readField(field, length)
 		if err != nil {
 			return nil, err
 		}
 		value = decodeSint(data, endian)
 
 	case TypeBInt:
-		data, err := ctx.Read(length)
+		data, err := ctx.readField(field, length)
 		if err != nil {
 			return nil, err
 		}
@@ -1464,7 +2242,7 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 			TypeFloat16: 2, TypeFloat32: 4, TypeFloat64: 8,
 			TypeF16: 2, TypeF32: 4, TypeF64: 8,
 		}[field.Type]
-		data, err := ctx.Read(size)
+		data, err := ctx.readField(field, size)
 		if err != nil {
 			return nil, err
 		}
@@ -1473,69 +2251,46 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 			return nil, err
 		}
 
+	case TypeFixed, TypeUFixed:
+		value, err = decodeFixed(field, ctx, endian)
+		if err != nil {
+			return nil, err
+		}
+
 	case TypeBool, TypeBoolLower:
-		// Bool extracts a single bit from the current byte
-		data, err := ctx.Peek(1, 0)
+		// Bool extracts a single bit from the byte at byte_offset
+		data, err := ctx.readField(field, 1)
 		if err != nil {
 			return nil, err
 		}
 		value = decodeBits(data[0], field.Bit, 1) != 0
-		// Consume bytes if specified
-		if field.Consume > 0 {
-			ctx.Read(field.Consume)
-		}
 
 	case TypeBits, TypeBitsLower:
-		data, err := ctx.Peek(1, field.ByteOffset)
+		value, err = decodeBitsField(field, ctx, endian)
 		if err != nil {
 			return nil, err
 		}
-		bits := field.Bits
-		if bits == 0 {
-			bits = 1
-		}
-		value = decodeBits(data[0], field.BitOffset, bits)
-
-	case TypeString, TypeStringLower:
-		// If length is specified, read bytes; otherwise use static value
-		if length > 0 {
-			data, err := ctx.Read(length)
-			if err != nil {
-				return nil, err
-			}
-			value = strings.TrimRight(string(data), "\x00")
-		} else {
-			value = field.Value
-		}
 
-	case TypeAscii, TypeAsciiLower:
-		data, err := ctx.Read(length)
-		if err != nil {
+	case TypeString, TypeStringLower, TypeAscii, TypeAsciiLower:
+		if isConstString(field) {
+			value = field.Const
+		} else if value, err = decodeText(field, ctx, length, endian); err != nil {
 			return nil, err
 		}
-		value = strings.TrimRight(string(data), "\x00")
 
 	case TypeEnum, TypeEnumLower:
 		// Enum: read base type and map to string
-		baseLen := 1
-		switch field.Base {
-		case "u8", "s8":
-			baseLen = 1
-		case "u16", "s16":
-			baseLen = 2
-		case "u32", "s32":
-			baseLen = 4
-		}
-		data, err := ctx.Read(baseLen)
+		data, err := ctx.Read(enumBaseLength(field))
 		if err != nil {
 			return nil, err
 		}
 		intVal := int(decodeUint(data, endian))
-		if field.Values != nil {
-			if str, ok := field.Values[intVal]; ok {
-				value = str
-			} else {
-				value = intVal // Return raw value if not in enum
+		if str, ok := field.Values[intVal]; ok {
+			value = str
+		} else if field.Values != nil {
+			// Not in enum: raw value unless unknown: says otherwise
+			if value, err = unknownValue(field, intVal); err != nil || value == nil {
+				return nil, err
 			}
 		} else {
 			value = intVal
@@ -1555,6 +2310,22 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 		}
 		return nil, nil
 
+	case TypeReserved, TypeReservedLower:
+		offset := ctx.Offset
+		data, err := ctx.Read(length)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ctx.checkReserved(field, data, offset)
+
+	case TypeMagic, TypeMagicLower:
+		offset := ctx.Offset
+		data, err := ctx.Read(length)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ctx.checkMagic(field, data, offset)
+
 	case TypeBytes, TypeBytesLower:
 		data, err := ctx.Read(length)
 		if err != nil {
@@ -1568,6 +2339,12 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 			return nil, err
 		}
 
+	case TypeSeries:
+		value, err = decodeSeries(field, ctx)
+		if err != nil {
+			return nil, err
+		}
+
 	case TypeBitfieldString:
 		data, err := ctx.Read(length)
 		if err != nil {
@@ -1607,7 +2384,7 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 		// Phase 2: ref with polynomial/transform, compute with guard
 		if field.Ref != "" {
 			refName := strings.TrimPrefix(field.Ref, "$")
-			refVal, ok := ctx.Variables[refName]
+			refVal, ok := lookupVar(ctx.Variables, refName)
 			if !ok {
 				return nil, fmt.Errorf("ref field not found: %s", refName)
 			}
@@ -1617,6 +2394,9 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 			if len(field.Polynomial) > 0 {
 				numVal = evaluatePolynomial(field.Polynomial, numVal)
 			}
+			if field.Curve != nil {
+				numVal = field.Curve.Apply(numVal)
+			}
 
 			// Apply transform array (for ref fields, transform comes before guard)
 			if len(field.Transform) > 0 {
@@ -1648,6 +2428,11 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 			}
 
 			value = numVal
+		} else if field.Compute != nil && field.Compute.Fn != "" {
+			value, err = evaluateAggregate(field.Compute, ctx)
+			if err != nil || value == nil {
+				return nil, err
+			}
 		} else if field.Compute != nil {
 			// Binary operation
 			result, err := evaluateCompute(field.Compute, ctx)
@@ -1657,7 +2442,11 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 			value = result
 		} else if field.Formula != "" {
 			// Legacy formula support
-			val, err := evaluateFormula(field.Formula, 0, ctx)
+			formula, err := formulaOf(field.formula, field.Formula)
+			if err != nil {
+				return nil, err
+			}
+			val, err := formula.eval(0, ctx.Variables)
 			if err != nil {
 				return nil, err
 			}
@@ -1670,11 +2459,16 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 		if field.Guard != nil {
 			if numVal, ok := toFloat64(value); ok {
 				value = evaluateGuard(field.Guard, numVal, ctx)
+				if value == nil {
+					return nil, nil
+				}
 			}
 		}
 
 	case TypeObject:
+		ctx.nested++
 		value, err = decodeFields(field.Fields, ctx)
+		ctx.nested--
 		if err != nil {
 			return nil, err
 		}
@@ -1688,15 +2482,47 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 	case TypeTLV, "tlv":
 		return decodeTLV(field, ctx)
 
+	case TypeVector3:
+		return decodeVector3(field, ctx)
+
 	default:
 		return nil, fmt.Errorf("unknown field type: %s", field.Type)
 	}
 
+	return finishValue(field, value, ctx)
+}
+
+// finishValue applies a field's formula/modifiers and lookup to a raw
+// decoded value and stores its variable.
+func finishValue(field Field, value any, ctx *DecodeContext) (any, error) {
+	// Sentinels are checked on the raw value, before any arithmetic
+	if len(field.Invalid) > 0 && isInvalidRaw(field, value) {
+		if field.Var != "" {
+			ctx.setVar(field, nil)
+		}
+		return invalidValue, nil
+	}
+
+	// Exact decimal strings come from the raw integer, not float arithmetic
+	if field.Output == OutputDecimalString && field.Scale != nil {
+		if str, ok := decimalString(value, *field.Scale); ok {
+			if field.Var != "" {
+				num, _ := strconv.ParseFloat(str, 64)
+				ctx.setVar(field, num)
+			}
+			return str, nil
+		}
+	}
+
 	// Formula takes precedence over top-level modifiers (per spec section 03)
 	// For TypeNumber with ref, transform is already applied in the ref block
 	if field.Formula != "" && field.Type != TypeNumber {
 		if numVal, ok := toFloat64(value); ok {
-			result, err := evaluateFormula(field.Formula, numVal, ctx)
+			formula, err := formulaOf(field.formula, field.Formula)
+			if err != nil {
+				return nil, err
+			}
+			result, err := formula.eval(numVal, ctx.Variables)
 			if err != nil {
 				return nil, err
 			}
@@ -1705,6 +2531,24 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 	} else if (field.Type == TypeNumber || field.Type == "number") && field.Ref != "" {
 		// Transform already applied in the ref block, skip
 	} else if numVal, ok := toFloat64(value); ok {
+		// scale_by scales the raw mantissa by its exponent
+		if field.ScaleBy != "" {
+			exp, ok, err := ctx.scaleByExponent(field)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				if field.Var != "" {
+					ctx.setVar(field, nil)
+				}
+				return invalidValue, nil
+			}
+			numVal = scaleByPow(numVal, field.ScaleBase, exp)
+		}
+		// Curve maps the raw value before any arithmetic
+		if field.Curve != nil {
+			numVal = field.Curve.Apply(numVal)
+		}
 		// Apply transformations in order
 		// Support both top-level shortcuts and transform array
 		if len(field.Transform) > 0 {
@@ -1767,11 +2611,30 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 		value = numVal
 	}
 
+	// Rounding applies to the modified value, before lookup
+	if numVal, ok := value.(float64); ok {
+		value = roundValue(field, numVal)
+	}
+
+	// Number fields apply their guard in decodeField, before modifiers
+	if field.Guard != nil && field.Type != TypeNumber {
+		value = evaluateGuard(field.Guard, value, ctx)
+		if value == nil {
+			return nil, nil
+		}
+	}
+
 	// Apply lookup
 	if field.Lookup != nil {
 		if intVal, ok := toInt(value); ok {
 			if lookup, found := field.Lookup[intVal]; found {
 				value = lookup
+			} else {
+				v, err := unknownValue(field, value)
+				if err != nil || v == nil {
+					return nil, err
+				}
+				value = v
 			}
 		}
 	}
@@ -1779,55 +2642,184 @@ func decodeField(field Field, ctx *DecodeContext) (any, error) {
 		if intVal, ok := toInt(value); ok {
 			if intVal >= 0 && intVal < len(field.LookupArray) {
 				value = field.LookupArray[intVal]
+			} else {
+				v, err := unknownValue(field, value)
+				if err != nil || v == nil {
+					return nil, err
+				}
+				value = v
 			}
 		}
 	}
 
 	// Store variable
 	if field.Var != "" {
-		ctx.Variables[field.Var] = value
+		ctx.setVar(field, value)
+	}
+
+	// String formatting is output-only; variables keep the number
+	if field.AsString {
+		if numVal, ok := value.(float64); ok {
+			value = formatNumber(field, numVal)
+		}
 	}
 
 	return value, nil
 }
 
-func decodeMatch(field Field, ctx *DecodeContext) (any, error) {
-	var matchValue int
+// invalidValue marks a raw value listed in a field's invalid: sentinels.
+// decodeFields emits it as null.
+var invalidValue = &struct{ invalid bool }{true}
 
-	if field.On != "" {
-		// Variable-based match
-		varName := strings.TrimPrefix(field.On, "$")
-		val, ok := ctx.Variables[varName]
-		if !ok {
-			return nil, fmt.Errorf("variable not found: $%s", varName)
-		}
-		matchValue, _ = toInt(val)
-	} else {
-		// Inline match - read bytes
-		length := field.Length
-		if length == 0 {
-			length = 1
-		}
-		data, err := ctx.Read(length)
-		if err != nil {
-			return nil, err
-		}
-		matchValue = int(decodeUint(data, ctx.Endian))
+// isInvalidRaw reports whether a raw decoded value is one of the field's
+// sentinels. Sentinels may be written as unsigned bit patterns, so 0x8000
+// matches -32768 on an s16.
+func isInvalidRaw(field Field, value any) bool {
+	raw, ok := toFloat64(value)
+	if !ok {
+		return false
 	}
-
-	// Find matching case
-	for _, c := range field.Cases {
-		if c.Default {
-			return decodeFields(c.Fields, ctx)
+	unsigned := raw
+	if n := inferLengthFromType(field.Type); raw < 0 && n > 0 && n < 8 {
+		unsigned = raw + math.Ldexp(1, 8*n)
+	}
+	for _, sentinel := range field.Invalid {
+		if raw == sentinel || unsigned == sentinel {
+			return true
 		}
+	}
+	return false
+}
 
-		caseVal := c.Case
-		if caseVal == nil {
-			caseVal = c.Match // Legacy support
-		}
+// Policies for enum/lookup values missing from the table (unknown:).
+const (
+	UnknownRaw   = "raw"   // pass the number through (default)
+	UnknownNull  = "null"  // omit the field
+	UnknownError = "error" // fail the decode
+)
 
-		if caseVal == nil {
-			continue
+// unknownValue applies the field's unknown: policy to a value that is not
+// in its enum or lookup table. A nil result means the field is omitted.
+func unknownValue(field Field, raw any) (any, error) {
+	switch mode := field.Unknown; {
+	case mode == "" || mode == UnknownRaw:
+		return raw, nil
+	case mode == UnknownNull:
+		return nil, nil
+	case mode == UnknownError:
+		return nil, fmt.Errorf("field %s: unknown value %v", field.Name, raw)
+	default:
+		if format, ok := unknownLabelFormat(mode); ok {
+			intVal, _ := toInt(raw)
+			return fmt.Sprintf(format, intVal), nil
+		}
+		return raw, nil
+	}
+}
+
+// unknownLabelFormat extracts the format from label("unknown_%d").
+func unknownLabelFormat(mode string) (string, bool) {
+	if !strings.HasPrefix(mode, "label(") || !strings.HasSuffix(mode, ")") {
+		return "", false
+	}
+	format := strings.TrimSpace(mode[len("label(") : len(mode)-1])
+	if unquoted, err := strconv.Unquote(format); err == nil {
+		format = unquoted
+	}
+	return format, true
+}
+
+// parseUnknownLabel reverses an unknown: label("...") placeholder.
+func parseUnknownLabel(field Field, s string) (int, bool) {
+	format, ok := unknownLabelFormat(field.Unknown)
+	if !ok {
+		return 0, false
+	}
+	var intVal int
+	if n, err := fmt.Sscanf(s, format, &intVal); err != nil || n != 1 {
+		return 0, false
+	}
+	return intVal, true
+}
+
+// roundValue applies round: (decimal places) and precision: (significant
+// digits) to a decoded number.
+func roundValue(field Field, v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	if field.Precision != nil && *field.Precision > 0 && v != 0 {
+		v, _ = strconv.ParseFloat(strconv.FormatFloat(v, 'g', *field.Precision, 64), 64)
+	}
+	if field.Round != nil && *field.Round >= 0 {
+		scale := math.Pow(10, float64(*field.Round))
+		v = math.Round(v*scale) / scale
+	}
+	return v
+}
+
+// formatNumber renders a number for as_string output: fixed decimals when
+// round: is set, otherwise the shortest representation.
+func formatNumber(field Field, v float64) string {
+	if field.Round != nil && *field.Round >= 0 {
+		return strconv.FormatFloat(v, 'f', *field.Round, 64)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func decodeMatch(field Field, ctx *DecodeContext) (any, error) {
+	var matchValue int
+
+	if field.On != "" && !bareVarPattern.MatchString(field.On) {
+		// Expression-based match, e.g. `on: "($hdr >> 4) & 0x0F"`
+		expr, err := formulaOf(field.onExpr, field.On)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range expr.vars {
+			if _, ok := ctx.Variables[name]; !ok {
+				return nil, fmt.Errorf("variable not found: $%s", name)
+			}
+		}
+		val, err := expr.eval(0, ctx.Variables)
+		if err != nil {
+			return nil, err
+		}
+		matchValue = int(val)
+	} else if field.On != "" {
+		// Variable-based match
+		varName := strings.TrimPrefix(field.On, "$")
+		val, ok := ctx.Variables[varName]
+		if !ok {
+			return nil, fmt.Errorf("variable not found: $%s", varName)
+		}
+		matchValue, _ = toInt(val)
+	} else {
+		// Inline match - read bytes
+		length := field.Length
+		if length == 0 {
+			length = 1
+		}
+		data, err := ctx.Read(length)
+		if err != nil {
+			return nil, err
+		}
+		matchValue = int(decodeUint(data, ctx.Endian))
+	}
+
+	// Find matching case
+	for _, c := range field.Cases {
+		if c.Default {
+			return decodeFields(c.Fields, ctx)
+		}
+
+		caseVal := c.Case
+		if caseVal == nil {
+			caseVal = c.Match // Legacy support
+		}
+
+		if caseVal == nil {
+			continue
 		}
 
 		matched := false
@@ -1861,6 +2853,9 @@ func decodeMatch(field Field, ctx *DecodeContext) (any, error) {
 		}
 	}
 
+	if field.revisions {
+		return nil, fmt.Errorf("no revision for %s = %d", strings.TrimPrefix(field.On, "$"), matchValue)
+	}
 	return nil, nil
 }
 
@@ -1878,9 +2873,21 @@ func decodeTLV(field Field, ctx *DecodeContext) (map[string]any, error) {
 
 	result := make(map[string]any)
 	var channels []map[string]any
+	var sources map[string]any
+	if merge && ctx.Limits.IncludeSources {
+		sources = make(map[string]any)
+	}
 
 	// Parse until end of data
-	for ctx.Remaining() > 0 {
+	maxRecords := ctx.Limits.maxTLVRecords()
+	for records := 0; ctx.Remaining() > 0; records++ {
+		recordStart := ctx.Offset
+		if records == maxRecords {
+			if err := ctx.limitReached(field.Name, "records", maxRecords); err != nil {
+				return nil, err
+			}
+			break
+		}
 		var tag []int
 		var tagValues map[string]int
 
@@ -1942,10 +2949,18 @@ func decodeTLV(field Field, ctx *DecodeContext) (map[string]any, error) {
 		}
 
 		// Find matching case
-		caseKey := findTLVCaseKey(field.TLVCases, tag)
+		caseKey := findTLVCase(field, tag)
 		
 		if caseKey != "" {
 			caseFields := field.TLVCases[caseKey]
+			bound := bindTag(field, caseKey, tag, tagValues)
+			if len(bound) > 0 && tagValues == nil {
+				tagValues = make(map[string]int, len(bound))
+			}
+			for name, v := range bound {
+				tagValues[name] = v
+				ctx.Variables[name] = float64(v)
+			}
 			caseResult, err := decodeFields(caseFields, ctx)
 			if err != nil {
 				return nil, err
@@ -1954,6 +2969,9 @@ func decodeTLV(field Field, ctx *DecodeContext) (map[string]any, error) {
 			if merge {
 				// Merge fields, converting to array if repeated
 				for k, v := range caseResult {
+					if field.NameTemplate != "" {
+						k = renderNameTemplate(field.NameTemplate, k, tag, tagValues)
+					}
 					if existing, ok := result[k]; ok {
 						if arr, isArr := existing.([]any); isArr {
 							result[k] = append(arr, v)
@@ -1963,9 +2981,15 @@ func decodeTLV(field Field, ctx *DecodeContext) (map[string]any, error) {
 					} else {
 						result[k] = v
 					}
+					if sources != nil && k != RawFieldsKey && k != SourcesKey {
+						addTLVSource(sources, k, tag, recordStart)
+					}
 				}
 			} else {
 				entry := map[string]any{"tag": tag}
+				for name, v := range bound {
+					entry[name] = float64(v)
+				}
 				for k, v := range caseResult {
 					entry[k] = v
 				}
@@ -1986,10 +3010,74 @@ func decodeTLV(field Field, ctx *DecodeContext) (map[string]any, error) {
 	if !merge {
 		result["channels"] = channels
 	}
+	if len(sources) > 0 {
+		result[SourcesKey] = sources
+	}
 
 	return result, nil
 }
 
+// addTLVSource records that the record with tag at offset produced key.
+// Like the value, the source becomes a list when the key repeats.
+func addTLVSource(sources map[string]any, key string, tag []int, offset int) {
+	src := map[string]any{"tag": tag, "offset": offset}
+	switch existing := sources[key].(type) {
+	case nil:
+		sources[key] = src
+	case []any:
+		sources[key] = append(existing, src)
+	default:
+		sources[key] = []any{existing, src}
+	}
+}
+
+var nameTemplatePattern = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// checkNameTemplate reports placeholders of a TLV name_template that are
+// not {name}, {tag}, a tag field or a tag_bind name.
+func checkNameTemplate(tmpl string, tagFields []Field, bound []string) error {
+	if !strings.Contains(tmpl, "{name}") {
+		return fmt.Errorf("name_template %q: needs {name} to keep the record's fields apart", tmpl)
+	}
+	for _, m := range nameTemplatePattern.FindAllStringSubmatch(tmpl, -1) {
+		if m[1] == "name" || m[1] == "tag" {
+			continue
+		}
+		found := false
+		for _, tf := range tagFields {
+			found = found || tf.Name == m[1]
+		}
+		for _, b := range bound {
+			found = found || b == m[1]
+		}
+		if !found {
+			return fmt.Errorf("name_template %q: {%s} is not name, tag, a tag field or a tag_bind", tmpl, m[1])
+		}
+	}
+	return nil
+}
+
+// renderNameTemplate names a decoded TLV value: {name} is the case
+// field's name, {tag} the record's tag (parts joined by _) and {x} the
+// tag field x, so channel-tagged records such as Cayenne LPP come out as
+// ch1_temperature, ch2_temperature instead of one merged array.
+func renderNameTemplate(tmpl, name string, tag []int, tagValues map[string]int) string {
+	return nameTemplatePattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		switch key := m[1 : len(m)-1]; key {
+		case "name":
+			return name
+		case "tag":
+			parts := make([]string, len(tag))
+			for i, t := range tag {
+				parts[i] = strconv.Itoa(t)
+			}
+			return strings.Join(parts, "_")
+		default:
+			return strconv.Itoa(tagValues[key])
+		}
+	})
+}
+
 func findTLVCaseKey(cases map[string][]Field, tag []int) string {
 	if cases == nil {
 		return ""
@@ -2057,9 +3145,11 @@ func formatBytes(data []byte, format, separator string) any {
 
 // decodeRepeat decodes a repeat/array field.
 func decodeRepeat(field Field, ctx *DecodeContext) ([]any, error) {
+	ctx.nested++
+	defer func() { ctx.nested-- }()
 	maxIterations := field.Max
 	if maxIterations == 0 {
-		maxIterations = 1000 // Safety limit
+		maxIterations = ctx.Limits.maxRepeat()
 	}
 	minIterations := field.Min
 
@@ -2087,11 +3177,14 @@ func decodeRepeat(field Field, ctx *DecodeContext) ([]any, error) {
 		}
 
 		if count > maxIterations {
+			if err := ctx.limitReached(field.Name, "elements", maxIterations); err != nil {
+				return nil, err
+			}
 			count = maxIterations
 		}
 
 		for i := 0; i < count; i++ {
-			element, err := decodeFields(field.Fields, ctx)
+			element, err := decodeElement(field, i, ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -2121,7 +3214,7 @@ func decodeRepeat(field Field, ctx *DecodeContext) ([]any, error) {
 		iterations := 0
 
 		for ctx.Offset < endOffset && iterations < maxIterations {
-			element, err := decodeFields(field.Fields, ctx)
+			element, err := decodeElement(field, iterations, ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -2139,13 +3232,18 @@ func decodeRepeat(field Field, ctx *DecodeContext) ([]any, error) {
 		iterations := 0
 
 		for ctx.Remaining() > 0 && iterations < maxIterations {
-			element, err := decodeFields(field.Fields, ctx)
+			element, err := decodeElement(field, iterations, ctx)
 			if err != nil {
 				return nil, err
 			}
 			result = append(result, element)
 			iterations++
 		}
+		if ctx.Remaining() > 0 {
+			if err := ctx.limitReached(field.Name, "elements", maxIterations); err != nil {
+				return nil, err
+			}
+		}
 
 	} else {
 		return nil, fmt.Errorf("repeat field must specify one of: count, byte_length, or until")
@@ -2157,13 +3255,50 @@ func decodeRepeat(field Field, ctx *DecodeContext) ([]any, error) {
 			len(result), minIterations)
 	}
 
+	if field.Flatten {
+		name, err := flattenName(field)
+		if err != nil {
+			return nil, err
+		}
+		for i, element := range result {
+			if m, ok := element.(map[string]any); ok {
+				result[i] = m[name]
+			}
+		}
+	}
+
 	return result, nil
 }
 
+// flattenName returns the single output field of a flattened repeat body.
+func flattenName(field Field) (string, error) {
+	name := ""
+	for _, f := range field.Fields {
+		if f.Name == "" || strings.HasPrefix(f.Name, "_") {
+			continue
+		}
+		if name != "" {
+			return "", fmt.Errorf("flatten: repeat %s has more than one output field", field.Name)
+		}
+		name = f.Name
+	}
+	if name == "" {
+		return "", fmt.Errorf("flatten: repeat %s has no output field", field.Name)
+	}
+	return name, nil
+}
+
 // =============================================================================
 // ENCODING
 // =============================================================================
 
+// Omit marks a field as deliberately absent in Encode input. It behaves
+// like a missing key, so callers can build a complete input map and still
+// drop individual fields or flagged groups; a zero value is encoded as zero.
+var Omit = omitValue{}
+
+type omitValue struct{}
+
 // Encode encodes data to binary using the schema.
 func (s *Schema) Encode(data map[string]any) ([]byte, error) {
 	return s.EncodeWithPort(data, 0)
@@ -2171,47 +3306,102 @@ func (s *Schema) Encode(data map[string]any) ([]byte, error) {
 
 // EncodeWithPort encodes data to binary using port-based schema selection.
 func (s *Schema) EncodeWithPort(data map[string]any, fPort int) ([]byte, error) {
+	out, err := s.AppendEncodeWithPort(make([]byte, 0), data, fPort)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AppendEncode appends the encoding of data to dst and returns the
+// extended buffer, so broadcasting many downlinks can reuse one buffer
+// (pass buf[:0]) instead of allocating per message. On error dst is
+// returned unextended.
+func (s *Schema) AppendEncode(dst []byte, data map[string]any) ([]byte, error) {
+	return s.AppendEncodeWithPort(dst, data, 0)
+}
+
+// AppendEncodeWithPort is AppendEncode using port-based schema selection.
+func (s *Schema) AppendEncodeWithPort(dst []byte, data map[string]any, fPort int) ([]byte, error) {
 	ctx := NewEncodeContext(s.Endian)
+	ctx.Buffer = dst
+	ctx.Definitions = s.Definitions
+	ctx.StrictTypes = s.StrictTypes
+	ctx.Overflow = s.encodeOverflow()
+	ctx.Rounding = s.EncodeRounding
+
+	// Resolve the port first, so that the header written is the one
+	// decoding the port reads
+	pd, err := s.resolvePort(fPort, PortDown)
+	if err != nil {
+		return dst, err
+	}
+	fields := s.topFields()
+	if pd != nil {
+		fields = pd.Fields
+	}
+
+	// A header flags field may gate groups among the port's fields
+	header := s.portHeader(pd)
+	ctx.flagScopes = append(ctx.flagScopes, flagPatches(append(append([]Field{}, header...), fields...), data, ctx.Definitions, 0))
 
 	// Encode header fields first
-	if len(s.Header) > 0 {
-		if err := encodeFields(s.Header, data, ctx); err != nil {
-			return nil, err
+	if len(header) > 0 {
+		if err := encodeFields(header, data, ctx); err != nil {
+			return dst, err
 		}
 	}
 
-	// Resolve fields (port-based or top-level)
-	fields, _ := s.ResolveFields(fPort)
-
 	// Encode main fields
 	if err := encodeFields(fields, data, ctx); err != nil {
-		return nil, err
+		return dst, err
+	}
+	if s.Frames != nil {
+		if err := encodeFrames(s.Frames, data, ctx); err != nil {
+			return dst, err
+		}
 	}
+	ctx.flushBits()
 
 	return ctx.Buffer, nil
 }
 
 func encodeFields(fields []Field, data map[string]any, ctx *EncodeContext) error {
+	// Raw values behind computed fields with encode_inverse
+	data, derived, err := deriveInverseValues(fields, data)
+	if err != nil {
+		return err
+	}
+
 	// Pre-scan flagged constructs to compute flag values
-	flagsPatches := map[string]int{}
+	ctx.flagScopes = append(ctx.flagScopes, flagPatches(fields, data, ctx.Definitions, 0))
+	inherited := ctx.Endian
+	defer func() {
+		ctx.Endian = inherited
+		ctx.flagScopes = ctx.flagScopes[:len(ctx.flagScopes)-1]
+	}()
+
 	for _, field := range fields {
-		if field.Flagged != nil {
-			flags := 0
-			for _, group := range field.Flagged.Groups {
-				for _, gf := range group.Fields {
-					if gf.Name != "" {
-						if _, ok := data[gf.Name]; ok {
-							flags |= (1 << group.Bit)
-							break
-						}
-					}
-				}
+		ctx.Endian = blockEndian(field, inherited)
+
+		// $ref to definition: its fields are flattened into data
+		if field.Ref2 != "" {
+			def, err := lookupDefinition(field.Ref2, ctx.Definitions)
+			if err != nil {
+				return err
+			}
+			if ctx.refDepth >= maxRefDepth {
+				return fmt.Errorf("$ref %s: nested deeper than %d (circular reference?)", field.Ref2, maxRefDepth)
 			}
-			flagsPatches[field.Flagged.Field] = flags
+			ctx.refDepth++
+			err = encodeFields(def.Fields, data, ctx)
+			ctx.refDepth--
+			if err != nil {
+				return err
+			}
+			continue
 		}
-	}
 
-	for _, field := range fields {
 		// Flagged construct
 		if field.Flagged != nil {
 			if err := encodeFlagged(field.Flagged, data, ctx); err != nil {
@@ -2220,7 +3410,54 @@ func encodeFields(fields []Field, data map[string]any, ctx *EncodeContext) error
 			continue
 		}
 
-		if field.Name == "" || strings.HasPrefix(field.Name, "_") {
+		// Inline match: the chosen case's fields sit beside the selector
+		if field.MatchInline != nil {
+			if err := encodeMatch(*field.MatchInline, data, data, ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Type == TypeMatch || field.Type == "CTRL-SWITCH" || field.Type == "Switch" {
+			caseData := data
+			if field.Name != "" {
+				caseData, _ = data[field.Name].(map[string]any)
+			}
+			if err := encodeMatch(field, data, caseData, ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// TLV records come from flattened keys or a channels array
+		if field.TLVInline != nil || field.Type == TypeTLV || field.Type == TypeTLVLower {
+			tlv := field
+			if field.TLVInline != nil {
+				tlv = *field.TLVInline
+			}
+			if err := encodeTLV(tlv, data, ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Byte group (shared-byte bitfields)
+		if len(field.ByteGroup) > 0 {
+			if err := encodeByteGroup(field, data, ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Padding is written as zeros
+		if isSkipField(field) {
+			if err := encodeField(field, nil, ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.Name == "" || strings.HasPrefix(field.Name, "_") && !derived[field.Name] {
+			ctx.fillBitsWord(field)
 			continue
 		}
 
@@ -2229,9 +3466,18 @@ func encodeFields(fields []Field, data map[string]any, ctx *EncodeContext) error
 			continue
 		}
 
+		// Positional views re-read bytes owned by other fields
+		if isPositionalView(field) {
+			continue
+		}
+
 		// Bitfield string encoding
 		if field.Type == TypeBitfieldString {
-			if strVal, ok := data[field.Name].(string); ok {
+			raw, exists := lookupEncodeValue(field, data)
+			if !exists && field.Required {
+				return errRequired(field)
+			}
+			if strVal, ok := raw.(string); ok {
 				if err := encodeBitfieldString(field, strVal, ctx); err != nil {
 					return err
 				}
@@ -2241,12 +3487,23 @@ func encodeFields(fields []Field, data map[string]any, ctx *EncodeContext) error
 
 		// Patch flags value
 		var value any
-		if patchedFlags, ok := flagsPatches[field.Name]; ok {
+		if patch, ok := ctx.flagPatch(field.Name); ok {
+			// Bits no group owns are kept from the given value
+			patchedFlags := patch.flags
+			if given, exists := lookupEncodeValue(field, data); exists {
+				if n, ok := toInt(given); ok {
+					patchedFlags |= n &^ patch.mask
+				}
+			}
 			value = float64(patchedFlags)
 		} else {
 			var exists bool
-			value, exists = data[field.Name]
+			value, exists = lookupEncodeValue(field, data)
 			if !exists {
+				if field.Required {
+					return errRequired(field)
+				}
+				ctx.fillBitsWord(field)
 				continue
 			}
 		}
@@ -2254,66 +3511,368 @@ func encodeFields(fields []Field, data map[string]any, ctx *EncodeContext) error
 		if err := encodeField(field, value, ctx); err != nil {
 			return err
 		}
+		if field.Var != "" {
+			ctx.Variables[field.Var] = value
+		}
 	}
 	return nil
 }
 
-func encodeFlagged(fd *FlaggedDef, data map[string]any, ctx *EncodeContext) error {
-	flags := 0
-	for _, group := range fd.Groups {
-		for _, gf := range group.Fields {
-			if gf.Name != "" {
-				if _, ok := data[gf.Name]; ok {
-					flags |= (1 << group.Bit)
-					break
+// lookupEncodeValue returns the input value for a field, accepting the
+// field name or any of its aliases. Keys set to Omit count as absent.
+func lookupEncodeValue(field Field, data map[string]any) (any, bool) {
+	if v, ok := data[field.Name]; ok {
+		return v, v != Omit
+	}
+	for _, alias := range field.Aliases {
+		if v, ok := data[alias]; ok {
+			return v, v != Omit
+		}
+	}
+	return nil, false
+}
+
+// hasEncodeValues reports whether data has a value for any named field in
+// fields, following $ref definitions and nested flagged groups.
+func hasEncodeValues(fields []Field, data map[string]any, defs map[string]*DefinitionDef, depth int) bool {
+	for _, f := range fields {
+		if f.Ref2 != "" && depth < maxRefDepth {
+			if def, err := lookupDefinition(f.Ref2, defs); err == nil &&
+				hasEncodeValues(def.Fields, data, defs, depth+1) {
+				return true
+			}
+			continue
+		}
+		if f.Flagged != nil && depth < maxRefDepth {
+			for _, group := range f.Flagged.Groups {
+				if hasEncodeValues(group.Fields, data, defs, depth+1) {
+					return true
 				}
 			}
+			continue
+		}
+		if f.Name != "" {
+			if _, ok := lookupEncodeValue(f, data); ok {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	for _, group := range fd.Groups {
-		if (flags>>group.Bit)&1 == 0 {
+func isSkipField(field Field) bool {
+	return field.Type == TypeSkip || field.Type == TypeSkipLower || isReservedType(field.Type) || isMagicType(field.Type)
+}
+
+func errRequired(field Field) error {
+	return fmt.Errorf("required field %s missing from encode input", field.Name)
+}
+
+// encodeByteGroup packs byte group members back into their shared bytes.
+// Each member must fit its bit range; out-of-range values are an error
+// rather than being silently masked into neighbouring members.
+func encodeByteGroup(field Field, data map[string]any, ctx *EncodeContext) error {
+	size := byteGroupSize(field)
+	var rawVal uint64
+	for _, subfield := range field.ByteGroup {
+		value, ok := lookupEncodeValue(subfield, data)
+		if !ok || subfield.Name == "" {
+			if !ok && subfield.Required {
+				return errRequired(subfield)
+			}
 			continue
 		}
-		for _, gf := range group.Fields {
-			if gf.Name == "" || strings.HasPrefix(gf.Name, "_") {
-				continue
-			}
-			if gf.Formula != "" && (gf.Type == TypeNumber || gf.Type == "number") {
-				continue
+		bitStart, bitLen, _ := byteGroupBits(subfield)
+		if bitStart+bitLen > size*8 {
+			return fmt.Errorf("byte_group member %s: bits %d..%d exceed %d-byte group",
+				subfield.Name, bitStart, bitStart+bitLen-1, size)
+		}
+		var bits uint64
+		if b, isBool := value.(bool); isBool {
+			if b {
+				bits = 1
 			}
-			value, ok := data[gf.Name]
+		} else {
+			numVal, ok := toFloat64(reverseValue(subfield, value))
 			if !ok {
-				continue
+				return fmt.Errorf("byte_group member %s: cannot encode %v", subfield.Name, value)
 			}
-			if err := encodeField(gf, value, ctx); err != nil {
-				return err
+			numVal = ctx.round(numVal)
+			maxVal := math.Ldexp(1, bitLen) - 1
+			if numVal < 0 || numVal > maxVal {
+				return fmt.Errorf("byte_group member %s: %v overflows %d-bit range [0, %v]",
+					subfield.Name, numVal, bitLen, maxVal)
 			}
+			bits = uint64(numVal)
+		}
+		rawVal |= bits << bitStart
+		if subfield.Var != "" {
+			ctx.Variables[subfield.Var] = value
 		}
 	}
+	ctx.Write(encodeUint(rawVal, size, "little"))
 	return nil
 }
 
-func encodeBitfieldString(field Field, strVal string, ctx *EncodeContext) error {
-	parts := field.Parts
-	delimiter := field.Delimiter
-	if delimiter == "" {
-		delimiter = "."
+// encodeFlagged writes the groups of fd that have values in data, in
+// the order they are declared, as decodeFlagged reads them.
+func encodeFlagged(fd *FlaggedDef, data map[string]any, ctx *EncodeContext) error {
+	for _, group := range fd.Groups {
+		if !hasEncodeValues(group.Fields, data, ctx.Definitions, 0) {
+			continue
+		}
+		// Required fields are only required when their group is sent
+		if err := encodeFields(group.Fields, data, ctx); err != nil {
+			return err
+		}
 	}
-	prefix := field.Prefix
+	return nil
+}
 
-	if prefix != "" && strings.HasPrefix(strVal, prefix) {
-		strVal = strVal[len(prefix):]
-	}
+// flagPatch is the value a flags field is encoded with: the bits of the
+// groups present, within the bits the groups own.
+type flagPatch struct {
+	flags int
+	mask  int
+}
 
-	segments := strings.Split(strVal, delimiter)
-	length := field.Length
-	if length == 0 {
-		length = 2
+// flagPatches computes the flags field values implied by the flagged
+// constructs encoded from fields, by flags field name. It follows $refs
+// and flagged groups, which share data, and nested objects; a flags field
+// declared inside an object is resolved by the object's own scan. Flags
+// shared by several constructs combine their groups.
+func flagPatches(fields []Field, data map[string]any, defs map[string]*DefinitionDef, depth int) map[string]flagPatch {
+	patches := map[string]flagPatch{}
+	if depth >= maxRefDepth {
+		return patches
+	}
+	merge := func(from map[string]flagPatch, skip func(string) bool) {
+		for name, p := range from {
+			if skip != nil && skip(name) {
+				continue
+			}
+			q := patches[name]
+			patches[name] = flagPatch{flags: q.flags | p.flags, mask: q.mask | p.mask}
+		}
 	}
-	endian := field.Endian
-	if endian == "" {
-		endian = ctx.Endian
+	for _, field := range fields {
+		switch {
+		case field.Ref2 != "":
+			if def, err := lookupDefinition(field.Ref2, defs); err == nil {
+				merge(flagPatches(def.Fields, data, defs, depth+1), nil)
+			}
+		case field.Flagged != nil:
+			var p flagPatch
+			for _, group := range field.Flagged.Groups {
+				p.mask |= 1 << group.Bit
+				if hasEncodeValues(group.Fields, data, defs, 0) {
+					p.flags |= 1 << group.Bit
+				}
+				merge(flagPatches(group.Fields, data, defs, depth+1), nil)
+			}
+			merge(map[string]flagPatch{field.Flagged.Field: p}, nil)
+		case (field.Type == TypeObject || field.Type == TypeObjectLower) && field.Name != "":
+			sub, _ := data[field.Name].(map[string]any)
+			merge(flagPatches(field.Fields, sub, defs, depth+1), func(name string) bool {
+				return declaresField(field.Fields, name, defs, 0)
+			})
+		}
+	}
+	return patches
+}
+
+// declaresField reports whether fields encode a field called name at
+// their own level.
+func declaresField(fields []Field, name string, defs map[string]*DefinitionDef, depth int) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+		if f.Ref2 != "" && depth < maxRefDepth {
+			if def, err := lookupDefinition(f.Ref2, defs); err == nil && declaresField(def.Fields, name, defs, depth+1) {
+				return true
+			}
+		}
+		if f.Flagged != nil {
+			for _, group := range f.Flagged.Groups {
+				if declaresField(group.Fields, name, defs, depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// flagPatch finds the value of a flags field, looking outward from the
+// field list being encoded.
+func (ctx *EncodeContext) flagPatch(name string) (flagPatch, bool) {
+	for i := len(ctx.flagScopes) - 1; i >= 0; i-- {
+		if p, ok := ctx.flagScopes[i][name]; ok {
+			return p, true
+		}
+	}
+	return flagPatch{}, false
+}
+
+// encodeMatch encodes the case a match decodes. A variable selector is
+// taken from its value in scope; otherwise the first case whose fields
+// have values in data is used, and an inline selector is written for it.
+func encodeMatch(field Field, scope, data map[string]any, ctx *EncodeContext) error {
+	chosen, selector, err := chooseEncodeCase(field, ctx.Variables, scope, data, ctx.Definitions)
+	if err != nil {
+		return err
+	}
+
+	if field.On == "" {
+		if chosen == nil {
+			return fmt.Errorf("match: no case has values to encode")
+		}
+		length := field.Length
+		if length == 0 {
+			length = 1
+		}
+		ctx.Write(encodeUint(uint64(selector), length, ctx.Endian))
+	}
+	if chosen == nil && field.revisions {
+		return fmt.Errorf("no revision for %s = %d", strings.TrimPrefix(field.On, "$"), selector)
+	}
+	if chosen == nil {
+		// No case matching decodes nothing
+		return nil
+	}
+	return encodeFields(chosen.Fields, data, ctx)
+}
+
+// chooseEncodeCase returns the match case data encodes and its selector
+// value: the case the $var selector picks, else the first case with values
+// in data, else the default case. The selector is the variable set by the
+// field declaring var:, or else the input value of that name in scope.
+func chooseEncodeCase(field Field, vars, scope, data map[string]any, defs map[string]*DefinitionDef) (*Case, int, error) {
+	var chosen *Case
+	selector := 0
+	if field.On != "" && bareVarPattern.MatchString(field.On) {
+		name := strings.TrimPrefix(field.On, "$")
+		v, ok := vars[name]
+		if !ok {
+			v, ok = scope[name]
+		}
+		if !ok {
+			return nil, 0, fmt.Errorf("match on %s: selector value missing", field.On)
+		}
+		selector, _ = toInt(v)
+		for i := range field.Cases {
+			if field.Cases[i].Default || caseMatches(field.Cases[i], selector) {
+				chosen = &field.Cases[i]
+				break
+			}
+		}
+		return chosen, selector, nil
+	}
+	for i := range field.Cases {
+		c := &field.Cases[i]
+		if rep, ok := caseRepresentative(*c); ok && !c.Default && hasEncodeValues(c.Fields, data, defs, 0) {
+			return c, int(rep), nil
+		}
+	}
+	for i := range field.Cases {
+		if field.Cases[i].Default {
+			rep, ok := unmatchedCaseValue(field.Cases)
+			if !ok {
+				return nil, 0, fmt.Errorf("match: no selector value reaches the default case")
+			}
+			return &field.Cases[i], int(rep), nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// caseRepresentative returns a selector value that matches the case.
+func caseRepresentative(c Case) (uint64, bool) {
+	v := c.Case
+	if v == nil {
+		v = c.Match
+	}
+	switch cv := v.(type) {
+	case []any:
+		if len(cv) == 0 {
+			return 0, false
+		}
+		v = cv[0]
+	case map[string]any:
+		v = cv["min"]
+		if v == nil {
+			v = 0
+		}
+	}
+	n, ok := toInt(v)
+	return uint64(n), ok && n >= 0
+}
+
+// unmatchedCaseValue returns a selector value no explicit case matches.
+func unmatchedCaseValue(cases []Case) (uint64, bool) {
+	for v := 0; v < 256; v++ {
+		matched := false
+		for _, c := range cases {
+			if !c.Default && caseMatches(c, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return uint64(v), true
+		}
+	}
+	return 0, false
+}
+
+func caseMatches(c Case, v int) bool {
+	cv := c.Case
+	if cv == nil {
+		cv = c.Match
+	}
+	switch x := cv.(type) {
+	case []any:
+		for _, item := range x {
+			if n, ok := toInt(item); ok && n == v {
+				return true
+			}
+		}
+		return false
+	case map[string]any:
+		lo, hi := math.MinInt, math.MaxInt
+		if m, ok := x["min"]; ok {
+			lo, _ = toInt(m)
+		}
+		if m, ok := x["max"]; ok {
+			hi, _ = toInt(m)
+		}
+		return v >= lo && v <= hi
+	}
+	n, ok := toInt(cv)
+	return ok && n == v
+}
+
+func encodeBitfieldString(field Field, strVal string, ctx *EncodeContext) error {
+	parts := field.Parts
+	delimiter := field.Delimiter
+	if delimiter == "" {
+		delimiter = "."
+	}
+	prefix := field.Prefix
+
+	if prefix != "" && strings.HasPrefix(strVal, prefix) {
+		strVal = strVal[len(prefix):]
+	}
+
+	segments := strings.Split(strVal, delimiter)
+	length := field.Length
+	if length == 0 {
+		length = 2
+	}
+	endian := field.Endian
+	if endian == "" {
+		endian = ctx.Endian
 	}
 
 	var intVal uint64
@@ -2359,7 +3918,11 @@ func encodeBitfieldString(field Field, strVal string, ctx *EncodeContext) error
 	return nil
 }
 
-func encodeField(field Field, value any, ctx *EncodeContext) error {
+func encodeField(field Field, value any, ctx *EncodeContext) (err error) {
+	if field.Type == TypeVector3 {
+		// Modifiers apply per axis
+		return encodeVector3(field, value, ctx)
+	}
 	length := field.Length
 	if length == 0 {
 		length = inferLengthFromType(field.Type)
@@ -2369,6 +3932,215 @@ func encodeField(field Field, value any, ctx *EncodeContext) error {
 		endian = ctx.Endian
 	}
 
+	if value == nil && len(field.Invalid) > 0 {
+		// null round-trips to the first sentinel
+		value = field.Invalid[0]
+	} else if value == nil && field.NonFinite == NonFiniteNull {
+		value = math.NaN()
+	} else if field.Output == OutputDecimalString && field.Scale != nil {
+		if value, err = decimalEncodeValue(field, value); err != nil {
+			return err
+		}
+	} else {
+		value = reverseValue(field, value)
+		if field.ScaleBy != "" {
+			if value, err = ctx.unscale(field, value); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.StrictTypes {
+		if err := ctx.checkEncodeType(field, value); err != nil {
+			return err
+		}
+	}
+
+	if field.ByteOrder != "" {
+		start := len(ctx.Buffer)
+		defer func() {
+			if err == nil {
+				err = scrambleBytes(field, ctx.Buffer[start:])
+			}
+		}()
+	}
+	if readsRest(field) {
+		return encodeRest(field, value, endian, ctx)
+	}
+
+	switch field.Type {
+	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU32, TypeU64, TypeU24:
+		u, ok, err := ctx.fitUint(field, value, length)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ctx.Write(encodeUint(u, length, endian))
+		}
+
+	case TypeSInt, TypeS8, TypeS16, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64, TypeS24:
+		n, ok, err := ctx.fitSint(field, value, length)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ctx.Write(encodeSint(n, length, endian))
+		}
+
+	case TypeBInt:
+		u, ok, err := ctx.fitUint(field, value, length)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ctx.Write(encodeUint(u, length, "big"))
+		}
+
+	case TypeFloat16, TypeF16:
+		if numVal, ok := toFloat64(value); ok {
+			ctx.Write(encodeFloat16(numVal, endian))
+		}
+
+	case TypeFloat32, TypeF32:
+		if numVal, ok := toFloat64(value); ok {
+			ctx.Write(encodeFloat32(float32(numVal), endian))
+		}
+
+	case TypeFloat64, TypeF64:
+		if numVal, ok := toFloat64(value); ok {
+			ctx.Write(encodeFloat64(numVal, endian))
+		}
+
+	case TypeFixed, TypeUFixed:
+		if err := encodeFixed(field, value, endian, ctx); err != nil {
+			return err
+		}
+
+	case TypeEnum, TypeEnumLower:
+		intVal, err := enumEncodeValue(field, value)
+		if err != nil {
+			return err
+		}
+		ctx.Write(encodeUint(uint64(intVal), enumBaseLength(field), endian))
+
+	case TypeString, TypeStringLower, TypeAscii, TypeAsciiLower:
+		if !isConstString(field) {
+			if err := encodeText(field, value, length, endian, ctx); err != nil {
+				return err
+			}
+		}
+
+	case TypeHex:
+		if strVal, ok := value.(string); ok {
+			strVal = strings.ReplaceAll(strVal, ":", "")
+			strVal = strings.ReplaceAll(strVal, "-", "")
+			data, _ := hex.DecodeString(strVal)
+			padded := make([]byte, length)
+			copy(padded, data)
+			ctx.Write(padded)
+		}
+
+	case TypeBytes, TypeBytesLower:
+		if err := encodeBytes(field, value, length, ctx); err != nil {
+			return err
+		}
+
+	case TypeObject:
+		if mapVal, ok := value.(map[string]any); ok {
+			ctx.path = append(ctx.path, field.Name)
+			err := encodeFields(field.Fields, mapVal, ctx)
+			ctx.path = ctx.path[:len(ctx.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+
+	case TypeRepeat, TypeRepeatLower, TypeSeries:
+		if arrVal, ok := value.([]any); ok {
+			for i, elem := range arrVal {
+				// Flattened repeats accept bare values as well as objects
+				if _, isMap := elem.(map[string]any); !isMap && field.Flatten {
+					name, err := flattenName(field)
+					if err != nil {
+						return err
+					}
+					elem = map[string]any{name: elem}
+				}
+				if elemMap, ok := elem.(map[string]any); ok {
+					ctx.path = append(ctx.path, fmt.Sprintf("%s[%d]", field.Name, i))
+					err := encodeFields(field.Fields, elemMap, ctx)
+					ctx.path = ctx.path[:len(ctx.path)-1]
+					if err != nil {
+						return err
+					}
+				} else if ctx.StrictTypes {
+					return fmt.Errorf("%s[%d]: expected object, got %s", ctx.fieldPath(field.Name), i, describeInput(elem))
+				}
+			}
+		}
+
+	case TypeBool, TypeBoolLower:
+		if err := ctx.encodeBool(field, value); err != nil {
+			return err
+		}
+
+	case TypeBits, TypeBitsLower:
+		if err := ctx.encodeBits(field, value, endian); err != nil {
+			return err
+		}
+
+	case TypeSkip, TypeSkipLower:
+		ctx.Write(make([]byte, length))
+
+	case TypeReserved, TypeReservedLower:
+		ctx.Write(reservedBytes(field, length))
+
+	case TypeMagic, TypeMagicLower:
+		ctx.Write(field.Expect)
+	}
+
+	return nil
+}
+
+// enumBaseLength returns the byte size of an enum's base: type.
+func enumBaseLength(field Field) int {
+	switch field.Base {
+	case "u16", "s16":
+		return 2
+	case "u32", "s32":
+		return 4
+	}
+	return 1
+}
+
+// enumEncodeValue maps an enum name back to its integer via values:.
+// Numbers pass through, mirroring decode of values missing from the table.
+func enumEncodeValue(field Field, value any) (int, error) {
+	if strVal, ok := value.(string); ok {
+		for k, v := range field.Values {
+			if v == strVal {
+				return k, nil
+			}
+		}
+		if intVal, ok := parseUnknownLabel(field, strVal); ok {
+			return intVal, nil
+		}
+		return 0, fmt.Errorf("field %s: %q is not a value of the enum", field.Name, strVal)
+	}
+	if intVal, ok := toInt(value); ok {
+		return intVal, nil
+	}
+	return 0, fmt.Errorf("field %s: cannot encode %T as enum", field.Name, value)
+}
+
+// reverseValue undoes a field's lookup and modifiers for encoding.
+func reverseValue(field Field, value any) any {
+	// Formatted numbers come back as strings
+	if strVal, ok := value.(string); ok && field.AsString {
+		if numVal, err := strconv.ParseFloat(strings.TrimSpace(strVal), 64); err == nil {
+			value = numVal
+		}
+	}
+
 	// Reverse lookup if value is a string and lookup exists
 	if strVal, ok := value.(string); ok && field.Lookup != nil {
 		for k, v := range field.Lookup {
@@ -2377,10 +4149,16 @@ func encodeField(field Field, value any, ctx *EncodeContext) error {
 				break
 			}
 		}
+		if _, unmatched := value.(string); unmatched {
+			if intVal, ok := parseUnknownLabel(field, strVal); ok {
+				value = float64(intVal)
+			}
+		}
 	}
 
 	// Reverse modifiers for numeric values
 	if numVal, ok := toFloat64(value); ok {
+		input := numVal
 		// Reverse stages in reverse order; within each stage, reverse ops
 		if len(field.Transform) > 0 {
 			for i := len(field.Transform) - 1; i >= 0; i-- {
@@ -2438,75 +4216,25 @@ func encodeField(field Field, value any, ctx *EncodeContext) error {
 				numVal = numVal - *field.Add
 			}
 		}
-		value = numVal
-	}
-
-	switch field.Type {
-	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU32, TypeU64:
-		if numVal, ok := toFloat64(value); ok {
-			ctx.Write(encodeUint(uint64(numVal), length, endian))
-		}
-
-	case TypeSInt, TypeS8, TypeS16, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64:
-		if numVal, ok := toFloat64(value); ok {
-			ctx.Write(encodeSint(int64(numVal), length, endian))
-		}
-
-	case TypeFloat32, TypeF32:
-		if numVal, ok := toFloat64(value); ok {
-			ctx.Write(encodeFloat32(float32(numVal), endian))
-		}
-
-	case TypeFloat64, TypeF64:
-		if numVal, ok := toFloat64(value); ok {
-			ctx.Write(encodeFloat64(numVal, endian))
-		}
-
-	case TypeAscii:
-		if strVal, ok := value.(string); ok {
-			data := make([]byte, length)
-			copy(data, []byte(strVal))
-			ctx.Write(data)
-		}
-
-	case TypeHex:
-		if strVal, ok := value.(string); ok {
-			strVal = strings.ReplaceAll(strVal, ":", "")
-			strVal = strings.ReplaceAll(strVal, "-", "")
-			data, _ := hex.DecodeString(strVal)
-			padded := make([]byte, length)
-			copy(padded, data)
-			ctx.Write(padded)
-		}
-
-	case TypeBytes, TypeBytesLower:
-		if err := encodeBytes(field, value, length, ctx); err != nil {
-			return err
-		}
-
-	case TypeObject:
-		if mapVal, ok := value.(map[string]any); ok {
-			if err := encodeFields(field.Fields, mapVal, ctx); err != nil {
-				return err
-			}
-		}
-
-	case TypeRepeat, TypeRepeatLower:
-		if arrVal, ok := value.([]any); ok {
-			for _, elem := range arrVal {
-				if elemMap, ok := elem.(map[string]any); ok {
-					if err := encodeFields(field.Fields, elemMap, ctx); err != nil {
-						return err
-					}
+		if field.Curve != nil {
+			if raw, ok := field.Curve.Invert(numVal); ok {
+				numVal = raw
+				switch field.Type {
+				case TypeF16, TypeF32, TypeF64, TypeFloat16, TypeFloat32, TypeFloat64, TypeFixed, TypeUFixed:
+				default:
+					// Integer raw values: nearest step, not truncation
+					numVal = math.Round(raw)
 				}
 			}
 		}
-
-	case TypeSkip, TypeSkipLower:
-		ctx.Write(make([]byte, length))
+		if numVal == 0 && !math.Signbit(input) {
+			// 0 through a negative factor is -0, which float fields
+			// would write with the sign bit set
+			numVal = 0
+		}
+		value = numVal
 	}
-
-	return nil
+	return value
 }
 
 func encodeBytes(field Field, value any, length int, ctx *EncodeContext) error {
@@ -2553,6 +4281,24 @@ func encodeBytes(field Field, value any, length int, ctx *EncodeContext) error {
 
 func encodeUint(val uint64, length int, endian string) []byte {
 	buf := make([]byte, length)
+	var order binary.ByteOrder = binary.BigEndian
+	if endian == "little" {
+		order = binary.LittleEndian
+	}
+	switch length {
+	case 1:
+		buf[0] = byte(val)
+		return buf
+	case 2:
+		order.PutUint16(buf, uint16(val))
+		return buf
+	case 4:
+		order.PutUint32(buf, uint32(val))
+		return buf
+	case 8:
+		order.PutUint64(buf, val)
+		return buf
+	}
 	if endian == "little" {
 		for i := 0; i < length; i++ {
 			buf[i] = byte(val >> (8 * i))
@@ -2574,6 +4320,53 @@ func encodeSint(val int64, length int, endian string) []byte {
 	return encodeUint(uint64(val), length, endian)
 }
 
+func encodeFloat16(val float64, endian string) []byte {
+	buf := make([]byte, 2)
+	if endian == "little" {
+		binary.LittleEndian.PutUint16(buf, float64ToFloat16(val))
+	} else {
+		binary.BigEndian.PutUint16(buf, float64ToFloat16(val))
+	}
+	return buf
+}
+
+// float64ToFloat16 converts to IEEE 754 half precision, rounding to
+// nearest even. Out-of-range values become infinity.
+func float64ToFloat16(f float64) uint16 {
+	b := math.Float32bits(float32(f))
+	sign := uint16(b>>16) & 0x8000
+	mant := b & 0x7fffff
+	if (b>>23)&0xff == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00 // NaN
+		}
+		return sign | 0x7c00
+	}
+	exp := int((b>>23)&0xff) - 127 + 15
+	switch {
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		// Subnormal half
+		if exp < -10 {
+			return sign
+		}
+		full := mant | 0x800000
+		shift := uint(14 - exp)
+		m := full >> shift
+		rem, half := full&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > half || (rem == half && m&1 == 1) {
+			m++
+		}
+		return sign | uint16(m)
+	}
+	h := sign | uint16(exp)<<10 | uint16(mant>>13)
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && h&1 == 1) {
+		h++ // May carry into the exponent, up to infinity
+	}
+	return h
+}
+
 func encodeFloat32(val float32, endian string) []byte {
 	buf := make([]byte, 4)
 	bits := math.Float32bits(val)
@@ -2600,9 +4393,32 @@ func encodeFloat64(val float64, endian string) []byte {
 // Helper functions
 // =============================================================================
 
+// decodeUint reads an unsigned integer of any width up to 8 bytes. The
+// common widths go through encoding/binary; others are assembled a byte
+// at a time.
 func decodeUint(data []byte, endian string) uint64 {
+	little := endian == "little"
+	switch len(data) {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		if little {
+			return uint64(binary.LittleEndian.Uint16(data))
+		}
+		return uint64(binary.BigEndian.Uint16(data))
+	case 4:
+		if little {
+			return uint64(binary.LittleEndian.Uint32(data))
+		}
+		return uint64(binary.BigEndian.Uint32(data))
+	case 8:
+		if little {
+			return binary.LittleEndian.Uint64(data)
+		}
+		return binary.BigEndian.Uint64(data)
+	}
 	var val uint64
-	if endian == "little" {
+	if little {
 		for i := len(data) - 1; i >= 0; i-- {
 			val = (val << 8) | uint64(data[i])
 		}
@@ -2616,6 +4432,16 @@ func decodeUint(data []byte, endian string) uint64 {
 
 func decodeSint(data []byte, endian string) int64 {
 	uval := decodeUint(data, endian)
+	switch len(data) {
+	case 1:
+		return int64(int8(uval))
+	case 2:
+		return int64(int16(uval))
+	case 4:
+		return int64(int32(uval))
+	case 8:
+		return int64(uval)
+	}
 	bits := len(data) * 8
 	signBit := uint64(1) << (bits - 1)
 	if uval >= signBit {
@@ -2720,9 +4546,56 @@ func toInt(v any) (int, bool) {
 	return 0, false
 }
 
-// Compact format parsing
+// Numeric coercion for schema sources. YAML yields int for whole numbers
+// while JSON always yields float64; schema keys are read through these
+// helpers so both sources parse identically.
+
+// numberKey reads a numeric key of either representation.
+func numberKey(m map[string]any, key string) (float64, bool) {
+	return toFloat64(m[key])
+}
+
+// numberPtr reads an optional numeric key, returning nil when absent.
+func numberPtr(m map[string]any, key string) *float64 {
+	if v, ok := numberKey(m, key); ok {
+		return &v
+	}
+	return nil
+}
 
-var compactFormatPattern = regexp.MustCompile(`(\d*)([a-zA-Z?]):?(\w*)`)
+// intKey reads an integer key. Floats are accepted only when whole, so
+// `length: 2.0` from JSON matches `length: 2` from YAML.
+func intKey(m map[string]any, key string) (int, bool) {
+	return toWholeInt(m[key])
+}
+
+func toWholeInt(v any) (int, bool) {
+	if f, ok := v.(float64); ok && f != math.Trunc(f) {
+		return 0, false
+	}
+	return toInt(v)
+}
+
+// wholeNumber converts whole floats to int so match case values compare
+// the same regardless of source; other values are returned unchanged.
+func wholeNumber(v any) any {
+	if f, ok := v.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int(f)
+	}
+	return v
+}
+
+// Compact format parsing
+//
+// The grammar, after optional leading blanks and comments:
+//
+//	format  = [order] { blank | comment | item }
+//	order   = ">" | "<" | "!" | "=" | "@"
+//	item    = [count] code [":" name]
+//	count   = digit { digit }
+//	code    = one of the structFormats keys
+//	name    = word { word }             (letters, digits, _)
+//	comment = "#" { any } end of line
 
 var structFormats = map[byte]struct {
 	Type   FieldType
@@ -2756,44 +4629,125 @@ var byteOrderPrefixes = map[byte]string{
 	'@': "native",
 }
 
+// hostEndian is the byte order of the machine running the decoder, which
+// the compact format's native prefixes ('=' and '@') select.
+var hostEndian = func() string {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) == 1 {
+		return "little"
+	}
+	return "big"
+}()
+
+// maxCompactCount bounds a compact repeat count, which expands into that
+// many fields before any payload is read.
+const maxCompactCount = 1 << 20
+
 // ParseCompactFormat parses a Python struct-like format string into fields.
+// Blanks separate items and "#" starts a comment; anything else that does
+// not fit the grammar is an error naming its byte position.
+// The returned endian is the prefix's order ("native" for '=' and '@');
+// the fields carry the resolved order. As in Python, '@' also aligns each
+// multi-byte number to its size, inserting skip fields. Sizes are always
+// the standard ones.
 func ParseCompactFormat(format string) ([]Field, string, error) {
-	endian := "big"
+	return parseCompactFormat(format, -1)
+}
 
-	if len(format) > 0 {
-		if e, ok := byteOrderPrefixes[format[0]]; ok {
-			endian = e
-			format = format[1:]
-		}
-	}
+// parseCompactFormat is ParseCompactFormat that stops with a buffer
+// underflow once the fields need more than maxBytes (if not negative), so
+// a huge count is not expanded for a short payload.
+func parseCompactFormat(format string, maxBytes int) ([]Field, string, error) {
+	endian := "big"
+	align := false
+	fieldEndian := endian
 
 	var fields []Field
-	matches := compactFormatPattern.FindAllStringSubmatch(format, -1)
+	offset := 0
+	started := false
+
+	for pos := 0; pos < len(format); {
+		c := format[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+			continue
+		case c == '#':
+			for pos < len(format) && format[pos] != '\n' {
+				pos++
+			}
+			continue
+		}
 
-	for _, match := range matches {
-		countStr, fmtChar, name := match[1], match[2][0], match[3]
+		if e, ok := byteOrderPrefixes[c]; ok {
+			if started {
+				return nil, "", fmt.Errorf("compact format: byte order %q at position %d must come first", c, pos)
+			}
+			endian, align = e, c == '@'
+			fieldEndian = endian
+			if endian == "native" {
+				fieldEndian = hostEndian
+			}
+			started = true
+			pos++
+			continue
+		}
+		started = true
 
+		start := pos
+		for pos < len(format) && format[pos] >= '0' && format[pos] <= '9' {
+			pos++
+		}
 		count := 1
-		if countStr != "" {
-			count, _ = strconv.Atoi(countStr)
+		if pos > start {
+			n, err := strconv.Atoi(format[start:pos])
+			if err != nil || n > maxCompactCount {
+				return nil, "", fmt.Errorf("compact format: count %s at position %d is too large", format[start:pos], start)
+			}
+			count = n
+		}
+		if pos == len(format) {
+			return nil, "", fmt.Errorf("compact format: count at position %d has no format character", start)
 		}
 
+		fmtChar := format[pos]
 		spec, ok := structFormats[fmtChar]
 		if !ok {
-			return nil, "", fmt.Errorf("unknown format character: %c", fmtChar)
+			return nil, "", fmt.Errorf("compact format: unknown format character %q at position %d", fmtChar, pos)
+		}
+		pos++
+
+		name := ""
+		if pos < len(format) && format[pos] == ':' {
+			pos++
+			nameStart := pos
+			for pos < len(format) && isIdentByte(format[pos]) {
+				pos++
+			}
+			if pos == nameStart {
+				return nil, "", fmt.Errorf("compact format: expected a name after ':' at position %d", nameStart)
+			}
+			name = format[nameStart:pos]
 		}
 
 		length := spec.Length
 		if fmtChar == 's' || fmtChar == 'p' {
 			length = count
 			count = 1
+		} else if align && length > 1 && offset%length != 0 {
+			pad := length - offset%length
+			fields = append(fields, Field{Type: TypeSkip, Length: pad})
+			offset += pad
 		}
 
+		if maxBytes >= 0 && offset+count*length > maxBytes {
+			return nil, "", fmt.Errorf("buffer underflow: format needs %d bytes, but payload has %d",
+				offset+count*length, maxBytes)
+		}
 		for i := 0; i < count; i++ {
 			field := Field{
 				Type:   spec.Type,
 				Length: length,
-				Endian: endian,
+				Endian: fieldEndian,
 			}
 			if name != "" {
 				if count > 1 {
@@ -2803,6 +4757,7 @@ func ParseCompactFormat(format string) ([]Field, string, error) {
 				}
 			}
 			fields = append(fields, field)
+			offset += length
 		}
 	}
 
@@ -2811,7 +4766,7 @@ func ParseCompactFormat(format string) ([]Field, string, error) {
 
 // DecodeCompact decodes binary data using a compact format string.
 func DecodeCompact(format string, data []byte) (map[string]any, error) {
-	fields, endian, err := ParseCompactFormat(format)
+	fields, endian, err := parseCompactFormat(format, len(data))
 	if err != nil {
 		return nil, err
 	}
@@ -2880,7 +4835,7 @@ func evaluateCompute(cd *ComputeDef, ctx *DecodeContext) (float64, error) {
 func resolveOperand(op string, ctx *DecodeContext) (float64, error) {
 	if strings.HasPrefix(op, "$") {
 		name := op[1:]
-		if val, ok := ctx.Variables[name]; ok {
+		if val, ok := lookupVar(ctx.Variables, name); ok {
 			if f, ok := toFloat64(val); ok {
 				return f, nil
 			}
@@ -2891,387 +4846,158 @@ func resolveOperand(op string, ctx *DecodeContext) (float64, error) {
 }
 
 // evaluateGuard applies guard conditions, returning value if all pass or else.
-func evaluateGuard(gd *GuardDef, value float64, ctx *DecodeContext) float64 {
+func evaluateGuard(gd *GuardDef, value any, ctx *DecodeContext) any {
 	for _, cond := range gd.When {
-		fieldName := strings.TrimPrefix(cond.Field, "$")
-		fieldVal, ok := ctx.Variables[fieldName]
-		if !ok {
-			return gd.Else
-		}
-		fv, ok := toFloat64(fieldVal)
-		if !ok {
-			return gd.Else
-		}
-
-		// Check all conditions on this field
-		if cond.Gt != nil && !(fv > *cond.Gt) {
-			return gd.Else
-		}
-		if cond.Gte != nil && !(fv >= *cond.Gte) {
-			return gd.Else
-		}
-		if cond.Lt != nil && !(fv < *cond.Lt) {
-			return gd.Else
-		}
-		if cond.Lte != nil && !(fv <= *cond.Lte) {
-			return gd.Else
-		}
-		if cond.Eq != nil && fv != *cond.Eq {
+		if holds, known := guardHolds(cond, value, ctx); !holds || !known {
 			return gd.Else
 		}
 	}
 	return value
 }
 
-// evaluateFormula (DEPRECATED - use polynomial/compute/guard instead)
-// Supports: $field_name references, x (raw value), pow/abs/sqrt/min/max,
-// arithmetic operators, ternary (cond ? a : b), and/or.
-func evaluateFormula(formula string, x float64, ctx *DecodeContext) (float64, error) {
-	expr := formula
-
-	// Substitute $field_name references
-	varPattern := regexp.MustCompile(`\$([a-zA-Z_][a-zA-Z0-9_]*)`)
-	expr = varPattern.ReplaceAllStringFunc(expr, func(match string) string {
-		name := match[1:]
-		if val, ok := ctx.Variables[name]; ok {
-			if f, ok := toFloat64(val); ok {
-				return strconv.FormatFloat(f, 'f', -1, 64)
+// guardHolds evaluates one condition: its tests on field and its any/all/
+// not groups, all of which must hold. known is false when the condition
+// cannot be evaluated (a missing variable, or a non-number for a numeric
+// test); such a condition fails the guard even under not.
+func guardHolds(cond GuardCondition, value any, ctx *DecodeContext) (holds, known bool) {
+	if len(cond.All) > 0 {
+		for _, sub := range cond.All {
+			if holds, known := guardHolds(sub, value, ctx); !holds || !known {
+				return false, known
 			}
 		}
-		return "0"
-	})
-
-	// Replace standalone 'x' with raw value
-	xPattern := regexp.MustCompile(`\bx\b`)
-	expr = xPattern.ReplaceAllString(expr, strconv.FormatFloat(x, 'f', -1, 64))
-
-	// Replace 'and'/'or' with Go-compatible tokens for our evaluator
-	expr = regexp.MustCompile(`\band\b`).ReplaceAllString(expr, "&&")
-	expr = regexp.MustCompile(`\bor\b`).ReplaceAllString(expr, "||")
-
-	return evalExpr(expr)
-}
-
-// evalExpr is a simple recursive descent expression parser.
-// Supports: +, -, *, /, >, <, >=, <=, ==, !=, &&, ||, ternary (? :),
-// pow(), abs(), sqrt(), min(), max(), parentheses, and numeric literals.
-func evalExpr(expr string) (float64, error) {
-	p := &exprParser{input: strings.TrimSpace(expr), pos: 0}
-	val, err := p.parseTernary()
-	if err != nil {
-		return 0, fmt.Errorf("formula eval failed for %q: %w", expr, err)
-	}
-	return val, nil
-}
-
-type exprParser struct {
-	input string
-	pos   int
-}
-
-func (p *exprParser) skipSpaces() {
-	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
-		p.pos++
 	}
-}
-
-func (p *exprParser) peek() byte {
-	p.skipSpaces()
-	if p.pos >= len(p.input) {
-		return 0
-	}
-	return p.input[p.pos]
-}
-
-func (p *exprParser) peekStr(n int) string {
-	p.skipSpaces()
-	end := p.pos + n
-	if end > len(p.input) {
-		end = len(p.input)
-	}
-	return p.input[p.pos:end]
-}
-
-func (p *exprParser) parseTernary() (float64, error) {
-	val, err := p.parseOr()
-	if err != nil {
-		return 0, err
-	}
-	p.skipSpaces()
-	if p.pos < len(p.input) && p.input[p.pos] == '?' {
-		p.pos++
-		trueVal, err := p.parseTernary()
-		if err != nil {
-			return 0, err
-		}
-		p.skipSpaces()
-		if p.pos < len(p.input) && p.input[p.pos] == ':' {
-			p.pos++
-			falseVal, err := p.parseTernary()
-			if err != nil {
-				return 0, err
-			}
-			if val != 0 {
-				return trueVal, nil
+	if len(cond.Any) > 0 {
+		anyKnown, anyHolds := false, false
+		for _, sub := range cond.Any {
+			holds, known := guardHolds(sub, value, ctx)
+			if holds && known {
+				anyHolds = true
+				break
 			}
-			return falseVal, nil
+			anyKnown = anyKnown || known
 		}
-		return 0, fmt.Errorf("expected ':' in ternary")
-	}
-	return val, nil
-}
-
-func (p *exprParser) parseOr() (float64, error) {
-	val, err := p.parseAnd()
-	if err != nil {
-		return 0, err
-	}
-	for {
-		if p.peekStr(2) == "||" {
-			p.pos += 2
-			right, err := p.parseAnd()
-			if err != nil {
-				return 0, err
-			}
-			if val != 0 || right != 0 {
-				val = 1
-			} else {
-				val = 0
-			}
-		} else {
-			break
+		if !anyHolds {
+			return false, anyKnown
 		}
 	}
-	return val, nil
-}
-
-func (p *exprParser) parseAnd() (float64, error) {
-	val, err := p.parseComparison()
-	if err != nil {
-		return 0, err
-	}
-	for {
-		if p.peekStr(2) == "&&" {
-			p.pos += 2
-			right, err := p.parseComparison()
-			if err != nil {
-				return 0, err
-			}
-			if val != 0 && right != 0 {
-				val = 1
-			} else {
-				val = 0
-			}
-		} else {
-			break
+	if cond.Not != nil {
+		if holds, known := guardHolds(*cond.Not, value, ctx); holds || !known {
+			return false, known
 		}
 	}
-	return val, nil
-}
 
-func (p *exprParser) parseComparison() (float64, error) {
-	val, err := p.parseAddSub()
-	if err != nil {
-		return 0, err
+	numeric := cond.Gt != nil || cond.Gte != nil || cond.Lt != nil || cond.Lte != nil || cond.Eq != nil || cond.Ne != nil
+	if !numeric && cond.In == nil && cond.NotIn == nil {
+		return true, true
 	}
-	for {
-		p.skipSpaces()
-		if p.peekStr(2) == ">=" {
-			p.pos += 2
-			right, err := p.parseAddSub()
-			if err != nil {
-				return 0, err
-			}
-			if val >= right { val = 1 } else { val = 0 }
-		} else if p.peekStr(2) == "<=" {
-			p.pos += 2
-			right, err := p.parseAddSub()
-			if err != nil {
-				return 0, err
-			}
-			if val <= right { val = 1 } else { val = 0 }
-		} else if p.peekStr(2) == "==" {
-			p.pos += 2
-			right, err := p.parseAddSub()
-			if err != nil {
-				return 0, err
-			}
-			if val == right { val = 1 } else { val = 0 }
-		} else if p.peekStr(2) == "!=" {
-			p.pos += 2
-			right, err := p.parseAddSub()
-			if err != nil {
-				return 0, err
-			}
-			if val != right { val = 1 } else { val = 0 }
-		} else if p.peek() == '>' {
-			p.pos++
-			right, err := p.parseAddSub()
-			if err != nil {
-				return 0, err
-			}
-			if val > right { val = 1 } else { val = 0 }
-		} else if p.peek() == '<' {
-			p.pos++
-			right, err := p.parseAddSub()
-			if err != nil {
-				return 0, err
-			}
-			if val < right { val = 1 } else { val = 0 }
-		} else {
-			break
+	var fieldVal any
+	if cond.Field == "" || cond.Field == "x" {
+		fieldVal = value
+	} else {
+		v, ok := ctx.Variables[strings.TrimPrefix(cond.Field, "$")]
+		if !ok {
+			return false, false
 		}
+		fieldVal = v
 	}
-	return val, nil
-}
-
-func (p *exprParser) parseAddSub() (float64, error) {
-	val, err := p.parseMulDiv()
-	if err != nil {
-		return 0, err
+	if cond.In != nil && !guardListHas(cond.In, fieldVal) {
+		return false, true
 	}
-	for {
-		p.skipSpaces()
-		if p.peek() == '+' {
-			p.pos++
-			right, err := p.parseMulDiv()
-			if err != nil {
-				return 0, err
-			}
-			val += right
-		} else if p.peek() == '-' {
-			p.pos++
-			right, err := p.parseMulDiv()
-			if err != nil {
-				return 0, err
-			}
-			val -= right
-		} else {
-			break
-		}
+	if cond.NotIn != nil && guardListHas(cond.NotIn, fieldVal) {
+		return false, true
 	}
-	return val, nil
-}
-
-func (p *exprParser) parseMulDiv() (float64, error) {
-	val, err := p.parseUnary()
-	if err != nil {
-		return 0, err
+	if !numeric {
+		return true, true
 	}
-	for {
-		p.skipSpaces()
-		if p.peek() == '*' {
-			p.pos++
-			right, err := p.parseUnary()
-			if err != nil {
-				return 0, err
-			}
-			val *= right
-		} else if p.peek() == '/' {
-			p.pos++
-			right, err := p.parseUnary()
-			if err != nil {
-				return 0, err
-			}
-			if right == 0 {
-				val = 0
-			} else {
-				val /= right
-			}
-		} else {
-			break
-		}
+	fv, ok := toFloat64(fieldVal)
+	if !ok {
+		return false, false
 	}
-	return val, nil
+
+	// Check all conditions on this field
+	holds = (cond.Gt == nil || fv > *cond.Gt) &&
+		(cond.Gte == nil || fv >= *cond.Gte) &&
+		(cond.Lt == nil || fv < *cond.Lt) &&
+		(cond.Lte == nil || fv <= *cond.Lte) &&
+		(cond.Eq == nil || fv == *cond.Eq) &&
+		(cond.Ne == nil || fv != *cond.Ne)
+	return holds, true
 }
 
-func (p *exprParser) parseUnary() (float64, error) {
-	p.skipSpaces()
-	if p.peek() == '-' {
-		p.pos++
-		val, err := p.parsePrimary()
-		if err != nil {
-			return 0, err
+// parseGuardConditions parses a when:, any: or all: list.
+func parseGuardConditions(raw []any) []GuardCondition {
+	var conds []GuardCondition
+	for _, w := range raw {
+		if wm, ok := w.(map[string]any); ok {
+			conds = append(conds, parseGuardCondition(wm))
 		}
-		return -val, nil
 	}
-	return p.parsePrimary()
+	return conds
 }
 
-func (p *exprParser) parsePrimary() (float64, error) {
-	p.skipSpaces()
+func parseGuardCondition(wm map[string]any) GuardCondition {
+	gc := GuardCondition{}
+	if field, ok := wm["field"].(string); ok {
+		gc.Field = field
+	}
+	gc.Gt = numberPtr(wm, "gt")
+	gc.Gte = numberPtr(wm, "gte")
+	gc.Lt = numberPtr(wm, "lt")
+	gc.Lte = numberPtr(wm, "lte")
+	gc.Eq = numberPtr(wm, "eq")
+	gc.Ne = numberPtr(wm, "ne")
+	// String eq/ne (lookup results) are one-element lists
+	if str, ok := wm["eq"].(string); ok {
+		gc.In = []any{str}
+	}
+	if str, ok := wm["ne"].(string); ok {
+		gc.NotIn = []any{str}
+	}
+	gc.In = append(gc.In, guardList(wm["in"])...)
+	gc.NotIn = append(gc.NotIn, guardList(wm["not_in"])...)
+	if list, ok := wm["any"].([]any); ok {
+		gc.Any = parseGuardConditions(list)
+	}
+	if list, ok := wm["all"].([]any); ok {
+		gc.All = parseGuardConditions(list)
+	}
+	if not, ok := wm["not"].(map[string]any); ok {
+		sub := parseGuardCondition(not)
+		gc.Not = &sub
+	}
+	return gc
+}
 
-	// Parenthesized expression
-	if p.peek() == '(' {
-		p.pos++
-		val, err := p.parseTernary()
-		if err != nil {
-			return 0, err
-		}
-		p.skipSpaces()
-		if p.peek() == ')' {
-			p.pos++
-		}
-		return val, nil
+// guardList parses an in:/not_in: list; numbers become float64 so they
+// compare equal to decoded values.
+func guardList(raw any) []any {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil
 	}
-
-	// Function calls: pow, abs, sqrt, min, max
-	for _, fname := range []string{"pow", "abs", "sqrt", "min", "max"} {
-		if strings.HasPrefix(p.input[p.pos:], fname+"(") {
-			p.pos += len(fname) + 1
-			arg1, err := p.parseTernary()
-			if err != nil {
-				return 0, err
-			}
-			p.skipSpaces()
-
-			switch fname {
-			case "abs":
-				if p.peek() == ')' { p.pos++ }
-				return math.Abs(arg1), nil
-			case "sqrt":
-				if p.peek() == ')' { p.pos++ }
-				return math.Sqrt(arg1), nil
-			}
-
-			// Two-argument functions
-			if p.peek() == ',' {
-				p.pos++
-			}
-			arg2, err := p.parseTernary()
-			if err != nil {
-				return 0, err
-			}
-			p.skipSpaces()
-			if p.peek() == ')' { p.pos++ }
-
-			switch fname {
-			case "pow":
-				return math.Pow(arg1, arg2), nil
-			case "min":
-				return math.Min(arg1, arg2), nil
-			case "max":
-				return math.Max(arg1, arg2), nil
-			}
+	list := make([]any, 0, len(items))
+	for _, item := range items {
+		if f, ok := toFloat64(item); ok {
+			item = f
 		}
+		list = append(list, item)
 	}
+	return list
+}
 
-	// Number literal
-	start := p.pos
-	if p.pos < len(p.input) && (p.input[p.pos] == '-' || p.input[p.pos] == '+') {
-		p.pos++
-	}
-	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.' || p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
-		p.pos++
+// guardListHas reports whether v is in list: strings match exactly,
+// numbers by value.
+func guardListHas(list []any, v any) bool {
+	if f, ok := toFloat64(v); ok {
+		v = f
 	}
-	if p.pos > start {
-		numStr := p.input[start:p.pos]
-		val, err := strconv.ParseFloat(numStr, 64)
-		if err != nil {
-			return 0, fmt.Errorf("invalid number: %s", numStr)
+	for _, item := range list {
+		if item == v {
+			return true
 		}
-		return val, nil
 	}
-
-	return 0, fmt.Errorf("unexpected token at position %d: %q", p.pos, p.input[p.pos:])
+	return false
 }
+
+var bareVarPattern = regexp.MustCompile(`^\$?[a-zA-Z_][a-zA-Z0-9_]*$`)
@@ -6,8 +6,11 @@ package schema
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -22,6 +25,11 @@ func TestDecodeUint(t *testing.T) {
 		{"uint16 big", []byte{0x01, 0x00}, "big", 256},
 		{"uint16 little", []byte{0x00, 0x01}, "little", 256},
 		{"uint32 big", []byte{0x00, 0x01, 0x00, 0x00}, "big", 65536},
+		{"uint32 little", []byte{0x00, 0x00, 0x01, 0x00}, "little", 65536},
+		{"uint24 big", []byte{0x01, 0x02, 0x03}, "big", 0x010203},
+		{"uint24 little", []byte{0x01, 0x02, 0x03}, "little", 0x030201},
+		{"uint64 big", []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, "big", 0x0102030405060708},
+		{"uint64 little", []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, "little", 0x0807060504030201},
 	}
 
 	for _, tt := range tests {
@@ -44,6 +52,9 @@ func TestDecodeSint(t *testing.T) {
 		{"positive", []byte{0x7f}, "big", 127},
 		{"negative byte", []byte{0xff}, "big", -1},
 		{"negative short", []byte{0xff, 0xfe}, "big", -2},
+		{"negative int24", []byte{0xff, 0xff, 0xfe}, "big", -2},
+		{"negative int32 little", []byte{0xfe, 0xff, 0xff, 0xff}, "little", -2},
+		{"min int64", []byte{0x80, 0, 0, 0, 0, 0, 0, 0}, "big", math.MinInt64},
 	}
 
 	for _, tt := range tests {
@@ -56,6 +67,28 @@ func TestDecodeSint(t *testing.T) {
 	}
 }
 
+func TestIntegerRoundTripAllWidths(t *testing.T) {
+	for length := 1; length <= 8; length++ {
+		for _, endian := range []string{"big", "little"} {
+			max := uint64(1)<<(8*length) - 1
+			if length == 8 {
+				max = math.MaxUint64
+			}
+			for _, v := range []uint64{0, 1, 0x5A, max / 3, max} {
+				if got := decodeUint(encodeUint(v, length, endian), endian); got != v {
+					t.Errorf("%d-byte %s: decodeUint(encodeUint(%d)) = %d", length, endian, v, got)
+				}
+			}
+			minVal := -int64(max/2) - 1
+			for _, v := range []int64{-1, minVal, int64(max / 2)} {
+				if got := decodeSint(encodeSint(v, length, endian), endian); got != v {
+					t.Errorf("%d-byte %s: decodeSint(encodeSint(%d)) = %d", length, endian, v, got)
+				}
+			}
+		}
+	}
+}
+
 func TestDecodeBits(t *testing.T) {
 	// 0xB4 = 0b10110100
 	byteVal := byte(0xB4)
@@ -429,6 +462,21 @@ fields:
 	}
 }
 
+func TestNegativeLengthError(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - {name: head, type: u8}
+  - {name: blob, type: bytes, length: -1}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := schema.Decode([]byte{0x01, 0x02}); err == nil || !strings.Contains(err.Error(), "invalid length -1") {
+		t.Errorf("Decode() error = %v, want invalid length", err)
+	}
+}
+
 func TestTLVSimple(t *testing.T) {
 	// Simple TLV with single-byte tags (Elsys style)
 	schemaYAML := `
@@ -1639,6 +1687,101 @@ func TestEncodePortBased(t *testing.T) {
 	}
 }
 
+func TestEncodeOmitVersusZero(t *testing.T) {
+	schemaYAML := `
+name: enc_omit
+endian: big
+fields:
+  - name: flags
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - name: temperature
+              type: s16
+        - bit: 1
+          fields:
+            - name: battery
+              type: u8
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	// Zero is a value: the group is sent
+	encoded, err := schema.Encode(map[string]any{"temperature": float64(0), "battery": Omit})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x01, 0x00, 0x00}) {
+		t.Errorf("encoded = %X, want 010000", encoded)
+	}
+
+	// Omit drops the group, same as a missing key
+	encoded, err = schema.Encode(map[string]any{"temperature": Omit, "battery": float64(0)})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x02, 0x00}) {
+		t.Errorf("encoded = %X, want 0200", encoded)
+	}
+}
+
+func TestEncodeRequired(t *testing.T) {
+	schemaYAML := `
+name: enc_required
+fields:
+  - name: version
+    type: u8
+    required: true
+  - name: flags
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - name: interval
+              type: u8
+            - name: retries
+              type: u8
+              required: true
+  - name: optional
+    type: u8
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if !schema.Fields[0].Required {
+		t.Fatal("version should be required")
+	}
+
+	if _, err := schema.Encode(map[string]any{}); err == nil ||
+		!strings.Contains(err.Error(), "version") {
+		t.Errorf("missing required field: err = %v", err)
+	}
+	if _, err := schema.Encode(map[string]any{"version": Omit}); err == nil {
+		t.Error("Omit on a required field should fail")
+	}
+
+	// Required inside a flagged group applies only when the group is sent
+	encoded, err := schema.Encode(map[string]any{"version": float64(1)})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x01, 0x00}) {
+		t.Errorf("encoded = %X, want 0100", encoded)
+	}
+	if _, err := schema.Encode(map[string]any{"version": float64(1), "interval": float64(5)}); err == nil ||
+		!strings.Contains(err.Error(), "retries") {
+		t.Errorf("missing required group field: err = %v", err)
+	}
+}
+
 func TestEncodeFlaggedRoundtrip(t *testing.T) {
 	schemaYAML := `
 name: roundtrip_flagged
@@ -1690,6 +1833,149 @@ fields:
 	}
 }
 
+func TestEncodeFlaggedNested(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		fPort  int
+		data   map[string]any
+		want   string
+	}{
+		{
+			name: "flags and groups in an object",
+			schema: `
+fields:
+  - name: env
+    type: Object
+    fields:
+      - {name: flags, type: u8}
+      - flagged:
+          field: flags
+          groups:
+            - {bit: 0, fields: [{name: temp, type: u8}]}
+            - {bit: 1, fields: [{name: hum, type: u8}]}
+`,
+			data: map[string]any{"env": map[string]any{"hum": 50.0}},
+			want: "0232",
+		},
+		{
+			name: "header flags gate port fields",
+			schema: `
+header:
+  - {name: flags, type: u8}
+ports:
+  "2":
+    fields:
+      - flagged:
+          field: flags
+          groups:
+            - {bit: 0, fields: [{name: temp, type: u8}]}
+            - {bit: 2, fields: [{name: hum, type: u8}]}
+`,
+			fPort: 2,
+			data:  map[string]any{"temp": 20.0, "hum": 50.0},
+			want:  "051432",
+		},
+		{
+			name: "outer flags shared with an object's groups",
+			schema: `
+fields:
+  - {name: flags, type: u8}
+  - flagged:
+      field: flags
+      groups:
+        - {bit: 0, fields: [{name: battery, type: u8}]}
+  - name: env
+    type: Object
+    fields:
+      - flagged:
+          field: flags
+          groups:
+            - {bit: 1, fields: [{name: temp, type: u8}]}
+`,
+			data: map[string]any{"env": map[string]any{"temp": 20.0}},
+			want: "0214",
+		},
+		{
+			name: "flagged inside a group",
+			schema: `
+fields:
+  - {name: flags, type: u8}
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - {name: sub_flags, type: u8}
+            - flagged:
+                field: sub_flags
+                groups:
+                  - {bit: 3, fields: [{name: co2, type: u16}]}
+`,
+			data: map[string]any{"co2": 400.0},
+			want: "01080190",
+		},
+		{
+			name: "object flags shadow outer flags",
+			schema: `
+fields:
+  - {name: flags, type: u8}
+  - flagged:
+      field: flags
+      groups:
+        - {bit: 0, fields: [{name: battery, type: u8}]}
+  - name: env
+    type: Object
+    fields:
+      - {name: flags, type: u8}
+      - flagged:
+          field: flags
+          groups:
+            - {bit: 4, fields: [{name: temp, type: u8}]}
+`,
+			data: map[string]any{"battery": 9.0, "env": map[string]any{"temp": 20.0}},
+			want: "01091014",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchema("name: nested_flagged\n" + tt.schema)
+			if err != nil {
+				t.Fatalf("ParseSchema() error = %v", err)
+			}
+			encoded, err := s.EncodeWithPort(tt.data, tt.fPort)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got := fmt.Sprintf("%x", encoded); got != tt.want {
+				t.Fatalf("Encode() = %s, want %s", got, tt.want)
+			}
+			var decoded map[string]any
+			if tt.fPort != 0 {
+				decoded, err = s.DecodeWithPort(encoded, tt.fPort)
+			} else {
+				decoded, err = s.Decode(encoded)
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			for k, v := range tt.data {
+				if !reflect.DeepEqual(decoded[k], v) && k != "env" {
+					t.Errorf("decoded %s = %v, want %v", k, decoded[k], v)
+				}
+			}
+			if env, ok := tt.data["env"].(map[string]any); ok {
+				got, _ := decoded["env"].(map[string]any)
+				for k, v := range env {
+					if got[k] != v {
+						t.Errorf("decoded env.%s = %v, want %v", k, got[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
 func floatPtr(f float64) *float64 { return &f }
 
 // Phase 2 Tests: polynomial, compute, guard, ref
@@ -2053,6 +2339,167 @@ fields:
 	}
 }
 
+func TestEnumLookupUnknownPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    any
+		omitted bool
+		wantErr bool
+	}{
+		{"", float64(9), false, false},
+		{"raw", float64(9), false, false},
+		{"null", nil, true, false},
+		{"error", nil, false, true},
+		{`'label("unknown_%d")'`, "unknown_9", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			unknown := ""
+			if tt.policy != "" {
+				unknown = "\n    unknown: " + tt.policy
+			}
+			schema, err := ParseSchema(`
+name: unknown_policy
+fields:
+  - name: mode
+    type: enum
+    base: u8
+    values:
+      0: off
+      1: on` + unknown + `
+  - name: state
+    type: u8
+    lookup:
+      0: idle
+      1: busy` + unknown + `
+`)
+			if err != nil {
+				t.Fatalf("ParseSchema() error = %v", err)
+			}
+			if len(schema.Warnings) != 0 {
+				t.Fatalf("unexpected warnings: %v", schema.Warnings)
+			}
+
+			decoded, err := schema.Decode([]byte{0x09, 0x09})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "unknown value") {
+					t.Errorf("Decode() err = %v, want unknown value error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			for _, key := range []string{"mode", "state"} {
+				got, present := decoded[key]
+				if tt.omitted {
+					if present {
+						t.Errorf("%s = %v, want omitted", key, got)
+					}
+					continue
+				}
+				if got != tt.want {
+					t.Errorf("%s = %#v, want %#v", key, got, tt.want)
+				}
+			}
+
+			// Known values are unaffected
+			decoded, _ = schema.Decode([]byte{0x01, 0x01})
+			if decoded["mode"] != "on" || decoded["state"] != "busy" {
+				t.Errorf("known values = %v", decoded)
+			}
+		})
+	}
+
+	// Labels encode back to their number
+	schema, err := ParseSchema(`
+name: label_roundtrip
+fields:
+  - name: mode
+    type: enum
+    values: {0: off}
+    unknown: label("mode_%d")
+  - name: state
+    type: u8
+    lookup: {0: idle}
+    unknown: label("state_%d")
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	encoded, err := schema.Encode(map[string]any{"mode": "mode_5", "state": "state_6"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{5, 6}) {
+		t.Errorf("encoded = %X, want 0506", encoded)
+	}
+
+	bad, _ := ParseSchema("name: bad\nfields:\n  - name: m\n    type: enum\n    values: {0: off}\n    unknown: drop\n")
+	if len(bad.Warnings) != 1 {
+		t.Errorf("invalid policy: Warnings = %v", bad.Warnings)
+	}
+}
+
+func TestEncodeEnum(t *testing.T) {
+	schemaYAML := `
+name: enum_encode_test
+endian: big
+fields:
+  - name: status
+    type: enum
+    base: u8
+    values:
+      0: idle
+      1: running
+  - name: mode
+    type: enum
+    base: u16
+    values:
+      256: eco
+      512: boost
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	encoded, err := schema.Encode(map[string]any{"status": "running", "mode": "boost"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x01, 0x02, 0x00}) {
+		t.Errorf("encoded = %X, want 010200", encoded)
+	}
+
+	// Raw numbers pass through, as on decode
+	encoded, err = schema.Encode(map[string]any{"status": float64(7), "mode": "eco"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x07, 0x01, 0x00}) {
+		t.Errorf("encoded = %X, want 070100", encoded)
+	}
+
+	if _, err := schema.Encode(map[string]any{"status": "sleeping"}); err == nil ||
+		!strings.Contains(err.Error(), "sleeping") {
+		t.Errorf("unknown enum name: err = %v", err)
+	}
+
+	// Roundtrip
+	decoded, err := schema.Decode([]byte{0x00, 0x01, 0x00})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	encoded, err = schema.Encode(decoded)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x00, 0x01, 0x00}) {
+		t.Errorf("roundtrip = %X, want 000100", encoded)
+	}
+}
+
 // =============================================================================
 // BYTE GROUP TESTS
 // =============================================================================
@@ -2196,31 +2643,177 @@ fields:
 	}
 }
 
-// =============================================================================
-// SKIP TYPE TESTS
-// =============================================================================
-
-func TestSkipType(t *testing.T) {
+func TestDefinitionsRefEverywhere(t *testing.T) {
 	schemaYAML := `
-name: skip_test
-fields:
-  - name: start
-    type: u8
-  - name: _reserved
-    type: skip
-    length: 2
-  - name: end
-    type: u8
-`
-	schema, err := ParseSchema(schemaYAML)
-	if err != nil {
-		t.Fatalf("ParseSchema() error = %v", err)
-	}
-
-	// start=0x01, skip 2 bytes (0xFF, 0xFF), end=0x02
-	decoded, err := schema.Decode([]byte{0x01, 0xFF, 0xFF, 0x02})
-	if err != nil {
-		t.Fatalf("Decode() error = %v", err)
+name: ref_everywhere
+definitions:
+  battery:
+    fields:
+      - name: battery
+        type: u8
+        div: 10
+  env:
+    fields:
+      - name: temp
+        type: s16
+        div: 10
+      - $ref: '#/definitions/battery'
+ports:
+  1:
+    fields:
+      - name: kind
+        type: u8
+        var: kind
+      - match:
+          field: $kind
+          cases:
+            1:
+              - $ref: '#/definitions/env'
+            2:
+              - name: readings
+                type: repeat
+                count: 2
+                fields:
+                  - $ref: '#/definitions/battery'
+  2:
+    fields:
+      - tlv:
+          tag_size: 1
+          cases:
+            "1":
+              - $ref: '#/definitions/battery'
+  3:
+    fields:
+      - name: flags
+        type: u8
+      - flagged:
+          field: flags
+          groups:
+            - bit: 0
+              fields:
+                - $ref: '#/definitions/env'
+      - name: nested
+        type: Object
+        fields:
+          - $ref: '#/definitions/battery'
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(schema.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", schema.Warnings)
+	}
+
+	decoded, err := schema.DecodeWithPort([]byte{0x01, 0x00, 0xFA, 0x24}, 1)
+	if err != nil {
+		t.Fatalf("DecodeWithPort(1) error = %v", err)
+	}
+	if decoded["temp"] != 25.0 || decoded["battery"] != 3.6 {
+		t.Errorf("port 1 match = %v", decoded)
+	}
+
+	decoded, err = schema.DecodeWithPort([]byte{0x02, 0x24, 0x1E}, 1)
+	if err != nil {
+		t.Fatalf("DecodeWithPort(1) error = %v", err)
+	}
+	readings, _ := decoded["readings"].([]any)
+	if len(readings) != 2 || readings[1].(map[string]any)["battery"] != 3.0 {
+		t.Errorf("port 1 repeat = %v", decoded)
+	}
+
+	decoded, err = schema.DecodeWithPort([]byte{0x01, 0x24}, 2)
+	if err != nil {
+		t.Fatalf("DecodeWithPort(2) error = %v", err)
+	}
+	if decoded["battery"] != 3.6 {
+		t.Errorf("port 2 tlv = %v", decoded)
+	}
+
+	payload := []byte{0x01, 0x00, 0xFA, 0x24, 0x1E}
+	decoded, err = schema.DecodeWithPort(payload, 3)
+	if err != nil {
+		t.Fatalf("DecodeWithPort(3) error = %v", err)
+	}
+	nested, _ := decoded["nested"].(map[string]any)
+	if decoded["temp"] != 25.0 || decoded["battery"] != 3.6 || nested["battery"] != 3.0 {
+		t.Errorf("port 3 = %v", decoded)
+	}
+
+	// Encode follows refs, including flag computation
+	encoded, err := schema.EncodeWithPort(map[string]any{
+		"temp": 25.0, "battery": 3.6, "nested": map[string]any{"battery": 3.0},
+	}, 3)
+	if err != nil {
+		t.Fatalf("EncodeWithPort(3) error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("EncodeWithPort(3) = %X, want %X", encoded, payload)
+	}
+}
+
+func TestDefinitionsRefErrors(t *testing.T) {
+	schema, err := ParseSchema(`
+name: ref_errors
+definitions:
+  loop:
+    fields:
+      - $ref: '#/definitions/loop'
+fields:
+  - name: nested
+    type: Object
+    fields:
+      - $ref: '#/definitions/missing'
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], "definition not found: missing") {
+		t.Errorf("Warnings = %v", schema.Warnings)
+	}
+	if _, err := schema.Decode([]byte{0x00}); err == nil {
+		t.Error("Decode() should fail for a missing definition")
+	}
+
+	loop, _ := ParseSchema(`
+name: ref_loop
+definitions:
+  loop:
+    fields:
+      - $ref: '#/definitions/loop'
+fields:
+  - $ref: '#/definitions/loop'
+`)
+	if _, err := loop.Decode([]byte{0x00}); err == nil || !strings.Contains(err.Error(), "circular") {
+		t.Errorf("Decode() err = %v, want circular reference error", err)
+	}
+}
+
+// =============================================================================
+// SKIP TYPE TESTS
+// =============================================================================
+
+func TestSkipType(t *testing.T) {
+	schemaYAML := `
+name: skip_test
+fields:
+  - name: start
+    type: u8
+  - name: _reserved
+    type: skip
+    length: 2
+  - name: end
+    type: u8
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	// start=0x01, skip 2 bytes (0xFF, 0xFF), end=0x02
+	decoded, err := schema.Decode([]byte{0x01, 0xFF, 0xFF, 0x02})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
 	}
 
 	if decoded["start"] != float64(1) {
@@ -3465,6 +4058,73 @@ fields:
 	}
 }
 
+func TestRepeatFlatten(t *testing.T) {
+	schemaYAML := `
+name: flatten_test
+fields:
+  - name: samples
+    type: repeat
+    until: end
+    flatten: true
+    fields:
+      - name: value
+        type: s8
+        div: 2
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	decoded, err := schema.Decode([]byte{0x02, 0xFC, 0x05})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	samples, ok := decoded["samples"].([]any)
+	if !ok || len(samples) != 3 {
+		t.Fatalf("samples = %v, want 3 values", decoded["samples"])
+	}
+	for i, want := range []float64{1, -2, 2.5} {
+		if samples[i] != want {
+			t.Errorf("samples[%d] = %v, want %v", i, samples[i], want)
+		}
+	}
+
+	// Encode accepts both bare values and objects
+	encoded, err := schema.Encode(map[string]any{
+		"samples": []any{float64(1), map[string]any{"value": float64(-2)}, 2.5},
+	})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x02, 0xFC, 0x05}) {
+		t.Errorf("encoded = %x, want 02fc05", encoded)
+	}
+
+	bad, err := ParseSchema(`
+name: flatten_bad
+fields:
+  - name: pairs
+    type: repeat
+    count: 1
+    flatten: true
+    fields:
+      - name: a
+        type: u8
+      - name: b
+        type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(bad.Warnings) != 1 || !strings.Contains(bad.Warnings[0], "more than one output field") {
+		t.Errorf("Warnings = %v", bad.Warnings)
+	}
+	if _, err := bad.Decode([]byte{1, 2}); err == nil {
+		t.Error("Decode() should fail for a multi-field flattened repeat")
+	}
+}
+
 func TestEncodeAscii(t *testing.T) {
 	schemaYAML := `
 name: encode_ascii_test
@@ -4200,6 +4860,8 @@ func TestGuardAllConditions(t *testing.T) {
 		{"lte fail", "lte", 50, 51, 100, -1},  // 51 <= 50 = false
 		{"eq pass", "eq", 50, 50, 100, 100},   // 50 == 50 = true
 		{"eq fail", "eq", 50, 51, 100, -1},    // 51 == 50 = false
+		{"ne pass", "ne", 50, 51, 100, 100},   // 51 != 50 = true
+		{"ne fail", "ne", 50, 50, 100, -1},    // 50 != 50 = false
 	}
 
 	for _, tt := range tests {
@@ -4238,6 +4900,98 @@ fields:
 	}
 }
 
+func TestGuardNonNumberFields(t *testing.T) {
+	schemaYAML := `
+name: guard_any_type
+fields:
+  - name: status
+    type: u8
+    var: status
+  - name: temperature
+    type: s16
+    div: 10
+    guard:
+      when:
+        - field: x
+          ne: -3276.8
+      else: null
+  - name: humidity
+    type: u8
+    guard:
+      when:
+        - field: $status
+          eq: 0
+      else: invalid
+  - name: door_open
+    type: bool
+    guard:
+      when:
+        - field: $status
+          eq: 0
+      else: null
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	decoded, err := schema.Decode([]byte{0x00, 0x00, 0xFA, 0x32, 0x01})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded["temperature"] != 25.0 || decoded["humidity"] != 50.0 || decoded["door_open"] != true {
+		t.Errorf("passing guards changed values: %v", decoded)
+	}
+
+	// 0x8000 sentinel with a non-zero status
+	decoded, err = schema.Decode([]byte{0x01, 0x80, 0x00, 0x32, 0x01})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if _, ok := decoded["temperature"]; ok {
+		t.Errorf("temperature should be omitted, got %v", decoded["temperature"])
+	}
+	if decoded["humidity"] != "invalid" {
+		t.Errorf("humidity = %v, want invalid", decoded["humidity"])
+	}
+	if _, ok := decoded["door_open"]; ok {
+		t.Errorf("door_open should be omitted, got %v", decoded["door_open"])
+	}
+}
+
+func TestGuardNumberElseNull(t *testing.T) {
+	schemaYAML := `
+name: guard_null
+fields:
+  - name: raw
+    type: u8
+    var: raw
+  - name: result
+    type: number
+    ref: $raw
+    guard:
+      when:
+        - field: x
+          lt: 255
+      else: null
+`
+	schema, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	decoded, err := schema.Decode([]byte{0xFF})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if _, ok := decoded["result"]; ok {
+		t.Errorf("result should be omitted, got %v", decoded["result"])
+	}
+	decoded, _ = schema.Decode([]byte{0x10})
+	if decoded["result"] != 16.0 {
+		t.Errorf("result = %v, want 16", decoded["result"])
+	}
+}
+
 func TestMatchRangeCase(t *testing.T) {
 	schemaYAML := `
 name: match_range_test
@@ -4849,6 +5603,88 @@ func TestParseSchemaJSONFormat(t *testing.T) {
 	}
 }
 
+func TestParseSchemaJSONNumbersMatchYAML(t *testing.T) {
+	schemaYAML := `
+name: coerce
+version: 2
+fields:
+  - name: flags
+    type: u8
+    bit: 3
+    consume: 0
+  - name: level
+    type: u16
+    byte_offset: 1
+    bits: 12
+    bit_offset: 2
+    round: 1
+    mult: 2
+    resolution: 1
+  - name: items
+    type: repeat
+    count: 2
+    max: 4
+    min: 1
+    fields:
+      - name: v
+        type: u8
+  - name: kind
+    type: match
+    on: $flags
+    cases:
+      - case: 1
+        fields:
+          - name: a
+            type: u8
+  - name: ratio
+    type: number
+    compute: {op: div, a: 10, b: $flags}
+    guard:
+      when:
+        - field: $flags
+          ne: 0
+  - flagged:
+      field: flags
+      groups:
+        - bit: 1
+          fields:
+            - name: extra
+              type: bytes
+              length: 2
+`
+	schemaJSON := `{
+		"name": "coerce", "version": 2.0,
+		"fields": [
+			{"name": "flags", "type": "u8", "bit": 3.0, "consume": 0.0},
+			{"name": "level", "type": "u16", "byte_offset": 1.0, "bits": 12.0, "bit_offset": 2.0,
+			 "round": 1.0, "mult": 2.0, "resolution": 1.0},
+			{"name": "items", "type": "repeat", "count": 2, "max": 4.0, "min": 1.0,
+			 "fields": [{"name": "v", "type": "u8"}]},
+			{"name": "kind", "type": "match", "on": "$flags",
+			 "cases": [{"case": 1.0, "fields": [{"name": "a", "type": "u8"}]}]},
+			{"name": "ratio", "type": "number", "compute": {"op": "div", "a": 10.0, "b": "$flags"},
+			 "guard": {"when": [{"field": "$flags", "ne": 0.0}]}},
+			{"flagged": {"field": "flags", "groups": [
+				{"bit": 1.0, "fields": [{"name": "extra", "type": "bytes", "length": 2.0}]}]}}
+		]
+	}`
+
+	fromYAML, err := ParseSchema(schemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema(YAML) error = %v", err)
+	}
+	fromJSON, err := ParseSchema(schemaJSON)
+	if err != nil {
+		t.Fatalf("ParseSchema(JSON) error = %v", err)
+	}
+	if fromJSON.Version != 2 {
+		t.Errorf("version = %d, want 2", fromJSON.Version)
+	}
+	if !reflect.DeepEqual(fromYAML.Fields, fromJSON.Fields) {
+		t.Errorf("JSON and YAML schemas differ:\nyaml: %+v\njson: %+v", fromYAML.Fields, fromJSON.Fields)
+	}
+}
+
 func TestParseSchemaWithVersion(t *testing.T) {
 	schemaYAML := `
 version: 1
@@ -7303,18 +8139,201 @@ fields:
 	}
 }
 
-func TestEdgeCaseMatchNoMatchingCase(t *testing.T) {
-	schemaYAML := `
+func TestDecodeOptionsRaw(t *testing.T) {
+	schema, err := ParseSchema(`
 name: test
+endian: big
+definitions:
+  battery:
+    fields:
+      - {name: battery, type: u8}
 fields:
-  - name: type
+  - name: temperature
+    type: s16
+    div: 10
+  - name: alarm
+    type: bool
+    bit: 7
+  - name: flags
     type: u8
-    var: t
-  - name: data
-    type: match
-    on: $t
-    cases:
-      - case: 1
+  - name: reading
+    type: Object
+    fields:
+      - {name: level, type: u16}
+  - $ref: '#/definitions/battery'
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x00, 0xFA, 0x80, 0x01, 0x02, 0x5A}
+
+	result, _ := schema.Decode(payload)
+	if _, ok := result[RawKey]; ok {
+		t.Error("_raw present without IncludeRaw")
+	}
+
+	schema.DecodeLimits = DecodeLimits{IncludeRaw: true, IncludeFieldRaw: true}
+	result, err = schema.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if result[RawKey] != "00fa8001025a" {
+		t.Errorf("_raw = %v", result[RawKey])
+	}
+	want := map[string]any{"temperature": "00fa", "flags": "80", "alarm": "80", "reading": "0102", "battery": "5a"}
+	if !reflect.DeepEqual(result[RawFieldsKey], want) {
+		t.Errorf("_raw_fields = %v, want %v", result[RawFieldsKey], want)
+	}
+	reading := result["reading"].(map[string]any)
+	if !reflect.DeepEqual(reading[RawFieldsKey], map[string]any{"level": "0102"}) {
+		t.Errorf("reading._raw_fields = %v", reading[RawFieldsKey])
+	}
+}
+
+func TestDecodeNonFinite(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+endian: big
+fields:
+  - {name: a, type: f32}
+  - {name: b, type: f16}
+  - {name: c, type: f32, non_finite: -999}
+  - {name: d, type: f32, non_finite: keep}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// NaN, +Inf (f16), -Inf, NaN
+	payload := []byte{0x7F, 0xC0, 0x00, 0x00, 0x7C, 0x00, 0xFF, 0x80, 0x00, 0x00, 0x7F, 0xC0, 0x00, 0x00}
+
+	result, err := schema.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if a, _ := result["a"].(float64); !math.IsNaN(a) {
+		t.Errorf("a = %v, want NaN kept by default", result["a"])
+	}
+	if result["c"] != -999.0 {
+		t.Errorf("c = %v, want field sentinel -999", result["c"])
+	}
+
+	schema.DecodeLimits = DecodeLimits{NonFinite: NonFiniteNull}
+	result, err = schema.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if v, ok := result["a"]; !ok || v != nil {
+		t.Errorf("a = %v, want null", v)
+	}
+	if v, ok := result["b"]; !ok || v != nil {
+		t.Errorf("b = %v, want null", v)
+	}
+	if result["c"] != -999.0 {
+		t.Errorf("c = %v, want field sentinel to override", result["c"])
+	}
+	if d, _ := result["d"].(float64); !math.IsNaN(d) {
+		t.Errorf("d = %v, want NaN kept by the field", result["d"])
+	}
+	want := map[string]string{"a": "non_finite", "b": "non_finite", "c": "non_finite"}
+	if !reflect.DeepEqual(result["_quality"], want) {
+		t.Errorf("_quality = %v, want %v", result["_quality"], want)
+	}
+
+	schema.DecodeLimits = DecodeLimits{NonFinite: NonFiniteError}
+	if _, err := schema.Decode(payload); err == nil || !strings.Contains(err.Error(), "a: non-finite value NaN") {
+		t.Errorf("Decode() error = %v, want non-finite error", err)
+	}
+
+	bad, _ := ParseSchema("name: t\nfields:\n  - {name: a, type: f32, non_finite: drop}\n")
+	if len(bad.Warnings) == 0 || !strings.Contains(bad.Warnings[0], "non_finite") {
+		t.Errorf("Warnings = %v, want non_finite warning", bad.Warnings)
+	}
+}
+
+func TestDecodeOptionsLimits(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: items
+    type: repeat
+    until: end
+    fields:
+      - name: x
+        type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := make([]byte, 50)
+
+	schema.DecodeLimits = DecodeLimits{MaxRepeat: 20}
+	result, err := schema.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if items := result["items"].([]any); len(items) != 20 {
+		t.Errorf("items count = %d, want 20", len(items))
+	}
+
+	schema.DecodeLimits.ErrorOnLimit = true
+	if _, err := schema.Decode(payload); err == nil || !strings.Contains(err.Error(), "items: truncated at 20 elements") {
+		t.Errorf("Decode() error = %v, want truncation error", err)
+	}
+	// Reaching the limit exactly is not truncation
+	if _, err := schema.Decode(payload[:20]); err != nil {
+		t.Errorf("Decode(20 bytes) error = %v", err)
+	}
+
+	tlv, err := ParseSchema(`
+name: tlv
+fields:
+  - name: data
+    type: tlv
+    tag_size: 1
+    cases:
+      "1":
+        - {name: a, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	tlv.DecodeLimits = DecodeLimits{MaxTLVRecords: 2, ErrorOnLimit: true}
+	if _, err := tlv.Decode([]byte{1, 5, 1, 6, 1, 7}); err == nil || !strings.Contains(err.Error(), "truncated at 2 records") {
+		t.Errorf("TLV Decode() error = %v, want record limit", err)
+	}
+
+	nested, err := ParseSchema(`
+name: nested
+fields:
+  - name: a
+    type: Object
+    fields:
+      - name: b
+        type: Object
+        fields:
+          - {name: c, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	nested.DecodeLimits = DecodeLimits{MaxDepth: 2}
+	if _, err := nested.Decode([]byte{1}); err == nil || !strings.Contains(err.Error(), "nested deeper than 2") {
+		t.Errorf("Decode() error = %v, want depth limit", err)
+	}
+}
+
+func TestEdgeCaseMatchNoMatchingCase(t *testing.T) {
+	schemaYAML := `
+name: test
+fields:
+  - name: type
+    type: u8
+    var: t
+  - name: data
+    type: match
+    on: $t
+    cases:
+      - case: 1
         fields:
           - name: a
             type: u8
@@ -7693,6 +8712,113 @@ func TestDecodeCompactEdgeCaseHugeCount(t *testing.T) {
 	}
 }
 
+func TestCompactFormatErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{">B:a ; H:b", "unknown format character ';' at position 5"},
+		{">B:a H:b )", "unknown format character ')' at position 9"},
+		{">B:a 3", "count at position 5 has no format character"},
+		{">B: H", "expected a name after ':' at position 3"},
+		{"B<H", "byte order '<' at position 1 must come first"},
+		{"99999999999999999999B", "too large"},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseCompactFormat(tt.format)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseCompactFormat(%q) error = %v, want %q", tt.format, err, tt.want)
+		}
+	}
+}
+
+func TestCompactFormatCommentsAndWhitespace(t *testing.T) {
+	fields, endian, err := ParseCompactFormat(`
+		# header
+		<B:version   # format version
+		H:length
+		2x           # reserved
+		I:timestamp
+	`)
+	if err != nil {
+		t.Fatalf("ParseCompactFormat() error = %v", err)
+	}
+	if endian != "little" || len(fields) != 5 {
+		t.Fatalf("endian = %s, fields = %d; want little, 5", endian, len(fields))
+	}
+	if fields[0].Name != "version" || fields[4].Name != "timestamp" || fields[4].Endian != "little" {
+		t.Errorf("fields = %+v", fields)
+	}
+}
+
+func TestDecodeCompactFloat16Endian(t *testing.T) {
+	// 1.5 is 0x3E00 as a half-precision float
+	for _, tt := range []struct {
+		format string
+		data   []byte
+	}{
+		{">e:v", []byte{0x3E, 0x00}},
+		{"<e:v", []byte{0x00, 0x3E}},
+		{"!e:v", []byte{0x3E, 0x00}},
+	} {
+		result, err := DecodeCompact(tt.format, tt.data)
+		if err != nil {
+			t.Fatalf("DecodeCompact(%q) error = %v", tt.format, err)
+		}
+		if result["v"] != 1.5 {
+			t.Errorf("DecodeCompact(%q) = %v, want 1.5", tt.format, result["v"])
+		}
+	}
+}
+
+func TestDecodeCompactNativeOrder(t *testing.T) {
+	data := binary.NativeEndian.AppendUint16(nil, 0x0102)
+	for _, format := range []string{"=H:v", "@H:v"} {
+		result, err := DecodeCompact(format, data)
+		if err != nil {
+			t.Fatalf("DecodeCompact(%q) error = %v", format, err)
+		}
+		if result["v"] != float64(0x0102) {
+			t.Errorf("DecodeCompact(%q) = %v, want 258", format, result["v"])
+		}
+	}
+}
+
+func TestCompactFormatNativeAlignment(t *testing.T) {
+	// '@' pads each number to its own size; '=' packs
+	fields, _, err := ParseCompactFormat("@B:a H:b B:c I:d 3s:e f:g")
+	if err != nil {
+		t.Fatalf("ParseCompactFormat() error = %v", err)
+	}
+	var layout []string
+	for _, f := range fields {
+		if f.Type == TypeSkip {
+			layout = append(layout, fmt.Sprintf("pad%d", f.Length))
+		} else {
+			layout = append(layout, f.Name)
+		}
+	}
+	want := "a pad1 b c pad3 d e pad1 g"
+	if got := strings.Join(layout, " "); got != want {
+		t.Errorf("layout = %s, want %s", got, want)
+	}
+
+	fields, _, _ = ParseCompactFormat("=B:a H:b")
+	if len(fields) != 2 {
+		t.Errorf("'=' fields = %d, want 2 (no padding)", len(fields))
+	}
+
+	data := []byte{7, 0}
+	data = binary.NativeEndian.AppendUint16(data, 300)
+	result, err := DecodeCompact("@B:a H:b", data)
+	if err != nil {
+		t.Fatalf("DecodeCompact() error = %v", err)
+	}
+	if result["a"] != 7.0 || result["b"] != 300.0 {
+		t.Errorf("DecodeCompact() = %v", result)
+	}
+}
+
 // Tests for semantic fields: valid_range, resolution, unece
 
 func TestValidRangeInRange(t *testing.T) {
@@ -7792,6 +8918,68 @@ fields:
 	}
 }
 
+func TestInvalidSentinel(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+endian: big
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+    invalid: [0x7FFF, 0x8000]
+    valid_range: [-40, 85]
+  - name: humidity
+    type: u8
+    invalid: 0xFF
+    var: rh
+  - byte_group:
+      - {name: level, type: "u8[0:3]", invalid: 15}
+      - {name: mode, type: "u8[4:7]"}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		temp    any
+		rh      any
+		level   any
+	}{
+		{"valid", []byte{0x00, 0xD7, 0x32, 0x13}, 21.5, 50.0, 3.0},
+		{"max sentinel", []byte{0x7F, 0xFF, 0xFF, 0x1F}, nil, nil, nil},
+		{"signed sentinel", []byte{0x80, 0x00, 0x32, 0x13}, nil, 50.0, 3.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := schema.Decode(tt.payload)
+			if err != nil {
+				t.Fatalf("Decode error: %v", err)
+			}
+			for key, want := range map[string]any{"temperature": tt.temp, "humidity": tt.rh, "level": tt.level} {
+				got, present := result[key]
+				if !present || got != want {
+					t.Errorf("%s = %v (present %v), want %v", key, got, present, want)
+				}
+			}
+			quality, _ := result["_quality"].(map[string]string)
+			if wantQ := map[bool]string{true: "invalid", false: "good"}[tt.temp == nil]; quality["temperature"] != wantQ {
+				t.Errorf("quality[temperature] = %q, want %q", quality["temperature"], wantQ)
+			}
+		})
+	}
+
+	// null encodes back to the first sentinel
+	encoded, err := schema.Encode(map[string]any{"temperature": nil, "humidity": nil, "level": 2.0, "mode": 1.0})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if want := []byte{0x7F, 0xFF, 0xFF, 0x12}; !bytes.Equal(encoded, want) {
+		t.Errorf("Encode = %X, want %X", encoded, want)
+	}
+}
+
 func TestValidRangeMultipleFields(t *testing.T) {
 	schema, err := ParseSchema(`
 name: test
@@ -7854,3 +9042,1020 @@ fields:
 		t.Errorf("unece = %v, want CEL", field.UNECE)
 	}
 }
+
+func TestFieldAliasesEncode(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+    aliases: [temp]
+  - name: humidity
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	// Legacy name accepted on encode
+	encoded, err := schema.Encode(map[string]any{"temp": 23.1, "humidity": 50.0})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x00, 0xE7, 0x32}) {
+		t.Errorf("Encode = %x, want 00e732", encoded)
+	}
+
+	// Aliases are not emitted by default
+	result, err := schema.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if _, ok := result["temp"]; ok {
+		t.Errorf("alias emitted without emit_aliases: %v", result)
+	}
+}
+
+func TestFieldAliasesEmitAndDeprecated(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+emit_aliases: true
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+    aliases: [temp, t]
+  - name: legacy_status
+    type: u8
+    deprecated: true
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	result, err := schema.Decode([]byte{0x00, 0xE7, 0x01})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	for _, key := range []string{"temperature", "temp", "t"} {
+		if v, ok := result[key].(float64); !ok || math.Abs(v-23.1) > 0.001 {
+			t.Errorf("%s = %v, want 23.1", key, result[key])
+		}
+	}
+
+	meta := schema.GetFieldMetadata("legacy_status")
+	if !meta["legacy_status"].Deprecated {
+		t.Errorf("legacy_status metadata not deprecated: %+v", meta)
+	}
+	meta = schema.GetFieldMetadata("temperature")
+	if len(meta["temperature"].Aliases) != 2 {
+		t.Errorf("temperature aliases = %v, want [temp t]", meta["temperature"].Aliases)
+	}
+}
+
+func TestFieldAliasesFlaggedEncode(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: flags
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - name: battery
+              type: u8
+              aliases: [bat]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	encoded, err := schema.Encode(map[string]any{"bat": 90.0})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x01, 0x5A}) {
+		t.Errorf("Encode = %x, want 015a", encoded)
+	}
+}
+
+func TestByteOffsetPeekInteger(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: msg_type
+    type: u8
+    byte_offset: 2
+  - name: counter
+    type: u16
+  - name: kind
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	// msg_type peeks ahead at offset 2 without moving the cursor
+	result, err := schema.Decode([]byte{0x01, 0x02, 0x07})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["msg_type"] != float64(7) {
+		t.Errorf("msg_type = %v, want 7", result["msg_type"])
+	}
+	if result["counter"] != float64(0x0102) {
+		t.Errorf("counter = %v, want 258", result["counter"])
+	}
+	if result["kind"] != float64(7) {
+		t.Errorf("kind = %v, want 7", result["kind"])
+	}
+}
+
+func TestByteOffsetExplicitConsume(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: raw_word
+    type: u16
+    consume: 0
+  - name: high
+    type: u8
+  - name: low
+    type: u8
+  - name: tail
+    type: f32
+    byte_offset: 0
+    consume: 4
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	result, err := schema.Decode([]byte{0x12, 0x34, 0x3F, 0x80, 0x00, 0x00})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["raw_word"] != float64(0x1234) {
+		t.Errorf("raw_word = %v, want 0x1234", result["raw_word"])
+	}
+	if result["high"] != float64(0x12) || result["low"] != float64(0x34) {
+		t.Errorf("high/low = %v/%v, want 0x12/0x34", result["high"], result["low"])
+	}
+	if result["tail"] != float64(1.0) {
+		t.Errorf("tail = %v, want 1.0", result["tail"])
+	}
+
+	// Peeked raw_word is a view and is not written on encode
+	encoded, err := schema.Encode(map[string]any{"raw_word": 1.0, "high": 0x12, "low": 0x34, "tail": 1.0})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x12, 0x34, 0x3F, 0x80, 0x00, 0x00}) {
+		t.Errorf("Encode = %x, want 12343f800000", encoded)
+	}
+}
+
+func TestExplicitConsumeOwnSizeRoundTrip(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: a
+    type: u16
+    consume: 2
+  - name: b
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	payload := []byte{0x01, 0x02, 0x03}
+	result, err := schema.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["a"] != float64(258) || result["b"] != float64(3) {
+		t.Fatalf("Decode = %v, want a=258 b=3", result)
+	}
+
+	// consume: equal to the field size is not a view; a is written
+	encoded, err := schema.Encode(result)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode = %x, want 010203", encoded)
+	}
+}
+
+func TestBoolSharedByteRoundTrip(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - {name: a, type: bool, bit: 0}
+  - {name: b, type: bool, bit: 1, consume: 1}
+  - {name: c, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	encoded, err := schema.Encode(map[string]any{"a": true, "b": true, "c": 9})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x03, 0x09}) {
+		t.Errorf("Encode = %x, want 0309", encoded)
+	}
+	result, err := schema.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["a"] != true || result["b"] != true || result["c"] != float64(9) {
+		t.Errorf("Decode = %v, want a, b true and c 9", result)
+	}
+
+	if _, err := schema.GenerateGolden(1); err != nil {
+		t.Errorf("GenerateGolden error: %v", err)
+	}
+}
+
+func TestByteOffsetBoolAndBits(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: alarm
+    type: bool
+    bit: 7
+    byte_offset: 1
+  - name: mode
+    type: bits
+    bit_offset: 4
+    bits: 4
+  - name: first
+    type: u8
+  - name: second
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	result, err := schema.Decode([]byte{0xA1, 0x80})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["alarm"] != true {
+		t.Errorf("alarm = %v, want true", result["alarm"])
+	}
+	if result["mode"] != float64(10) {
+		t.Errorf("mode = %v, want 10", result["mode"])
+	}
+	if result["first"] != float64(0xA1) || result["second"] != float64(0x80) {
+		t.Errorf("first/second = %v/%v", result["first"], result["second"])
+	}
+}
+
+func TestByteOffsetUnderflow(t *testing.T) {
+	schema, _ := ParseSchema(`
+name: test
+fields:
+  - name: v
+    type: u16
+    byte_offset: 3
+`)
+	if _, err := schema.Decode([]byte{0x01, 0x02, 0x03}); err == nil {
+		t.Error("expected underflow error for byte_offset past end")
+	}
+}
+
+func TestBitfieldGroupMixedMembers(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - bitfield:
+      size: 1
+      fields:
+        - name: motion
+          type: bool
+          bit: 0
+        - name: door_open
+          type: bool
+          bit: 1
+        - name: mode
+          type: bits
+          bit_offset: 4
+          bits: 3
+          lookup:
+            0: idle
+            5: active
+  - name: battery
+    type: u8
+    div: 10
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", schema.Warnings)
+	}
+
+	// 0x51 = 0101 0001 -> motion=1, door=0, mode=5
+	result, err := schema.Decode([]byte{0x51, 0x24})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["motion"] != true || result["door_open"] != false {
+		t.Errorf("motion/door_open = %v/%v, want true/false", result["motion"], result["door_open"])
+	}
+	if result["mode"] != "active" {
+		t.Errorf("mode = %v, want active", result["mode"])
+	}
+	if v, _ := result["battery"].(float64); math.Abs(v-3.6) > 1e-9 {
+		t.Errorf("battery = %v, want 3.6", result["battery"])
+	}
+
+	encoded, err := schema.Encode(result)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x51, 0x24}) {
+		t.Errorf("Encode = %x, want 5124", encoded)
+	}
+}
+
+func TestByteGroupInferredSize(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - byte_group:
+      - name: low
+        type: u16[0:3]
+      - name: high
+        type: u16[12:15]
+  - name: next
+    type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if got := byteGroupSize(schema.Fields[0]); got != 2 {
+		t.Fatalf("inferred size = %d, want 2", got)
+	}
+	result, err := schema.Decode([]byte{0x0A, 0xB0, 0xFF})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["low"] != float64(0xA) || result["high"] != float64(0xB) || result["next"] != float64(0xFF) {
+		t.Errorf("result = %v, want low=10 high=11 next=255", result)
+	}
+	encoded, err := schema.Encode(result)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x0A, 0xB0, 0xFF}) {
+		t.Errorf("Encode = %x, want 0ab0ff", encoded)
+	}
+}
+
+func TestByteGroupEncodeOverflow(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - byte_group:
+      size: 1
+      fields:
+        - name: mode
+          type: u8[0:2]
+        - name: level
+          type: u8[3:6]
+          mult: 0.5
+        - name: alarm
+          type: bool
+          bit: 7
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	encoded, err := schema.Encode(map[string]any{"mode": 7.0, "level": 7.5, "alarm": true})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0xFF}) {
+		t.Errorf("Encode = %x, want ff", encoded)
+	}
+
+	tests := []struct {
+		name string
+		data map[string]any
+	}{
+		{"too large", map[string]any{"mode": 8.0}},
+		{"negative", map[string]any{"mode": -1.0}},
+		{"too large after modifiers", map[string]any{"level": 8.0}},
+		{"not a number", map[string]any{"mode": "fast"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := schema.Encode(tt.data); err == nil {
+				t.Errorf("Encode(%v) should fail", tt.data)
+			}
+		})
+	}
+
+	// A member range outside the declared size is an error
+	bad, err := ParseSchema(`
+name: test
+fields:
+  - byte_group:
+      size: 1
+      fields:
+        - name: wide
+          type: u16[4:11]
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if _, err := bad.Encode(map[string]any{"wide": 1.0}); err == nil ||
+		!strings.Contains(err.Error(), "exceed") {
+		t.Errorf("Encode err = %v, want range error", err)
+	}
+}
+
+func TestConsumeOnBoolDeprecated(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: a
+    type: bool
+    bit: 0
+  - name: b
+    type: bool
+    bit: 1
+    consume: 1
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], "deprecated") {
+		t.Errorf("Warnings = %v, want consume deprecation", schema.Warnings)
+	}
+}
+
+func TestRoundAfterModifiers(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: battery_voltage
+    type: u16
+    mult: 0.001
+    add: 0.5
+    round: 2
+  - name: pressure
+    type: u32
+    div: 3
+    precision: 4
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	// 2666 * 0.001 + 0.5 = 3.1659999999999995 -> 3.17; 100000/3 -> 33330
+	result, err := schema.Decode([]byte{0x0A, 0x6A, 0x00, 0x01, 0x86, 0xA0})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["battery_voltage"] != 3.17 {
+		t.Errorf("battery_voltage = %v, want 3.17", result["battery_voltage"])
+	}
+	if result["pressure"] != float64(33330) {
+		t.Errorf("pressure = %v, want 33330", result["pressure"])
+	}
+}
+
+func TestRoundAsString(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: temperature
+    type: s16
+    div: 10
+    round: 2
+    as_string: true
+    var: temp
+  - name: doubled
+    type: number
+    compute:
+      op: mul
+      a: $temp
+      b: 2
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	result, err := schema.Decode([]byte{0x00, 0xE7})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if result["temperature"] != "23.10" {
+		t.Errorf("temperature = %#v, want \"23.10\"", result["temperature"])
+	}
+	if v, _ := result["doubled"].(float64); math.Abs(v-46.2) > 1e-9 {
+		t.Errorf("doubled = %v, want 46.2 (variable keeps number)", result["doubled"])
+	}
+
+	encoded, err := schema.Encode(map[string]any{"temperature": "23.10"})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0x00, 0xE7}) {
+		t.Errorf("Encode = %x, want 00e7", encoded)
+	}
+}
+
+func TestMatchOnExpression(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: header
+    type: u8
+    var: hdr
+  - name: body
+    type: Match
+    on: "($hdr >> 4) & 0x0F"
+    cases:
+      - case: 1
+        fields:
+          - name: temperature
+            type: s16
+      - case: 2
+        fields:
+          - name: humidity
+            type: u8
+  - match:
+      field: "$hdr & 0x0F"
+      cases:
+        3:
+          - name: battery
+            type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	// hdr = 0x23: type 2 (humidity), low nibble 3 (battery)
+	result, err := schema.Decode([]byte{0x23, 0x40, 0x5A})
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	body, ok := result["body"].(map[string]any)
+	if !ok || body["humidity"] != float64(0x40) {
+		t.Errorf("body = %v, want humidity=64", result["body"])
+	}
+	if result["battery"] != float64(0x5A) {
+		t.Errorf("battery = %v, want 90", result["battery"])
+	}
+}
+
+func TestMatchOnExpressionMissingVariable(t *testing.T) {
+	schema, _ := ParseSchema(`
+name: test
+fields:
+  - name: body
+    type: Match
+    on: "$nope & 0x0F"
+    cases:
+      - case: 0
+        fields:
+          - name: v
+            type: u8
+`)
+	if _, err := schema.Decode([]byte{0x01}); err == nil {
+		t.Error("expected error for missing variable in match expression")
+	}
+}
+
+func TestFormulaBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"0xF0 >> 4", 15},
+		{"1 << 3", 8},
+		{"0x1234 & 0xFF", 0x34},
+		{"0x10 | 0x01", 0x11},
+		{"0xFF ^ 0x0F", 0xF0},
+		{"(0xAB >> 4) & 0x0F == 10", 0}, // & binds looser than ==
+		{"((0xAB >> 4) & 0x0F) == 10", 1},
+		{"1 && 2 & 3", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := evalExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("evalExpr error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evalExpr(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendEncode(t *testing.T) {
+	s, err := ParseSchema(`
+name: cmd
+fields:
+  - {name: command, type: u8}
+  - {name: interval, type: u16, required: true}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	buf := make([]byte, 0, 16)
+	buf = append(buf, 0xFF)
+	out, err := s.AppendEncode(buf, map[string]any{"command": 1, "interval": 600})
+	if err != nil {
+		t.Fatalf("AppendEncode() error = %v", err)
+	}
+	if !bytes.Equal(out, []byte{0xFF, 0x01, 0x02, 0x58}) {
+		t.Errorf("AppendEncode() = %X, want FF010258", out)
+	}
+	if &out[0] != &buf[0] {
+		t.Error("AppendEncode() reallocated a buffer with spare capacity")
+	}
+
+	// Reusing the buffer overwrites the previous message
+	out, err = s.AppendEncode(out[:0], map[string]any{"command": 2, "interval": 1})
+	if err != nil {
+		t.Fatalf("AppendEncode() error = %v", err)
+	}
+	if !bytes.Equal(out, []byte{0x02, 0x00, 0x01}) {
+		t.Errorf("AppendEncode() reuse = %X, want 020001", out)
+	}
+
+	// On error the buffer comes back as it was passed in
+	prefix := []byte{0xFF}
+	out, err = s.AppendEncode(prefix, map[string]any{"command": 3})
+	if err == nil {
+		t.Fatal("AppendEncode() without a required field: no error")
+	}
+	if !bytes.Equal(out, []byte{0xFF}) {
+		t.Errorf("AppendEncode() after error = %X, want FF", out)
+	}
+}
+
+func TestFormulaStringComparison(t *testing.T) {
+	ctx := NewDecodeContext(nil, "big")
+	ctx.Variables["mode"] = "fast"
+	ctx.Variables["status"] = 2.0
+	tests := []struct {
+		formula string
+		want    float64
+	}{
+		{`$mode == "fast"`, 1},
+		{`$mode != "fast"`, 0},
+		{`$mode == 'slow' ? 10 : 20`, 20},
+		{`"fast" == $mode && $status > 1`, 1},
+		{`$mode in ["slow", "fast"]`, 1},
+		{`$mode not in ['slow', 'fast']`, 0},
+		{`$status in [1, 2, 3]`, 1},
+		{`$status not in [1, 3]`, 1},
+		{`$status in [1, 1 + 2]`, 0},
+		{`$status == "2"`, 0},
+		{`$mode == "max x and y" or x > 3`, 1},
+	}
+	for _, tt := range tests {
+		got, err := evaluateFormula(tt.formula, 5, ctx)
+		if err != nil {
+			t.Errorf("evaluateFormula(%q) error = %v", tt.formula, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("evaluateFormula(%q) = %v, want %v", tt.formula, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`$mode > "a"`, `$mode in [1`, `"open`} {
+		if _, err := evaluateFormula(bad, 0, ctx); err == nil {
+			t.Errorf("evaluateFormula(%q): no error", bad)
+		}
+	}
+}
+
+func TestGuardStringConditions(t *testing.T) {
+	s, err := ParseSchema(`
+name: modes
+fields:
+  - name: mode
+    type: u8
+    lookup: {0: idle, 1: fast, 2: slow}
+  - name: rate
+    type: u8
+    guard:
+      when:
+        - field: $mode
+          in: [fast, slow]
+      else: null
+  - name: idle_time
+    type: u8
+    guard:
+      when:
+        - field: $mode
+          eq: idle
+      else: null
+  - name: boost
+    type: u8
+    formula: '$mode == "fast" ? x * 2 : x'
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		payload []byte
+		want    map[string]any
+	}{
+		{[]byte{0, 5, 6, 7}, map[string]any{"mode": "idle", "idle_time": 6.0, "boost": 7.0}},
+		{[]byte{1, 5, 6, 7}, map[string]any{"mode": "fast", "rate": 5.0, "boost": 14.0}},
+		{[]byte{2, 5, 6, 7}, map[string]any{"mode": "slow", "rate": 5.0, "boost": 7.0}},
+	}
+	for _, tt := range tests {
+		got, err := s.Decode(tt.payload)
+		if err != nil {
+			t.Fatalf("Decode(%X) error = %v", tt.payload, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode(%X) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestGuardGroups(t *testing.T) {
+	s, err := ParseSchema(`
+name: grouped
+fields:
+  - {name: a, type: s8}
+  - {name: b, type: s8}
+  - {name: c, type: u8}
+  - name: reading
+    type: u16
+    guard:
+      when:
+        - any:
+            - all:
+                - {field: $a, gt: 0}
+                - {field: $b, gt: 0}
+            - {field: $c, eq: 1}
+        - not: {field: x, eq: 0xFFFF}
+      else: null
+  - name: missing
+    type: u8
+    guard:
+      when:
+        - not: {field: $nope, eq: 1}
+      else: null
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		want    bool
+	}{
+		{"a and b", []byte{1, 1, 0, 0x00, 0x10, 0}, true},
+		{"a only", []byte{1, 0xFF, 0, 0x00, 0x10, 0}, false},
+		{"c", []byte{0, 0, 1, 0x00, 0x10, 0}, true},
+		{"sentinel", []byte{1, 1, 1, 0xFF, 0xFF, 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Decode(tt.payload)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if _, ok := got["reading"]; ok != tt.want {
+				t.Errorf("reading present = %v, want %v (%v)", ok, tt.want, got)
+			}
+			if _, ok := got["missing"]; ok {
+				t.Errorf("guard on an undecoded variable passed under not: %v", got["missing"])
+			}
+		})
+	}
+}
+
+func TestBlockEndianInherited(t *testing.T) {
+	s, err := ParseSchema(`
+name: mixed
+endian: big
+fields:
+  - {name: kind, type: u16}
+  - name: sensors
+    type: Object
+    endian: little
+    fields:
+      - {name: temp, type: s16, div: 10}
+      - {name: pressure, type: u32}
+      - {name: legacy, type: u16, endian: big}
+  - match:
+      field: $kind
+      endian: little
+      cases:
+        1:
+          - {name: count, type: u16}
+  - {name: kind_after, type: u16}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{
+		0x00, 0x01, // kind (big) = 1
+		0xFA, 0x00, // temp (little) = 250
+		0x10, 0x27, 0x00, 0x00, // pressure (little) = 10000
+		0x12, 0x34, // legacy (big) = 0x1234
+		0x02, 0x00, // count (little) = 2
+		0x00, 0x03, // kind_after (big again) = 3
+	}
+	got, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"kind":       1.0,
+		"sensors":    map[string]any{"temp": 25.0, "pressure": 10000.0, "legacy": 4660.0},
+		"count":      2.0,
+		"kind_after": 3.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+
+	encoded, err := s.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = %X, want %X", encoded, payload)
+	}
+}
+
+func TestTLVNameTemplate(t *testing.T) {
+	s, err := ParseSchema(`
+name: cayenne
+fields:
+  - type: TLV
+    tag_fields:
+      - {name: channel, type: u8}
+      - {name: kind, type: u8}
+    tag_key: kind
+    name_template: "ch{channel}_{name}"
+    cases:
+      "103":
+        - {name: temperature, type: s16, div: 10}
+      "104":
+        - {name: humidity, type: u8, div: 2}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", s.Warnings)
+	}
+
+	got, err := s.Decode([]byte{
+		0x01, 0x67, 0x00, 0xFA, // ch1 temperature 25.0
+		0x02, 0x67, 0xFF, 0xD8, // ch2 temperature -4.0
+		0x03, 0x68, 0x50, // ch3 humidity 40
+	})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{"ch1_temperature": 25.0, "ch2_temperature": -4.0, "ch3_humidity": 40.0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+
+	for tmpl, msg := range map[string]string{
+		"ch{channel}":          "needs {name}",
+		"ch{port}_{name}":      "{port} is not",
+		"t{tag}_{name}_{kind}": "",
+	} {
+		s, err := ParseSchema(`
+name: bad
+fields:
+  - type: TLV
+    tag_fields: [{name: channel, type: u8}, {name: kind, type: u8}]
+    tag_key: kind
+    name_template: "` + tmpl + `"
+    cases: {"1": [{name: v, type: u8}]}
+`)
+		if err != nil {
+			t.Fatalf("ParseSchema(%q) error = %v", tmpl, err)
+		}
+		warnings := strings.Join(s.Warnings, "; ")
+		if msg == "" && warnings != "" || msg != "" && !strings.Contains(warnings, msg) {
+			t.Errorf("name_template %q: warnings = %q, want %q", tmpl, warnings, msg)
+		}
+	}
+}
+
+func TestDecodeWithInfo(t *testing.T) {
+	s, err := ParseSchema(`
+name: env_sensor
+version: 3
+fields:
+  - {name: temperature, type: s16, div: 10, valid_range: [-40, 85]}
+  - {name: humidity, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	result, info, err := s.DecodeWithInfo([]byte{0x03, 0xE8, 0x32, 0xAA, 0xBB}, 2)
+	if err != nil {
+		t.Fatalf("DecodeWithInfo() error = %v", err)
+	}
+	if result["humidity"] != 50.0 {
+		t.Errorf("humidity = %v, want 50", result["humidity"])
+	}
+	if info.Schema != "env_sensor" || info.Version != 3 || info.FPort != 2 {
+		t.Errorf("info identity = %s v%d port %d", info.Schema, info.Version, info.FPort)
+	}
+	if info.BytesConsumed != 3 || info.TrailingBytes != 2 {
+		t.Errorf("info bytes = %d consumed, %d trailing; want 3, 2", info.BytesConsumed, info.TrailingBytes)
+	}
+	if len(info.Warnings) != 1 || !strings.Contains(info.Warnings[0], "temperature") {
+		t.Errorf("info warnings = %v, want the out-of-range temperature", info.Warnings)
+	}
+
+	_, info, err = s.DecodeWithInfo([]byte{0x00, 0xFA, 0x32}, 2)
+	if err != nil {
+		t.Fatalf("DecodeWithInfo() error = %v", err)
+	}
+	if info.TrailingBytes != 0 || len(info.Warnings) != 0 {
+		t.Errorf("exact payload info = %+v", info)
+	}
+}
+
+func TestVariableScope(t *testing.T) {
+	const nested = `
+name: scoped
+fields:
+  - {name: first, type: u8, var: v}
+  - name: outer
+    type: Object
+    fields:
+      - name: inner
+        type: Object
+        fields:
+          - {name: a, type: u8, var: pa, scope: parent}
+          - {name: b, type: u8, var: v, scope: local}
+          - {name: b_copy, type: number, ref: $v}
+      - {name: a_copy, type: number, ref: $pa}
+  - {name: v_after, type: number, ref: $v}
+`
+	s, err := ParseSchema(nested)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	got, err := s.Decode([]byte{1, 2, 3})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"first": 1.0,
+		"outer": map[string]any{
+			"inner":  map[string]any{"a": 2.0, "b": 3.0, "b_copy": 3.0},
+			"a_copy": 2.0,
+		},
+		"v_after": 1.0, // The local v no longer shadows the global one
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+
+	// A parent-scoped variable ends with the enclosing list
+	s, err = ParseSchema(nested + "  - {name: pa_after, type: number, ref: $pa}\n")
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.Decode([]byte{1, 2, 3}); err == nil || !strings.Contains(err.Error(), "pa") {
+		t.Errorf("Decode() error = %v, want pa not found", err)
+	}
+
+	s, err = ParseSchema(`
+name: bad_scope
+fields:
+  - {name: a, type: u8, var: a, scope: block}
+  - {name: b, type: u8, scope: local}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) != 2 {
+		t.Errorf("Warnings = %v, want 2 scope warnings", s.Warnings)
+	}
+}
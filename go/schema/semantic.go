@@ -0,0 +1,57 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+// semanticPresets expand `semantic:` field shorthands into the field (or
+// byte group) they stand for. name is the field's own name, or the
+// preset's default when the schema gives none.
+var semanticPresets = map[string]struct {
+	defaultName string
+	expand      func(name string) map[string]any
+}{
+	// Top bit: running on external power; low 7 bits: battery percent
+	"battery_percent_flag": {"battery", func(name string) map[string]any {
+		return map[string]any{"byte_group": []any{
+			map[string]any{"name": "external_power", "type": "bool", "bit": 7},
+			map[string]any{"name": name, "type": "u8[0:6]", "unit": "%", "valid_range": []any{0, 100}},
+		}}
+	}},
+	// One byte of voltage: (x + 150) * 0.01 V, 1.50 V to 4.05 V
+	"battery_voltage_offset": {"battery_voltage", func(name string) map[string]any {
+		return map[string]any{"name": name, "type": "u8", "add": 150, "mult": 0.01, "round": 2, "unit": "V"}
+	}},
+	// Milesight: battery level in percent
+	"battery_milesight": {"battery", func(name string) map[string]any {
+		return map[string]any{"name": name, "type": "u8", "unit": "%", "valid_range": []any{0, 100}}
+	}},
+	// Elsys: supply voltage in millivolts
+	"battery_elsys": {"battery_voltage", func(name string) map[string]any {
+		return map[string]any{"name": name, "type": "u16", "unit": "mV"}
+	}},
+}
+
+// expandSemantic returns the field map a `semantic:` preset stands for.
+// Keys given next to semantic override the preset's own (so `div`,
+// `valid_range` or `var` can be adjusted); modifiers apply as add, mult,
+// div. Other semantic values (IPSO maps, "temperature.air" tags) are
+// metadata and leave the field as written.
+func expandSemantic(fm map[string]any) (map[string]any, bool) {
+	sem, _ := fm["semantic"].(string)
+	preset, ok := semanticPresets[sem]
+	if !ok {
+		return nil, false
+	}
+	name, _ := fm["name"].(string)
+	if name == "" {
+		name = preset.defaultName
+	}
+	expanded := preset.expand(name)
+	for k, v := range fm {
+		if k != "semantic" && k != "name" {
+			expanded[k] = v
+		}
+	}
+	return expanded, true
+}
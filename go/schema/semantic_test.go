@@ -0,0 +1,72 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSemanticBatteryPresets(t *testing.T) {
+	s, err := ParseSchema(`
+name: tracker
+fields:
+  - semantic: battery_percent_flag
+  - name: cell
+    semantic: battery_voltage_offset
+  - semantic: battery_elsys
+    name: vdd
+    var: vdd
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if w := s.checkInvalid(); len(w) != 0 {
+		t.Fatalf("checkInvalid() = %v", w)
+	}
+	result, err := s.Decode([]byte{0xD5, 0xB4, 0x0C, 0xE4})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"external_power": true,
+		"battery":        85.0,
+		"cell":           3.3,
+		"vdd":            3300.0,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Decode() = %v, want %v", result, want)
+	}
+	if s.Fields[2].Var != "vdd" || s.Fields[2].Extensions["unit"] != "mV" {
+		t.Errorf("battery_elsys field = %+v", s.Fields[2])
+	}
+
+	encoded, err := s.Encode(map[string]any{"external_power": true, "battery": 85, "cell": 3.3, "vdd": 3300})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, []byte{0xD5, 0xB4, 0x0C, 0xE4}) {
+		t.Errorf("Encode() = %X", encoded)
+	}
+}
+
+func TestSemanticMetadataKept(t *testing.T) {
+	s, err := ParseSchema(`
+name: env
+fields:
+  - {name: temperature, type: s16, semantic: temperature.air}
+  - {name: humidity, type: u8, semantic: {ipso: 3304}}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if got := s.Fields[0].Extensions["semantic"]; got != "temperature.air" || s.Fields[0].Type != TypeS16 {
+		t.Errorf("Fields[0] = %+v, want semantic tag kept", s.Fields[0])
+	}
+	if _, ok := s.Fields[1].Extensions["semantic"].(map[string]any); !ok {
+		t.Errorf("Fields[1].Extensions = %v, want semantic map kept", s.Fields[1].Extensions)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// SenML output (RFC 8428) renders a decoded result as a pack of records,
+// one per value, for LwM2M servers and other SenML consumers. Fields name
+// and annotate their records with the senml: key:
+//
+//	- name: temp
+//	  type: s16
+//	  mult: 0.1
+//	  senml: {name: temperature, unit: Cel}
+//
+// Without it a record is named by its flattened output key (readings.0.temp)
+// and takes its unit from unit: or senml_unit:. Fields with role: timestamp
+// set the pack's base time instead of becoming records.
+
+// SenMLOptions controls SenML output.
+type SenMLOptions struct {
+	FPort    int       // Port the result was decoded on (port-based schemas)
+	BaseName string    // bn: prefix of every name, e.g. "urn:dev:deveui:0004a30b001c0530:"
+	BaseTime time.Time // bt: when no field has role: timestamp; zero omits it
+}
+
+// SenMLRecord is one SenML record. Exactly one of the value fields is set.
+type SenMLRecord struct {
+	BaseName    string
+	BaseTime    float64 // Seconds since the Unix epoch; 0 if unset
+	Name        string
+	Unit        string
+	Value       *float64
+	StringValue *string
+	BoolValue   *bool
+	DataValue   []byte
+}
+
+// SenMLPack is a list of SenML records. The base fields are set on the
+// first record only.
+type SenMLPack []SenMLRecord
+
+// SenML converts a decoded result to a SenML pack, in schema order.
+func (s *Schema) SenML(result map[string]any, opts SenMLOptions) (SenMLPack, error) {
+	annotated := map[string]Field{}
+	if err := s.walkOutputPaths(opts.FPort, func(f Field, path string) {
+		annotated[path] = f
+	}); err != nil {
+		return nil, err
+	}
+
+	var pack SenMLPack
+	baseTime := opts.BaseTime
+	err := s.walkLeaves(result, opts.FPort, func(prefix string, v any) error {
+		if v == nil {
+			return nil
+		}
+		f := annotated[s.rolePath(prefix)]
+		if f.Role == RoleTimestamp {
+			t, err := pointTime(v)
+			if err != nil {
+				return fmt.Errorf("senml: %s: %w", prefix, err)
+			}
+			baseTime = t
+			return nil
+		}
+		name, unit := senmlAnnotation(f)
+		rec := SenMLRecord{Name: prefix, Unit: unit}
+		if name != "" {
+			if i := strings.LastIndexByte(prefix, '.'); i >= 0 {
+				name = prefix[:i+1] + name
+			}
+			rec.Name = name
+		}
+		if !setSenMLValue(&rec, v) {
+			return nil
+		}
+		pack = append(pack, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pack) == 0 {
+		return nil, fmt.Errorf("senml: result has no values")
+	}
+	pack[0].BaseName = opts.BaseName
+	if !baseTime.IsZero() {
+		pack[0].BaseTime = float64(baseTime.UnixNano()) / 1e9
+	}
+	return pack, nil
+}
+
+// senmlAnnotation returns the record name and unit a field declares.
+func senmlAnnotation(f Field) (name, unit string) {
+	if ann, ok := f.Extensions["senml"].(map[string]any); ok {
+		name, _ = ann["name"].(string)
+		unit, _ = ann["unit"].(string)
+	}
+	if unit == "" {
+		unit = fieldUnit(f)
+	}
+	return name, unit
+}
+
+// setSenMLValue stores v in the record's value field for its type,
+// reporting false for values SenML cannot carry (NaN, infinities).
+func setSenMLValue(rec *SenMLRecord, v any) bool {
+	switch val := v.(type) {
+	case bool:
+		rec.BoolValue = &val
+	case string:
+		rec.StringValue = &val
+	case []byte:
+		rec.DataValue = val
+	default:
+		num, ok := toFloat64(v)
+		if !ok || math.IsNaN(num) || math.IsInf(num, 0) {
+			return false
+		}
+		rec.Value = &num
+	}
+	return true
+}
+
+// MarshalJSON encodes the record as a SenML JSON object (RFC 8428 §5).
+func (r SenMLRecord) MarshalJSON() ([]byte, error) {
+	m := map[string]any{}
+	for _, f := range r.fields() {
+		if data, ok := f.value.([]byte); ok {
+			// Unpadded base64url (RFC 8428 §4.3)
+			m[f.json] = base64.RawURLEncoding.EncodeToString(data)
+			continue
+		}
+		m[f.json] = f.value
+	}
+	return json.Marshal(m)
+}
+
+// senmlLabel is a record field with its JSON and CBOR labels.
+type senmlLabel struct {
+	json  string
+	cbor  int
+	value any
+}
+
+// fields lists the record's set fields in CBOR label order.
+func (r SenMLRecord) fields() []senmlLabel {
+	var out []senmlLabel
+	add := func(jsonKey string, cborKey int, set bool, v any) {
+		if set {
+			out = append(out, senmlLabel{jsonKey, cborKey, v})
+		}
+	}
+	add("bn", -2, r.BaseName != "", r.BaseName)
+	add("bt", -3, r.BaseTime != 0, r.BaseTime)
+	add("n", 0, r.Name != "", r.Name)
+	add("u", 1, r.Unit != "", r.Unit)
+	if r.Value != nil {
+		add("v", 2, true, *r.Value)
+	}
+	if r.StringValue != nil {
+		add("vs", 3, true, *r.StringValue)
+	}
+	if r.BoolValue != nil {
+		add("vb", 4, true, *r.BoolValue)
+	}
+	add("vd", 8, r.DataValue != nil, r.DataValue)
+	return out
+}
+
+// JSON encodes the pack as SenML JSON.
+func (p SenMLPack) JSON() ([]byte, error) {
+	return json.Marshal([]SenMLRecord(p))
+}
+
+// CBOR encodes the pack as SenML CBOR (RFC 8428 §6): an array of maps
+// with integer labels. Integral values are encoded as integers, others as
+// 64-bit floats.
+func (p SenMLPack) CBOR() []byte {
+	buf := cborHead(nil, 4, uint64(len(p)))
+	for _, r := range p {
+		fields := r.fields()
+		buf = cborHead(buf, 5, uint64(len(fields)))
+		for _, f := range fields {
+			buf = cborInt(buf, int64(f.cbor))
+			switch v := f.value.(type) {
+			case string:
+				buf = cborHead(buf, 3, uint64(len(v)))
+				buf = append(buf, v...)
+			case []byte:
+				buf = cborHead(buf, 2, uint64(len(v)))
+				buf = append(buf, v...)
+			case bool:
+				if v {
+					buf = append(buf, 0xF5)
+				} else {
+					buf = append(buf, 0xF4)
+				}
+			case float64:
+				buf = cborNumber(buf, v)
+			}
+		}
+	}
+	return buf
+}
+
+// cborHead appends a CBOR initial byte and argument.
+func cborHead(buf []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(buf, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, m|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, m|27), n)
+}
+
+func cborInt(buf []byte, n int64) []byte {
+	if n < 0 {
+		return cborHead(buf, 1, uint64(-1-n))
+	}
+	return cborHead(buf, 0, uint64(n))
+}
+
+// cborNumber appends v as an integer when it is one, else as a float64.
+func cborNumber(buf []byte, v float64) []byte {
+	if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+		return cborInt(buf, int64(v))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xFB), math.Float64bits(v))
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+const senmlSchema = `
+name: door_sensor
+fields:
+  - {name: time, type: u32, role: timestamp}
+  - name: temp
+    type: s16
+    mult: 0.1
+    round: 1
+    senml: {name: temperature, unit: Cel}
+  - {name: door_open, type: bool, consume: 1}
+  - {name: humidity, type: u8, unit: "%RH"}
+`
+
+func TestSenML(t *testing.T) {
+	s, err := ParseSchema(senmlSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode([]byte{0x65, 0x53, 0xF1, 0x00, 0x00, 0xE7, 0x01, 0x32})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	pack, err := s.SenML(result, SenMLOptions{BaseName: "urn:dev:x:"})
+	if err != nil {
+		t.Fatalf("SenML() error = %v", err)
+	}
+
+	js, err := pack.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	wantJSON := `[{"bn":"urn:dev:x:","bt":1700000000,"n":"temperature","u":"Cel","v":23.1},` +
+		`{"n":"door_open","vb":true},{"n":"humidity","u":"%RH","v":50}]`
+	if string(js) != wantJSON {
+		t.Errorf("JSON() = %s\nwant %s", js, wantJSON)
+	}
+
+	wantCBOR, _ := hex.DecodeString("83" +
+		"a5" + "21" + "6a" + hex.EncodeToString([]byte("urn:dev:x:")) +
+		"22" + "1a6553f100" +
+		"00" + "6b" + hex.EncodeToString([]byte("temperature")) +
+		"01" + "63" + hex.EncodeToString([]byte("Cel")) +
+		"02" + "fb403719999999999a" +
+		"a2" + "00" + "69" + hex.EncodeToString([]byte("door_open")) + "04" + "f5" +
+		"a3" + "00" + "68" + hex.EncodeToString([]byte("humidity")) +
+		"01" + "63" + hex.EncodeToString([]byte("%RH")) + "02" + "1832")
+	if got := pack.CBOR(); !bytes.Equal(got, wantCBOR) {
+		t.Errorf("CBOR() = %x\nwant %x", got, wantCBOR)
+	}
+}
+
+func TestSenMLNested(t *testing.T) {
+	s, err := ParseSchema(`
+name: logger
+fields:
+  - name: readings
+    type: repeat
+    count: 2
+    fields:
+      - {name: t, type: s8, senml: {name: temperature, unit: Cel}}
+  - {name: serial, type: Hex, length: 2}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode([]byte{0x15, 0xFE, 0xAB, 0xCD})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	pack, err := s.SenML(result, SenMLOptions{})
+	if err != nil {
+		t.Fatalf("SenML() error = %v", err)
+	}
+	js, _ := pack.JSON()
+	want := `[{"n":"readings.0.temperature","u":"Cel","v":21},` +
+		`{"n":"readings.1.temperature","u":"Cel","v":-2},{"n":"serial","vs":"abcd"}]`
+	if string(js) != want {
+		t.Errorf("JSON() = %s\nwant %s", js, want)
+	}
+	// -2 is a negative CBOR integer
+	if cbor := pack.CBOR(); !bytes.Contains(cbor, []byte{0x02, 0x21}) {
+		t.Errorf("CBOR() = %x, want -2 as 0x21", cbor)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A series (type: series) is a repeat of samples logged at a fixed
+// interval, the last (or, with order: newest_first, the first) taken at
+// transmit time:
+//
+//	- name: temperature_log
+//	  type: series
+//	  interval: 600      # Seconds between samples, or $var
+//	  until: end         # Or count: / byte_length:, as for repeat
+//	  fields:
+//	    - {name: value, type: s16, div: 10}
+//
+// Each element gets offset_s, its age relative to the transmission as a
+// negative number of seconds, and when decoding with DecodeAt also time,
+// the absolute sample time in RFC 3339.
+
+// Series sample order (the `order:` key).
+const (
+	SeriesOldestFirst = "oldest_first" // Last sample taken at transmit time (default)
+	SeriesNewestFirst = "newest_first" // First sample taken at transmit time
+)
+
+// Keys added to each series element.
+const (
+	SeriesOffsetKey = "offset_s"
+	SeriesTimeKey   = "time"
+)
+
+// checkSeries reports series settings that cannot work.
+func checkSeries(f Field) string {
+	if f.Type != TypeSeries {
+		return ""
+	}
+	switch iv := f.Interval.(type) {
+	case nil:
+		return fmt.Sprintf("%s: series needs interval", f.Name)
+	case string:
+		if !strings.HasPrefix(iv, "$") {
+			return fmt.Sprintf("%s: interval must be a number of seconds or a $variable", f.Name)
+		}
+	default:
+		if n, ok := toFloat64(iv); !ok || n <= 0 {
+			return fmt.Sprintf("%s: interval must be a positive number of seconds", f.Name)
+		}
+	}
+	switch f.SeriesOrder {
+	case "", SeriesOldestFirst, SeriesNewestFirst:
+	default:
+		return fmt.Sprintf("%s: order: expected %s or %s, got %q", f.Name, SeriesOldestFirst, SeriesNewestFirst, f.SeriesOrder)
+	}
+	if f.Flatten {
+		return fmt.Sprintf("%s: series elements cannot be flattened", f.Name)
+	}
+	return ""
+}
+
+// decodeSeries decodes a series like a repeat and adds the sample offsets.
+func decodeSeries(field Field, ctx *DecodeContext) ([]any, error) {
+	var interval float64
+	switch iv := field.Interval.(type) {
+	case string:
+		name := strings.TrimPrefix(iv, "$")
+		v, ok := ctx.Variables[name]
+		if !ok {
+			return nil, fmt.Errorf("series interval variable not found: %s", name)
+		}
+		if interval, ok = toFloat64(v); !ok {
+			return nil, fmt.Errorf("series interval variable %s is not a number", name)
+		}
+	default:
+		interval, _ = toFloat64(iv)
+	}
+
+	elems, err := decodeRepeat(field, ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range elems {
+		m, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		age := len(elems) - 1 - i
+		if field.SeriesOrder == SeriesNewestFirst {
+			age = i
+		}
+		offset := -float64(age) * interval
+		m[SeriesOffsetKey] = offset
+		if !ctx.ReceivedAt.IsZero() {
+			t := ctx.ReceivedAt.Add(time.Duration(offset * float64(time.Second)))
+			m[SeriesTimeKey] = t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return elems, nil
+}
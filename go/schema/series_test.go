@@ -0,0 +1,117 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const seriesSchema = `
+name: logger
+endian: big
+fields:
+  - name: interval_min
+    type: u8
+    var: interval_min
+  - name: interval_s
+    type: number
+    ref: $interval_min
+    mult: 60
+    var: interval_s
+  - name: temperature_log
+    type: series
+    interval: $interval_s
+    until: end
+    fields:
+      - {name: value, type: s16, div: 10}
+`
+
+func TestSeriesDecode(t *testing.T) {
+	s, err := ParseSchema(seriesSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{10, 0x00, 0xC8, 0x00, 0xD2, 0x00, 0xDC}
+
+	result, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []any{
+		map[string]any{"value": 20.0, "offset_s": -1200.0},
+		map[string]any{"value": 21.0, "offset_s": -600.0},
+		map[string]any{"value": 22.0, "offset_s": 0.0},
+	}
+	if !reflect.DeepEqual(result["temperature_log"], want) {
+		t.Errorf("temperature_log = %v, want %v", result["temperature_log"], want)
+	}
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	result, err = s.DecodeAt(payload, 0, at)
+	if err != nil {
+		t.Fatalf("DecodeAt() error = %v", err)
+	}
+	first := result["temperature_log"].([]any)[0].(map[string]any)
+	if first["time"] != "2026-03-01T11:40:00Z" {
+		t.Errorf("first sample time = %v", first["time"])
+	}
+
+	encoded, err := s.Encode(result)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = % X, want % X", encoded, payload)
+	}
+}
+
+func TestSeriesNewestFirst(t *testing.T) {
+	s, err := ParseSchema(`
+name: logger
+fields:
+  - name: log
+    type: series
+    interval: 300
+    order: newest_first
+    count: 2
+    fields:
+      - {name: value, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode([]byte{5, 4})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	log := result["log"].([]any)
+	if log[0].(map[string]any)["offset_s"] != 0.0 || log[1].(map[string]any)["offset_s"] != -300.0 {
+		t.Errorf("log = %v", log)
+	}
+}
+
+func TestSeriesWarnings(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{"{name: log, type: series, count: 2, fields: [{name: v, type: u8}]}", "needs interval"},
+		{"{name: log, type: series, interval: -5, count: 2, fields: [{name: v, type: u8}]}", "positive number"},
+		{"{name: log, type: series, interval: fast, count: 2, fields: [{name: v, type: u8}]}", "$variable"},
+		{"{name: log, type: series, interval: 60, order: random, count: 2, fields: [{name: v, type: u8}]}", "order:"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.field, s.Warnings, tt.want)
+		}
+	}
+}
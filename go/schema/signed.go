@@ -0,0 +1,102 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors from LoadSignedBundle, for errors.Is.
+var (
+	ErrBundleDigest    = errors.New("bundle digest mismatch")
+	ErrBundleUnsigned  = errors.New("bundle is not signed")
+	ErrBundleSignature = errors.New("bundle signature invalid")
+)
+
+// SignedBundle is the envelope a management plane ships a bundle in: the
+// bundle source verbatim, its SHA-256 digest and an optional Ed25519
+// signature over the source. It is stored as YAML (or JSON):
+//
+//	sha256: 3f0a...
+//	signature: base64...
+//	bundle: |
+//	  name: sensor_uplink
+//	  ...
+type SignedBundle struct {
+	SHA256    string `json:"sha256" yaml:"sha256"`                           // Hex digest of Bundle
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"` // Base64 Ed25519 signature of Bundle
+	Bundle    string `json:"bundle" yaml:"bundle"`                           // Bundle source (see ParseBundle)
+}
+
+// SignBundle wraps bundle source in a SignedBundle envelope with its
+// digest and, if key is not nil, an Ed25519 signature.
+func SignBundle(bundle string, key ed25519.PrivateKey) ([]byte, error) {
+	sum := sha256.Sum256([]byte(bundle))
+	env := SignedBundle{SHA256: hex.EncodeToString(sum[:]), Bundle: bundle}
+	if key != nil {
+		env.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(bundle)))
+	}
+	out, err := yaml.Marshal(&env)
+	if err != nil {
+		return nil, err
+	}
+	// Block scalars cannot carry every string (a leading blank line is
+	// lost), and the digest needs the exact bytes back
+	var back SignedBundle
+	if yaml.Unmarshal(out, &back) == nil && back.Bundle == bundle {
+		return out, nil
+	}
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, kv := range [][2]string{{"sha256", env.SHA256}, {"signature", env.Signature}, {"bundle", env.Bundle}} {
+		if kv[1] == "" {
+			continue
+		}
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: kv[0]},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: kv[1], Style: yaml.DoubleQuotedStyle})
+	}
+	return yaml.Marshal(doc)
+}
+
+// LoadSignedBundle verifies a SignedBundle envelope and parses the bundle
+// inside. The digest is always checked. With a public key the envelope
+// must also carry a valid signature from it; with a nil key signatures are
+// not checked, which only guards against corruption, not tampering.
+func LoadSignedBundle(data []byte, pubkey ed25519.PublicKey) (*Bundle, error) {
+	var env SignedBundle
+	if err := yaml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("signed bundle: %w", err)
+	}
+	if env.Bundle == "" {
+		return nil, fmt.Errorf("signed bundle: no bundle")
+	}
+
+	want, err := hex.DecodeString(strings.TrimSpace(env.SHA256))
+	if err != nil || len(want) != sha256.Size {
+		return nil, fmt.Errorf("signed bundle: sha256 must be %d hex bytes", sha256.Size)
+	}
+	if sum := sha256.Sum256([]byte(env.Bundle)); string(sum[:]) != string(want) {
+		return nil, ErrBundleDigest
+	}
+
+	if pubkey != nil {
+		if env.Signature == "" {
+			return nil, ErrBundleUnsigned
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(env.Signature))
+		if err != nil || len(pubkey) != ed25519.PublicKeySize || !ed25519.Verify(pubkey, []byte(env.Bundle), sig) {
+			return nil, ErrBundleSignature
+		}
+	}
+	return ParseBundle(env.Bundle)
+}
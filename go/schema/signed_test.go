@@ -0,0 +1,54 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignedBundle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(strings.NewReader(strings.Repeat("seed", 16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(strings.NewReader(strings.Repeat("other", 16)))
+
+	signed, err := SignBundle(deviceFamilyBundle, priv)
+	if err != nil {
+		t.Fatalf("SignBundle() error = %v", err)
+	}
+	b, err := LoadSignedBundle(signed, pub)
+	if err != nil {
+		t.Fatalf("LoadSignedBundle() error = %v", err)
+	}
+	if len(b.Names) != 3 || b.Get("sensor_downlink") == nil {
+		t.Errorf("bundle names = %v", b.Names)
+	}
+	if _, err := LoadSignedBundle(signed, nil); err != nil {
+		t.Errorf("LoadSignedBundle(nil key) error = %v", err)
+	}
+	if _, err := LoadSignedBundle(signed, otherPub); !errors.Is(err, ErrBundleSignature) {
+		t.Errorf("wrong key: error = %v, want ErrBundleSignature", err)
+	}
+
+	tampered := strings.Replace(string(signed), "div: 10", "div: 100", 1)
+	if _, err := LoadSignedBundle([]byte(tampered), pub); !errors.Is(err, ErrBundleDigest) {
+		t.Errorf("tampered: error = %v, want ErrBundleDigest", err)
+	}
+
+	unsigned, err := SignBundle(deviceFamilyBundle, nil)
+	if err != nil {
+		t.Fatalf("SignBundle(nil) error = %v", err)
+	}
+	if _, err := LoadSignedBundle(unsigned, nil); err != nil {
+		t.Errorf("digest-only bundle: error = %v", err)
+	}
+	if _, err := LoadSignedBundle(unsigned, pub); !errors.Is(err, ErrBundleUnsigned) {
+		t.Errorf("digest-only bundle with key: error = %v, want ErrBundleUnsigned", err)
+	}
+}
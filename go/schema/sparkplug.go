@@ -0,0 +1,134 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sparkplug B datatypes used for decoded values and their properties
+// (Sparkplug 3.0 §6.4.16).
+const (
+	SparkplugInt32   uint32 = 3
+	SparkplugDouble  uint32 = 10
+	SparkplugBoolean uint32 = 11
+	SparkplugString  uint32 = 12
+	SparkplugBytes   uint32 = 17
+)
+
+// Sparkplug quality property values (Sparkplug 3.0 §6.4.8).
+const (
+	SparkplugQualityBad  int32 = 0
+	SparkplugQualityGood int32 = 192
+)
+
+// SparkplugOptions controls Sparkplug export.
+type SparkplugOptions struct {
+	FPort     int       // Port the result was decoded on (port-based schemas)
+	Folder    string    // Prepended to every metric name, e.g. "tank1/"
+	Timestamp time.Time // Used when no field has role: timestamp; zero omits it
+}
+
+// SparkplugMetric is one Sparkplug B metric, ready to copy into a
+// protobuf Payload.Metric of an NBIRTH, DBIRTH or NDATA message.
+type SparkplugMetric struct {
+	Name       string
+	Timestamp  uint64 // Milliseconds since the Unix epoch; 0 if unknown
+	Datatype   uint32
+	Value      any // float64, bool, string or []byte per Datatype
+	Properties map[string]SparkplugProperty
+}
+
+// SparkplugProperty is a typed entry of a metric's PropertySet.
+type SparkplugProperty struct {
+	Type  uint32
+	Value any
+}
+
+// SparkplugMetrics converts a decoded result to Sparkplug B metrics in
+// schema order. Names are the flattened output keys with "/" as the
+// folder separator (readings/0/temp). Properties carry engUnit from
+// unit:, engLow and engHigh from valid_range:, and Quality from the
+// _quality map (192 good, 0 otherwise). A role: timestamp field sets the
+// timestamp of every metric instead of becoming one. Null values are
+// skipped.
+func (s *Schema) SparkplugMetrics(result map[string]any, opts SparkplugOptions) ([]SparkplugMetric, error) {
+	known := map[string]Field{}
+	if err := s.walkOutputPaths(opts.FPort, func(f Field, path string) {
+		known[path] = f
+	}); err != nil {
+		return nil, err
+	}
+	quality := qualityMap(result["_quality"])
+
+	var metrics []SparkplugMetric
+	ts := opts.Timestamp
+	err := s.walkLeaves(result, opts.FPort, func(key string, v any) error {
+		if v == nil {
+			return nil
+		}
+		f := known[s.rolePath(key)]
+		if f.Role == RoleTimestamp {
+			t, err := pointTime(v)
+			if err != nil {
+				return fmt.Errorf("sparkplug: %s: %w", key, err)
+			}
+			ts = t
+			return nil
+		}
+		m := SparkplugMetric{Name: opts.Folder + strings.ReplaceAll(key, ".", "/")}
+		switch val := v.(type) {
+		case bool:
+			m.Datatype, m.Value = SparkplugBoolean, val
+		case string:
+			m.Datatype, m.Value = SparkplugString, val
+		case []byte:
+			m.Datatype, m.Value = SparkplugBytes, val
+		default:
+			num, ok := toFloat64(v)
+			if !ok {
+				return nil
+			}
+			m.Datatype, m.Value = SparkplugDouble, num
+		}
+
+		props := map[string]SparkplugProperty{}
+		if u := fieldUnit(f); u != "" {
+			props["engUnit"] = SparkplugProperty{SparkplugString, u}
+		}
+		if len(f.ValidRange) == 2 {
+			props["engLow"] = SparkplugProperty{SparkplugDouble, f.ValidRange[0]}
+			props["engHigh"] = SparkplugProperty{SparkplugDouble, f.ValidRange[1]}
+		}
+		q, ok := quality[s.rolePath(key)]
+		if !ok {
+			q, ok = quality[f.Name]
+		}
+		if ok {
+			code := SparkplugQualityBad
+			if q == "good" {
+				code = SparkplugQualityGood
+			}
+			props["Quality"] = SparkplugProperty{SparkplugInt32, code}
+		}
+		if len(props) > 0 {
+			m.Properties = props
+		}
+		metrics = append(metrics, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !ts.IsZero() {
+		for i := range metrics {
+			metrics[i].Timestamp = uint64(ts.UnixMilli())
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSparkplugMetrics(t *testing.T) {
+	s, err := ParseSchema(`
+name: tank
+fields:
+  - {name: time, type: u32, role: timestamp}
+  - {name: temperature, type: s16, mult: 0.1, unit: "°C", valid_range: [-20, 60]}
+  - {name: pump, type: u8, lookup: {0: off, 1: on}}
+  - name: levels
+    type: repeat
+    count: 2
+    fields:
+      - {name: level, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// temperature 70.0 is outside valid_range
+	result, err := s.Decode([]byte{0x65, 0x53, 0xF1, 0x00, 0x02, 0xBC, 0x01, 0x28, 0x32})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	metrics, err := s.SparkplugMetrics(result, SparkplugOptions{Folder: "tank1/"})
+	if err != nil {
+		t.Fatalf("SparkplugMetrics() error = %v", err)
+	}
+	const ms = 1700000000000
+	want := []SparkplugMetric{
+		{
+			Name: "tank1/temperature", Timestamp: ms, Datatype: SparkplugDouble, Value: float64(70),
+			Properties: map[string]SparkplugProperty{
+				"engUnit": {SparkplugString, "°C"},
+				"engLow":  {SparkplugDouble, float64(-20)},
+				"engHigh": {SparkplugDouble, float64(60)},
+				"Quality": {SparkplugInt32, SparkplugQualityBad},
+			},
+		},
+		{Name: "tank1/pump", Timestamp: ms, Datatype: SparkplugString, Value: "on"},
+		{Name: "tank1/levels/0/level", Timestamp: ms, Datatype: SparkplugDouble, Value: float64(40)},
+		{Name: "tank1/levels/1/level", Timestamp: ms, Datatype: SparkplugDouble, Value: float64(50)},
+	}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("SparkplugMetrics() =\n%+v\nwant\n%+v", metrics, want)
+	}
+}
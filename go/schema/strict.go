@@ -0,0 +1,90 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// encodeKind is the kind of input a field type encodes from under
+// strict_types, or "" for types that check their own input (enum, fixed
+// point) or take none.
+func encodeKind(t FieldType) string {
+	switch t {
+	case TypeByte, TypeUInt, TypeU8, TypeU16, TypeU32, TypeU64, TypeU24, TypeBInt,
+		TypeSInt, TypeS8, TypeS16, TypeS32, TypeS64, TypeI8, TypeI16, TypeI32, TypeI64, TypeS24,
+		TypeFloat16, TypeFloat32, TypeFloat64, TypeF16, TypeF32, TypeF64:
+		return "number"
+	case TypeAscii, TypeAsciiLower, TypeHex:
+		return "string"
+	case TypeBytes, TypeBytesLower:
+		return "string or byte array"
+	case TypeObject:
+		return "object"
+	case TypeRepeat, TypeRepeatLower, TypeSeries:
+		return "array"
+	}
+	return ""
+}
+
+// checkEncodeType rejects input that the encoder would otherwise skip,
+// leaving the payload short. value is the input after lookups and
+// modifiers are reversed, so lookup labels have become numbers.
+func (ctx *EncodeContext) checkEncodeType(field Field, value any) error {
+	want := encodeKind(field.Type)
+	ok := true
+	switch want {
+	case "":
+		return nil
+	case "number":
+		_, ok = toFloat64(value)
+	case "string":
+		_, ok = value.(string)
+	case "string or byte array":
+		switch value.(type) {
+		case string, []byte, []any:
+		default:
+			ok = false
+		}
+	case "object":
+		_, ok = value.(map[string]any)
+	case "array":
+		_, ok = value.([]any)
+	}
+	if ok {
+		return nil
+	}
+	return fmt.Errorf("%s: expected %s for %s, got %s", ctx.fieldPath(field.Name), want, field.Type, describeInput(value))
+}
+
+// fieldPath is the path of a field in the encode input, e.g.
+// readings[2].temperature.
+func (ctx *EncodeContext) fieldPath(name string) string {
+	if len(ctx.path) == 0 {
+		return name
+	}
+	return strings.Join(ctx.path, ".") + "." + name
+}
+
+// describeInput names the type of an input value in JSON terms.
+func describeInput(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", val)
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	if _, ok := toFloat64(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
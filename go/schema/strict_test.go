@@ -0,0 +1,82 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const strictTypesSchema = `
+name: config
+strict_types: true
+fields:
+  - name: interval
+    type: u16
+  - name: mode
+    type: u8
+    lookup: {0: "off", 1: "on"}
+  - name: label
+    type: ascii
+    length: 2
+  - name: readings
+    type: repeat
+    count: 2
+    fields:
+      - name: temperature
+        type: s16
+`
+
+func TestStrictTypesEncode(t *testing.T) {
+	s, err := ParseSchema(strictTypesSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	good := map[string]any{
+		"interval": 300,
+		"mode":     "on",
+		"label":    "AB",
+		"readings": []any{map[string]any{"temperature": 1}, map[string]any{"temperature": -1}},
+	}
+	encoded, err := s.Encode(good)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := []byte{0x01, 0x2C, 0x01, 'A', 'B', 0x00, 0x01, 0xFF, 0xFF}
+	if !bytes.Equal(encoded, want) {
+		t.Errorf("Encode() = % X, want % X", encoded, want)
+	}
+
+	tests := []struct {
+		key   string
+		value any
+		want  string
+	}{
+		{"interval", "300", `interval: expected number for u16, got string "300"`},
+		{"label", 12, "label: expected string for ascii, got number"},
+		{"readings", map[string]any{}, "readings: expected array for repeat, got object"},
+		{"readings", []any{map[string]any{"temperature": 1}, 5}, "readings[1]: expected object, got number"},
+		{"readings", []any{map[string]any{"temperature": 1}, map[string]any{"temperature": nil}},
+			"readings[1].temperature: expected number for s16, got null"},
+	}
+	for _, tt := range tests {
+		input := map[string]any{tt.key: tt.value}
+		_, err := s.Encode(input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Encode(%s: %v) error = %v, want %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+
+	// Without strict_types the same input is skipped
+	s.StrictTypes = false
+	if _, err := s.Encode(map[string]any{"interval": "300"}); err != nil {
+		t.Errorf("lenient Encode() error = %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tag_bind extracts parts of a TLV record's tag into variables, for tags
+// that carry a channel index in some of their bits:
+//
+//	- tlv:
+//	    tag_bind: {channel: "tag & 0x0F"}   # For every case, or per case:
+//	    cases:
+//	      "0x80/0xF0":
+//	        tag_bind: {channel: "tag & 0x0F", kind: "tag >> 4"}
+//	        fields:
+//	          - {name: temperature, type: s16, div: 10}
+//
+// Expressions combine tag (the record's tag), tag field names and integer
+// literals with & | ^ << >> + -, evaluated left to right; use parentheses
+// to group. Bound values are variables ($channel) for the case's fields,
+// placeholders in name_template, and keys of unmerged records.
+
+// tagBinding is a compiled tag_bind entry.
+type tagBinding struct {
+	name string
+	expr *tagExpr
+}
+
+// tagExpr is a node of a tag_bind expression: a literal, an identifier or
+// a binary operation.
+type tagExpr struct {
+	op          string
+	ident       string
+	lit         int64
+	left, right *tagExpr
+}
+
+func (e *tagExpr) eval(tag int64, vals map[string]int) int64 {
+	switch {
+	case e.op == "" && e.ident == "tag":
+		return tag
+	case e.op == "" && e.ident != "":
+		return int64(vals[e.ident])
+	case e.op == "":
+		return e.lit
+	}
+	a, b := e.left.eval(tag, vals), e.right.eval(tag, vals)
+	switch e.op {
+	case "&":
+		return a & b
+	case "|":
+		return a | b
+	case "^":
+		return a ^ b
+	case "<<":
+		return a << uint(b&63)
+	case ">>":
+		return a >> uint(b&63)
+	case "+":
+		return a + b
+	default:
+		return a - b
+	}
+}
+
+// parseTagBind compiles a tag_bind map. Identifiers other than tag must
+// be tag fields.
+func parseTagBind(raw any, tagFields []Field) ([]tagBinding, []string) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, []string{fmt.Sprintf("tag_bind: expected a map of name: expression, got %T", raw)}
+	}
+	known := map[string]bool{"tag": true}
+	for _, tf := range tagFields {
+		known[tf.Name] = true
+	}
+	var binds []tagBinding
+	var msgs []string
+	for _, name := range sortedKeys(m) {
+		src, ok := m[name].(string)
+		if !ok {
+			src = fmt.Sprint(m[name])
+		}
+		expr, err := parseTagExpr(src, known)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("tag_bind %s: %v", name, err))
+			continue
+		}
+		binds = append(binds, tagBinding{name: name, expr: expr})
+	}
+	return binds, msgs
+}
+
+// parseTagExpr parses a left-to-right tag expression.
+func parseTagExpr(src string, known map[string]bool) (*tagExpr, error) {
+	toks, err := tagExprTokens(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &tagExprParser{toks: toks, known: known}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("%q: unexpected %q", src, p.toks[p.pos])
+	}
+	return e, nil
+}
+
+type tagExprParser struct {
+	toks  []string
+	pos   int
+	known map[string]bool
+}
+
+func (p *tagExprParser) expr() (*tagExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.toks) && isTagOp(p.toks[p.pos]) {
+		op := p.toks[p.pos]
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &tagExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *tagExprParser) operand() (*tagExpr, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("expression ends early")
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	switch {
+	case tok == "(":
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.toks) || p.toks[p.pos] != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, nil
+	case tok[0] >= '0' && tok[0] <= '9':
+		n, err := strconv.ParseInt(tok, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", tok)
+		}
+		return &tagExpr{lit: n}, nil
+	case isIdentStart(tok[0]):
+		if !p.known[tok] {
+			return nil, fmt.Errorf("%s is not tag or a tag field", tok)
+		}
+		return &tagExpr{ident: tok}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok)
+}
+
+func isTagOp(tok string) bool {
+	switch tok {
+	case "&", "|", "^", "<<", ">>", "+", "-":
+		return true
+	}
+	return false
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func tagExprTokens(src string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(src[i:], "<<") || strings.HasPrefix(src[i:], ">>"):
+			toks = append(toks, src[i:i+2])
+			i += 2
+		case strings.ContainsRune("&|^+-()", rune(c)):
+			toks = append(toks, string(c))
+			i++
+		case c >= '0' && c <= '9' || isIdentStart(c):
+			j := i + 1
+			for j < len(src) && (isIdentStart(src[j]) || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, src[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("%q: unexpected %q at position %d", src, c, i)
+		}
+	}
+	return toks, nil
+}
+
+// bindTag evaluates the TLV-level and case bindings for a record.
+func bindTag(field Field, caseKey string, tag []int, tagValues map[string]int) map[string]int {
+	binds := append(append([]tagBinding{}, field.tagBinds...), field.tlvCaseBinds[caseKey]...)
+	if len(binds) == 0 {
+		return nil
+	}
+	var t int64
+	if len(tag) > 0 {
+		t = int64(tag[0])
+	}
+	out := make(map[string]int, len(binds))
+	for _, b := range binds {
+		out[b.name] = int(b.expr.eval(t, tagValues))
+	}
+	return out
+}
+
+// tagBindNames lists every name bound by a TLV field or its cases.
+func tagBindNames(f *Field) []string {
+	seen := map[string]bool{}
+	for _, b := range f.tagBinds {
+		seen[b.name] = true
+	}
+	for _, binds := range f.tlvCaseBinds {
+		for _, b := range binds {
+			seen[b.name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
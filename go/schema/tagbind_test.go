@@ -0,0 +1,96 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTLVTagBind(t *testing.T) {
+	s, err := ParseSchema(`
+name: multi
+fields:
+  - name: records
+    type: tlv
+    name_template: "ch{channel}_{name}"
+    cases:
+      "0x80/0xF0":
+        tag_bind: {channel: "tag & 0x0F"}
+        fields:
+          - {name: temperature, type: s8}
+          - {name: offset, type: number, ref: $channel, mult: 10}
+      "0x0B":
+        tag_bind: {channel: "(tag | 0x10) - 26"}
+        fields:
+          - {name: humidity, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+	got, err := s.Decode([]byte{0x83, 0xFE, 0x85, 0x14, 0x0B, 0x32})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"ch3_temperature": -2.0, "ch3_offset": 30.0,
+		"ch5_temperature": 20.0, "ch5_offset": 50.0,
+		"ch1_humidity": 50.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, want %v", got, want)
+	}
+}
+
+func TestTLVTagBindUnmerged(t *testing.T) {
+	s, err := ParseSchema(`
+name: multi
+fields:
+  - name: records
+    type: tlv
+    merge: false
+    tag_bind: {channel: "tag & 0x0F", kind: "tag >> 4"}
+    cases:
+      "0x80/0xF0":
+        - {name: temperature, type: s8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	got, err := s.Decode([]byte{0x82, 0x05})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []map[string]any{{"tag": []int{0x82}, "channel": 2.0, "kind": 8.0, "temperature": 5.0}}
+	if !reflect.DeepEqual(got["channels"], want) {
+		t.Errorf("channels = %v, want %v", got["channels"], want)
+	}
+}
+
+func TestTLVTagBindWarnings(t *testing.T) {
+	tests := []struct {
+		bind, want string
+	}{
+		{`{ch: "tag & nibble"}`, "nibble is not tag or a tag field"},
+		{`{ch: "tag &"}`, "ends early"},
+		{`{ch: "(tag & 3"}`, "missing )"},
+		{`{ch: "tag * 2"}`, "unexpected '*'"},
+		{`[tag]`, "expected a map"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - name: r\n    type: tlv\n    tag_bind: " + tt.bind +
+			"\n    cases:\n      1: [{name: v, type: u8}]\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.bind, s.Warnings, tt.want)
+		}
+	}
+}
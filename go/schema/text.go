@@ -0,0 +1,272 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Text fields (string, ascii) read length: bytes and trim the padding. A
+// string field without length: is a constant that reads nothing:
+//
+//	- {name: name, type: string, length: 16, pad: space, overflow: error}
+//	- {name: msgtype, type: string, const: "updf"}
+//
+// On encode, text shorter than length: is padded with NULs (pad: null) or
+// spaces (pad: space); longer text is cut at a character boundary
+// (overflow: truncate, the default) or rejected (overflow: error).
+//
+// encoding: selects the character set: utf8 (default), ascii, latin1 or
+// ucs2 (two bytes per character in the field's endianness; length: counts
+// bytes). Bytes that are not valid text, and characters the encoding
+// cannot represent, are replaced (U+FFFD on decode, "?" on encode) or
+// rejected with invalid_chars: error.
+
+// Text padding and overflow policies.
+const (
+	PadNull          = "null"
+	PadSpace         = "space"
+	OverflowTruncate = "truncate"
+	OverflowError    = "error"
+)
+
+// Text encodings and invalid character policies.
+const (
+	EncodingUTF8        = "utf8"
+	EncodingASCII       = "ascii"
+	EncodingLatin1      = "latin1"
+	EncodingUCS2        = "ucs2"
+	InvalidCharsReplace = "replace"
+	InvalidCharsError   = "error"
+)
+
+// parseTextOptions reads const, pad and overflow.
+func parseTextOptions(fm map[string]any, f *Field) []string {
+	var msgs []string
+	if c, ok := fm["const"]; ok {
+		if f.Type != TypeString && f.Type != TypeStringLower {
+			msgs = append(msgs, "const: applies to string fields")
+		} else if f.Length > 0 {
+			msgs = append(msgs, "const: a constant string reads no bytes; remove length:")
+		} else {
+			f.Const = c
+		}
+	}
+	if pad, ok := fm["pad"].(string); ok {
+		switch pad {
+		case PadNull, PadSpace:
+			f.Pad = pad
+		default:
+			msgs = append(msgs, fmt.Sprintf("pad: expected null or space, got %q", pad))
+		}
+	}
+	if overflow, ok := fm["overflow"].(string); ok {
+		switch overflow {
+		case OverflowTruncate, OverflowError:
+			f.Overflow = overflow
+		default:
+			msgs = append(msgs, fmt.Sprintf("overflow: expected truncate or error, got %q", overflow))
+		}
+	}
+	if enc, ok := fm["encoding"]; ok {
+		name, _ := enc.(string)
+		switch {
+		case !isTextType(f.Type):
+			msgs = append(msgs, "encoding: applies to string and ascii fields")
+		case name == EncodingUTF8 || name == EncodingASCII || name == EncodingLatin1:
+			f.Encoding = name
+		case name == EncodingUCS2:
+			f.Encoding = name
+			if f.Length%2 != 0 {
+				msgs = append(msgs, fmt.Sprintf("encoding: ucs2 needs an even length, got %d", f.Length))
+			}
+		default:
+			msgs = append(msgs, fmt.Sprintf("encoding: expected utf8, ascii, latin1 or ucs2, got %v", enc))
+		}
+	}
+	if policy, ok := fm["invalid_chars"].(string); ok {
+		switch policy {
+		case InvalidCharsReplace, InvalidCharsError:
+			f.InvalidChars = policy
+		default:
+			msgs = append(msgs, fmt.Sprintf("invalid_chars: expected replace or error, got %q", policy))
+		}
+	}
+	return msgs
+}
+
+func isTextType(t FieldType) bool {
+	switch t {
+	case TypeString, TypeStringLower, TypeAscii, TypeAsciiLower:
+		return true
+	}
+	return false
+}
+
+// isConstString reports whether a field is a string constant rather than
+// text read from the payload.
+func isConstString(f Field) bool {
+	return (f.Type == TypeString || f.Type == TypeStringLower) && f.Length == 0 && !readsRest(f)
+}
+
+// decodeText reads a text field and trims its padding.
+func decodeText(field Field, ctx *DecodeContext, length int, endian string) (string, error) {
+	data, err := ctx.Read(length)
+	if err != nil {
+		return "", err
+	}
+	text, err := textFromBytes(field, data, endian)
+	if err != nil {
+		return "", err
+	}
+	if field.Pad == PadSpace {
+		return strings.TrimRight(text, " \x00"), nil
+	}
+	return strings.TrimRight(text, "\x00"), nil
+}
+
+// textFromBytes decodes data in the field's encoding.
+func textFromBytes(field Field, data []byte, endian string) (string, error) {
+	strict := field.InvalidChars == InvalidCharsError
+	invalid := func(at int) error {
+		return fmt.Errorf("%s: invalid %s text at byte %d", field.Name, textEncoding(field), at)
+	}
+	var sb strings.Builder
+	switch field.Encoding {
+	case EncodingASCII, EncodingLatin1:
+		for i, b := range data {
+			if b > 0x7F && field.Encoding == EncodingASCII {
+				if strict {
+					return "", invalid(i)
+				}
+				sb.WriteRune(utf8.RuneError)
+				continue
+			}
+			sb.WriteRune(rune(b))
+		}
+	case EncodingUCS2:
+		for i := 0; i+1 < len(data); i += 2 {
+			r := rune(decodeUint(data[i:i+2], endian))
+			if r >= 0xD800 && r <= 0xDFFF {
+				if strict {
+					return "", invalid(i)
+				}
+				r = utf8.RuneError
+			}
+			sb.WriteRune(r)
+		}
+	default:
+		if utf8.Valid(data) {
+			return string(data), nil
+		}
+		if strict {
+			for i := 0; i < len(data); {
+				r, n := utf8.DecodeRune(data[i:])
+				if r == utf8.RuneError && n <= 1 {
+					return "", invalid(i)
+				}
+				i += n
+			}
+		}
+		return strings.ToValidUTF8(string(data), string(utf8.RuneError)), nil
+	}
+	return sb.String(), nil
+}
+
+// textToBytes encodes text in the field's encoding, one slice per
+// character so that truncation keeps characters whole.
+func textToBytes(field Field, text, path, endian string) ([][]byte, error) {
+	strict := field.InvalidChars == InvalidCharsError
+	var chars [][]byte
+	for i, r := range text {
+		if r == utf8.RuneError && !strings.HasPrefix(text[i:], string(utf8.RuneError)) {
+			// Invalid UTF-8 in the input itself
+			if strict {
+				return nil, fmt.Errorf("%s: input is not valid UTF-8 at byte %d", path, i)
+			}
+		}
+		var b []byte
+		switch field.Encoding {
+		case EncodingASCII, EncodingLatin1:
+			limit := rune(0xFF)
+			if field.Encoding == EncodingASCII {
+				limit = 0x7F
+			}
+			if r > limit {
+				if strict {
+					return nil, fmt.Errorf("%s: %q cannot be encoded as %s", path, r, field.Encoding)
+				}
+				r = '?'
+			}
+			b = []byte{byte(r)}
+		case EncodingUCS2:
+			if r > 0xFFFF || r >= 0xD800 && r <= 0xDFFF {
+				if strict {
+					return nil, fmt.Errorf("%s: %q cannot be encoded as ucs2", path, r)
+				}
+				r = '?'
+			}
+			b = encodeUint(uint64(r), 2, endian)
+		default:
+			b = utf8.AppendRune(nil, r)
+		}
+		chars = append(chars, b)
+	}
+	return chars, nil
+}
+
+// textEncoding returns the encoding a text field uses.
+func textEncoding(field Field) string {
+	if field.Encoding == "" {
+		return EncodingUTF8
+	}
+	return field.Encoding
+}
+
+// encodeText writes text padded or cut to length bytes.
+func encodeText(field Field, value any, length int, endian string, ctx *EncodeContext) error {
+	strVal, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	path := ctx.fieldPath(field.Name)
+	chars, err := textToBytes(field, strVal, path, endian)
+	if err != nil {
+		return err
+	}
+	if field.Overflow == OverflowError {
+		n := 0
+		for _, c := range chars {
+			n += len(c)
+		}
+		if n > length {
+			return fmt.Errorf("%s: %d bytes of text exceed length %d", path, n, length)
+		}
+	}
+	data := make([]byte, 0, length)
+	for _, c := range chars {
+		if len(data)+len(c) > length {
+			break
+		}
+		data = append(data, c...)
+	}
+	pad := []byte{0}
+	if field.Pad == PadSpace {
+		chars, _ := textToBytes(field, " ", path, endian)
+		pad = chars[0]
+	} else if field.Encoding == EncodingUCS2 {
+		pad = []byte{0, 0}
+	}
+	for len(data)+len(pad) <= length {
+		data = append(data, pad...)
+	}
+	for len(data) < length {
+		data = append(data, 0)
+	}
+	ctx.Write(data)
+	return nil
+}
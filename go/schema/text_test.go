@@ -0,0 +1,154 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStringDecodeEncode(t *testing.T) {
+	s, err := ParseSchema(`
+name: text
+fields:
+  - {name: kind, type: string, const: "status"}
+  - {name: label, type: string, length: 6}
+  - {name: site, type: string, length: 5, pad: space}
+  - {name: serial, type: ascii, length: 4}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	data := []byte("Café\x00AB   X1\x00\x00")
+	got, err := s.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["kind"] != "status" || got["label"] != "Café" || got["site"] != "AB" || got["serial"] != "X1" {
+		t.Errorf("Decode() = %q", got)
+	}
+
+	out, err := s.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("Encode() = %q, want %q", out, data)
+	}
+}
+
+func TestStringEncodeOverflow(t *testing.T) {
+	s, err := ParseSchema(`
+name: text
+fields:
+  - {name: label, type: string, length: 4}
+  - {name: code, type: string, length: 2, overflow: error}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	// Truncation does not split the two-byte é
+	out, err := s.Encode(map[string]any{"label": "abcé", "code": "OK"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := []byte("abc\x00OK"); !bytes.Equal(out, want) {
+		t.Errorf("Encode() = %q, want %q", out, want)
+	}
+
+	_, err = s.Encode(map[string]any{"label": "a", "code": "TOO"})
+	if err == nil || !strings.Contains(err.Error(), "code: 3 bytes of text exceed length 2") {
+		t.Errorf("Encode() error = %v, want overflow", err)
+	}
+}
+
+func TestTextOptionWarnings(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{"{name: a, type: u8, const: x}", "applies to string fields"},
+		{"{name: a, type: string, length: 2, const: x}", "reads no bytes"},
+		{"{name: a, type: string, length: 2, pad: zero}", "expected null or space"},
+		{"{name: a, type: string, length: 2, overflow: wrap}", "expected truncate or error"},
+		{"{name: a, type: u16, encoding: ucs2}", "applies to string and ascii fields"},
+		{"{name: a, type: string, length: 3, encoding: ucs2}", "even length"},
+		{"{name: a, type: string, length: 2, encoding: ebcdic}", "expected utf8, ascii, latin1 or ucs2"},
+		{"{name: a, type: string, length: 2, invalid_chars: skip}", "expected replace or error"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.field, s.Warnings, tt.want)
+		}
+	}
+}
+
+func TestTextEncodings(t *testing.T) {
+	s, err := ParseSchema(`
+name: text
+fields:
+  - {name: name, type: string, length: 6, encoding: ucs2, endian: little}
+  - {name: city, type: string, length: 5, encoding: latin1, pad: space}
+  - {name: code, type: ascii, length: 3, encoding: ascii}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+
+	data := []byte{'Z', 0, 0xFC, 0, 0, 0, 'K', 0xF6, 'l', 'n', ' ', 'A', 0xC0, 'B'}
+	got, err := s.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got["name"] != "Zü" || got["city"] != "Köln" || got["code"] != "A�B" {
+		t.Errorf("Decode() = %q", got)
+	}
+
+	out, err := s.Encode(map[string]any{"name": "Zü", "city": "Köln", "code": "A€B"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := []byte{'Z', 0, 0xFC, 0, 0, 0, 'K', 0xF6, 'l', 'n', ' ', 'A', '?', 'B'}
+	if !bytes.Equal(out, want) {
+		t.Errorf("Encode() = % X, want % X", out, want)
+	}
+}
+
+func TestTextInvalidCharsError(t *testing.T) {
+	s, err := ParseSchema(`
+name: text
+fields:
+  - {name: label, type: string, length: 3, invalid_chars: error}
+  - {name: code, type: string, length: 2, encoding: latin1, invalid_chars: error}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.Decode([]byte{'a', 0xFF, 'b', 'x', 'y'}); err == nil || !strings.Contains(err.Error(), "invalid utf8 text at byte 1") {
+		t.Errorf("Decode() error = %v", err)
+	}
+	if _, err := s.Encode(map[string]any{"label": "ab", "code": "€"}); err == nil || !strings.Contains(err.Error(), "cannot be encoded as latin1") {
+		t.Errorf("Encode() error = %v", err)
+	}
+
+	// Replacement is the default
+	s, _ = ParseSchema("name: t\nfields:\n  - {name: label, type: string, length: 3}\n")
+	got, err := s.Decode([]byte{'a', 0xFF, 'b'})
+	if err != nil || got["label"] != "a�b" {
+		t.Errorf("Decode() = %q, %v", got["label"], err)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time-series roles (the `role:` field key). Fields without a role are
+// measurements.
+const (
+	RoleTag       = "tag"       // Dimension: indexed, low cardinality (mode, channel id)
+	RoleField     = "field"     // Measurement value
+	RoleTimestamp = "timestamp" // Point time: Unix seconds or an RFC 3339 string
+)
+
+// LineProtocolOptions controls LineProtocol output.
+type LineProtocolOptions struct {
+	Measurement string            // Defaults to the schema name
+	FPort       int               // Port the result was decoded on (port-based schemas)
+	Tags        map[string]string // Extra tags, e.g. the device EUI
+	Time        time.Time         // Point time when no field has role: timestamp; zero omits it
+}
+
+// LineProtocol renders a decoded result as one InfluxDB line-protocol
+// point. Nested objects and arrays are flattened to dotted keys (see
+// Flatten); fields with role: tag become tags, role: timestamp sets the
+// point time and everything else is a field. Integers carry the `i`
+// suffix, NaN and infinite values are dropped and keys starting with an
+// underscore (decoder metadata) are skipped.
+func (s *Schema) LineProtocol(result map[string]any, opts LineProtocolOptions) (string, error) {
+	roles, err := s.Roles(opts.FPort)
+	if err != nil {
+		return "", err
+	}
+	measurement := opts.Measurement
+	if measurement == "" {
+		measurement = s.Name
+	}
+	if measurement == "" {
+		return "", fmt.Errorf("line protocol: no measurement name")
+	}
+
+	tags := make(map[string]string, len(opts.Tags))
+	for k, v := range opts.Tags {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+	fields := map[string]string{}
+	ts := opts.Time
+	for key, v := range Flatten(result) {
+		if strings.HasPrefix(key, "_") || v == nil {
+			continue
+		}
+		switch roles[s.rolePath(key)] {
+		case RoleTag:
+			if tv := tagValue(v); tv != "" {
+				tags[key] = tv
+			}
+		case RoleTimestamp:
+			t, err := pointTime(v)
+			if err != nil {
+				return "", fmt.Errorf("line protocol: %s: %w", key, err)
+			}
+			ts = t
+		default:
+			if fv, ok := fieldValue(v); ok {
+				fields[key] = fv
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("line protocol: %s has no field values", measurement)
+	}
+
+	var b strings.Builder
+	b.WriteString(lpEscape(measurement, ", "))
+	for _, k := range sortedKeys(tags) {
+		b.WriteString("," + lpEscape(k, ",= ") + "=" + lpEscape(tags[k], ",= "))
+	}
+	for i, k := range sortedKeys(fields) {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(lpEscape(k, ",= ") + "=" + fields[k])
+	}
+	if !ts.IsZero() {
+		b.WriteString(" " + strconv.FormatInt(ts.UnixNano(), 10))
+	}
+	return b.String(), nil
+}
+
+// Roles returns the declared time-series role of each output key on
+// fPort, keyed by dotted path without array indices (so "readings.temp"
+// covers every element of readings). Fields without a role are omitted.
+func (s *Schema) Roles(fPort int) (map[string]string, error) {
+	roles := map[string]string{}
+	err := s.walkOutputPaths(fPort, func(f Field, path string) {
+		if f.Role != "" {
+			roles[path] = f.Role
+		}
+	})
+	return roles, err
+}
+
+// walkOutputPaths calls fn for every named field decoded on fPort with
+// the dotted output path (restyled, without array indices) its value
+// lands under. The body field of a flattened repeat is reported under
+// the repeat's own path.
+func (s *Schema) walkOutputPaths(fPort int, fn func(f Field, path string)) error {
+	fields, err := s.ResolveFields(fPort)
+	if err != nil {
+		return err
+	}
+	s.walkPaths(s.ResolveHeader(fPort), "", fn, 0)
+	s.walkPaths(fields, "", fn, 0)
+	return nil
+}
+
+func (s *Schema) walkPaths(fields []Field, prefix string, fn func(f Field, path string), depth int) {
+	if depth > maxRefDepth {
+		return
+	}
+	for _, f := range fields {
+		if f.Ref2 != "" {
+			if def, err := lookupDefinition(f.Ref2, s.Definitions); err == nil {
+				s.walkPaths(def.Fields, prefix, fn, depth+1)
+			}
+			continue
+		}
+		path := prefix + s.Output.restyleKey(f.Name)
+		if f.Flatten && len(f.Fields) == 1 {
+			fn(f.Fields[0], path)
+			continue
+		}
+		inner := prefix
+		if f.Name != "" {
+			fn(f, path)
+			if len(f.Fields) > 0 || len(f.Cases) > 0 {
+				inner = path + "."
+			}
+		}
+		s.walkPaths(f.Fields, inner, fn, depth)
+		s.walkPaths(f.ByteGroup, prefix, fn, depth)
+		for _, c := range f.Cases {
+			s.walkPaths(c.Fields, inner, fn, depth)
+		}
+		if f.MatchInline != nil {
+			for _, c := range f.MatchInline.Cases {
+				s.walkPaths(c.Fields, prefix, fn, depth)
+			}
+		}
+		if f.Flagged != nil {
+			for _, g := range f.Flagged.Groups {
+				s.walkPaths(g.Fields, prefix, fn, depth)
+			}
+		}
+		for _, c := range f.TLVCases {
+			s.walkPaths(c, prefix, fn, depth)
+		}
+		if f.TLVInline != nil {
+			for _, c := range f.TLVInline.TLVCases {
+				s.walkPaths(c, prefix, fn, depth)
+			}
+		}
+	}
+}
+
+// rolePath maps a flattened output key back to its Roles key by removing
+// the namespace prefix and array indices.
+func (s *Schema) rolePath(key string) string {
+	if ns := s.Output.Namespace; ns != "" {
+		key = strings.TrimPrefix(key, ns+".")
+	}
+	return stripIndices(key)
+}
+
+func tagValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// fieldValue formats v as a line-protocol field value.
+func fieldValue(v any) (string, bool) {
+	switch val := v.(type) {
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case float32:
+		return fieldValue(float64(val))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%di", val), true
+	case bool:
+		return strconv.FormatBool(val), true
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val) + `"`, true
+	}
+	return "", false
+}
+
+// pointTime interprets a timestamp-role value: Unix seconds (fractions
+// allowed), an RFC 3339 string or a time.Time.
+func pointTime(v any) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, val)
+	}
+	if f, ok := toFloat64(v); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot use %v (%T) as a timestamp", v, v)
+}
+
+// lpEscape backslash-escapes the characters in special.
+func lpEscape(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineProtocol(t *testing.T) {
+	s, err := ParseSchema(`
+name: env sensor
+fields:
+  - name: mode
+    type: u8
+    lookup: {0: idle, 1: active}
+    role: tag
+  - name: time
+    type: u32
+    role: timestamp
+  - name: temperature
+    type: s16
+    div: 10
+  - name: channels
+    type: repeat
+    count: 2
+    fields:
+      - {name: id, type: u8, role: tag}
+      - {name: level, type: u8}
+  - name: label
+    type: ascii
+    length: 3
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode([]byte{0x01, 0x65, 0x00, 0x00, 0x00, 0x00, 0xD7, 0x07, 0x10, 0x08, 0x20, 'a', ' ', 'b'})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	line, err := s.LineProtocol(result, LineProtocolOptions{Tags: map[string]string{"dev_eui": "0011"}})
+	if err != nil {
+		t.Fatalf("LineProtocol() error = %v", err)
+	}
+	want := `env\ sensor,channels.0.id=7,channels.1.id=8,dev_eui=0011,mode=active ` +
+		`channels.0.level=16,channels.1.level=32,label="a b",temperature=21.5 1694498816000000000`
+	if line != want {
+		t.Errorf("LineProtocol() =\n %s\nwant\n %s", line, want)
+	}
+
+	// Without a timestamp field the option time is used
+	delete(result, "time")
+	at := time.Unix(10, 0)
+	line, err = s.LineProtocol(result, LineProtocolOptions{Measurement: "env", Time: at})
+	if err != nil {
+		t.Fatalf("LineProtocol() error = %v", err)
+	}
+	if !strings.HasPrefix(line, "env,") || !strings.HasSuffix(line, " 10000000000") {
+		t.Errorf("LineProtocol() = %s", line)
+	}
+}
+
+func TestLineProtocolNoFields(t *testing.T) {
+	s, err := ParseSchema(`
+name: tags_only
+fields:
+  - {name: mode, type: u8, role: tag}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if _, err := s.LineProtocol(map[string]any{"mode": 1.0}, LineProtocolOptions{}); err == nil {
+		t.Error("LineProtocol() succeeded with no field values")
+	}
+}
+
+func TestRoleInvalid(t *testing.T) {
+	s, err := ParseSchema(`
+name: bad
+fields:
+  - {name: mode, type: u8, role: dimension}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if w := s.checkInvalid(); len(w) != 1 || !strings.Contains(w[0], "role") {
+		t.Errorf("checkInvalid() = %v, want role warning", w)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TLV sections encode from either form decoding produces:
+//
+//   - Flattened keys, as merged decode output: {battery: 100, temperature:
+//     27.2}. Each case whose fields have a value is written once, with the
+//     tag taken from its key (composite keys such as [3, 0x67] set the
+//     tag_key fields in order). Cases are written in tag order; a key
+//     shared by several cases is written by the case with the lowest tag.
+//     Pattern keys (value/mask, ranges) name no single tag and are skipped.
+//   - A channels array, as unmerged decode output: {channels: [{tag: [3,
+//     0x67], temperature: 27.2}]}. Records are written in array order, so
+//     a tag may repeat. Each entry gives its tag as tag:, or as the values
+//     of the tag_key fields (channel_id, channel_type).
+//
+// A channels array takes precedence: when the input has one, flattened
+// keys are not read. With length_size the length is the size of the
+// encoded record value.
+
+// TLVChannelsKey is the input key of an explicit TLV record list, as
+// decoded with merge: false.
+const TLVChannelsKey = "channels"
+
+// encodeTLV writes the records of a TLV section.
+func encodeTLV(field Field, data map[string]any, ctx *EncodeContext) error {
+	if raw, ok := data[TLVChannelsKey]; ok {
+		var channels []any
+		switch list := raw.(type) {
+		case []any:
+			channels = list
+		case []map[string]any:
+			// As decoded
+			for _, entry := range list {
+				channels = append(channels, entry)
+			}
+		default:
+			return fmt.Errorf("%s: expected an array of records, got %s", ctx.fieldPath(TLVChannelsKey), describeInput(raw))
+		}
+		for i, item := range channels {
+			entry, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s[%d]: expected an object, got %s", ctx.fieldPath(TLVChannelsKey), i, describeInput(item))
+			}
+			tag, err := channelTag(field, entry)
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %w", ctx.fieldPath(TLVChannelsKey), i, err)
+			}
+			key := findTLVCase(field, tag)
+			if key == "" {
+				return fmt.Errorf("%s[%d]: no TLV case for tag %v", ctx.fieldPath(TLVChannelsKey), i, tag)
+			}
+			if err := encodeTLVRecord(field, tag, entry, field.TLVCases[key], entry, ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if field.NameTemplate != "" {
+		if hasTemplatedKeys(field, data) {
+			return fmt.Errorf("TLV with name_template: encode from a %s array", TLVChannelsKey)
+		}
+		return nil
+	}
+
+	type record struct {
+		tag    []int
+		fields []Field
+	}
+	var records []record
+	for key, fields := range field.TLVCases {
+		if tag, ok := caseKeyTag(key); ok {
+			records = append(records, record{tag, fields})
+		}
+	}
+	slices.SortFunc(records, func(a, b record) int { return slices.Compare(a.tag, b.tag) })
+
+	claimed := map[string]bool{}
+	for _, r := range records {
+		fresh := false
+		for _, f := range r.fields {
+			if _, ok := lookupEncodeValue(f, data); ok && f.Name != "" && !claimed[f.Name] {
+				fresh = true
+			}
+		}
+		if !fresh && !hasRefValues(r.fields, data, ctx) {
+			continue
+		}
+		for _, f := range r.fields {
+			if _, ok := lookupEncodeValue(f, data); ok {
+				claimed[f.Name] = true
+			}
+		}
+		if err := encodeTLVRecord(field, r.tag, nil, r.fields, data, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasRefValues reports whether the $ref fields of a case have input.
+func hasRefValues(fields []Field, data map[string]any, ctx *EncodeContext) bool {
+	for _, f := range fields {
+		if f.Ref2 != "" && hasEncodeValues([]Field{f}, data, ctx.Definitions, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTemplatedKeys reports whether data holds a key that looks renamed by
+// a name_template (ending in a case field's name), which flattened input
+// cannot map back to a tag.
+func hasTemplatedKeys(field Field, data map[string]any) bool {
+	for _, fields := range field.TLVCases {
+		for _, f := range fields {
+			if f.Name == "" {
+				continue
+			}
+			for k := range data {
+				if k != f.Name && strings.HasSuffix(k, f.Name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// caseKeyTag returns the tag an exact case key names.
+func caseKeyTag(key string) ([]int, bool) {
+	if n, err := strconv.Atoi(key); err == nil {
+		return []int{n}, true
+	}
+	return parseCompositeKey(key)
+}
+
+// channelTag reads the tag of a channels entry: tag: as a number or list,
+// else the entry's tag_key fields.
+func channelTag(field Field, entry map[string]any) ([]int, error) {
+	switch t := entry["tag"].(type) {
+	case nil:
+	case []any:
+		tag := make([]int, len(t))
+		for i, part := range t {
+			n, ok := toInt(part)
+			if !ok {
+				return nil, fmt.Errorf("tag: expected integers, got %s", describeInput(part))
+			}
+			tag[i] = n
+		}
+		return tag, nil
+	case []int:
+		return t, nil
+	default:
+		n, ok := toInt(t)
+		if !ok {
+			return nil, fmt.Errorf("tag: expected an integer or a list, got %s", describeInput(t))
+		}
+		return []int{n}, nil
+	}
+
+	names := tagKeyNames(field)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("record has no tag")
+	}
+	tag := make([]int, len(names))
+	for i, name := range names {
+		n, ok := toInt(entry[name])
+		if !ok {
+			return nil, fmt.Errorf("record has no tag: or %s", name)
+		}
+		tag[i] = n
+	}
+	return tag, nil
+}
+
+// tagKeyNames lists the tag fields forming the tag, as decodeTLV reads
+// tag_key.
+func tagKeyNames(field Field) []string {
+	if len(field.TagFields) == 0 {
+		return nil
+	}
+	switch tk := field.TagKey.(type) {
+	case []any:
+		var names []string
+		for _, k := range tk {
+			if name, ok := k.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	case []string:
+		return tk
+	case string:
+		return []string{tk}
+	}
+	if field.TagFields[0].Name != "" {
+		return []string{field.TagFields[0].Name}
+	}
+	return nil
+}
+
+// encodeTLVRecord writes one record: its tag, its length if the TLV has
+// one, and the case's fields from values. entry, if set, supplies tag
+// fields outside tag_key.
+func encodeTLVRecord(field Field, tag []int, entry map[string]any, fields []Field, values map[string]any, ctx *EncodeContext) error {
+	if len(field.TagFields) > 0 {
+		tagValues := map[string]int{}
+		for _, tf := range field.TagFields {
+			if n, ok := toInt(entry[tf.Name]); ok {
+				tagValues[tf.Name] = n
+			}
+		}
+		names := tagKeyNames(field)
+		if len(names) != len(tag) {
+			return fmt.Errorf("TLV tag %v: expected %d values for %v", tag, len(names), names)
+		}
+		for i, name := range names {
+			tagValues[name] = tag[i]
+		}
+		for _, tf := range field.TagFields {
+			length := tf.Length
+			if length == 0 {
+				length = 1
+			}
+			ctx.Write(encodeUint(uint64(tagValues[tf.Name]), length, ctx.Endian))
+		}
+	} else {
+		tagSize := field.TagSize
+		if tagSize == 0 {
+			tagSize = 1
+		}
+		if len(tag) != 1 {
+			return fmt.Errorf("TLV tag %v: expected a single tag", tag)
+		}
+		ctx.Write(encodeUint(uint64(tag[0]), tagSize, ctx.Endian))
+	}
+
+	body := NewEncodeContext(ctx.Endian)
+	body.Definitions = ctx.Definitions
+	body.StrictTypes = ctx.StrictTypes
+	body.Overflow = ctx.Overflow
+	body.Rounding = ctx.Rounding
+	body.Variables = ctx.Variables
+	body.path = ctx.path
+	if err := encodeFields(fields, values, body); err != nil {
+		return err
+	}
+	if field.LengthSize > 0 {
+		ctx.Write(encodeUint(uint64(len(body.Buffer)), field.LengthSize, ctx.Endian))
+	}
+	ctx.Write(body.Buffer)
+	return nil
+}
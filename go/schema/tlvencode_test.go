@@ -0,0 +1,171 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const compositeTLVSchema = `
+name: milesight_am307
+endian: little
+fields:
+  - tlv:
+      tag_fields:
+        - {name: channel_id, type: u8}
+        - {name: channel_type, type: u8}
+      tag_key: [channel_id, channel_type]
+      cases:
+        "[1, 0x75]":
+          - {name: battery, type: u8}
+        "[3, 0x67]":
+          - {name: temperature, type: s16, mult: 0.1}
+        "[4, 0x68]":
+          - {name: humidity, type: u8, mult: 0.5}
+`
+
+func TestTLVCompositeEncodeFlattened(t *testing.T) {
+	s, err := ParseSchema(compositeTLVSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+	payload := []byte{
+		0x01, 0x75, 0x64, // battery 100
+		0x03, 0x67, 0x10, 0x01, // temperature 27.2
+		0x04, 0x68, 0x5A, // humidity 45
+	}
+
+	encoded, err := s.Encode(map[string]any{"battery": 100, "temperature": 27.2, "humidity": 45})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("Encode() = % X, want % X", encoded, payload)
+	}
+
+	// Only cases with input are written
+	encoded, err = s.Encode(map[string]any{"humidity": 45})
+	if err != nil || !bytes.Equal(encoded, payload[7:]) {
+		t.Errorf("Encode(humidity) = % X, %v; want % X", encoded, err, payload[7:])
+	}
+
+	decoded, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	again, err := s.Encode(decoded)
+	if err != nil || !bytes.Equal(again, payload) {
+		t.Errorf("round trip = % X, %v; want % X", again, err, payload)
+	}
+}
+
+func TestTLVCompositeEncodeChannels(t *testing.T) {
+	s, err := ParseSchema(strings.Replace(compositeTLVSchema, "tag_key:", "merge: false\n      tag_key:", 1))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	// Two temperature records, out of tag order
+	payload := []byte{
+		0x03, 0x67, 0x10, 0x01,
+		0x01, 0x75, 0x64,
+		0x03, 0x67, 0xF6, 0xFF,
+	}
+	decoded, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	encoded, err := s.Encode(decoded)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(encoded, payload) {
+		t.Errorf("round trip = % X, want % X", encoded, payload)
+	}
+
+	// Tags may also be given as the tag_key fields; channels win over
+	// flattened keys
+	encoded, err = s.Encode(map[string]any{
+		"battery": 50,
+		"channels": []any{
+			map[string]any{"channel_id": 1, "channel_type": 0x75, "battery": 100},
+		},
+	})
+	if err != nil || !bytes.Equal(encoded, payload[4:7]) {
+		t.Errorf("Encode(channels) = % X, %v; want % X", encoded, err, payload[4:7])
+	}
+
+	for _, entry := range []map[string]any{
+		{"tag": []any{9, 9}, "battery": 1},
+		{"battery": 1},
+	} {
+		if _, err := s.Encode(map[string]any{"channels": []any{entry}}); err == nil {
+			t.Errorf("Encode(%v): no error", entry)
+		}
+	}
+}
+
+func TestTLVEncodeLength(t *testing.T) {
+	s, err := ParseSchema(`
+name: tlv_length
+fields:
+  - tlv:
+      tag_size: 1
+      length_size: 1
+      cases:
+        "0x01":
+          - {name: temperature, type: s16, div: 10}
+        "0x02":
+          - {name: serial, type: ascii, length: 4}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	input := map[string]any{"temperature": -1.5, "serial": "AB12"}
+	encoded, err := s.Encode(input)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := []byte{0x01, 0x02, 0xFF, 0xF1, 0x02, 0x04, 'A', 'B', '1', '2'}
+	if !bytes.Equal(encoded, want) {
+		t.Errorf("Encode() = % X, want % X", encoded, want)
+	}
+	decoded, err := s.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded["serial"], "AB12") || toComparable(decoded["temperature"]) != -1.5 {
+		t.Errorf("Decode() = %v", decoded)
+	}
+}
+
+func TestTLVCompositeSpacedKeys(t *testing.T) {
+	data, err := os.ReadFile("../../schemas/devices/milesight/ws303.yaml")
+	if err != nil {
+		t.Skip("ws303.yaml not available")
+	}
+	s, err := ParseSchema(string(data))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x01, 0x75, 0x64, 0x03, 0x00, 0x01}
+	decoded, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if toComparable(decoded["battery"]) != 100.0 || toComparable(decoded["leak_status"]) != 1.0 {
+		t.Errorf("Decode() = %v", decoded)
+	}
+	encoded, err := s.Encode(decoded)
+	if err != nil || !bytes.Equal(encoded, payload) {
+		t.Errorf("round trip = % X, %v; want % X", encoded, err, payload)
+	}
+}
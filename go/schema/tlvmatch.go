@@ -0,0 +1,168 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TLV case keys can match a set of tags, for vendors that put a channel
+// index in the low bits of the tag:
+//
+//	"0x80/0xF0"   value/mask: any tag whose high nibble is 8
+//	"16-31"       inclusive range (also "16..31" or "0x10-0x1F")
+//
+// An exact key always wins; among patterns the narrowest match wins.
+
+// tlvPattern is a TLV case key matching a set of single tags.
+type tlvPattern struct {
+	key         string
+	value, mask uint64 // Masked: tag&mask == value
+	lo, hi      uint64 // Range: lo <= tag <= hi (when mask is 0)
+	width       uint64 // Number of tags matched
+}
+
+func (p tlvPattern) matches(tag uint64) bool {
+	if p.mask != 0 {
+		return tag&p.mask == p.value
+	}
+	return tag >= p.lo && tag <= p.hi
+}
+
+// parseTLVPattern parses a masked or range case key. ok is false for keys
+// that are not patterns (exact tags, composite [a, b] keys).
+func parseTLVPattern(key string, tagSize int) (p tlvPattern, ok bool, err error) {
+	p.key = key
+	tagBits := 8 * tagSize
+	if tagBits <= 0 || tagBits > 64 {
+		tagBits = 64
+	}
+	if v, m, found := strings.Cut(key, "/"); found {
+		value, err1 := strconv.ParseUint(strings.TrimSpace(v), 0, 64)
+		mask, err2 := strconv.ParseUint(strings.TrimSpace(m), 0, 64)
+		if err1 != nil || err2 != nil || mask == 0 {
+			return p, true, fmt.Errorf("TLV case %q: expected value/mask, e.g. 0x80/0xF0", key)
+		}
+		if value&^mask != 0 {
+			return p, true, fmt.Errorf("TLV case %q: value has bits outside the mask", key)
+		}
+		p.value, p.mask = value, mask
+		free := tagBits - bits.OnesCount64(mask)
+		p.width = math.MaxUint64
+		if free < 64 {
+			p.width = 1 << max(free, 0)
+		}
+		return p, true, nil
+	}
+	lo, hi, found := strings.Cut(key, "..")
+	if !found {
+		lo, hi, found = strings.Cut(key, "-")
+	}
+	if !found {
+		return p, false, nil
+	}
+	var err1, err2 error
+	p.lo, err1 = strconv.ParseUint(strings.TrimSpace(lo), 0, 64)
+	p.hi, err2 = strconv.ParseUint(strings.TrimSpace(hi), 0, 64)
+	if err1 != nil || err2 != nil || p.lo > p.hi {
+		return p, true, fmt.Errorf("TLV case %q: expected a range low-high, e.g. 16-31", key)
+	}
+	p.width = p.hi - p.lo + 1
+	return p, true, nil
+}
+
+// compileTLVCases turns the pattern keys of a TLV field's cases into
+// matchers, narrowest first, and hex exact keys into decimal ones.
+func compileTLVCases(f *Field) []string {
+	var msgs []string
+	f.tlvPatterns = nil
+	for _, key := range sortedKeys(f.TLVCases) {
+		p, isPattern, err := parseTLVPattern(key, f.TagSize)
+		switch {
+		case err != nil:
+			msgs = append(msgs, err.Error())
+		case isPattern:
+			f.tlvPatterns = append(f.tlvPatterns, p)
+		case strings.HasPrefix(key, "0x") || strings.HasPrefix(key, "0X"):
+			// Quoted hex keys ("0x0B67") match like unquoted ones
+			if n, err := strconv.ParseUint(key, 0, 64); err == nil {
+				renameTLVCase(f, key, strconv.FormatUint(n, 10))
+			}
+		case strings.HasPrefix(key, "["):
+			// Composite keys match however they are spaced: "[1, 0x75]"
+			if tag, ok := parseCompositeKey(key); ok {
+				renameTLVCase(f, key, compositeKey(tag))
+			} else {
+				msgs = append(msgs, fmt.Sprintf("TLV case %q: expected a list of tag values, e.g. [1, 0x75]", key))
+			}
+		}
+	}
+	sort.SliceStable(f.tlvPatterns, func(i, j int) bool {
+		return f.tlvPatterns[i].width < f.tlvPatterns[j].width
+	})
+	return msgs
+}
+
+// renameTLVCase moves a case to its canonical key, unless a case already
+// has that key.
+func renameTLVCase(f *Field, key, canonical string) {
+	if _, dup := f.TLVCases[canonical]; dup || canonical == key {
+		return
+	}
+	f.TLVCases[canonical] = f.TLVCases[key]
+	delete(f.TLVCases, key)
+	if binds, ok := f.tlvCaseBinds[key]; ok {
+		f.tlvCaseBinds[canonical] = binds
+		delete(f.tlvCaseBinds, key)
+	}
+}
+
+// parseCompositeKey reads a composite case key such as "[1, 0x75]".
+func parseCompositeKey(key string) ([]int, bool) {
+	inner, ok := strings.CutPrefix(strings.TrimSpace(key), "[")
+	if inner, ok = strings.CutSuffix(inner, "]"); !ok {
+		return nil, false
+	}
+	var tag []int
+	for _, part := range strings.Split(inner, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(part), 0, 32)
+		if err != nil {
+			return nil, false
+		}
+		tag = append(tag, int(n))
+	}
+	return tag, true
+}
+
+// compositeKey is the canonical case key of a composite tag, as decode
+// looks it up.
+func compositeKey(tag []int) string {
+	parts := make([]string, len(tag))
+	for i, t := range tag {
+		parts[i] = strconv.Itoa(t)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// findTLVCase returns the case key for tag: an exact key, else the
+// narrowest pattern matching it.
+func findTLVCase(field Field, tag []int) string {
+	if key := findTLVCaseKey(field.TLVCases, tag); key != "" {
+		return key
+	}
+	if len(tag) == 1 && tag[0] >= 0 {
+		for _, p := range field.tlvPatterns {
+			if p.matches(uint64(tag[0])) {
+				return p.key
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTLVMaskedCases(t *testing.T) {
+	s, err := ParseSchema(`
+name: multi
+fields:
+  - name: records
+    type: tlv
+    merge: false
+    cases:
+      "0x80/0xF0":
+        - {name: temperature, type: s8}
+      "0x81":
+        - {name: special, type: u8}
+      "16-31":
+        - {name: counter, type: u8}
+      "0x20..0x2F":
+        - {name: level, type: u8}
+      "0x00/0x80":
+        - {name: low, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if len(s.Warnings) > 0 {
+		t.Fatalf("Warnings = %v", s.Warnings)
+	}
+	// 0x83 masked, 0x81 exact, 0x12 range (narrower than 0x00/0x80), 0x2A hex range
+	got, err := s.Decode([]byte{0x83, 0xFE, 0x81, 0x07, 0x12, 0x05, 0x2A, 0x09, 0x40, 0x01})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []map[string]any{
+		{"tag": []int{0x83}, "temperature": -2.0},
+		{"tag": []int{0x81}, "special": 7.0},
+		{"tag": []int{0x12}, "counter": 5.0},
+		{"tag": []int{0x2A}, "level": 9.0},
+		{"tag": []int{0x40}, "low": 1.0},
+	}
+	if !reflect.DeepEqual(got["channels"], want) {
+		t.Errorf("channels = %v, want %v", got["channels"], want)
+	}
+}
+
+func TestTLVMaskedCaseWarnings(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{`"0x81/0xF0"`, "bits outside the mask"},
+		{`"0x80/zz"`, "expected value/mask"},
+		{`"31-16"`, "expected a range"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - name: r\n    type: tlv\n    cases:\n      " + tt.key + ": [{name: v, type: u8}]\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.key, s.Warnings, tt.want)
+		}
+	}
+}
+
+func TestTLVSources(t *testing.T) {
+	s, err := ParseSchema(`
+name: vendor
+fields:
+  - {name: version, type: u8}
+  - type: TLV
+    cases:
+      "1":
+        - {name: temperature, type: s8}
+      "2":
+        - {name: temperature, type: s8}
+        - {name: humidity, type: u8}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{0x01, 0x01, 0x15, 0x02, 0x16, 0x40}
+
+	got, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if _, ok := got[SourcesKey]; ok {
+		t.Error("_sources present without IncludeSources")
+	}
+
+	got, err = s.Decode(payload, WithLimits(DecodeLimits{IncludeSources: true}))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"temperature": []any{
+			map[string]any{"tag": []int{1}, "offset": 1},
+			map[string]any{"tag": []int{2}, "offset": 3},
+		},
+		"humidity": map[string]any{"tag": []int{2}, "offset": 3},
+	}
+	if !reflect.DeepEqual(got[SourcesKey], want) {
+		t.Errorf("_sources = %v, want %v", got[SourcesKey], want)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// outputName records where a decoded output key is first produced.
+type outputName struct {
+	path string
+	kind string
+}
+
+// nameChecker walks a field tree looking for output keys that would
+// silently overwrite each other during decode.
+type nameChecker struct {
+	schema   *Schema
+	warnings []string
+	refs     map[string]bool
+}
+
+// CheckOutputNames reports output keys that more than one reachable field
+// writes into the same decoded object. Alternatives that can never be
+// decoded together (cases of the same match) are not reported; TLV cases
+// may share a key only when they produce the same kind of value, since
+// repeated keys are merged into arrays.
+func (s *Schema) CheckOutputNames() []string {
+	nc := &nameChecker{schema: s, refs: map[string]bool{}}
+	if len(s.Ports) == 0 {
+		seen := map[string]outputName{}
+		nc.walk(s.Header, seen, "header")
+		nc.walk(s.topFields(), seen, "fields")
+		return nc.warnings
+	}
+
+	keys := make([]string, 0, len(s.Ports))
+	for k := range s.Ports {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		seen := map[string]outputName{}
+		if pd := s.Ports[k]; pd.Header != nil {
+			nc.walk(pd.Header, seen, "ports."+k+".header")
+		} else {
+			nc.walk(s.Header, seen, "header")
+		}
+		nc.walk(s.Ports[k].Fields, seen, "ports."+k)
+	}
+	return nc.warnings
+}
+
+func (nc *nameChecker) add(seen map[string]outputName, name, path, kind string) {
+	if prev, ok := seen[name]; ok {
+		nc.warnings = append(nc.warnings,
+			fmt.Sprintf("duplicate output name %q: %s overwrites %s", name, path, prev.path))
+		return
+	}
+	seen[name] = outputName{path: path, kind: kind}
+}
+
+func (nc *nameChecker) walk(fields []Field, seen map[string]outputName, path string) {
+	for i, f := range fields {
+		fp := fmt.Sprintf("%s[%d]", path, i)
+		if f.Name != "" {
+			fp = path + "." + f.Name
+		}
+
+		switch {
+		case f.Ref2 != "":
+			defName := strings.TrimPrefix(f.Ref2, "#/definitions/")
+			if nc.schema == nil || nc.schema.Definitions == nil || nc.refs[defName] {
+				continue
+			}
+			if def, ok := nc.schema.Definitions[defName]; ok {
+				nc.refs[defName] = true
+				nc.walk(def.Fields, seen, fp+"."+defName)
+				delete(nc.refs, defName)
+			}
+
+		case len(f.ByteGroup) > 0:
+			nc.walk(f.ByteGroup, seen, fp)
+
+		case f.Flagged != nil:
+			for _, g := range f.Flagged.Groups {
+				nc.walk(g.Fields, seen, fmt.Sprintf("%s.flagged[bit %d]", fp, g.Bit))
+			}
+
+		case f.Type == TypeTLV || f.Type == TypeTLVLower:
+			nc.walkTLV(f, seen, fp)
+
+		case f.TLVInline != nil:
+			nc.walkTLV(*f.TLVInline, seen, fp+".tlv")
+
+		case f.MatchInline != nil:
+			nc.walkCases(f.MatchInline.Cases, seen, fp+".match")
+
+		default:
+			if f.Name == "" {
+				continue
+			}
+			nc.add(seen, f.Name, fp, outputKind(f))
+			for _, ae := range f.AlsoEmit {
+				nc.add(seen, ae.Name, fp+".also_emit", "number")
+			}
+			// Nested structures start a new output object
+			switch f.Type {
+			case TypeObject, TypeObjectLower:
+				nc.walk(f.Fields, map[string]outputName{}, fp)
+			case TypeRepeat, TypeRepeatLower, TypeSeries:
+				nc.walk(f.Fields, map[string]outputName{}, fp+"[]")
+			case TypeMatch, TypeMatchLower:
+				nc.walkCases(f.Cases, map[string]outputName{}, fp)
+			}
+		}
+		if f.Name != "" && len(f.Aliases) > 0 {
+			for _, alias := range f.Aliases {
+				if prev, ok := seen[alias]; ok && prev.path != fp {
+					nc.warnings = append(nc.warnings,
+						fmt.Sprintf("alias %q of %s collides with %s", alias, fp, prev.path))
+				}
+			}
+		}
+	}
+}
+
+// walkCases checks mutually exclusive match cases against the enclosing
+// object, then records the union of their names.
+func (nc *nameChecker) walkCases(cases []Case, seen map[string]outputName, path string) {
+	union := map[string]outputName{}
+	for i, c := range cases {
+		caseSeen := make(map[string]outputName, len(seen))
+		for k, v := range seen {
+			caseSeen[k] = v
+		}
+		label := fmt.Sprintf("%s.case[%v]", path, c.Case)
+		if c.Default {
+			label = path + ".default"
+		} else if c.Case == nil {
+			label = fmt.Sprintf("%s.case[%d]", path, i)
+		}
+		nc.walk(c.Fields, caseSeen, label)
+		for k, v := range caseSeen {
+			if _, outer := seen[k]; !outer {
+				if _, dup := union[k]; !dup {
+					union[k] = v
+				}
+			}
+		}
+	}
+	for k, v := range union {
+		seen[k] = v
+	}
+}
+
+// walkTLV checks TLV cases: keys may repeat across cases (they are merged
+// into arrays) but must keep the same kind of value.
+func (nc *nameChecker) walkTLV(f Field, seen map[string]outputName, path string) {
+	keys := make([]string, 0, len(f.TLVCases))
+	for k := range f.TLVCases {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tlvNames := map[string]outputName{}
+	for _, k := range keys {
+		caseSeen := map[string]outputName{}
+		casePath := fmt.Sprintf("%s.cases[%s]", path, k)
+		nc.walk(f.TLVCases[k], caseSeen, casePath)
+		for name, on := range caseSeen {
+			if prev, ok := seen[name]; ok {
+				nc.warnings = append(nc.warnings,
+					fmt.Sprintf("duplicate output name %q: %s overwrites %s", name, on.path, prev.path))
+				continue
+			}
+			if prev, ok := tlvNames[name]; ok {
+				if prev.kind != on.kind {
+					nc.warnings = append(nc.warnings,
+						fmt.Sprintf("TLV output name %q has conflicting types: %s (%s) vs %s (%s)",
+							name, prev.path, prev.kind, on.path, on.kind))
+				}
+				continue
+			}
+			tlvNames[name] = on
+		}
+	}
+	for k, v := range tlvNames {
+		seen[k] = v
+	}
+}
+
+// outputKind classifies the decoded value a field produces.
+func outputKind(f Field) string {
+	if f.Lookup != nil || f.LookupArray != nil {
+		return "string"
+	}
+	switch f.Type {
+	case TypeBool, TypeBoolLower:
+		return "bool"
+	case TypeAscii, TypeAsciiLower, TypeString, TypeStringLower, TypeHex, TypeBase64,
+		TypeBitfieldString, TypeEnum, TypeEnumLower:
+		return "string"
+	case TypeBytes, TypeBytesLower:
+		if f.Format == "array" {
+			return "array"
+		}
+		return "string"
+	case TypeObject, TypeObjectLower, TypeMatch, TypeMatchLower:
+		return "object"
+	case TypeVector3:
+		if f.Format == "array" {
+			return "array"
+		}
+		return "object"
+	case TypeRepeat, TypeRepeatLower, TypeSeries:
+		return "array"
+	default:
+		return "number"
+	}
+}
+
+// checkDeprecated reports use of deprecated schema constructs.
+func (s *Schema) checkDeprecated() []string {
+	var warnings []string
+	s.walkAllFields(func(f Field, fp string) {
+		switch f.Type {
+		case TypeBool, TypeBoolLower, TypeBits, TypeBitsLower:
+			if f.ConsumeSet {
+				warnings = append(warnings, fmt.Sprintf(
+					"%s: consume on %s fields is deprecated; group shared-byte fields in a byte_group", fp, f.Type))
+			}
+		}
+	})
+	sort.Strings(warnings)
+	return warnings
+}
+
+// checkInvalid reports field keys that were present but could not be
+// parsed (and are therefore ignored), and unresolvable $ref targets.
+func (s *Schema) checkInvalid() []string {
+	var warnings []string
+	s.walkAllFields(func(f Field, fp string) {
+		for _, msg := range f.invalid {
+			warnings = append(warnings, fp+": "+msg)
+		}
+		if f.Ref2 != "" {
+			if _, err := lookupDefinition(f.Ref2, s.Definitions); err != nil {
+				warnings = append(warnings, fp+": "+err.Error())
+			}
+		}
+	})
+	sort.Strings(warnings)
+	return warnings
+}
+
+// walkAllFields calls fn for every field in the schema, including nested
+// fields, match/TLV/flagged cases, revisions, frames, ports and definitions.
+func (s *Schema) walkAllFields(fn func(f Field, path string)) {
+	var walk func(fields []Field, path string)
+	walk = func(fields []Field, path string) {
+		for i, f := range fields {
+			fp := fmt.Sprintf("%s[%d]", path, i)
+			if f.Name != "" {
+				fp = path + "." + f.Name
+			}
+			fn(f, fp)
+			walk(f.Fields, fp)
+			walk(f.ByteGroup, fp)
+			for _, c := range f.Cases {
+				walk(c.Fields, fp)
+			}
+			if f.MatchInline != nil {
+				for _, c := range f.MatchInline.Cases {
+					walk(c.Fields, fp+".match")
+				}
+			}
+			if f.Flagged != nil {
+				for _, g := range f.Flagged.Groups {
+					walk(g.Fields, fp)
+				}
+			}
+			for k, c := range f.TLVCases {
+				walk(c, fp+".cases["+k+"]")
+			}
+			if f.TLVInline != nil {
+				for k, c := range f.TLVInline.TLVCases {
+					walk(c, fp+".tlv.cases["+k+"]")
+				}
+			}
+		}
+	}
+	walk(s.Header, "header")
+	walk(s.Fields, "fields")
+	if s.Revisions != nil {
+		for i, c := range s.Revisions.Match.Cases {
+			walk(c.Fields, fmt.Sprintf("revisions.cases[%d]", i))
+		}
+	}
+	if s.Frames != nil {
+		walk(s.Frames.Header, "frames.header")
+		for i, c := range s.Frames.Match.Cases {
+			walk(c.Fields, fmt.Sprintf("frames.cases[%d]", i))
+		}
+	}
+	for k, pd := range s.Ports {
+		walk(pd.Header, "ports."+k+".header")
+		walk(pd.Fields, "ports."+k)
+	}
+	for k, dd := range s.Definitions {
+		walk(dd.Fields, "definitions."+k)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutputNamesFlaggedOverlap(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: flags
+    type: u8
+  - name: battery
+    type: u8
+  - flagged:
+      field: flags
+      groups:
+        - bit: 0
+          fields:
+            - name: battery
+              type: u16
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], `"battery"`) {
+		t.Errorf("Warnings = %v, want one battery overlap", schema.Warnings)
+	}
+}
+
+func TestOutputNamesStrictMode(t *testing.T) {
+	_, err := ParseSchema(`
+name: test
+strict: true
+fields:
+  - name: temperature
+    type: u8
+  - name: temperature
+    type: u16
+`)
+	if err == nil || !strings.Contains(err.Error(), "duplicate output name") {
+		t.Errorf("expected strict-mode duplicate error, got %v", err)
+	}
+}
+
+func TestOutputNamesTLVConflictingTypes(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: readings
+    type: tlv
+    cases:
+      "1":
+        - name: value
+          type: u8
+      "2":
+        - name: value
+          type: ascii
+          length: 4
+      "3":
+        - name: count
+          type: u8
+      "4":
+        - name: count
+          type: u16
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], "conflicting types") {
+		t.Errorf("Warnings = %v, want one conflicting-type warning for value", schema.Warnings)
+	}
+}
+
+func TestOutputNamesMatchCasesAreExclusive(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: msg_type
+    type: u8
+    var: msg_type
+  - match:
+      field: $msg_type
+      cases:
+        1:
+          - name: temperature
+            type: s16
+        2:
+          - name: temperature
+            type: s16
+          - name: msg_type
+            type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], `"msg_type"`) {
+		t.Errorf("Warnings = %v, want only the msg_type overlap", schema.Warnings)
+	}
+}
+
+func TestOutputNamesNestedObjectsSeparate(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+fields:
+  - name: value
+    type: u8
+  - name: nested
+    type: Object
+    fields:
+      - name: value
+        type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 0 {
+		t.Errorf("Warnings = %v, want none", schema.Warnings)
+	}
+}
+
+func TestOutputNamesPerPort(t *testing.T) {
+	schema, err := ParseSchema(`
+name: test
+ports:
+  1:
+    fields:
+      - name: a
+        type: u8
+  2:
+    fields:
+      - name: a
+        type: u8
+      - name: a
+        type: u8
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+	if len(schema.Warnings) != 1 || !strings.Contains(schema.Warnings[0], "ports.2") {
+		t.Errorf("Warnings = %v, want one overlap in port 2", schema.Warnings)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Variable references in formulas, ref: and compute: may step into the
+// arrays and objects decoded before them, so summary fields can read a
+// repeat's elements:
+//
+//	- name: first_temp
+//	  type: number
+//	  ref: $readings[0].temp
+//	- name: delta
+//	  type: number
+//	  formula: "$values[-1] - $values[0]"
+//
+// [i] indexes an array, counting from the end when negative, and .key
+// reads an object member. A step that does not resolve (an index out of
+// range, a missing key) makes the whole reference missing.
+
+// varStep is one [index] or .key step of a variable path.
+type varStep struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// parseVarPath reads a variable name and its steps from the start of s
+// (without the $), returning how many bytes it consumed.
+func parseVarPath(s string) (name string, steps []varStep, n int, err error) {
+	for n < len(s) && isIdentByte(s[n]) {
+		n++
+	}
+	if n == 0 || s[0] >= '0' && s[0] <= '9' {
+		return "", nil, 0, fmt.Errorf("invalid variable reference %q", s)
+	}
+	name = s[:n]
+	for n < len(s) {
+		switch {
+		case s[n] == '[':
+			end := n + 1
+			if end < len(s) && s[end] == '-' {
+				end++
+			}
+			for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+				end++
+			}
+			if end >= len(s) || s[end] != ']' {
+				return "", nil, 0, fmt.Errorf("invalid index in %q: expected [integer]", s)
+			}
+			idx, err := strconv.Atoi(s[n+1 : end])
+			if err != nil {
+				return "", nil, 0, fmt.Errorf("invalid index in %q: expected [integer]", s)
+			}
+			steps = append(steps, varStep{index: idx})
+			n = end + 1
+		case s[n] == '.' && n+1 < len(s) && isIdentByte(s[n+1]) && !(s[n+1] >= '0' && s[n+1] <= '9'):
+			end := n + 1
+			for end < len(s) && isIdentByte(s[end]) {
+				end++
+			}
+			steps = append(steps, varStep{key: s[n+1 : end], isKey: true})
+			n = end
+		default:
+			return name, steps, n, nil
+		}
+	}
+	return name, steps, n, nil
+}
+
+// resolveVarPath follows steps from the variable name.
+func resolveVarPath(vars map[string]any, name string, steps []varStep) (any, bool) {
+	v, ok := vars[name]
+	for _, step := range steps {
+		if !ok {
+			return nil, false
+		}
+		if step.isKey {
+			m, isMap := v.(map[string]any)
+			if !isMap {
+				return nil, false
+			}
+			v, ok = m[step.key]
+			continue
+		}
+		arr, isArr := v.([]any)
+		if !isArr {
+			return nil, false
+		}
+		i := step.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil, false
+		}
+		v = arr[i]
+	}
+	return v, ok
+}
+
+// lookupVar resolves a variable reference such as $battery or
+// $readings[-1].temp.
+func lookupVar(vars map[string]any, ref string) (any, bool) {
+	if len(ref) > 0 && ref[0] == '$' {
+		ref = ref[1:]
+	}
+	if v, ok := vars[ref]; ok {
+		return v, true
+	}
+	name, steps, n, err := parseVarPath(ref)
+	if err != nil || n != len(ref) || len(steps) == 0 {
+		return nil, false
+	}
+	return resolveVarPath(vars, name, steps)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import "testing"
+
+func TestIndexedVariableReferences(t *testing.T) {
+	s, err := ParseSchema(`
+name: logger
+fields:
+  - name: readings
+    type: repeat
+    count: 3
+    fields:
+      - {name: temp, type: s8}
+  - name: values
+    type: repeat
+    count: 2
+    flatten: true
+    fields:
+      - {name: v, type: u8}
+  - {name: first_temp, type: number, ref: "$readings[0].temp"}
+  - {name: temp_delta, type: number, formula: "$readings[-1].temp - $readings[0].temp"}
+  - name: value_sum
+    type: number
+    compute: {op: add, a: "$values[0]", b: "$values[-1]"}
+`)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	result, err := s.Decode([]byte{0x14, 0x16, 0x19, 0x05, 0x07})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	for key, want := range map[string]float64{"first_temp": 20, "temp_delta": 5, "value_sum": 12} {
+		if result[key] != want {
+			t.Errorf("%s = %v, want %v", key, result[key], want)
+		}
+	}
+}
+
+func TestLookupVar(t *testing.T) {
+	vars := map[string]any{
+		"readings": []any{map[string]any{"temp": 20.0}, map[string]any{"temp": 25.0}},
+		"battery":  3.3,
+	}
+	tests := []struct {
+		ref  string
+		want any
+		ok   bool
+	}{
+		{"$battery", 3.3, true},
+		{"$readings[1].temp", 25.0, true},
+		{"readings[-2].temp", 20.0, true},
+		{"$readings[2].temp", nil, false},
+		{"$readings[0].humidity", nil, false},
+		{"$battery[0]", nil, false},
+		{"$readings[x]", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookupVar(vars, tt.ref)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("lookupVar(%q) = %v, %v; want %v, %v", tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
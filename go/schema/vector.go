@@ -0,0 +1,109 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import "fmt"
+
+// A vector3 (type: vector3) is the x/y/z triplet reported by
+// accelerometer, magnetometer and gyro channels, three consecutive values
+// of one base type sharing their modifiers:
+//
+//	- name: acceleration
+//	  type: vector3
+//	  base: s16          # Type of each axis
+//	  mult: 0.001        # Modifiers, lookup, unit etc. apply to every axis
+//	  format: array      # Optional: [x, y, z] instead of {x, y, z}
+//
+// Encoding accepts either form.
+
+// Vector axis names, in wire order.
+var vectorAxisNames = [3]string{"x", "y", "z"}
+
+// checkVector3 reports vector3 settings that cannot work.
+func checkVector3(f Field) string {
+	if f.Type != TypeVector3 {
+		return ""
+	}
+	if f.Base == "" {
+		return fmt.Sprintf("%s: vector3 needs base", f.Name)
+	}
+	if encodeKind(FieldType(f.Base)) != "number" {
+		return fmt.Sprintf("%s: vector3 base must be a numeric type, got %q", f.Name, f.Base)
+	}
+	switch f.Format {
+	case "", "object", "array":
+	default:
+		return fmt.Sprintf("%s: format: expected object or array, got %q", f.Name, f.Format)
+	}
+	return ""
+}
+
+// vectorAxes returns the three axis fields of a vector3, each the vector
+// field with its own name and the base type.
+func vectorAxes(f Field) []Field {
+	axes := make([]Field, len(vectorAxisNames))
+	for i, name := range vectorAxisNames {
+		axis := f
+		axis.Name = name
+		axis.Type = FieldType(f.Base)
+		axis.Base = ""
+		axis.Var = ""
+		axis.Format = ""
+		axis.ByteOffset = 0
+		axes[i] = axis
+	}
+	return axes
+}
+
+// decodeVector3 decodes the three axes as an object, or an array with
+// format: array.
+func decodeVector3(field Field, ctx *DecodeContext) (any, error) {
+	values := make([]any, len(vectorAxisNames))
+	for i, axis := range vectorAxes(field) {
+		v, err := decodeField(axis, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", field.Name, axis.Name, err)
+		}
+		values[i] = v
+	}
+	if field.Format == "array" {
+		return values, nil
+	}
+	out := make(map[string]any, len(values))
+	for i, name := range vectorAxisNames {
+		out[name] = values[i]
+	}
+	return out, nil
+}
+
+// encodeVector3 encodes {x, y, z} or [x, y, z].
+func encodeVector3(field Field, value any, ctx *EncodeContext) error {
+	var values []any
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range vectorAxisNames {
+			axis, ok := v[name]
+			if !ok {
+				return fmt.Errorf("%s: missing axis %s", ctx.fieldPath(field.Name), name)
+			}
+			values = append(values, axis)
+		}
+	case []any:
+		if len(v) != len(vectorAxisNames) {
+			return fmt.Errorf("%s: expected 3 axis values, got %d", ctx.fieldPath(field.Name), len(v))
+		}
+		values = v
+	default:
+		return fmt.Errorf("%s: expected {x, y, z} or [x, y, z], got %s", ctx.fieldPath(field.Name), describeInput(value))
+	}
+	ctx.path = append(ctx.path, field.Name)
+	defer func() { ctx.path = ctx.path[:len(ctx.path)-1] }()
+	for i, axis := range vectorAxes(field) {
+		if err := encodeField(axis, values[i], ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
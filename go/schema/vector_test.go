@@ -0,0 +1,101 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const vectorSchema = `
+name: motion
+endian: little
+fields:
+  - {name: acceleration, type: vector3, base: s16, mult: 0.001, unit: g}
+  - {name: gyro, type: vector3, base: s16, div: 10, format: array}
+`
+
+func TestVector3(t *testing.T) {
+	s, err := ParseSchema(vectorSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	payload := []byte{
+		0xE8, 0x03, 0x18, 0xFC, 0x00, 0x00, // 1000, -1000, 0
+		0x0F, 0x00, 0xF1, 0xFF, 0x64, 0x00, // 15, -15, 100
+	}
+	got, err := s.Decode(payload)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := map[string]any{
+		"acceleration": map[string]any{"x": 1.0, "y": -1.0, "z": 0.0},
+		"gyro":         []any{1.5, -1.5, 10.0},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	encoded, err := s.Encode(got)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !reflect.DeepEqual(encoded, payload) {
+		t.Errorf("Encode() = %X, want %X", encoded, payload)
+	}
+
+	// Either form encodes
+	swapped := map[string]any{
+		"acceleration": []any{1.0, -1.0, 0.0},
+		"gyro":         map[string]any{"x": 1.5, "y": -1.5, "z": 10.0},
+	}
+	if encoded, err = s.Encode(swapped); err != nil || !reflect.DeepEqual(encoded, payload) {
+		t.Errorf("Encode(swapped) = %X, %v, want %X", encoded, err, payload)
+	}
+}
+
+func TestVector3Errors(t *testing.T) {
+	s, err := ParseSchema(vectorSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	tests := []struct {
+		accel any
+		want  string
+	}{
+		{map[string]any{"x": 1.0, "y": 1.0}, "acceleration: missing axis z"},
+		{[]any{1.0, 2.0}, "acceleration: expected 3 axis values, got 2"},
+		{1.0, "acceleration: expected {x, y, z} or [x, y, z]"},
+	}
+	for _, tt := range tests {
+		_, err := s.Encode(map[string]any{"acceleration": tt.accel, "gyro": []any{0.0, 0.0, 0.0}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Encode(%v) error = %v, want %q", tt.accel, err, tt.want)
+		}
+	}
+
+}
+
+func TestVector3Warnings(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{"{name: v, type: vector3}", "needs base"},
+		{"{name: v, type: vector3, base: ascii}", "numeric type"},
+		{"{name: v, type: vector3, base: u8, format: list}", "format:"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchema("name: t\nfields:\n  - " + tt.field + "\n")
+		if err != nil {
+			t.Fatalf("ParseSchema() error = %v", err)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[0], tt.want) {
+			t.Errorf("%s: Warnings = %v, want %q", tt.field, s.Warnings, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultWASMTimeout is the deadline of a wasm: decode call without
+// timeout_ms.
+const DefaultWASMTimeout = 50 * time.Millisecond
+
+// WASMDef references a decode function exported by a vendor WASM module.
+//
+// This package has no WASM engine of its own and does not sandbox modules:
+// calls go to the WASMRuntime set on the schema, which loads the module and
+// decides what memory and CPU it may use.
+//
+//	wasm:
+//	  module: vendor.wasm
+//	  fn: decode_port_5
+//	  timeout_ms: 20
+type WASMDef struct {
+	Module    string `json:"module" yaml:"module"`
+	Fn        string `json:"fn" yaml:"fn"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"` // Deadline of the call's context
+}
+
+// WASMResult is the output of a decode call.
+// Output must be a JSON object; Consumed is the number of input bytes used.
+type WASMResult struct {
+	Output   []byte
+	Consumed int
+}
+
+// WASMRuntime executes decode functions exported by WASM modules.
+//
+// The decode ABI is: the function receives the remaining payload bytes and
+// returns a JSON object with the decoded fields plus the number of bytes it
+// consumed. The runtime resolves module names to module bytes. Invoke runs
+// on the decoding goroutine and must return once ctx is done; its deadline
+// is the field's timeout_ms.
+type WASMRuntime interface {
+	Invoke(ctx context.Context, module, fn string, payload []byte) (*WASMResult, error)
+}
+
+// timeout returns the deadline of a call to the definition's function.
+func (wd *WASMDef) timeout() time.Duration {
+	if wd.TimeoutMs > 0 {
+		return time.Duration(wd.TimeoutMs) * time.Millisecond
+	}
+	return DefaultWASMTimeout
+}
+
+func parseWASMDef(raw map[string]any) *WASMDef {
+	wd := &WASMDef{}
+	if module, ok := raw["module"].(string); ok {
+		wd.Module = module
+	}
+	if fn, ok := raw["fn"].(string); ok {
+		wd.Fn = fn
+	}
+	if timeout, ok := intKey(raw, "timeout_ms"); ok {
+		wd.TimeoutMs = timeout
+	}
+	return wd
+}
+
+// decodeWASM runs a WASM decode function on the remaining payload and
+// advances the cursor by the number of bytes it reports as consumed.
+func decodeWASM(wd *WASMDef, ctx *DecodeContext) (map[string]any, error) {
+	if ctx.WASM == nil {
+		return nil, fmt.Errorf("wasm %s:%s: no WASM runtime configured", wd.Module, wd.Fn)
+	}
+	timeout := wd.timeout()
+	callCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	payload := append([]byte(nil), ctx.Data[ctx.Offset:]...)
+	res, err := ctx.WASM.Invoke(callCtx, wd.Module, wd.Fn, payload)
+	if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("wasm %s:%s: exceeded time limit of %v", wd.Module, wd.Fn, timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s:%s: %w", wd.Module, wd.Fn, err)
+	}
+	if res == nil {
+		return nil, fmt.Errorf("wasm %s:%s: no result", wd.Module, wd.Fn)
+	}
+	if res.Consumed < 0 || res.Consumed > ctx.Remaining() {
+		return nil, fmt.Errorf("wasm %s:%s: consumed %d bytes, but only %d remaining",
+			wd.Module, wd.Fn, res.Consumed, ctx.Remaining())
+	}
+
+	result := make(map[string]any)
+	if len(res.Output) > 0 {
+		if err := json.Unmarshal(res.Output, &result); err != nil {
+			return nil, fmt.Errorf("wasm %s:%s: invalid output: %w", wd.Module, wd.Fn, err)
+		}
+	}
+	ctx.Offset += res.Consumed
+	return result, nil
+}
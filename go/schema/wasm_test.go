@@ -0,0 +1,120 @@
+// Copyright (c) 2024-2026 Multitech Systems, Inc.
+// Author: Jason Reiss
+// SPDX-License-Identifier: MIT
+
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeWASMRuntime stands in for a real WASM engine in tests.
+type fakeWASMRuntime struct {
+	fn func(ctx context.Context, payload []byte) (*WASMResult, error)
+}
+
+func (r *fakeWASMRuntime) Invoke(ctx context.Context, module, fn string, payload []byte) (*WASMResult, error) {
+	if module != "vendor.wasm" || fn != "decode_port_5" {
+		return nil, fmt.Errorf("unknown export %s:%s", module, fn)
+	}
+	return r.fn(ctx, payload)
+}
+
+const wasmSchemaYAML = `
+name: wasm_test
+fields:
+  - name: header
+    type: u8
+  - wasm:
+      module: vendor.wasm
+      fn: decode_port_5
+      timeout_ms: 20
+  - name: trailer
+    type: u8
+`
+
+func TestWASMDecode(t *testing.T) {
+	schema, err := ParseSchema(wasmSchemaYAML)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if schema.Fields[1].WASM == nil || schema.Fields[1].WASM.Fn != "decode_port_5" {
+		t.Fatalf("wasm definition not parsed: %+v", schema.Fields[1])
+	}
+
+	var deadline time.Duration
+	schema.WASMRuntime = &fakeWASMRuntime{fn: func(ctx context.Context, payload []byte) (*WASMResult, error) {
+		if d, ok := ctx.Deadline(); ok {
+			deadline = time.Until(d)
+		}
+		return &WASMResult{
+			Output:   []byte(fmt.Sprintf(`{"vendor_value": %d}`, int(payload[0])<<8|int(payload[1]))),
+			Consumed: 2,
+		}, nil
+	}}
+
+	result, err := schema.Decode([]byte{0x01, 0x01, 0x02, 0xFF})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if result["vendor_value"] != float64(258) {
+		t.Errorf("vendor_value = %v, want 258", result["vendor_value"])
+	}
+	if result["trailer"] != float64(255) {
+		t.Errorf("trailer = %v, want 255", result["trailer"])
+	}
+	if deadline <= 0 || deadline > 20*time.Millisecond {
+		t.Errorf("call deadline in %v, want within 20ms", deadline)
+	}
+}
+
+func TestWASMNoRuntime(t *testing.T) {
+	schema, _ := ParseSchema(wasmSchemaYAML)
+	_, err := schema.Decode([]byte{0x01, 0x01, 0x02, 0xFF})
+	if err == nil || !strings.Contains(err.Error(), "no WASM runtime") {
+		t.Errorf("expected missing runtime error, got %v", err)
+	}
+}
+
+func TestWASMTimeLimit(t *testing.T) {
+	schema, _ := ParseSchema(wasmSchemaYAML)
+	returned := false
+	schema.WASMRuntime = &fakeWASMRuntime{fn: func(ctx context.Context, payload []byte) (*WASMResult, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		returned = true
+		return &WASMResult{Output: []byte(`{}`)}, nil
+	}}
+	_, err := schema.Decode([]byte{0x01, 0x01, 0x02, 0xFF})
+	if err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Errorf("expected time limit error, got %v", err)
+	}
+	// The call has finished, not been left running
+	if !returned {
+		t.Error("Decode returned before the runtime call")
+	}
+}
+
+func TestWASMConsumedOverrun(t *testing.T) {
+	schema, _ := ParseSchema(wasmSchemaYAML)
+	schema.WASMRuntime = &fakeWASMRuntime{fn: func(ctx context.Context, payload []byte) (*WASMResult, error) {
+		return &WASMResult{Output: []byte(`{}`), Consumed: len(payload) + 1}, nil
+	}}
+	if _, err := schema.Decode([]byte{0x01, 0x01}); err == nil {
+		t.Error("expected error when module consumes more than remaining")
+	}
+}
+
+func TestWASMInvalidOutput(t *testing.T) {
+	schema, _ := ParseSchema(wasmSchemaYAML)
+	schema.WASMRuntime = &fakeWASMRuntime{fn: func(ctx context.Context, payload []byte) (*WASMResult, error) {
+		return &WASMResult{Output: []byte(`[1,2]`), Consumed: 1}, nil
+	}}
+	if _, err := schema.Decode([]byte{0x01, 0x01, 0x02}); err == nil {
+		t.Error("expected error for non-object output")
+	}
+}